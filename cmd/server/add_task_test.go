@@ -0,0 +1,114 @@
+package main
+
+import (
+	"cdk-get/internal/api"
+	"cdk-get/internal/auth"
+	"cdk-get/internal/config"
+	"cdk-get/internal/storage"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestAddGiftCodeEndpoint tests POST /api/admin/tasks against a real repository
+func TestAddGiftCodeEndpoint(t *testing.T) {
+	cfg := &config.Config{
+		Logging: config.LoggingConfig{
+			Level:  "error",
+			Format: "json",
+		},
+		Admin: config.AdminConfig{
+			TokenSecret:   "test-secret",
+			TokenDuration: 24 * time.Hour,
+		},
+	}
+
+	logger := logrus.New()
+	logger.SetLevel(logrus.ErrorLevel)
+
+	sqliteConfig := storage.DefaultSqliteConfig()
+	sqliteConfig.Path = filepath.Join(t.TempDir(), "tasks.db")
+	repository, err := storage.NewSqliteRepository(sqliteConfig, logger)
+	require.NoError(t, err)
+	defer repository.Close()
+
+	ctx := context.Background()
+	group := &storage.UserGroup{Name: "vip"}
+	require.NoError(t, repository.CreateUserGroup(ctx, group))
+
+	mockRepo := &storage.MockRepository{
+		GetAdminFunc: func(ctx context.Context, username string) (*storage.Admin, error) {
+			return &storage.Admin{Username: username, Role: storage.AdminRoleOwner}, nil
+		},
+		GetAdminSessionFunc: func(ctx context.Context, id string) (*storage.AdminSession, error) {
+			return &storage.AdminSession{ID: id, Username: "admin", ExpiresAt: time.Now().Add(time.Hour)}, nil
+		},
+	}
+
+	authService := auth.NewAuthService(mockRepo, cfg.Admin.TokenSecret, cfg.Admin.TokenDuration, 7*24*time.Hour, "cdk-get")
+	adminHandlers := api.NewAdminHandlers(authService, repository, nil, nil, nil, nil, logger)
+	server := setupServer(cfg, api.NewHandlers(nil, nil, repository, nil, logger), adminHandlers, authService, mockRepo, logger)
+
+	token, _, err := authService.GenerateToken("admin", storage.AdminRoleOwner, "test-session")
+	require.NoError(t, err)
+
+	post := func(body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/api/admin/tasks", strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("Authorization", "Bearer "+token)
+		w := httptest.NewRecorder()
+		server.Handler.ServeHTTP(w, req)
+		return w
+	}
+
+	// 分组不存在时不创建任务
+	w := post(`{"code":"NOGROUP","group_id":999}`)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	_, err = repository.GetTaskByCode(ctx, "NOGROUP")
+	assert.Error(t, err)
+
+	w = post(`{"code":"VIP","group_id":` + strconv.FormatInt(group.ID, 10) + `}`)
+	require.Equal(t, http.StatusOK, w.Code)
+	task, err := repository.GetTaskByCode(ctx, "VIP")
+	require.NoError(t, err)
+	require.NotNil(t, task.TargetGroupID)
+	assert.Equal(t, group.ID, *task.TargetGroupID)
+
+	// 重复添加且未指定 group_id 时保留原目标分组
+	w = post(`{"code":"VIP"}`)
+	require.Equal(t, http.StatusOK, w.Code)
+	task, err = repository.GetTaskByCode(ctx, "VIP")
+	require.NoError(t, err)
+	require.NotNil(t, task.TargetGroupID)
+	assert.Equal(t, group.ID, *task.TargetGroupID)
+
+	// 公开接口不会恢复回收站中的兑换码
+	require.NoError(t, repository.DeleteTask(ctx, "VIP"))
+	req := httptest.NewRequest(http.MethodPost, "/giftcode?code=VIP", nil)
+	w = httptest.NewRecorder()
+	server.Handler.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusConflict, w.Code)
+	assert.Contains(t, w.Body.String(), "ALREADY_EXISTS")
+
+	// 从回收站恢复时同样保留目标分组，指定分组不存在时保持在回收站中
+	w = post(`{"code":"VIP","group_id":999}`)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	_, err = repository.GetTaskByCode(ctx, "VIP")
+	assert.Error(t, err)
+
+	w = post(`{"code":"VIP"}`)
+	require.Equal(t, http.StatusOK, w.Code)
+	task, err = repository.GetTaskByCode(ctx, "VIP")
+	require.NoError(t, err)
+	require.NotNil(t, task.TargetGroupID)
+	assert.Equal(t, group.ID, *task.TargetGroupID)
+}
@@ -0,0 +1,169 @@
+package main
+
+import (
+	"cdk-get/internal/api"
+	"cdk-get/internal/auth"
+	"cdk-get/internal/config"
+	"cdk-get/internal/storage"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+)
+
+// TestAPIKeyScopes tests that the public endpoints require an API key with the matching scope
+func TestAPIKeyScopes(t *testing.T) {
+	cfg := &config.Config{
+		Logging: config.LoggingConfig{
+			Level:  "error",
+			Format: "json",
+		},
+		Server: config.ServerConfig{
+			Host: "localhost",
+			Port: 8080,
+		},
+		Security: config.SecurityConfig{
+			APIKey: config.APIKeyConfig{Required: true},
+		},
+		Admin: config.AdminConfig{
+			TokenSecret:   "test-secret",
+			TokenDuration: 24 * time.Hour,
+		},
+	}
+
+	logger := logrus.New()
+	logger.SetLevel(logrus.ErrorLevel)
+
+	keys := map[string]*storage.APIKey{
+		auth.HashAPIKey("cdk_reader"): {ID: 1, Name: "reader", Scopes: []string{storage.APIKeyScopeReadOnly}},
+		auth.HashAPIKey("cdk_adder"):  {ID: 2, Name: "adder", Scopes: []string{storage.APIKeyScopeAddUser}},
+	}
+	mockRepo := &storage.MockRepository{
+		GetAPIKeyByHashFunc: func(ctx context.Context, keyHash string) (*storage.APIKey, error) {
+			if key, ok := keys[keyHash]; ok {
+				return key, nil
+			}
+			return nil, storage.ErrAPIKeyNotFound
+		},
+	}
+
+	authService := auth.NewAuthService(mockRepo, cfg.Admin.TokenSecret, cfg.Admin.TokenDuration, 7*24*time.Hour, "cdk-get")
+	handlers := api.NewHandlers(nil, nil, mockRepo, nil, logger)
+	adminHandlers := api.NewAdminHandlers(authService, mockRepo, nil, nil, nil, nil, logger)
+	server := setupServer(cfg, handlers, adminHandlers, authService, mockRepo, logger)
+
+	request := func(method, path, key string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, nil)
+		if key != "" {
+			req.Header.Set("X-API-Key", key)
+		}
+		w := httptest.NewRecorder()
+		server.Handler.ServeHTTP(w, req)
+		return w
+	}
+
+	// 未携带密钥或密钥无效时返回 401
+	w := request(http.MethodPost, "/giftcode?code=ABC", "")
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+	assert.Contains(t, w.Body.String(), "API_KEY_REQUIRED")
+	w = request(http.MethodGet, "/giftcode/ABC", "cdk_unknown")
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+	assert.Contains(t, w.Body.String(), "INVALID_API_KEY")
+
+	// 缺少对应权限时返回 403
+	w = request(http.MethodPost, "/giftcode?code=ABC", "cdk_reader")
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	assert.Contains(t, w.Body.String(), "INSUFFICIENT_SCOPE")
+	assert.Equal(t, http.StatusForbidden, request(http.MethodGet, "/giftcode/ABC", "cdk_adder").Code)
+
+	// 拥有权限的密钥可以访问
+	assert.Equal(t, http.StatusOK, request(http.MethodGet, "/giftcode/ABC", "cdk_reader").Code)
+
+	// /ip 不需要密钥
+	assert.Equal(t, http.StatusOK, request(http.MethodGet, "/ip", "").Code)
+}
+
+// TestAPIKeyFailureLimit tests that invalid API keys are throttled per IP before the key lookup
+// and that key usage is written at most once per interval
+func TestAPIKeyFailureLimit(t *testing.T) {
+	cfg := &config.Config{
+		Logging: config.LoggingConfig{
+			Level:  "error",
+			Format: "json",
+		},
+		Server: config.ServerConfig{
+			Host: "localhost",
+			Port: 8080,
+		},
+		Security: config.SecurityConfig{
+			APIKey: config.APIKeyConfig{Required: true},
+			RateLimit: config.RateLimitConfig{
+				Enabled:     true,
+				Rate:        10,
+				Burst:       20,
+				MaxKeys:     100,
+				IdleTimeout: time.Minute,
+				Groups: map[string]config.RateLimitRule{
+					config.RateLimitGroupAPIKey: {Rate: 0.01, Burst: 2},
+				},
+			},
+		},
+		Admin: config.AdminConfig{
+			TokenSecret:   "test-secret",
+			TokenDuration: 24 * time.Hour,
+		},
+	}
+
+	logger := logrus.New()
+	logger.SetLevel(logrus.ErrorLevel)
+
+	lookups := 0
+	var usageCounts []int64
+	mockRepo := &storage.MockRepository{
+		GetAPIKeyByHashFunc: func(ctx context.Context, keyHash string) (*storage.APIKey, error) {
+			lookups++
+			if keyHash == auth.HashAPIKey("cdk_reader") {
+				return &storage.APIKey{ID: 1, Name: "reader", Scopes: []string{storage.APIKeyScopeReadOnly}}, nil
+			}
+			return nil, storage.ErrAPIKeyNotFound
+		},
+		RecordAPIKeyUsageFunc: func(ctx context.Context, id int64, count int64, at time.Time, ip string) error {
+			usageCounts = append(usageCounts, count)
+			return nil
+		},
+	}
+
+	authService := auth.NewAuthService(mockRepo, cfg.Admin.TokenSecret, cfg.Admin.TokenDuration, 7*24*time.Hour, "cdk-get")
+	handlers := api.NewHandlers(nil, nil, mockRepo, nil, logger)
+	adminHandlers := api.NewAdminHandlers(authService, mockRepo, nil, nil, nil, nil, logger)
+	server := setupServer(cfg, handlers, adminHandlers, authService, mockRepo, logger)
+
+	request := func(key, remoteAddr string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/giftcode/ABC", nil)
+		req.Header.Set("X-API-Key", key)
+		req.RemoteAddr = remoteAddr
+		w := httptest.NewRecorder()
+		server.Handler.ServeHTTP(w, req)
+		return w
+	}
+
+	// 无效密钥用完 api_key 分组的突发额度后，该 IP 的请求在查询密钥前返回 429
+	for range 2 {
+		assert.Equal(t, http.StatusUnauthorized, request("cdk_guess", "192.0.2.1:1234").Code)
+	}
+	w := request("cdk_guess", "192.0.2.1:1234")
+	assert.Equal(t, http.StatusTooManyRequests, w.Code)
+	assert.Contains(t, w.Body.String(), "RATE_LIMIT_EXCEEDED")
+	assert.NotEmpty(t, w.Header().Get("Retry-After"))
+	assert.Equal(t, http.StatusTooManyRequests, request("cdk_reader", "192.0.2.1:1234").Code)
+	assert.Equal(t, 2, lookups)
+
+	// 其他 IP 不受影响，有效密钥的使用次数在内存中累计，间隔内只写入一次
+	assert.Equal(t, http.StatusOK, request("cdk_reader", "192.0.2.2:1234").Code)
+	assert.Equal(t, http.StatusOK, request("cdk_reader", "192.0.2.2:1234").Code)
+	assert.Equal(t, []int64{1}, usageCounts)
+}
@@ -0,0 +1,128 @@
+package main
+
+import (
+	"cdk-get/internal/api"
+	"cdk-get/internal/auth"
+	"cdk-get/internal/config"
+	"cdk-get/internal/errors"
+	"cdk-get/internal/storage"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestAPIV1 tests the versioned public API routes, error mapping and the generated OpenAPI document
+func TestAPIV1(t *testing.T) {
+	cfg := &config.Config{
+		Logging: config.LoggingConfig{
+			Level:  "error",
+			Format: "json",
+		},
+		Server: config.ServerConfig{
+			Host: "localhost",
+			Port: 8080,
+		},
+		Security: config.SecurityConfig{
+			APIKey: config.APIKeyConfig{Required: true},
+		},
+		Admin: config.AdminConfig{
+			TokenSecret:   "test-secret",
+			TokenDuration: 24 * time.Hour,
+		},
+	}
+
+	logger := logrus.New()
+	logger.SetLevel(logrus.ErrorLevel)
+
+	keys := map[string]*storage.APIKey{
+		auth.HashAPIKey("cdk_reader"): {ID: 1, Name: "reader", Scopes: []string{storage.APIKeyScopeReadOnly}},
+		auth.HashAPIKey("cdk_writer"): {ID: 2, Name: "writer", Scopes: []string{storage.APIKeyScopeEnqueueCode}},
+	}
+	tasks := map[string]*storage.Task{"EXISTING": {Code: "EXISTING"}}
+	mockRepo := &storage.MockRepository{
+		GetAPIKeyByHashFunc: func(ctx context.Context, keyHash string) (*storage.APIKey, error) {
+			if key, ok := keys[keyHash]; ok {
+				return key, nil
+			}
+			return nil, storage.ErrAPIKeyNotFound
+		},
+		GetTaskByCodeFunc: func(ctx context.Context, code string) (*storage.Task, error) {
+			if task, ok := tasks[code]; ok {
+				return task, nil
+			}
+			return nil, errors.NewNotFoundError("task", code)
+		},
+		CreateTaskFunc: func(ctx context.Context, code string) (bool, error) {
+			// 回收站中的兑换码已存在，不会插入
+			_, ok := tasks[code]
+			return !ok && code != "TRASHED", nil
+		},
+	}
+
+	authService := auth.NewAuthService(mockRepo, cfg.Admin.TokenSecret, cfg.Admin.TokenDuration, 7*24*time.Hour, "cdk-get")
+	handlers := api.NewHandlers(nil, nil, mockRepo, nil, logger)
+	adminHandlers := api.NewAdminHandlers(authService, mockRepo, nil, nil, nil, nil, logger)
+	server := setupServer(cfg, handlers, adminHandlers, authService, mockRepo, logger)
+
+	request := func(method, path, key, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		if key != "" {
+			req.Header.Set("X-API-Key", key)
+		}
+		w := httptest.NewRecorder()
+		server.Handler.ServeHTTP(w, req)
+		return w
+	}
+
+	// 已存在的兑换码返回 200，请求体缺少 code 时返回 400
+	w := request(http.MethodPost, "/api/v1/gift-codes", "cdk_writer", `{"code":"EXISTING"}`)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), `"code":"EXISTING"`)
+	w = request(http.MethodPost, "/api/v1/gift-codes", "cdk_writer", `{}`)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Contains(t, w.Body.String(), "VALIDATION_ERROR")
+
+	// 新兑换码创建后在回收站中（仓库中查不到）时返回 409
+	w = request(http.MethodPost, "/api/v1/gift-codes", "cdk_writer", `{"code":"TRASHED"}`)
+	assert.Equal(t, http.StatusConflict, w.Code)
+	assert.Contains(t, w.Body.String(), "ALREADY_EXISTS")
+
+	// AppError 的 NOT_FOUND 映射为 404，权限不足仍由 API 密钥中间件返回 403
+	w = request(http.MethodGet, "/api/v1/gift-codes/MISSING", "cdk_reader", "")
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	assert.Contains(t, w.Body.String(), "NOT_FOUND")
+	assert.Equal(t, http.StatusForbidden, request(http.MethodGet, "/api/v1/gift-codes/EXISTING", "cdk_writer", "").Code)
+
+	// 分页参数校验，游标和状态由仓库校验
+	w = request(http.MethodGet, "/api/v1/gift-codes?status=completed&limit=10", "cdk_reader", "")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), `"items":[]`)
+	assert.Equal(t, http.StatusBadRequest, request(http.MethodGet, "/api/v1/gift-codes?limit=0", "cdk_reader", "").Code)
+	assert.Equal(t, http.StatusBadRequest, request(http.MethodGet, "/api/v1/users?kid=abc", "cdk_reader", "").Code)
+
+	// 旧路由保留
+	assert.Equal(t, http.StatusOK, request(http.MethodPost, "/giftcode?code=EXISTING", "cdk_writer", "").Code)
+
+	// OpenAPI 文档无需密钥，包含路由表中的全部路径
+	w = request(http.MethodGet, "/api/v1/openapi.json", "", "")
+	require.Equal(t, http.StatusOK, w.Code)
+	var doc struct {
+		OpenAPI string                    `json:"openapi"`
+		Paths   map[string]map[string]any `json:"paths"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &doc))
+	assert.Equal(t, "3.0.3", doc.OpenAPI)
+	assert.Contains(t, doc.Paths, "/gift-codes/{code}")
+	assert.Contains(t, doc.Paths["/gift-codes"], "post")
+	assert.Contains(t, doc.Paths["/users"], "get")
+	assert.Contains(t, w.Body.String(), `"#/components/schemas/Task"`)
+}
@@ -0,0 +1,105 @@
+package main
+
+import (
+	"cdk-get/internal/api"
+	"cdk-get/internal/auth"
+	"cdk-get/internal/config"
+	"cdk-get/internal/storage"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+)
+
+// TestAuditLogMutatingRequests tests that successful mutating admin requests are written to the audit log
+func TestAuditLogMutatingRequests(t *testing.T) {
+	cfg := &config.Config{
+		Logging: config.LoggingConfig{
+			Level:  "error",
+			Format: "json",
+		},
+		Server: config.ServerConfig{
+			Host: "localhost",
+			Port: 8080,
+		},
+		Admin: config.AdminConfig{
+			TokenSecret:   "test-secret",
+			TokenDuration: 24 * time.Hour,
+		},
+	}
+
+	logger := logrus.New()
+	logger.SetLevel(logrus.ErrorLevel)
+
+	var entries []*storage.AuditEntry
+	mockRepo := &storage.MockRepository{
+		GetAdminFunc: func(ctx context.Context, username string) (*storage.Admin, error) {
+			return &storage.Admin{Username: username, Role: storage.AdminRoleOwner}, nil
+		},
+		GetAdminSessionFunc: func(ctx context.Context, id string) (*storage.AdminSession, error) {
+			return &storage.AdminSession{ID: id, Username: "alice", ExpiresAt: time.Now().Add(time.Hour)}, nil
+		},
+		GetTaskByCodeFunc: func(ctx context.Context, code string) (*storage.Task, error) {
+			return &storage.Task{Code: code}, nil
+		},
+		DeleteTaskFunc: func(ctx context.Context, code string) error {
+			if code == "MISSING" {
+				return storage.ErrTaskNotFound
+			}
+			return nil
+		},
+		CreateAuditEntryFunc: func(ctx context.Context, entry *storage.AuditEntry) error {
+			entries = append(entries, entry)
+			return nil
+		},
+	}
+
+	authService := auth.NewAuthService(mockRepo, cfg.Admin.TokenSecret, cfg.Admin.TokenDuration, 7*24*time.Hour, "cdk-get")
+	handlers := api.NewHandlers(nil, nil, nil, nil, logger)
+	adminHandlers := api.NewAdminHandlers(authService, mockRepo, nil, nil, nil, nil, logger)
+	server := setupServer(cfg, handlers, adminHandlers, authService, mockRepo, logger)
+
+	token, _, err := authService.GenerateToken("alice", storage.AdminRoleOwner, "alice-session")
+	assert.NoError(t, err)
+
+	// 未配置可信代理时忽略客户端伪造的 X-Forwarded-For
+	request := func(method, path string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		req.Header.Set("X-Request-ID", "req-42")
+		req.Header.Set("X-Forwarded-For", "203.0.113.9")
+		req.RemoteAddr = "10.0.0.7:12345"
+		w := httptest.NewRecorder()
+		server.Handler.ServeHTTP(w, req)
+		return w
+	}
+
+	// 读取请求和失败的请求不记录
+	assert.Equal(t, http.StatusOK, request(http.MethodGet, "/api/admin/tasks").Code)
+	assert.Equal(t, http.StatusNotFound, request(http.MethodDelete, "/api/admin/tasks/MISSING").Code)
+	assert.Empty(t, entries)
+
+	assert.Equal(t, http.StatusOK, request(http.MethodDelete, "/api/admin/tasks/VIP888").Code)
+	if assert.Len(t, entries, 1) {
+		entry := entries[0]
+		assert.Equal(t, "alice", entry.Actor)
+		assert.Equal(t, "task.delete", entry.Action)
+		assert.Equal(t, "VIP888", entry.Target)
+		assert.Equal(t, "10.0.0.7", entry.IP)
+		assert.Equal(t, "req-42", entry.RequestID)
+		assert.Contains(t, string(entry.Before), `"code":"VIP888"`)
+		assert.Empty(t, entry.After)
+	}
+
+	// 请求来自可信代理时使用 X-Forwarded-For 中的客户端地址
+	cfg.Server.TrustedProxies = []string{"10.0.0.0/8"}
+	server = setupServer(cfg, handlers, adminHandlers, authService, mockRepo, logger)
+	assert.Equal(t, http.StatusOK, request(http.MethodDelete, "/api/admin/tasks/VIP999").Code)
+	if assert.Len(t, entries, 2) {
+		assert.Equal(t, "203.0.113.9", entries[1].IP)
+	}
+}
@@ -10,6 +10,7 @@ import (
 	"net/http"
 	"net/http/httptest"
 	"net/url"
+	"strings"
 	"testing"
 	"time"
 
@@ -52,25 +53,27 @@ func TestDeleteTaskEndpoint(t *testing.T) {
 	logger.SetLevel(logrus.ErrorLevel)
 
 	// Create mock repository
-	mockRepo := &storage.MockRepository{}
+	mockRepo := &storage.MockRepository{
+		GetAdminFunc: func(ctx context.Context, username string) (*storage.Admin, error) {
+			return &storage.Admin{Username: username, Role: storage.AdminRoleOwner}, nil
+		},
+		GetAdminSessionFunc: func(ctx context.Context, id string) (*storage.AdminSession, error) {
+			return &storage.AdminSession{ID: id, Username: "admin", ExpiresAt: time.Now().Add(time.Hour)}, nil
+		},
+	}
 
 	// Create auth service
-	authService := auth.NewAuthService(
-		cfg.Admin.Username,
-		cfg.Admin.PasswordHash,
-		cfg.Admin.TokenSecret,
-		cfg.Admin.TokenDuration,
-	)
+	authService := auth.NewAuthService(mockRepo, cfg.Admin.TokenSecret, cfg.Admin.TokenDuration, 7*24*time.Hour, "cdk-get")
 
 	// Create handlers
-	handlers := api.NewHandlers(nil, nil, logger)
-	adminHandlers := api.NewAdminHandlers(authService, mockRepo, logger)
+	handlers := api.NewHandlers(nil, nil, nil, nil, logger)
+	adminHandlers := api.NewAdminHandlers(authService, mockRepo, nil, nil, nil, nil, logger)
 
 	// Setup server
-	server := setupServer(cfg, handlers, adminHandlers, authService, logger)
+	server := setupServer(cfg, handlers, adminHandlers, authService, mockRepo, logger)
 
 	// Generate a valid token for authentication
-	token, _, err := authService.GenerateToken("admin")
+	token, _, err := authService.GenerateToken("admin", storage.AdminRoleOwner, "test-session")
 	assert.NoError(t, err)
 
 	tests := []struct {
@@ -202,19 +205,14 @@ func TestDeleteTaskUnauthorized(t *testing.T) {
 	mockRepo := &storage.MockRepository{}
 
 	// Create auth service
-	authService := auth.NewAuthService(
-		cfg.Admin.Username,
-		cfg.Admin.PasswordHash,
-		cfg.Admin.TokenSecret,
-		cfg.Admin.TokenDuration,
-	)
+	authService := auth.NewAuthService(mockRepo, cfg.Admin.TokenSecret, cfg.Admin.TokenDuration, 7*24*time.Hour, "cdk-get")
 
 	// Create handlers
-	handlers := api.NewHandlers(nil, nil, logger)
-	adminHandlers := api.NewAdminHandlers(authService, mockRepo, logger)
+	handlers := api.NewHandlers(nil, nil, nil, nil, logger)
+	adminHandlers := api.NewAdminHandlers(authService, mockRepo, nil, nil, nil, nil, logger)
 
 	// Setup server
-	server := setupServer(cfg, handlers, adminHandlers, authService, logger)
+	server := setupServer(cfg, handlers, adminHandlers, authService, mockRepo, logger)
 
 	tests := []struct {
 		name           string
@@ -255,3 +253,100 @@ func TestDeleteTaskUnauthorized(t *testing.T) {
 		})
 	}
 }
+
+// TestDeleteTaskRoles tests that viewers can list tasks but not delete them
+func TestDeleteTaskRoles(t *testing.T) {
+	cfg := &config.Config{
+		Logging: config.LoggingConfig{
+			Level:  "error",
+			Format: "json",
+		},
+		Server: config.ServerConfig{
+			Host: "localhost",
+			Port: 8080,
+		},
+		Admin: config.AdminConfig{
+			TokenSecret:   "test-secret",
+			TokenDuration: 24 * time.Hour,
+		},
+	}
+
+	logger := logrus.New()
+	logger.SetLevel(logrus.ErrorLevel)
+
+	// 角色以仓库中账号的当前角色为准
+	roles := map[string]string{
+		"owner":      storage.AdminRoleOwner,
+		"operator":   storage.AdminRoleOperator,
+		"viewer":     storage.AdminRoleViewer,
+		"loggedout":  storage.AdminRoleOwner,
+		"unenrolled": storage.AdminRoleOwner,
+	}
+	deleted := 0
+	mockRepo := &storage.MockRepository{
+		GetAdminFunc: func(ctx context.Context, username string) (*storage.Admin, error) {
+			role, ok := roles[username]
+			if !ok {
+				return nil, storage.ErrAdminNotFound
+			}
+			// unenrolled 被要求启用两步验证但尚未启用
+			return &storage.Admin{Username: username, Role: role, TOTPRequired: username == "unenrolled"}, nil
+		},
+		// 会话ID为 "<用户名>-session"，loggedout 的会话已撤销
+		GetAdminSessionFunc: func(ctx context.Context, id string) (*storage.AdminSession, error) {
+			username := strings.TrimSuffix(id, "-session")
+			session := &storage.AdminSession{ID: id, Username: username, ExpiresAt: time.Now().Add(time.Hour)}
+			if username == "loggedout" {
+				revokedAt := time.Now()
+				session.RevokedAt = &revokedAt
+			}
+			return session, nil
+		},
+		DeleteTaskFunc: func(ctx context.Context, code string) error {
+			deleted++
+			return nil
+		},
+	}
+
+	authService := auth.NewAuthService(mockRepo, cfg.Admin.TokenSecret, cfg.Admin.TokenDuration, 7*24*time.Hour, "cdk-get")
+	handlers := api.NewHandlers(nil, nil, nil, nil, logger)
+	adminHandlers := api.NewAdminHandlers(authService, mockRepo, nil, nil, nil, nil, logger)
+	server := setupServer(cfg, handlers, adminHandlers, authService, mockRepo, logger)
+
+	tests := []struct {
+		name           string
+		username       string
+		method         string
+		path           string
+		expectedStatus int
+	}{
+		{"Viewer can list tasks", "viewer", http.MethodGet, "/api/admin/tasks", http.StatusOK},
+		{"Viewer cannot delete tasks", "viewer", http.MethodDelete, "/api/admin/tasks/TEST123", http.StatusForbidden},
+		{"Operator can delete tasks", "operator", http.MethodDelete, "/api/admin/tasks/TEST123", http.StatusOK},
+		{"Operator cannot purge tasks", "operator", http.MethodDelete, "/api/admin/trash/tasks/TEST123", http.StatusForbidden},
+		{"Operator cannot manage admins", "operator", http.MethodGet, "/api/admin/admins", http.StatusForbidden},
+		{"Owner can manage admins", "owner", http.MethodGet, "/api/admin/admins", http.StatusOK},
+		{"Deleted admin is rejected", "removed", http.MethodGet, "/api/admin/tasks", http.StatusUnauthorized},
+		{"Revoked session is rejected", "loggedout", http.MethodGet, "/api/admin/tasks", http.StatusUnauthorized},
+		{"Unenrolled admin cannot list tasks", "unenrolled", http.MethodGet, "/api/admin/tasks", http.StatusForbidden},
+		{"Unenrolled admin can check two-factor status", "unenrolled", http.MethodGet, "/api/admin/me/totp", http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// 令牌中的角色不影响授权
+			token, _, err := authService.GenerateToken(tt.username, storage.AdminRoleOwner, tt.username+"-session")
+			assert.NoError(t, err)
+
+			req := httptest.NewRequest(tt.method, tt.path, nil)
+			req.Header.Set("Authorization", "Bearer "+token)
+			w := httptest.NewRecorder()
+
+			server.Handler.ServeHTTP(w, req)
+
+			assert.Equal(t, tt.expectedStatus, w.Code, "Status code mismatch")
+		})
+	}
+
+	assert.Equal(t, 1, deleted, "only the operator should have deleted the task")
+}
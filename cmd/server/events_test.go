@@ -0,0 +1,251 @@
+package main
+
+import (
+	"bufio"
+	"cdk-get/internal/api"
+	"cdk-get/internal/auth"
+	"cdk-get/internal/config"
+	"cdk-get/internal/events"
+	"cdk-get/internal/storage"
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// readSSE reads one server-sent event and returns its name and data
+func readSSE(t *testing.T, r *bufio.Reader) (string, string) {
+	t.Helper()
+	var name, data string
+	for {
+		line, err := r.ReadString('\n')
+		require.NoError(t, err)
+		line = strings.TrimRight(line, "\n")
+		switch {
+		case line == "":
+			if name != "" || data != "" {
+				return name, data
+			}
+		case strings.HasPrefix(line, "event:"):
+			name = line[len("event:"):]
+		case strings.HasPrefix(line, "data:"):
+			data = line[len("data:"):]
+		}
+	}
+}
+
+// TestEventStreamEndpoint tests GET /api/admin/events streaming repository changes to viewers
+func TestEventStreamEndpoint(t *testing.T) {
+	cfg := &config.Config{
+		Logging: config.LoggingConfig{
+			Level:  "error",
+			Format: "json",
+		},
+		Server: config.ServerConfig{
+			Host: "localhost",
+			Port: 8080,
+		},
+		Admin: config.AdminConfig{
+			TokenSecret:   "test-secret",
+			TokenDuration: 24 * time.Hour,
+		},
+	}
+
+	logger := logrus.New()
+	logger.SetLevel(logrus.ErrorLevel)
+
+	mockRepo := &storage.MockRepository{
+		GetAdminFunc: func(ctx context.Context, username string) (*storage.Admin, error) {
+			return &storage.Admin{Username: username, Role: storage.AdminRoleViewer}, nil
+		},
+		GetAdminSessionFunc: func(ctx context.Context, id string) (*storage.AdminSession, error) {
+			return &storage.AdminSession{ID: id, Username: "bob", ExpiresAt: time.Now().Add(time.Hour)}, nil
+		},
+	}
+
+	bus := events.NewBus()
+	repository := storage.NewEventRepository(mockRepo, bus)
+	authService := auth.NewAuthService(mockRepo, cfg.Admin.TokenSecret, cfg.Admin.TokenDuration, 7*24*time.Hour, "cdk-get")
+	handlers := api.NewHandlers(nil, nil, nil, nil, logger)
+	server := httptest.NewServer(setupServer(cfg, handlers, api.NewAdminHandlers(authService, repository, nil, nil, nil, bus, logger), authService, mockRepo, logger).Handler)
+	defer server.Close()
+
+	open := func(token, query string) *http.Response {
+		req, err := http.NewRequest(http.MethodGet, server.URL+"/api/admin/events"+query, nil)
+		require.NoError(t, err)
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		return resp
+	}
+
+	resp := open("", "")
+	resp.Body.Close()
+	assert.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+
+	viewerToken, _, err := authService.GenerateToken("bob", storage.AdminRoleViewer, "bob-session")
+	require.NoError(t, err)
+
+	// 只订阅任务事件，用户变更不推送
+	resp = open(viewerToken, "?types=task.")
+	defer resp.Body.Close()
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Contains(t, resp.Header.Get("Content-Type"), "text/event-stream")
+
+	reader := bufio.NewReader(resp.Body)
+	name, data := readSSE(t, reader)
+	assert.Equal(t, "ready", name)
+	assert.JSONEq(t, `{"types":["task."]}`, data)
+
+	require.NoError(t, repository.DeleteUser(context.Background(), "1"))
+	_, err = repository.CreateTask(context.Background(), "VIP")
+	require.NoError(t, err)
+	name, data = readSSE(t, reader)
+	assert.Equal(t, events.TaskChanged, name)
+	assert.Contains(t, data, `"type":"task.changed"`)
+	assert.Contains(t, data, `"data":{"code":"VIP","change":"created"}`)
+
+	// 关闭事件总线时结束事件流
+	bus.Close()
+	_, err = io.ReadAll(reader)
+	assert.NoError(t, err)
+
+	// 未配置事件总线时不可用
+	noBusServer := setupServer(cfg, handlers, api.NewAdminHandlers(authService, mockRepo, nil, nil, nil, nil, logger), authService, mockRepo, logger)
+	req := httptest.NewRequest(http.MethodGet, "/api/admin/events", nil)
+	req.Header.Set("Authorization", "Bearer "+viewerToken)
+	w := httptest.NewRecorder()
+	noBusServer.Handler.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusServiceUnavailable, w.Code)
+}
+
+// TestEventStreamEndsAtTokenExpiry tests that the event stream is closed once the access token expires
+func TestEventStreamEndsAtTokenExpiry(t *testing.T) {
+	cfg := &config.Config{
+		Logging: config.LoggingConfig{
+			Level:  "error",
+			Format: "json",
+		},
+		Admin: config.AdminConfig{
+			TokenSecret:   "test-secret",
+			TokenDuration: time.Second,
+		},
+	}
+
+	logger := logrus.New()
+	logger.SetLevel(logrus.ErrorLevel)
+
+	mockRepo := &storage.MockRepository{
+		GetAdminFunc: func(ctx context.Context, username string) (*storage.Admin, error) {
+			return &storage.Admin{Username: username, Role: storage.AdminRoleViewer}, nil
+		},
+		GetAdminSessionFunc: func(ctx context.Context, id string) (*storage.AdminSession, error) {
+			return &storage.AdminSession{ID: id, Username: "bob", ExpiresAt: time.Now().Add(time.Hour)}, nil
+		},
+	}
+
+	authService := auth.NewAuthService(mockRepo, cfg.Admin.TokenSecret, cfg.Admin.TokenDuration, time.Hour, "cdk-get")
+	server := httptest.NewServer(setupServer(cfg, api.NewHandlers(nil, nil, nil, nil, logger), api.NewAdminHandlers(authService, mockRepo, nil, nil, nil, events.NewBus(), logger), authService, mockRepo, logger).Handler)
+	defer server.Close()
+
+	token, _, err := authService.GenerateToken("bob", storage.AdminRoleViewer, "bob-session")
+	require.NoError(t, err)
+
+	req, err := http.NewRequest(http.MethodGet, server.URL+"/api/admin/events", nil)
+	require.NoError(t, err)
+	req.Header.Set("Authorization", "Bearer "+token)
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+
+	reader := bufio.NewReader(resp.Body)
+	name, _ := readSSE(t, reader)
+	assert.Equal(t, "ready", name)
+	name, _ = readSSE(t, reader)
+	assert.Equal(t, "expired", name)
+}
+
+// TestUpdateUserPublishesAfterCommit tests that the transactional admin user update publishes user.changed once committed
+func TestUpdateUserPublishesAfterCommit(t *testing.T) {
+	cfg := &config.Config{
+		Logging: config.LoggingConfig{
+			Level:  "error",
+			Format: "json",
+		},
+		Admin: config.AdminConfig{
+			TokenSecret:   "test-secret",
+			TokenDuration: 24 * time.Hour,
+		},
+	}
+
+	logger := logrus.New()
+	logger.SetLevel(logrus.ErrorLevel)
+
+	sqliteConfig := storage.DefaultSqliteConfig()
+	sqliteConfig.Path = filepath.Join(t.TempDir(), "events.db")
+	sqliteRepo, err := storage.NewSqliteRepository(sqliteConfig, logger)
+	require.NoError(t, err)
+	defer sqliteRepo.Close()
+	require.NoError(t, sqliteRepo.SaveUser(context.Background(), &storage.User{FID: "1", Nickname: "alice"}))
+
+	mockRepo := &storage.MockRepository{
+		GetAdminFunc: func(ctx context.Context, username string) (*storage.Admin, error) {
+			return &storage.Admin{Username: username, Role: storage.AdminRoleOwner}, nil
+		},
+		GetAdminSessionFunc: func(ctx context.Context, id string) (*storage.AdminSession, error) {
+			return &storage.AdminSession{ID: id, Username: "admin", ExpiresAt: time.Now().Add(time.Hour)}, nil
+		},
+	}
+
+	bus := events.NewBus()
+	defer bus.Close()
+	received, _, cancel := bus.Subscribe(events.UserChanged)
+	defer cancel()
+
+	repository := storage.NewEventRepository(sqliteRepo, bus)
+	authService := auth.NewAuthService(mockRepo, cfg.Admin.TokenSecret, cfg.Admin.TokenDuration, 7*24*time.Hour, "cdk-get")
+	server := setupServer(cfg, api.NewHandlers(nil, nil, nil, nil, logger), api.NewAdminHandlers(authService, repository, nil, nil, nil, bus, logger), authService, mockRepo, logger)
+
+	token, _, err := authService.GenerateToken("admin", storage.AdminRoleOwner, "admin-session")
+	require.NoError(t, err)
+
+	update := func(body string) int {
+		req := httptest.NewRequest(http.MethodPut, "/api/admin/users/1", strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("Authorization", "Bearer "+token)
+		w := httptest.NewRecorder()
+		server.Handler.ServeHTTP(w, req)
+		return w.Code
+	}
+
+	// 事务回滚时不发布
+	assert.Equal(t, http.StatusBadRequest, update(`{"disabled":true,"group_ids":[999]}`))
+	select {
+	case event := <-received:
+		t.Fatalf("expected no event after rollback, got %v", event.Data)
+	default:
+	}
+
+	require.Equal(t, http.StatusOK, update(`{"nickname":"bob","disabled":true}`))
+	var changes []string
+	for len(changes) < 2 {
+		select {
+		case event := <-received:
+			changes = append(changes, event.Data.(events.UserChange).Change)
+		default:
+			t.Fatalf("expected 2 user.changed events, got %v", changes)
+		}
+	}
+	assert.Equal(t, []string{events.ChangeUpdated, events.ChangeDisabled}, changes)
+}
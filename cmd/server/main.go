@@ -3,9 +3,12 @@ package main
 import (
 	"cdk-get/internal/api"
 	"cdk-get/internal/auth"
+	"cdk-get/internal/captcha"
 	"cdk-get/internal/config"
+	"cdk-get/internal/events"
 	"cdk-get/internal/job"
 	"cdk-get/internal/logging"
+	"cdk-get/internal/metrics"
 	"cdk-get/internal/notification"
 	"cdk-get/internal/service"
 	"cdk-get/internal/storage"
@@ -50,6 +53,9 @@ func main() {
 	// 添加敏感数据脱敏钩子
 	logger.AddHook(&logging.SensitiveHook{})
 
+	// 检查示例/默认密钥，非开发模式下拒绝启动
+	checkSecrets(cfg, logger)
+
 	// 初始化数据库 - 使用新的Repository接口
 	repoConfig := storage.SqliteConfig{
 		Path:            cfg.Database.Path,
@@ -65,48 +71,76 @@ func main() {
 		logger.Fatalf("Failed to initialize repository: %v", err)
 	}
 
+	// 初始化事件总线，任务、用户写入和通知投递通过 eventRepository 等发布事件，推送到管理后台
+	bus := events.NewBus()
+	eventRepository := storage.NewEventRepository(repository, bus)
+
+	// 采集指标时统计未完成的兑换码任务数
+	metrics.SetPendingTasksSource(func(ctx context.Context) (int, error) {
+		tasks, err := repository.ListPendingTasks(ctx)
+		return len(tasks), err
+	})
+
+	// 首次启动时以 admin.* 配置创建第一个 owner
+	bootstrapAdmin(cfg, repository, logger)
+
 	// 初始化认证服务
-	authService := auth.NewAuthService(
-		cfg.Admin.Username,
-		cfg.Admin.PasswordHash,
-		cfg.Admin.TokenSecret,
-		cfg.Admin.TokenDuration,
-	)
+	authService := auth.NewAuthService(repository, cfg.Admin.TokenSecret, cfg.Admin.TokenDuration, cfg.Admin.RefreshTokenDuration, cfg.Admin.TOTPIssuer)
 
 	// 初始化通知服务
 	var notificationService *service.NotificationService
-	if cfg.Notification.WxPusher.AppToken != "" && cfg.Notification.WxPusher.UID != "" {
-		// 创建WxPusher通知器
-		wxpusherNotifier := notification.NewWxPusherNotifier(
-			cfg.Notification.WxPusher.AppToken,
-			cfg.Notification.WxPusher.UID,
-			logger,
-		)
-
-		// 创建通知服务
-		notificationService = service.NewNotificationService(
-			wxpusherNotifier,
-			repository,
-			logger,
-		)
-
-		logger.Info("Notification service initialized with WxPusher")
+	notifiers, err := notification.NewNotifiersFromConfig(cfg.Notification, logger)
+	if err != nil {
+		logger.Fatalf("Failed to initialize notification channels: %v", err)
+	}
+	channelNames := make([]string, 0, len(notifiers))
+	for _, notifier := range notifiers {
+		channelNames = append(channelNames, notifier.GetChannel())
+	}
+	router, err := notification.NewRouter(cfg.Notification, channelNames)
+	if err != nil {
+		logger.Fatalf("Failed to initialize notification routes: %v", err)
+	}
+	if len(notifiers) > 0 {
+		notificationService = service.NewNotificationService(notifiers, router, cfg.Notification, repository, bus, logger)
+		notificationService.Start()
+		logger.WithField("channels", notificationService.Channels()).Info("Notification service initialized")
+	} else {
+		logger.Warn("Notification service not initialized: no notification channel configured")
+	}
+
+	// 初始化礼品码服务，用于添加用户时校验fid、定期刷新用户资料和管理后台立即兑换
+	// 查询玩家资料不依赖验证码，验证码池初始化失败时仍可使用，但无法立即兑换
+	captchaPool, err := captcha.NewCaptchaPool(cfg.Captcha.Providers)
+	if err != nil {
+		logger.Warnf("Captcha pool not initialized: %v", err)
 	} else {
-		logger.Warn("Notification service not initialized: WxPusher configuration missing")
+		// 记录每次验证码识别，用于统计识别费用和识别耗时指标
+		captchaPool.Observe(func(provider string, elapsed time.Duration, err error) {
+			metrics.ObserveCaptcha(provider, elapsed, err)
+			call := &storage.CaptchaCall{Provider: provider, Success: err == nil, Cost: cfg.Captcha.UnitPrice(provider)}
+			if err := repository.RecordCaptchaCall(context.Background(), call); err != nil {
+				logger.WithError(err).Warn("failed to record captcha call")
+			}
+		})
 	}
+	giftService := service.NewGiftService(eventRepository, repository, captchaPool, nil, cfg.Job.GameAPIURL, cfg.Job.WorkerPoolSize, logger)
 
-	// 初始化API处理器 (暂时不使用GiftService，因为Repository接口还未完全实现)
-	handlers := api.NewHandlers(nil, repository, logger)
+	// 初始化API处理器
+	handlers := api.NewHandlers(giftService, repository, eventRepository, notificationService, logger)
 
 	// 初始化管理后台处理器
-	adminHandlers := api.NewAdminHandlers(authService, repository, logger)
+	loginGuard := auth.NewLoginGuard(repository, cfg.Security.LoginProtection)
+	adminHandlers := api.NewAdminHandlers(authService, eventRepository, giftService, notificationService, loginGuard, bus, logger)
 
 	// 初始化任务调度器（保持向后兼容）
-	svcCtx := svc.NewServiceContext(repository, repository, notificationService)
+	svcCtx := svc.NewServiceContext(cfg, repository, eventRepository, notificationService, giftService, bus)
 	_ = job.InitTask(svcCtx)
 
 	// 创建服务器
-	server := setupServer(cfg, handlers, adminHandlers, authService, logger)
+	server := setupServer(cfg, handlers, adminHandlers, authService, repository, logger)
+	// 关闭服务器时结束管理后台的事件流，否则长连接会阻塞关闭
+	server.RegisterOnShutdown(bus.Close)
 
 	// 启动服务器
 	go func() {
@@ -117,11 +151,49 @@ func main() {
 	}()
 
 	// 优雅关闭
-	gracefulShutdown(server, repository, logger)
+	gracefulShutdown(server, repository, notificationService, logger)
+}
+
+// checkSecrets 扫描配置中的示例、默认或占位密钥
+// 开发模式下仅记录警告，其余模式下拒绝启动
+func checkSecrets(cfg *config.Config, logger *logrus.Logger) {
+	findings := cfg.ScanSecrets()
+	if len(findings) == 0 {
+		return
+	}
+
+	for _, finding := range findings {
+		logger.WithFields(logrus.Fields{
+			"field":  finding.Field,
+			"reason": finding.Reason,
+		}).Warn("insecure secret in configuration")
+	}
+
+	if cfg.Server.IsDev() {
+		logger.Warn("Starting with insecure secrets because server mode is dev")
+		return
+	}
+	logger.Fatalf("Refusing to start: %d insecure secret(s) in configuration, replace them or set server.mode to dev for local development", len(findings))
+}
+
+// bootstrapAdmin 管理员账号表为空时，以 admin.username / admin.password_hash 创建 owner
+// 已有账号时配置中的用户名和密码哈希不再生效
+func bootstrapAdmin(cfg *config.Config, repository storage.Repository, logger *logrus.Logger) {
+	created, err := repository.BootstrapAdmin(context.Background(), &storage.Admin{
+		Username:     cfg.Admin.Username,
+		PasswordHash: cfg.Admin.PasswordHash,
+		Role:         storage.AdminRoleOwner,
+	})
+	if err != nil {
+		logger.Fatalf("Failed to bootstrap admin account: %v", err)
+	}
+	if created {
+		logger.WithField("username", cfg.Admin.Username).Info("Created owner account from admin config")
+	}
 }
 
 // setupServer 设置服务器和路由
-func setupServer(cfg *config.Config, handlers *api.Handlers, adminHandlers *api.AdminHandlers, authService auth.AuthService, logger *logrus.Logger) *http.Server {
+func setupServer(cfg *config.Config, handlers *api.Handlers, adminHandlers *api.AdminHandlers, authService auth.AuthService, repository storage.Repository, logger *logrus.Logger) *http.Server {
 	// 设置Gin模式
 	if cfg.Logging.Level == "debug" {
 		gin.SetMode(gin.DebugMode)
@@ -131,6 +203,11 @@ func setupServer(cfg *config.Config, handlers *api.Handlers, adminHandlers *api.
 
 	engine := gin.New()
 
+	// 仅信任配置的反向代理发来的 X-Forwarded-For，未配置时客户端 IP 始终为连接的对端地址
+	if err := engine.SetTrustedProxies(cfg.Server.TrustedProxies); err != nil {
+		logger.Fatalf("Invalid trusted proxies: %v", err)
+	}
+
 	// 添加中间件
 	engine.Use(api.RequestIDMiddleware())
 	engine.Use(api.RecoveryMiddleware(logger))
@@ -150,74 +227,199 @@ func setupServer(cfg *config.Config, handlers *api.Handlers, adminHandlers *api.
 		engine.Use(cors.New(corsConfig))
 	}
 
-	// 添加限流中间件（如果启用）
-	if cfg.Security.RateLimit.Enabled {
-		engine.Use(api.RateLimitMiddleware(cfg.Security.RateLimit.Rate, cfg.Security.RateLimit.Burst))
+	// 限流中间件，每个路由分组独立计数（未启用时不限流）
+	rateLimit := func(group string) gin.HandlerFunc {
+		return api.RateLimitMiddleware(api.NewKeyedLimiter(cfg.Security.RateLimit, group), logger)
 	}
+	staticRateLimit := rateLimit(config.RateLimitGroupStatic)
 
 	// 注册管理后台API路由（必须在catch-all路由之前）
 	adminAPI := engine.Group("/api/admin")
 	{
-		// 公开路由 - 登录
-		adminAPI.POST("/login", adminHandlers.Login)
+		// 公开路由 - 登录和刷新令牌，按客户端 IP 限流
+		loginRateLimit := rateLimit(config.RateLimitGroupLogin)
+		adminAPI.POST("/login", loginRateLimit, adminHandlers.Login)
+		adminAPI.POST("/refresh", loginRateLimit, adminHandlers.Refresh)
 
 		// 创建认证服务适配器用于中间件
 		authAdapter := api.NewAuthServiceAdapter(authService)
 
-		// 受保护的路由 - 需要认证
+		// 受保护的路由 - 需要认证，修改数据的请求写入审计日志
+		// 按角色分组：viewer 只读，operator 可修改数据，owner 可管理管理员账号和彻底删除
 		protected := adminAPI.Group("")
-		protected.Use(api.AuthMiddleware(authAdapter, logger))
+		protected.Use(api.AuthMiddleware(authAdapter, logger), api.AuditMiddleware(repository, logger), rateLimit(config.RateLimitGroupAdmin))
+		{
+			// 当前账号和会话
+			protected.POST("/logout", adminHandlers.Logout)
+			protected.GET("/me", adminHandlers.GetCurrentAdmin)
+			protected.PUT("/me/password", adminHandlers.ChangePassword)
+			protected.GET("/me/sessions", adminHandlers.ListSessions)
+			protected.DELETE("/me/sessions", adminHandlers.RevokeOtherSessions)
+			protected.DELETE("/me/sessions/:id", adminHandlers.RevokeSession)
+
+			// 两步验证
+			protected.GET("/me/totp", adminHandlers.GetTOTPStatus)
+			protected.POST("/me/totp/setup", adminHandlers.BeginTOTPSetup)
+			protected.POST("/me/totp/enable", adminHandlers.EnableTOTP)
+			protected.POST("/me/totp/recovery-codes", adminHandlers.RegenerateRecoveryCodes)
+			protected.POST("/me/totp/disable", adminHandlers.DisableTOTP)
+		}
+
+		// 被要求启用两步验证的账号启用前只能访问上面的账号接口
+		enrolled := protected.Group("")
+		enrolled.Use(api.RequireTOTPEnrollment(logger))
+
+		viewer := enrolled.Group("")
+		viewer.Use(api.RequireRole(storage.AdminRoleViewer, logger))
+		{
+			viewer.GET("/users", adminHandlers.ListUsers)
+			viewer.GET("/users/:fid", adminHandlers.GetUser)
+			viewer.GET("/users/:fid/codes", adminHandlers.GetUserGiftCodes)
+			viewer.GET("/groups", adminHandlers.ListUserGroups)
+			viewer.GET("/tasks", adminHandlers.ListTasks)
+			viewer.GET("/tasks/completed", adminHandlers.ListCompletedTasks)
+			viewer.GET("/notifications", adminHandlers.ListNotifications)
+			viewer.POST("/notifications/preview", adminHandlers.PreviewNotification)
+			viewer.GET("/trash", adminHandlers.ListTrash)
+			viewer.GET("/events", adminHandlers.StreamEvents)
+			viewer.GET("/stats/redemptions", adminHandlers.GetRedemptionStats)
+			viewer.GET("/stats/codes", adminHandlers.GetCodeStats)
+			viewer.GET("/stats/completion", adminHandlers.GetCompletionStats)
+			viewer.GET("/stats/failures", adminHandlers.GetFailureStats)
+			viewer.GET("/stats/coverage", adminHandlers.GetCoverageStats)
+			viewer.GET("/stats/captcha", adminHandlers.GetCaptchaStats)
+			viewer.GET("/maintenance/retention", adminHandlers.GetRetentionStatus)
+		}
+
+		operator := enrolled.Group("")
+		operator.Use(api.RequireRole(storage.AdminRoleOperator, logger))
 		{
 			// 用户管理
-			protected.GET("/users", adminHandlers.ListUsers)
-			protected.POST("/users", adminHandlers.AddUser)
-			protected.GET("/users/:fid/codes", adminHandlers.GetUserGiftCodes)
+			operator.POST("/users", adminHandlers.AddUser)
+			operator.PUT("/users/:fid", adminHandlers.UpdateUser)
+			operator.DELETE("/users/:fid", adminHandlers.DeleteUser)
+			operator.POST("/users/:fid/restore", adminHandlers.RestoreUser)
+
+			// 分组管理
+			operator.POST("/groups", adminHandlers.CreateUserGroup)
+			operator.PUT("/groups/:id", adminHandlers.UpdateUserGroup)
+			operator.DELETE("/groups/:id", adminHandlers.DeleteUserGroup)
 
 			// 任务管理
-			protected.GET("/tasks", adminHandlers.ListTasks)
-			protected.POST("/tasks", adminHandlers.AddGiftCode)
-			protected.GET("/tasks/completed", adminHandlers.ListCompletedTasks)
-			protected.DELETE("/tasks/:code", adminHandlers.DeleteTask)
+			operator.POST("/tasks", adminHandlers.AddGiftCode)
+			operator.POST("/redeem", adminHandlers.RedeemNow)
+			operator.DELETE("/tasks/:code", adminHandlers.DeleteTask)
+			operator.POST("/tasks/:code/restore", adminHandlers.RestoreTask)
 
 			// 通知管理
-			protected.GET("/notifications", adminHandlers.ListNotifications)
+			operator.POST("/notifications/:id/resend", adminHandlers.ResendNotification)
+		}
+
+		owner := enrolled.Group("")
+		owner.Use(api.RequireRole(storage.AdminRoleOwner, logger))
+		{
+			// 回收站彻底删除
+			owner.DELETE("/trash/tasks/:code", adminHandlers.PurgeTask)
+			owner.DELETE("/trash/users/:fid", adminHandlers.PurgeUser)
+
+			// 管理员账号
+			owner.GET("/admins", adminHandlers.ListAdmins)
+			owner.POST("/admins", adminHandlers.CreateAdmin)
+			owner.PUT("/admins/:username", adminHandlers.UpdateAdmin)
+			owner.DELETE("/admins/:username", adminHandlers.DeleteAdmin)
+			owner.DELETE("/admins/:username/sessions", adminHandlers.RevokeAdminSessions)
+			owner.DELETE("/admins/:username/totp", adminHandlers.ResetAdminTOTP)
+
+			// 登录防爆破
+			owner.GET("/security/lockouts", adminHandlers.ListLoginLockouts)
+			owner.DELETE("/security/lockouts/:kind/:identity", adminHandlers.UnlockLogin)
+			owner.GET("/security/login-failures", adminHandlers.ListLoginFailures)
+
+			// API 密钥
+			owner.GET("/api-keys", adminHandlers.ListAPIKeys)
+			owner.POST("/api-keys", adminHandlers.CreateAPIKey)
+			owner.DELETE("/api-keys/:id", adminHandlers.RevokeAPIKey)
+
+			// 审计日志
+			owner.GET("/audit-log", adminHandlers.ListAuditLog)
 		}
 	}
 
 	// 注册现有路由
-	engine.POST("/giftcode", handlers.AddGiftCode)
-	engine.POST("/add_user", handlers.AddUser)
-	engine.GET("/ip", handlers.GetIP)
+	// 兑换码和用户接口需要携带对应权限的 API 密钥，密钥校验在限流之前，使限流按密钥计数
+	// 无效密钥在 api_key 分组中按 IP 限流，超出后不再查询数据库
+	if !cfg.Security.APIKey.Required {
+		logger.Warn("API key enforcement disabled, public endpoints accept requests without X-API-Key")
+	}
+	keyAuth := api.NewAPIKeyAuth(repository, cfg.Security.APIKey.Required,
+		api.NewKeyedLimiter(cfg.Security.RateLimit, config.RateLimitGroupAPIKey), logger)
+	apiKeyAuth := keyAuth.Middleware
+	publicRateLimit := rateLimit(config.RateLimitGroupPublic)
+	public := engine.Group("")
+	{
+		public.POST("/giftcode", apiKeyAuth(storage.APIKeyScopeEnqueueCode), publicRateLimit, handlers.AddGiftCode)
+		public.GET("/giftcode/:code", apiKeyAuth(storage.APIKeyScopeReadOnly), publicRateLimit, handlers.GetGiftCode)
+		public.POST("/add_user", apiKeyAuth(storage.APIKeyScopeAddUser), publicRateLimit, handlers.AddUser)
+		public.GET("/ip", publicRateLimit, handlers.GetIP)
+	}
+
+	// 公开接口 v1，使用 JSON 请求体和统一的分页、错误格式
+	// 路由和 OpenAPI 文档由同一份路由表生成，上面的旧路由保留用于兼容已有脚本
+	v1Routes := handlers.V1Routes()
+	v1 := engine.Group("/api/v1")
+	{
+		for _, route := range v1Routes {
+			chain := []gin.HandlerFunc{}
+			if route.Scope != "" {
+				chain = append(chain, apiKeyAuth(route.Scope))
+			}
+			chain = append(chain, publicRateLimit, route.Handler)
+			v1.Handle(route.Method, route.Path, chain...)
+		}
+
+		openAPI := api.NewOpenAPIDocument("/api/v1", "1.0.0", v1Routes)
+		v1.GET("/openapi.json", publicRateLimit, func(c *gin.Context) {
+			c.JSON(http.StatusOK, openAPI)
+		})
+	}
+
+	// Prometheus 指标，配置了 metrics.token 时需要携带该令牌
+	if cfg.Metrics.Enabled {
+		if cfg.Metrics.Token == "" {
+			logger.Warn("Metrics token not configured, /metrics is readable without authentication")
+		}
+		engine.GET("/metrics", api.MetricsHandler(cfg.Metrics.Token, logger))
+	}
 
 	// 管理后台静态文件路由
 	// 处理 /admin 和 /admin/ 重定向
-	engine.GET("/admin", func(c *gin.Context) {
+	engine.GET("/admin", staticRateLimit, func(c *gin.Context) {
 		// 重定向到登录页（前端会检查token并决定是否跳转到dashboard）
 		c.Redirect(http.StatusFound, "/admin/login.html")
 	})
 
 	// 提供管理后台静态文件 - 使用具体的文件路由
-	engine.GET("/admin/login.html", func(c *gin.Context) {
+	engine.GET("/admin/login.html", staticRateLimit, func(c *gin.Context) {
 		c.Writer.Header().Set("Cache-Control", "max-age="+strconv.Itoa(300))
 		c.FileFromFS("static/admin/login.html", http.FS(staticFS))
 	})
-	engine.GET("/admin/dashboard.html", func(c *gin.Context) {
+	engine.GET("/admin/dashboard.html", staticRateLimit, func(c *gin.Context) {
 		c.Writer.Header().Set("Cache-Control", "max-age="+strconv.Itoa(300))
 		c.FileFromFS("static/admin/dashboard.html", http.FS(staticFS))
 	})
-	engine.GET("/admin/dashboard.js", func(c *gin.Context) {
+	engine.GET("/admin/dashboard.js", staticRateLimit, func(c *gin.Context) {
 		c.Writer.Header().Set("Cache-Control", "max-age="+strconv.Itoa(300))
 		c.Writer.Header().Set("Content-Type", "application/javascript")
 		c.FileFromFS("static/admin/dashboard.js", http.FS(staticFS))
 	})
-	engine.GET("/admin/styles.css", func(c *gin.Context) {
+	engine.GET("/admin/styles.css", staticRateLimit, func(c *gin.Context) {
 		c.Writer.Header().Set("Cache-Control", "max-age="+strconv.Itoa(300))
 		c.Writer.Header().Set("Content-Type", "text/css")
 		c.FileFromFS("static/admin/styles.css", http.FS(staticFS))
 	})
 
 	// 静态文件路由（必须最后注册，因为是catch-all）
-	engine.NoRoute(func(c *gin.Context) {
+	engine.NoRoute(staticRateLimit, func(c *gin.Context) {
 		path := c.Request.URL.Path
 		if path == "" || path == "/" {
 			c.Redirect(http.StatusFound, "/admin/login.html")
@@ -238,7 +440,7 @@ func setupServer(cfg *config.Config, handlers *api.Handlers, adminHandlers *api.
 }
 
 // gracefulShutdown 优雅关闭服务器
-func gracefulShutdown(server *http.Server, repository storage.Repository, logger *logrus.Logger) {
+func gracefulShutdown(server *http.Server, repository storage.Repository, notificationService *service.NotificationService, logger *logrus.Logger) {
 	// 监听中断信号
 	quit := make(chan os.Signal, 1)
 	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
@@ -255,6 +457,9 @@ func gracefulShutdown(server *http.Server, repository storage.Repository, logger
 		logger.Errorf("Server forced to shutdown: %v", err)
 	}
 
+	// 停止通知投递，未投递的通知保留为 pending，重启后继续投递
+	notificationService.Stop()
+
 	// 关闭数据库连接
 	if err := repository.Close(); err != nil {
 		logger.Errorf("Failed to close repository: %v", err)
@@ -52,19 +52,14 @@ func TestAdminStaticRoutes(t *testing.T) {
 	mockRepo := &storage.MockRepository{}
 
 	// Create auth service
-	authService := auth.NewAuthService(
-		cfg.Admin.Username,
-		cfg.Admin.PasswordHash,
-		cfg.Admin.TokenSecret,
-		cfg.Admin.TokenDuration,
-	)
+	authService := auth.NewAuthService(mockRepo, cfg.Admin.TokenSecret, cfg.Admin.TokenDuration, 7*24*time.Hour, "cdk-get")
 
 	// Create handlers
-	handlers := api.NewHandlers(nil, nil, logger)
-	adminHandlers := api.NewAdminHandlers(authService, mockRepo, logger)
+	handlers := api.NewHandlers(nil, nil, nil, nil, logger)
+	adminHandlers := api.NewAdminHandlers(authService, mockRepo, nil, nil, nil, nil, logger)
 
 	// Setup server
-	server := setupServer(cfg, handlers, adminHandlers, authService, logger)
+	server := setupServer(cfg, handlers, adminHandlers, authService, mockRepo, logger)
 
 	tests := []struct {
 		name           string
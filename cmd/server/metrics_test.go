@@ -0,0 +1,71 @@
+package main
+
+import (
+	"cdk-get/internal/api"
+	"cdk-get/internal/auth"
+	"cdk-get/internal/config"
+	"cdk-get/internal/storage"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+)
+
+// TestMetricsEndpoint tests that /metrics exposes request metrics behind the optional bearer token
+func TestMetricsEndpoint(t *testing.T) {
+	cfg := &config.Config{
+		Logging: config.LoggingConfig{
+			Level:  "error",
+			Format: "json",
+		},
+		Admin: config.AdminConfig{
+			TokenSecret:   "test-secret",
+			TokenDuration: 24 * time.Hour,
+		},
+		Metrics: config.MetricsConfig{
+			Enabled: true,
+			Token:   "scrape-token",
+		},
+	}
+
+	logger := logrus.New()
+	logger.SetLevel(logrus.ErrorLevel)
+
+	mockRepo := &storage.MockRepository{}
+	authService := auth.NewAuthService(mockRepo, cfg.Admin.TokenSecret, cfg.Admin.TokenDuration, 7*24*time.Hour, "cdk-get")
+	newServer := func() *http.Server {
+		return setupServer(cfg, api.NewHandlers(nil, nil, nil, nil, logger), api.NewAdminHandlers(authService, mockRepo, nil, nil, nil, nil, logger), authService, mockRepo, logger)
+	}
+	server := newServer()
+
+	request := func(server *http.Server, path, token string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		w := httptest.NewRecorder()
+		server.Handler.ServeHTTP(w, req)
+		return w
+	}
+
+	assert.Equal(t, http.StatusUnauthorized, request(server, "/metrics", "").Code)
+	assert.Equal(t, http.StatusUnauthorized, request(server, "/metrics", "wrong-token").Code)
+
+	// 按路由模板而不是实际路径记录请求
+	request(server, "/api/admin/users/12345", "")
+	w := request(server, "/metrics", "scrape-token")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), `cdk_get_http_requests_total{method="GET",route="/api/admin/users/:fid",status="401"}`)
+	assert.NotContains(t, w.Body.String(), `route="/api/admin/users/12345"`)
+
+	// 未配置令牌时无需认证
+	cfg.Metrics.Token = ""
+	assert.Equal(t, http.StatusOK, request(newServer(), "/metrics", "").Code)
+
+	// 关闭后不提供指标接口
+	cfg.Metrics.Enabled = false
+	assert.NotEqual(t, http.StatusOK, request(newServer(), "/metrics", "").Code)
+}
@@ -0,0 +1,129 @@
+package main
+
+import (
+	"cdk-get/internal/api"
+	"cdk-get/internal/auth"
+	"cdk-get/internal/config"
+	"cdk-get/internal/service"
+	"cdk-get/internal/storage"
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestPlayerLookup tests how the user endpoints map player API results, using a local player API
+func TestPlayerLookup(t *testing.T) {
+	// fid 1 不存在，fid 2 接口出错，其余返回玩家资料
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch fid := r.FormValue("fid"); fid {
+		case "1":
+			fmt.Fprint(w, `{"code":1,"msg":"role not exist.","data":[]}`)
+		case "2":
+			w.WriteHeader(http.StatusInternalServerError)
+		default:
+			fmt.Fprintf(w, `{"code":0,"msg":"success","data":{"fid":%s,"nickname":"player-%s","kid":7,"avatar_image":""}}`, fid, fid)
+		}
+	}))
+	defer upstream.Close()
+
+	cfg := &config.Config{
+		Logging: config.LoggingConfig{
+			Level:  "error",
+			Format: "json",
+		},
+		Admin: config.AdminConfig{
+			TokenSecret:   "test-secret",
+			TokenDuration: 24 * time.Hour,
+		},
+	}
+
+	logger := logrus.New()
+	logger.SetLevel(logrus.ErrorLevel)
+
+	sqliteConfig := storage.DefaultSqliteConfig()
+	sqliteConfig.Path = filepath.Join(t.TempDir(), "players.db")
+	repository, err := storage.NewSqliteRepository(sqliteConfig, logger)
+	require.NoError(t, err)
+	defer repository.Close()
+
+	authService := auth.NewAuthService(repository, cfg.Admin.TokenSecret, cfg.Admin.TokenDuration, 7*24*time.Hour, "cdk-get")
+	newServer := func(giftService *service.GiftService) *http.Server {
+		handlers := api.NewHandlers(giftService, nil, repository, nil, logger)
+		return setupServer(cfg, handlers, api.NewAdminHandlers(authService, repository, giftService, nil, nil, nil, logger), authService, repository, logger)
+	}
+	server := newServer(service.NewGiftService(repository, nil, nil, nil, upstream.URL+"/", 0, logger))
+
+	request := func(server *http.Server, method, path, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		server.Handler.ServeHTTP(w, req)
+		return w
+	}
+
+	// 玩家不存在返回 400，接口出错返回 502
+	w := request(server, http.MethodPost, "/add_user?fid=1", "")
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Contains(t, w.Body.String(), "PLAYER_NOT_FOUND")
+	w = request(server, http.MethodPost, "/add_user?fid=2", "")
+	assert.Equal(t, http.StatusBadGateway, w.Code)
+	assert.Contains(t, w.Body.String(), "EXTERNAL_API_ERROR")
+
+	// v1 接口按 AppError 映射
+	w = request(server, http.MethodPost, "/api/v1/users", `{"fid":"1"}`)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	w = request(server, http.MethodPost, "/api/v1/users", `{"fid":"2"}`)
+	assert.Equal(t, http.StatusBadGateway, w.Code)
+
+	// 未配置兑换服务时返回 503
+	w = request(newServer(nil), http.MethodPost, "/add_user?fid=3", "")
+	assert.Equal(t, http.StatusServiceUnavailable, w.Code)
+	assert.Contains(t, w.Body.String(), "SERVICE_UNAVAILABLE")
+
+	// 查询成功时保存用户
+	w = request(server, http.MethodPost, "/add_user?fid=3", "")
+	require.Equal(t, http.StatusOK, w.Code)
+	user, err := repository.GetUser(context.Background(), "3")
+	require.NoError(t, err)
+	assert.Equal(t, "player-3", user.Nickname)
+	assert.Equal(t, 7, user.KID)
+
+	// 回收站中的用户不会通过公开接口恢复
+	require.NoError(t, repository.DeleteUser(context.Background(), "3"))
+	w = request(server, http.MethodPost, "/add_user?fid=3", "")
+	assert.Equal(t, http.StatusConflict, w.Code)
+	assert.Contains(t, w.Body.String(), "ALREADY_EXISTS")
+	w = request(server, http.MethodPost, "/api/v1/users", `{"fid":"3"}`)
+	assert.Equal(t, http.StatusConflict, w.Code)
+	_, err = repository.GetUser(context.Background(), "3")
+	assert.Error(t, err)
+
+	// 回收站中的用户资料与变更记录保持不变
+	ctx := context.Background()
+	require.NoError(t, repository.SaveUser(ctx, &storage.User{FID: "4", Nickname: "old-4", KID: 1}))
+	require.NoError(t, repository.DeleteUser(ctx, "4"))
+	w = request(server, http.MethodPost, "/add_user?fid=4", "")
+	assert.Equal(t, http.StatusConflict, w.Code)
+	w = request(server, http.MethodPost, "/api/v1/users", `{"fid":"4"}`)
+	assert.Equal(t, http.StatusConflict, w.Code)
+	deleted, err := repository.ListDeletedUsers(ctx)
+	require.NoError(t, err)
+	for _, user := range deleted {
+		if user.FID == "4" {
+			assert.Equal(t, "old-4", user.Nickname)
+			assert.Equal(t, 1, user.KID)
+		}
+	}
+	history, err := repository.ListUserProfileHistory(ctx, "4")
+	require.NoError(t, err)
+	assert.Empty(t, history)
+}
@@ -0,0 +1,108 @@
+package main
+
+import (
+	"cdk-get/internal/api"
+	"cdk-get/internal/auth"
+	"cdk-get/internal/config"
+	"cdk-get/internal/storage"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+)
+
+// TestRateLimitPerClient tests that clients and route groups are limited independently
+func TestRateLimitPerClient(t *testing.T) {
+	cfg := &config.Config{
+		Logging: config.LoggingConfig{
+			Level:  "error",
+			Format: "json",
+		},
+		Server: config.ServerConfig{
+			Host: "localhost",
+			Port: 8080,
+		},
+		Security: config.SecurityConfig{
+			RateLimit: config.RateLimitConfig{
+				Enabled:     true,
+				Rate:        1,
+				Burst:       2,
+				MaxKeys:     100,
+				IdleTimeout: time.Minute,
+				Groups: map[string]config.RateLimitRule{
+					config.RateLimitGroupAdmin: {Rate: 1, Burst: 3},
+				},
+			},
+		},
+		Admin: config.AdminConfig{
+			TokenSecret:   "test-secret",
+			TokenDuration: 24 * time.Hour,
+		},
+	}
+
+	logger := logrus.New()
+	logger.SetLevel(logrus.ErrorLevel)
+
+	mockRepo := &storage.MockRepository{
+		GetAdminFunc: func(ctx context.Context, username string) (*storage.Admin, error) {
+			return &storage.Admin{Username: username, Role: storage.AdminRoleOwner}, nil
+		},
+		GetAdminSessionFunc: func(ctx context.Context, id string) (*storage.AdminSession, error) {
+			return &storage.AdminSession{ID: id, Username: id, ExpiresAt: time.Now().Add(time.Hour)}, nil
+		},
+	}
+
+	authService := auth.NewAuthService(mockRepo, cfg.Admin.TokenSecret, cfg.Admin.TokenDuration, 7*24*time.Hour, "cdk-get")
+	handlers := api.NewHandlers(nil, nil, nil, nil, logger)
+	adminHandlers := api.NewAdminHandlers(authService, mockRepo, nil, nil, nil, nil, logger)
+	server := setupServer(cfg, handlers, adminHandlers, authService, mockRepo, logger)
+
+	request := func(path, ip, username string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		req.RemoteAddr = ip + ":12345"
+		if username != "" {
+			token, _, err := authService.GenerateToken(username, storage.AdminRoleOwner, username)
+			assert.NoError(t, err)
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		w := httptest.NewRecorder()
+		server.Handler.ServeHTTP(w, req)
+		return w
+	}
+
+	// 公开接口按 IP 计数，令牌用完后返回 429 和 Retry-After
+	w := request("/ip", "10.0.0.1", "")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "2", w.Header().Get("RateLimit-Limit"))
+	assert.Equal(t, "1", w.Header().Get("RateLimit-Remaining"))
+	assert.Equal(t, "1", w.Header().Get("RateLimit-Reset"))
+	assert.Equal(t, http.StatusOK, request("/ip", "10.0.0.1", "").Code)
+	w = request("/ip", "10.0.0.1", "")
+	assert.Equal(t, http.StatusTooManyRequests, w.Code)
+	assert.Equal(t, "1", w.Header().Get("Retry-After"))
+	assert.Equal(t, "0", w.Header().Get("RateLimit-Remaining"))
+
+	// 未配置可信代理时，伪造 X-Forwarded-For 不会得到新的计数
+	req := httptest.NewRequest(http.MethodGet, "/ip", nil)
+	req.RemoteAddr = "10.0.0.1:12345"
+	req.Header.Set("X-Forwarded-For", "10.0.0.9")
+	w = httptest.NewRecorder()
+	server.Handler.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusTooManyRequests, w.Code)
+
+	// 其他 IP 和其他路由分组不受影响
+	assert.Equal(t, http.StatusOK, request("/ip", "10.0.0.2", "").Code)
+	w = request("/api/admin/me", "10.0.0.1", "alice")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "3", w.Header().Get("RateLimit-Limit"))
+
+	// 管理接口按管理员计数，同一 IP 的其他管理员不受影响
+	assert.Equal(t, http.StatusOK, request("/api/admin/me", "10.0.0.1", "alice").Code)
+	assert.Equal(t, http.StatusOK, request("/api/admin/me", "10.0.0.3", "alice").Code)
+	assert.Equal(t, http.StatusTooManyRequests, request("/api/admin/me", "10.0.0.4", "alice").Code)
+	assert.Equal(t, http.StatusOK, request("/api/admin/me", "10.0.0.1", "bob").Code)
+}
@@ -0,0 +1,107 @@
+package main
+
+import (
+	"cdk-get/internal/api"
+	"cdk-get/internal/auth"
+	"cdk-get/internal/captcha"
+	"cdk-get/internal/config"
+	"cdk-get/internal/service"
+	"cdk-get/internal/storage"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestRedeemNowEndpoint tests POST /api/admin/redeem, including the streamed per-fid progress
+func TestRedeemNowEndpoint(t *testing.T) {
+	cfg := &config.Config{
+		Logging: config.LoggingConfig{
+			Level:  "error",
+			Format: "json",
+		},
+		Server: config.ServerConfig{
+			Host: "localhost",
+			Port: 8080,
+		},
+		Admin: config.AdminConfig{
+			TokenSecret:   "test-secret",
+			TokenDuration: 24 * time.Hour,
+		},
+	}
+
+	logger := logrus.New()
+	logger.SetLevel(logrus.ErrorLevel)
+
+	var entries []*storage.AuditEntry
+	mockRepo := &storage.MockRepository{
+		GetAdminFunc: func(ctx context.Context, username string) (*storage.Admin, error) {
+			role := storage.AdminRoleOperator
+			if username == "bob" {
+				role = storage.AdminRoleViewer
+			}
+			return &storage.Admin{Username: username, Role: role}, nil
+		},
+		GetAdminSessionFunc: func(ctx context.Context, id string) (*storage.AdminSession, error) {
+			return &storage.AdminSession{ID: id, Username: strings.TrimSuffix(id, "-session"), ExpiresAt: time.Now().Add(time.Hour)}, nil
+		},
+		// 已兑换的用户不需要调用兑换接口
+		IsGiftCodeReceivedFunc: func(ctx context.Context, fid, code string) (bool, error) {
+			return true, nil
+		},
+		CreateAuditEntryFunc: func(ctx context.Context, entry *storage.AuditEntry) error {
+			entries = append(entries, entry)
+			return nil
+		},
+	}
+
+	authService := auth.NewAuthService(mockRepo, cfg.Admin.TokenSecret, cfg.Admin.TokenDuration, 7*24*time.Hour, "cdk-get")
+	handlers := api.NewHandlers(nil, nil, nil, nil, logger)
+	giftService := service.NewGiftService(mockRepo, nil, &captcha.CaptchaPool{}, nil, "", 2, logger)
+	server := setupServer(cfg, handlers, api.NewAdminHandlers(authService, mockRepo, giftService, nil, nil, nil, logger), authService, mockRepo, logger)
+	// 验证码池未初始化时无法兑换
+	noCaptchaServer := setupServer(cfg, handlers, api.NewAdminHandlers(authService, mockRepo, nil, nil, nil, nil, logger), authService, mockRepo, logger)
+
+	operatorToken, _, err := authService.GenerateToken("alice", storage.AdminRoleOperator, "alice-session")
+	require.NoError(t, err)
+	viewerToken, _, err := authService.GenerateToken("bob", storage.AdminRoleViewer, "bob-session")
+	require.NoError(t, err)
+
+	request := func(server *http.Server, token, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/api/admin/redeem", strings.NewReader(body))
+		req.Header.Set("Authorization", "Bearer "+token)
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		server.Handler.ServeHTTP(w, req)
+		return w
+	}
+
+	assert.Equal(t, http.StatusForbidden, request(server, viewerToken, `{"code":"VIP","fids":["1"]}`).Code)
+	assert.Equal(t, http.StatusServiceUnavailable, request(noCaptchaServer, operatorToken, `{"code":"VIP","fids":["1"]}`).Code)
+	assert.Equal(t, http.StatusBadRequest, request(server, operatorToken, `{"code":" ","fids":["1"]}`).Code)
+	assert.Equal(t, http.StatusBadRequest, request(server, operatorToken, `{"code":"VIP","fids":["abc"]}`).Code)
+	// 没有参与兑换的用户
+	assert.Equal(t, http.StatusBadRequest, request(server, operatorToken, `{"code":"VIP"}`).Code)
+	assert.Empty(t, entries)
+
+	// 重复的 fid 只兑换一次，每个用户完成时推送 result 事件
+	w := request(server, operatorToken, `{"code":"VIP","fids":["1","2","2"]}`)
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Header().Get("Content-Type"), "text/event-stream")
+	body := w.Body.String()
+	assert.Contains(t, body, "event:start\ndata:{\"code\":\"VIP\",\"total\":2}")
+	assert.Equal(t, 2, strings.Count(body, "event:result"))
+	assert.Contains(t, body, `"message":"兑换码已兑换"`)
+	assert.Contains(t, body, "event:done\ndata:{\"code\":\"VIP\",\"total\":2,\"done\":2,\"success\":2,\"failed\":0,\"canceled\":false}")
+
+	require.Len(t, entries, 1)
+	assert.Equal(t, "task.redeem", entries[0].Action)
+	assert.Equal(t, "VIP", entries[0].Target)
+	assert.JSONEq(t, `{"code":"VIP","total":2,"done":2,"success":2,"failed":0,"canceled":false}`, string(entries[0].After))
+}
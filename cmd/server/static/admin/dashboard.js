@@ -16,6 +16,17 @@ function hideLoading() {
     document.getElementById('loadingOverlay').classList.remove('show');
 }
 
+/**
+ * Escape text for use in HTML
+ * @param {string} text - Raw text
+ * @returns {string} - Escaped text
+ */
+function escapeHtml(text) {
+    const div = document.createElement('div');
+    div.textContent = text;
+    return div.innerHTML.replace(/"/g, '&quot;');
+}
+
 /**
  * Show confirmation dialog
  * @param {string} title - Dialog title
@@ -110,7 +121,8 @@ function validateForm(form) {
 /**
  * Authentication interceptor that automatically:
  * - Adds Authorization header to all API requests
- * - Handles 401 errors (token expiration)
+ * - Renews the short-lived access token with the refresh token on 401
+ * - Handles 401 errors (session expired or revoked)
  * - Redirects to login page when authentication fails
  * - Clears expired tokens from LocalStorage
  */
@@ -135,6 +147,43 @@ const AuthInterceptor = {
     clearAuth() {
         localStorage.removeItem('admin_token');
         localStorage.removeItem('admin_token_expires_at');
+        localStorage.removeItem('admin_refresh_token');
+        localStorage.removeItem('admin_refresh_expires_at');
+    },
+
+    // In-flight refresh, shared so concurrent 401s rotate the refresh token only once
+    refreshing: null,
+
+    /**
+     * Exchange the refresh token for a new token pair
+     * @returns {Promise<boolean>} - Whether new tokens were stored
+     */
+    refresh() {
+        const refreshToken = localStorage.getItem('admin_refresh_token');
+        if (!refreshToken) {
+            return Promise.resolve(false);
+        }
+        if (!this.refreshing) {
+            this.refreshing = fetch(`${API_BASE}/refresh`, {
+                method: 'POST',
+                headers: { 'Content-Type': 'application/json' },
+                body: JSON.stringify({ refresh_token: refreshToken })
+            })
+                .then(async response => {
+                    if (!response.ok) return false;
+                    const data = await response.json();
+                    localStorage.setItem('admin_token', data.data.token);
+                    localStorage.setItem('admin_token_expires_at', data.data.expires_at);
+                    localStorage.setItem('admin_refresh_token', data.data.refresh_token);
+                    localStorage.setItem('admin_refresh_expires_at', data.data.refresh_expires_at);
+                    return true;
+                })
+                .catch(() => false)
+                .finally(() => {
+                    this.refreshing = null;
+                });
+        }
+        return this.refreshing;
     },
 
     /**
@@ -160,7 +209,7 @@ const AuthInterceptor = {
      * @param {object} options - Fetch options
      * @returns {Promise<Response>}
      */
-    async fetch(url, options = {}) {
+    async fetch(url, options = {}, retried = false) {
         // Get token
         const token = this.getToken();
 
@@ -182,8 +231,11 @@ const AuthInterceptor = {
                 headers
             });
 
-            // Handle 401 Unauthorized - token expired or invalid
+            // Handle 401 Unauthorized - renew the access token once, then give up
             if (response.status === 401) {
+                if (!retried && await this.refresh()) {
+                    return this.fetch(url, options, true);
+                }
                 this.handleAuthFailure();
                 // Return a rejected promise to prevent further processing
                 return Promise.reject(new Error('UNAUTHORIZED'));
@@ -212,28 +264,56 @@ const AuthInterceptor = {
     }
 };
 
+// API base URL
+const API_BASE = '/api/admin';
+
 // Check authentication on page load
 if (!AuthInterceptor.hasToken()) {
     AuthInterceptor.redirectToLogin();
 }
 
-// API base URL
-const API_BASE = '/api/admin';
-
 // Current view state
 let currentView = 'users';
-let autoRefreshInterval = null;
-let refreshInterval = 30000; // Default 30 seconds
+let tasksMode = 'pending'; // Tasks view shows pending or completed tasks
+let usersCache = {};
+let userGroupsCache = [];
+let currentAdmin = null;
 
 // Initialize
 document.addEventListener('DOMContentLoaded', () => {
-    loadRefreshInterval();
     setupNavigation();
     setupFormValidation();
     setupModalCloseOnOutsideClick();
+    loadCurrentAdmin();
     loadUsersView();
+    connectLiveEvents();
 });
 
+// Load the signed-in admin and hide actions the role is not allowed to use
+async function loadCurrentAdmin() {
+    try {
+        const response = await apiRequest('/me');
+        if (!response) return;
+        currentAdmin = response.data.admin;
+        document.getElementById('username').textContent = `${currentAdmin.username} (${formatAdminRole(currentAdmin.role)})`;
+        document.body.classList.add(`role-${currentAdmin.role}`);
+
+        // The owner requires two-factor authentication for this account; other views stay locked until it is enabled
+        if (currentAdmin.totp_required && !currentAdmin.totp_enabled) {
+            showView('totp');
+            showMessage('totp', '该账号被要求启用两步验证，启用后才能使用其他功能', 'warning');
+        }
+    } catch (error) {
+        console.error('Failed to load current admin:', error);
+    }
+}
+
+// Format admin role
+function formatAdminRole(role) {
+    const roles = { owner: '所有者', operator: '操作员', viewer: '只读' };
+    return roles[role] || role;
+}
+
 // Setup navigation
 function setupNavigation() {
     const navItems = document.querySelectorAll('.nav-item');
@@ -264,38 +344,8 @@ function setupModalCloseOnOutsideClick() {
     });
 }
 
-// Load refresh interval from localStorage
-function loadRefreshInterval() {
-    const savedInterval = localStorage.getItem('refreshInterval');
-    if (savedInterval) {
-        refreshInterval = parseInt(savedInterval, 10);
-    }
-}
-
-// Change refresh interval
-function changeRefreshInterval(interval) {
-    refreshInterval = parseInt(interval, 10);
-    localStorage.setItem('refreshInterval', refreshInterval);
-
-    // Restart auto-refresh with new interval
-    if (currentView === 'tasks' && autoRefreshInterval) {
-        clearInterval(autoRefreshInterval);
-        autoRefreshInterval = setInterval(() => {
-            if (currentView === 'tasks') {
-                loadTasksView();
-            }
-        }, refreshInterval);
-    }
-}
-
 // Show view
 function showView(viewName) {
-    // Clear auto-refresh
-    if (autoRefreshInterval) {
-        clearInterval(autoRefreshInterval);
-        autoRefreshInterval = null;
-    }
-
     // Update navigation
     document.querySelectorAll('.nav-item').forEach(item => {
         item.classList.remove('active');
@@ -315,12 +365,39 @@ function showView(viewName) {
     if (viewName === 'users') {
         document.getElementById('users-view').classList.add('active');
         loadUsersView();
+    } else if (viewName === 'groups') {
+        document.getElementById('groups-view').classList.add('active');
+        loadGroupsView();
     } else if (viewName === 'tasks') {
         document.getElementById('tasks-view').classList.add('active');
         loadTasksView();
     } else if (viewName === 'notifications') {
         document.getElementById('notifications-view').classList.add('active');
         loadNotificationsView();
+    } else if (viewName === 'stats') {
+        document.getElementById('stats-view').classList.add('active');
+        loadStatsView();
+    } else if (viewName === 'trash') {
+        document.getElementById('trash-view').classList.add('active');
+        loadTrashView();
+    } else if (viewName === 'sessions') {
+        document.getElementById('sessions-view').classList.add('active');
+        loadSessionsView();
+    } else if (viewName === 'totp') {
+        document.getElementById('totp-view').classList.add('active');
+        loadTOTPView();
+    } else if (viewName === 'admins') {
+        document.getElementById('admins-view').classList.add('active');
+        loadAdminsView();
+    } else if (viewName === 'security') {
+        document.getElementById('security-view').classList.add('active');
+        loadSecurityView();
+    } else if (viewName === 'apikeys') {
+        document.getElementById('apikeys-view').classList.add('active');
+        loadAPIKeysView();
+    } else if (viewName === 'audit') {
+        document.getElementById('audit-view').classList.add('active');
+        loadAuditView();
     }
 }
 
@@ -329,7 +406,13 @@ function logout() {
     showConfirmDialog(
         '确认退出',
         '您确定要退出登录吗？',
-        () => {
+        async () => {
+            // Revoke the session on the server; leave even if the request fails
+            try {
+                await apiRequest('/logout', { method: 'POST' });
+            } catch (error) {
+                console.error('Logout request failed:', error);
+            }
             AuthInterceptor.clearAuth();
             AuthInterceptor.redirectToLogin();
         }
@@ -369,17 +452,175 @@ function showMessage(viewId, message, type = 'success') {
     }, 5000);
 }
 
+// ============================================
+// List Filters & Pagination
+// ============================================
+
+const LIST_PAGE_SIZE = 50;
+
+// Filter, sort and page state of the paginated tables, kept while switching views.
+// cursors[i] is the cursor of page i, so going back reuses the cursor of the previous page.
+const listDefaults = {
+    users: { q: '', kid: '', status: '', sort: '', order: '' },
+    tasks: { q: '', status: 'pending', since: '', until: '', sort: '', order: '' },
+    completed: { q: '', since: '', until: '', sort: '', order: '' },
+    notifications: { q: '', status: '', channel: '', event: '', since: '', until: '', sort: '' }
+};
+const listStates = {};
+Object.keys(listDefaults).forEach(name => {
+    listStates[name] = { filters: { ...listDefaults[name] }, cursors: [''], page: 0, next: '' };
+});
+
+const listLoaders = {
+    users: () => loadUsersView(),
+    tasks: () => loadTasksView(),
+    completed: () => loadCompletedTasksView(),
+    notifications: () => loadNotificationsView()
+};
+
+// Convert local whole-day dates (yyyy-mm-dd) to an RFC 3339 since/until range
+// 日期筛选按本地时间的整天计算
+function setDateRangeParams(params, since, until) {
+    if (since) params.set('since', new Date(`${since}T00:00:00`).toISOString());
+    if (until) {
+        const end = new Date(`${until}T00:00:00`);
+        end.setDate(end.getDate() + 1);
+        params.set('until', end.toISOString());
+    }
+}
+
+// Build the query string of a paginated table from its filters and current cursor
+function listQuery(name) {
+    const state = listStates[name];
+    const params = new URLSearchParams({ limit: String(LIST_PAGE_SIZE) });
+    Object.entries(state.filters).forEach(([key, value]) => {
+        if (value && key !== 'since' && key !== 'until') params.set(key, value);
+    });
+    setDateRangeParams(params, state.filters.since, state.filters.until);
+    if (state.cursors[state.page]) params.set('cursor', state.cursors[state.page]);
+    return params.toString();
+}
+
+// Remember the next_cursor of the page just loaded
+function setListNextCursor(name, nextCursor) {
+    listStates[name].next = nextCursor || '';
+}
+
+// Render a filter form; fields is the inner HTML of the form fields
+function renderListFilters(name, fields) {
+    return `
+        <form onsubmit="applyListFilters(event, '${name}')" style="display: flex; gap: 1rem; flex-wrap: wrap; align-items: flex-end; margin-bottom: 1rem;">
+            ${fields}
+            <button type="submit" class="btn">筛选</button>
+            <button type="button" class="btn btn-secondary" onclick="resetListFilters('${name}')">重置</button>
+        </form>
+    `;
+}
+
+// Render a filter input bound to the current filter value
+function renderFilterInput(name, key, label, attrs = '') {
+    return `
+        <div class="form-group" style="margin: 0;">
+            <label>${label}</label>
+            <input name="${key}" value="${escapeHtml(listStates[name].filters[key])}" ${attrs}>
+        </div>
+    `;
+}
+
+// Render a filter select; options is a list of [value, label]
+function renderFilterSelect(name, key, label, options) {
+    const current = listStates[name].filters[key];
+    const optionsHtml = options
+        .map(([value, text]) => `<option value="${value}" ${value === current ? 'selected' : ''}>${text}</option>`)
+        .join('');
+    return `
+        <div class="form-group" style="margin: 0;">
+            <label>${label}</label>
+            <select name="${key}">${optionsHtml}</select>
+        </div>
+    `;
+}
+
+// Render the previous/next page controls
+function renderPager(name) {
+    const state = listStates[name];
+    if (state.page === 0 && !state.next) return '';
+    return `
+        <div style="display: flex; gap: 1rem; align-items: center; justify-content: flex-end; margin-top: 1rem;">
+            <button class="btn btn-secondary btn-sm" onclick="changeListPage('${name}', -1)" ${state.page === 0 ? 'disabled' : ''}>上一页</button>
+            <span>第 ${state.page + 1} 页</span>
+            <button class="btn btn-secondary btn-sm" onclick="changeListPage('${name}', 1)" ${state.next ? '' : 'disabled'}>下一页</button>
+        </div>
+    `;
+}
+
+// Apply the filters of a table and go back to its first page
+function applyListFilters(event, name) {
+    event.preventDefault();
+    const formData = new FormData(event.target);
+    const state = listStates[name];
+    Object.keys(state.filters).forEach(key => {
+        if (formData.has(key)) state.filters[key] = (formData.get(key) || '').trim();
+    });
+    state.cursors = [''];
+    state.page = 0;
+    listLoaders[name]();
+}
+
+// Clear the filters of a table
+function resetListFilters(name) {
+    listStates[name] = { filters: { ...listDefaults[name] }, cursors: [''], page: 0, next: '' };
+    listLoaders[name]();
+}
+
+// Go to the previous (-1) or next (1) page of a table
+function changeListPage(name, delta) {
+    const state = listStates[name];
+    if (delta > 0) {
+        if (!state.next) return;
+        state.cursors[state.page + 1] = state.next;
+        state.page++;
+    } else if (state.page > 0) {
+        state.page--;
+    }
+    listLoaders[name]();
+}
+
+// Sort options shared by the filter forms: [value, label] where value is "field:order"
+function renderSortSelect(name, options) {
+    const state = listStates[name];
+    const current = state.filters.sort ? `${state.filters.sort}:${state.filters.order || ''}` : '';
+    const optionsHtml = options
+        .map(([value, text]) => `<option value="${value}" ${value === current ? 'selected' : ''}>${text}</option>`)
+        .join('');
+    return `
+        <div class="form-group" style="margin: 0;">
+            <label>排序</label>
+            <select name="sort_by" onchange="this.form.sort.value = this.value.split(':')[0]; this.form.order.value = this.value.split(':')[1] || ''">
+                ${optionsHtml}
+            </select>
+            <input type="hidden" name="sort" value="${escapeHtml(state.filters.sort)}">
+            <input type="hidden" name="order" value="${escapeHtml(state.filters.order || '')}">
+        </div>
+    `;
+}
+
 // Load users view
 async function loadUsersView() {
     const contentEl = document.getElementById('users-content');
     contentEl.innerHTML = '<div class="loading">加载中...</div>';
 
     try {
-        const response = await apiRequest('/users');
+        const [response, groupsResponse] = await Promise.all([
+            apiRequest(`/users?${listQuery('users')}`),
+            apiRequest('/groups')
+        ]);
         const users = response.data.users || [];
+        setListNextCursor('users', response.data.next_cursor);
+        userGroupsCache = groupsResponse.data.groups || [];
 
         let html = `
-            <div style="margin-bottom: 2rem;">
+            <div class="requires-operator" style="margin-bottom: 2rem;">
                 <h3 style="margin-bottom: 1rem;">添加用户</h3>
                 <form id="add-user-form" onsubmit="addUser(event)">
                     <div class="form-group">
@@ -390,7 +631,18 @@ async function loadUsersView() {
                 </form>
             </div>
 
-            <h3 style="margin-bottom: 1rem;">用户列表 (${users.length})</h3>
+            <h3 style="margin-bottom: 1rem;">用户列表</h3>
+            ${renderListFilters('users', `
+                ${renderFilterInput('users', 'q', '搜索', 'placeholder="FID 或昵称"')}
+                ${renderFilterInput('users', 'kid', '区服', 'type="number" min="1" style="width: 6rem;"')}
+                ${renderFilterSelect('users', 'status', '状态', [['', '全部'], ['active', '启用'], ['disabled', '已禁用']])}
+                ${renderSortSelect('users', [
+                    ['', 'FID 倒序'],
+                    ['fid:asc', 'FID 正序'],
+                    ['nickname:asc', '昵称'],
+                    ['kid:asc', '区服']
+                ])}
+            `)}
         `;
 
         if (users.length === 0) {
@@ -405,15 +657,20 @@ async function loadUsersView() {
                                 <th>用户ID (FID)</th>
                                 <th>昵称</th>
                                 <th>KID</th>
-                                <th>创建时间</th>
+                                <th>分组</th>
+                                <th>状态</th>
                                 <th>操作</th>
                             </tr>
                         </thead>
                         <tbody>
             `;
 
+            usersCache = {};
             users.forEach(user => {
-                const createdAt = user.created_at ? new Date(user.created_at).toLocaleString('zh-CN') : '-';
+                usersCache[user.fid] = user;
+                const groups = (user.groups || []).join(', ') || '-';
+                const status = user.disabled ? 'failed' : 'completed';
+                const statusText = user.disabled ? '已禁用' : '启用';
                 const avatar = user.avatar_image || 'data:image/svg+xml,%3Csvg xmlns="http://www.w3.org/2000/svg" width="40" height="40"%3E%3Crect fill="%23ddd" width="40" height="40"/%3E%3C/svg%3E';
                 
                 html += `
@@ -422,8 +679,14 @@ async function loadUsersView() {
                         <td>${user.fid || '-'}</td>
                         <td>${user.nickname || '-'}</td>
                         <td>${user.kid || '-'}</td>
-                        <td>${createdAt}</td>
-                        <td><span class="clickable" onclick="showUserDetails('${user.fid}')">查看兑换记录</span></td>
+                        <td>${groups}</td>
+                        <td><span class="status-badge status-${status}">${statusText}</span></td>
+                        <td>
+                            <span class="clickable" onclick="showUserDetails('${user.fid}')">查看兑换记录</span>
+                            <button class="btn btn-secondary btn-sm requires-operator" onclick="showEditUserModal('${user.fid}')" style="padding: 0.25rem 0.5rem; font-size: 0.85rem; margin-left: 0.5rem;">编辑</button>
+                            <button class="btn btn-secondary btn-sm requires-operator" onclick="toggleUserDisabled('${user.fid}', ${!user.disabled})" style="padding: 0.25rem 0.5rem; font-size: 0.85rem;">${user.disabled ? '启用' : '禁用'}</button>
+                            <button class="btn btn-danger btn-sm requires-operator" onclick="deleteUser('${user.fid}')" style="padding: 0.25rem 0.5rem; font-size: 0.85rem; margin-left: 0.5rem;">删除</button>
+                        </td>
                     </tr>
                 `;
             });
@@ -435,6 +698,7 @@ async function loadUsersView() {
             `;
         }
 
+        html += renderPager('users');
         contentEl.innerHTML = html;
     } catch (error) {
         contentEl.innerHTML = '<div class="empty-state">加载失败，请重试</div>';
@@ -456,23 +720,22 @@ async function addUser(event) {
     
     const formData = new FormData(form);
     
+    // Nickname, kid and avatar are fetched from the player API by the server
     const userData = {
-        fid: formData.get('fid').trim(),
-        nickname: '',  // Empty default
-        kid: 0,        // Zero default
-        avatar_image: '' // Empty default
+        fid: formData.get('fid').trim()
     };
 
     // Show loading
     showLoading();
 
     try {
-        await apiRequest('/users', {
+        const response = await apiRequest('/users', {
             method: 'POST',
             body: JSON.stringify(userData)
         });
 
-        showMessage('users', '用户添加成功', 'success');
+        const { nickname, kid } = response.data;
+        showMessage('users', `用户添加成功: ${nickname} (区服 ${kid})`, 'success');
         form.reset();
         loadUsersView();
     } catch (error) {
@@ -482,41 +745,400 @@ async function addUser(event) {
     }
 }
 
+// Delete user (move to trash)
+function deleteUser(fid) {
+    showConfirmDialog(
+        '确认删除用户',
+        `用户 ${fid} 将移至回收站，不再参与兑换，兑换记录会保留，可在回收站中恢复。`,
+        async () => {
+            showLoading();
+            try {
+                await apiRequest(`/users/${encodeURIComponent(fid)}`, { method: 'DELETE' });
+                showMessage('users', '用户已移至回收站', 'success');
+                loadUsersView();
+            } catch (error) {
+                showMessage('users', `删除失败: ${error.message}`, 'error');
+            } finally {
+                hideLoading();
+            }
+        }
+    );
+}
+
+// Enable or disable user
+async function toggleUserDisabled(fid, disabled) {
+    showLoading();
+    try {
+        await apiRequest(`/users/${encodeURIComponent(fid)}`, {
+            method: 'PUT',
+            body: JSON.stringify({ disabled })
+        });
+        showMessage('users', disabled ? '用户已禁用' : '用户已启用', 'success');
+        loadUsersView();
+    } catch (error) {
+        showMessage('users', `操作失败: ${error.message}`, 'error');
+    } finally {
+        hideLoading();
+    }
+}
+
+/**
+ * Show a modal containing a form
+ * @param {string} title - Modal title
+ * @param {string} bodyHtml - Form fields HTML
+ * @param {function(FormData): Promise<void>} onSubmit - Called with form data when submitted
+ */
+function showFormModal(title, bodyHtml, onSubmit) {
+    const overlay = document.createElement('div');
+    overlay.className = 'modal-overlay show';
+    overlay.innerHTML = `
+        <div class="modal">
+            <form>
+                <div class="modal-header"><h3></h3></div>
+                <div class="modal-body">${bodyHtml}</div>
+                <div class="modal-footer">
+                    <button type="button" class="btn btn-secondary" data-action="cancel">取消</button>
+                    <button type="submit" class="btn">保存</button>
+                </div>
+            </form>
+        </div>
+    `;
+    overlay.querySelector('h3').textContent = title;
+
+    const close = () => {
+        if (overlay.parentNode) {
+            document.body.removeChild(overlay);
+        }
+    };
+    overlay.querySelector('[data-action="cancel"]').onclick = close;
+    overlay.addEventListener('click', (e) => {
+        if (e.target === overlay) close();
+    });
+    overlay.querySelector('form').addEventListener('submit', async (e) => {
+        e.preventDefault();
+        const form = e.target;
+        if (!validateForm(form)) return;
+        await onSubmit(new FormData(form));
+        close();
+    });
+
+    document.body.appendChild(overlay);
+}
+
+// Show edit user modal
+async function showEditUserModal(fid) {
+    const user = usersCache[fid];
+    if (!user) return;
+
+    // 个人通知目标，每行一个 "类型:目标"
+    let targets = [];
+    try {
+        const response = await apiRequest(`/users/${encodeURIComponent(fid)}`);
+        targets = response.data.notification_targets || [];
+    } catch (error) {
+        showMessage('users', `加载用户详情失败: ${error.message}`, 'error');
+        return;
+    }
+    const targetLines = targets.map(t => `${t.type}:${t.target}`).join('\n');
+
+    const groupCheckboxes = userGroupsCache.length === 0
+        ? '<div style="color: #6c757d;">暂无分组，请先在分组管理中创建</div>'
+        : userGroupsCache.map(group => `
+            <label style="display: inline-flex; align-items: center; gap: 0.25rem; margin-right: 1rem; font-weight: normal;">
+                <input type="checkbox" name="group_ids" value="${group.id}" ${(user.groups || []).includes(group.name) ? 'checked' : ''}>
+                ${group.name}
+            </label>
+        `).join('');
+
+    showFormModal(`编辑用户 ${fid}`, `
+        <div class="form-group">
+            <label>昵称</label>
+            <input type="text" name="nickname" value="${user.nickname || ''}">
+        </div>
+        <div class="form-group">
+            <label>KID</label>
+            <input type="number" name="kid" value="${user.kid || 0}">
+        </div>
+        <div class="form-group">
+            <label>分组</label>
+            <div>${groupCheckboxes}</div>
+        </div>
+        <div class="form-group">
+            <label>个人通知目标</label>
+            <textarea name="notification_targets" rows="3" placeholder="每行一个，例如:&#10;wxpusher:UID_xxx&#10;email:owner@example.com&#10;telegram:123456789&#10;webhook:https://example.com/hook">${targetLines}</textarea>
+        </div>
+    `, async (formData) => {
+        const notificationTargets = formData.get('notification_targets')
+            .split('\n')
+            .map(line => line.trim())
+            .filter(line => line !== '')
+            .map(line => {
+                const index = line.indexOf(':');
+                return index < 0
+                    ? { type: line, target: '' }
+                    : { type: line.substring(0, index).trim(), target: line.substring(index + 1).trim() };
+            });
+
+        showLoading();
+        try {
+            await apiRequest(`/users/${encodeURIComponent(fid)}`, {
+                method: 'PUT',
+                body: JSON.stringify({
+                    nickname: formData.get('nickname').trim(),
+                    kid: parseInt(formData.get('kid'), 10) || 0,
+                    group_ids: formData.getAll('group_ids').map(id => parseInt(id, 10)),
+                    notification_targets: notificationTargets
+                })
+            });
+            showMessage('users', '用户更新成功', 'success');
+            loadUsersView();
+        } catch (error) {
+            showMessage('users', `更新失败: ${error.message}`, 'error');
+        } finally {
+            hideLoading();
+        }
+    });
+}
+
+// Load groups view
+async function loadGroupsView() {
+    const contentEl = document.getElementById('groups-content');
+    contentEl.innerHTML = '<div class="loading">加载中...</div>';
+
+    try {
+        const response = await apiRequest('/groups');
+        const groups = response.data.groups || [];
+        userGroupsCache = groups;
+
+        let html = `
+            <div class="requires-operator" style="margin-bottom: 2rem;">
+                <h3 style="margin-bottom: 1rem;">创建分组</h3>
+                <form id="add-group-form" onsubmit="createGroup(event)">
+                    <div class="form-group">
+                        <label>分组名称 *</label>
+                        <input type="text" name="name" required placeholder="例如: 联盟、主号、小号">
+                    </div>
+                    <div class="form-group">
+                        <label>描述</label>
+                        <input type="text" name="description" placeholder="可选">
+                    </div>
+                    <button type="submit" class="btn">创建分组</button>
+                </form>
+            </div>
+
+            <h3 style="margin-bottom: 1rem;">分组列表 (${groups.length})</h3>
+        `;
+
+        if (groups.length === 0) {
+            html += '<div class="empty-state">暂无分组</div>';
+        } else {
+            html += `
+                <div class="table-container">
+                    <table>
+                        <thead>
+                            <tr>
+                                <th>名称</th>
+                                <th>描述</th>
+                                <th>成员数</th>
+                                <th>创建时间</th>
+                                <th>操作</th>
+                            </tr>
+                        </thead>
+                        <tbody>
+            `;
+
+            groups.forEach(group => {
+                const createdAt = group.created_at ? new Date(group.created_at).toLocaleString('zh-CN') : '-';
+
+                html += `
+                    <tr>
+                        <td>${group.name}</td>
+                        <td>${group.description || '-'}</td>
+                        <td>${group.member_count}</td>
+                        <td>${createdAt}</td>
+                        <td>
+                            <button class="btn btn-secondary btn-sm requires-operator" onclick="showEditGroupModal(${group.id})" style="padding: 0.25rem 0.5rem; font-size: 0.85rem;">编辑</button>
+                            <button class="btn btn-danger btn-sm requires-operator" onclick="deleteGroup(${group.id})" style="padding: 0.25rem 0.5rem; font-size: 0.85rem;">删除</button>
+                        </td>
+                    </tr>
+                `;
+            });
+
+            html += `
+                        </tbody>
+                    </table>
+                </div>
+            `;
+        }
+
+        contentEl.innerHTML = html;
+    } catch (error) {
+        contentEl.innerHTML = '<div class="empty-state">加载失败，请重试</div>';
+        showMessage('groups', error.message, 'error');
+    }
+}
+
+// Create group
+async function createGroup(event) {
+    event.preventDefault();
+
+    const form = event.target;
+    if (!validateForm(form)) {
+        showMessage('groups', '请填写所有必填字段', 'error');
+        return;
+    }
+
+    const formData = new FormData(form);
+
+    showLoading();
+    try {
+        await apiRequest('/groups', {
+            method: 'POST',
+            body: JSON.stringify({
+                name: formData.get('name').trim(),
+                description: formData.get('description').trim()
+            })
+        });
+        showMessage('groups', '分组创建成功', 'success');
+        form.reset();
+        loadGroupsView();
+    } catch (error) {
+        showMessage('groups', `创建失败: ${error.message}`, 'error');
+    } finally {
+        hideLoading();
+    }
+}
+
+// Show edit group modal
+function showEditGroupModal(id) {
+    const group = userGroupsCache.find(g => g.id === id);
+    if (!group) return;
+
+    showFormModal(`编辑分组 ${group.name}`, `
+        <div class="form-group">
+            <label>分组名称 *</label>
+            <input type="text" name="name" required value="${group.name}">
+        </div>
+        <div class="form-group">
+            <label>描述</label>
+            <input type="text" name="description" value="${group.description || ''}">
+        </div>
+    `, async (formData) => {
+        showLoading();
+        try {
+            await apiRequest(`/groups/${id}`, {
+                method: 'PUT',
+                body: JSON.stringify({
+                    name: formData.get('name').trim(),
+                    description: formData.get('description').trim()
+                })
+            });
+            showMessage('groups', '分组更新成功', 'success');
+            loadGroupsView();
+        } catch (error) {
+            showMessage('groups', `更新失败: ${error.message}`, 'error');
+        } finally {
+            hideLoading();
+        }
+    });
+}
+
+// Delete group
+function deleteGroup(id) {
+    const group = userGroupsCache.find(g => g.id === id);
+    showConfirmDialog(
+        '确认删除分组',
+        `您确定要删除分组 ${group ? group.name : id} 吗？成员用户不会被删除。`,
+        async () => {
+            showLoading();
+            try {
+                await apiRequest(`/groups/${id}`, { method: 'DELETE' });
+                showMessage('groups', '分组删除成功', 'success');
+                loadGroupsView();
+            } catch (error) {
+                showMessage('groups', `删除失败: ${error.message}`, 'error');
+            } finally {
+                hideLoading();
+            }
+        }
+    );
+}
+
 // Load tasks view
 async function loadTasksView() {
     const contentEl = document.getElementById('tasks-content');
     contentEl.innerHTML = '<div class="loading">加载中...</div>';
+    tasksMode = 'pending';
 
     try {
-        const response = await apiRequest('/tasks');
+        const [response, groupsResponse] = await Promise.all([
+            apiRequest(`/tasks?${listQuery('tasks')}`),
+            apiRequest('/groups')
+        ]);
         const tasks = response.data.tasks || [];
+        setListNextCursor('tasks', response.data.next_cursor);
+        userGroupsCache = groupsResponse.data.groups || [];
+        const groupOptions = userGroupsCache
+            .map(group => `<option value="${group.id}">${group.name} (${group.member_count})</option>`)
+            .join('');
 
         let html = `
-            <div style="margin-bottom: 2rem;">
+            <div class="requires-operator" style="margin-bottom: 2rem;">
                 <h3 style="margin-bottom: 1rem;">添加兑换码</h3>
                 <form id="add-giftcode-form" onsubmit="addGiftCode(event)">
                     <div class="form-group">
                         <label>兑换码 *</label>
                         <input type="text" name="code" required placeholder="请输入兑换码">
                     </div>
+                    <div class="form-group">
+                        <label>目标分组</label>
+                        <select name="group_id">
+                            <option value="">所有用户</option>
+                            ${groupOptions}
+                        </select>
+                    </div>
                     <button type="submit" class="btn">添加兑换码</button>
                 </form>
             </div>
 
+            <div class="requires-operator" style="margin-bottom: 2rem;">
+                <h3 style="margin-bottom: 1rem;">立即兑换</h3>
+                <form id="redeem-now-form" onsubmit="submitRedeemNow(event)">
+                    <div class="form-group">
+                        <label>兑换码 *</label>
+                        <input type="text" name="code" required placeholder="请输入兑换码">
+                    </div>
+                    <div class="form-group">
+                        <label>兑换用户</label>
+                        <select name="group_id">
+                            <option value="">所有用户</option>
+                            ${groupOptions}
+                        </select>
+                    </div>
+                    <div class="form-group">
+                        <label>指定 FID（可选，填写后忽略分组，可包括已禁用的用户）</label>
+                        <textarea name="fids" rows="2" placeholder="多个 FID 用空格、逗号或换行分隔"></textarea>
+                    </div>
+                    <button type="submit" class="btn">立即兑换</button>
+                </form>
+            </div>
+
             <div style="margin-bottom: 1rem; display: flex; gap: 1rem; align-items: center; flex-wrap: wrap;">
-                <h3 style="margin: 0;">任务列表 (${tasks.length})</h3>
-                <div style="display: flex; align-items: center; gap: 0.5rem;">
-                    <label for="refresh-interval" style="font-size: 0.9rem; color: #6c757d;">自动刷新:</label>
-                    <select id="refresh-interval" onchange="changeRefreshInterval(this.value)" style="padding: 0.25rem 0.5rem; border: 1px solid #ddd; border-radius: 4px; font-size: 0.9rem;">
-                        <option value="1000" ${refreshInterval === 1000 ? 'selected' : ''}>1秒</option>
-                        <option value="2000" ${refreshInterval === 2000 ? 'selected' : ''}>2秒</option>
-                        <option value="5000" ${refreshInterval === 5000 ? 'selected' : ''}>5秒</option>
-                        <option value="10000" ${refreshInterval === 10000 ? 'selected' : ''}>10秒</option>
-                        <option value="30000" ${refreshInterval === 30000 ? 'selected' : ''}>30秒</option>
-                    </select>
-                </div>
+                <h3 style="margin: 0;">任务列表</h3>
                 <button class="btn btn-secondary" onclick="loadCompletedTasksView()">历史任务</button>
+                <span id="tasks-live-progress" style="font-size: 0.9rem; color: #6c757d;">${escapeHtml(lastTaskProgress)}</span>
             </div>
+            ${renderListFilters('tasks', `
+                ${renderFilterInput('tasks', 'q', '兑换码', 'placeholder="搜索兑换码"')}
+                ${renderFilterSelect('tasks', 'status', '状态', [['pending', '未完成'], ['all', '全部']])}
+                ${renderFilterInput('tasks', 'since', '创建日期从', 'type="date"')}
+                ${renderFilterInput('tasks', 'until', '至', 'type="date"')}
+                ${renderSortSelect('tasks', [
+                    ['', '兑换码'],
+                    ['created_at:desc', '创建时间'],
+                    ['retry_count:desc', '重试次数']
+                ])}
+            `)}
         `;
 
         if (tasks.length === 0) {
@@ -528,11 +1150,13 @@ async function loadTasksView() {
                         <thead>
                             <tr>
                                 <th>兑换码</th>
+                                <th>目标分组</th>
                                 <th>状态</th>
                                 <th>重试次数</th>
                                 <th>错误信息</th>
                                 <th>创建时间</th>
                                 <th>完成时间</th>
+                                <th class="requires-operator">操作</th>
                             </tr>
                         </thead>
                         <tbody>
@@ -561,11 +1185,15 @@ async function loadTasksView() {
                 html += `
                     <tr>
                         <td>${task.code || '-'}</td>
+                        <td>${task.target_group || '所有用户'}</td>
                         <td><span class="status-badge status-${status}">${statusText}</span></td>
                         <td>${task.retry_count || 0}</td>
                         <td style="max-width: 300px; overflow: hidden; text-overflow: ellipsis; white-space: nowrap;" title="${error}">${error}</td>
                         <td>${createdAt}</td>
                         <td>${completedAt}</td>
+                        <td class="requires-operator">
+                            ${task.all_done ? '-' : `<button class="btn btn-secondary btn-sm" onclick="redeemTaskNow('${escapeHtml(task.code)}', ${task.target_group_id || 'null'})" style="padding: 0.25rem 0.5rem; font-size: 0.85rem;">立即兑换</button>`}
+                        </td>
                     </tr>
                 `;
             });
@@ -577,18 +1205,8 @@ async function loadTasksView() {
             `;
         }
 
+        html += renderPager('tasks');
         contentEl.innerHTML = html;
-
-        // Setup auto-refresh with selected interval
-        if (autoRefreshInterval) {
-            clearInterval(autoRefreshInterval);
-        }
-        autoRefreshInterval = setInterval(() => {
-            if (currentView === 'tasks') {
-                loadTasksView();
-            }
-        }, refreshInterval);
-
     } catch (error) {
         contentEl.innerHTML = '<div class="empty-state">加载失败，请重试</div>';
         showMessage('tasks', error.message, 'error');
@@ -605,7 +1223,7 @@ function renderTaskDeleteButton(task) {
     const taskCode = task.code || task.id || '';
     const taskName = task.code || '未命名任务';
     
-    return `<button class="btn btn-danger btn-sm" 
+    return `<button class="btn btn-danger btn-sm requires-operator" 
                     data-task-id="${taskCode}" 
                     data-task-name="${taskName}"
                     style="padding: 0.25rem 0.5rem; font-size: 0.85rem;">
@@ -641,7 +1259,7 @@ async function showDeleteConfirmation(taskId, taskName) {
         const body = document.createElement('div');
         body.className = 'modal-body';
         const message = document.createElement('p');
-        message.innerHTML = `您确定要删除以下任务吗？<br><br><strong>任务 ID:</strong> ${taskId}<br><strong>兑换码:</strong> ${taskName}<br><br>此操作将删除任务及其所有关联的兑换码记录，且无法撤销。`;
+        message.innerHTML = `您确定要删除以下任务吗？<br><br><strong>任务 ID:</strong> ${taskId}<br><strong>兑换码:</strong> ${taskName}<br><br>任务将移至回收站，关联的兑换码记录会保留，可在回收站中恢复。`;
         body.appendChild(message);
         
         // Modal footer
@@ -879,21 +1497,29 @@ async function loadCompletedTasksView() {
     const contentEl = document.getElementById('tasks-content');
     contentEl.innerHTML = '<div class="loading">加载中...</div>';
 
-    // Clear auto-refresh when viewing completed tasks
-    if (autoRefreshInterval) {
-        clearInterval(autoRefreshInterval);
-        autoRefreshInterval = null;
-    }
+    tasksMode = 'completed';
 
     try {
-        const response = await apiRequest('/tasks/completed?limit=100');
+        const response = await apiRequest(`/tasks/completed?${listQuery('completed')}`);
         const tasks = response.data.tasks || [];
+        setListNextCursor('completed', response.data.next_cursor);
 
         let html = `
             <div style="margin-bottom: 1rem; display: flex; gap: 1rem; align-items: center;">
-                <h3 style="margin: 0;">历史任务列表 (${tasks.length})</h3>
+                <h3 style="margin: 0;">历史任务列表</h3>
                 <button class="btn btn-secondary" onclick="loadTasksView()">返回当前任务</button>
             </div>
+            ${renderListFilters('completed', `
+                ${renderFilterInput('completed', 'q', '兑换码', 'placeholder="搜索兑换码"')}
+                ${renderFilterInput('completed', 'since', '完成日期从', 'type="date"')}
+                ${renderFilterInput('completed', 'until', '至', 'type="date"')}
+                ${renderSortSelect('completed', [
+                    ['', '完成时间'],
+                    ['created_at:desc', '创建时间'],
+                    ['code:asc', '兑换码'],
+                    ['retry_count:desc', '重试次数']
+                ])}
+            `)}
         `;
 
         if (tasks.length === 0) {
@@ -944,6 +1570,7 @@ async function loadCompletedTasksView() {
             `;
         }
 
+        html += renderPager('completed');
         contentEl.innerHTML = html;
         
         // Bind delete button click events using event delegation
@@ -974,6 +1601,7 @@ async function addGiftCode(event) {
     
     const formData = new FormData(form);
     const code = formData.get('code').trim();
+    const groupId = formData.get('group_id');
 
     // Validate input
     if (!code) {
@@ -987,7 +1615,7 @@ async function addGiftCode(event) {
     try {
         await apiRequest('/tasks', {
             method: 'POST',
-            body: JSON.stringify({ code })
+            body: JSON.stringify({ code, group_id: groupId ? parseInt(groupId, 10) : null })
         });
 
         showMessage('tasks', '兑换码添加成功', 'success');
@@ -1021,10 +1649,61 @@ async function showUserDetails(fid) {
     contentEl.innerHTML = '<div class="loading">加载中...</div>';
 
     try {
-        const response = await apiRequest(`/users/${fid}/codes`);
+        const [response, detailResponse] = await Promise.all([
+            apiRequest(`/users/${fid}/codes`),
+            apiRequest(`/users/${fid}`)
+        ]);
         const records = response.data.records || [];
+        const user = detailResponse.data.user || {};
+        const history = detailResponse.data.profile_history || [];
+
+        let html = `
+            <h3 style="margin-bottom: 1rem;">用户 ${fid}: ${user.nickname || '-'} (区服 ${user.kid || '-'})</h3>
+            <h3 style="margin-bottom: 1rem;">资料变更记录 (${history.length})</h3>
+        `;
+
+        if (history.length === 0) {
+            html += '<div class="empty-state">暂无改名、转区或头像变更记录</div>';
+        } else {
+            const fieldNames = { nickname: '昵称', kid: '区服', avatar_image: '头像' };
+            html += `
+                <div class="table-container" style="margin-bottom: 2rem;">
+                    <table>
+                        <thead>
+                            <tr>
+                                <th>变更时间</th>
+                                <th>字段</th>
+                                <th>原值</th>
+                                <th>新值</th>
+                            </tr>
+                        </thead>
+                        <tbody>
+            `;
+
+            history.forEach(change => {
+                const changedAt = change.changed_at ? new Date(change.changed_at).toLocaleString('zh-CN') : '-';
+                const isAvatar = change.field === 'avatar_image';
+                const oldValue = isAvatar ? `<img src="${change.old_value}" class="avatar" alt="原头像">` : change.old_value;
+                const newValue = isAvatar ? `<img src="${change.new_value}" class="avatar" alt="新头像">` : change.new_value;
+
+                html += `
+                    <tr>
+                        <td>${changedAt}</td>
+                        <td>${fieldNames[change.field] || change.field}</td>
+                        <td>${oldValue}</td>
+                        <td>${newValue}</td>
+                    </tr>
+                `;
+            });
+
+            html += `
+                        </tbody>
+                    </table>
+                </div>
+            `;
+        }
 
-        let html = `<h3 style="margin-bottom: 1rem;">用户 ${fid} 的兑换记录 (${records.length})</h3>`;
+        html += `<h3 style="margin-bottom: 1rem;">兑换记录 (${records.length})</h3>`;
 
         if (records.length === 0) {
             html += '<div class="empty-state">该用户暂无兑换记录</div>';
@@ -1079,10 +1758,22 @@ async function loadNotificationsView() {
     contentEl.innerHTML = '<div class="loading">加载中...</div>';
 
     try {
-        const response = await apiRequest('/notifications?limit=100');
+        const response = await apiRequest(`/notifications?${listQuery('notifications')}`);
         const notifications = response.data.notifications || [];
+        setListNextCursor('notifications', response.data.next_cursor);
 
-        let html = `<h3 style="margin-bottom: 1rem;">通知历史 (${notifications.length})</h3>`;
+        let html = `
+            <h3 style="margin-bottom: 1rem;">通知历史</h3>
+            ${renderListFilters('notifications', `
+                ${renderFilterInput('notifications', 'q', '搜索', 'placeholder="FID、标题或内容"')}
+                ${renderFilterSelect('notifications', 'status', '状态', [['', '全部'], ['pending', '等待发送'], ['success', '成功'], ['failed', '失败']])}
+                ${renderFilterInput('notifications', 'channel', '渠道', 'placeholder="如 webhook" style="width: 8rem;"')}
+                ${renderFilterInput('notifications', 'event', '事件', 'placeholder="如 task_completed" style="width: 10rem;"')}
+                ${renderFilterInput('notifications', 'since', '日期从', 'type="date"')}
+                ${renderFilterInput('notifications', 'until', '至', 'type="date"')}
+                ${renderSortSelect('notifications', [['', '时间'], ['attempts:desc', '尝试次数']])}
+            `)}
+        `;
 
         if (notifications.length === 0) {
             html += '<div class="empty-state">暂无通知记录</div>';
@@ -1093,11 +1784,14 @@ async function loadNotificationsView() {
                         <thead>
                             <tr>
                                 <th>渠道</th>
+                                <th>事件</th>
                                 <th>标题</th>
                                 <th>内容</th>
                                 <th>时间</th>
                                 <th>状态</th>
+                                <th>尝试</th>
                                 <th>结果</th>
+                                <th>操作</th>
                             </tr>
                         </thead>
                         <tbody>
@@ -1107,8 +1801,12 @@ async function loadNotificationsView() {
                 const createdAt = notif.created_at ? 
                     new Date(notif.created_at).toLocaleString('zh-CN') : '-';
 
-                const status = notif.status === 'success' ? 'completed' : 'failed';
-                const statusText = notif.status === 'success' ? '成功' : '失败';
+                const statusMap = {
+                    pending: ['pending', '等待发送'],
+                    success: ['completed', '成功'],
+                    failed: ['failed', '失败'],
+                };
+                const [status, statusText] = statusMap[notif.status] || ['failed', notif.status];
 
                 // Truncate long content
                 const content = notif.content.length > 50 ? 
@@ -1119,11 +1817,16 @@ async function loadNotificationsView() {
                 html += `
                     <tr>
                         <td>${notif.channel || '-'}</td>
+                        <td>${notif.event || '-'}</td>
                         <td>${notif.title || '-'}</td>
                         <td title="${notif.content}">${content}</td>
                         <td>${createdAt}</td>
                         <td><span class="status-badge status-${status}">${statusText}</span></td>
+                        <td>${notif.attempts || 0}</td>
                         <td title="${notif.result}">${result}</td>
+                        <td>
+                            ${notif.status === 'failed' ? `<button class="btn btn-secondary btn-sm requires-operator" onclick="resendNotification(${notif.id})" style="padding: 0.25rem 0.5rem; font-size: 0.85rem;">重新发送</button>` : '-'}
+                        </td>
                     </tr>
                 `;
             });
@@ -1135,9 +1838,1692 @@ async function loadNotificationsView() {
             `;
         }
 
+        html += renderPager('notifications');
         contentEl.innerHTML = html;
     } catch (error) {
         contentEl.innerHTML = '<div class="empty-state">加载失败，请重试</div>';
         showMessage('notifications', error.message, 'error');
     }
 }
+
+// Resend a failed notification
+async function resendNotification(id) {
+    try {
+        await apiRequest(`/notifications/${id}/resend`, { method: 'POST' });
+        showMessage('notifications', '已重新加入发送队列', 'success');
+        loadNotificationsView();
+    } catch (error) {
+        showMessage('notifications', error.message, 'error');
+    }
+}
+
+// Load trash view
+async function loadTrashView() {
+    const contentEl = document.getElementById('trash-content');
+    contentEl.innerHTML = '<div class="loading">加载中...</div>';
+
+    try {
+        const response = await apiRequest('/trash');
+        const tasks = response.data.tasks || [];
+        const users = response.data.users || [];
+
+        let html = `<h3 style="margin-bottom: 1rem;">已删除任务 (${tasks.length})</h3>`;
+
+        if (tasks.length === 0) {
+            html += '<div class="empty-state">回收站中暂无任务</div>';
+        } else {
+            html += `
+                <div class="table-container">
+                    <table>
+                        <thead>
+                            <tr>
+                                <th>兑换码</th>
+                                <th>状态</th>
+                                <th>创建时间</th>
+                                <th>删除时间</th>
+                                <th>操作</th>
+                            </tr>
+                        </thead>
+                        <tbody>
+            `;
+
+            tasks.forEach(task => {
+                const createdAt = task.created_at ? new Date(task.created_at).toLocaleString('zh-CN') : '-';
+                const deletedAt = task.deleted_at ? new Date(task.deleted_at).toLocaleString('zh-CN') : '-';
+                const status = task.all_done ? 'completed' : 'pending';
+                const statusText = task.all_done ? '已完成' : '未完成';
+
+                html += `
+                    <tr>
+                        <td>${task.code || '-'}</td>
+                        <td><span class="status-badge status-${status}">${statusText}</span></td>
+                        <td>${createdAt}</td>
+                        <td>${deletedAt}</td>
+                        <td>
+                            <button class="btn btn-sm requires-operator" onclick="restoreTrashItem('tasks', '${task.code}')" style="padding: 0.25rem 0.5rem; font-size: 0.85rem;">恢复</button>
+                            <button class="btn btn-danger btn-sm requires-owner" onclick="purgeTrashItem('tasks', '${task.code}')" style="padding: 0.25rem 0.5rem; font-size: 0.85rem;">彻底删除</button>
+                        </td>
+                    </tr>
+                `;
+            });
+
+            html += `
+                        </tbody>
+                    </table>
+                </div>
+            `;
+        }
+
+        html += `<h3 style="margin: 2rem 0 1rem;">已删除用户 (${users.length})</h3>`;
+
+        if (users.length === 0) {
+            html += '<div class="empty-state">回收站中暂无用户</div>';
+        } else {
+            html += `
+                <div class="table-container">
+                    <table>
+                        <thead>
+                            <tr>
+                                <th>用户ID (FID)</th>
+                                <th>昵称</th>
+                                <th>KID</th>
+                                <th>删除时间</th>
+                                <th>操作</th>
+                            </tr>
+                        </thead>
+                        <tbody>
+            `;
+
+            users.forEach(user => {
+                const deletedAt = user.deleted_at ? new Date(user.deleted_at).toLocaleString('zh-CN') : '-';
+
+                html += `
+                    <tr>
+                        <td>${user.fid || '-'}</td>
+                        <td>${user.nickname || '-'}</td>
+                        <td>${user.kid || '-'}</td>
+                        <td>${deletedAt}</td>
+                        <td>
+                            <button class="btn btn-sm requires-operator" onclick="restoreTrashItem('users', '${user.fid}')" style="padding: 0.25rem 0.5rem; font-size: 0.85rem;">恢复</button>
+                            <button class="btn btn-danger btn-sm requires-owner" onclick="purgeTrashItem('users', '${user.fid}')" style="padding: 0.25rem 0.5rem; font-size: 0.85rem;">彻底删除</button>
+                        </td>
+                    </tr>
+                `;
+            });
+
+            html += `
+                        </tbody>
+                    </table>
+                </div>
+            `;
+        }
+
+        contentEl.innerHTML = html;
+    } catch (error) {
+        contentEl.innerHTML = '<div class="empty-state">加载失败，请重试</div>';
+        showMessage('trash', error.message, 'error');
+    }
+}
+
+/**
+ * Restore a task or user from trash
+ * @param {string} kind - 'tasks' or 'users'
+ * @param {string} id - Task code or user FID
+ */
+async function restoreTrashItem(kind, id) {
+    showLoading();
+    try {
+        await apiRequest(`/${kind}/${encodeURIComponent(id)}/restore`, { method: 'POST' });
+        showMessage('trash', '恢复成功', 'success');
+        loadTrashView();
+    } catch (error) {
+        showMessage('trash', `恢复失败: ${error.message}`, 'error');
+    } finally {
+        hideLoading();
+    }
+}
+
+/**
+ * Permanently delete a task or user from trash
+ * @param {string} kind - 'tasks' or 'users'
+ * @param {string} id - Task code or user FID
+ */
+function purgeTrashItem(kind, id) {
+    const target = kind === 'tasks' ? `任务 ${id}` : `用户 ${id}`;
+    showConfirmDialog(
+        '确认彻底删除',
+        `${target} 及其所有兑换码记录将被彻底删除，且无法撤销。`,
+        async () => {
+            showLoading();
+            try {
+                await apiRequest(`/trash/${kind}/${encodeURIComponent(id)}`, { method: 'DELETE' });
+                showMessage('trash', '已彻底删除', 'success');
+                loadTrashView();
+            } catch (error) {
+                showMessage('trash', `删除失败: ${error.message}`, 'error');
+            } finally {
+                hideLoading();
+            }
+        }
+    );
+}
+
+// ============================================
+// Redeem Now
+// ============================================
+
+// Parse a Server-Sent Events stream, calling onEvent(name, data) with the JSON data of each event
+async function readEventStream(response, onEvent) {
+    const reader = response.body.pipeThrough(new TextDecoderStream()).getReader();
+    let buffer = '';
+    while (true) {
+        const { value, done } = await reader.read();
+        if (done) break;
+        buffer += value;
+
+        let index;
+        while ((index = buffer.indexOf('\n\n')) >= 0) {
+            const block = buffer.slice(0, index);
+            buffer = buffer.slice(index + 2);
+
+            let name = '';
+            let data = '';
+            block.split('\n').forEach(line => {
+                if (line.startsWith('event:')) name = line.slice(6).trim();
+                else if (line.startsWith('data:')) data += line.slice(5);
+            });
+            // Comment-only blocks are heartbeats
+            if (!name && !data) continue;
+            onEvent(name || 'message', data ? JSON.parse(data) : null);
+        }
+    }
+}
+
+// Submit the redeem now form
+function submitRedeemNow(event) {
+    event.preventDefault();
+
+    const formData = new FormData(event.target);
+    const code = formData.get('code').trim();
+    if (!code) {
+        showMessage('tasks', '兑换码不能为空', 'error');
+        return;
+    }
+
+    const fids = (formData.get('fids') || '').split(/[\s,，]+/).filter(Boolean);
+    const groupId = formData.get('group_id');
+    const body = { code };
+    if (fids.length > 0) {
+        body.fids = fids;
+    } else if (groupId) {
+        body.group_id = parseInt(groupId, 10);
+    }
+    startRedeemNow(body);
+}
+
+// Redeem a pending task now for the users of its target group
+function redeemTaskNow(code, groupId) {
+    showConfirmDialog(
+        '确认立即兑换',
+        `立即为${groupId ? '目标分组中' : '所有'}未禁用的用户兑换 ${code}？`,
+        () => startRedeemNow(groupId ? { code, group_id: groupId } : { code })
+    );
+}
+
+// Redeem a code now and show per-user results in a modal as they arrive
+// Closing the modal before completion stops redeeming for the remaining users
+async function startRedeemNow(body) {
+    const controller = new AbortController();
+    let finished = false;
+
+    const overlay = document.createElement('div');
+    overlay.className = 'modal-overlay show';
+    overlay.innerHTML = `
+        <div class="modal" style="max-width: 720px; width: 90%;">
+            <div class="modal-header"><h3></h3></div>
+            <div class="modal-body">
+                <div style="background: #e9ecef; border-radius: 4px; height: 8px; overflow: hidden; margin-bottom: 0.5rem;">
+                    <div data-role="bar" style="background: #28a745; height: 100%; width: 0; transition: width 0.2s;"></div>
+                </div>
+                <div data-role="summary" style="margin-bottom: 1rem; color: #6c757d;">正在连接...</div>
+                <div class="table-container" style="max-height: 50vh; overflow-y: auto;">
+                    <table>
+                        <thead>
+                            <tr>
+                                <th>FID</th>
+                                <th>昵称</th>
+                                <th>区服</th>
+                                <th>结果</th>
+                            </tr>
+                        </thead>
+                        <tbody data-role="results"></tbody>
+                    </table>
+                </div>
+            </div>
+            <div class="modal-footer">
+                <button type="button" class="btn btn-secondary" data-action="close">停止并关闭</button>
+            </div>
+        </div>
+    `;
+    overlay.querySelector('h3').textContent = `立即兑换 ${body.code}`;
+    const bar = overlay.querySelector('[data-role="bar"]');
+    const summary = overlay.querySelector('[data-role="summary"]');
+    const results = overlay.querySelector('[data-role="results"]');
+    const closeButton = overlay.querySelector('[data-action="close"]');
+
+    closeButton.onclick = () => {
+        if (!finished) controller.abort();
+        document.body.removeChild(overlay);
+        if (currentView === 'tasks') loadTasksView();
+    };
+    document.body.appendChild(overlay);
+
+    let success = 0;
+    let failed = 0;
+    try {
+        const response = await AuthInterceptor.fetch(`${API_BASE}/redeem`, {
+            method: 'POST',
+            body: JSON.stringify(body),
+            signal: controller.signal
+        });
+        if (!response.ok) {
+            const data = await response.json().catch(() => ({}));
+            throw new Error(data.error?.message || 'Request failed');
+        }
+
+        await readEventStream(response, (name, data) => {
+            if (name === 'start') {
+                summary.textContent = `共 ${data.total} 个用户，兑换中...`;
+            } else if (name === 'result') {
+                data.success ? success++ : failed++;
+                bar.style.width = `${(data.done / data.total) * 100}%`;
+                summary.textContent = `${data.done} / ${data.total}，成功 ${success}，失败 ${failed}`;
+                const status = data.success ? 'completed' : 'failed';
+                results.insertAdjacentHTML('beforeend', `
+                    <tr>
+                        <td>${escapeHtml(data.fid)}</td>
+                        <td>${escapeHtml(data.nickname || '-')}</td>
+                        <td>${data.kid || '-'}</td>
+                        <td><span class="status-badge status-${status}">${escapeHtml(data.message)}</span></td>
+                    </tr>
+                `);
+            } else if (name === 'done') {
+                finished = true;
+                bar.style.width = '100%';
+                summary.textContent = `兑换完成：共 ${data.total} 个用户，成功 ${data.success}，失败 ${data.failed}`;
+            }
+        });
+        if (!finished) {
+            summary.textContent += '（连接已断开，兑换可能未全部完成）';
+        }
+    } catch (error) {
+        if (error.name === 'AbortError' || error.message === 'UNAUTHORIZED' || error.message === 'INVALID_TOKEN_FORMAT') return;
+        summary.textContent = `兑换失败: ${error.message}`;
+    } finally {
+        finished = true;
+        closeButton.textContent = '关闭';
+    }
+}
+
+// ============================================
+// Live Updates
+// ============================================
+
+// Views reloaded when an event whose type starts with the prefix arrives
+const LIVE_VIEWS_BY_EVENT = [
+    ['task.changed', ['tasks', 'trash', 'stats']],
+    ['user.changed', ['users', 'trash']],
+    ['notification.', ['notifications']]
+];
+const LIVE_RECONNECT_DELAY = 5000;
+const LIVE_REFRESH_DELAY = 500;
+
+const liveRefreshTimers = {};
+const liveRunningJobs = new Set();
+let liveConnected = false;
+let liveReconnectNow = false;
+let lastTaskProgress = '';
+
+// Keep the event stream open while the page is, reconnecting when it drops
+async function connectLiveEvents() {
+    while (true) {
+        liveReconnectNow = false;
+        try {
+            const response = await AuthInterceptor.fetch(`${API_BASE}/events`);
+            // Accounts that still have to enable two-factor authentication cannot subscribe
+            if (response.status === 403) return;
+            if (!response.ok) throw new Error(`HTTP ${response.status}`);
+            setLiveConnected(true);
+            await readEventStream(response, handleLiveEvent);
+        } catch (error) {
+            if (error.message === 'UNAUTHORIZED' || error.message === 'INVALID_TOKEN_FORMAT') return;
+            console.error('Event stream failed:', error);
+        }
+        setLiveConnected(false);
+        // The stream ends when the access token expires; reconnect at once to renew it
+        if (!liveReconnectNow) {
+            await new Promise(resolve => setTimeout(resolve, LIVE_RECONNECT_DELAY));
+        }
+    }
+}
+
+// Handle an event pushed by the server
+function handleLiveEvent(name, event) {
+    switch (name) {
+        case 'ready':
+            return;
+        case 'expired':
+            liveReconnectNow = true;
+            return;
+        case 'overflow':
+            // Events were dropped; reload the current view to catch up
+            scheduleLiveRefresh(currentView);
+            return;
+        case 'job.started':
+            liveRunningJobs.add(event.data.job);
+            renderLiveStatus();
+            return;
+        case 'job.finished':
+            liveRunningJobs.delete(event.data.job);
+            renderLiveStatus();
+            return;
+        case 'task.progress':
+            showTaskProgress(event.data);
+            return;
+    }
+
+    LIVE_VIEWS_BY_EVENT.forEach(([prefix, views]) => {
+        if (name.startsWith(prefix) && views.includes(currentView)) {
+            scheduleLiveRefresh(currentView);
+        }
+    });
+}
+
+// Reload a view shortly, coalescing bursts of events into one request
+function scheduleLiveRefresh(view) {
+    if (liveRefreshTimers[view]) return;
+    liveRefreshTimers[view] = setTimeout(() => {
+        delete liveRefreshTimers[view];
+        if (currentView !== view) return;
+
+        // Don't wipe out a form the admin is filling in; try again later
+        const active = document.activeElement;
+        if (active && active.closest(`#${view}-view`) && ['INPUT', 'TEXTAREA', 'SELECT'].includes(active.tagName)) {
+            scheduleLiveRefresh(view);
+            return;
+        }
+        refreshLiveView(view);
+    }, LIVE_REFRESH_DELAY);
+}
+
+// Reload the content of a view, keeping its filters and page
+function refreshLiveView(view) {
+    if (view === 'users') {
+        loadUsersView();
+    } else if (view === 'tasks') {
+        if (tasksMode === 'completed') {
+            loadCompletedTasksView();
+        } else {
+            loadTasksView();
+        }
+    } else if (view === 'notifications') {
+        loadNotificationsView();
+    } else if (view === 'trash') {
+        loadTrashView();
+    } else if (view === 'stats') {
+        loadStatsView();
+    }
+}
+
+// Show the latest per-user result of the scheduled redeem job in the tasks view
+function showTaskProgress(progress) {
+    lastTaskProgress = `${progress.code} ${progress.index}/${progress.total}: ${progress.nickname || progress.fid} ${progress.result}`;
+    const el = document.getElementById('tasks-live-progress');
+    if (el) {
+        el.textContent = lastTaskProgress;
+    }
+}
+
+// Update the connection indicator in the header
+function setLiveConnected(connected) {
+    liveConnected = connected;
+    if (!connected) {
+        liveRunningJobs.clear();
+    }
+    renderLiveStatus();
+}
+
+function renderLiveStatus() {
+    const el = document.getElementById('live-status');
+    if (!el) return;
+
+    if (!liveConnected) {
+        el.className = 'live-status';
+        el.textContent = '未连接';
+    } else if (liveRunningJobs.size > 0) {
+        el.className = 'live-status running';
+        el.textContent = `${[...liveRunningJobs].join(', ')} 运行中`;
+    } else {
+        el.className = 'live-status connected';
+        el.textContent = '实时更新';
+    }
+}
+
+// ============================================
+// Admin Accounts
+// ============================================
+
+// Load admins view
+async function loadAdminsView() {
+    const contentEl = document.getElementById('admins-content');
+    contentEl.innerHTML = '<div class="loading">加载中...</div>';
+
+    try {
+        const response = await apiRequest('/admins');
+        const admins = response.data.admins || [];
+
+        let html = `
+            <div style="margin-bottom: 2rem;">
+                <h3 style="margin-bottom: 1rem;">添加管理员</h3>
+                <form id="add-admin-form" onsubmit="createAdmin(event)">
+                    <div class="form-group">
+                        <label>用户名 *</label>
+                        <input type="text" name="username" required placeholder="请输入用户名">
+                    </div>
+                    <div class="form-group">
+                        <label>密码 * (至少 8 位)</label>
+                        <input type="password" name="password" required minlength="8" autocomplete="new-password">
+                    </div>
+                    <div class="form-group">
+                        <label>角色</label>
+                        ${renderAdminRoleSelect('viewer')}
+                    </div>
+                    <button type="submit" class="btn">添加管理员</button>
+                </form>
+            </div>
+
+            <h3 style="margin-bottom: 1rem;">管理员列表 (${admins.length})</h3>
+            <div class="table-container">
+                <table>
+                    <thead>
+                        <tr>
+                            <th>用户名</th>
+                            <th>角色</th>
+                            <th>两步验证</th>
+                            <th>创建时间</th>
+                            <th>操作</th>
+                        </tr>
+                    </thead>
+                    <tbody>
+        `;
+
+        admins.forEach(admin => {
+            const isSelf = currentAdmin && currentAdmin.username === admin.username;
+            html += `
+                <tr>
+                    <td>${escapeHtml(admin.username)}${isSelf ? ' (当前账号)' : ''}</td>
+                    <td>${formatAdminRole(admin.role)}</td>
+                    <td>${admin.totp_enabled ? '已启用' : '未启用'}${admin.totp_required ? ' (强制)' : ''}</td>
+                    <td>${new Date(admin.created_at).toLocaleString('zh-CN')}</td>
+                    <td>
+                        <button class="btn btn-secondary btn-sm" data-admin-action="edit" data-username="${escapeHtml(admin.username)}" data-role="${admin.role}" data-totp-required="${admin.totp_required}" style="padding: 0.25rem 0.5rem; font-size: 0.85rem;">编辑</button>
+                        ${admin.totp_enabled ? `<button class="btn btn-secondary btn-sm" data-admin-action="reset-totp" data-username="${escapeHtml(admin.username)}" style="padding: 0.25rem 0.5rem; font-size: 0.85rem;">重置两步验证</button>` : ''}
+                        ${isSelf ? '' : `<button class="btn btn-secondary btn-sm" data-admin-action="revoke" data-username="${escapeHtml(admin.username)}" style="padding: 0.25rem 0.5rem; font-size: 0.85rem;">强制下线</button>`}
+                        ${isSelf ? '' : `<button class="btn btn-danger btn-sm" data-admin-action="delete" data-username="${escapeHtml(admin.username)}" style="padding: 0.25rem 0.5rem; font-size: 0.85rem;">删除</button>`}
+                    </td>
+                </tr>
+            `;
+        });
+
+        html += `
+                    </tbody>
+                </table>
+            </div>
+        `;
+
+        contentEl.innerHTML = html;
+
+        contentEl.querySelectorAll('[data-admin-action]').forEach(button => {
+            const username = button.dataset.username;
+            if (button.dataset.adminAction === 'edit') {
+                button.addEventListener('click', () => showEditAdminModal(username, button.dataset.role, button.dataset.totpRequired === 'true'));
+            } else if (button.dataset.adminAction === 'reset-totp') {
+                button.addEventListener('click', () => resetAdminTOTP(username));
+            } else if (button.dataset.adminAction === 'revoke') {
+                button.addEventListener('click', () => revokeAdminSessions(username));
+            } else {
+                button.addEventListener('click', () => deleteAdmin(username));
+            }
+        });
+    } catch (error) {
+        contentEl.innerHTML = `<div class="empty-state">加载失败: ${error.message}</div>`;
+    }
+}
+
+// Render admin role select
+function renderAdminRoleSelect(selected) {
+    const options = ['owner', 'operator', 'viewer']
+        .map(role => `<option value="${role}" ${role === selected ? 'selected' : ''}>${formatAdminRole(role)}</option>`)
+        .join('');
+    return `<select name="role">${options}</select>`;
+}
+
+// Create admin
+async function createAdmin(event) {
+    event.preventDefault();
+    const form = event.target;
+    if (!validateForm(form)) return;
+
+    const formData = new FormData(form);
+    showLoading();
+    try {
+        await apiRequest('/admins', {
+            method: 'POST',
+            body: JSON.stringify({
+                username: formData.get('username').trim(),
+                password: formData.get('password'),
+                role: formData.get('role')
+            })
+        });
+        showMessage('admins', '管理员添加成功', 'success');
+        loadAdminsView();
+    } catch (error) {
+        showMessage('admins', `添加失败: ${error.message}`, 'error');
+    } finally {
+        hideLoading();
+    }
+}
+
+// Show edit admin modal: change role, reset password or require two-factor authentication
+function showEditAdminModal(username, role, totpRequired) {
+    const body = `
+        <div class="form-group">
+            <label>角色</label>
+            ${renderAdminRoleSelect(role)}
+        </div>
+        <div class="form-group">
+            <label><input type="checkbox" name="totp_required" ${totpRequired ? 'checked' : ''}> 要求启用两步验证</label>
+        </div>
+        <div class="form-group">
+            <label>重置密码 (留空则不修改)</label>
+            <input type="password" name="password" minlength="8" autocomplete="new-password">
+        </div>
+    `;
+    showFormModal(`编辑管理员 ${username}`, body, async (formData) => {
+        const payload = { role: formData.get('role'), totp_required: formData.get('totp_required') === 'on' };
+        if (formData.get('password')) {
+            payload.password = formData.get('password');
+        }
+        showLoading();
+        try {
+            await apiRequest(`/admins/${encodeURIComponent(username)}`, {
+                method: 'PUT',
+                body: JSON.stringify(payload)
+            });
+            showMessage('admins', '管理员更新成功', 'success');
+            loadAdminsView();
+        } catch (error) {
+            showMessage('admins', `更新失败: ${error.message}`, 'error');
+        } finally {
+            hideLoading();
+        }
+    });
+}
+
+// Delete admin
+function deleteAdmin(username) {
+    showConfirmDialog(
+        '确认删除',
+        `确定要删除管理员 ${username} 吗？该账号将立即无法登录。`,
+        async () => {
+            showLoading();
+            try {
+                await apiRequest(`/admins/${encodeURIComponent(username)}`, { method: 'DELETE' });
+                showMessage('admins', '管理员已删除', 'success');
+                loadAdminsView();
+            } catch (error) {
+                showMessage('admins', `删除失败: ${error.message}`, 'error');
+            } finally {
+                hideLoading();
+            }
+        }
+    );
+}
+
+// Show change password modal for the signed-in admin
+function showChangePasswordModal() {
+    const body = `
+        <div class="form-group">
+            <label>当前密码 *</label>
+            <input type="password" name="current_password" required autocomplete="current-password">
+        </div>
+        <div class="form-group">
+            <label>新密码 * (至少 8 位)</label>
+            <input type="password" name="new_password" required minlength="8" autocomplete="new-password">
+        </div>
+    `;
+    showFormModal('修改密码', body, async (formData) => {
+        showLoading();
+        try {
+            await apiRequest('/me/password', {
+                method: 'PUT',
+                body: JSON.stringify({
+                    current_password: formData.get('current_password'),
+                    new_password: formData.get('new_password')
+                })
+            });
+            showMessage(currentView, '密码修改成功', 'success');
+        } catch (error) {
+            showMessage(currentView, `修改密码失败: ${error.message}`, 'error');
+        } finally {
+            hideLoading();
+        }
+    });
+}
+
+// Revoke all sessions of another admin
+function revokeAdminSessions(username) {
+    showConfirmDialog(
+        '确认强制下线',
+        `确定要撤销管理员 ${username} 的全部会话吗？其所有设备都需要重新登录。`,
+        async () => {
+            showLoading();
+            try {
+                const response = await apiRequest(`/admins/${encodeURIComponent(username)}/sessions`, { method: 'DELETE' });
+                showMessage('admins', `已撤销 ${response.data.revoked} 个会话`, 'success');
+            } catch (error) {
+                showMessage('admins', `操作失败: ${error.message}`, 'error');
+            } finally {
+                hideLoading();
+            }
+        }
+    );
+}
+
+// Reset the two-factor authentication of another admin who lost their authenticator
+function resetAdminTOTP(username) {
+    showConfirmDialog(
+        '确认重置两步验证',
+        `确定要重置管理员 ${username} 的两步验证吗？其验证器和恢复码将全部失效。`,
+        async () => {
+            showLoading();
+            try {
+                await apiRequest(`/admins/${encodeURIComponent(username)}/totp`, { method: 'DELETE' });
+                showMessage('admins', '两步验证已重置', 'success');
+                loadAdminsView();
+            } catch (error) {
+                showMessage('admins', `操作失败: ${error.message}`, 'error');
+            } finally {
+                hideLoading();
+            }
+        }
+    );
+}
+
+// ============================================
+// Login Security
+// ============================================
+
+// Load login security view: throttled identities and recent failed logins
+async function loadSecurityView() {
+    const contentEl = document.getElementById('security-content');
+    contentEl.innerHTML = '<div class="loading">加载中...</div>';
+
+    try {
+        const [lockoutsResponse, failuresResponse] = await Promise.all([
+            apiRequest('/security/lockouts'),
+            apiRequest('/security/login-failures?limit=100')
+        ]);
+        const lockouts = lockoutsResponse.data.lockouts || [];
+        const failures = failuresResponse.data.failures || [];
+
+        let html = `
+            <h3 style="margin-bottom: 1rem;">失败计数 (${lockouts.length})</h3>
+            <div class="table-container" style="margin-bottom: 2rem;">
+                <table>
+                    <thead>
+                        <tr>
+                            <th>类型</th>
+                            <th>IP / 用户名</th>
+                            <th>连续失败</th>
+                            <th>锁定次数</th>
+                            <th>最近失败</th>
+                            <th>状态</th>
+                            <th>操作</th>
+                        </tr>
+                    </thead>
+                    <tbody>
+        `;
+
+        if (lockouts.length === 0) {
+            html += '<tr><td colspan="7" class="empty-state">暂无失败记录</td></tr>';
+        }
+        lockouts.forEach(lockout => {
+            const status = lockout.locked
+                ? `<span class="status-badge status-failed">锁定至 ${new Date(lockout.locked_until).toLocaleString('zh-CN')}</span>`
+                : '未锁定';
+            html += `
+                <tr>
+                    <td>${lockout.kind === 'ip' ? 'IP' : '用户名'}</td>
+                    <td>${escapeHtml(lockout.identity)}</td>
+                    <td>${lockout.failures}</td>
+                    <td>${lockout.lockouts}</td>
+                    <td>${new Date(lockout.last_failed_at).toLocaleString('zh-CN')}</td>
+                    <td>${status}</td>
+                    <td>
+                        <button class="btn btn-secondary btn-sm" data-kind="${lockout.kind}" data-identity="${escapeHtml(lockout.identity)}" style="padding: 0.25rem 0.5rem; font-size: 0.85rem;">${lockout.locked ? '解除锁定' : '清除计数'}</button>
+                    </td>
+                </tr>
+            `;
+        });
+
+        html += `
+                    </tbody>
+                </table>
+            </div>
+
+            <h3 style="margin-bottom: 1rem;">最近失败的登录 (${failures.length})</h3>
+            <div class="table-container">
+                <table>
+                    <thead>
+                        <tr>
+                            <th>时间</th>
+                            <th>用户名</th>
+                            <th>IP</th>
+                            <th>浏览器</th>
+                            <th>原因</th>
+                        </tr>
+                    </thead>
+                    <tbody>
+        `;
+
+        if (failures.length === 0) {
+            html += '<tr><td colspan="5" class="empty-state">暂无失败记录</td></tr>';
+        }
+        failures.forEach(failure => {
+            html += `
+                <tr>
+                    <td>${new Date(failure.created_at).toLocaleString('zh-CN')}</td>
+                    <td>${escapeHtml(failure.username || '-')}</td>
+                    <td>${escapeHtml(failure.ip || '-')}</td>
+                    <td title="${escapeHtml(failure.user_agent)}">${escapeHtml(truncateText(failure.user_agent || '-', 60))}</td>
+                    <td>${formatLoginFailureReason(failure.reason)}</td>
+                </tr>
+            `;
+        });
+
+        html += `
+                    </tbody>
+                </table>
+            </div>
+        `;
+
+        contentEl.innerHTML = html;
+
+        contentEl.querySelectorAll('[data-kind]').forEach(button => {
+            button.addEventListener('click', () => unlockLogin(button.dataset.kind, button.dataset.identity));
+        });
+    } catch (error) {
+        contentEl.innerHTML = `<div class="empty-state">加载失败: ${error.message}</div>`;
+    }
+}
+
+// Format login failure reason for display
+function formatLoginFailureReason(reason) {
+    const reasons = {
+        invalid_credentials: '用户名或密码错误',
+        invalid_totp: '两步验证码错误',
+        blocked: '锁定或等待期间尝试'
+    };
+    return reasons[reason] || escapeHtml(reason);
+}
+
+// Clear the failure count of an IP or username, lifting any lockout
+function unlockLogin(kind, identity) {
+    showConfirmDialog(
+        '确认解除锁定',
+        `确定要清除${kind === 'ip' ? 'IP' : '用户名'} ${identity} 的失败计数吗？`,
+        async () => {
+            showLoading();
+            try {
+                await apiRequest(`/security/lockouts/${encodeURIComponent(kind)}/${encodeURIComponent(identity)}`, { method: 'DELETE' });
+                showMessage('security', '已解除锁定', 'success');
+                loadSecurityView();
+            } catch (error) {
+                showMessage('security', `操作失败: ${error.message}`, 'error');
+            } finally {
+                hideLoading();
+            }
+        }
+    );
+}
+
+// ============================================
+// API Keys
+// ============================================
+
+// Load API keys view: keys used by bots and scripts to call /giftcode and /add_user
+async function loadAPIKeysView() {
+    const contentEl = document.getElementById('apikeys-content');
+    contentEl.innerHTML = '<div class="loading">加载中...</div>';
+
+    try {
+        const response = await apiRequest('/api-keys');
+        const keys = response.data.api_keys || [];
+        const scopes = response.data.scopes || [];
+
+        const scopeCheckboxes = scopes.map(scope => `
+            <label style="display: inline-flex; align-items: center; gap: 0.25rem; margin-right: 1rem; font-weight: normal;">
+                <input type="checkbox" name="scopes" value="${scope}">
+                ${formatAPIKeyScope(scope)}
+            </label>
+        `).join('');
+
+        let html = `
+            <div style="margin-bottom: 2rem;">
+                <h3 style="margin-bottom: 1rem;">创建密钥</h3>
+                <p style="margin-bottom: 1rem; color: #6c757d;">调用方通过请求头 X-API-Key 传递密钥。密钥只在创建时显示一次。</p>
+                <form id="add-apikey-form" onsubmit="createAPIKey(event)">
+                    <div class="form-group">
+                        <label>名称 *</label>
+                        <input type="text" name="name" required placeholder="例如 discord-bot">
+                    </div>
+                    <div class="form-group">
+                        <label>权限 *</label>
+                        ${scopeCheckboxes}
+                    </div>
+                    <button type="submit" class="btn">创建密钥</button>
+                </form>
+            </div>
+
+            <h3 style="margin-bottom: 1rem;">密钥列表 (${keys.length})</h3>
+            <div class="table-container">
+                <table>
+                    <thead>
+                        <tr>
+                            <th>名称</th>
+                            <th>密钥</th>
+                            <th>权限</th>
+                            <th>使用次数</th>
+                            <th>最近使用</th>
+                            <th>创建</th>
+                            <th>状态</th>
+                            <th>操作</th>
+                        </tr>
+                    </thead>
+                    <tbody>
+        `;
+
+        if (keys.length === 0) {
+            html += '<tr><td colspan="8" class="empty-state">暂无密钥</td></tr>';
+        }
+        keys.forEach(key => {
+            const lastUsed = key.last_used_at
+                ? `${new Date(key.last_used_at).toLocaleString('zh-CN')} (${escapeHtml(key.last_used_ip)})`
+                : '从未使用';
+            const status = key.revoked_at
+                ? `<span class="status-badge status-failed">已撤销</span>`
+                : '有效';
+            html += `
+                <tr>
+                    <td>${escapeHtml(key.name)}</td>
+                    <td><code>${escapeHtml(key.prefix)}…</code></td>
+                    <td>${key.scopes.map(formatAPIKeyScope).join('、')}</td>
+                    <td>${key.usage_count}</td>
+                    <td>${lastUsed}</td>
+                    <td>${escapeHtml(key.created_by)}<br>${new Date(key.created_at).toLocaleString('zh-CN')}</td>
+                    <td>${status}</td>
+                    <td>
+                        ${key.revoked_at ? '' : `<button class="btn btn-danger btn-sm" data-apikey-id="${key.id}" data-apikey-name="${escapeHtml(key.name)}" style="padding: 0.25rem 0.5rem; font-size: 0.85rem;">撤销</button>`}
+                    </td>
+                </tr>
+            `;
+        });
+
+        html += `
+                    </tbody>
+                </table>
+            </div>
+        `;
+
+        contentEl.innerHTML = html;
+
+        contentEl.querySelectorAll('[data-apikey-id]').forEach(button => {
+            button.addEventListener('click', () => revokeAPIKey(button.dataset.apikeyId, button.dataset.apikeyName));
+        });
+    } catch (error) {
+        contentEl.innerHTML = `<div class="empty-state">加载失败: ${error.message}</div>`;
+    }
+}
+
+// Format API key scope for display
+function formatAPIKeyScope(scope) {
+    const scopes = {
+        'enqueue-code': '添加兑换码',
+        'add-user': '添加用户',
+        'read-only': '查询任务'
+    };
+    return scopes[scope] || escapeHtml(scope);
+}
+
+// Create API key and show it once
+async function createAPIKey(event) {
+    event.preventDefault();
+    const form = event.target;
+    if (!validateForm(form)) return;
+
+    const formData = new FormData(form);
+    const scopes = formData.getAll('scopes');
+    if (scopes.length === 0) {
+        showMessage('apikeys', '请至少选择一个权限', 'error');
+        return;
+    }
+
+    showLoading();
+    try {
+        const response = await apiRequest('/api-keys', {
+            method: 'POST',
+            body: JSON.stringify({
+                name: formData.get('name').trim(),
+                scopes: scopes
+            })
+        });
+        document.getElementById('apikeys-content').innerHTML = `
+            <p style="margin-bottom: 0.5rem;">密钥 ${escapeHtml(response.data.api_key.name)} 已创建。请立即复制保存，密钥只显示这一次。</p>
+            <div class="secret-box">${escapeHtml(response.data.key)}</div>
+            <button class="btn" onclick="loadAPIKeysView()">我已保存</button>
+        `;
+    } catch (error) {
+        showMessage('apikeys', `创建失败: ${error.message}`, 'error');
+    } finally {
+        hideLoading();
+    }
+}
+
+// Revoke API key
+function revokeAPIKey(id, name) {
+    showConfirmDialog(
+        '确认撤销',
+        `确定要撤销密钥 ${name} 吗？使用该密钥的脚本将立即无法调用接口。`,
+        async () => {
+            showLoading();
+            try {
+                await apiRequest(`/api-keys/${encodeURIComponent(id)}`, { method: 'DELETE' });
+                showMessage('apikeys', '密钥已撤销', 'success');
+                loadAPIKeysView();
+            } catch (error) {
+                showMessage('apikeys', `撤销失败: ${error.message}`, 'error');
+            } finally {
+                hideLoading();
+            }
+        }
+    );
+}
+
+// ============================================
+// Audit Log
+// ============================================
+
+// Current audit log filters, kept while switching views
+let auditFilters = { actor: '', action: '', target: '', since: '', until: '' };
+
+// Load audit log view: mutating admin actions matching the current filters
+async function loadAuditView() {
+    const contentEl = document.getElementById('audit-content');
+    contentEl.innerHTML = '<div class="loading">加载中...</div>';
+
+    const params = new URLSearchParams({ limit: '200' });
+    ['actor', 'action', 'target'].forEach(key => {
+        if (auditFilters[key]) params.set(key, auditFilters[key]);
+    });
+    setDateRangeParams(params, auditFilters.since, auditFilters.until);
+
+    try {
+        const response = await apiRequest(`/audit-log?${params}`);
+        const entries = response.data.entries || [];
+
+        let html = `
+            <form id="audit-filter-form" onsubmit="filterAuditLog(event)" style="display: flex; gap: 1rem; flex-wrap: wrap; align-items: flex-end; margin-bottom: 2rem;">
+                <div class="form-group" style="margin: 0;">
+                    <label>操作人</label>
+                    <input type="text" name="actor" value="${escapeHtml(auditFilters.actor)}" placeholder="用户名">
+                </div>
+                <div class="form-group" style="margin: 0;">
+                    <label>操作</label>
+                    <input type="text" name="action" value="${escapeHtml(auditFilters.action)}" placeholder="如 task.delete 或 task.">
+                </div>
+                <div class="form-group" style="margin: 0;">
+                    <label>对象</label>
+                    <input type="text" name="target" value="${escapeHtml(auditFilters.target)}" placeholder="兑换码、fid 等">
+                </div>
+                <div class="form-group" style="margin: 0;">
+                    <label>开始日期</label>
+                    <input type="date" name="since" value="${escapeHtml(auditFilters.since)}">
+                </div>
+                <div class="form-group" style="margin: 0;">
+                    <label>结束日期</label>
+                    <input type="date" name="until" value="${escapeHtml(auditFilters.until)}">
+                </div>
+                <button type="submit" class="btn">筛选</button>
+                <button type="button" class="btn btn-secondary" onclick="resetAuditFilters()">重置</button>
+            </form>
+
+            <h3 style="margin-bottom: 1rem;">操作记录 (${entries.length})</h3>
+            <div class="table-container">
+                <table>
+                    <thead>
+                        <tr>
+                            <th>时间</th>
+                            <th>操作人</th>
+                            <th>操作</th>
+                            <th>对象</th>
+                            <th>IP</th>
+                            <th>请求ID</th>
+                            <th>变更</th>
+                        </tr>
+                    </thead>
+                    <tbody>
+        `;
+
+        if (entries.length === 0) {
+            html += '<tr><td colspan="7" class="empty-state">暂无操作记录</td></tr>';
+        }
+        entries.forEach(entry => {
+            html += `
+                <tr>
+                    <td>${new Date(entry.created_at).toLocaleString('zh-CN')}</td>
+                    <td>${escapeHtml(entry.actor || '-')}</td>
+                    <td><code>${escapeHtml(entry.action)}</code></td>
+                    <td>${escapeHtml(entry.target || '-')}</td>
+                    <td>${escapeHtml(entry.ip || '-')}</td>
+                    <td title="${escapeHtml(entry.request_id)}">${escapeHtml(truncateText(entry.request_id || '-', 12))}</td>
+                    <td>${renderAuditChange(entry)}</td>
+                </tr>
+            `;
+        });
+
+        html += `
+                    </tbody>
+                </table>
+            </div>
+        `;
+
+        contentEl.innerHTML = html;
+    } catch (error) {
+        contentEl.innerHTML = `<div class="empty-state">加载失败: ${error.message}</div>`;
+    }
+}
+
+// Render the before/after snapshots of an audit entry
+function renderAuditChange(entry) {
+    if (!entry.before && !entry.after) return '-';
+
+    let html = '<details><summary>查看</summary>';
+    if (entry.before) {
+        html += `<div style="margin-top: 0.5rem;">变更前:</div><div class="secret-box">${escapeHtml(JSON.stringify(entry.before, null, 2))}</div>`;
+    }
+    if (entry.after) {
+        html += `<div style="margin-top: 0.5rem;">变更后:</div><div class="secret-box">${escapeHtml(JSON.stringify(entry.after, null, 2))}</div>`;
+    }
+    return html + '</details>';
+}
+
+// Apply audit log filters
+function filterAuditLog(event) {
+    event.preventDefault();
+    const formData = new FormData(event.target);
+    Object.keys(auditFilters).forEach(key => {
+        auditFilters[key] = (formData.get(key) || '').trim();
+    });
+    loadAuditView();
+}
+
+// Clear audit log filters
+function resetAuditFilters() {
+    auditFilters = { actor: '', action: '', target: '', since: '', until: '' };
+    loadAuditView();
+}
+
+// ============================================
+// Statistics
+// ============================================
+
+// Colors of the redemption results in the statistics charts
+const STATS_RESULTS = [
+    ['success', '兑换成功', 'var(--success-color)'],
+    ['received', '已兑换', 'var(--primary-color)'],
+    ['not_found', '兑换码不存在', 'var(--gray-400)'],
+    ['failed', '失败', 'var(--danger-color)']
+];
+
+let statsFilters = { interval: 'day', since: '', until: '' };
+
+// Load statistics view: redemption trend, per-code results, failures, coverage and captcha cost
+async function loadStatsView() {
+    const contentEl = document.getElementById('stats-content');
+    contentEl.innerHTML = '<div class="loading">加载中...</div>';
+
+    const range = new URLSearchParams();
+    setDateRangeParams(range, statsFilters.since, statsFilters.until);
+    const trend = new URLSearchParams(range);
+    trend.set('interval', statsFilters.interval);
+    trend.set('utc_offset', String(-new Date().getTimezoneOffset()));
+
+    try {
+        const [redemptions, codes, completion, failures, coverage, captcha] = await Promise.all([
+            apiRequest(`/stats/redemptions?${trend}`),
+            apiRequest(`/stats/codes?${range}`),
+            apiRequest(`/stats/completion?${range}`),
+            apiRequest(`/stats/failures?${range}`),
+            apiRequest('/stats/coverage?limit=50'),
+            apiRequest(`/stats/captcha?${range}`)
+        ]);
+
+        const total = redemptions.data.total;
+        const attempts = total.success + total.received + total.not_found + total.failed;
+        const successRate = attempts > 0 ? (total.success + total.received) / attempts : 0;
+
+        let html = `
+            <form id="stats-filter-form" onsubmit="filterStats(event)" style="display: flex; gap: 1rem; flex-wrap: wrap; align-items: flex-end; margin-bottom: 2rem;">
+                <div class="form-group" style="margin: 0;">
+                    <label>粒度</label>
+                    <select name="interval">
+                        <option value="day" ${statsFilters.interval === 'day' ? 'selected' : ''}>按天</option>
+                        <option value="week" ${statsFilters.interval === 'week' ? 'selected' : ''}>按周</option>
+                    </select>
+                </div>
+                <div class="form-group" style="margin: 0;">
+                    <label>开始日期</label>
+                    <input type="date" name="since" value="${escapeHtml(statsFilters.since)}">
+                </div>
+                <div class="form-group" style="margin: 0;">
+                    <label>结束日期</label>
+                    <input type="date" name="until" value="${escapeHtml(statsFilters.until)}">
+                </div>
+                <button type="submit" class="btn">筛选</button>
+                <button type="button" class="btn btn-secondary" onclick="resetStatsFilters()">重置</button>
+            </form>
+
+            <div class="stats-cards">
+                ${renderStatsCard(attempts, '兑换请求')}
+                ${renderStatsCard(formatPercent(successRate), '成功率')}
+                ${renderStatsCard(formatSeconds(completion.data.completion.avg_seconds), `平均完成耗时 (${completion.data.completion.tasks} 个任务)`)}
+                ${renderStatsCard(captcha.data.total.cost.toFixed(2), `验证码费用 (${captcha.data.total.calls} 次识别)`)}
+            </div>
+
+            <h3 style="margin-bottom: 1rem;">兑换趋势</h3>
+            ${renderRedemptionChart(redemptions.data.buckets)}
+
+            <h3 style="margin: 2rem 0 1rem;">兑换码成功率</h3>
+            ${renderCodeStats(codes.data.codes)}
+
+            <h3 style="margin: 2rem 0 1rem;">失败原因</h3>
+            ${renderFailureReasons(failures.data.reasons)}
+
+            <h3 style="margin: 2rem 0 1rem;">用户领取覆盖率（最低 50 个）</h3>
+            ${renderCoverage(coverage.data.users)}
+
+            <h3 style="margin: 2rem 0 1rem;">验证码识别</h3>
+            ${renderCaptchaUsage(captcha.data.providers, captcha.data.total)}
+        `;
+
+        contentEl.innerHTML = html;
+    } catch (error) {
+        contentEl.innerHTML = `<div class="empty-state">加载失败: ${error.message}</div>`;
+    }
+}
+
+function renderStatsCard(value, label) {
+    return `
+        <div class="stats-card">
+            <div class="stats-card-value">${escapeHtml(String(value))}</div>
+            <div class="stats-card-label">${escapeHtml(label)}</div>
+        </div>
+    `;
+}
+
+function formatPercent(ratio) {
+    return `${(ratio * 100).toFixed(1)}%`;
+}
+
+// Format a duration in seconds as the largest fitting unit
+function formatSeconds(seconds) {
+    if (!seconds) return '-';
+    if (seconds < 60) return `${seconds.toFixed(0)} 秒`;
+    if (seconds < 3600) return `${(seconds / 60).toFixed(1)} 分钟`;
+    if (seconds < 86400) return `${(seconds / 3600).toFixed(1)} 小时`;
+    return `${(seconds / 86400).toFixed(1)} 天`;
+}
+
+// Horizontal bar whose width is value / max of the track
+function renderHBar(value, max, color) {
+    const width = max > 0 ? Math.max(1, Math.round(value / max * 100)) : 0;
+    const style = color ? ` background: ${color};` : '';
+    return `<div class="hbar-track"><div class="hbar" style="width: ${width}%;${style}"></div></div>`;
+}
+
+// Stacked bar chart of redemption results per day or week
+function renderRedemptionChart(buckets) {
+    const totals = buckets.map(bucket => STATS_RESULTS.reduce((sum, [key]) => sum + bucket[key], 0));
+    const max = Math.max(0, ...totals);
+    if (max === 0) {
+        return '<div class="empty-state">所选时间范围内暂无兑换请求</div>';
+    }
+
+    let html = '<div class="bar-legend">';
+    STATS_RESULTS.forEach(([, label, color]) => {
+        html += `<span style="--legend-color: ${color};">${label}</span>`;
+    });
+    html += '</div><div class="bar-chart">';
+    buckets.forEach((bucket, i) => {
+        const title = [bucket.period, ...STATS_RESULTS.map(([key, label]) => `${label}: ${bucket[key]}`)].join('\n');
+        html += `<div class="bar-stack" title="${escapeHtml(title)}">`;
+        STATS_RESULTS.forEach(([key, , color]) => {
+            if (bucket[key] > 0) {
+                html += `<div class="bar-segment" style="height: ${bucket[key] / max * 100}%; background: ${color};"></div>`;
+            }
+        });
+        html += '</div>';
+    });
+    html += `</div>
+        <div class="bar-labels">
+            <span>${escapeHtml(buckets[0].period)}</span>
+            <span>${escapeHtml(buckets[buckets.length - 1].period)}</span>
+        </div>`;
+    return html;
+}
+
+function renderCodeStats(codes) {
+    if (codes.length === 0) {
+        return '<div class="empty-state">暂无兑换请求</div>';
+    }
+    let html = `
+        <div class="table-container">
+            <table>
+                <thead>
+                    <tr>
+                        <th>兑换码</th>
+                        <th>请求数</th>
+                        <th>成功</th>
+                        <th>已兑换</th>
+                        <th>不存在</th>
+                        <th>失败</th>
+                        <th>用户数</th>
+                        <th>成功率</th>
+                    </tr>
+                </thead>
+                <tbody>
+    `;
+    codes.forEach(stat => {
+        html += `
+            <tr>
+                <td><code>${escapeHtml(stat.code)}</code></td>
+                <td>${stat.attempts}</td>
+                <td>${stat.success}</td>
+                <td>${stat.received}</td>
+                <td>${stat.not_found}</td>
+                <td>${stat.failed}</td>
+                <td>${stat.fids}</td>
+                <td title="${formatPercent(stat.success_rate)}">${renderHBar(stat.success_rate, 1, 'var(--success-color)')}</td>
+            </tr>
+        `;
+    });
+    return html + '</tbody></table></div>';
+}
+
+function renderFailureReasons(reasons) {
+    if (reasons.length === 0) {
+        return '<div class="empty-state">暂无失败记录</div>';
+    }
+    const max = Math.max(...reasons.map(reason => reason.count));
+    let html = `
+        <div class="table-container">
+            <table>
+                <thead>
+                    <tr>
+                        <th>原因</th>
+                        <th>次数</th>
+                        <th></th>
+                        <th>兑换码数</th>
+                        <th>用户数</th>
+                    </tr>
+                </thead>
+                <tbody>
+    `;
+    reasons.forEach(reason => {
+        html += `
+            <tr>
+                <td title="${escapeHtml(reason.reason)}">${escapeHtml(truncateText(reason.reason, 60))}</td>
+                <td>${reason.count}</td>
+                <td>${renderHBar(reason.count, max, 'var(--danger-color)')}</td>
+                <td>${reason.codes}</td>
+                <td>${reason.fids}</td>
+            </tr>
+        `;
+    });
+    return html + '</tbody></table></div>';
+}
+
+function renderCoverage(users) {
+    if (users.length === 0) {
+        return '<div class="empty-state">暂无用户</div>';
+    }
+    let html = `
+        <div class="table-container">
+            <table>
+                <thead>
+                    <tr>
+                        <th>FID</th>
+                        <th>昵称</th>
+                        <th>区服</th>
+                        <th>已领取 / 可领取</th>
+                        <th>覆盖率</th>
+                    </tr>
+                </thead>
+                <tbody>
+    `;
+    users.forEach(user => {
+        html += `
+            <tr>
+                <td>${escapeHtml(user.fid)}</td>
+                <td>${escapeHtml(user.nickname || '-')}${user.disabled ? ' <span class="text-muted">(已停用)</span>' : ''}</td>
+                <td>${user.kid || '-'}</td>
+                <td>${user.received} / ${user.available}</td>
+                <td title="${formatPercent(user.coverage)}">${renderHBar(user.coverage, 1)}</td>
+            </tr>
+        `;
+    });
+    return html + '</tbody></table></div>';
+}
+
+function renderCaptchaUsage(providers, total) {
+    if (providers.length === 0) {
+        return '<div class="empty-state">暂无验证码识别记录</div>';
+    }
+    let html = `
+        <div class="table-container">
+            <table>
+                <thead>
+                    <tr>
+                        <th>提供商</th>
+                        <th>识别次数</th>
+                        <th>失败次数</th>
+                        <th>估算费用</th>
+                    </tr>
+                </thead>
+                <tbody>
+    `;
+    [...providers, total].forEach(usage => {
+        const name = usage === total ? '<strong>合计</strong>' : escapeHtml(usage.provider);
+        html += `
+            <tr>
+                <td>${name}</td>
+                <td>${usage.calls}</td>
+                <td>${usage.failures}</td>
+                <td>${usage.cost.toFixed(2)}</td>
+            </tr>
+        `;
+    });
+    return html + '</tbody></table></div>';
+}
+
+// Apply statistics filters
+function filterStats(event) {
+    event.preventDefault();
+    const formData = new FormData(event.target);
+    Object.keys(statsFilters).forEach(key => {
+        statsFilters[key] = (formData.get(key) || '').trim();
+    });
+    loadStatsView();
+}
+
+// Clear statistics filters
+function resetStatsFilters() {
+    statsFilters = { interval: 'day', since: '', until: '' };
+    loadStatsView();
+}
+
+// ============================================
+// Two-Factor Authentication
+// ============================================
+
+// Load two-factor view: status of the signed-in admin
+async function loadTOTPView() {
+    const contentEl = document.getElementById('totp-content');
+    contentEl.innerHTML = '<div class="loading">加载中...</div>';
+
+    try {
+        const response = await apiRequest('/me/totp');
+        const status = response.data;
+
+        if (!status.enabled) {
+            contentEl.innerHTML = `
+                <p style="margin-bottom: 1rem;">两步验证未启用。启用后，登录时除密码外还需输入验证器应用（如 Google Authenticator、Microsoft Authenticator）生成的6位动态码。</p>
+                <button class="btn" onclick="startTOTPSetup()">启用两步验证</button>
+            `;
+            return;
+        }
+
+        contentEl.innerHTML = `
+            <p style="margin-bottom: 1rem;">两步验证已启用${status.required ? '（管理员要求启用，不能自行停用）' : ''}。剩余可用恢复码: ${status.recovery_codes_remaining} 个</p>
+            <button class="btn btn-secondary" onclick="showRegenerateRecoveryCodesModal()">重新生成恢复码</button>
+            ${status.required ? '' : '<button class="btn btn-danger" onclick="showDisableTOTPModal()">停用两步验证</button>'}
+        `;
+    } catch (error) {
+        contentEl.innerHTML = `<div class="empty-state">加载失败: ${error.message}</div>`;
+    }
+}
+
+// Start enrollment: show the secret and otpauth:// link, then confirm with a code
+async function startTOTPSetup() {
+    showLoading();
+    try {
+        const response = await apiRequest('/me/totp/setup', { method: 'POST' });
+        const enrollment = response.data;
+        document.getElementById('totp-content').innerHTML = `
+            <p style="margin-bottom: 0.5rem;">1. 在验证器应用中扫描由以下链接生成的二维码，或手动输入密钥：</p>
+            <div class="secret-box"><a href="${escapeHtml(enrollment.uri)}">${escapeHtml(enrollment.uri)}</a></div>
+            <div class="secret-box">${escapeHtml(enrollment.secret)}</div>
+            <p style="margin-bottom: 0.5rem;">2. 输入验证器显示的6位动态码完成启用：</p>
+            <form onsubmit="enableTOTP(event)">
+                <div class="form-group">
+                    <input type="text" name="code" required inputmode="numeric" autocomplete="one-time-code" placeholder="6位动态码">
+                </div>
+                <button type="submit" class="btn">确认启用</button>
+            </form>
+        `;
+    } catch (error) {
+        showMessage('totp', `操作失败: ${error.message}`, 'error');
+    } finally {
+        hideLoading();
+    }
+}
+
+// Confirm enrollment with the first code
+async function enableTOTP(event) {
+    event.preventDefault();
+    const form = event.target;
+    if (!validateForm(form)) return;
+
+    showLoading();
+    try {
+        const response = await apiRequest('/me/totp/enable', {
+            method: 'POST',
+            body: JSON.stringify({ code: new FormData(form).get('code').trim() })
+        });
+        if (currentAdmin) {
+            currentAdmin.totp_enabled = true;
+        }
+        showRecoveryCodes(response.data.recovery_codes);
+        showMessage('totp', '两步验证已启用', 'success');
+    } catch (error) {
+        showMessage('totp', `启用失败: ${error.message}`, 'error');
+    } finally {
+        hideLoading();
+    }
+}
+
+// Show recovery codes once; they cannot be retrieved again
+function showRecoveryCodes(codes) {
+    document.getElementById('totp-content').innerHTML = `
+        <p style="margin-bottom: 0.5rem;">请妥善保存以下恢复码。验证器丢失时，每个恢复码可代替动态码登录一次。恢复码只显示这一次。</p>
+        <div class="secret-box">${codes.map(escapeHtml).join('\n')}</div>
+        <button class="btn" onclick="loadTOTPView()">我已保存</button>
+    `;
+}
+
+// Regenerate recovery codes after confirming a code
+function showRegenerateRecoveryCodesModal() {
+    const body = `
+        <div class="form-group">
+            <label>动态码 * (重新生成后旧的恢复码全部失效)</label>
+            <input type="text" name="code" required inputmode="numeric" autocomplete="one-time-code">
+        </div>
+    `;
+    showFormModal('重新生成恢复码', body, async (formData) => {
+        showLoading();
+        try {
+            const response = await apiRequest('/me/totp/recovery-codes', {
+                method: 'POST',
+                body: JSON.stringify({ code: formData.get('code').trim() })
+            });
+            showRecoveryCodes(response.data.recovery_codes);
+        } catch (error) {
+            showMessage('totp', `操作失败: ${error.message}`, 'error');
+        } finally {
+            hideLoading();
+        }
+    });
+}
+
+// Disable two-factor authentication with password and a code
+function showDisableTOTPModal() {
+    const body = `
+        <div class="form-group">
+            <label>当前密码 *</label>
+            <input type="password" name="password" required autocomplete="current-password">
+        </div>
+        <div class="form-group">
+            <label>动态码或恢复码 *</label>
+            <input type="text" name="code" required autocomplete="one-time-code">
+        </div>
+    `;
+    showFormModal('停用两步验证', body, async (formData) => {
+        showLoading();
+        try {
+            await apiRequest('/me/totp/disable', {
+                method: 'POST',
+                body: JSON.stringify({
+                    password: formData.get('password'),
+                    code: formData.get('code').trim()
+                })
+            });
+            showMessage('totp', '两步验证已停用', 'success');
+            loadTOTPView();
+        } catch (error) {
+            showMessage('totp', `停用失败: ${error.message}`, 'error');
+        } finally {
+            hideLoading();
+        }
+    });
+}
+
+// ============================================
+// Sessions
+// ============================================
+
+// Load sessions view: the signed-in admin's active sessions
+async function loadSessionsView() {
+    const contentEl = document.getElementById('sessions-content');
+    contentEl.innerHTML = '<div class="loading">加载中...</div>';
+
+    try {
+        const response = await apiRequest('/me/sessions');
+        const sessions = response.data.sessions || [];
+
+        let html = `
+            <div style="margin-bottom: 1rem;">
+                <button class="btn btn-danger" onclick="revokeOtherSessions()" ${sessions.length <= 1 ? 'disabled' : ''}>退出其他所有会话</button>
+            </div>
+
+            <h3 style="margin-bottom: 1rem;">有效会话 (${sessions.length})</h3>
+            <div class="table-container">
+                <table>
+                    <thead>
+                        <tr>
+                            <th>IP</th>
+                            <th>浏览器</th>
+                            <th>登录时间</th>
+                            <th>最近使用</th>
+                            <th>过期时间</th>
+                            <th>操作</th>
+                        </tr>
+                    </thead>
+                    <tbody>
+        `;
+
+        sessions.forEach(session => {
+            html += `
+                <tr>
+                    <td>${escapeHtml(session.ip || '-')}</td>
+                    <td title="${escapeHtml(session.user_agent)}">${escapeHtml(truncateText(session.user_agent || '-', 60))}</td>
+                    <td>${new Date(session.created_at).toLocaleString('zh-CN')}</td>
+                    <td>${new Date(session.last_used_at).toLocaleString('zh-CN')}</td>
+                    <td>${new Date(session.expires_at).toLocaleString('zh-CN')}</td>
+                    <td>
+                        ${session.current ? '当前会话' : `<button class="btn btn-secondary btn-sm" onclick="revokeSession('${session.id}')" style="padding: 0.25rem 0.5rem; font-size: 0.85rem;">退出</button>`}
+                    </td>
+                </tr>
+            `;
+        });
+
+        html += `
+                    </tbody>
+                </table>
+            </div>
+        `;
+
+        contentEl.innerHTML = html;
+    } catch (error) {
+        contentEl.innerHTML = `<div class="empty-state">加载失败: ${error.message}</div>`;
+    }
+}
+
+// Truncate long text for table cells
+function truncateText(text, maxLength) {
+    return text.length > maxLength ? `${text.slice(0, maxLength)}…` : text;
+}
+
+// Revoke one session of the signed-in admin
+async function revokeSession(id) {
+    showLoading();
+    try {
+        await apiRequest(`/me/sessions/${encodeURIComponent(id)}`, { method: 'DELETE' });
+        showMessage('sessions', '会话已退出', 'success');
+        loadSessionsView();
+    } catch (error) {
+        showMessage('sessions', `操作失败: ${error.message}`, 'error');
+    } finally {
+        hideLoading();
+    }
+}
+
+// Revoke every session except the current one
+function revokeOtherSessions() {
+    showConfirmDialog(
+        '确认退出其他会话',
+        '其他设备和浏览器上的登录将全部失效，当前会话保留。',
+        async () => {
+            showLoading();
+            try {
+                const response = await apiRequest('/me/sessions', { method: 'DELETE' });
+                showMessage('sessions', `已退出 ${response.data.revoked} 个会话`, 'success');
+                loadSessionsView();
+            } catch (error) {
+                showMessage('sessions', `操作失败: ${error.message}`, 'error');
+            } finally {
+                hideLoading();
+            }
+        }
+    );
+}
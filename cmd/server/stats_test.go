@@ -0,0 +1,123 @@
+package main
+
+import (
+	"cdk-get/internal/api"
+	"cdk-get/internal/auth"
+	"cdk-get/internal/config"
+	"cdk-get/internal/storage"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestStatsEndpoints tests the aggregate statistics endpoints available to viewers
+func TestStatsEndpoints(t *testing.T) {
+	cfg := &config.Config{
+		Logging: config.LoggingConfig{
+			Level:  "error",
+			Format: "json",
+		},
+		Admin: config.AdminConfig{
+			TokenSecret:   "test-secret",
+			TokenDuration: 24 * time.Hour,
+		},
+	}
+
+	logger := logrus.New()
+	logger.SetLevel(logrus.ErrorLevel)
+
+	var gotFilter storage.StatsFilter
+	var gotOffset time.Duration
+	mockRepo := &storage.MockRepository{
+		GetAdminFunc: func(ctx context.Context, username string) (*storage.Admin, error) {
+			return &storage.Admin{Username: username, Role: storage.AdminRoleViewer}, nil
+		},
+		GetAdminSessionFunc: func(ctx context.Context, id string) (*storage.AdminSession, error) {
+			return &storage.AdminSession{ID: id, Username: "bob", ExpiresAt: time.Now().Add(time.Hour)}, nil
+		},
+		CountRedemptionsFunc: func(ctx context.Context, filter storage.StatsFilter, interval string, offset time.Duration) ([]*storage.RedemptionBucket, error) {
+			gotFilter, gotOffset = filter, offset
+			return []*storage.RedemptionBucket{
+				{Period: "2026-03-02", Success: 2, Failed: 1},
+				{Period: "2026-03-04", Received: 3},
+			}, nil
+		},
+	}
+
+	authService := auth.NewAuthService(mockRepo, cfg.Admin.TokenSecret, cfg.Admin.TokenDuration, 7*24*time.Hour, "cdk-get")
+	server := setupServer(cfg, api.NewHandlers(nil, nil, nil, nil, logger), api.NewAdminHandlers(authService, mockRepo, nil, nil, nil, nil, logger), authService, mockRepo, logger)
+
+	token, _, err := authService.GenerateToken("bob", storage.AdminRoleViewer, "bob-session")
+	require.NoError(t, err)
+
+	request := func(path string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		w := httptest.NewRecorder()
+		server.Handler.ServeHTTP(w, req)
+		return w
+	}
+
+	// 没有请求的时间段补零
+	w := request("/api/admin/stats/redemptions?since=2026-03-01T16:00:00Z&until=2026-03-05T16:00:00Z&utc_offset=480")
+	require.Equal(t, http.StatusOK, w.Code)
+	var resp struct {
+		Data struct {
+			Buckets []*storage.RedemptionBucket `json:"buckets"`
+			Total   storage.RedemptionBucket    `json:"total"`
+		} `json:"data"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	var periods []string
+	for _, bucket := range resp.Data.Buckets {
+		periods = append(periods, bucket.Period)
+	}
+	assert.Equal(t, []string{"2026-03-02", "2026-03-03", "2026-03-04", "2026-03-05"}, periods)
+	assert.Equal(t, int64(2), resp.Data.Buckets[0].Success)
+	assert.Equal(t, int64(0), resp.Data.Buckets[1].Success+resp.Data.Buckets[1].Failed)
+	assert.Equal(t, storage.RedemptionBucket{Success: 2, Received: 3, Failed: 1}, resp.Data.Total)
+	assert.Equal(t, 8*time.Hour, gotOffset)
+	assert.Equal(t, time.Date(2026, 3, 1, 16, 0, 0, 0, time.UTC), gotFilter.Since.UTC())
+
+	// 按周统计从所在周的周一开始，默认最近 12 周
+	w = request("/api/admin/stats/redemptions?interval=week&until=2026-03-05T00:00:00Z")
+	require.Equal(t, http.StatusOK, w.Code)
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	require.Len(t, resp.Data.Buckets, 12)
+	assert.Equal(t, "2025-12-15", resp.Data.Buckets[0].Period)
+	assert.Equal(t, "2026-03-02", resp.Data.Buckets[11].Period)
+
+	for _, path := range []string{
+		"/api/admin/stats/redemptions?interval=month",
+		"/api/admin/stats/redemptions?utc_offset=9999",
+		"/api/admin/stats/redemptions?since=yesterday",
+		"/api/admin/stats/redemptions?since=2026-03-05T00:00:00Z&until=2026-03-01T00:00:00Z",
+		"/api/admin/stats/redemptions?since=2000-01-01T00:00:00Z",
+		"/api/admin/stats/codes?until=later",
+	} {
+		assert.Equal(t, http.StatusBadRequest, request(path).Code, path)
+	}
+
+	for _, path := range []string{
+		"/api/admin/stats/codes",
+		"/api/admin/stats/completion",
+		"/api/admin/stats/failures",
+		"/api/admin/stats/coverage",
+		"/api/admin/stats/captcha",
+	} {
+		assert.Equal(t, http.StatusOK, request(path).Code, path)
+	}
+
+	// 未登录不可访问
+	req := httptest.NewRequest(http.MethodGet, "/api/admin/stats/codes", nil)
+	w = httptest.NewRecorder()
+	server.Handler.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+}
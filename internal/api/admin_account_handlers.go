@@ -0,0 +1,310 @@
+package api
+
+import (
+	"cdk-get/internal/auth"
+	"cdk-get/internal/storage"
+	"errors"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+)
+
+// CreateAdminRequest 创建管理员账号请求结构
+type CreateAdminRequest struct {
+	Username string `json:"username" binding:"required"`
+	Password string `json:"password" binding:"required"`
+	Role     string `json:"role" binding:"required"`
+}
+
+// UpdateAdminRequest 修改管理员账号请求结构，未提供的字段保持不变
+type UpdateAdminRequest struct {
+	Role         *string `json:"role"`
+	Password     *string `json:"password"`
+	TOTPRequired *bool   `json:"totp_required"`
+}
+
+// ChangePasswordRequest 修改自己密码请求结构
+type ChangePasswordRequest struct {
+	CurrentPassword string `json:"current_password" binding:"required"`
+	NewPassword     string `json:"new_password" binding:"required"`
+}
+
+// GetCurrentAdmin 获取当前登录的管理员账号
+// 处理 GET /api/admin/me
+func (h *AdminHandlers) GetCurrentAdmin(c *gin.Context) {
+	// 获取请求ID用于日志关联
+	requestID, _ := c.Get("request_id")
+	ctx := c.Request.Context()
+
+	admin, err := h.repository.GetAdmin(ctx, c.GetString("admin_username"))
+	if err != nil {
+		h.respondAdminError(c, requestID, err, "Failed to fetch admin")
+		return
+	}
+
+	c.JSON(200, SuccessResponse(gin.H{"admin": admin}))
+}
+
+// ChangePassword 修改当前管理员的密码，需要验证当前密码
+// 处理 PUT /api/admin/me/password
+func (h *AdminHandlers) ChangePassword(c *gin.Context) {
+	// 获取请求ID用于日志关联
+	requestID, _ := c.Get("request_id")
+
+	var req ChangePasswordRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(400, ErrorResponse("VALIDATION_ERROR", err.Error()))
+		return
+	}
+
+	ctx := c.Request.Context()
+	username := c.GetString("admin_username")
+
+	if _, err := h.authService.ValidateCredentials(ctx, username, req.CurrentPassword); err != nil {
+		if errors.Is(err, auth.ErrInvalidCredentials) {
+			h.logger.WithFields(logrus.Fields{
+				"request_id": requestID,
+				"username":   username,
+			}).Warn("password change rejected: wrong current password")
+
+			c.JSON(400, ErrorResponse("INVALID_CREDENTIALS", "Current password is incorrect"))
+			return
+		}
+		h.respondAdminError(c, requestID, err, "Failed to change password")
+		return
+	}
+
+	if err := h.setAdminPassword(c, requestID, username, req.NewPassword); err != nil {
+		return
+	}
+
+	h.logger.WithFields(logrus.Fields{
+		"request_id": requestID,
+		"username":   username,
+	}).Info("admin changed own password")
+
+	h.revokeSessionsAfterPasswordChange(c, requestID, username)
+
+	setAudit(c, "admin.change_password", username, nil, nil)
+	c.JSON(200, SuccessResponse(gin.H{"message": "Password changed successfully"}))
+}
+
+// ListAdmins 获取管理员账号列表
+// 处理 GET /api/admin/admins
+func (h *AdminHandlers) ListAdmins(c *gin.Context) {
+	// 获取请求ID用于日志关联
+	requestID, _ := c.Get("request_id")
+	ctx := c.Request.Context()
+
+	admins, err := h.repository.ListAdmins(ctx)
+	if err != nil {
+		h.respondAdminError(c, requestID, err, "Failed to fetch admins")
+		return
+	}
+
+	// 处理空列表情况 - 返回空数组而不是nil
+	if admins == nil {
+		admins = []*storage.Admin{}
+	}
+
+	c.JSON(200, SuccessResponse(gin.H{"admins": admins}))
+}
+
+// CreateAdmin 创建管理员账号
+// 处理 POST /api/admin/admins
+func (h *AdminHandlers) CreateAdmin(c *gin.Context) {
+	// 获取请求ID用于日志关联
+	requestID, _ := c.Get("request_id")
+
+	var req CreateAdminRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(400, ErrorResponse("VALIDATION_ERROR", err.Error()))
+		return
+	}
+
+	hash, err := auth.HashPassword(req.Password)
+	if err != nil {
+		h.respondAdminError(c, requestID, err, "Failed to create admin")
+		return
+	}
+
+	ctx := c.Request.Context()
+
+	admin := &storage.Admin{
+		Username:     req.Username,
+		PasswordHash: hash,
+		Role:         req.Role,
+	}
+	if err := h.repository.CreateAdmin(ctx, admin); err != nil {
+		h.respondAdminError(c, requestID, err, "Failed to create admin")
+		return
+	}
+
+	h.logger.WithFields(logrus.Fields{
+		"request_id": requestID,
+		"username":   admin.Username,
+		"role":       admin.Role,
+		"created_by": c.GetString("admin_username"),
+	}).Info("admin created successfully")
+
+	setAudit(c, "admin.create", admin.Username, nil, admin)
+	c.JSON(200, SuccessResponse(gin.H{"admin": admin}))
+}
+
+// UpdateAdmin 修改管理员账号的角色、重置密码或设置是否必须启用两步验证
+// 处理 PUT /api/admin/admins/:username
+func (h *AdminHandlers) UpdateAdmin(c *gin.Context) {
+	// 获取请求ID用于日志关联
+	requestID, _ := c.Get("request_id")
+	username := c.Param("username")
+
+	var req UpdateAdminRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(400, ErrorResponse("VALIDATION_ERROR", err.Error()))
+		return
+	}
+	if req.Role == nil && req.Password == nil && req.TOTPRequired == nil {
+		c.JSON(400, ErrorResponse("VALIDATION_ERROR", "role, password or totp_required is required"))
+		return
+	}
+	// 先校验角色，避免密码已重置而角色修改失败
+	if req.Role != nil && !storage.IsValidAdminRole(*req.Role) {
+		c.JSON(400, ErrorResponse("VALIDATION_ERROR", "role must be one of owner, operator, viewer"))
+		return
+	}
+
+	ctx := c.Request.Context()
+
+	before, err := h.repository.GetAdmin(ctx, username)
+	if err != nil {
+		h.respondAdminError(c, requestID, err, "Failed to fetch admin")
+		return
+	}
+
+	if req.Password != nil {
+		if err := h.setAdminPassword(c, requestID, username, *req.Password); err != nil {
+			return
+		}
+		h.revokeSessionsAfterPasswordChange(c, requestID, username)
+	}
+	if req.Role != nil {
+		if err := h.repository.UpdateAdminRole(ctx, username, *req.Role); err != nil {
+			h.respondAdminError(c, requestID, err, "Failed to update admin")
+			return
+		}
+	}
+	if req.TOTPRequired != nil {
+		if err := h.repository.SetAdminTOTPRequired(ctx, username, *req.TOTPRequired); err != nil {
+			h.respondAdminError(c, requestID, err, "Failed to update admin")
+			return
+		}
+	}
+
+	admin, err := h.repository.GetAdmin(ctx, username)
+	if err != nil {
+		h.respondAdminError(c, requestID, err, "Failed to fetch admin")
+		return
+	}
+
+	h.logger.WithFields(logrus.Fields{
+		"request_id":     requestID,
+		"username":       username,
+		"role":           admin.Role,
+		"password_reset": req.Password != nil,
+		"totp_required":  admin.TOTPRequired,
+		"updated_by":     c.GetString("admin_username"),
+	}).Info("admin updated successfully")
+
+	// 密码不写入审计日志，只记录是否重置过
+	setAudit(c, "admin.update", username, before, struct {
+		*storage.Admin
+		PasswordReset bool `json:"password_reset,omitempty"`
+	}{admin, req.Password != nil})
+	c.JSON(200, SuccessResponse(gin.H{"admin": admin}))
+}
+
+// DeleteAdmin 删除管理员账号，不能删除自己
+// 处理 DELETE /api/admin/admins/:username
+func (h *AdminHandlers) DeleteAdmin(c *gin.Context) {
+	// 获取请求ID用于日志关联
+	requestID, _ := c.Get("request_id")
+	username := c.Param("username")
+
+	if username == c.GetString("admin_username") {
+		c.JSON(400, ErrorResponse("VALIDATION_ERROR", "You cannot delete your own account"))
+		return
+	}
+
+	ctx := c.Request.Context()
+
+	before, _ := h.repository.GetAdmin(ctx, username)
+
+	if err := h.repository.DeleteAdmin(ctx, username); err != nil {
+		h.respondAdminError(c, requestID, err, "Failed to delete admin")
+		return
+	}
+
+	h.logger.WithFields(logrus.Fields{
+		"request_id": requestID,
+		"username":   username,
+		"deleted_by": c.GetString("admin_username"),
+	}).Info("admin deleted successfully")
+
+	setAudit(c, "admin.delete", username, before, nil)
+	c.JSON(200, SuccessResponse(gin.H{
+		"message":  "Admin deleted successfully",
+		"username": username,
+	}))
+}
+
+// setAdminPassword 哈希并保存新密码，失败时写入错误响应并返回错误
+func (h *AdminHandlers) setAdminPassword(c *gin.Context, requestID interface{}, username, password string) error {
+	hash, err := auth.HashPassword(password)
+	if err == nil {
+		err = h.repository.UpdateAdminPassword(c.Request.Context(), username, hash)
+	}
+	if err != nil {
+		h.respondAdminError(c, requestID, err, "Failed to update password")
+		return err
+	}
+	return nil
+}
+
+// revokeSessionsAfterPasswordChange 密码修改后撤销该账号的其他会话，当前会话保留
+// 密码已修改成功，撤销失败只记录日志
+func (h *AdminHandlers) revokeSessionsAfterPasswordChange(c *gin.Context, requestID interface{}, username string) {
+	exceptID := ""
+	if username == c.GetString("admin_username") {
+		exceptID = c.GetString("admin_session_id")
+	}
+	if _, err := h.repository.RevokeAdminSessions(c.Request.Context(), username, exceptID); err != nil {
+		h.logger.WithFields(logrus.Fields{
+			"request_id": requestID,
+			"username":   username,
+			"error":      err.Error(),
+		}).Error("failed to revoke sessions after password change")
+	}
+}
+
+// respondAdminError 将管理员账号相关的错误转换为HTTP响应
+func (h *AdminHandlers) respondAdminError(c *gin.Context, requestID interface{}, err error, message string) {
+	switch {
+	case errors.Is(err, storage.ErrAdminNotFound):
+		c.JSON(404, ErrorResponse("NOT_FOUND", "Admin not found"))
+	case errors.Is(err, storage.ErrAdminNameTaken):
+		c.JSON(409, ErrorResponse("ALREADY_EXISTS", "Admin username already exists"))
+	case errors.Is(err, storage.ErrLastOwner):
+		c.JSON(409, ErrorResponse("LAST_OWNER", "At least one owner must remain"))
+	case errors.Is(err, auth.ErrPasswordTooShort):
+		c.JSON(400, ErrorResponse("VALIDATION_ERROR", err.Error()))
+	case isValidationError(err):
+		c.JSON(400, ErrorResponse("VALIDATION_ERROR", err.Error()))
+	default:
+		h.logger.WithFields(logrus.Fields{
+			"request_id": requestID,
+			"error":      err.Error(),
+		}).Error(message)
+
+		c.JSON(500, ErrorResponse("DATABASE_ERROR", message))
+	}
+}
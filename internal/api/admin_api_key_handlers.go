@@ -0,0 +1,137 @@
+package api
+
+import (
+	"cdk-get/internal/auth"
+	"cdk-get/internal/storage"
+	"errors"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+)
+
+// CreateAPIKeyRequest 创建 API 密钥请求结构
+type CreateAPIKeyRequest struct {
+	Name   string   `json:"name" binding:"required"`
+	Scopes []string `json:"scopes" binding:"required"`
+}
+
+// ListAPIKeys 获取 API 密钥列表，包括已撤销的密钥
+// 处理 GET /api/admin/api-keys
+func (h *AdminHandlers) ListAPIKeys(c *gin.Context) {
+	// 获取请求ID用于日志关联
+	requestID, _ := c.Get("request_id")
+
+	keys, err := h.repository.ListAPIKeys(c.Request.Context())
+	if err != nil {
+		h.respondAPIKeyError(c, requestID, err, "Failed to fetch API keys")
+		return
+	}
+
+	// 处理空列表情况 - 返回空数组而不是nil
+	if keys == nil {
+		keys = []*storage.APIKey{}
+	}
+
+	c.JSON(200, SuccessResponse(gin.H{
+		"api_keys": keys,
+		"scopes":   storage.APIKeyScopes,
+	}))
+}
+
+// CreateAPIKey 创建 API 密钥，密钥本身只在响应中返回这一次
+// 处理 POST /api/admin/api-keys
+func (h *AdminHandlers) CreateAPIKey(c *gin.Context) {
+	// 获取请求ID用于日志关联
+	requestID, _ := c.Get("request_id")
+
+	var req CreateAPIKeyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(400, ErrorResponse("VALIDATION_ERROR", err.Error()))
+		return
+	}
+
+	rawKey, prefix, hash, err := auth.GenerateAPIKey()
+	if err != nil {
+		h.logger.WithFields(logrus.Fields{
+			"request_id": requestID,
+			"error":      err.Error(),
+		}).Error("failed to generate api key")
+
+		c.JSON(500, ErrorResponse("INTERNAL_ERROR", "Failed to generate API key"))
+		return
+	}
+
+	key := &storage.APIKey{
+		Name:      req.Name,
+		Prefix:    prefix,
+		KeyHash:   hash,
+		Scopes:    req.Scopes,
+		CreatedBy: c.GetString("admin_username"),
+	}
+	if err := h.repository.CreateAPIKey(c.Request.Context(), key); err != nil {
+		h.respondAPIKeyError(c, requestID, err, "Failed to create API key")
+		return
+	}
+
+	h.logger.WithFields(logrus.Fields{
+		"request_id": requestID,
+		"api_key_id": key.ID,
+		"name":       key.Name,
+		"scopes":     key.Scopes,
+		"created_by": key.CreatedBy,
+	}).Info("api key created")
+
+	setAudit(c, "api_key.create", strconv.FormatInt(key.ID, 10), nil, key)
+	c.JSON(200, SuccessResponse(gin.H{
+		"api_key": key,
+		"key":     rawKey,
+	}))
+}
+
+// RevokeAPIKey 撤销 API 密钥，撤销后使用该密钥的请求立即被拒绝
+// 处理 DELETE /api/admin/api-keys/:id
+func (h *AdminHandlers) RevokeAPIKey(c *gin.Context) {
+	// 获取请求ID用于日志关联
+	requestID, _ := c.Get("request_id")
+
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(400, ErrorResponse("VALIDATION_ERROR", "id must be a valid integer"))
+		return
+	}
+
+	if err := h.repository.RevokeAPIKey(c.Request.Context(), id); err != nil {
+		h.respondAPIKeyError(c, requestID, err, "Failed to revoke API key")
+		return
+	}
+
+	h.logger.WithFields(logrus.Fields{
+		"request_id": requestID,
+		"api_key_id": id,
+		"revoked_by": c.GetString("admin_username"),
+	}).Info("api key revoked")
+
+	setAudit(c, "api_key.revoke", strconv.FormatInt(id, 10), nil, nil)
+	c.JSON(200, SuccessResponse(gin.H{
+		"message": "API key revoked successfully",
+		"id":      id,
+	}))
+}
+
+// respondAPIKeyError 将 API 密钥相关的仓库错误转换为HTTP响应
+func (h *AdminHandlers) respondAPIKeyError(c *gin.Context, requestID any, err error, message string) {
+	switch {
+	case errors.Is(err, storage.ErrAPIKeyNotFound):
+		c.JSON(404, ErrorResponse("NOT_FOUND", "API key not found or already revoked"))
+	case isValidationError(err):
+		c.JSON(400, ErrorResponse("VALIDATION_ERROR", err.Error()))
+	default:
+		h.logger.WithFields(logrus.Fields{
+			"request_id": requestID,
+			"error":      err.Error(),
+		}).Error(message)
+
+		c.JSON(500, ErrorResponse("DATABASE_ERROR", message))
+	}
+}
@@ -0,0 +1,116 @@
+package api
+
+import (
+	"cdk-get/internal/storage"
+	"context"
+	"encoding/json"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+)
+
+// 审计信息在 gin 上下文中的键
+const (
+	auditRecordKey = "audit_record"
+	auditSkipKey   = "audit_skip"
+)
+
+// auditRecord 处理器提供的审计信息
+type auditRecord struct {
+	action string
+	target string
+	before any // 变更前的记录，nil 表示不适用
+	after  any // 变更后的记录，nil 表示不适用
+}
+
+// setAudit 设置当前请求的审计信息，请求成功后由 AuditMiddleware 写入审计日志
+func setAudit(c *gin.Context, action, target string, before, after any) {
+	c.Set(auditRecordKey, &auditRecord{action: action, target: target, before: before, after: after})
+}
+
+// skipAudit 标记当前请求没有修改数据，不写入审计日志
+func skipAudit(c *gin.Context) {
+	c.Set(auditSkipKey, true)
+}
+
+// saveAuditEntry 写入一条审计日志，写入失败只记录日志，不影响响应
+func saveAuditEntry(c *gin.Context, store AuditLogStore, logger *logrus.Logger, actor string, record *auditRecord) {
+	requestID := c.GetString("request_id")
+	entry := &storage.AuditEntry{
+		Actor:     actor,
+		Action:    record.action,
+		Target:    record.target,
+		IP:        c.ClientIP(),
+		RequestID: requestID,
+		Before:    marshalAuditData(record.before),
+		After:     marshalAuditData(record.after),
+	}
+
+	// 客户端在流式响应结束前断开时请求的 context 已取消，操作仍需记录
+	if err := store.CreateAuditEntry(context.WithoutCancel(c.Request.Context()), entry); err != nil {
+		logger.WithFields(logrus.Fields{
+			"request_id": requestID,
+			"actor":      actor,
+			"action":     record.action,
+			"target":     record.target,
+			"error":      err.Error(),
+		}).Error("failed to write audit log")
+	}
+}
+
+// marshalAuditData 将记录快照序列化为 JSON，nil 或序列化失败时返回空
+func marshalAuditData(v any) json.RawMessage {
+	if v == nil {
+		return nil
+	}
+	data, err := json.Marshal(v)
+	if err != nil || string(data) == "null" {
+		return nil
+	}
+	return data
+}
+
+// ListAuditLog 获取管理操作审计记录
+// 处理 GET /api/admin/audit-log?actor=&action=&target=&since=&until=&limit=
+// action 以 "." 结尾时按前缀匹配，since/until 为 RFC 3339 时间
+func (h *AdminHandlers) ListAuditLog(c *gin.Context) {
+	// 获取请求ID用于日志关联
+	requestID, _ := c.Get("request_id")
+
+	filter := storage.AuditFilter{
+		Actor:  c.Query("actor"),
+		Action: c.Query("action"),
+		Target: c.Query("target"),
+		Limit:  100,
+	}
+
+	// 从query参数读取limit（默认100）
+	if limitStr := c.Query("limit"); limitStr != "" {
+		if parsedLimit, err := strconv.Atoi(limitStr); err == nil && parsedLimit > 0 {
+			filter.Limit = parsedLimit
+		}
+	}
+
+	if !timeRange(c, &filter.Since, &filter.Until) {
+		return
+	}
+
+	entries, err := h.repository.ListAuditEntries(c.Request.Context(), filter)
+	if err != nil {
+		h.logger.WithFields(logrus.Fields{
+			"request_id": requestID,
+			"error":      err.Error(),
+		}).Error("failed to fetch audit log")
+
+		c.JSON(500, ErrorResponse("DATABASE_ERROR", "Failed to fetch audit log"))
+		return
+	}
+
+	// 处理空列表情况 - 返回空数组而不是nil
+	if entries == nil {
+		entries = []*storage.AuditEntry{}
+	}
+
+	c.JSON(200, SuccessResponse(gin.H{"entries": entries}))
+}
@@ -0,0 +1,98 @@
+package api
+
+import (
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+)
+
+// eventHeartbeatInterval 事件流心跳间隔，避免空闲连接被反向代理断开
+const eventHeartbeatInterval = 30 * time.Second
+
+// StreamEvents 实时事件流处理器
+// 处理 GET /api/admin/events
+// 以 Server-Sent Events 推送事件总线上的事件，事件名为事件类型，数据为完整事件（id、type、time、data）
+// types 参数为逗号分隔的事件类型前缀，如 types=task.,notification.，为空时推送全部事件
+// 连接建立时推送 ready 事件；推送不及时丢弃了事件时推送 overflow 事件，客户端应重新加载数据；
+// 访问令牌过期时推送 expired 事件并结束，客户端刷新令牌后重新连接
+func (h *AdminHandlers) StreamEvents(c *gin.Context) {
+	// 获取请求ID用于日志关联
+	requestID, _ := c.Get("request_id")
+
+	if h.events == nil {
+		c.JSON(503, ErrorResponse("SERVICE_UNAVAILABLE", "Event stream is not available"))
+		return
+	}
+
+	prefixes := []string{}
+	for _, prefix := range strings.Split(c.Query("types"), ",") {
+		if prefix = strings.TrimSpace(prefix); prefix != "" {
+			prefixes = append(prefixes, prefix)
+		}
+	}
+
+	events, dropped, cancel := h.events.Subscribe(prefixes...)
+	defer cancel()
+
+	h.logger.WithFields(logrus.Fields{
+		"request_id": requestID,
+		"username":   c.GetString("admin_username"),
+		"types":      prefixes,
+	}).Info("event stream opened")
+
+	// 事件流为长连接，取消本次响应的写超时
+	_ = http.NewResponseController(c.Writer).SetWriteDeadline(time.Time{})
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-cache")
+	c.Header("Connection", "keep-alive")
+	// 禁止 nginx 等反向代理缓冲响应
+	c.Header("X-Accel-Buffering", "no")
+
+	c.SSEvent("ready", gin.H{"types": prefixes})
+	c.Writer.Flush()
+
+	heartbeat := time.NewTicker(eventHeartbeatInterval)
+	defer heartbeat.Stop()
+
+	// 令牌过期后结束事件流，不再向已失效的令牌推送数据
+	var expired <-chan time.Time
+	if expiresAt, ok := c.Get("admin_token_expires_at"); ok {
+		if t, ok := expiresAt.(time.Time); ok && !t.IsZero() {
+			timer := time.NewTimer(time.Until(t))
+			defer timer.Stop()
+			expired = timer.C
+		}
+	}
+
+	ctx := c.Request.Context()
+	var reported uint64
+	for {
+		select {
+		case <-ctx.Done():
+			h.logger.WithField("request_id", requestID).Debug("event stream closed by client")
+			return
+		case <-expired:
+			c.SSEvent("expired", gin.H{})
+			c.Writer.Flush()
+			return
+		case <-heartbeat.C:
+			// 注释行，EventSource 和 dashboard 均忽略
+			_, _ = c.Writer.WriteString(": ping\n\n")
+			c.Writer.Flush()
+		case event, ok := <-events:
+			// 服务关闭时事件总线关闭，结束事件流
+			if !ok {
+				return
+			}
+			if n := dropped(); n > reported {
+				c.SSEvent("overflow", gin.H{"dropped": n - reported})
+				reported = n
+			}
+			c.SSEvent(event.Type, event)
+			c.Writer.Flush()
+		}
+	}
+}
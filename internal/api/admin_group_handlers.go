@@ -0,0 +1,179 @@
+package api
+
+import (
+	"cdk-get/internal/storage"
+	"context"
+	"errors"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+)
+
+// UserGroupRequest 创建/更新分组请求结构
+type UserGroupRequest struct {
+	Name        string `json:"name" binding:"required"`
+	Description string `json:"description"`
+}
+
+// ListUserGroups 获取分组列表处理器
+// 处理 GET /api/admin/groups
+func (h *AdminHandlers) ListUserGroups(c *gin.Context) {
+	// 获取请求ID用于日志关联
+	requestID, _ := c.Get("request_id")
+	ctx := c.Request.Context()
+
+	groups, err := h.repository.ListUserGroups(ctx)
+	if err != nil {
+		h.logger.WithFields(logrus.Fields{
+			"request_id": requestID,
+			"error":      err.Error(),
+		}).Error("failed to fetch user groups")
+
+		c.JSON(500, ErrorResponse("DATABASE_ERROR", "Failed to fetch user groups"))
+		return
+	}
+
+	// 处理空列表情况 - 返回空数组而不是nil
+	if groups == nil {
+		groups = []*storage.UserGroup{}
+	}
+
+	c.JSON(200, SuccessResponse(gin.H{"groups": groups}))
+}
+
+// CreateUserGroup 创建分组处理器
+// 处理 POST /api/admin/groups
+func (h *AdminHandlers) CreateUserGroup(c *gin.Context) {
+	// 获取请求ID用于日志关联
+	requestID, _ := c.Get("request_id")
+
+	var req UserGroupRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(400, ErrorResponse("VALIDATION_ERROR", err.Error()))
+		return
+	}
+
+	ctx := c.Request.Context()
+
+	group := &storage.UserGroup{
+		Name:        req.Name,
+		Description: req.Description,
+	}
+	if err := h.repository.CreateUserGroup(ctx, group); err != nil {
+		h.respondGroupError(c, requestID, err, "Failed to create user group")
+		return
+	}
+
+	h.logger.WithFields(logrus.Fields{
+		"request_id": requestID,
+		"group_id":   group.ID,
+		"name":       group.Name,
+	}).Info("user group created successfully")
+
+	setAudit(c, "group.create", strconv.FormatInt(group.ID, 10), nil, h.findUserGroup(ctx, group.ID))
+	c.JSON(200, SuccessResponse(gin.H{"group": group}))
+}
+
+// UpdateUserGroup 更新分组处理器
+// 处理 PUT /api/admin/groups/:id
+func (h *AdminHandlers) UpdateUserGroup(c *gin.Context) {
+	// 获取请求ID用于日志关联
+	requestID, _ := c.Get("request_id")
+
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(400, ErrorResponse("VALIDATION_ERROR", "id must be a valid integer"))
+		return
+	}
+
+	var req UserGroupRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(400, ErrorResponse("VALIDATION_ERROR", err.Error()))
+		return
+	}
+
+	ctx := c.Request.Context()
+
+	before := h.findUserGroup(ctx, id)
+
+	group := &storage.UserGroup{
+		ID:          id,
+		Name:        req.Name,
+		Description: req.Description,
+	}
+	if err := h.repository.UpdateUserGroup(ctx, group); err != nil {
+		h.respondGroupError(c, requestID, err, "Failed to update user group")
+		return
+	}
+
+	h.logger.WithFields(logrus.Fields{
+		"request_id": requestID,
+		"group_id":   id,
+	}).Info("user group updated successfully")
+
+	setAudit(c, "group.update", strconv.FormatInt(id, 10), before, h.findUserGroup(ctx, id))
+	c.JSON(200, SuccessResponse(gin.H{"group": group}))
+}
+
+// DeleteUserGroup 删除分组处理器
+// 处理 DELETE /api/admin/groups/:id
+func (h *AdminHandlers) DeleteUserGroup(c *gin.Context) {
+	// 获取请求ID用于日志关联
+	requestID, _ := c.Get("request_id")
+
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(400, ErrorResponse("VALIDATION_ERROR", "id must be a valid integer"))
+		return
+	}
+
+	ctx := c.Request.Context()
+	before := h.findUserGroup(ctx, id)
+
+	if err := h.repository.DeleteUserGroup(ctx, id); err != nil {
+		h.respondGroupError(c, requestID, err, "Failed to delete user group")
+		return
+	}
+
+	h.logger.WithFields(logrus.Fields{
+		"request_id": requestID,
+		"group_id":   id,
+	}).Info("user group deleted successfully")
+
+	setAudit(c, "group.delete", strconv.FormatInt(id, 10), before, nil)
+	c.JSON(200, SuccessResponse(gin.H{
+		"message": "User group deleted successfully",
+		"id":      id,
+	}))
+}
+
+// findUserGroup 查找分组，用于审计日志记录变更前的数据，查询失败或不存在时返回 nil
+func (h *AdminHandlers) findUserGroup(ctx context.Context, id int64) *storage.UserGroup {
+	group, err := h.repository.GetUserGroup(ctx, id)
+	if err != nil {
+		return nil
+	}
+	return group
+}
+
+// respondGroupError 将分组相关的仓库错误转换为HTTP响应
+func (h *AdminHandlers) respondGroupError(c *gin.Context, requestID interface{}, err error, message string) {
+	switch {
+	case errors.Is(err, storage.ErrGroupNotFound):
+		c.JSON(404, ErrorResponse("NOT_FOUND", "User group not found"))
+	case errors.Is(err, storage.ErrGroupNameTaken):
+		c.JSON(409, ErrorResponse("ALREADY_EXISTS", "User group name already exists"))
+	case errors.Is(err, storage.ErrGroupInUse):
+		c.JSON(409, ErrorResponse("GROUP_IN_USE", "User group is targeted by unfinished tasks"))
+	case isValidationError(err):
+		c.JSON(400, ErrorResponse("VALIDATION_ERROR", err.Error()))
+	default:
+		h.logger.WithFields(logrus.Fields{
+			"request_id": requestID,
+			"error":      err.Error(),
+		}).Error(message)
+
+		c.JSON(500, ErrorResponse("DATABASE_ERROR", message))
+	}
+}
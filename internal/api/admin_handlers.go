@@ -2,8 +2,13 @@ package api
 
 import (
 	"cdk-get/internal/auth"
+	"cdk-get/internal/events"
+	"cdk-get/internal/notification"
+	"cdk-get/internal/service"
 	"cdk-get/internal/storage"
+	"context"
 	"errors"
+	"fmt"
 	"strconv"
 	"strings"
 	"time"
@@ -14,30 +19,60 @@ import (
 
 // AdminHandlers 管理后台API处理器
 type AdminHandlers struct {
-	authService auth.AuthService
-	repository  storage.Repository
-	logger      *logrus.Logger
+	authService         auth.AuthService
+	repository          storage.Repository
+	giftService         *service.GiftService
+	notificationService *service.NotificationService
+	loginGuard          *auth.LoginGuard // 为 nil 时不限制登录尝试，也不记录失败登录
+	events              *events.Bus      // 为 nil 时不提供实时事件流
+	logger              *logrus.Logger
 }
 
 // NewAdminHandlers 创建管理后台处理器实例
-func NewAdminHandlers(authService auth.AuthService, repository storage.Repository, logger *logrus.Logger) *AdminHandlers {
+func NewAdminHandlers(authService auth.AuthService, repository storage.Repository, giftService *service.GiftService, notificationService *service.NotificationService, loginGuard *auth.LoginGuard, bus *events.Bus, logger *logrus.Logger) *AdminHandlers {
 	return &AdminHandlers{
-		authService: authService,
-		repository:  repository,
-		logger:      logger,
+		authService:         authService,
+		repository:          repository,
+		giftService:         giftService,
+		notificationService: notificationService,
+		loginGuard:          loginGuard,
+		events:              bus,
+		logger:              logger,
 	}
 }
 
 // LoginRequest 登录请求结构
+// 账号启用两步验证后需同时提供 totp_code（6 位动态码或恢复码）
 type LoginRequest struct {
 	Username string `json:"username" binding:"required"`
 	Password string `json:"password" binding:"required"`
+	TOTPCode string `json:"totp_code"`
 }
 
-// LoginResponse 登录响应结构
+// LoginResponse 登录/刷新响应结构
+// token 为短期访问令牌，过期前用 refresh_token 换取新的令牌对
 type LoginResponse struct {
-	Token     string    `json:"token"`
-	ExpiresAt time.Time `json:"expires_at"`
+	Token            string    `json:"token"`
+	ExpiresAt        time.Time `json:"expires_at"`
+	RefreshToken     string    `json:"refresh_token"`
+	RefreshExpiresAt time.Time `json:"refresh_expires_at"`
+	Username         string    `json:"username"`
+	Role             string    `json:"role"`
+	// TOTPSetupRequired 账号被要求启用两步验证，启用前只能访问账号相关接口
+	TOTPSetupRequired bool `json:"totp_setup_required"`
+}
+
+// newLoginResponse 由令牌对和管理员账号构造登录响应
+func newLoginResponse(pair *auth.TokenPair, admin *storage.Admin) LoginResponse {
+	return LoginResponse{
+		Token:             pair.AccessToken,
+		ExpiresAt:         pair.AccessExpiresAt,
+		RefreshToken:      pair.RefreshToken,
+		RefreshExpiresAt:  pair.RefreshExpiresAt,
+		Username:          admin.Username,
+		Role:              admin.Role,
+		TOTPSetupRequired: admin.TOTPRequired && !admin.TOTPEnabled,
+	}
 }
 
 // Login 管理员登录处理器
@@ -57,19 +92,101 @@ func (h *AdminHandlers) Login(c *gin.Context) {
 		return
 	}
 
+	ctx := c.Request.Context()
+	ip := c.ClientIP()
+
+	// 处于锁定或失败后的等待期时不校验密码
+	if h.loginGuard != nil {
+		if err := h.loginGuard.Check(ctx, req.Username, ip); err != nil {
+			var blocked *auth.LoginBlockedError
+			if !errors.As(err, &blocked) {
+				h.logger.WithFields(logrus.Fields{
+					"request_id": requestID,
+					"username":   req.Username,
+					"error":      err.Error(),
+				}).Error("failed to check login throttle")
+
+				c.JSON(500, ErrorResponse("INTERNAL_ERROR", "Failed to validate credentials"))
+				return
+			}
+
+			h.logger.WithFields(logrus.Fields{
+				"request_id":  requestID,
+				"username":    req.Username,
+				"ip":          ip,
+				"kind":        blocked.Kind,
+				"locked":      blocked.Locked,
+				"retry_after": blocked.RetryAfter.String(),
+			}).Warn("login attempt blocked")
+
+			h.recordLoginFailure(c, requestID, req.Username, storage.LoginFailureBlocked)
+			respondLoginBlocked(c, blocked)
+			return
+		}
+	}
+
 	// 验证凭证
-	if err := h.authService.ValidateCredentials(req.Username, req.Password); err != nil {
+	admin, err := h.authService.ValidateCredentials(ctx, req.Username, req.Password)
+	if err != nil {
+		if !errors.Is(err, auth.ErrInvalidCredentials) {
+			h.logger.WithFields(logrus.Fields{
+				"request_id": requestID,
+				"username":   req.Username,
+				"error":      err.Error(),
+			}).Error("failed to validate credentials")
+
+			c.JSON(500, ErrorResponse("INTERNAL_ERROR", "Failed to validate credentials"))
+			return
+		}
+
 		h.logger.WithFields(logrus.Fields{
 			"request_id": requestID,
 			"username":   req.Username,
 		}).Warn("invalid credentials")
 
+		h.recordLoginFailure(c, requestID, req.Username, storage.LoginFailureInvalidCredentials)
 		c.JSON(401, ErrorResponse("INVALID_CREDENTIALS", "Invalid username or password"))
 		return
 	}
 
-	// 生成token
-	token, expiresAt, err := h.authService.GenerateToken(req.Username)
+	// 已启用两步验证的账号需校验动态码或恢复码
+	if err := h.authService.VerifySecondFactor(ctx, admin, req.TOTPCode); err != nil {
+		switch {
+		case errors.Is(err, auth.ErrTOTPRequired):
+			c.JSON(401, ErrorResponse("TOTP_REQUIRED", "Two-factor authentication code required"))
+		case errors.Is(err, auth.ErrInvalidTOTP):
+			h.logger.WithFields(logrus.Fields{
+				"request_id": requestID,
+				"username":   req.Username,
+				"error":      err.Error(),
+			}).Warn("invalid two-factor code")
+
+			h.recordLoginFailure(c, requestID, req.Username, storage.LoginFailureInvalidTOTP)
+			c.JSON(401, ErrorResponse("INVALID_TOTP", "Invalid two-factor authentication code"))
+		default:
+			h.logger.WithFields(logrus.Fields{
+				"request_id": requestID,
+				"username":   req.Username,
+				"error":      err.Error(),
+			}).Error("failed to verify two-factor code")
+
+			c.JSON(500, ErrorResponse("INTERNAL_ERROR", "Failed to verify two-factor code"))
+		}
+		return
+	}
+
+	if h.loginGuard != nil {
+		if err := h.loginGuard.RecordSuccess(ctx, req.Username, ip); err != nil {
+			h.logger.WithFields(logrus.Fields{
+				"request_id": requestID,
+				"username":   req.Username,
+				"error":      err.Error(),
+			}).Error("failed to reset login throttle")
+		}
+	}
+
+	// 创建会话并签发令牌
+	pair, err := h.authService.CreateSession(ctx, admin, ip, c.Request.UserAgent())
 	if err != nil {
 		h.logger.WithFields(logrus.Fields{
 			"request_id": requestID,
@@ -83,52 +200,99 @@ func (h *AdminHandlers) Login(c *gin.Context) {
 
 	h.logger.WithFields(logrus.Fields{
 		"request_id": requestID,
-		"username":   req.Username,
+		"username":   admin.Username,
+		"role":       admin.Role,
+		"session_id": pair.SessionID,
 	}).Info("login successful")
 
-	c.JSON(200, SuccessResponse(LoginResponse{
-		Token:     token,
-		ExpiresAt: expiresAt,
-	}))
+	h.checkLoginIP(ctx, requestID, admin.Username, ip)
+
+	// 登录不经过 AuditMiddleware，直接写入审计日志
+	saveAuditEntry(c, h.repository, h.logger, admin.Username, &auditRecord{
+		action: "admin.login",
+		target: admin.Username,
+		after:  gin.H{"session_id": pair.SessionID, "user_agent": c.Request.UserAgent()},
+	})
+
+	c.JSON(200, SuccessResponse(newLoginResponse(pair, admin)))
+}
+
+// checkLoginIP 记录登录IP，从新的IP登录时发布通知
+// 记录失败不影响登录
+func (h *AdminHandlers) checkLoginIP(ctx context.Context, requestID any, username, ip string) {
+	newIP, err := h.repository.RecordLoginIP(ctx, username, ip)
+	if err != nil {
+		h.logger.WithFields(logrus.Fields{
+			"request_id": requestID,
+			"username":   username,
+			"error":      err.Error(),
+		}).Error("failed to record login ip")
+		return
+	}
+	if !newIP {
+		return
+	}
+
+	publishEvent(h.notificationService, h.logger, requestID, notification.NewEvent(
+		notification.EventLoginNewIP,
+		"管理员从新的IP登录",
+		fmt.Sprintf("管理员[%s]从新的IP %s 登录", username, ip),
+		fmt.Sprintf("用户名: %s\nIP: %s\n时间: %s", username, ip, time.Now().Format(time.DateTime)),
+		map[string]any{"username": username, "ip": ip},
+	))
 }
 
 // ListUsers 获取用户列表处理器
 // 处理 GET /api/admin/users
+// 支持 q（fid、昵称）、kid、status（active/disabled）筛选，以及 sort、order、cursor、limit 分页排序
 func (h *AdminHandlers) ListUsers(c *gin.Context) {
 	// 获取请求ID用于日志关联
 	requestID, _ := c.Get("request_id")
 	ctx := c.Request.Context()
 
-	users, err := h.repository.ListUsers(ctx)
-	if err != nil {
-		h.logger.WithFields(logrus.Fields{
-			"request_id": requestID,
-			"error":      err.Error(),
-		}).Error("failed to fetch users")
-
-		c.JSON(500, ErrorResponse("DATABASE_ERROR", "Failed to fetch users"))
+	filter := storage.UserFilter{
+		ListOptions: listOptions(c, adminDefaultPageSize),
+		Search:      c.Query("q"),
+	}
+	if kidStr := c.Query("kid"); kidStr != "" {
+		kid, err := strconv.Atoi(kidStr)
+		if err != nil {
+			c.JSON(400, ErrorResponse("VALIDATION_ERROR", "kid must be a valid integer"))
+			return
+		}
+		filter.KID = &kid
+	}
+	switch c.Query("status") {
+	case "":
+	case "active", "disabled":
+		disabled := c.Query("status") == "disabled"
+		filter.Disabled = &disabled
+	default:
+		c.JSON(400, ErrorResponse("VALIDATION_ERROR", "status must be active or disabled"))
 		return
 	}
 
-	// 处理空列表情况 - 返回空数组而不是nil
-	if users == nil {
-		users = []*storage.User{}
+	page, err := h.repository.QueryUsers(ctx, filter)
+	if err != nil {
+		h.respondListError(c, requestID, err, "Failed to fetch users")
+		return
 	}
 
 	h.logger.WithFields(logrus.Fields{
 		"request_id": requestID,
-		"count":      len(users),
+		"count":      len(page.Items),
 	}).Info("users fetched successfully")
 
-	c.JSON(200, SuccessResponse(gin.H{"users": users}))
+	c.JSON(200, SuccessResponse(gin.H{
+		"users":       page.Items,
+		"next_cursor": page.NextCursor,
+	}))
 }
 
 // AddUserRequest 添加用户请求结构
+// 昵称、区服和头像以玩家接口返回的资料为准
 type AddUserRequest struct {
-	FID         string `json:"fid" binding:"required"`
-	Nickname    string `json:"nickname"`
-	KID         int    `json:"kid"`
-	AvatarImage string `json:"avatar_image"`
+	FID string `json:"fid" binding:"required"`
 }
 
 // AddUser 添加用户处理器
@@ -148,16 +312,41 @@ func (h *AdminHandlers) AddUser(c *gin.Context) {
 		return
 	}
 
+	req.FID = strings.TrimSpace(req.FID)
+	if _, err := strconv.ParseInt(req.FID, 10, 64); err != nil {
+		c.JSON(400, ErrorResponse("VALIDATION_ERROR", "fid must be a valid integer"))
+		return
+	}
+
+	// 通过玩家接口校验fid并获取资料
+	player, ok := lookupPlayer(c, h.giftService, h.logger, req.FID)
+	if !ok {
+		return
+	}
+
 	ctx := c.Request.Context()
 
 	user := &storage.User{
 		FID:         req.FID,
-		Nickname:    req.Nickname,
-		KID:         req.KID,
-		AvatarImage: req.AvatarImage,
+		Nickname:    player.Data.Nickname,
+		KID:         player.Data.Kid,
+		AvatarImage: player.Data.Avatar,
 	}
 
-	if err := h.repository.SaveUser(ctx, user); err != nil {
+	// 已存在的用户会被覆盖资料，记录覆盖前的数据
+	var before *storage.User
+	if existing, err := h.repository.GetUser(ctx, req.FID); err == nil {
+		before = existing
+	}
+
+	err := h.repository.WithTransaction(ctx, func(repo storage.Repository) error {
+		// 用户在回收站中时，重新添加即恢复
+		if err := repo.RestoreUser(ctx, req.FID); err != nil && !errors.Is(err, storage.ErrUserNotFound) {
+			return err
+		}
+		return repo.SaveUser(ctx, user)
+	})
+	if err != nil {
 		h.logger.WithFields(logrus.Fields{
 			"request_id": requestID,
 			"fid":        req.FID,
@@ -171,11 +360,16 @@ func (h *AdminHandlers) AddUser(c *gin.Context) {
 	h.logger.WithFields(logrus.Fields{
 		"request_id": requestID,
 		"fid":        req.FID,
+		"nickname":   user.Nickname,
+		"kid":        user.KID,
 	}).Info("user added successfully")
 
+	setAudit(c, "user.create", req.FID, before, user)
 	c.JSON(200, SuccessResponse(gin.H{
-		"message": "User added successfully",
-		"fid":     req.FID,
+		"message":  "User added successfully",
+		"fid":      req.FID,
+		"nickname": user.Nickname,
+		"kid":      user.KID,
 	}))
 }
 
@@ -217,38 +411,52 @@ func (h *AdminHandlers) GetUserGiftCodes(c *gin.Context) {
 
 // ListTasks 获取任务列表处理器
 // 处理 GET /api/admin/tasks
+// status 默认为 pending，为 all 时包括已完成任务；支持 q（兑换码）、since、until 筛选以及分页排序
 func (h *AdminHandlers) ListTasks(c *gin.Context) {
+	status := c.DefaultQuery("status", storage.TaskStatusPending)
+	if status == "all" {
+		status = ""
+	}
+	h.listTasks(c, status, "tasks fetched successfully", "Failed to fetch tasks")
+}
+
+// listTasks 按 status 及请求中的筛选、分页参数列出任务
+func (h *AdminHandlers) listTasks(c *gin.Context, status, logMessage, errMessage string) {
 	// 获取请求ID用于日志关联
 	requestID, _ := c.Get("request_id")
 	ctx := c.Request.Context()
 
-	tasks, err := h.repository.ListPendingTasks(ctx)
-	if err != nil {
-		h.logger.WithFields(logrus.Fields{
-			"request_id": requestID,
-			"error":      err.Error(),
-		}).Error("failed to fetch tasks")
-
-		c.JSON(500, ErrorResponse("DATABASE_ERROR", "Failed to fetch tasks"))
+	filter := storage.TaskFilter{
+		ListOptions: listOptions(c, adminDefaultPageSize),
+		Search:      c.Query("q"),
+		Status:      status,
+	}
+	if !timeRange(c, &filter.Since, &filter.Until) {
 		return
 	}
 
-	// 处理空列表情况 - 返回空数组而不是nil
-	if tasks == nil {
-		tasks = []*storage.Task{}
+	page, err := h.repository.QueryTasks(ctx, filter)
+	if err != nil {
+		h.respondListError(c, requestID, err, errMessage)
+		return
 	}
 
 	h.logger.WithFields(logrus.Fields{
 		"request_id": requestID,
-		"count":      len(tasks),
-	}).Info("tasks fetched successfully")
+		"count":      len(page.Items),
+	}).Info(logMessage)
 
-	c.JSON(200, SuccessResponse(gin.H{"tasks": tasks}))
+	c.JSON(200, SuccessResponse(gin.H{
+		"tasks":       page.Items,
+		"next_cursor": page.NextCursor,
+	}))
 }
 
 // AddGiftCodeRequest 添加兑换码请求结构
 type AddGiftCodeRequest struct {
 	Code string `json:"code" binding:"required"`
+	// GroupID 目标分组，为空时任务面向所有用户
+	GroupID *int64 `json:"group_id"`
 }
 
 // AddGiftCode 添加兑换码处理器
@@ -282,7 +490,53 @@ func (h *AdminHandlers) AddGiftCode(c *gin.Context) {
 
 	ctx := c.Request.Context()
 
-	if err := h.repository.CreateTask(ctx, req.Code); err != nil {
+	// 写入前先确认目标分组存在
+	if req.GroupID != nil {
+		if _, err := h.repository.GetUserGroup(ctx, *req.GroupID); err != nil {
+			if errors.Is(err, storage.ErrGroupNotFound) {
+				c.JSON(400, ErrorResponse("VALIDATION_ERROR", "Group not found"))
+				return
+			}
+
+			h.logger.WithFields(logrus.Fields{
+				"request_id": requestID,
+				"group_id":   *req.GroupID,
+				"error":      err.Error(),
+			}).Error("failed to get user group")
+
+			c.JSON(500, ErrorResponse("DATABASE_ERROR", "Failed to create task"))
+			return
+		}
+	}
+
+	existing, _ := h.repository.GetTaskByCode(ctx, req.Code)
+
+	// 在事务中恢复、创建并设置目标分组，避免失败时留下半完成的任务
+	// 已存在的任务仅在显式指定 group_id 时修改目标分组
+	var inserted bool
+	err := h.repository.WithTransaction(ctx, func(repo storage.Repository) error {
+		// 任务在回收站中时，重新添加即恢复
+		if err := repo.RestoreTask(ctx, req.Code); err != nil && !errors.Is(err, storage.ErrTaskNotFound) {
+			return err
+		}
+
+		created, err := repo.CreateTask(ctx, req.Code)
+		if err != nil {
+			return err
+		}
+		inserted = created
+
+		if inserted || req.GroupID != nil {
+			return repo.SetTaskTargetGroup(ctx, req.Code, req.GroupID)
+		}
+		return nil
+	})
+	if err != nil {
+		if errors.Is(err, storage.ErrGroupNotFound) {
+			c.JSON(400, ErrorResponse("VALIDATION_ERROR", "Group not found"))
+			return
+		}
+
 		h.logger.WithFields(logrus.Fields{
 			"request_id": requestID,
 			"code":       req.Code,
@@ -296,8 +550,16 @@ func (h *AdminHandlers) AddGiftCode(c *gin.Context) {
 	h.logger.WithFields(logrus.Fields{
 		"request_id": requestID,
 		"code":       req.Code,
+		"group_id":   req.GroupID,
 	}).Info("gift code task created successfully")
 
+	if inserted {
+		publishEvent(h.notificationService, h.logger, requestID, newCodeDiscoveredEvent(req.Code, "admin"))
+	}
+
+	created, _ := h.repository.GetTaskByCode(ctx, req.Code)
+	setAudit(c, "task.create", req.Code, existing, created)
+
 	c.JSON(200, SuccessResponse(gin.H{
 		"message": "Gift code task created successfully",
 		"code":    req.Code,
@@ -306,80 +568,60 @@ func (h *AdminHandlers) AddGiftCode(c *gin.Context) {
 
 // ListCompletedTasks 获取已完成任务列表处理器
 // 处理 GET /api/admin/tasks/completed
+// 默认按完成时间倒序，since、until 按完成时间筛选
 func (h *AdminHandlers) ListCompletedTasks(c *gin.Context) {
+	h.listTasks(c, storage.TaskStatusCompleted, "completed tasks fetched successfully", "Failed to fetch completed tasks")
+}
+
+// ListNotifications 获取通知历史列表处理器
+// 处理 GET /api/admin/notifications
+// 支持 q（fid、标题、内容）、status、channel、event、since、until 筛选以及分页排序
+func (h *AdminHandlers) ListNotifications(c *gin.Context) {
 	// 获取请求ID用于日志关联
 	requestID, _ := c.Get("request_id")
 	ctx := c.Request.Context()
 
-	// 从query参数读取limit（默认100）
-	limit := 100
-	if limitStr := c.Query("limit"); limitStr != "" {
-		if parsedLimit, err := strconv.Atoi(limitStr); err == nil && parsedLimit > 0 {
-			limit = parsedLimit
-		}
+	filter := storage.NotificationFilter{
+		ListOptions: listOptions(c, adminDefaultPageSize),
+		Search:      c.Query("q"),
+		Status:      c.Query("status"),
+		Channel:     c.Query("channel"),
+		Event:       c.Query("event"),
 	}
-
-	tasks, err := h.repository.ListCompletedTasks(ctx, limit)
-	if err != nil {
-		h.logger.WithFields(logrus.Fields{
-			"request_id": requestID,
-			"error":      err.Error(),
-		}).Error("failed to fetch completed tasks")
-
-		c.JSON(500, ErrorResponse("DATABASE_ERROR", "Failed to fetch completed tasks"))
+	if !timeRange(c, &filter.Since, &filter.Until) {
 		return
 	}
 
-	// 处理空列表情况 - 返回空数组而不是nil
-	if tasks == nil {
-		tasks = []*storage.Task{}
+	page, err := h.repository.QueryNotifications(ctx, filter)
+	if err != nil {
+		h.respondListError(c, requestID, err, "Failed to fetch notifications")
+		return
 	}
 
 	h.logger.WithFields(logrus.Fields{
 		"request_id": requestID,
-		"count":      len(tasks),
-	}).Info("completed tasks fetched successfully")
+		"count":      len(page.Items),
+	}).Info("notifications fetched successfully")
 
-	c.JSON(200, SuccessResponse(gin.H{"tasks": tasks}))
+	c.JSON(200, SuccessResponse(gin.H{
+		"notifications": page.Items,
+		"next_cursor":   page.NextCursor,
+	}))
 }
 
-// ListNotifications 获取通知历史列表处理器
-// 处理 GET /api/admin/notifications
-func (h *AdminHandlers) ListNotifications(c *gin.Context) {
-	// 获取请求ID用于日志关联
-	requestID, _ := c.Get("request_id")
-	ctx := c.Request.Context()
-
-	// 从query参数读取limit（默认100）
-	limit := 100
-	if limitStr := c.Query("limit"); limitStr != "" {
-		if parsedLimit, err := strconv.Atoi(limitStr); err == nil && parsedLimit > 0 {
-			limit = parsedLimit
-		}
-	}
-
-	notifications, err := h.repository.ListNotifications(ctx, limit)
-	if err != nil {
-		h.logger.WithFields(logrus.Fields{
-			"request_id": requestID,
-			"error":      err.Error(),
-		}).Error("failed to fetch notifications")
-
-		c.JSON(500, ErrorResponse("DATABASE_ERROR", "Failed to fetch notifications"))
+// respondListError 将列表查询的错误转换为HTTP响应，无效的筛选、排序或游标参数返回 400
+func (h *AdminHandlers) respondListError(c *gin.Context, requestID any, err error, message string) {
+	if isValidationError(err) {
+		c.JSON(400, ErrorResponse("VALIDATION_ERROR", err.Error()))
 		return
 	}
 
-	// 处理空列表情况 - 返回空数组而不是nil
-	if notifications == nil {
-		notifications = []*storage.Notification{}
-	}
-
 	h.logger.WithFields(logrus.Fields{
 		"request_id": requestID,
-		"count":      len(notifications),
-	}).Info("notifications fetched successfully")
+		"error":      err.Error(),
+	}).Error(message)
 
-	c.JSON(200, SuccessResponse(gin.H{"notifications": notifications}))
+	c.JSON(500, ErrorResponse("DATABASE_ERROR", message))
 }
 
 // DeleteTask 删除任务处理器
@@ -418,6 +660,9 @@ func (h *AdminHandlers) DeleteTask(c *gin.Context) {
 
 	ctx := c.Request.Context()
 
+	// 记录删除前的任务，写入审计日志
+	before, _ := h.repository.GetTaskByCode(ctx, trimmedCode)
+
 	// 调用repository删除任务
 	err := h.repository.DeleteTask(ctx, trimmedCode)
 	if err != nil {
@@ -463,8 +708,58 @@ func (h *AdminHandlers) DeleteTask(c *gin.Context) {
 		"result":     "success",
 	}).Info("task deleted successfully")
 
+	setAudit(c, "task.delete", trimmedCode, before, nil)
 	c.JSON(200, gin.H{
 		"success": true,
 		"message": "任务删除成功",
 	})
 }
+
+// GetRetentionStatus 获取数据保留状态处理器
+// 处理 GET /api/admin/maintenance/retention
+func (h *AdminHandlers) GetRetentionStatus(c *gin.Context) {
+	// 获取请求ID用于日志关联
+	requestID, _ := c.Get("request_id")
+	ctx := c.Request.Context()
+
+	tables, err := h.repository.GetTableStats(ctx)
+	if err != nil {
+		h.logger.WithFields(logrus.Fields{
+			"request_id": requestID,
+			"error":      err.Error(),
+		}).Error("failed to fetch table stats")
+
+		c.JSON(500, ErrorResponse("DATABASE_ERROR", "Failed to fetch table stats"))
+		return
+	}
+
+	runs, err := h.repository.ListLatestPruneRuns(ctx)
+	if err != nil {
+		h.logger.WithFields(logrus.Fields{
+			"request_id": requestID,
+			"error":      err.Error(),
+		}).Error("failed to fetch prune runs")
+
+		c.JSON(500, ErrorResponse("DATABASE_ERROR", "Failed to fetch prune runs"))
+		return
+	}
+
+	// 处理空列表情况 - 返回空数组而不是nil
+	if tables == nil {
+		tables = []*storage.TableStat{}
+	}
+	if runs == nil {
+		runs = []*storage.PruneRun{}
+	}
+
+	h.logger.WithFields(logrus.Fields{
+		"request_id": requestID,
+		"tables":     len(tables),
+		"runs":       len(runs),
+	}).Info("retention status fetched successfully")
+
+	c.JSON(200, SuccessResponse(gin.H{
+		"tables":    tables,
+		"last_runs": runs,
+	}))
+}
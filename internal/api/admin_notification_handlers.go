@@ -0,0 +1,123 @@
+package api
+
+import (
+	"cdk-get/internal/notification"
+	"cdk-get/internal/service"
+	"cdk-get/internal/storage"
+	"errors"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+)
+
+// PreviewNotificationRequest 通知预览请求结构
+type PreviewNotificationRequest struct {
+	Event string `json:"event" binding:"required"`
+	// Channel 预览的渠道，为空时预览事件按路由规则发送到的所有渠道
+	Channel string `json:"channel"`
+	// Data 覆盖示例事件数据中的字段
+	Data map[string]any `json:"data"`
+}
+
+// PreviewNotification 通知模板预览处理器
+// 使用示例数据渲染事件，不实际发送
+// 处理 POST /api/admin/notifications/preview
+func (h *AdminHandlers) PreviewNotification(c *gin.Context) {
+	// 获取请求ID用于日志关联
+	requestID, _ := c.Get("request_id")
+	skipAudit(c)
+
+	var req PreviewNotificationRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(400, ErrorResponse("VALIDATION_ERROR", err.Error()))
+		return
+	}
+
+	eventType := notification.EventType(req.Event)
+	if !eventType.IsValid() {
+		c.JSON(400, ErrorResponse("VALIDATION_ERROR", "Unknown event type"))
+		return
+	}
+
+	if h.notificationService == nil {
+		c.JSON(503, ErrorResponse("SERVICE_UNAVAILABLE", "No notification channel configured"))
+		return
+	}
+
+	previews, err := h.notificationService.Preview(notification.SampleEvent(eventType, req.Data), req.Channel)
+	if err != nil {
+		if errors.Is(err, service.ErrUnknownChannel) {
+			c.JSON(404, ErrorResponse("NOT_FOUND", "Notification channel not found"))
+			return
+		}
+
+		h.logger.WithFields(logrus.Fields{
+			"request_id": requestID,
+			"event":      req.Event,
+			"error":      err.Error(),
+		}).Error("failed to preview notification")
+
+		c.JSON(500, ErrorResponse("INTERNAL_ERROR", "Failed to preview notification"))
+		return
+	}
+
+	c.JSON(200, SuccessResponse(gin.H{
+		"event":    req.Event,
+		"previews": previews,
+	}))
+}
+
+// ResendNotification 重新投递通知处理器
+// 通知重置为 pending 并清零尝试次数，由投递任务立即发送
+// 处理 POST /api/admin/notifications/:id/resend
+func (h *AdminHandlers) ResendNotification(c *gin.Context) {
+	// 获取请求ID用于日志关联
+	requestID, _ := c.Get("request_id")
+
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(400, ErrorResponse("VALIDATION_ERROR", "Invalid notification id"))
+		return
+	}
+
+	ctx := c.Request.Context()
+	if err := h.repository.ResendNotification(ctx, id); err != nil {
+		if errors.Is(err, storage.ErrNotificationNotFound) {
+			c.JSON(404, ErrorResponse("NOT_FOUND", "Notification not found"))
+			return
+		}
+
+		h.logger.WithFields(logrus.Fields{
+			"request_id": requestID,
+			"id":         id,
+			"error":      err.Error(),
+		}).Error("failed to resend notification")
+
+		c.JSON(500, ErrorResponse("DATABASE_ERROR", "Failed to resend notification"))
+		return
+	}
+
+	h.notificationService.Wake()
+
+	notif, err := h.repository.GetNotification(ctx, id)
+	if err != nil {
+		h.logger.WithFields(logrus.Fields{
+			"request_id": requestID,
+			"id":         id,
+			"error":      err.Error(),
+		}).Error("failed to fetch notification")
+
+		c.JSON(500, ErrorResponse("DATABASE_ERROR", "Failed to fetch notification"))
+		return
+	}
+
+	h.logger.WithFields(logrus.Fields{
+		"request_id": requestID,
+		"id":         id,
+		"channel":    notif.Channel,
+	}).Info("notification queued for resend")
+
+	setAudit(c, "notification.resend", strconv.FormatInt(id, 10), nil, notif)
+	c.JSON(200, SuccessResponse(gin.H{"notification": notif}))
+}
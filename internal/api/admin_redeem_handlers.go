@@ -0,0 +1,202 @@
+package api
+
+import (
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+)
+
+// RedeemNowRequest 立即兑换请求结构
+// FIDs 和 GroupID 均为空时为所有参与兑换的用户（未禁用）兑换
+type RedeemNowRequest struct {
+	Code string `json:"code" binding:"required"`
+	// FIDs 指定兑换的用户，可包括已禁用的用户
+	FIDs []string `json:"fids"`
+	// GroupID 为该分组中未禁用的用户兑换，指定了 FIDs 时忽略
+	GroupID *int64 `json:"group_id"`
+}
+
+// redeemProgress 单个用户兑换完成时推送的进度
+type redeemProgress struct {
+	FID      string `json:"fid"`
+	Success  bool   `json:"success"`
+	Message  string `json:"message"`
+	Nickname string `json:"nickname,omitempty"`
+	KID      int    `json:"kid,omitempty"`
+	Done     int    `json:"done"`
+	Total    int    `json:"total"`
+}
+
+// redeemSummary 兑换结束时推送的汇总，同时写入审计日志
+type redeemSummary struct {
+	Code     string `json:"code"`
+	Total    int    `json:"total"`
+	Done     int    `json:"done"`
+	Success  int    `json:"success"`
+	Failed   int    `json:"failed"`
+	Canceled bool   `json:"canceled"`
+}
+
+// RedeemNow 立即兑换处理器
+// 处理 POST /api/admin/redeem
+// 参数校验失败时返回普通 JSON 错误响应；校验通过后以 Server-Sent Events 推送进度：
+// start 事件包含兑换码和用户数，每个用户兑换完成时推送 result 事件，全部完成后推送 done 事件
+// 客户端断开连接后不再开始新的兑换
+func (h *AdminHandlers) RedeemNow(c *gin.Context) {
+	// 获取请求ID用于日志关联
+	requestID, _ := c.Get("request_id")
+
+	var req RedeemNowRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		h.logger.WithFields(logrus.Fields{
+			"request_id": requestID,
+			"error":      err.Error(),
+		}).Warn("redeem now request validation failed")
+
+		c.JSON(400, ErrorResponse("VALIDATION_ERROR", err.Error()))
+		return
+	}
+
+	code := strings.TrimSpace(req.Code)
+	if code == "" {
+		c.JSON(400, ErrorResponse("VALIDATION_ERROR", "Gift code cannot be empty or whitespace only"))
+		return
+	}
+
+	if h.giftService == nil || !h.giftService.CanRedeem() {
+		c.JSON(503, ErrorResponse("SERVICE_UNAVAILABLE", "Captcha service is not configured"))
+		return
+	}
+
+	fids, ok := h.redeemFids(c, requestID, req)
+	if !ok {
+		return
+	}
+	if len(fids) == 0 {
+		c.JSON(400, ErrorResponse("VALIDATION_ERROR", "No users to redeem for"))
+		return
+	}
+
+	h.logger.WithFields(logrus.Fields{
+		"request_id": requestID,
+		"code":       code,
+		"fid_count":  len(fids),
+	}).Info("redeem now started")
+
+	// 兑换耗时可能超过服务器的写超时，取消本次响应的写超时
+	_ = http.NewResponseController(c.Writer).SetWriteDeadline(time.Time{})
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-cache")
+	c.Header("Connection", "keep-alive")
+	// 禁止 nginx 等反向代理缓冲响应
+	c.Header("X-Accel-Buffering", "no")
+
+	summary := redeemSummary{Code: code, Total: len(fids)}
+	c.SSEvent("start", gin.H{"code": code, "total": len(fids)})
+	c.Writer.Flush()
+
+	ctx := c.Request.Context()
+	for result := range h.giftService.StreamRedeemGiftCode(ctx, fids, code) {
+		summary.Done++
+		if result.Success {
+			summary.Success++
+		} else {
+			summary.Failed++
+		}
+		if ctx.Err() != nil {
+			continue
+		}
+		c.SSEvent("result", redeemProgress{
+			FID:      result.FID,
+			Success:  result.Success,
+			Message:  result.Message,
+			Nickname: result.Nickname,
+			KID:      result.Kid,
+			Done:     summary.Done,
+			Total:    summary.Total,
+		})
+		c.Writer.Flush()
+	}
+	summary.Canceled = summary.Done < summary.Total
+
+	h.logger.WithFields(logrus.Fields{
+		"request_id": requestID,
+		"code":       code,
+		"total":      summary.Total,
+		"done":       summary.Done,
+		"success":    summary.Success,
+		"failed":     summary.Failed,
+	}).Info("redeem now finished")
+
+	setAudit(c, "task.redeem", code, nil, summary)
+	if ctx.Err() == nil {
+		c.SSEvent("done", summary)
+		c.Writer.Flush()
+	}
+}
+
+// redeemFids 解析立即兑换的用户列表，失败时写入错误响应并返回 false
+func (h *AdminHandlers) redeemFids(c *gin.Context, requestID any, req RedeemNowRequest) ([]string, bool) {
+	ctx := c.Request.Context()
+
+	if len(req.FIDs) > 0 {
+		fids := make([]string, 0, len(req.FIDs))
+		for _, fid := range req.FIDs {
+			fid = strings.TrimSpace(fid)
+			if _, err := strconv.ParseInt(fid, 10, 64); err != nil {
+				c.JSON(400, ErrorResponse("VALIDATION_ERROR", "Invalid fid: "+fid))
+				return nil, false
+			}
+			if slices.Contains(fids, fid) {
+				continue
+			}
+			if _, err := h.repository.GetUser(ctx, fid); err != nil {
+				if isNotFoundError(err) {
+					c.JSON(400, ErrorResponse("VALIDATION_ERROR", "User not found: "+fid))
+					return nil, false
+				}
+				h.respondRedeemFidsError(c, requestID, err)
+				return nil, false
+			}
+			fids = append(fids, fid)
+		}
+		return fids, true
+	}
+
+	if req.GroupID != nil {
+		fids, err := h.repository.ListGroupFids(ctx, *req.GroupID)
+		if err != nil {
+			h.respondRedeemFidsError(c, requestID, err)
+			return nil, false
+		}
+		return fids, true
+	}
+
+	users, err := h.repository.ListUsers(ctx)
+	if err != nil {
+		h.respondRedeemFidsError(c, requestID, err)
+		return nil, false
+	}
+	fids := make([]string, 0, len(users))
+	for _, user := range users {
+		if !user.Disabled {
+			fids = append(fids, user.FID)
+		}
+	}
+	return fids, true
+}
+
+// respondRedeemFidsError 查询兑换用户失败时记录日志并返回 500
+func (h *AdminHandlers) respondRedeemFidsError(c *gin.Context, requestID any, err error) {
+	h.logger.WithFields(logrus.Fields{
+		"request_id": requestID,
+		"error":      err.Error(),
+	}).Error("failed to fetch users to redeem for")
+
+	c.JSON(500, ErrorResponse("DATABASE_ERROR", "Failed to fetch users"))
+}
@@ -0,0 +1,178 @@
+package api
+
+import (
+	"cdk-get/internal/auth"
+	"cdk-get/internal/notification"
+	"cdk-get/internal/storage"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+)
+
+// LoginThrottleResponse 登录失败计数列表项，locked 标记当前是否处于锁定中
+type LoginThrottleResponse struct {
+	*storage.LoginThrottle
+	Locked bool `json:"locked"`
+}
+
+// ListLoginLockouts 列出被统计失败次数的 IP 和用户名
+// 处理 GET /api/admin/security/lockouts
+func (h *AdminHandlers) ListLoginLockouts(c *gin.Context) {
+	// 获取请求ID用于日志关联
+	requestID, _ := c.Get("request_id")
+
+	throttles, err := h.repository.ListLoginThrottles(c.Request.Context())
+	if err != nil {
+		h.respondSecurityError(c, requestID, err, "Failed to fetch login lockouts")
+		return
+	}
+
+	now := time.Now()
+	lockouts := make([]LoginThrottleResponse, 0, len(throttles))
+	for _, throttle := range throttles {
+		lockouts = append(lockouts, LoginThrottleResponse{
+			LoginThrottle: throttle,
+			Locked:        throttle.Locked(now),
+		})
+	}
+
+	c.JSON(200, SuccessResponse(gin.H{"lockouts": lockouts}))
+}
+
+// UnlockLogin 清除 IP 或用户名的失败计数，解除锁定
+// 处理 DELETE /api/admin/security/lockouts/:kind/:identity
+func (h *AdminHandlers) UnlockLogin(c *gin.Context) {
+	// 获取请求ID用于日志关联
+	requestID, _ := c.Get("request_id")
+	kind := c.Param("kind")
+	identity := c.Param("identity")
+
+	if kind != storage.LoginThrottleKindIP && kind != storage.LoginThrottleKindUsername {
+		c.JSON(400, ErrorResponse("VALIDATION_ERROR", "kind must be ip or username"))
+		return
+	}
+
+	before, _ := h.repository.GetLoginThrottle(c.Request.Context(), kind, identity)
+
+	if err := h.repository.DeleteLoginThrottle(c.Request.Context(), kind, identity); err != nil {
+		h.respondSecurityError(c, requestID, err, "Failed to unlock login")
+		return
+	}
+
+	h.logger.WithFields(logrus.Fields{
+		"request_id":  requestID,
+		"kind":        kind,
+		"identity":    identity,
+		"unlocked_by": c.GetString("admin_username"),
+	}).Info("login unlocked")
+
+	setAudit(c, "login.unlock", kind+":"+identity, before, nil)
+	c.JSON(200, SuccessResponse(gin.H{
+		"message":  "Login unlocked successfully",
+		"kind":     kind,
+		"identity": identity,
+	}))
+}
+
+// ListLoginFailures 获取失败登录审计记录
+// 处理 GET /api/admin/security/login-failures
+func (h *AdminHandlers) ListLoginFailures(c *gin.Context) {
+	// 获取请求ID用于日志关联
+	requestID, _ := c.Get("request_id")
+
+	// 从query参数读取limit（默认100）
+	limit := 100
+	if limitStr := c.Query("limit"); limitStr != "" {
+		if parsedLimit, err := strconv.Atoi(limitStr); err == nil && parsedLimit > 0 {
+			limit = parsedLimit
+		}
+	}
+
+	failures, err := h.repository.ListLoginFailures(c.Request.Context(), limit)
+	if err != nil {
+		h.respondSecurityError(c, requestID, err, "Failed to fetch login failures")
+		return
+	}
+
+	// 处理空列表情况 - 返回空数组而不是nil
+	if failures == nil {
+		failures = []*storage.LoginFailure{}
+	}
+
+	c.JSON(200, SuccessResponse(gin.H{"failures": failures}))
+}
+
+// recordLoginFailure 记录失败的登录，IP 或用户名因此被锁定时发布通知
+// 记录失败不影响登录响应
+func (h *AdminHandlers) recordLoginFailure(c *gin.Context, requestID any, username, reason string) {
+	if h.loginGuard == nil {
+		return
+	}
+
+	ip := c.ClientIP()
+	locked, err := h.loginGuard.RecordFailure(c.Request.Context(), username, ip, c.Request.UserAgent(), reason)
+	if err != nil {
+		h.logger.WithFields(logrus.Fields{
+			"request_id": requestID,
+			"username":   username,
+			"error":      err.Error(),
+		}).Error("failed to record login failure")
+	}
+
+	for _, throttle := range locked {
+		lockedUntil := throttle.LockedUntil.Format(time.DateTime)
+		kindName := "用户名"
+		if throttle.Kind == storage.LoginThrottleKindIP {
+			kindName = "IP"
+		}
+
+		h.logger.WithFields(logrus.Fields{
+			"request_id":   requestID,
+			"kind":         throttle.Kind,
+			"identity":     throttle.Identity,
+			"lockouts":     throttle.Lockouts,
+			"locked_until": lockedUntil,
+		}).Warn("login locked after repeated failures")
+
+		publishEvent(h.notificationService, h.logger, requestID, notification.NewEvent(
+			notification.EventLoginLocked,
+			"管理员登录已锁定",
+			fmt.Sprintf("%s[%s]登录失败次数过多，已锁定至 %s", kindName, throttle.Identity, lockedUntil),
+			fmt.Sprintf("锁定对象: %s %s\n最近失败来源IP: %s\n锁定至: %s", kindName, throttle.Identity, ip, lockedUntil),
+			map[string]any{"kind": throttle.Kind, "identity": throttle.Identity, "ip": ip, "locked_until": lockedUntil},
+		))
+	}
+}
+
+// respondLoginBlocked 返回登录尝试被限制的响应，Retry-After 为需等待的秒数
+func respondLoginBlocked(c *gin.Context, blocked *auth.LoginBlockedError) {
+	retryAfter := ceilSeconds(blocked.RetryAfter)
+	c.Header("Retry-After", strconv.Itoa(retryAfter))
+
+	if blocked.Locked {
+		c.JSON(429, ErrorResponse("LOGIN_LOCKED",
+			fmt.Sprintf("Too many failed login attempts, try again in %d seconds", retryAfter)))
+		return
+	}
+	c.JSON(429, ErrorResponse("LOGIN_THROTTLED",
+		fmt.Sprintf("Please wait %d seconds before trying again", retryAfter)))
+}
+
+// respondSecurityError 将登录防护相关的存储错误转换为HTTP响应
+func (h *AdminHandlers) respondSecurityError(c *gin.Context, requestID any, err error, message string) {
+	if errors.Is(err, storage.ErrLoginThrottleNotFound) {
+		c.JSON(404, ErrorResponse("NOT_FOUND", "No failed attempts recorded for this identity"))
+		return
+	}
+
+	h.logger.WithFields(logrus.Fields{
+		"request_id": requestID,
+		"error":      err.Error(),
+	}).Error(message)
+
+	c.JSON(500, ErrorResponse("DATABASE_ERROR", message))
+}
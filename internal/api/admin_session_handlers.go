@@ -0,0 +1,217 @@
+package api
+
+import (
+	"cdk-get/internal/auth"
+	"cdk-get/internal/storage"
+	"errors"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+)
+
+// RefreshRequest 刷新令牌请求结构
+type RefreshRequest struct {
+	RefreshToken string `json:"refresh_token" binding:"required"`
+}
+
+// SessionResponse 会话列表项，current 标记发起请求的会话
+type SessionResponse struct {
+	*storage.AdminSession
+	Current bool `json:"current"`
+}
+
+// Refresh 用刷新令牌换取新的令牌对
+// 处理 POST /api/admin/refresh
+func (h *AdminHandlers) Refresh(c *gin.Context) {
+	// 获取请求ID用于日志关联
+	requestID, _ := c.Get("request_id")
+
+	var req RefreshRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(400, ErrorResponse("VALIDATION_ERROR", err.Error()))
+		return
+	}
+
+	pair, admin, err := h.authService.Refresh(c.Request.Context(), req.RefreshToken, c.ClientIP(), c.Request.UserAgent())
+	if err != nil {
+		if errors.Is(err, auth.ErrInvalidToken) {
+			h.logger.WithFields(logrus.Fields{
+				"request_id": requestID,
+				"ip":         c.ClientIP(),
+				"error":      err.Error(),
+			}).Warn("refresh token rejected")
+
+			c.JSON(401, ErrorResponse("UNAUTHORIZED", "Invalid or expired refresh token"))
+			return
+		}
+
+		h.logger.WithFields(logrus.Fields{
+			"request_id": requestID,
+			"error":      err.Error(),
+		}).Error("failed to refresh token")
+
+		c.JSON(500, ErrorResponse("TOKEN_GENERATION_FAILED", "Failed to refresh token"))
+		return
+	}
+
+	h.logger.WithFields(logrus.Fields{
+		"request_id": requestID,
+		"username":   admin.Username,
+		"session_id": pair.SessionID,
+	}).Debug("token refreshed")
+
+	c.JSON(200, SuccessResponse(newLoginResponse(pair, admin)))
+}
+
+// Logout 退出登录，撤销当前会话
+// 处理 POST /api/admin/logout
+func (h *AdminHandlers) Logout(c *gin.Context) {
+	// 获取请求ID用于日志关联
+	requestID, _ := c.Get("request_id")
+	username := c.GetString("admin_username")
+	sessionID := c.GetString("admin_session_id")
+
+	err := h.repository.RevokeAdminSession(c.Request.Context(), username, sessionID)
+	if err != nil && !errors.Is(err, storage.ErrSessionNotFound) {
+		h.respondSessionError(c, requestID, err, "Failed to logout")
+		return
+	}
+
+	h.logger.WithFields(logrus.Fields{
+		"request_id": requestID,
+		"username":   username,
+		"session_id": sessionID,
+	}).Info("logout successful")
+
+	setAudit(c, "admin.logout", username, nil, gin.H{"session_id": sessionID})
+	c.JSON(200, SuccessResponse(gin.H{"message": "Logged out successfully"}))
+}
+
+// ListSessions 列出当前管理员的有效会话
+// 处理 GET /api/admin/me/sessions
+func (h *AdminHandlers) ListSessions(c *gin.Context) {
+	// 获取请求ID用于日志关联
+	requestID, _ := c.Get("request_id")
+	currentID := c.GetString("admin_session_id")
+
+	sessions, err := h.repository.ListAdminSessions(c.Request.Context(), c.GetString("admin_username"), time.Now())
+	if err != nil {
+		h.respondSessionError(c, requestID, err, "Failed to fetch sessions")
+		return
+	}
+
+	items := make([]SessionResponse, 0, len(sessions))
+	for _, session := range sessions {
+		items = append(items, SessionResponse{AdminSession: session, Current: session.ID == currentID})
+	}
+
+	c.JSON(200, SuccessResponse(gin.H{"sessions": items}))
+}
+
+// RevokeSession 撤销当前管理员的一个会话
+// 处理 DELETE /api/admin/me/sessions/:id
+func (h *AdminHandlers) RevokeSession(c *gin.Context) {
+	// 获取请求ID用于日志关联
+	requestID, _ := c.Get("request_id")
+	username := c.GetString("admin_username")
+	sessionID := c.Param("id")
+
+	if err := h.repository.RevokeAdminSession(c.Request.Context(), username, sessionID); err != nil {
+		h.respondSessionError(c, requestID, err, "Failed to revoke session")
+		return
+	}
+
+	h.logger.WithFields(logrus.Fields{
+		"request_id": requestID,
+		"username":   username,
+		"session_id": sessionID,
+	}).Info("session revoked")
+
+	setAudit(c, "session.revoke", username, nil, gin.H{"session_id": sessionID})
+	c.JSON(200, SuccessResponse(gin.H{
+		"message": "Session revoked successfully",
+		"id":      sessionID,
+	}))
+}
+
+// RevokeOtherSessions 撤销当前管理员除当前会话外的全部会话
+// 处理 DELETE /api/admin/me/sessions
+func (h *AdminHandlers) RevokeOtherSessions(c *gin.Context) {
+	// 获取请求ID用于日志关联
+	requestID, _ := c.Get("request_id")
+	username := c.GetString("admin_username")
+
+	revoked, err := h.repository.RevokeAdminSessions(c.Request.Context(), username, c.GetString("admin_session_id"))
+	if err != nil {
+		h.respondSessionError(c, requestID, err, "Failed to revoke sessions")
+		return
+	}
+
+	h.logger.WithFields(logrus.Fields{
+		"request_id": requestID,
+		"username":   username,
+		"revoked":    revoked,
+	}).Info("other sessions revoked")
+
+	setAudit(c, "session.revoke_others", username, nil, gin.H{"revoked": revoked})
+	c.JSON(200, SuccessResponse(gin.H{
+		"message": "Other sessions revoked successfully",
+		"revoked": revoked,
+	}))
+}
+
+// RevokeAdminSessions 撤销指定管理员的全部会话，使其所有设备重新登录
+// 处理 DELETE /api/admin/admins/:username/sessions
+func (h *AdminHandlers) RevokeAdminSessions(c *gin.Context) {
+	// 获取请求ID用于日志关联
+	requestID, _ := c.Get("request_id")
+	username := c.Param("username")
+	ctx := c.Request.Context()
+
+	if _, err := h.repository.GetAdmin(ctx, username); err != nil {
+		h.respondAdminError(c, requestID, err, "Failed to revoke sessions")
+		return
+	}
+
+	// 撤销自己的会话时保留当前会话
+	exceptID := ""
+	if username == c.GetString("admin_username") {
+		exceptID = c.GetString("admin_session_id")
+	}
+
+	revoked, err := h.repository.RevokeAdminSessions(ctx, username, exceptID)
+	if err != nil {
+		h.respondSessionError(c, requestID, err, "Failed to revoke sessions")
+		return
+	}
+
+	h.logger.WithFields(logrus.Fields{
+		"request_id": requestID,
+		"username":   username,
+		"revoked":    revoked,
+		"revoked_by": c.GetString("admin_username"),
+	}).Info("admin sessions revoked")
+
+	setAudit(c, "session.revoke_all", username, nil, gin.H{"revoked": revoked})
+	c.JSON(200, SuccessResponse(gin.H{
+		"message":  "Sessions revoked successfully",
+		"username": username,
+		"revoked":  revoked,
+	}))
+}
+
+// respondSessionError 将会话相关的错误转换为HTTP响应
+func (h *AdminHandlers) respondSessionError(c *gin.Context, requestID interface{}, err error, message string) {
+	if errors.Is(err, storage.ErrSessionNotFound) {
+		c.JSON(404, ErrorResponse("NOT_FOUND", "Session not found"))
+		return
+	}
+
+	h.logger.WithFields(logrus.Fields{
+		"request_id": requestID,
+		"error":      err.Error(),
+	}).Error(message)
+
+	c.JSON(500, ErrorResponse("DATABASE_ERROR", message))
+}
@@ -0,0 +1,252 @@
+package api
+
+import (
+	"cdk-get/internal/storage"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+)
+
+// 统计接口参数
+const (
+	statsDefaultDays   = 30  // 按天统计默认最近 30 天
+	statsDefaultWeeks  = 12  // 按周统计默认最近 12 周
+	statsMaxPeriods    = 800 // 单次最多返回的时间段数
+	statsMaxUTCOffset  = 14 * 60
+	statsDateLayout    = "2006-01-02"
+	statsDefaultLimit  = 20
+	statsCoverageLimit = 100
+)
+
+// statsFilter 读取 since、until、limit 参数，格式错误时写入 400 响应并返回 false
+// limit 无效时使用 defaultLimit，最大为 storage.MaxPageSize
+func statsFilter(c *gin.Context, defaultLimit int) (storage.StatsFilter, bool) {
+	filter := storage.StatsFilter{Limit: defaultLimit}
+	if limitStr := c.Query("limit"); limitStr != "" {
+		if parsedLimit, err := strconv.Atoi(limitStr); err == nil && parsedLimit > 0 {
+			filter.Limit = min(parsedLimit, storage.MaxPageSize)
+		}
+	}
+	if !timeRange(c, &filter.Since, &filter.Until) {
+		return filter, false
+	}
+	return filter, true
+}
+
+// statsError 记录统计查询失败并返回 500
+func (h *AdminHandlers) statsError(c *gin.Context, err error, msg string) {
+	requestID, _ := c.Get("request_id")
+	h.logger.WithFields(logrus.Fields{
+		"request_id": requestID,
+		"error":      err.Error(),
+	}).Error(msg)
+
+	c.JSON(500, ErrorResponse("DATABASE_ERROR", "Failed to fetch statistics"))
+}
+
+// GetRedemptionStats 按天或周统计兑换请求结果
+// 处理 GET /api/admin/stats/redemptions?interval=day|week&since=&until=&utc_offset=
+// utc_offset 为划分日期所用时区相对 UTC 的分钟数，如 480 表示 UTC+8；
+// 未指定 since 时统计最近 30 天或 12 周，没有请求的时间段也会返回，各结果数为 0
+func (h *AdminHandlers) GetRedemptionStats(c *gin.Context) {
+	interval := c.DefaultQuery("interval", storage.StatsIntervalDay)
+	if interval != storage.StatsIntervalDay && interval != storage.StatsIntervalWeek {
+		c.JSON(400, ErrorResponse("VALIDATION_ERROR", "interval must be day or week"))
+		return
+	}
+
+	offsetMinutes := 0
+	if offsetStr := c.Query("utc_offset"); offsetStr != "" {
+		parsed, err := strconv.Atoi(offsetStr)
+		if err != nil || parsed < -statsMaxUTCOffset || parsed > statsMaxUTCOffset {
+			c.JSON(400, ErrorResponse("VALIDATION_ERROR", "utc_offset must be minutes between -840 and 840"))
+			return
+		}
+		offsetMinutes = parsed
+	}
+	offset := time.Duration(offsetMinutes) * time.Minute
+	loc := time.FixedZone("", offsetMinutes*60)
+
+	filter, ok := statsFilter(c, 0)
+	if !ok {
+		return
+	}
+
+	// 时间段按本地日期划分，第一个时间段从 since 所在的日期（或周一）开始
+	until := time.Now()
+	if !filter.Until.IsZero() {
+		until = filter.Until
+	}
+	var start time.Time
+	step := 1
+	if filter.Since.IsZero() {
+		start = statsPeriodStart(until.In(loc), interval)
+		if interval == storage.StatsIntervalWeek {
+			start = start.AddDate(0, 0, -7*(statsDefaultWeeks-1))
+		} else {
+			start = start.AddDate(0, 0, -(statsDefaultDays - 1))
+		}
+		filter.Since = start
+	} else {
+		start = statsPeriodStart(filter.Since.In(loc), interval)
+	}
+	if interval == storage.StatsIntervalWeek {
+		step = 7
+	}
+	if !filter.Since.Before(until) {
+		c.JSON(400, ErrorResponse("VALIDATION_ERROR", "since must be before until"))
+		return
+	}
+
+	var periods []string
+	for day := start; day.Before(until); day = day.AddDate(0, 0, step) {
+		if len(periods) == statsMaxPeriods {
+			c.JSON(400, ErrorResponse("VALIDATION_ERROR", "time range is too large"))
+			return
+		}
+		periods = append(periods, day.Format(statsDateLayout))
+	}
+
+	counted, err := h.repository.CountRedemptions(c.Request.Context(), filter, interval, offset)
+	if err != nil {
+		h.statsError(c, err, "failed to count redemptions")
+		return
+	}
+
+	// 补齐没有请求的时间段
+	byPeriod := make(map[string]*storage.RedemptionBucket, len(counted))
+	for _, bucket := range counted {
+		byPeriod[bucket.Period] = bucket
+	}
+	buckets := make([]*storage.RedemptionBucket, 0, len(periods))
+	total := storage.RedemptionBucket{}
+	for _, period := range periods {
+		bucket, ok := byPeriod[period]
+		if !ok {
+			bucket = &storage.RedemptionBucket{Period: period}
+		}
+		buckets = append(buckets, bucket)
+		total.Success += bucket.Success
+		total.Received += bucket.Received
+		total.NotFound += bucket.NotFound
+		total.Failed += bucket.Failed
+	}
+
+	c.JSON(200, SuccessResponse(gin.H{
+		"interval":   interval,
+		"utc_offset": offsetMinutes,
+		"since":      filter.Since,
+		"until":      until,
+		"buckets":    buckets,
+		"total":      total,
+	}))
+}
+
+// statsPeriodStart 返回 t 所在日期的零点，按周统计时为所在周周一的零点
+func statsPeriodStart(t time.Time, interval string) time.Time {
+	day := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+	if interval == storage.StatsIntervalWeek {
+		// time.Sunday 为 0，周日属于上一周
+		day = day.AddDate(0, 0, -((int(day.Weekday()) + 6) % 7))
+	}
+	return day
+}
+
+// GetCodeStats 统计各兑换码的兑换请求结果和成功率
+// 处理 GET /api/admin/stats/codes?since=&until=&limit=
+// 按最近一次兑换请求倒序返回，limit 默认 20，最大 500
+func (h *AdminHandlers) GetCodeStats(c *gin.Context) {
+	filter, ok := statsFilter(c, statsDefaultLimit)
+	if !ok {
+		return
+	}
+
+	codes, err := h.repository.ListCodeStats(c.Request.Context(), filter)
+	if err != nil {
+		h.statsError(c, err, "failed to list code stats")
+		return
+	}
+
+	c.JSON(200, SuccessResponse(gin.H{"codes": codes}))
+}
+
+// GetCompletionStats 统计任务从添加到完成的平均耗时
+// 处理 GET /api/admin/stats/completion?since=&until=
+// since/until 限定任务的完成时间
+func (h *AdminHandlers) GetCompletionStats(c *gin.Context) {
+	filter, ok := statsFilter(c, 0)
+	if !ok {
+		return
+	}
+
+	completion, err := h.repository.GetCompletionStats(c.Request.Context(), filter)
+	if err != nil {
+		h.statsError(c, err, "failed to get completion stats")
+		return
+	}
+
+	c.JSON(200, SuccessResponse(gin.H{"completion": completion}))
+}
+
+// GetFailureStats 统计兑换失败的原因
+// 处理 GET /api/admin/stats/failures?since=&until=&limit=
+// 按次数倒序返回，limit 默认 20，最大 500
+func (h *AdminHandlers) GetFailureStats(c *gin.Context) {
+	filter, ok := statsFilter(c, statsDefaultLimit)
+	if !ok {
+		return
+	}
+
+	reasons, err := h.repository.ListFailureReasons(c.Request.Context(), filter)
+	if err != nil {
+		h.statsError(c, err, "failed to list failure reasons")
+		return
+	}
+
+	c.JSON(200, SuccessResponse(gin.H{"reasons": reasons}))
+}
+
+// GetCoverageStats 统计每个用户已领取的兑换码数与可领取的兑换码数
+// 处理 GET /api/admin/stats/coverage?limit=
+// 按领取比例升序返回，limit 默认 100，最大 500
+func (h *AdminHandlers) GetCoverageStats(c *gin.Context) {
+	filter, ok := statsFilter(c, statsCoverageLimit)
+	if !ok {
+		return
+	}
+
+	users, err := h.repository.ListFidCoverage(c.Request.Context(), filter.Limit)
+	if err != nil {
+		h.statsError(c, err, "failed to list fid coverage")
+		return
+	}
+
+	c.JSON(200, SuccessResponse(gin.H{"users": users}))
+}
+
+// GetCaptchaStats 按提供商统计验证码识别次数和估算费用
+// 处理 GET /api/admin/stats/captcha?since=&until=
+// 费用按识别时配置的提供商单价（captcha.providers[].unit_price）累计
+func (h *AdminHandlers) GetCaptchaStats(c *gin.Context) {
+	filter, ok := statsFilter(c, 0)
+	if !ok {
+		return
+	}
+
+	providers, err := h.repository.ListCaptchaUsage(c.Request.Context(), filter)
+	if err != nil {
+		h.statsError(c, err, "failed to list captcha usage")
+		return
+	}
+
+	total := storage.CaptchaUsage{Provider: "total"}
+	for _, usage := range providers {
+		total.Calls += usage.Calls
+		total.Failures += usage.Failures
+		total.Cost += usage.Cost
+	}
+
+	c.JSON(200, SuccessResponse(gin.H{"providers": providers, "total": total}))
+}
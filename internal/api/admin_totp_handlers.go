@@ -0,0 +1,249 @@
+package api
+
+import (
+	"cdk-get/internal/auth"
+	"cdk-get/internal/storage"
+	"errors"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+)
+
+// TOTPCodeRequest 提交动态码请求结构
+type TOTPCodeRequest struct {
+	Code string `json:"code" binding:"required"`
+}
+
+// DisableTOTPRequest 停用两步验证请求结构，需同时验证密码和动态码（或恢复码）
+type DisableTOTPRequest struct {
+	Password string `json:"password" binding:"required"`
+	Code     string `json:"code" binding:"required"`
+}
+
+// GetTOTPStatus 获取当前管理员的两步验证状态
+// 处理 GET /api/admin/me/totp
+func (h *AdminHandlers) GetTOTPStatus(c *gin.Context) {
+	// 获取请求ID用于日志关联
+	requestID, _ := c.Get("request_id")
+	ctx := c.Request.Context()
+
+	admin, err := h.repository.GetAdmin(ctx, c.GetString("admin_username"))
+	if err != nil {
+		h.respondAdminError(c, requestID, err, "Failed to fetch two-factor status")
+		return
+	}
+
+	remaining := 0
+	if admin.TOTPEnabled {
+		remaining, err = h.repository.CountAdminRecoveryCodes(ctx, admin.Username)
+		if err != nil {
+			h.respondTOTPError(c, requestID, err, "Failed to fetch two-factor status")
+			return
+		}
+	}
+
+	c.JSON(200, SuccessResponse(gin.H{
+		"enabled":                  admin.TOTPEnabled,
+		"required":                 admin.TOTPRequired,
+		"recovery_codes_remaining": remaining,
+	}))
+}
+
+// BeginTOTPSetup 开始启用两步验证，返回密钥和 otpauth:// 链接
+// 处理 POST /api/admin/me/totp/setup
+func (h *AdminHandlers) BeginTOTPSetup(c *gin.Context) {
+	// 获取请求ID用于日志关联
+	requestID, _ := c.Get("request_id")
+	ctx := c.Request.Context()
+
+	admin, err := h.repository.GetAdmin(ctx, c.GetString("admin_username"))
+	if err != nil {
+		h.respondAdminError(c, requestID, err, "Failed to start two-factor setup")
+		return
+	}
+
+	enrollment, err := h.authService.BeginTOTPEnrollment(ctx, admin)
+	if err != nil {
+		h.respondTOTPError(c, requestID, err, "Failed to start two-factor setup")
+		return
+	}
+
+	h.logger.WithFields(logrus.Fields{
+		"request_id": requestID,
+		"username":   admin.Username,
+	}).Info("two-factor setup started")
+
+	setAudit(c, "totp.setup", admin.Username, nil, nil)
+	c.JSON(200, SuccessResponse(enrollment))
+}
+
+// EnableTOTP 用验证器生成的动态码确认启用两步验证，返回只展示一次的恢复码
+// 处理 POST /api/admin/me/totp/enable
+func (h *AdminHandlers) EnableTOTP(c *gin.Context) {
+	// 获取请求ID用于日志关联
+	requestID, _ := c.Get("request_id")
+
+	var req TOTPCodeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(400, ErrorResponse("VALIDATION_ERROR", err.Error()))
+		return
+	}
+
+	ctx := c.Request.Context()
+
+	admin, err := h.repository.GetAdmin(ctx, c.GetString("admin_username"))
+	if err != nil {
+		h.respondAdminError(c, requestID, err, "Failed to enable two-factor authentication")
+		return
+	}
+
+	codes, err := h.authService.ConfirmTOTPEnrollment(ctx, admin, req.Code)
+	if err != nil {
+		h.respondTOTPError(c, requestID, err, "Failed to enable two-factor authentication")
+		return
+	}
+
+	h.logger.WithFields(logrus.Fields{
+		"request_id": requestID,
+		"username":   admin.Username,
+	}).Info("two-factor authentication enabled")
+
+	setAudit(c, "totp.enable", admin.Username, nil, nil)
+	c.JSON(200, SuccessResponse(gin.H{"recovery_codes": codes}))
+}
+
+// RegenerateRecoveryCodes 重新生成恢复码，旧的恢复码全部失效
+// 处理 POST /api/admin/me/totp/recovery-codes
+func (h *AdminHandlers) RegenerateRecoveryCodes(c *gin.Context) {
+	// 获取请求ID用于日志关联
+	requestID, _ := c.Get("request_id")
+
+	var req TOTPCodeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(400, ErrorResponse("VALIDATION_ERROR", err.Error()))
+		return
+	}
+
+	ctx := c.Request.Context()
+
+	admin, err := h.repository.GetAdmin(ctx, c.GetString("admin_username"))
+	if err != nil {
+		h.respondAdminError(c, requestID, err, "Failed to regenerate recovery codes")
+		return
+	}
+
+	codes, err := h.authService.RegenerateRecoveryCodes(ctx, admin, req.Code)
+	if err != nil {
+		h.respondTOTPError(c, requestID, err, "Failed to regenerate recovery codes")
+		return
+	}
+
+	h.logger.WithFields(logrus.Fields{
+		"request_id": requestID,
+		"username":   admin.Username,
+	}).Info("recovery codes regenerated")
+
+	setAudit(c, "totp.regenerate_recovery_codes", admin.Username, nil, nil)
+	c.JSON(200, SuccessResponse(gin.H{"recovery_codes": codes}))
+}
+
+// DisableTOTP 停用当前管理员的两步验证
+// owner 要求启用两步验证的账号不能自行停用
+// 处理 POST /api/admin/me/totp/disable
+func (h *AdminHandlers) DisableTOTP(c *gin.Context) {
+	// 获取请求ID用于日志关联
+	requestID, _ := c.Get("request_id")
+
+	var req DisableTOTPRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(400, ErrorResponse("VALIDATION_ERROR", err.Error()))
+		return
+	}
+
+	ctx := c.Request.Context()
+	username := c.GetString("admin_username")
+
+	admin, err := h.authService.ValidateCredentials(ctx, username, req.Password)
+	if err != nil {
+		if errors.Is(err, auth.ErrInvalidCredentials) {
+			c.JSON(400, ErrorResponse("INVALID_CREDENTIALS", "Current password is incorrect"))
+			return
+		}
+		h.respondAdminError(c, requestID, err, "Failed to disable two-factor authentication")
+		return
+	}
+	if !admin.TOTPEnabled {
+		h.respondTOTPError(c, requestID, auth.ErrTOTPNotEnabled, "Failed to disable two-factor authentication")
+		return
+	}
+	if admin.TOTPRequired {
+		c.JSON(409, ErrorResponse("TOTP_ENFORCED", "Two-factor authentication is required for this account"))
+		return
+	}
+	if err := h.authService.VerifySecondFactor(ctx, admin, req.Code); err != nil {
+		h.respondTOTPError(c, requestID, err, "Failed to disable two-factor authentication")
+		return
+	}
+
+	if err := h.repository.DisableAdminTOTP(ctx, username); err != nil {
+		h.respondAdminError(c, requestID, err, "Failed to disable two-factor authentication")
+		return
+	}
+
+	h.logger.WithFields(logrus.Fields{
+		"request_id": requestID,
+		"username":   username,
+	}).Info("two-factor authentication disabled")
+
+	setAudit(c, "totp.disable", username, nil, nil)
+	c.JSON(200, SuccessResponse(gin.H{"message": "Two-factor authentication disabled"}))
+}
+
+// ResetAdminTOTP 重置指定管理员的两步验证，用于验证器和恢复码都丢失的情况
+// 账号被要求启用两步验证时，下次登录后需重新启用
+// 处理 DELETE /api/admin/admins/:username/totp
+func (h *AdminHandlers) ResetAdminTOTP(c *gin.Context) {
+	// 获取请求ID用于日志关联
+	requestID, _ := c.Get("request_id")
+	username := c.Param("username")
+
+	if err := h.repository.DisableAdminTOTP(c.Request.Context(), username); err != nil {
+		h.respondAdminError(c, requestID, err, "Failed to reset two-factor authentication")
+		return
+	}
+
+	h.logger.WithFields(logrus.Fields{
+		"request_id": requestID,
+		"username":   username,
+		"reset_by":   c.GetString("admin_username"),
+	}).Info("admin two-factor authentication reset")
+
+	setAudit(c, "totp.reset", username, nil, nil)
+	c.JSON(200, SuccessResponse(gin.H{
+		"message":  "Two-factor authentication reset successfully",
+		"username": username,
+	}))
+}
+
+// respondTOTPError 将两步验证相关的错误转换为HTTP响应
+func (h *AdminHandlers) respondTOTPError(c *gin.Context, requestID interface{}, err error, message string) {
+	switch {
+	case errors.Is(err, auth.ErrTOTPRequired):
+		c.JSON(400, ErrorResponse("VALIDATION_ERROR", "code is required"))
+	case errors.Is(err, auth.ErrInvalidTOTP):
+		c.JSON(400, ErrorResponse("INVALID_TOTP", "Invalid two-factor authentication code"))
+	case errors.Is(err, auth.ErrTOTPAlreadyEnabled):
+		c.JSON(409, ErrorResponse("TOTP_ALREADY_ENABLED", "Two-factor authentication is already enabled"))
+	case errors.Is(err, auth.ErrTOTPNotEnabled):
+		c.JSON(409, ErrorResponse("TOTP_NOT_ENABLED", "Two-factor authentication is not enabled"))
+	case errors.Is(err, storage.ErrAdminNotFound):
+		c.JSON(404, ErrorResponse("NOT_FOUND", "Admin not found"))
+	default:
+		h.logger.WithFields(logrus.Fields{
+			"request_id": requestID,
+			"error":      err.Error(),
+		}).Error(message)
+
+		c.JSON(500, ErrorResponse("DATABASE_ERROR", message))
+	}
+}
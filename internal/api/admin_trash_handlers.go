@@ -0,0 +1,279 @@
+package api
+
+import (
+	"cdk-get/internal/storage"
+	"errors"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+)
+
+// DeleteUser 删除用户处理器（移至回收站）
+// 处理 DELETE /api/admin/users/:fid
+func (h *AdminHandlers) DeleteUser(c *gin.Context) {
+	// 获取请求ID用于日志关联
+	requestID, _ := c.Get("request_id")
+
+	fid := strings.TrimSpace(c.Param("fid"))
+	if fid == "" {
+		c.JSON(400, ErrorResponse("VALIDATION_ERROR", "fid is required"))
+		return
+	}
+
+	ctx := c.Request.Context()
+
+	// 记录删除前的用户，写入审计日志
+	before, _ := h.repository.GetUser(ctx, fid)
+
+	if err := h.repository.DeleteUser(ctx, fid); err != nil {
+		if errors.Is(err, storage.ErrUserNotFound) {
+			h.logger.WithFields(logrus.Fields{
+				"request_id": requestID,
+				"fid":        fid,
+			}).Warn("user not found for deletion")
+
+			c.JSON(404, ErrorResponse("NOT_FOUND", "User not found"))
+			return
+		}
+
+		h.logger.WithFields(logrus.Fields{
+			"request_id": requestID,
+			"fid":        fid,
+			"error":      err.Error(),
+		}).Error("failed to delete user")
+
+		c.JSON(500, ErrorResponse("DATABASE_ERROR", "Failed to delete user"))
+		return
+	}
+
+	h.logger.WithFields(logrus.Fields{
+		"request_id": requestID,
+		"fid":        fid,
+	}).Info("user moved to trash")
+
+	setAudit(c, "user.delete", fid, before, nil)
+	c.JSON(200, SuccessResponse(gin.H{
+		"message": "User moved to trash",
+		"fid":     fid,
+	}))
+}
+
+// ListTrash 获取回收站内容处理器
+// 处理 GET /api/admin/trash
+func (h *AdminHandlers) ListTrash(c *gin.Context) {
+	// 获取请求ID用于日志关联
+	requestID, _ := c.Get("request_id")
+	ctx := c.Request.Context()
+
+	tasks, err := h.repository.ListDeletedTasks(ctx)
+	if err != nil {
+		h.logger.WithFields(logrus.Fields{
+			"request_id": requestID,
+			"error":      err.Error(),
+		}).Error("failed to fetch deleted tasks")
+
+		c.JSON(500, ErrorResponse("DATABASE_ERROR", "Failed to fetch deleted tasks"))
+		return
+	}
+
+	users, err := h.repository.ListDeletedUsers(ctx)
+	if err != nil {
+		h.logger.WithFields(logrus.Fields{
+			"request_id": requestID,
+			"error":      err.Error(),
+		}).Error("failed to fetch deleted users")
+
+		c.JSON(500, ErrorResponse("DATABASE_ERROR", "Failed to fetch deleted users"))
+		return
+	}
+
+	// 处理空列表情况 - 返回空数组而不是nil
+	if tasks == nil {
+		tasks = []*storage.Task{}
+	}
+	if users == nil {
+		users = []*storage.User{}
+	}
+
+	h.logger.WithFields(logrus.Fields{
+		"request_id": requestID,
+		"tasks":      len(tasks),
+		"users":      len(users),
+	}).Info("trash fetched successfully")
+
+	c.JSON(200, SuccessResponse(gin.H{
+		"tasks": tasks,
+		"users": users,
+	}))
+}
+
+// RestoreTask 恢复任务处理器
+// 处理 POST /api/admin/tasks/:code/restore
+func (h *AdminHandlers) RestoreTask(c *gin.Context) {
+	// 获取请求ID用于日志关联
+	requestID, _ := c.Get("request_id")
+
+	code := strings.TrimSpace(c.Param("code"))
+	if code == "" {
+		c.JSON(400, ErrorResponse("VALIDATION_ERROR", "code is required"))
+		return
+	}
+
+	ctx := c.Request.Context()
+
+	if err := h.repository.RestoreTask(ctx, code); err != nil {
+		if errors.Is(err, storage.ErrTaskNotFound) {
+			c.JSON(404, ErrorResponse("NOT_FOUND", "Task not found in trash"))
+			return
+		}
+
+		h.logger.WithFields(logrus.Fields{
+			"request_id": requestID,
+			"code":       code,
+			"error":      err.Error(),
+		}).Error("failed to restore task")
+
+		c.JSON(500, ErrorResponse("DATABASE_ERROR", "Failed to restore task"))
+		return
+	}
+
+	h.logger.WithFields(logrus.Fields{
+		"request_id": requestID,
+		"code":       code,
+	}).Info("task restored successfully")
+
+	restored, _ := h.repository.GetTaskByCode(ctx, code)
+	setAudit(c, "task.restore", code, nil, restored)
+	c.JSON(200, SuccessResponse(gin.H{
+		"message": "Task restored successfully",
+		"code":    code,
+	}))
+}
+
+// RestoreUser 恢复用户处理器
+// 处理 POST /api/admin/users/:fid/restore
+func (h *AdminHandlers) RestoreUser(c *gin.Context) {
+	// 获取请求ID用于日志关联
+	requestID, _ := c.Get("request_id")
+
+	fid := strings.TrimSpace(c.Param("fid"))
+	if fid == "" {
+		c.JSON(400, ErrorResponse("VALIDATION_ERROR", "fid is required"))
+		return
+	}
+
+	ctx := c.Request.Context()
+
+	if err := h.repository.RestoreUser(ctx, fid); err != nil {
+		if errors.Is(err, storage.ErrUserNotFound) {
+			c.JSON(404, ErrorResponse("NOT_FOUND", "User not found in trash"))
+			return
+		}
+
+		h.logger.WithFields(logrus.Fields{
+			"request_id": requestID,
+			"fid":        fid,
+			"error":      err.Error(),
+		}).Error("failed to restore user")
+
+		c.JSON(500, ErrorResponse("DATABASE_ERROR", "Failed to restore user"))
+		return
+	}
+
+	h.logger.WithFields(logrus.Fields{
+		"request_id": requestID,
+		"fid":        fid,
+	}).Info("user restored successfully")
+
+	restored, _ := h.repository.GetUser(ctx, fid)
+	setAudit(c, "user.restore", fid, nil, restored)
+	c.JSON(200, SuccessResponse(gin.H{
+		"message": "User restored successfully",
+		"fid":     fid,
+	}))
+}
+
+// PurgeTask 彻底删除任务处理器
+// 处理 DELETE /api/admin/trash/tasks/:code
+func (h *AdminHandlers) PurgeTask(c *gin.Context) {
+	// 获取请求ID用于日志关联
+	requestID, _ := c.Get("request_id")
+
+	code := strings.TrimSpace(c.Param("code"))
+	if code == "" {
+		c.JSON(400, ErrorResponse("VALIDATION_ERROR", "code is required"))
+		return
+	}
+
+	ctx := c.Request.Context()
+
+	if err := h.repository.PurgeTask(ctx, code); err != nil {
+		if errors.Is(err, storage.ErrTaskNotFound) {
+			c.JSON(404, ErrorResponse("NOT_FOUND", "Task not found in trash"))
+			return
+		}
+
+		h.logger.WithFields(logrus.Fields{
+			"request_id": requestID,
+			"code":       code,
+			"error":      err.Error(),
+		}).Error("failed to purge task")
+
+		c.JSON(500, ErrorResponse("DATABASE_ERROR", "Failed to purge task"))
+		return
+	}
+
+	h.logger.WithFields(logrus.Fields{
+		"request_id": requestID,
+		"code":       code,
+	}).Info("task purged successfully")
+
+	setAudit(c, "task.purge", code, nil, nil)
+	c.JSON(200, SuccessResponse(gin.H{
+		"message": "Task permanently deleted",
+		"code":    code,
+	}))
+}
+
+// PurgeUser 彻底删除用户处理器
+// 处理 DELETE /api/admin/trash/users/:fid
+func (h *AdminHandlers) PurgeUser(c *gin.Context) {
+	// 获取请求ID用于日志关联
+	requestID, _ := c.Get("request_id")
+
+	fid := strings.TrimSpace(c.Param("fid"))
+	if fid == "" {
+		c.JSON(400, ErrorResponse("VALIDATION_ERROR", "fid is required"))
+		return
+	}
+
+	ctx := c.Request.Context()
+
+	if err := h.repository.PurgeUser(ctx, fid); err != nil {
+		if errors.Is(err, storage.ErrUserNotFound) {
+			c.JSON(404, ErrorResponse("NOT_FOUND", "User not found in trash"))
+			return
+		}
+
+		h.logger.WithFields(logrus.Fields{
+			"request_id": requestID,
+			"fid":        fid,
+			"error":      err.Error(),
+		}).Error("failed to purge user")
+
+		c.JSON(500, ErrorResponse("DATABASE_ERROR", "Failed to purge user"))
+		return
+	}
+
+	h.logger.WithFields(logrus.Fields{
+		"request_id": requestID,
+		"fid":        fid,
+	}).Info("user purged successfully")
+
+	setAudit(c, "user.purge", fid, nil, nil)
+	c.JSON(200, SuccessResponse(gin.H{
+		"message": "User permanently deleted",
+		"fid":     fid,
+	}))
+}
@@ -0,0 +1,185 @@
+package api
+
+import (
+	"cdk-get/internal/storage"
+	"errors"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+)
+
+// UpdateUserRequest 更新用户请求结构
+// 所有字段均为可选，仅更新请求中出现的字段
+type UpdateUserRequest struct {
+	Nickname    *string  `json:"nickname"`
+	KID         *int     `json:"kid"`
+	AvatarImage *string  `json:"avatar_image"`
+	Disabled    *bool    `json:"disabled"`
+	GroupIDs    *[]int64 `json:"group_ids"`
+	// NotificationTargets 以列表覆盖用户的个人通知目标，空列表表示取消订阅
+	NotificationTargets *[]NotificationTargetRequest `json:"notification_targets"`
+}
+
+// NotificationTargetRequest 个人通知目标
+type NotificationTargetRequest struct {
+	Type   string `json:"type" binding:"required"`   // wxpusher, email, telegram, webhook
+	Target string `json:"target" binding:"required"` // WxPusher UID / 邮箱 / Telegram chat id / webhook 地址
+}
+
+// GetUser 获取用户详情处理器
+// 处理 GET /api/admin/users/:fid，返回用户资料、个人通知目标及昵称、区服、头像的变更记录
+func (h *AdminHandlers) GetUser(c *gin.Context) {
+	// 获取请求ID用于日志关联
+	requestID, _ := c.Get("request_id")
+
+	fid := strings.TrimSpace(c.Param("fid"))
+	ctx := c.Request.Context()
+
+	user, err := h.repository.GetUser(ctx, fid)
+	if err != nil {
+		c.JSON(404, ErrorResponse("NOT_FOUND", "User not found"))
+		return
+	}
+
+	history, err := h.repository.ListUserProfileHistory(ctx, fid)
+	if err != nil {
+		h.logger.WithFields(logrus.Fields{
+			"request_id": requestID,
+			"fid":        fid,
+			"error":      err.Error(),
+		}).Error("failed to fetch profile history")
+
+		c.JSON(500, ErrorResponse("DATABASE_ERROR", "Failed to fetch profile history"))
+		return
+	}
+
+	targets, err := h.repository.ListUserNotificationTargets(ctx, fid)
+	if err != nil {
+		h.logger.WithFields(logrus.Fields{
+			"request_id": requestID,
+			"fid":        fid,
+			"error":      err.Error(),
+		}).Error("failed to fetch notification targets")
+
+		c.JSON(500, ErrorResponse("DATABASE_ERROR", "Failed to fetch notification targets"))
+		return
+	}
+
+	// 处理空列表情况 - 返回空数组而不是nil
+	if history == nil {
+		history = []*storage.ProfileChange{}
+	}
+	if targets == nil {
+		targets = []*storage.NotificationTarget{}
+	}
+
+	c.JSON(200, SuccessResponse(gin.H{
+		"user":                 user,
+		"notification_targets": targets,
+		"profile_history":      history,
+	}))
+}
+
+// UpdateUser 更新用户处理器
+// 处理 PUT /api/admin/users/:fid
+func (h *AdminHandlers) UpdateUser(c *gin.Context) {
+	// 获取请求ID用于日志关联
+	requestID, _ := c.Get("request_id")
+
+	fid := strings.TrimSpace(c.Param("fid"))
+	if fid == "" {
+		c.JSON(400, ErrorResponse("VALIDATION_ERROR", "fid is required"))
+		return
+	}
+
+	var req UpdateUserRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		h.logger.WithFields(logrus.Fields{
+			"request_id": requestID,
+			"error":      err.Error(),
+		}).Warn("update user request validation failed")
+
+		c.JSON(400, ErrorResponse("VALIDATION_ERROR", err.Error()))
+		return
+	}
+
+	ctx := c.Request.Context()
+
+	user, err := h.repository.GetUser(ctx, fid)
+	if err != nil {
+		c.JSON(404, ErrorResponse("NOT_FOUND", "User not found"))
+		return
+	}
+	// user 会在更新时被修改，保留更新前的副本写入审计日志
+	before := *user
+
+	// 在事务中更新，保证资料、状态、分组和通知目标同时生效
+	err = h.repository.WithTransaction(ctx, func(repo storage.Repository) error {
+		if req.Nickname != nil || req.KID != nil || req.AvatarImage != nil {
+			if req.Nickname != nil {
+				user.Nickname = *req.Nickname
+			}
+			if req.KID != nil {
+				user.KID = *req.KID
+			}
+			if req.AvatarImage != nil {
+				user.AvatarImage = *req.AvatarImage
+			}
+			if err := repo.UpdateUser(ctx, user); err != nil {
+				return err
+			}
+		}
+		if req.Disabled != nil {
+			if err := repo.SetUserDisabled(ctx, fid, *req.Disabled); err != nil {
+				return err
+			}
+		}
+		if req.GroupIDs != nil {
+			if err := repo.SetUserGroups(ctx, fid, *req.GroupIDs); err != nil {
+				return err
+			}
+		}
+		if req.NotificationTargets != nil {
+			targets := make([]*storage.NotificationTarget, 0, len(*req.NotificationTargets))
+			for _, t := range *req.NotificationTargets {
+				targets = append(targets, &storage.NotificationTarget{Type: t.Type, Target: t.Target})
+			}
+			if err := repo.SetUserNotificationTargets(ctx, fid, targets); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		switch {
+		case errors.Is(err, storage.ErrUserNotFound):
+			c.JSON(404, ErrorResponse("NOT_FOUND", "User not found"))
+		case errors.Is(err, storage.ErrGroupNotFound):
+			c.JSON(400, ErrorResponse("VALIDATION_ERROR", "Group not found"))
+		case isValidationError(err):
+			c.JSON(400, ErrorResponse("VALIDATION_ERROR", err.Error()))
+		default:
+			h.logger.WithFields(logrus.Fields{
+				"request_id": requestID,
+				"fid":        fid,
+				"error":      err.Error(),
+			}).Error("failed to update user")
+
+			c.JSON(500, ErrorResponse("DATABASE_ERROR", "Failed to update user"))
+		}
+		return
+	}
+
+	h.logger.WithFields(logrus.Fields{
+		"request_id": requestID,
+		"fid":        fid,
+	}).Info("user updated successfully")
+
+	after, _ := h.repository.GetUser(ctx, fid)
+	setAudit(c, "user.update", fid, &before, after)
+	c.JSON(200, SuccessResponse(gin.H{
+		"message": "User updated successfully",
+		"fid":     fid,
+	}))
+}
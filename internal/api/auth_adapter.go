@@ -2,6 +2,7 @@ package api
 
 import (
 	"cdk-get/internal/auth"
+	"context"
 )
 
 // authServiceAdapter 适配器，将auth.AuthService转换为middleware.AuthService
@@ -17,13 +18,20 @@ func NewAuthServiceAdapter(authService auth.AuthService) AuthService {
 }
 
 // ValidateToken 验证JWT令牌并返回声明
-func (a *authServiceAdapter) ValidateToken(token string) (*AuthClaims, error) {
-	claims, err := a.authService.ValidateToken(token)
+func (a *authServiceAdapter) ValidateToken(ctx context.Context, token string) (*AuthClaims, error) {
+	claims, err := a.authService.ValidateToken(ctx, token)
 	if err != nil {
 		return nil, err
 	}
 
-	return &AuthClaims{
-		Username: claims.Username,
-	}, nil
+	authClaims := &AuthClaims{
+		Username:          claims.Username,
+		Role:              claims.Role,
+		SessionID:         claims.SessionID,
+		TOTPSetupRequired: claims.TOTPSetupRequired,
+	}
+	if claims.ExpiresAt != nil {
+		authClaims.ExpiresAt = claims.ExpiresAt.Time
+	}
+	return authClaims, nil
 }
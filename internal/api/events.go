@@ -0,0 +1,38 @@
+package api
+
+import (
+	"cdk-get/internal/notification"
+	"cdk-get/internal/service"
+	"context"
+	"fmt"
+
+	"github.com/sirupsen/logrus"
+)
+
+// publishEvent 异步发布通知事件，不阻塞请求
+// 请求结束后其 context 会被取消，因此使用独立的 context
+func publishEvent(notificationService *service.NotificationService, logger *logrus.Logger, requestID any, event notification.Event) {
+	if notificationService == nil {
+		return
+	}
+	go func() {
+		if err := notificationService.Publish(context.Background(), event); err != nil {
+			logger.WithFields(logrus.Fields{
+				"request_id": requestID,
+				"event":      event.Type,
+				"error":      err.Error(),
+			}).Warn("failed to publish notification event")
+		}
+	}()
+}
+
+// newCodeDiscoveredEvent 构建新增兑换码事件
+func newCodeDiscoveredEvent(code, source string) notification.Event {
+	return notification.NewEvent(
+		notification.EventNewCodeDiscovered,
+		"发现新兑换码",
+		fmt.Sprintf("新增兑换码[%s]", code),
+		fmt.Sprintf("兑换码: %s\n来源: %s", code, source),
+		map[string]any{"code": code, "source": source},
+	)
+}
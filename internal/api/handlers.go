@@ -1,28 +1,37 @@
 package api
 
 import (
+	"cdk-get/internal/giftcode"
 	"cdk-get/internal/service"
 	"cdk-get/internal/storage"
+	"context"
+	"errors"
 	"strconv"
 	"strings"
 
+	apperrors "cdk-get/internal/errors"
+
 	"github.com/gin-gonic/gin"
 	"github.com/sirupsen/logrus"
 )
 
 // Handlers API处理器集合
 type Handlers struct {
-	giftService *service.GiftService
-	storage     storage.KeyStorage
-	logger      *logrus.Logger
+	giftService         *service.GiftService
+	storage             storage.KeyStorage
+	repository          storage.Repository
+	notificationService *service.NotificationService
+	logger              *logrus.Logger
 }
 
 // NewHandlers 创建API处理器
-func NewHandlers(giftService *service.GiftService, storage storage.KeyStorage, logger *logrus.Logger) *Handlers {
+func NewHandlers(giftService *service.GiftService, storage storage.KeyStorage, repository storage.Repository, notificationService *service.NotificationService, logger *logrus.Logger) *Handlers {
 	return &Handlers{
-		giftService: giftService,
-		storage:     storage,
-		logger:      logger,
+		giftService:         giftService,
+		storage:             storage,
+		repository:          repository,
+		notificationService: notificationService,
+		logger:              logger,
 	}
 }
 
@@ -45,9 +54,13 @@ func (h *Handlers) AddGiftCode(c *gin.Context) {
 		return
 	}
 
-	// 添加任务到数据库
-	err := h.storage.AddTask(code)
-	if err != nil {
+	// 回收站中的兑换码不会重新创建任务
+	if _, _, err := h.enqueueGiftCode(c.Request.Context(), requestID, code, "api"); err != nil {
+		if isAlreadyExistsError(err) {
+			c.JSON(409, ErrorResponse("ALREADY_EXISTS", "Gift code task is in the trash"))
+			return
+		}
+
 		h.logger.WithFields(logrus.Fields{
 			"request_id": requestID,
 			"code":       code,
@@ -58,17 +71,39 @@ func (h *Handlers) AddGiftCode(c *gin.Context) {
 		return
 	}
 
-	h.logger.WithFields(logrus.Fields{
-		"request_id": requestID,
-		"code":       code,
-	}).Info("gift code task created successfully")
-
 	c.JSON(200, SuccessResponse(gin.H{
 		"message": "Gift code task added successfully",
 		"code":    code,
 	}))
 }
 
+// GetGiftCode 查询兑换码任务状态
+// 处理 GET /giftcode/:code
+func (h *Handlers) GetGiftCode(c *gin.Context) {
+	// 获取请求ID用于日志关联
+	requestID, _ := c.Get("request_id")
+	code := strings.TrimSpace(c.Param("code"))
+
+	task, err := h.repository.GetTaskByCode(c.Request.Context(), code)
+	if err != nil {
+		if isNotFoundError(err) {
+			c.JSON(404, ErrorResponse("NOT_FOUND", "Gift code task not found"))
+			return
+		}
+
+		h.logger.WithFields(logrus.Fields{
+			"request_id": requestID,
+			"code":       code,
+			"error":      err.Error(),
+		}).Error("failed to get task")
+
+		c.JSON(500, ErrorResponse("DATABASE_ERROR", "Failed to get gift code task"))
+		return
+	}
+
+	c.JSON(200, SuccessResponse(task))
+}
+
 // AddUser 添加用户
 func (h *Handlers) AddUser(c *gin.Context) {
 	// 获取请求ID用于日志关联
@@ -101,9 +136,20 @@ func (h *Handlers) AddUser(c *gin.Context) {
 		return
 	}
 
+	// 通过玩家接口校验fid并获取资料
+	player, ok := lookupPlayer(c, h.giftService, h.logger, strconv.FormatInt(ifid, 10))
+	if !ok {
+		return
+	}
+	d := player.Data
+
 	// 保存用户到数据库
-	err = h.storage.SaveFidInfo(int(ifid), "", -1, "")
-	if err != nil {
+	if _, err := h.savePlayer(c.Request.Context(), requestID, player); err != nil {
+		if isAlreadyExistsError(err) {
+			c.JSON(409, ErrorResponse("ALREADY_EXISTS", "User is in the trash"))
+			return
+		}
+
 		h.logger.WithFields(logrus.Fields{
 			"request_id": requestID,
 			"fid":        fid,
@@ -114,19 +160,85 @@ func (h *Handlers) AddUser(c *gin.Context) {
 		return
 	}
 
+	c.JSON(200, SuccessResponse(gin.H{
+		"message":  "User added successfully",
+		"fid":      fid,
+		"nickname": d.Nickname,
+		"kid":      d.Kid,
+	}))
+}
+
+// enqueueGiftCode 创建兑换码任务，返回任务以及是否为新兑换码
+// 已存在的兑换码不会重复创建；兑换码在回收站中时返回 ALREADY_EXISTS
+func (h *Handlers) enqueueGiftCode(ctx context.Context, requestID any, code, source string) (*storage.Task, bool, error) {
+	code = strings.TrimSpace(code)
+	if code == "" {
+		return nil, false, apperrors.NewValidationError("code", "must not be empty")
+	}
+
+	created, err := h.repository.CreateTask(ctx, code)
+	if err != nil {
+		return nil, false, err
+	}
+
+	task, err := h.repository.GetTaskByCode(ctx, code)
+	if isNotFoundError(err) {
+		return nil, false, apperrors.New(apperrors.ErrCodeAlreadyExists, "gift code task is in the trash: "+code).
+			WithContext("code", code)
+	}
+	if err != nil {
+		return nil, false, err
+	}
+
+	if created {
+		h.logger.WithFields(logrus.Fields{
+			"request_id": requestID,
+			"code":       code,
+		}).Info("gift code task created successfully")
+
+		publishEvent(h.notificationService, h.logger, requestID, newCodeDiscoveredEvent(code, source))
+	}
+
+	return task, created, nil
+}
+
+// savePlayer 保存玩家接口返回的资料，已存在的用户会更新昵称、区服和头像
+// 用户在回收站中时不会恢复也不会修改资料，返回 ALREADY_EXISTS
+func (h *Handlers) savePlayer(ctx context.Context, requestID any, player *giftcode.DdPlayerMsg) (*storage.User, error) {
+	d := player.Data
+	fid := strconv.Itoa(d.Fid)
+
+	err := h.repository.SaveUser(ctx, &storage.User{
+		FID:         fid,
+		Nickname:    d.Nickname,
+		KID:         d.Kid,
+		AvatarImage: d.Avatar,
+	})
+	if errors.Is(err, storage.ErrUserInTrash) {
+		return nil, apperrors.New(apperrors.ErrCodeAlreadyExists, "user is in the trash: "+fid).
+			WithContext("fid", fid)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	user, err := h.repository.GetUser(ctx, fid)
+	if err != nil {
+		return nil, err
+	}
+
 	h.logger.WithFields(logrus.Fields{
 		"request_id": requestID,
 		"fid":        fid,
+		"nickname":   d.Nickname,
+		"kid":        d.Kid,
 	}).Info("user added successfully")
 
-	c.JSON(200, SuccessResponse(gin.H{
-		"message": "User added successfully",
-		"fid":     fid,
-	}))
+	return user, nil
 }
 
 // GetIP 获取服务器IP地址
 func (h *Handlers) GetIP(c *gin.Context) {
 	// 返回配置的IP地址
-	c.String(200, "47.120.61.46")
+	c.String(200, serverIP)
 }
@@ -0,0 +1,45 @@
+package api
+
+import (
+	"cdk-get/internal/storage"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// adminDefaultPageSize 管理接口列表默认每页数量
+const adminDefaultPageSize = 100
+
+// listOptions 读取 cursor、limit、sort、order 分页参数，limit 无效时使用 defaultLimit
+func listOptions(c *gin.Context, defaultLimit int) storage.ListOptions {
+	opts := storage.ListOptions{
+		Cursor: c.Query("cursor"),
+		Limit:  defaultLimit,
+		Sort:   c.Query("sort"),
+		Order:  c.Query("order"),
+	}
+	if limitStr := c.Query("limit"); limitStr != "" {
+		if parsedLimit, err := strconv.Atoi(limitStr); err == nil && parsedLimit > 0 {
+			opts.Limit = parsedLimit
+		}
+	}
+	return opts
+}
+
+// timeRange 读取 RFC 3339 格式的 since、until 参数，格式错误时写入 400 响应并返回 false
+func timeRange(c *gin.Context, since, until *time.Time) bool {
+	for param, dst := range map[string]*time.Time{"since": since, "until": until} {
+		value := c.Query(param)
+		if value == "" {
+			continue
+		}
+		parsed, err := time.Parse(time.RFC3339, value)
+		if err != nil {
+			c.JSON(400, ErrorResponse("VALIDATION_ERROR", param+" must be an RFC 3339 time"))
+			return false
+		}
+		*dst = parsed
+	}
+	return true
+}
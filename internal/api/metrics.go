@@ -0,0 +1,33 @@
+package api
+
+import (
+	"cdk-get/internal/metrics"
+	"crypto/subtle"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+)
+
+// MetricsHandler Prometheus 指标处理器
+// 处理 GET /metrics，token 非空时请求须携带 Authorization: Bearer <token>
+func MetricsHandler(token string, logger *logrus.Logger) gin.HandlerFunc {
+	handler := metrics.Handler()
+	return func(c *gin.Context) {
+		if token != "" {
+			provided, ok := strings.CutPrefix(c.GetHeader("Authorization"), "Bearer ")
+			if !ok || subtle.ConstantTimeCompare([]byte(provided), []byte(token)) != 1 {
+				requestID, _ := c.Get("request_id")
+				logger.WithFields(logrus.Fields{
+					"request_id": requestID,
+					"ip":         c.ClientIP(),
+				}).Warn("metrics request with missing or invalid token")
+
+				c.Header("WWW-Authenticate", `Bearer realm="metrics"`)
+				c.JSON(401, ErrorResponse("UNAUTHORIZED", "Missing or invalid metrics token"))
+				return
+			}
+		}
+		handler.ServeHTTP(c.Writer, c.Request)
+	}
+}
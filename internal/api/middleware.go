@@ -1,13 +1,22 @@
 package api
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"sync"
 	"time"
 
+	"cdk-get/internal/auth"
+	"cdk-get/internal/metrics"
+	"cdk-get/internal/storage"
+
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
 	"github.com/sirupsen/logrus"
-	"golang.org/x/time/rate"
 )
 
 // RequestIDMiddleware 为每个请求生成唯一ID
@@ -52,6 +61,13 @@ func LoggerMiddleware(logger *logrus.Logger) gin.HandlerFunc {
 		// 计算请求耗时
 		duration := time.Since(startTime)
 
+		// 按路由模板记录请求指标，未匹配路由的请求合并为一个序列
+		route := c.FullPath()
+		if route == "" {
+			route = "unmatched"
+		}
+		metrics.ObserveHTTPRequest(c.Request.Method, route, c.Writer.Status(), duration)
+
 		// 记录响应信息
 		logger.WithFields(logrus.Fields{
 			"request_id": requestID,
@@ -90,14 +106,33 @@ func RecoveryMiddleware(logger *logrus.Logger) gin.HandlerFunc {
 	}
 }
 
-// RateLimitMiddleware 限流中间件
-func RateLimitMiddleware(rateLimit int, burst int) gin.HandlerFunc {
-	// 创建限流器
-	limiter := rate.NewLimiter(rate.Limit(rateLimit), burst)
-
+// RateLimitMiddleware 按计数对象限流的中间件，limiter 为 nil 时不限流
+// 响应中带有 RateLimit-Limit、RateLimit-Remaining、RateLimit-Reset 头，被拒绝时另带 Retry-After
+func RateLimitMiddleware(limiter *KeyedLimiter, logger *logrus.Logger) gin.HandlerFunc {
 	return func(c *gin.Context) {
+		if limiter == nil {
+			c.Next()
+			return
+		}
+
+		key := rateLimitKey(c)
+		result := limiter.Allow(key)
+
+		c.Header("RateLimit-Limit", strconv.Itoa(result.Limit))
+		c.Header("RateLimit-Remaining", strconv.Itoa(result.Remaining))
+		c.Header("RateLimit-Reset", strconv.Itoa(ceilSeconds(result.Reset)))
+
 		// 检查是否允许请求
-		if !limiter.Allow() {
+		if !result.Allowed {
+			requestID, _ := c.Get("request_id")
+			logger.WithFields(logrus.Fields{
+				"request_id": requestID,
+				"group":      limiter.group,
+				"key":        key,
+				"path":       c.Request.URL.Path,
+			}).Warn("rate limit exceeded")
+
+			c.Header("Retry-After", strconv.Itoa(max(ceilSeconds(result.RetryAfter), 1)))
 			c.JSON(429, ErrorResponse("RATE_LIMIT_EXCEEDED", "Too many requests"))
 			c.Abort()
 			return
@@ -107,6 +142,23 @@ func RateLimitMiddleware(rateLimit int, burst int) gin.HandlerFunc {
 	}
 }
 
+// rateLimitKey 限流的计数对象：已认证的管理员按用户名，通过 API 密钥认证的请求按密钥，其余按客户端 IP
+// 客户端 IP 仅在请求来自 server.trusted_proxies 时取自 X-Forwarded-For，客户端无法伪造请求头换取新的计数
+func rateLimitKey(c *gin.Context) string {
+	if username := c.GetString("admin_username"); username != "" {
+		return "admin:" + username
+	}
+	if keyID := c.GetString("api_key_id"); keyID != "" {
+		return "key:" + keyID
+	}
+	return "ip:" + c.ClientIP()
+}
+
+// ceilSeconds 将时长向上取整为秒
+func ceilSeconds(d time.Duration) int {
+	return int(math.Ceil(d.Seconds()))
+}
+
 // ValidationMiddleware 请求验证中间件
 func ValidationMiddleware() gin.HandlerFunc {
 	return func(c *gin.Context) {
@@ -136,12 +188,16 @@ func ValidationMiddleware() gin.HandlerFunc {
 
 // AuthService 认证服务接口（用于中间件）
 type AuthService interface {
-	ValidateToken(token string) (*AuthClaims, error)
+	ValidateToken(ctx context.Context, token string) (*AuthClaims, error)
 }
 
 // AuthClaims JWT声明结构（用于中间件）
 type AuthClaims struct {
-	Username string
+	Username          string
+	Role              string
+	SessionID         string
+	TOTPSetupRequired bool
+	ExpiresAt         time.Time // 令牌过期时间，令牌未设置时为零值
 }
 
 // AuthMiddleware JWT认证中间件
@@ -192,7 +248,7 @@ func AuthMiddleware(authService AuthService, logger *logrus.Logger) gin.HandlerF
 		}
 
 		// 验证token
-		claims, err := authService.ValidateToken(token)
+		claims, err := authService.ValidateToken(c.Request.Context(), token)
 		if err != nil {
 			logger.WithFields(logrus.Fields{
 				"request_id": requestID,
@@ -207,15 +263,264 @@ func AuthMiddleware(authService AuthService, logger *logrus.Logger) gin.HandlerF
 			return
 		}
 
-		// 将管理员用户名存入上下文
+		// 将管理员用户名、角色和会话存入上下文
 		c.Set("admin_username", claims.Username)
+		c.Set("admin_role", claims.Role)
+		c.Set("admin_session_id", claims.SessionID)
+		c.Set("admin_totp_setup_required", claims.TOTPSetupRequired)
+		c.Set("admin_token_expires_at", claims.ExpiresAt)
 
 		logger.WithFields(logrus.Fields{
 			"request_id": requestID,
 			"username":   claims.Username,
+			"role":       claims.Role,
 			"path":       c.Request.URL.Path,
 		}).Debug("authentication successful")
 
 		c.Next()
 	}
 }
+
+// RequireTOTPEnrollment 两步验证启用检查中间件，需在 AuthMiddleware 之后使用
+// 账号被要求启用两步验证但尚未启用时返回 403，启用前只能访问账号相关接口
+func RequireTOTPEnrollment(logger *logrus.Logger) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if c.GetBool("admin_totp_setup_required") {
+			requestID, _ := c.Get("request_id")
+			logger.WithFields(logrus.Fields{
+				"request_id": requestID,
+				"username":   c.GetString("admin_username"),
+				"method":     c.Request.Method,
+				"path":       c.Request.URL.Path,
+			}).Warn("two-factor enrollment required")
+
+			c.JSON(403, ErrorResponse("TOTP_SETUP_REQUIRED", "Two-factor authentication must be enabled for this account"))
+			c.Abort()
+			return
+		}
+
+		c.Next()
+	}
+}
+
+// RequireRole 角色校验中间件，需在 AuthMiddleware 之后使用
+// 当前管理员的角色低于 required 时返回 403
+func RequireRole(required string, logger *logrus.Logger) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		role := c.GetString("admin_role")
+		if !auth.HasRole(role, required) {
+			requestID, _ := c.Get("request_id")
+			logger.WithFields(logrus.Fields{
+				"request_id": requestID,
+				"username":   c.GetString("admin_username"),
+				"role":       role,
+				"required":   required,
+				"method":     c.Request.Method,
+				"path":       c.Request.URL.Path,
+			}).Warn("insufficient role")
+
+			c.JSON(403, ErrorResponse("FORBIDDEN", fmt.Sprintf("This action requires the %s role", required)))
+			c.Abort()
+			return
+		}
+
+		c.Next()
+	}
+}
+
+// APIKeyStore API 密钥存储接口（用于中间件）
+type APIKeyStore interface {
+	GetAPIKeyByHash(ctx context.Context, keyHash string) (*storage.APIKey, error)
+	RecordAPIKeyUsage(ctx context.Context, id int64, count int64, at time.Time, ip string) error
+}
+
+// apiKeyUsageInterval 每个密钥的使用情况最多每隔该时长写入一次数据库
+const apiKeyUsageInterval = time.Minute
+
+// apiKeyUsage 一个密钥在内存中累计、尚未写入数据库的使用次数
+type apiKeyUsage struct {
+	pending   int64
+	lastWrite time.Time
+}
+
+// APIKeyAuth API 密钥认证，所有路由共用同一个实例，使失败限流和使用次数按密钥累计
+type APIKeyAuth struct {
+	store    APIKeyStore
+	required bool
+	failures *KeyedLimiter // 按客户端 IP 统计无效密钥，为 nil 时不限制
+	logger   *logrus.Logger
+	now      func() time.Time
+
+	mu    sync.Mutex
+	usage map[int64]*apiKeyUsage
+}
+
+// NewAPIKeyAuth 创建 API 密钥认证
+// required 为 false 时未携带密钥的请求直接放行，携带了密钥的请求仍然校验
+// failures 限制每个客户端 IP 提交无效密钥的频率，超出后在查询数据库前直接拒绝
+func NewAPIKeyAuth(store APIKeyStore, required bool, failures *KeyedLimiter, logger *logrus.Logger) *APIKeyAuth {
+	return &APIKeyAuth{
+		store:    store,
+		required: required,
+		failures: failures,
+		logger:   logger,
+		now:      time.Now,
+		usage:    make(map[int64]*apiKeyUsage),
+	}
+}
+
+// Middleware API 密钥认证中间件，从 X-API-Key 头读取密钥并校验是否拥有 scope 权限
+// 需放在限流中间件之前，使限流按密钥计数
+func (a *APIKeyAuth) Middleware(scope string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		// 获取请求ID用于日志
+		requestID, _ := c.Get("request_id")
+
+		rawKey := c.GetHeader("X-API-Key")
+		if rawKey == "" {
+			if !a.required {
+				c.Next()
+				return
+			}
+
+			a.logger.WithFields(logrus.Fields{
+				"request_id": requestID,
+				"path":       c.Request.URL.Path,
+			}).Warn("missing api key")
+
+			c.JSON(401, ErrorResponse("API_KEY_REQUIRED", "Missing X-API-Key header"))
+			c.Abort()
+			return
+		}
+
+		// 无效密钥过多的 IP 在查询数据库前直接拒绝，防止猜测密钥
+		failureKey := "ip:" + c.ClientIP()
+		if a.failures != nil {
+			if result := a.failures.Peek(failureKey); !result.Allowed {
+				a.logger.WithFields(logrus.Fields{
+					"request_id": requestID,
+					"path":       c.Request.URL.Path,
+					"client_ip":  c.ClientIP(),
+				}).Warn("too many invalid api keys")
+
+				c.Header("Retry-After", strconv.Itoa(max(ceilSeconds(result.RetryAfter), 1)))
+				c.JSON(429, ErrorResponse("RATE_LIMIT_EXCEEDED", "Too many invalid API key attempts"))
+				c.Abort()
+				return
+			}
+		}
+
+		key, err := a.store.GetAPIKeyByHash(c.Request.Context(), auth.HashAPIKey(rawKey))
+		if err != nil {
+			if errors.Is(err, storage.ErrAPIKeyNotFound) {
+				if a.failures != nil {
+					a.failures.Allow(failureKey)
+				}
+
+				a.logger.WithFields(logrus.Fields{
+					"request_id": requestID,
+					"path":       c.Request.URL.Path,
+					"client_ip":  c.ClientIP(),
+				}).Warn("invalid api key")
+
+				c.JSON(401, ErrorResponse("INVALID_API_KEY", "Invalid or revoked API key"))
+				c.Abort()
+				return
+			}
+
+			a.logger.WithFields(logrus.Fields{
+				"request_id": requestID,
+				"error":      err.Error(),
+			}).Error("failed to look up api key")
+
+			c.JSON(500, ErrorResponse("DATABASE_ERROR", "Failed to verify API key"))
+			c.Abort()
+			return
+		}
+
+		if !key.HasScope(scope) {
+			a.logger.WithFields(logrus.Fields{
+				"request_id": requestID,
+				"api_key":    key.Name,
+				"required":   scope,
+				"path":       c.Request.URL.Path,
+			}).Warn("api key missing scope")
+
+			c.JSON(403, ErrorResponse("INSUFFICIENT_SCOPE", fmt.Sprintf("This API key lacks the %s scope", scope)))
+			c.Abort()
+			return
+		}
+
+		a.recordUsage(c, key)
+
+		// 将密钥存入上下文，限流按密钥计数
+		c.Set("api_key_id", strconv.FormatInt(key.ID, 10))
+		c.Set("api_key_name", key.Name)
+
+		c.Next()
+	}
+}
+
+// recordUsage 累计密钥的使用次数，距上次写入超过 apiKeyUsageInterval 时才写入数据库
+// 服务停止前最后一个间隔内的使用次数不会写入
+func (a *APIKeyAuth) recordUsage(c *gin.Context, key *storage.APIKey) {
+	now := a.now()
+
+	a.mu.Lock()
+	usage, ok := a.usage[key.ID]
+	if !ok {
+		usage = &apiKeyUsage{}
+		a.usage[key.ID] = usage
+	}
+	usage.pending++
+	if !usage.lastWrite.IsZero() && now.Sub(usage.lastWrite) < apiKeyUsageInterval {
+		a.mu.Unlock()
+		return
+	}
+	count := usage.pending
+	usage.pending = 0
+	usage.lastWrite = now
+	a.mu.Unlock()
+
+	// 记录使用情况失败不影响请求，未写入的次数留到下次写入
+	if err := a.store.RecordAPIKeyUsage(c.Request.Context(), key.ID, count, now, c.ClientIP()); err != nil {
+		a.mu.Lock()
+		usage.pending += count
+		a.mu.Unlock()
+
+		requestID, _ := c.Get("request_id")
+		a.logger.WithFields(logrus.Fields{
+			"request_id": requestID,
+			"api_key":    key.Name,
+			"error":      err.Error(),
+		}).Error("failed to record api key usage")
+	}
+}
+
+// AuditLogStore 审计日志存储接口（用于中间件）
+type AuditLogStore interface {
+	CreateAuditEntry(ctx context.Context, entry *storage.AuditEntry) error
+}
+
+// AuditMiddleware 管理操作审计中间件，需在 AuthMiddleware 之后使用
+// 修改数据的请求（POST/PUT/PATCH/DELETE）成功后写入审计日志，失败的请求没有修改数据，不记录
+// 处理器通过 setAudit 提供操作名称、操作对象和变更前后的数据，未提供时以请求方法和路由作为操作名称
+func AuditMiddleware(store AuditLogStore, logger *logrus.Logger) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Next()
+
+		switch c.Request.Method {
+		case http.MethodGet, http.MethodHead, http.MethodOptions:
+			return
+		}
+		if c.Writer.Status() >= 400 || c.GetBool(auditSkipKey) {
+			return
+		}
+
+		record := &auditRecord{action: c.Request.Method + " " + c.FullPath()}
+		if value, ok := c.Get(auditRecordKey); ok {
+			record = value.(*auditRecord)
+		}
+		saveAuditEntry(c, store, logger, c.GetString("admin_username"), record)
+	}
+}
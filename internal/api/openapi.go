@@ -0,0 +1,301 @@
+package api
+
+import (
+	"encoding/json"
+	"maps"
+	"net/http"
+	"reflect"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Route 公开接口的路由定义，同时用于注册路由和生成 OpenAPI 文档
+type Route struct {
+	Method string
+	// Path gin 格式的路由路径，如 /gift-codes/:code
+	Path string
+	// Name OpenAPI operationId
+	Name    string
+	Summary string
+	// Scope 需要的 API 密钥权限，为空表示无需密钥
+	Scope  string
+	Params []Param
+	// Request 请求体类型的零值，nil 表示没有请求体
+	Request any
+	// Response 响应 data 的类型零值，Paged 为 true 时为列表元素的类型
+	Response any
+	Paged    bool
+	// Created 为 true 时新建资源返回 201，资源已存在返回 200
+	Created bool
+	// Errors 除鉴权、限流和服务端错误以外可能返回的错误状态码
+	Errors  []int
+	Handler gin.HandlerFunc
+}
+
+// Param 路径或查询参数定义
+type Param struct {
+	Name string
+	// In 参数位置: path 或 query
+	In          string
+	Description string
+	// Type 参数类型，默认为 string
+	Type string
+	Enum []string
+}
+
+// OpenAPIDocument OpenAPI 3 文档
+type OpenAPIDocument struct {
+	OpenAPI    string                    `json:"openapi"`
+	Info       OpenAPIInfo               `json:"info"`
+	Servers    []OpenAPIServer           `json:"servers"`
+	Paths      map[string]map[string]any `json:"paths"`
+	Components OpenAPIComponents         `json:"components"`
+}
+
+// OpenAPIInfo 文档基本信息
+type OpenAPIInfo struct {
+	Title       string `json:"title"`
+	Description string `json:"description"`
+	Version     string `json:"version"`
+}
+
+// OpenAPIServer 接口地址
+type OpenAPIServer struct {
+	URL string `json:"url"`
+}
+
+// OpenAPIComponents 文档中可复用的结构定义和鉴权方式
+type OpenAPIComponents struct {
+	Schemas         map[string]any `json:"schemas"`
+	SecuritySchemes map[string]any `json:"securitySchemes"`
+}
+
+// openAPIDescription 文档说明，描述所有接口共用的约定
+const openAPIDescription = `所有接口返回 {"success": bool, "data": ..., "error": {"code", "message"}} 结构，` +
+	`错误代码与HTTP状态码一一对应。列表接口使用 limit 和 cursor 分页，` +
+	`响应中的 next_cursor 为空表示没有更多数据。需要鉴权的接口通过请求头 X-API-Key 传递 API 密钥。`
+
+// ginParamPattern 匹配 gin 路由中的路径参数
+var ginParamPattern = regexp.MustCompile(`:(\w+)`)
+
+var (
+	timeType       = reflect.TypeOf(time.Time{})
+	rawMessageType = reflect.TypeOf(json.RawMessage{})
+)
+
+// NewOpenAPIDocument 根据路由表生成 OpenAPI 3 文档，basePath 为路由表所在的路由分组
+func NewOpenAPIDocument(basePath, version string, routes []Route) *OpenAPIDocument {
+	gen := &schemaGenerator{schemas: map[string]any{}}
+	gen.schemas["ErrorResponse"] = map[string]any{
+		"type":     "object",
+		"required": []string{"success", "error"},
+		"properties": map[string]any{
+			"success": map[string]any{"type": "boolean"},
+			"error":   gen.schemaFor(reflect.TypeOf(ErrorInfo{})),
+		},
+	}
+
+	paths := map[string]map[string]any{}
+	for _, route := range routes {
+		path := ginParamPattern.ReplaceAllString(route.Path, "{$1}")
+		if paths[path] == nil {
+			paths[path] = map[string]any{}
+		}
+		paths[path][strings.ToLower(route.Method)] = gen.operation(route)
+	}
+
+	return &OpenAPIDocument{
+		OpenAPI: "3.0.3",
+		Info: OpenAPIInfo{
+			Title:       "cdk-get public API",
+			Description: openAPIDescription,
+			Version:     version,
+		},
+		Servers: []OpenAPIServer{{URL: basePath}},
+		Paths:   paths,
+		Components: OpenAPIComponents{
+			Schemas: gen.schemas,
+			SecuritySchemes: map[string]any{
+				"ApiKeyAuth": map[string]any{"type": "apiKey", "in": "header", "name": "X-API-Key"},
+			},
+		},
+	}
+}
+
+// schemaGenerator 通过反射生成 JSON Schema，结构体统一放入 components.schemas 并以 $ref 引用
+type schemaGenerator struct {
+	schemas map[string]any
+}
+
+// operation 生成单个路由的 OpenAPI operation
+func (g *schemaGenerator) operation(route Route) map[string]any {
+	op := map[string]any{
+		"operationId": route.Name,
+		"summary":     route.Summary,
+	}
+
+	params := route.Params
+	if route.Paged {
+		params = append(slices.Clone(params),
+			Param{Name: "limit", In: "query", Type: "integer", Description: "每页数量，默认 " + strconv.Itoa(v1DefaultPageSize) + "，最大 " + strconv.Itoa(v1MaxPageSize)},
+			Param{Name: "cursor", In: "query", Description: "上一页返回的 next_cursor"})
+	}
+	if len(params) > 0 {
+		parameters := make([]any, 0, len(params))
+		for _, param := range params {
+			schema := map[string]any{"type": "string"}
+			if param.Type != "" {
+				schema["type"] = param.Type
+			}
+			if len(param.Enum) > 0 {
+				schema["enum"] = param.Enum
+			}
+			parameters = append(parameters, map[string]any{
+				"name":        param.Name,
+				"in":          param.In,
+				"description": param.Description,
+				"required":    param.In == "path",
+				"schema":      schema,
+			})
+		}
+		op["parameters"] = parameters
+	}
+
+	if route.Request != nil {
+		op["requestBody"] = map[string]any{
+			"required": true,
+			"content":  jsonContent(g.schemaFor(reflect.TypeOf(route.Request))),
+		}
+	}
+
+	data := g.schemaFor(reflect.TypeOf(route.Response))
+	if route.Paged {
+		data = map[string]any{
+			"type":     "object",
+			"required": []string{"items"},
+			"properties": map[string]any{
+				"items":       map[string]any{"type": "array", "items": data},
+				"next_cursor": map[string]any{"type": "string"},
+			},
+		}
+	}
+	success := map[string]any{
+		"description": http.StatusText(http.StatusOK),
+		"content": jsonContent(map[string]any{
+			"type":     "object",
+			"required": []string{"success", "data"},
+			"properties": map[string]any{
+				"success": map[string]any{"type": "boolean"},
+				"data":    data,
+			},
+		}),
+	}
+	responses := map[string]any{"200": success}
+	if route.Created {
+		created := maps.Clone(success)
+		created["description"] = http.StatusText(http.StatusCreated)
+		responses["201"] = created
+	}
+
+	statuses := slices.Clone(route.Errors)
+	if route.Scope != "" {
+		op["description"] = "需要 API 密钥权限: " + route.Scope
+		op["security"] = []any{map[string]any{"ApiKeyAuth": []string{}}}
+		statuses = append(statuses, http.StatusUnauthorized, http.StatusForbidden)
+	}
+	statuses = append(statuses, http.StatusTooManyRequests, http.StatusInternalServerError)
+	for _, status := range statuses {
+		responses[strconv.Itoa(status)] = map[string]any{
+			"description": http.StatusText(status),
+			"content":     jsonContent(map[string]any{"$ref": "#/components/schemas/ErrorResponse"}),
+		}
+	}
+	op["responses"] = responses
+
+	return op
+}
+
+// schemaFor 生成类型 t 的 JSON Schema
+func (g *schemaGenerator) schemaFor(t reflect.Type) map[string]any {
+	switch t {
+	case timeType:
+		return map[string]any{"type": "string", "format": "date-time"}
+	case rawMessageType:
+		return map[string]any{}
+	}
+
+	switch t.Kind() {
+	case reflect.Pointer:
+		schema := g.schemaFor(t.Elem())
+		if _, ok := schema["$ref"]; !ok {
+			schema["nullable"] = true
+		}
+		return schema
+	case reflect.Struct:
+		if _, ok := g.schemas[t.Name()]; !ok {
+			// 先占位，避免自引用的结构体无限递归
+			g.schemas[t.Name()] = map[string]any{}
+			g.schemas[t.Name()] = g.structSchema(t)
+		}
+		return map[string]any{"$ref": "#/components/schemas/" + t.Name()}
+	case reflect.Slice, reflect.Array:
+		return map[string]any{"type": "array", "items": g.schemaFor(t.Elem())}
+	case reflect.Map:
+		return map[string]any{"type": "object", "additionalProperties": g.schemaFor(t.Elem())}
+	case reflect.String:
+		return map[string]any{"type": "string"}
+	case reflect.Bool:
+		return map[string]any{"type": "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32:
+		return map[string]any{"type": "integer"}
+	case reflect.Int64, reflect.Uint64:
+		return map[string]any{"type": "integer", "format": "int64"}
+	case reflect.Float32, reflect.Float64:
+		return map[string]any{"type": "number"}
+	default:
+		return map[string]any{}
+	}
+}
+
+// structSchema 按 json 标签生成结构体的 JSON Schema
+// 没有 omitempty 的非指针字段以及带 binding:"required" 的字段视为必填
+func (g *schemaGenerator) structSchema(t reflect.Type) map[string]any {
+	properties := map[string]any{}
+	var required []string
+	for i := range t.NumField() {
+		field := t.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+
+		name, options, _ := strings.Cut(field.Tag.Get("json"), ",")
+		if name == "-" {
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+
+		properties[name] = g.schemaFor(field.Type)
+		omitempty := slices.Contains(strings.Split(options, ","), "omitempty")
+		if (!omitempty && field.Type.Kind() != reflect.Pointer) || strings.Contains(field.Tag.Get("binding"), "required") {
+			required = append(required, name)
+		}
+	}
+
+	schema := map[string]any{"type": "object", "properties": properties}
+	if len(required) > 0 {
+		schema["required"] = required
+	}
+	return schema
+}
+
+// jsonContent 生成 application/json 类型的 content
+func jsonContent(schema map[string]any) map[string]any {
+	return map[string]any{"application/json": map[string]any{"schema": schema}}
+}
@@ -0,0 +1,75 @@
+package api
+
+import (
+	"cdk-get/internal/giftcode"
+	"cdk-get/internal/service"
+	"context"
+	"errors"
+
+	apperrors "cdk-get/internal/errors"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+)
+
+// findPlayer 通过玩家接口校验 fid 并获取玩家资料
+// 玩家不存在时返回 NOT_FOUND，接口未配置时返回 SERVICE_UNAVAILABLE，查询失败时返回 EXTERNAL_API_ERROR
+func findPlayer(ctx context.Context, giftService *service.GiftService, fid string) (*giftcode.DdPlayerMsg, error) {
+	if giftService == nil {
+		return nil, apperrors.NewUnavailableError("player lookup")
+	}
+
+	player, err := giftService.GetUserInfo(ctx, fid)
+	if err != nil {
+		if errors.Is(err, giftcode.ErrPlayerNotFound) {
+			return nil, apperrors.NewNotFoundError("player", fid)
+		}
+		return nil, apperrors.NewExternalAPIError("player_info", err)
+	}
+
+	return player, nil
+}
+
+// lookupPlayer 通过玩家接口校验 fid 并获取玩家资料
+// 查询失败时直接写入错误响应并返回 false
+func lookupPlayer(c *gin.Context, giftService *service.GiftService, logger *logrus.Logger, fid string) (*giftcode.DdPlayerMsg, bool) {
+	// 获取请求ID用于日志关联
+	requestID, _ := c.Get("request_id")
+
+	player, err := findPlayer(c.Request.Context(), giftService, fid)
+	if err == nil {
+		return player, true
+	}
+
+	// 不是 AppError 的错误按查询失败处理
+	var code string
+	var appErr *apperrors.AppError
+	if errors.As(err, &appErr) {
+		code = appErr.Code
+	}
+	switch code {
+	case apperrors.ErrCodeUnavailable:
+		logger.WithFields(logrus.Fields{
+			"request_id": requestID,
+			"fid":        fid,
+		}).Error("gift service not configured, cannot validate fid")
+
+		c.JSON(503, ErrorResponse("SERVICE_UNAVAILABLE", "Player lookup is not available"))
+	case apperrors.ErrCodeNotFound:
+		logger.WithFields(logrus.Fields{
+			"request_id": requestID,
+			"fid":        fid,
+		}).Warn("player not found")
+
+		c.JSON(400, ErrorResponse("PLAYER_NOT_FOUND", "No player exists with this fid"))
+	default:
+		logger.WithFields(logrus.Fields{
+			"request_id": requestID,
+			"fid":        fid,
+			"error":      err.Error(),
+		}).Error("failed to query player info")
+
+		c.JSON(502, ErrorResponse("EXTERNAL_API_ERROR", "Failed to query player info"))
+	}
+	return nil, false
+}
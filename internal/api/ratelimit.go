@@ -0,0 +1,153 @@
+package api
+
+import (
+	"container/list"
+	"math"
+	"sync"
+	"time"
+
+	"cdk-get/internal/config"
+
+	"golang.org/x/time/rate"
+)
+
+// KeyedLimiter 按计数对象分别限流的令牌桶集合
+// 令牌桶按最近使用顺序保存，闲置超过 idleTimeout 或数量超过 maxKeys 时淘汰最久未使用的
+type KeyedLimiter struct {
+	group       string
+	limit       rate.Limit
+	burst       int
+	maxKeys     int
+	idleTimeout time.Duration
+	now         func() time.Time
+
+	mu      sync.Mutex
+	buckets map[string]*list.Element
+	lru     *list.List // 元素为 *rateBucket，最近使用的在前
+}
+
+// rateBucket 一个计数对象的令牌桶
+type rateBucket struct {
+	key      string
+	limiter  *rate.Limiter
+	lastSeen time.Time
+}
+
+// RateLimitResult 一次限流判断的结果，用于生成 RateLimit-* 响应头
+type RateLimitResult struct {
+	Allowed    bool
+	Limit      int           // 令牌桶容量
+	Remaining  int           // 本次请求后剩余的令牌数
+	Reset      time.Duration // 令牌桶重新装满需要的时长
+	RetryAfter time.Duration // 被拒绝时，下一个令牌可用前需等待的时长
+}
+
+// NewKeyedLimiter 为路由分组创建限流器，限流未启用时返回 nil
+func NewKeyedLimiter(cfg config.RateLimitConfig, group string) *KeyedLimiter {
+	if !cfg.Enabled {
+		return nil
+	}
+
+	rule := cfg.Rule(group)
+	return &KeyedLimiter{
+		group:       group,
+		limit:       rate.Limit(rule.Rate),
+		burst:       rule.Burst,
+		maxKeys:     cfg.MaxKeys,
+		idleTimeout: cfg.IdleTimeout,
+		now:         time.Now,
+		buckets:     make(map[string]*list.Element),
+		lru:         list.New(),
+	}
+}
+
+// Allow 判断计数对象 key 是否允许再发起一次请求
+func (l *KeyedLimiter) Allow(key string) RateLimitResult {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.now()
+	l.evictIdle(now)
+
+	b := l.bucket(key, now)
+	allowed := b.limiter.AllowN(now, 1)
+	tokens := b.limiter.TokensAt(now)
+
+	result := RateLimitResult{
+		Allowed:   allowed,
+		Limit:     l.burst,
+		Remaining: max(int(math.Floor(tokens)), 0),
+		Reset:     l.refillDuration(float64(l.burst) - tokens),
+	}
+	if !allowed {
+		result.RetryAfter = l.refillDuration(1 - tokens)
+	}
+	return result
+}
+
+// Peek 判断计数对象 key 是否还有可用令牌，不消耗令牌也不创建令牌桶
+func (l *KeyedLimiter) Peek(key string) RateLimitResult {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.now()
+	l.evictIdle(now)
+
+	elem, ok := l.buckets[key]
+	if !ok {
+		return RateLimitResult{Allowed: true, Limit: l.burst, Remaining: l.burst}
+	}
+
+	tokens := elem.Value.(*rateBucket).limiter.TokensAt(now)
+	result := RateLimitResult{
+		Allowed:   tokens >= 1,
+		Limit:     l.burst,
+		Remaining: max(int(math.Floor(tokens)), 0),
+		Reset:     l.refillDuration(float64(l.burst) - tokens),
+	}
+	if !result.Allowed {
+		result.RetryAfter = l.refillDuration(1 - tokens)
+	}
+	return result
+}
+
+// bucket 获取或创建 key 的令牌桶并标记为最近使用，超出 maxKeys 时淘汰最久未使用的
+func (l *KeyedLimiter) bucket(key string, now time.Time) *rateBucket {
+	if elem, ok := l.buckets[key]; ok {
+		b := elem.Value.(*rateBucket)
+		b.lastSeen = now
+		l.lru.MoveToFront(elem)
+		return b
+	}
+
+	b := &rateBucket{key: key, limiter: rate.NewLimiter(l.limit, l.burst), lastSeen: now}
+	l.buckets[key] = l.lru.PushFront(b)
+	for l.lru.Len() > l.maxKeys {
+		l.remove(l.lru.Back())
+	}
+	return b
+}
+
+// evictIdle 淘汰闲置超过 idleTimeout 的令牌桶
+// 闲置期间令牌桶通常已重新装满，淘汰后重新创建与保留的效果相同
+func (l *KeyedLimiter) evictIdle(now time.Time) {
+	for elem := l.lru.Back(); elem != nil; elem = l.lru.Back() {
+		if now.Sub(elem.Value.(*rateBucket).lastSeen) <= l.idleTimeout {
+			return
+		}
+		l.remove(elem)
+	}
+}
+
+func (l *KeyedLimiter) remove(elem *list.Element) {
+	delete(l.buckets, elem.Value.(*rateBucket).key)
+	l.lru.Remove(elem)
+}
+
+// refillDuration 补充 tokens 个令牌需要的时长
+func (l *KeyedLimiter) refillDuration(tokens float64) time.Duration {
+	if tokens <= 0 {
+		return 0
+	}
+	return time.Duration(tokens / float64(l.limit) * float64(time.Second))
+}
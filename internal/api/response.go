@@ -1,5 +1,12 @@
 package api
 
+import (
+	"errors"
+	"net/http"
+
+	apperrors "cdk-get/internal/errors"
+)
+
 // Response 标准响应格式
 type Response struct {
 	Success bool        `json:"success"`
@@ -31,3 +38,36 @@ func ErrorResponse(code, message string) Response {
 		},
 	}
 }
+
+// isValidationError 判断错误是否为参数校验错误
+func isValidationError(err error) bool {
+	var appErr *apperrors.AppError
+	return errors.As(err, &appErr) && appErr.Code == apperrors.ErrCodeValidation
+}
+
+// isNotFoundError 判断错误是否为资源不存在错误
+func isNotFoundError(err error) bool {
+	var appErr *apperrors.AppError
+	return errors.As(err, &appErr) && appErr.Code == apperrors.ErrCodeNotFound
+}
+
+// isAlreadyExistsError 判断错误是否为资源已存在错误
+func isAlreadyExistsError(err error) bool {
+	var appErr *apperrors.AppError
+	return errors.As(err, &appErr) && appErr.Code == apperrors.ErrCodeAlreadyExists
+}
+
+// AppErrorResponse 按 AppError 的错误代码创建错误响应并返回对应的HTTP状态码
+// 非 AppError 以及数据库、内部错误不向调用方暴露错误详情
+func AppErrorResponse(err error) (int, Response) {
+	var appErr *apperrors.AppError
+	if !errors.As(err, &appErr) {
+		return 500, ErrorResponse(apperrors.ErrCodeInternal, "Internal server error")
+	}
+
+	status := apperrors.HTTPStatus(appErr.Code)
+	if status == http.StatusInternalServerError {
+		return status, ErrorResponse(appErr.Code, "Internal server error")
+	}
+	return status, ErrorResponse(appErr.Code, appErr.Message)
+}
@@ -0,0 +1,316 @@
+package api
+
+import (
+	"cdk-get/internal/storage"
+	"net/http"
+	"strconv"
+	"strings"
+
+	apperrors "cdk-get/internal/errors"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+)
+
+// 公开接口 v1 分页参数
+const (
+	v1DefaultPageSize = 50
+	v1MaxPageSize     = 200
+)
+
+// serverIP 服务器出口IP，兑换码网站需要将其加入白名单
+const serverIP = "47.120.61.46"
+
+// CreateGiftCodeRequest 添加兑换码请求结构
+type CreateGiftCodeRequest struct {
+	Code string `json:"code" binding:"required"`
+}
+
+// CreateUserRequest 添加用户请求结构
+type CreateUserRequest struct {
+	FID string `json:"fid" binding:"required"`
+}
+
+// ServerIP 服务器出口IP响应结构
+type ServerIP struct {
+	IP string `json:"ip"`
+}
+
+// V1Routes 公开接口 v1 的路由表，路径相对于 /api/v1
+// 路由注册和 OpenAPI 文档都由该路由表生成
+func (h *Handlers) V1Routes() []Route {
+	return []Route{
+		{
+			Method:   http.MethodPost,
+			Path:     "/gift-codes",
+			Name:     "createGiftCode",
+			Summary:  "添加兑换码任务，兑换码已存在时返回 200 和现有任务",
+			Scope:    storage.APIKeyScopeEnqueueCode,
+			Request:  CreateGiftCodeRequest{},
+			Response: storage.Task{},
+			Created:  true,
+			Errors:   []int{http.StatusBadRequest, http.StatusConflict},
+			Handler:  h.CreateGiftCodeV1,
+		},
+		{
+			Method:  http.MethodGet,
+			Path:    "/gift-codes",
+			Name:    "listGiftCodes",
+			Summary: "按状态列出兑换码任务",
+			Scope:   storage.APIKeyScopeReadOnly,
+			Params: []Param{
+				{Name: "status", In: "query", Description: "任务状态，默认为 pending", Enum: []string{"pending", "completed"}},
+				{Name: "q", In: "query", Description: "按兑换码模糊搜索"},
+			},
+			Response: storage.Task{},
+			Paged:    true,
+			Errors:   []int{http.StatusBadRequest},
+			Handler:  h.ListGiftCodesV1,
+		},
+		{
+			Method:   http.MethodGet,
+			Path:     "/gift-codes/:code",
+			Name:     "getGiftCode",
+			Summary:  "查询兑换码任务状态",
+			Scope:    storage.APIKeyScopeReadOnly,
+			Params:   []Param{{Name: "code", In: "path", Description: "兑换码"}},
+			Response: storage.Task{},
+			Errors:   []int{http.StatusNotFound},
+			Handler:  h.GetGiftCodeV1,
+		},
+		{
+			Method:   http.MethodPost,
+			Path:     "/users",
+			Name:     "createUser",
+			Summary:  "通过玩家接口校验 fid 并添加用户，用户已存在时更新资料并返回 200",
+			Scope:    storage.APIKeyScopeAddUser,
+			Request:  CreateUserRequest{},
+			Response: storage.User{},
+			Created:  true,
+			Errors:   []int{http.StatusBadRequest, http.StatusNotFound, http.StatusBadGateway, http.StatusServiceUnavailable},
+			Handler:  h.CreateUserV1,
+		},
+		{
+			Method:  http.MethodGet,
+			Path:    "/users",
+			Name:    "listUsers",
+			Summary: "列出用户",
+			Scope:   storage.APIKeyScopeReadOnly,
+			Params: []Param{
+				{Name: "kid", In: "query", Type: "integer", Description: "按区服筛选"},
+				{Name: "q", In: "query", Description: "按 fid 或昵称模糊搜索"},
+			},
+			Response: storage.User{},
+			Paged:    true,
+			Errors:   []int{http.StatusBadRequest},
+			Handler:  h.ListUsersV1,
+		},
+		{
+			Method:   http.MethodGet,
+			Path:     "/users/:fid",
+			Name:     "getUser",
+			Summary:  "查询用户",
+			Scope:    storage.APIKeyScopeReadOnly,
+			Params:   []Param{{Name: "fid", In: "path", Description: "玩家ID"}},
+			Response: storage.User{},
+			Errors:   []int{http.StatusNotFound},
+			Handler:  h.GetUserV1,
+		},
+		{
+			Method:   http.MethodGet,
+			Path:     "/ip",
+			Name:     "getServerIP",
+			Summary:  "获取服务器出口IP",
+			Response: ServerIP{},
+			Handler:  h.GetIPV1,
+		},
+	}
+}
+
+// CreateGiftCodeV1 添加兑换码任务
+// 处理 POST /api/v1/gift-codes
+func (h *Handlers) CreateGiftCodeV1(c *gin.Context) {
+	// 获取请求ID用于日志关联
+	requestID, _ := c.Get("request_id")
+
+	var req CreateGiftCodeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		h.respondV1Error(c, requestID, apperrors.NewValidationError("body", err.Error()))
+		return
+	}
+
+	task, created, err := h.enqueueGiftCode(c.Request.Context(), requestID, req.Code, "api")
+	if err != nil {
+		h.respondV1Error(c, requestID, err)
+		return
+	}
+
+	if created {
+		c.JSON(http.StatusCreated, SuccessResponse(task))
+		return
+	}
+	c.JSON(http.StatusOK, SuccessResponse(task))
+}
+
+// ListGiftCodesV1 按状态分页列出兑换码任务
+// 处理 GET /api/v1/gift-codes
+func (h *Handlers) ListGiftCodesV1(c *gin.Context) {
+	// 获取请求ID用于日志关联
+	requestID, _ := c.Get("request_id")
+
+	opts, err := pageParams(c)
+	if err != nil {
+		h.respondV1Error(c, requestID, err)
+		return
+	}
+
+	page, err := h.repository.QueryTasks(c.Request.Context(), storage.TaskFilter{
+		ListOptions: opts,
+		Search:      c.Query("q"),
+		Status:      c.DefaultQuery("status", storage.TaskStatusPending),
+	})
+	if err != nil {
+		h.respondV1Error(c, requestID, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, SuccessResponse(page))
+}
+
+// GetGiftCodeV1 查询兑换码任务状态
+// 处理 GET /api/v1/gift-codes/:code
+func (h *Handlers) GetGiftCodeV1(c *gin.Context) {
+	// 获取请求ID用于日志关联
+	requestID, _ := c.Get("request_id")
+
+	task, err := h.repository.GetTaskByCode(c.Request.Context(), strings.TrimSpace(c.Param("code")))
+	if err != nil {
+		h.respondV1Error(c, requestID, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, SuccessResponse(task))
+}
+
+// CreateUserV1 校验 fid 并添加用户
+// 处理 POST /api/v1/users
+func (h *Handlers) CreateUserV1(c *gin.Context) {
+	// 获取请求ID用于日志关联
+	requestID, _ := c.Get("request_id")
+
+	var req CreateUserRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		h.respondV1Error(c, requestID, apperrors.NewValidationError("body", err.Error()))
+		return
+	}
+
+	fid, err := strconv.ParseInt(strings.TrimSpace(req.FID), 10, 64)
+	if err != nil {
+		h.respondV1Error(c, requestID, apperrors.NewValidationError("fid", "must be a valid integer"))
+		return
+	}
+	ctx := c.Request.Context()
+
+	_, err = h.repository.GetUser(ctx, strconv.FormatInt(fid, 10))
+	if err != nil && !isNotFoundError(err) {
+		h.respondV1Error(c, requestID, err)
+		return
+	}
+	created := err != nil
+
+	player, err := findPlayer(ctx, h.giftService, strconv.FormatInt(fid, 10))
+	if err != nil {
+		h.respondV1Error(c, requestID, err)
+		return
+	}
+
+	user, err := h.savePlayer(ctx, requestID, player)
+	if err != nil {
+		h.respondV1Error(c, requestID, err)
+		return
+	}
+
+	if created {
+		c.JSON(http.StatusCreated, SuccessResponse(user))
+		return
+	}
+	c.JSON(http.StatusOK, SuccessResponse(user))
+}
+
+// ListUsersV1 分页列出用户
+// 处理 GET /api/v1/users
+func (h *Handlers) ListUsersV1(c *gin.Context) {
+	// 获取请求ID用于日志关联
+	requestID, _ := c.Get("request_id")
+
+	opts, err := pageParams(c)
+	if err != nil {
+		h.respondV1Error(c, requestID, err)
+		return
+	}
+
+	filter := storage.UserFilter{ListOptions: opts, Search: c.Query("q")}
+	if value := c.Query("kid"); value != "" {
+		kid, err := strconv.Atoi(value)
+		if err != nil {
+			h.respondV1Error(c, requestID, apperrors.NewValidationError("kid", "must be a valid integer"))
+			return
+		}
+		filter.KID = &kid
+	}
+
+	page, err := h.repository.QueryUsers(c.Request.Context(), filter)
+	if err != nil {
+		h.respondV1Error(c, requestID, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, SuccessResponse(page))
+}
+
+// GetUserV1 查询用户
+// 处理 GET /api/v1/users/:fid
+func (h *Handlers) GetUserV1(c *gin.Context) {
+	// 获取请求ID用于日志关联
+	requestID, _ := c.Get("request_id")
+
+	user, err := h.repository.GetUser(c.Request.Context(), strings.TrimSpace(c.Param("fid")))
+	if err != nil {
+		h.respondV1Error(c, requestID, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, SuccessResponse(user))
+}
+
+// GetIPV1 获取服务器出口IP
+// 处理 GET /api/v1/ip
+func (h *Handlers) GetIPV1(c *gin.Context) {
+	c.JSON(http.StatusOK, SuccessResponse(ServerIP{IP: serverIP}))
+}
+
+// respondV1Error 按 AppError 的错误代码写入错误响应，服务端错误记录日志
+func (h *Handlers) respondV1Error(c *gin.Context, requestID any, err error) {
+	status, response := AppErrorResponse(err)
+	if status >= http.StatusInternalServerError {
+		h.logger.WithFields(logrus.Fields{
+			"request_id": requestID,
+			"path":       c.FullPath(),
+			"error":      err.Error(),
+		}).Error("public api request failed")
+	}
+	c.JSON(status, response)
+}
+
+// pageParams 解析 limit 和 cursor 分页参数，cursor 为上一页返回的 next_cursor，由仓库校验
+func pageParams(c *gin.Context) (storage.ListOptions, error) {
+	opts := storage.ListOptions{Cursor: c.Query("cursor"), Limit: v1DefaultPageSize}
+	if value := c.Query("limit"); value != "" {
+		limit, err := strconv.Atoi(value)
+		if err != nil || limit < 1 || limit > v1MaxPageSize {
+			return opts, apperrors.NewValidationError("limit", "must be between 1 and "+strconv.Itoa(v1MaxPageSize))
+		}
+		opts.Limit = limit
+	}
+	return opts, nil
+}
@@ -0,0 +1,34 @@
+package auth
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+)
+
+// API 密钥格式为 "cdk_" 加 32 字节随机数的 base64url 编码
+const (
+	apiKeyPrefix    = "cdk_"
+	apiKeySize      = 32
+	apiKeyPrefixLen = len(apiKeyPrefix) + 8 // 列表中显示的密钥开头长度
+)
+
+// GenerateAPIKey 生成随机 API 密钥，返回密钥本身、用于识别的开头部分和保存到数据库的哈希
+// 密钥本身只在创建时返回一次
+func GenerateAPIKey() (key, prefix, hash string, err error) {
+	buf := make([]byte, apiKeySize)
+	if _, err := rand.Read(buf); err != nil {
+		return "", "", "", fmt.Errorf("failed to generate api key: %w", err)
+	}
+	key = apiKeyPrefix + base64.RawURLEncoding.EncodeToString(buf)
+	return key, key[:apiKeyPrefixLen], HashAPIKey(key), nil
+}
+
+// HashAPIKey 计算 API 密钥的哈希，数据库中只保存哈希
+// 密钥本身是高熵随机数，无需使用 bcrypt 这类慢哈希
+func HashAPIKey(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,27 @@
+package auth
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestGenerateAPIKey(t *testing.T) {
+	key, prefix, hash, err := GenerateAPIKey()
+	if err != nil {
+		t.Fatalf("GenerateAPIKey error: %v", err)
+	}
+	if !strings.HasPrefix(key, "cdk_") || !strings.HasPrefix(key, prefix) || len(prefix) >= len(key) {
+		t.Fatalf("unexpected key %q with prefix %q", key, prefix)
+	}
+	if hash != HashAPIKey(key) || hash == key {
+		t.Fatalf("unexpected hash %q", hash)
+	}
+
+	other, _, _, err := GenerateAPIKey()
+	if err != nil {
+		t.Fatalf("GenerateAPIKey error: %v", err)
+	}
+	if other == key {
+		t.Fatal("expected distinct keys")
+	}
+}
@@ -0,0 +1,237 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"cdk-get/internal/config"
+	"cdk-get/internal/storage"
+)
+
+// maxLoginUsernameLength 管理员用户名的最大长度，更长的用户名不可能存在，只按 IP 计数
+const maxLoginUsernameLength = 64
+
+// LoginThrottleStore 登录失败计数和审计记录存储，由 storage.Repository 实现
+type LoginThrottleStore interface {
+	GetLoginThrottle(ctx context.Context, kind, identity string) (*storage.LoginThrottle, error)
+	SaveLoginThrottle(ctx context.Context, throttle *storage.LoginThrottle) error
+	DeleteLoginThrottle(ctx context.Context, kind, identity string) error
+	CreateLoginFailure(ctx context.Context, failure *storage.LoginFailure) error
+}
+
+// LoginBlockedError 登录尝试被限制错误
+type LoginBlockedError struct {
+	Kind       string        // 触发限制的维度，ip 或 username
+	Identity   string        // 触发限制的 IP 或用户名
+	Locked     bool          // true 表示处于锁定中，false 表示处于失败后的等待期
+	RetryAfter time.Duration // 可再次尝试前需等待的时长
+}
+
+func (e *LoginBlockedError) Error() string {
+	if e.Locked {
+		return fmt.Sprintf("login locked for %s %s, retry after %v", e.Kind, e.Identity, e.RetryAfter)
+	}
+	return fmt.Sprintf("login throttled for %s %s, retry after %v", e.Kind, e.Identity, e.RetryAfter)
+}
+
+// LoginGuard 管理员登录防爆破
+// 按客户端 IP 和用户名分别统计连续失败次数，失败后逐次延长等待时间，达到上限后临时锁定
+// 未启用时不做限制，只记录失败登录的审计记录
+type LoginGuard struct {
+	store LoginThrottleStore
+	cfg   config.LoginProtectionConfig
+	now   func() time.Time
+	// mu 串行化失败计数的读改写
+	mu sync.Mutex
+}
+
+// NewLoginGuard 创建登录防爆破实例
+func NewLoginGuard(store LoginThrottleStore, cfg config.LoginProtectionConfig) *LoginGuard {
+	return &LoginGuard{
+		store: store,
+		cfg:   cfg,
+		now:   time.Now,
+	}
+}
+
+// Check 在校验密码前检查 IP 和用户名是否允许尝试登录
+// 被限制时返回 *LoginBlockedError，RetryAfter 取各维度中最长的等待时间
+func (g *LoginGuard) Check(ctx context.Context, username, ip string) error {
+	if !g.cfg.Enabled {
+		return nil
+	}
+
+	now := g.now()
+	var blocked *LoginBlockedError
+	for _, id := range loginIdentities(username, ip) {
+		throttle, err := g.store.GetLoginThrottle(ctx, id.kind, id.identity)
+		if errors.Is(err, storage.ErrLoginThrottleNotFound) {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+
+		var until time.Time
+		locked := throttle.Locked(now)
+		switch {
+		case locked:
+			until = *throttle.LockedUntil
+		case g.stale(throttle, now):
+			continue
+		default:
+			until = throttle.LastFailedAt.Add(g.delay(throttle.Failures))
+			if !now.Before(until) {
+				continue
+			}
+		}
+
+		if retryAfter := until.Sub(now); blocked == nil || retryAfter > blocked.RetryAfter {
+			blocked = &LoginBlockedError{Kind: id.kind, Identity: id.identity, Locked: locked, RetryAfter: retryAfter}
+		}
+	}
+
+	if blocked != nil {
+		return blocked
+	}
+	return nil
+}
+
+// RecordFailure 记录一次失败的登录并写入审计记录
+// reason 为 storage.LoginFailureBlocked 时只写审计记录，不增加失败次数
+// 返回本次失败后新进入锁定状态的计数
+func (g *LoginGuard) RecordFailure(ctx context.Context, username, ip, userAgent, reason string) ([]*storage.LoginThrottle, error) {
+	var locked []*storage.LoginThrottle
+	if g.cfg.Enabled && reason != storage.LoginFailureBlocked {
+		var err error
+		if locked, err = g.countFailure(ctx, username, ip); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := g.store.CreateLoginFailure(ctx, &storage.LoginFailure{
+		Username:  username,
+		IP:        ip,
+		UserAgent: userAgent,
+		Reason:    reason,
+		CreatedAt: g.now(),
+	}); err != nil {
+		return locked, err
+	}
+
+	return locked, nil
+}
+
+// RecordSuccess 登录成功后清除 IP 和用户名的失败计数
+func (g *LoginGuard) RecordSuccess(ctx context.Context, username, ip string) error {
+	if !g.cfg.Enabled {
+		return nil
+	}
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	for _, id := range loginIdentities(username, ip) {
+		if err := g.store.DeleteLoginThrottle(ctx, id.kind, id.identity); err != nil &&
+			!errors.Is(err, storage.ErrLoginThrottleNotFound) {
+			return err
+		}
+	}
+	return nil
+}
+
+// countFailure 增加 IP 和用户名的失败次数，达到上限时锁定
+func (g *LoginGuard) countFailure(ctx context.Context, username, ip string) ([]*storage.LoginThrottle, error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	now := g.now()
+	var locked []*storage.LoginThrottle
+	for _, id := range loginIdentities(username, ip) {
+		throttle, err := g.store.GetLoginThrottle(ctx, id.kind, id.identity)
+		if errors.Is(err, storage.ErrLoginThrottleNotFound) {
+			throttle = &storage.LoginThrottle{Kind: id.kind, Identity: id.identity}
+		} else if err != nil {
+			return nil, err
+		}
+
+		// 计数窗口外的失败重新计数；长时间没有失败后锁定时长也从头计算
+		if g.stale(throttle, now) {
+			throttle.Failures = 0
+		}
+		if now.Sub(throttle.LastFailedAt) > g.cfg.MaxLockoutDuration {
+			throttle.Lockouts = 0
+		}
+
+		throttle.Failures++
+		throttle.LastFailedAt = now
+		if throttle.Failures >= id.maxFailures(g.cfg) {
+			throttle.Lockouts++
+			throttle.Failures = 0
+			lockedUntil := now.Add(g.lockoutDuration(throttle.Lockouts))
+			throttle.LockedUntil = &lockedUntil
+			locked = append(locked, throttle)
+		}
+
+		if err := g.store.SaveLoginThrottle(ctx, throttle); err != nil {
+			return nil, err
+		}
+	}
+
+	return locked, nil
+}
+
+// stale 判断未锁定的计数是否已超出计数窗口
+func (g *LoginGuard) stale(throttle *storage.LoginThrottle, now time.Time) bool {
+	return !throttle.Locked(now) && now.Sub(throttle.LastFailedAt) > g.cfg.Window
+}
+
+// delay 连续失败 failures 次后需等待的时长：base_delay·2^(failures-1)，不超过 max_delay
+func (g *LoginGuard) delay(failures int) time.Duration {
+	if failures <= 0 {
+		return 0
+	}
+	return doubleUpTo(g.cfg.BaseDelay, failures-1, g.cfg.MaxDelay)
+}
+
+// lockoutDuration 第 lockouts 次连续锁定的时长：lockout_duration·2^(lockouts-1)，不超过 max_lockout_duration
+func (g *LoginGuard) lockoutDuration(lockouts int) time.Duration {
+	return doubleUpTo(g.cfg.LockoutDuration, lockouts-1, g.cfg.MaxLockoutDuration)
+}
+
+// doubleUpTo 将 d 翻倍 n 次，结果不超过 limit
+func doubleUpTo(d time.Duration, n int, limit time.Duration) time.Duration {
+	for i := 0; i < n && d < limit; i++ {
+		d *= 2
+	}
+	return min(d, limit)
+}
+
+// loginIdentity 一个登录失败计数维度
+type loginIdentity struct {
+	kind     string
+	identity string
+}
+
+// maxFailures 该维度允许的连续失败次数
+func (id loginIdentity) maxFailures(cfg config.LoginProtectionConfig) int {
+	if id.kind == storage.LoginThrottleKindIP {
+		return cfg.MaxFailuresPerIP
+	}
+	return cfg.MaxFailuresPerUser
+}
+
+// loginIdentities 返回一次登录尝试涉及的计数维度
+func loginIdentities(username, ip string) []loginIdentity {
+	var ids []loginIdentity
+	if ip != "" {
+		ids = append(ids, loginIdentity{kind: storage.LoginThrottleKindIP, identity: ip})
+	}
+	if username != "" && len(username) <= maxLoginUsernameLength {
+		ids = append(ids, loginIdentity{kind: storage.LoginThrottleKindUsername, identity: username})
+	}
+	return ids
+}
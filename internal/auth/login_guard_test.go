@@ -0,0 +1,198 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"cdk-get/internal/config"
+	"cdk-get/internal/storage"
+)
+
+// fakeThrottleStore 在内存中保存登录失败计数的 LoginThrottleStore
+type fakeThrottleStore struct {
+	throttles map[string]storage.LoginThrottle
+	failures  []*storage.LoginFailure
+}
+
+func (f *fakeThrottleStore) GetLoginThrottle(ctx context.Context, kind, identity string) (*storage.LoginThrottle, error) {
+	throttle, ok := f.throttles[kind+":"+identity]
+	if !ok {
+		return nil, storage.ErrLoginThrottleNotFound
+	}
+	return &throttle, nil
+}
+
+func (f *fakeThrottleStore) SaveLoginThrottle(ctx context.Context, throttle *storage.LoginThrottle) error {
+	f.throttles[throttle.Kind+":"+throttle.Identity] = *throttle
+	return nil
+}
+
+func (f *fakeThrottleStore) DeleteLoginThrottle(ctx context.Context, kind, identity string) error {
+	if _, ok := f.throttles[kind+":"+identity]; !ok {
+		return storage.ErrLoginThrottleNotFound
+	}
+	delete(f.throttles, kind+":"+identity)
+	return nil
+}
+
+func (f *fakeThrottleStore) CreateLoginFailure(ctx context.Context, failure *storage.LoginFailure) error {
+	f.failures = append(f.failures, failure)
+	return nil
+}
+
+func TestLoginGuard(t *testing.T) {
+	ctx := context.Background()
+	now := time.Date(2026, 10, 18, 12, 0, 0, 0, time.UTC)
+	store := &fakeThrottleStore{throttles: make(map[string]storage.LoginThrottle)}
+	guard := NewLoginGuard(store, config.LoginProtectionConfig{
+		Enabled:            true,
+		MaxFailuresPerUser: 3,
+		MaxFailuresPerIP:   10,
+		Window:             15 * time.Minute,
+		BaseDelay:          time.Second,
+		MaxDelay:           4 * time.Second,
+		LockoutDuration:    time.Minute,
+		MaxLockoutDuration: 3 * time.Minute,
+	})
+	guard.now = func() time.Time { return now }
+
+	fail := func() []*storage.LoginThrottle {
+		t.Helper()
+		locked, err := guard.RecordFailure(ctx, "admin", "10.0.0.1", "curl", storage.LoginFailureInvalidCredentials)
+		if err != nil {
+			t.Fatalf("RecordFailure error: %v", err)
+		}
+		return locked
+	}
+	retryAfter := func() *LoginBlockedError {
+		t.Helper()
+		err := guard.Check(ctx, "admin", "10.0.0.1")
+		if err == nil {
+			return nil
+		}
+		var blocked *LoginBlockedError
+		if !errors.As(err, &blocked) {
+			t.Fatalf("unexpected Check error: %v", err)
+		}
+		return blocked
+	}
+
+	if blocked := retryAfter(); blocked != nil {
+		t.Fatalf("expected first attempt to be allowed, got %v", blocked)
+	}
+
+	// 等待时间逐次翻倍
+	fail()
+	if blocked := retryAfter(); blocked == nil || blocked.Locked || blocked.RetryAfter != time.Second {
+		t.Fatalf("expected 1s delay after first failure, got %v", blocked)
+	}
+	now = now.Add(time.Second)
+	if blocked := retryAfter(); blocked != nil {
+		t.Fatalf("expected attempt after delay to be allowed, got %v", blocked)
+	}
+	fail()
+	if blocked := retryAfter(); blocked == nil || blocked.RetryAfter != 2*time.Second {
+		t.Fatalf("expected 2s delay after second failure, got %v", blocked)
+	}
+
+	// 达到上限后锁定用户名，被限制的尝试只写审计记录
+	now = now.Add(2 * time.Second)
+	locked := fail()
+	if len(locked) != 1 || locked[0].Kind != storage.LoginThrottleKindUsername {
+		t.Fatalf("expected username to be locked, got %+v", locked)
+	}
+	blocked := retryAfter()
+	if blocked == nil || !blocked.Locked || blocked.Kind != storage.LoginThrottleKindUsername || blocked.RetryAfter != time.Minute {
+		t.Fatalf("expected 1m lockout, got %v", blocked)
+	}
+	if _, err := guard.RecordFailure(ctx, "admin", "10.0.0.1", "curl", storage.LoginFailureBlocked); err != nil {
+		t.Fatalf("RecordFailure error: %v", err)
+	}
+	if got := store.throttles["ip:10.0.0.1"].Failures; got != 3 {
+		t.Fatalf("expected blocked attempt not to be counted, got %d ip failures", got)
+	}
+	if len(store.failures) != 4 || store.failures[3].Reason != storage.LoginFailureBlocked {
+		t.Fatalf("expected 4 audit entries, got %d", len(store.failures))
+	}
+
+	// 锁定结束后再次达到上限，锁定时长翻倍且不超过上限
+	now = now.Add(time.Minute)
+	for i := 0; i < 3; i++ {
+		now = now.Add(10 * time.Second)
+		locked = fail()
+	}
+	if blocked := retryAfter(); len(locked) != 1 || blocked == nil || blocked.RetryAfter != 2*time.Minute {
+		t.Fatalf("expected 2m lockout, got %+v, %v", locked, blocked)
+	}
+	now = now.Add(2 * time.Minute)
+	for i := 0; i < 3; i++ {
+		now = now.Add(10 * time.Second)
+		fail()
+	}
+	if blocked := retryAfter(); blocked == nil || blocked.RetryAfter != 3*time.Minute {
+		t.Fatalf("expected lockout capped at 3m, got %v", blocked)
+	}
+
+	// 其他用户名不受影响，IP 的失败次数仍在累计
+	if err := guard.Check(ctx, "other", "10.0.0.2"); err != nil {
+		t.Fatalf("expected other identities to be allowed, got %v", err)
+	}
+	if got := store.throttles["ip:10.0.0.1"].Failures; got != 9 {
+		t.Fatalf("expected 9 ip failures, got %d", got)
+	}
+
+	// 登录成功清除计数
+	if err := guard.RecordSuccess(ctx, "admin", "10.0.0.1"); err != nil {
+		t.Fatalf("RecordSuccess error: %v", err)
+	}
+	if blocked := retryAfter(); blocked != nil || len(store.throttles) != 0 {
+		t.Fatalf("expected throttles to be cleared, got %v, %d", blocked, len(store.throttles))
+	}
+}
+
+func TestLoginGuard_WindowAndDisabled(t *testing.T) {
+	ctx := context.Background()
+	now := time.Date(2026, 10, 18, 12, 0, 0, 0, time.UTC)
+	store := &fakeThrottleStore{throttles: make(map[string]storage.LoginThrottle)}
+	cfg := config.LoginProtectionConfig{
+		Enabled:            true,
+		MaxFailuresPerUser: 2,
+		MaxFailuresPerIP:   10,
+		Window:             time.Minute,
+		BaseDelay:          time.Second,
+		MaxDelay:           time.Second,
+		LockoutDuration:    time.Minute,
+		MaxLockoutDuration: time.Hour,
+	}
+	guard := NewLoginGuard(store, cfg)
+	guard.now = func() time.Time { return now }
+
+	// 计数窗口外的失败不累计
+	for i := 0; i < 3; i++ {
+		locked, err := guard.RecordFailure(ctx, "admin", "", "", storage.LoginFailureInvalidTOTP)
+		if err != nil || len(locked) != 0 {
+			t.Fatalf("expected no lockout, got %+v, %v", locked, err)
+		}
+		now = now.Add(2 * time.Minute)
+	}
+	if err := guard.Check(ctx, "admin", ""); err != nil {
+		t.Fatalf("expected stale failures to be ignored, got %v", err)
+	}
+
+	// 未启用时不限制，只写审计记录
+	cfg.Enabled = false
+	disabled := NewLoginGuard(store, cfg)
+	for i := 0; i < 5; i++ {
+		if _, err := disabled.RecordFailure(ctx, "root", "10.0.0.9", "", storage.LoginFailureInvalidCredentials); err != nil {
+			t.Fatalf("RecordFailure error: %v", err)
+		}
+	}
+	if err := disabled.Check(ctx, "root", "10.0.0.9"); err != nil {
+		t.Fatalf("expected disabled guard to allow login, got %v", err)
+	}
+	if _, ok := store.throttles["username:root"]; ok || len(store.failures) != 8 {
+		t.Fatalf("expected only audit entries, got %d failures", len(store.failures))
+	}
+}
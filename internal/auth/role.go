@@ -0,0 +1,16 @@
+package auth
+
+import "cdk-get/internal/storage"
+
+// roleRanks 角色权限等级，高等级包含低等级的全部权限
+var roleRanks = map[string]int{
+	storage.AdminRoleViewer:   1,
+	storage.AdminRoleOperator: 2,
+	storage.AdminRoleOwner:    3,
+}
+
+// HasRole 判断角色 role 是否具备 required 所需的权限，未知角色没有任何权限
+func HasRole(role, required string) bool {
+	rank, ok := roleRanks[role]
+	return ok && rank >= roleRanks[required]
+}
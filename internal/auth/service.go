@@ -1,12 +1,19 @@
 package auth
 
 import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
 	"errors"
 	"fmt"
 	"time"
 
 	"github.com/golang-jwt/jwt/v5"
-	"golang.org/x/crypto/bcrypt"
+	"github.com/google/uuid"
+
+	"cdk-get/internal/storage"
 )
 
 var (
@@ -20,71 +27,229 @@ var (
 
 // Claims JWT声明结构
 type Claims struct {
-	Username string `json:"username"`
+	Username  string `json:"username"`
+	Role      string `json:"role"`
+	SessionID string `json:"sid"`
+	// TOTPSetupRequired 账号被要求启用两步验证但尚未启用，由 ValidateToken 根据账号当前状态填充
+	TOTPSetupRequired bool `json:"-"`
 	jwt.RegisteredClaims
 }
 
+// AdminStore 管理员账号和会话存储，由 storage.Repository 实现
+type AdminStore interface {
+	GetAdmin(ctx context.Context, username string) (*storage.Admin, error)
+	CreateAdminSession(ctx context.Context, session *storage.AdminSession) error
+	GetAdminSession(ctx context.Context, id string) (*storage.AdminSession, error)
+	RotateAdminSession(ctx context.Context, oldHash string, session *storage.AdminSession) (*storage.AdminSession, error)
+	TouchAdminSession(ctx context.Context, id string, at time.Time) error
+	SetAdminTOTPSecret(ctx context.Context, username, secret string) error
+	EnableAdminTOTP(ctx context.Context, username string, step int64, recoveryCodeHashes []string) error
+	UseAdminTOTPStep(ctx context.Context, username string, step int64) (bool, error)
+	ReplaceAdminRecoveryCodes(ctx context.Context, username string, codeHashes []string) error
+	UseAdminRecoveryCode(ctx context.Context, username, codeHash string) (bool, error)
+}
+
+// TokenPair 一次登录或刷新签发的访问令牌和刷新令牌
+type TokenPair struct {
+	SessionID        string
+	AccessToken      string
+	AccessExpiresAt  time.Time
+	RefreshToken     string
+	RefreshExpiresAt time.Time
+}
+
 // AuthService 认证服务接口
 type AuthService interface {
-	// ValidateCredentials 验证管理员凭证
-	ValidateCredentials(username, password string) error
+	// ValidateCredentials 验证管理员凭证，成功时返回对应的管理员账号
+	ValidateCredentials(ctx context.Context, username, password string) (*storage.Admin, error)
+
+	// CreateSession 为已验证的管理员创建会话并签发令牌
+	CreateSession(ctx context.Context, admin *storage.Admin, ip, userAgent string) (*TokenPair, error)
+
+	// Refresh 用刷新令牌换取新的令牌对，旧的刷新令牌随即失效
+	// 刷新令牌无效、会话已撤销或账号已删除时返回 ErrInvalidToken
+	Refresh(ctx context.Context, refreshToken, ip, userAgent string) (*TokenPair, *storage.Admin, error)
+
+	// GenerateToken 为会话生成访问令牌（JWT）
+	GenerateToken(username, role, sessionID string) (token string, expiresAt time.Time, err error)
 
-	// GenerateToken 生成JWT令牌
-	GenerateToken(username string) (token string, expiresAt time.Time, err error)
+	// ValidateToken 验证访问令牌
+	// 返回的角色以账号当前角色为准，账号已删除或会话已撤销时令牌失效
+	ValidateToken(ctx context.Context, token string) (*Claims, error)
 
-	// ValidateToken 验证JWT令牌
-	ValidateToken(token string) (*Claims, error)
+	// VerifySecondFactor 校验已启用两步验证的账号提交的动态码或恢复码
+	// 账号未启用两步验证时直接通过；未提供时返回 ErrTOTPRequired，无效时返回 ErrInvalidTOTP
+	VerifySecondFactor(ctx context.Context, admin *storage.Admin, code string) error
+
+	// BeginTOTPEnrollment 为账号生成新的待启用密钥，已启用时返回 ErrTOTPAlreadyEnabled
+	BeginTOTPEnrollment(ctx context.Context, admin *storage.Admin) (*TOTPEnrollment, error)
+
+	// ConfirmTOTPEnrollment 用待启用密钥生成的动态码确认启用，返回恢复码明文
+	ConfirmTOTPEnrollment(ctx context.Context, admin *storage.Admin, code string) ([]string, error)
+
+	// RegenerateRecoveryCodes 校验动态码后重新生成恢复码，旧的恢复码全部失效
+	RegenerateRecoveryCodes(ctx context.Context, admin *storage.Admin, code string) ([]string, error)
 }
 
+// touchInterval 会话最近使用时间的更新间隔，避免每个请求都写数据库
+const touchInterval = time.Minute
+
 // authServiceImpl 认证服务实现
 type authServiceImpl struct {
-	username      string
-	passwordHash  string
-	tokenSecret   []byte
-	tokenDuration time.Duration
+	store           AdminStore
+	tokenSecret     []byte
+	tokenDuration   time.Duration
+	refreshDuration time.Duration
+	totpIssuer      string
+	now             func() time.Time
 }
 
 // NewAuthService 创建认证服务实例
-func NewAuthService(username, passwordHash, tokenSecret string, tokenDuration time.Duration) AuthService {
+// tokenDuration 为访问令牌有效期，refreshDuration 为刷新令牌有效期，
+// totpIssuer 为两步验证在验证器应用中显示的服务名称
+func NewAuthService(store AdminStore, tokenSecret string, tokenDuration, refreshDuration time.Duration, totpIssuer string) AuthService {
 	return &authServiceImpl{
-		username:      username,
-		passwordHash:  passwordHash,
-		tokenSecret:   []byte(tokenSecret),
-		tokenDuration: tokenDuration,
+		store:           store,
+		tokenSecret:     []byte(tokenSecret),
+		tokenDuration:   tokenDuration,
+		refreshDuration: refreshDuration,
+		totpIssuer:      totpIssuer,
+		now:             time.Now,
 	}
 }
 
+// dummyPasswordHash 用户名不存在时用于比对的 bcrypt 哈希（DefaultCost），
+// 使其与用户名存在时的耗时相同，避免通过响应时间判断用户名是否存在
+const dummyPasswordHash = "$2a$10$my.Q7OIlOHhkwFyubKzPHuMBOGh2mVK31VD.0tRBVYEUEvxMvBx6C"
+
 // ValidateCredentials 验证管理员凭证
-func (s *authServiceImpl) ValidateCredentials(username, password string) error {
-	// 验证用户名
-	if username != s.username {
-		return ErrInvalidCredentials
+func (s *authServiceImpl) ValidateCredentials(ctx context.Context, username, password string) (*storage.Admin, error) {
+	admin, err := s.store.GetAdmin(ctx, username)
+	if errors.Is(err, storage.ErrAdminNotFound) {
+		CheckPassword(dummyPasswordHash, password)
+		return nil, ErrInvalidCredentials
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to load admin: %w", err)
 	}
 
 	// 使用bcrypt验证密码
-	err := bcrypt.CompareHashAndPassword([]byte(s.passwordHash), []byte(password))
+	if err := CheckPassword(admin.PasswordHash, password); err != nil {
+		return nil, err
+	}
+
+	return admin, nil
+}
+
+// CreateSession 创建会话并签发令牌
+func (s *authServiceImpl) CreateSession(ctx context.Context, admin *storage.Admin, ip, userAgent string) (*TokenPair, error) {
+	refreshToken, refreshHash, err := newRefreshToken()
 	if err != nil {
-		if errors.Is(err, bcrypt.ErrMismatchedHashAndPassword) {
-			return ErrInvalidCredentials
-		}
-		return fmt.Errorf("password verification failed: %w", err)
+		return nil, err
+	}
+
+	now := s.now()
+	session := &storage.AdminSession{
+		ID:               uuid.New().String(),
+		Username:         admin.Username,
+		RefreshTokenHash: refreshHash,
+		IP:               ip,
+		UserAgent:        userAgent,
+		CreatedAt:        now,
+		LastUsedAt:       now,
+		ExpiresAt:        now.Add(s.refreshDuration),
+	}
+	if err := s.store.CreateAdminSession(ctx, session); err != nil {
+		return nil, fmt.Errorf("failed to create session: %w", err)
+	}
+
+	return s.issue(admin, session, refreshToken)
+}
+
+// Refresh 轮换刷新令牌并签发新的访问令牌
+func (s *authServiceImpl) Refresh(ctx context.Context, refreshToken, ip, userAgent string) (*TokenPair, *storage.Admin, error) {
+	newToken, newHash, err := newRefreshToken()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	now := s.now()
+	session, err := s.store.RotateAdminSession(ctx, hashRefreshToken(refreshToken), &storage.AdminSession{
+		RefreshTokenHash: newHash,
+		IP:               ip,
+		UserAgent:        userAgent,
+		LastUsedAt:       now,
+		ExpiresAt:        now.Add(s.refreshDuration),
+	})
+	if errors.Is(err, storage.ErrSessionNotFound) || errors.Is(err, storage.ErrRefreshTokenReused) {
+		return nil, nil, fmt.Errorf("%w: %v", ErrInvalidToken, err)
+	}
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to rotate session: %w", err)
+	}
+
+	admin, err := s.store.GetAdmin(ctx, session.Username)
+	if errors.Is(err, storage.ErrAdminNotFound) {
+		return nil, nil, fmt.Errorf("%w: admin %s no longer exists", ErrInvalidToken, session.Username)
+	}
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to load admin: %w", err)
+	}
+
+	pair, err := s.issue(admin, session, newToken)
+	if err != nil {
+		return nil, nil, err
+	}
+	return pair, admin, nil
+}
+
+// issue 为会话签发访问令牌，与刷新令牌组成令牌对
+func (s *authServiceImpl) issue(admin *storage.Admin, session *storage.AdminSession, refreshToken string) (*TokenPair, error) {
+	accessToken, accessExpiresAt, err := s.GenerateToken(admin.Username, admin.Role, session.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	return &TokenPair{
+		SessionID:        session.ID,
+		AccessToken:      accessToken,
+		AccessExpiresAt:  accessExpiresAt,
+		RefreshToken:     refreshToken,
+		RefreshExpiresAt: session.ExpiresAt,
+	}, nil
+}
+
+// newRefreshToken 生成随机刷新令牌及其哈希
+func newRefreshToken() (token, hash string, err error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", "", fmt.Errorf("failed to generate refresh token: %w", err)
 	}
+	token = base64.RawURLEncoding.EncodeToString(buf)
+	return token, hashRefreshToken(token), nil
+}
 
-	return nil
+// hashRefreshToken 计算刷新令牌的哈希，数据库中只保存哈希
+func hashRefreshToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
 }
 
 // GenerateToken 生成JWT令牌
-func (s *authServiceImpl) GenerateToken(username string) (string, time.Time, error) {
+func (s *authServiceImpl) GenerateToken(username, role, sessionID string) (string, time.Time, error) {
 	// 计算过期时间
-	expiresAt := time.Now().Add(s.tokenDuration)
+	now := s.now()
+	expiresAt := now.Add(s.tokenDuration)
 
 	// 创建声明
 	claims := &Claims{
-		Username: username,
+		Username:  username,
+		Role:      role,
+		SessionID: sessionID,
 		RegisteredClaims: jwt.RegisteredClaims{
 			ExpiresAt: jwt.NewNumericDate(expiresAt),
-			IssuedAt:  jwt.NewNumericDate(time.Now()),
-			NotBefore: jwt.NewNumericDate(time.Now()),
+			IssuedAt:  jwt.NewNumericDate(now),
+			NotBefore: jwt.NewNumericDate(now),
 			Issuer:    "admin-dashboard",
 		},
 	}
@@ -102,7 +267,7 @@ func (s *authServiceImpl) GenerateToken(username string) (string, time.Time, err
 }
 
 // ValidateToken 验证JWT令牌
-func (s *authServiceImpl) ValidateToken(tokenString string) (*Claims, error) {
+func (s *authServiceImpl) ValidateToken(ctx context.Context, tokenString string) (*Claims, error) {
 	// 解析令牌
 	token, err := jwt.ParseWithClaims(tokenString, &Claims{}, func(token *jwt.Token) (interface{}, error) {
 		// 验证签名方法
@@ -110,7 +275,7 @@ func (s *authServiceImpl) ValidateToken(tokenString string) (*Claims, error) {
 			return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
 		}
 		return s.tokenSecret, nil
-	})
+	}, jwt.WithTimeFunc(s.now))
 
 	if err != nil {
 		// 检查是否是过期错误
@@ -126,5 +291,33 @@ func (s *authServiceImpl) ValidateToken(tokenString string) (*Claims, error) {
 		return nil, ErrInvalidToken
 	}
 
+	// 会话撤销（退出登录）后访问令牌立即失效
+	session, err := s.store.GetAdminSession(ctx, claims.SessionID)
+	if errors.Is(err, storage.ErrSessionNotFound) {
+		return nil, fmt.Errorf("%w: session not found", ErrInvalidToken)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to load session: %w", err)
+	}
+	now := s.now()
+	if session.Username != claims.Username || !session.Active(now) {
+		return nil, fmt.Errorf("%w: session revoked", ErrInvalidToken)
+	}
+	if now.Sub(session.LastUsedAt) >= touchInterval {
+		// 最近使用时间仅用于展示，更新失败不影响认证
+		_ = s.store.TouchAdminSession(ctx, session.ID, now)
+	}
+
+	// 账号被删除或角色变更后立即生效
+	admin, err := s.store.GetAdmin(ctx, claims.Username)
+	if errors.Is(err, storage.ErrAdminNotFound) {
+		return nil, fmt.Errorf("%w: admin %s no longer exists", ErrInvalidToken, claims.Username)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to load admin: %w", err)
+	}
+	claims.Role = admin.Role
+	claims.TOTPSetupRequired = admin.TOTPRequired && !admin.TOTPEnabled
+
 	return claims, nil
 }
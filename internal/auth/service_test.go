@@ -0,0 +1,40 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"cdk-get/internal/storage"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+func TestValidateCredentials(t *testing.T) {
+	ctx := context.Background()
+	hash, err := HashPassword("correct-password")
+	if err != nil {
+		t.Fatalf("HashPassword error: %v", err)
+	}
+	store := &fakeAdminStore{admin: &storage.Admin{Username: "alice", PasswordHash: hash, Role: storage.AdminRoleOwner}}
+	svc := NewAuthService(store, "test-secret", 0, 0, "cdk-get")
+
+	if admin, err := svc.ValidateCredentials(ctx, "alice", "correct-password"); err != nil || admin.Username != "alice" {
+		t.Fatalf("expected alice to log in, got %v, %v", admin, err)
+	}
+	if _, err := svc.ValidateCredentials(ctx, "alice", "wrong-password"); !errors.Is(err, ErrInvalidCredentials) {
+		t.Errorf("expected ErrInvalidCredentials for wrong password, got %v", err)
+	}
+	if _, err := svc.ValidateCredentials(ctx, "mallory", "correct-password"); !errors.Is(err, ErrInvalidCredentials) {
+		t.Errorf("expected ErrInvalidCredentials for unknown user, got %v", err)
+	}
+
+	// 用户名不存在时比对的哈希与真实密码哈希的成本相同，耗时一致
+	cost, err := bcrypt.Cost([]byte(dummyPasswordHash))
+	if err != nil {
+		t.Fatalf("dummy hash is not a bcrypt hash: %v", err)
+	}
+	if cost != bcrypt.DefaultCost {
+		t.Errorf("expected dummy hash cost %d, got %d", bcrypt.DefaultCost, cost)
+	}
+}
@@ -0,0 +1,257 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"cdk-get/internal/storage"
+)
+
+// rfc6238Secret RFC 6238 附录 B 中 SHA1 测试向量的密钥 "12345678901234567890"
+const rfc6238Secret = "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ"
+
+func TestTOTPCode_RFC6238Vectors(t *testing.T) {
+	// 附录 B 给出 8 位结果，6 位动态码为其后 6 位
+	tests := []struct {
+		unix int64
+		want string
+	}{
+		{59, "287082"},
+		{1111111109, "081804"},
+		{1111111111, "050471"},
+		{1234567890, "005924"},
+		{2000000000, "279037"},
+		{20000000000, "353130"},
+	}
+
+	for _, tt := range tests {
+		got, err := TOTPCode(rfc6238Secret, time.Unix(tt.unix, 0))
+		if err != nil {
+			t.Fatalf("TOTPCode(%d) error: %v", tt.unix, err)
+		}
+		if got != tt.want {
+			t.Errorf("TOTPCode(%d) = %s, want %s", tt.unix, got, tt.want)
+		}
+	}
+}
+
+func TestValidateTOTP_Window(t *testing.T) {
+	now := time.Unix(1111111111, 0)
+	code, err := TOTPCode(rfc6238Secret, now)
+	if err != nil {
+		t.Fatalf("TOTPCode error: %v", err)
+	}
+
+	tests := []struct {
+		name   string
+		at     time.Time
+		wantOK bool
+	}{
+		{"same step", now, true},
+		{"one step later", now.Add(totpPeriod), true},
+		{"one step earlier", now.Add(-totpPeriod), true},
+		{"two steps later", now.Add(2 * totpPeriod), false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			step, ok := ValidateTOTP(rfc6238Secret, code, tt.at)
+			if ok != tt.wantOK {
+				t.Fatalf("ValidateTOTP ok = %v, want %v", ok, tt.wantOK)
+			}
+			if ok && step != totpStep(now) {
+				t.Errorf("matched step = %d, want %d", step, totpStep(now))
+			}
+		})
+	}
+
+	if _, ok := ValidateTOTP(rfc6238Secret, "12345", now); ok {
+		t.Error("expected short code to be rejected")
+	}
+	if _, ok := ValidateTOTP("not base32!", code, now); ok {
+		t.Error("expected invalid secret to be rejected")
+	}
+}
+
+func TestTOTPProvisioningURI(t *testing.T) {
+	uri := TOTPProvisioningURI("cdk-get", "alice", rfc6238Secret)
+
+	parsed, err := url.Parse(uri)
+	if err != nil {
+		t.Fatalf("invalid uri %q: %v", uri, err)
+	}
+	if parsed.Scheme != "otpauth" || parsed.Host != "totp" || parsed.Path != "/cdk-get:alice" {
+		t.Errorf("unexpected uri %q", uri)
+	}
+	query := parsed.Query()
+	if query.Get("secret") != rfc6238Secret || query.Get("issuer") != "cdk-get" || query.Get("digits") != "6" {
+		t.Errorf("unexpected query %v", query)
+	}
+}
+
+func TestGenerateRecoveryCodes(t *testing.T) {
+	codes, hashes, err := GenerateRecoveryCodes()
+	if err != nil {
+		t.Fatalf("GenerateRecoveryCodes error: %v", err)
+	}
+	if len(codes) != recoveryCodeCount || len(hashes) != recoveryCodeCount {
+		t.Fatalf("expected %d codes, got %d codes and %d hashes", recoveryCodeCount, len(codes), len(hashes))
+	}
+
+	seen := make(map[string]bool)
+	for i, code := range codes {
+		if seen[code] {
+			t.Errorf("duplicate recovery code %s", code)
+		}
+		seen[code] = true
+		if isTOTPCode(code) {
+			t.Errorf("recovery code %s looks like a totp code", code)
+		}
+		// 输入时忽略大小写和连字符
+		if HashRecoveryCode(strings.ToUpper(strings.ReplaceAll(code, "-", ""))) != hashes[i] {
+			t.Errorf("hash of %s does not match", code)
+		}
+	}
+}
+
+// fakeAdminStore 在内存中保存两步验证状态的 AdminStore
+type fakeAdminStore struct {
+	admin         *storage.Admin
+	recoveryCodes map[string]bool
+}
+
+func (f *fakeAdminStore) GetAdmin(ctx context.Context, username string) (*storage.Admin, error) {
+	if f.admin == nil || f.admin.Username != username {
+		return nil, storage.ErrAdminNotFound
+	}
+	return f.admin, nil
+}
+
+func (f *fakeAdminStore) CreateAdminSession(ctx context.Context, session *storage.AdminSession) error {
+	return nil
+}
+
+func (f *fakeAdminStore) GetAdminSession(ctx context.Context, id string) (*storage.AdminSession, error) {
+	return nil, storage.ErrSessionNotFound
+}
+
+func (f *fakeAdminStore) RotateAdminSession(ctx context.Context, oldHash string, session *storage.AdminSession) (*storage.AdminSession, error) {
+	return nil, storage.ErrSessionNotFound
+}
+
+func (f *fakeAdminStore) TouchAdminSession(ctx context.Context, id string, at time.Time) error {
+	return nil
+}
+
+func (f *fakeAdminStore) SetAdminTOTPSecret(ctx context.Context, username, secret string) error {
+	f.admin.TOTPSecret = secret
+	return nil
+}
+
+func (f *fakeAdminStore) EnableAdminTOTP(ctx context.Context, username string, step int64, recoveryCodeHashes []string) error {
+	f.admin.TOTPEnabled = true
+	f.admin.TOTPLastStep = step
+	return f.ReplaceAdminRecoveryCodes(ctx, username, recoveryCodeHashes)
+}
+
+func (f *fakeAdminStore) UseAdminTOTPStep(ctx context.Context, username string, step int64) (bool, error) {
+	if step <= f.admin.TOTPLastStep {
+		return false, nil
+	}
+	f.admin.TOTPLastStep = step
+	return true, nil
+}
+
+func (f *fakeAdminStore) ReplaceAdminRecoveryCodes(ctx context.Context, username string, codeHashes []string) error {
+	f.recoveryCodes = make(map[string]bool)
+	for _, hash := range codeHashes {
+		f.recoveryCodes[hash] = true
+	}
+	return nil
+}
+
+func (f *fakeAdminStore) UseAdminRecoveryCode(ctx context.Context, username, codeHash string) (bool, error) {
+	if !f.recoveryCodes[codeHash] {
+		return false, nil
+	}
+	delete(f.recoveryCodes, codeHash)
+	return true, nil
+}
+
+func TestAuthService_TOTPEnrollmentAndLogin(t *testing.T) {
+	ctx := context.Background()
+	now := time.Date(2026, 10, 18, 12, 0, 0, 0, time.UTC)
+	store := &fakeAdminStore{admin: &storage.Admin{Username: "alice", Role: storage.AdminRoleOwner}}
+	svc := &authServiceImpl{
+		store:      store,
+		totpIssuer: "cdk-get",
+		now:        func() time.Time { return now },
+	}
+
+	// 未启用时不需要动态码
+	if err := svc.VerifySecondFactor(ctx, store.admin, ""); err != nil {
+		t.Fatalf("expected no second factor before enrollment, got %v", err)
+	}
+
+	enrollment, err := svc.BeginTOTPEnrollment(ctx, store.admin)
+	if err != nil {
+		t.Fatalf("BeginTOTPEnrollment error: %v", err)
+	}
+	if !strings.Contains(enrollment.URI, "secret="+enrollment.Secret) {
+		t.Errorf("uri %q does not contain the secret", enrollment.URI)
+	}
+
+	if _, err := svc.ConfirmTOTPEnrollment(ctx, store.admin, "000000"); !errors.Is(err, ErrInvalidTOTP) {
+		t.Fatalf("expected ErrInvalidTOTP for wrong code, got %v", err)
+	}
+	code, _ := TOTPCode(enrollment.Secret, now)
+	recoveryCodes, err := svc.ConfirmTOTPEnrollment(ctx, store.admin, code)
+	if err != nil {
+		t.Fatalf("ConfirmTOTPEnrollment error: %v", err)
+	}
+	if !store.admin.TOTPEnabled || len(recoveryCodes) != recoveryCodeCount {
+		t.Fatalf("expected totp enabled with recovery codes, got %+v, %d codes", store.admin, len(recoveryCodes))
+	}
+	if _, err := svc.BeginTOTPEnrollment(ctx, store.admin); !errors.Is(err, ErrTOTPAlreadyEnabled) {
+		t.Fatalf("expected ErrTOTPAlreadyEnabled, got %v", err)
+	}
+
+	if err := svc.VerifySecondFactor(ctx, store.admin, ""); !errors.Is(err, ErrTOTPRequired) {
+		t.Fatalf("expected ErrTOTPRequired, got %v", err)
+	}
+	// 确认启用时使用的动态码不能再用于登录
+	if err := svc.VerifySecondFactor(ctx, store.admin, code); !errors.Is(err, ErrInvalidTOTP) {
+		t.Fatalf("expected replayed code to be rejected, got %v", err)
+	}
+
+	now = now.Add(totpPeriod)
+	code, _ = TOTPCode(enrollment.Secret, now)
+	if err := svc.VerifySecondFactor(ctx, store.admin, code); err != nil {
+		t.Fatalf("expected next code to be accepted, got %v", err)
+	}
+
+	// 恢复码只能使用一次
+	if err := svc.VerifySecondFactor(ctx, store.admin, recoveryCodes[0]); err != nil {
+		t.Fatalf("expected recovery code to be accepted, got %v", err)
+	}
+	if err := svc.VerifySecondFactor(ctx, store.admin, recoveryCodes[0]); !errors.Is(err, ErrInvalidTOTP) {
+		t.Fatalf("expected used recovery code to be rejected, got %v", err)
+	}
+
+	// 重新生成恢复码只接受动态码，旧恢复码随即失效
+	if _, err := svc.RegenerateRecoveryCodes(ctx, store.admin, recoveryCodes[1]); !errors.Is(err, ErrInvalidTOTP) {
+		t.Fatalf("expected recovery code to be rejected for regeneration, got %v", err)
+	}
+	now = now.Add(totpPeriod)
+	code, _ = TOTPCode(enrollment.Secret, now)
+	if _, err := svc.RegenerateRecoveryCodes(ctx, store.admin, code); err != nil {
+		t.Fatalf("RegenerateRecoveryCodes error: %v", err)
+	}
+	if err := svc.VerifySecondFactor(ctx, store.admin, recoveryCodes[1]); !errors.Is(err, ErrInvalidTOTP) {
+		t.Fatalf("expected old recovery code to be rejected, got %v", err)
+	}
+}
@@ -0,0 +1,142 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"cdk-get/internal/storage"
+)
+
+var (
+	// ErrTOTPRequired 账号已启用两步验证但未提供动态码错误
+	ErrTOTPRequired = errors.New("totp code required")
+	// ErrInvalidTOTP 动态码或恢复码无效错误
+	ErrInvalidTOTP = errors.New("invalid totp code")
+	// ErrTOTPAlreadyEnabled 两步验证已启用错误
+	ErrTOTPAlreadyEnabled = errors.New("totp already enabled")
+	// ErrTOTPNotEnabled 两步验证未启用或未开始启用错误
+	ErrTOTPNotEnabled = errors.New("totp not enabled")
+)
+
+// VerifySecondFactor 校验动态码或恢复码
+// 6 位数字按动态码校验，其余按恢复码校验
+func (s *authServiceImpl) VerifySecondFactor(ctx context.Context, admin *storage.Admin, code string) error {
+	if !admin.TOTPEnabled {
+		return nil
+	}
+
+	code = strings.TrimSpace(code)
+	if code == "" {
+		return ErrTOTPRequired
+	}
+
+	if isTOTPCode(code) {
+		return s.verifyTOTP(ctx, admin, code)
+	}
+
+	used, err := s.store.UseAdminRecoveryCode(ctx, admin.Username, HashRecoveryCode(code))
+	if err != nil {
+		return fmt.Errorf("failed to use recovery code: %w", err)
+	}
+	if !used {
+		return ErrInvalidTOTP
+	}
+	return nil
+}
+
+// BeginTOTPEnrollment 生成待启用的密钥
+// 重复调用会替换尚未确认的密钥
+func (s *authServiceImpl) BeginTOTPEnrollment(ctx context.Context, admin *storage.Admin) (*TOTPEnrollment, error) {
+	if admin.TOTPEnabled {
+		return nil, ErrTOTPAlreadyEnabled
+	}
+
+	secret, err := GenerateTOTPSecret()
+	if err != nil {
+		return nil, err
+	}
+	if err := s.store.SetAdminTOTPSecret(ctx, admin.Username, secret); err != nil {
+		return nil, fmt.Errorf("failed to save totp secret: %w", err)
+	}
+
+	return &TOTPEnrollment{
+		Secret: secret,
+		URI:    TOTPProvisioningURI(s.totpIssuer, admin.Username, secret),
+	}, nil
+}
+
+// ConfirmTOTPEnrollment 校验待启用密钥的动态码并启用两步验证
+func (s *authServiceImpl) ConfirmTOTPEnrollment(ctx context.Context, admin *storage.Admin, code string) ([]string, error) {
+	if admin.TOTPEnabled {
+		return nil, ErrTOTPAlreadyEnabled
+	}
+	if admin.TOTPSecret == "" {
+		return nil, ErrTOTPNotEnabled
+	}
+
+	step, ok := ValidateTOTP(admin.TOTPSecret, strings.TrimSpace(code), s.now())
+	if !ok {
+		return nil, ErrInvalidTOTP
+	}
+
+	codes, hashes, err := GenerateRecoveryCodes()
+	if err != nil {
+		return nil, err
+	}
+	if err := s.store.EnableAdminTOTP(ctx, admin.Username, step, hashes); err != nil {
+		return nil, fmt.Errorf("failed to enable totp: %w", err)
+	}
+	return codes, nil
+}
+
+// RegenerateRecoveryCodes 校验动态码后重新生成恢复码
+// 只接受动态码，避免用恢复码换取新的恢复码
+func (s *authServiceImpl) RegenerateRecoveryCodes(ctx context.Context, admin *storage.Admin, code string) ([]string, error) {
+	if !admin.TOTPEnabled {
+		return nil, ErrTOTPNotEnabled
+	}
+	if err := s.verifyTOTP(ctx, admin, strings.TrimSpace(code)); err != nil {
+		return nil, err
+	}
+
+	codes, hashes, err := GenerateRecoveryCodes()
+	if err != nil {
+		return nil, err
+	}
+	if err := s.store.ReplaceAdminRecoveryCodes(ctx, admin.Username, hashes); err != nil {
+		return nil, fmt.Errorf("failed to replace recovery codes: %w", err)
+	}
+	return codes, nil
+}
+
+// verifyTOTP 校验动态码，并拒绝已使用过的时间步
+func (s *authServiceImpl) verifyTOTP(ctx context.Context, admin *storage.Admin, code string) error {
+	step, ok := ValidateTOTP(admin.TOTPSecret, code, s.now())
+	if !ok {
+		return ErrInvalidTOTP
+	}
+
+	fresh, err := s.store.UseAdminTOTPStep(ctx, admin.Username, step)
+	if err != nil {
+		return fmt.Errorf("failed to record totp step: %w", err)
+	}
+	if !fresh {
+		return fmt.Errorf("%w: code already used", ErrInvalidTOTP)
+	}
+	return nil
+}
+
+// isTOTPCode 判断是否为动态码格式（totpDigits 位数字）
+func isTOTPCode(code string) bool {
+	if len(code) != totpDigits {
+		return false
+	}
+	for _, r := range code {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return true
+}
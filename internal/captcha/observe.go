@@ -0,0 +1,35 @@
+package captcha
+
+import (
+	"io"
+	"time"
+)
+
+// CallObserver 每次识别结束后调用，provider 为提供商类型，elapsed 为识别耗时，err 为识别错误
+type CallObserver func(provider string, elapsed time.Duration, err error)
+
+// observedClient 每次识别结束后调用 observe 的客户端
+type observedClient struct {
+	RemoteClient
+	provider string
+	observe  CallObserver
+}
+
+// Observe 包装 client，每次识别结束后以 provider 调用 observe，用于统计识别次数、耗时和费用
+func Observe(client RemoteClient, provider string, observe CallObserver) RemoteClient {
+	return &observedClient{RemoteClient: client, provider: provider, observe: observe}
+}
+
+func (o *observedClient) DoWithBase64Img(base64Img string) (*CaptchaResponse, error) {
+	start := time.Now()
+	resp, err := o.RemoteClient.DoWithBase64Img(base64Img)
+	o.observe(o.provider, time.Since(start), err)
+	return resp, err
+}
+
+func (o *observedClient) DoWithReader(r io.Reader) (*CaptchaResponse, error) {
+	start := time.Now()
+	resp, err := o.RemoteClient.DoWithReader(r)
+	o.observe(o.provider, time.Since(start), err)
+	return resp, err
+}
@@ -11,8 +11,9 @@ import (
 // CaptchaPool 验证码客户端池
 // 使用无锁轮询算法分配客户端
 type CaptchaPool struct {
-	clients []RemoteClient
-	idx     atomic.Uint32
+	clients   []RemoteClient
+	providers []string // 与 clients 一一对应的提供商类型
+	idx       atomic.Uint32
 }
 
 // NewCaptchaPool 从配置创建验证码客户端池
@@ -22,6 +23,7 @@ func NewCaptchaPool(providers []config.CaptchaProvider) (*CaptchaPool, error) {
 	}
 
 	var clients []RemoteClient
+	var names []string
 
 	for i, provider := range providers {
 		var client RemoteClient
@@ -70,6 +72,7 @@ func NewCaptchaPool(providers []config.CaptchaProvider) (*CaptchaPool, error) {
 		}
 
 		clients = append(clients, client)
+		names = append(names, provider.Type)
 	}
 
 	if len(clients) == 0 {
@@ -79,7 +82,8 @@ func NewCaptchaPool(providers []config.CaptchaProvider) (*CaptchaPool, error) {
 	logrus.Infof("captcha pool initialized with %d clients", len(clients))
 
 	return &CaptchaPool{
-		clients: clients,
+		clients:   clients,
+		providers: names,
 	}, nil
 }
 
@@ -95,6 +99,14 @@ func (p *CaptchaPool) Get() RemoteClient {
 	return p.clients[idx%uint32(len(p.clients))]
 }
 
+// Observe 包装池中的所有客户端，每次识别结束后调用 observe
+// 须在开始使用池之前调用
+func (p *CaptchaPool) Observe(observe CallObserver) {
+	for i, client := range p.clients {
+		p.clients[i] = Observe(client, p.providers[i], observe)
+	}
+}
+
 // Size 返回池中客户端数量
 func (p *CaptchaPool) Size() int {
 	return len(p.clients)
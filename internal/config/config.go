@@ -2,7 +2,11 @@ package config
 
 import (
 	"fmt"
+	"net"
+	"net/url"
 	"os"
+	"slices"
+	"strings"
 	"time"
 
 	"gopkg.in/yaml.v2"
@@ -18,16 +22,33 @@ type Config struct {
 	Security     SecurityConfig     `yaml:"security"`
 	Admin        AdminConfig        `yaml:"admin"`
 	Notification NotificationConfig `yaml:"notification"`
+	Retention    RetentionConfig    `yaml:"retention"`
+	Metrics      MetricsConfig      `yaml:"metrics"`
 }
 
 // ServerConfig HTTP服务器配置
 type ServerConfig struct {
+	Mode         string        `yaml:"mode"` // production 或 dev，dev 模式下允许使用示例密钥启动
 	Host         string        `yaml:"host"`
 	Port         int           `yaml:"port"`
 	ReadTimeout  time.Duration `yaml:"read_timeout"`
 	WriteTimeout time.Duration `yaml:"write_timeout"`
 	IdleTimeout  time.Duration `yaml:"idle_timeout"`
 	CORS         CORSConfig    `yaml:"cors"`
+	// TrustedProxies 可信反向代理的 IP 或 CIDR，仅这些地址发来的 X-Forwarded-For 用于识别客户端 IP
+	// 默认为空，即始终使用连接的对端地址，防止伪造请求头绕过登录锁定和限流
+	TrustedProxies []string `yaml:"trusted_proxies"`
+}
+
+// 运行模式
+const (
+	ServerModeProduction = "production"
+	ServerModeDev        = "dev"
+)
+
+// IsDev 是否为开发模式
+func (s ServerConfig) IsDev() bool {
+	return s.Mode == ServerModeDev
 }
 
 // CORSConfig CORS配置
@@ -56,17 +77,31 @@ type CaptchaConfig struct {
 
 // CaptchaProvider 验证码提供商配置
 type CaptchaProvider struct {
-	Type            string `yaml:"type"`             // "ali", "tencent", or "google"
-	AccessKey       string `yaml:"access_key"`       // 可以从环境变量覆盖 (ali/tencent)
-	SecretKey       string `yaml:"secret_key"`       // 可以从环境变量覆盖 (ali/tencent)
-	CredentialsJSON string `yaml:"credentials_json"` // Google Cloud credentials JSON (google)
+	Type            string  `yaml:"type"`             // "ali", "tencent", or "google"
+	AccessKey       string  `yaml:"access_key"`       // 可以从环境变量覆盖 (ali/tencent)
+	SecretKey       string  `yaml:"secret_key"`       // 可以从环境变量覆盖 (ali/tencent)
+	CredentialsJSON string  `yaml:"credentials_json"` // Google Cloud credentials JSON (google)
+	UnitPrice       float64 `yaml:"unit_price"`       // 每次识别的费用，用于统计页估算验证码费用，0 表示不计费
+}
+
+// UnitPrice 返回 providerType 类型提供商的单次识别费用，同一类型配置了多个时取第一个
+func (c CaptchaConfig) UnitPrice(providerType string) float64 {
+	for _, provider := range c.Providers {
+		if provider.Type == providerType {
+			return provider.UnitPrice
+		}
+	}
+	return 0
 }
 
 // JobConfig 任务调度配置
 type JobConfig struct {
-	DelayTime      time.Duration `yaml:"delay_time"`
-	PeriodTime     time.Duration `yaml:"period_time"`
-	WorkerPoolSize int           `yaml:"worker_pool_size"`
+	DelayTime           time.Duration `yaml:"delay_time"`
+	PeriodTime          time.Duration `yaml:"period_time"`
+	WorkerPoolSize      int           `yaml:"worker_pool_size"`
+	UserRefreshInterval time.Duration `yaml:"user_refresh_interval"` // 用户资料刷新周期, 0 表示不刷新
+	MaxTaskRetries      int           `yaml:"max_task_retries"`      // 任务最大重试次数, 达到后不再处理, 0 表示不限制
+	GameAPIURL          string        `yaml:"game_api_url"`          // 游戏兑换接口的地址前缀
 }
 
 // LoggingConfig 日志配置
@@ -77,33 +112,283 @@ type LoggingConfig struct {
 
 // SecurityConfig 安全配置
 type SecurityConfig struct {
-	RateLimit RateLimitConfig `yaml:"rate_limit"`
+	RateLimit       RateLimitConfig       `yaml:"rate_limit"`
+	LoginProtection LoginProtectionConfig `yaml:"login_protection"`
+	APIKey          APIKeyConfig          `yaml:"api_key"`
+}
+
+// APIKeyConfig 公开接口 API 密钥配置
+// 密钥由管理员在管理后台创建，调用方通过 X-API-Key 请求头传递
+type APIKeyConfig struct {
+	// Required 为 false 时未携带密钥的请求仍然放行，仅用于迁移期间让旧脚本继续工作
+	// 携带了密钥的请求始终校验密钥和权限
+	Required bool `yaml:"required"`
 }
 
 // RateLimitConfig 限流配置
+// 每个路由分组使用独立的令牌桶集合，按已认证的管理员、API 密钥或客户端 IP 分别计数
 type RateLimitConfig struct {
-	Enabled bool `yaml:"enabled"`
-	Rate    int  `yaml:"rate"`  // 每秒请求数
-	Burst   int  `yaml:"burst"` // 突发请求数
+	Enabled     bool                     `yaml:"enabled"`
+	Rate        float64                  `yaml:"rate"`         // 默认每秒请求数，用于未单独配置的分组
+	Burst       int                      `yaml:"burst"`        // 默认突发请求数
+	MaxKeys     int                      `yaml:"max_keys"`     // 每个分组最多保留的令牌桶数，超出时淘汰最久未使用的
+	IdleTimeout time.Duration            `yaml:"idle_timeout"` // 闲置超过该时长的令牌桶被淘汰
+	Groups      map[string]RateLimitRule `yaml:"groups"`       // 按路由分组覆盖默认限额
+}
+
+// RateLimitRule 令牌桶限额
+type RateLimitRule struct {
+	Rate  float64 `yaml:"rate"`  // 每秒请求数
+	Burst int     `yaml:"burst"` // 突发请求数
+}
+
+// 限流路由分组
+const (
+	RateLimitGroupPublic = "public"  // /giftcode、/add_user、/ip
+	RateLimitGroupLogin  = "login"   // /api/admin/login、/api/admin/refresh
+	RateLimitGroupAdmin  = "admin"   // 需要登录的管理接口，按管理员计数
+	RateLimitGroupStatic = "static"  // 管理后台页面和静态文件
+	RateLimitGroupAPIKey = "api_key" // 无效的 API 密钥，按客户端 IP 计数
+)
+
+// RateLimitGroups lists every rate limit route group
+var RateLimitGroups = []string{RateLimitGroupPublic, RateLimitGroupLogin, RateLimitGroupAdmin, RateLimitGroupStatic, RateLimitGroupAPIKey}
+
+// Rule 返回路由分组的限额，未单独配置的分组使用默认限额
+func (r RateLimitConfig) Rule(group string) RateLimitRule {
+	if rule, ok := r.Groups[group]; ok {
+		return rule
+	}
+	return RateLimitRule{Rate: r.Rate, Burst: r.Burst}
+}
+
+// LoginProtectionConfig 管理员登录防爆破配置
+// 按客户端 IP 和用户名分别统计计数窗口内的连续失败次数：
+// 每次失败后需等待 base_delay·2^(失败次数-1)（不超过 max_delay）才能再次尝试，
+// 达到上限后锁定 lockout_duration，连续锁定时长逐次翻倍，不超过 max_lockout_duration
+type LoginProtectionConfig struct {
+	Enabled            bool          `yaml:"enabled"`
+	MaxFailuresPerUser int           `yaml:"max_failures_per_user"` // 同一用户名允许的连续失败次数
+	MaxFailuresPerIP   int           `yaml:"max_failures_per_ip"`   // 同一 IP 允许的连续失败次数
+	Window             time.Duration `yaml:"window"`                // 计数窗口，距最近一次失败超过该时长后重新计数
+	BaseDelay          time.Duration `yaml:"base_delay"`            // 首次失败后的等待时间
+	MaxDelay           time.Duration `yaml:"max_delay"`             // 等待时间上限
+	LockoutDuration    time.Duration `yaml:"lockout_duration"`      // 首次锁定时长
+	MaxLockoutDuration time.Duration `yaml:"max_lockout_duration"`  // 锁定时长上限
 }
 
 // AdminConfig 管理员配置
 type AdminConfig struct {
-	Username      string        `yaml:"username"`       // 管理员用户名
-	PasswordHash  string        `yaml:"password_hash"`  // 管理员密码的bcrypt哈希
-	TokenSecret   string        `yaml:"token_secret"`   // JWT签名密钥
-	TokenDuration time.Duration `yaml:"token_duration"` // JWT令牌有效期
+	Username             string        `yaml:"username"`               // 管理员用户名
+	PasswordHash         string        `yaml:"password_hash"`          // 管理员密码的bcrypt哈希
+	TokenSecret          string        `yaml:"token_secret"`           // JWT签名密钥
+	TokenDuration        time.Duration `yaml:"token_duration"`         // 访问令牌（JWT）有效期，过期后用刷新令牌换取新令牌
+	RefreshTokenDuration time.Duration `yaml:"refresh_token_duration"` // 刷新令牌有效期，会话闲置超过该时间需重新登录
+	TOTPIssuer           string        `yaml:"totp_issuer"`            // 两步验证在验证器应用中显示的服务名称
 }
 
 // NotificationConfig 通知配置
 type NotificationConfig struct {
-	WxPusher WxPusherConfig `yaml:"wxpusher"`
+	WxPusher  WxPusherConfig                  `yaml:"wxpusher"`  // 兼容旧配置，等价于一个 wxpusher 渠道
+	Channels  []NotificationChannel           `yaml:"channels"`  // 通知渠道列表
+	Routes    []NotificationRoute             `yaml:"routes"`    // 事件路由规则，未配置时所有事件发送到所有渠道
+	Templates map[string]NotificationTemplate `yaml:"templates"` // 按事件类型配置的消息模板
+	Outbox    OutboxConfig                    `yaml:"outbox"`    // 通知投递重试配置
+}
+
+// OutboxConfig 通知投递配置
+// 通知先以 pending 状态写入数据库，由后台任务投递，失败后按指数退避重试
+type OutboxConfig struct {
+	MaxAttempts    int           `yaml:"max_attempts"`    // 最大投递次数，达到后标记为 failed
+	InitialBackoff time.Duration `yaml:"initial_backoff"` // 首次重试间隔，之后每次翻倍
+	MaxBackoff     time.Duration `yaml:"max_backoff"`     // 重试间隔上限
+	PollInterval   time.Duration `yaml:"poll_interval"`   // 检查待投递通知的周期
+}
+
+// NotificationRoute 通知路由规则
+// 事件类型和级别都匹配时，事件会发送到该规则的渠道；多条规则匹配时取渠道并集
+type NotificationRoute struct {
+	Events      []string `yaml:"events"`       // 匹配的事件类型，为空表示所有事件
+	MinSeverity string   `yaml:"min_severity"` // 最低事件级别: info, warning, critical，默认 info
+	Channels    []string `yaml:"channels"`     // 目标渠道名称
+}
+
+// NotificationTemplate 通知消息模板
+// 标题、摘要和纯文本/Markdown 正文使用 text/template，HTML 正文使用 html/template。
+// 模板数据为事件本身，可引用 .Title .Summary .Content .Severity 和 .Data 中的字段；留空的字段使用默认文本。
+// 正文按渠道格式选择 markdown/html 模板，未配置时使用 content 并自动转换格式。
+type NotificationTemplate struct {
+	Title        string                          `yaml:"title"`
+	Summary      string                          `yaml:"summary"`
+	Content      string                          `yaml:"content"`       // 纯文本正文
+	Markdown     string                          `yaml:"markdown"`      // Markdown 渠道使用的正文
+	HTML         string                          `yaml:"html"`          // HTML 渠道使用的正文
+	ContentFile  string                          `yaml:"content_file"`  // 从文件读取纯文本正文模板
+	MarkdownFile string                          `yaml:"markdown_file"` // 从文件读取 Markdown 正文模板
+	HTMLFile     string                          `yaml:"html_file"`     // 从文件读取 HTML 正文模板
+	Channels     map[string]NotificationTemplate `yaml:"channels"`      // 按渠道名称覆盖模板，未配置的字段沿用事件模板
 }
 
 // WxPusherConfig WxPusher通知配置
 type WxPusherConfig struct {
-	AppToken string `yaml:"app_token"` // WxPusher应用Token
-	UID      string `yaml:"uid"`       // WxPusher用户UID
+	AppToken     string        `yaml:"app_token"`     // WxPusher应用Token
+	UID          string        `yaml:"uid"`           // WxPusher用户UID
+	DigestWindow time.Duration `yaml:"digest_window"` // 汇总窗口，见 NotificationChannel
+	QuietHours   string        `yaml:"quiet_hours"`   // 免打扰时段，见 NotificationChannel
+}
+
+// 通知内容格式
+const (
+	ContentFormatPlain    = "plain"
+	ContentFormatMarkdown = "markdown"
+	ContentFormatHTML     = "html"
+)
+
+// channelFormats 可配置内容格式的渠道及其支持的格式，其余渠道格式固定
+var channelFormats = map[string][]string{
+	ChannelTypeWxPusher: {ContentFormatPlain, ContentFormatMarkdown, ContentFormatHTML},
+	ChannelTypeWebhook:  {ContentFormatPlain, ContentFormatMarkdown, ContentFormatHTML},
+	ChannelTypeEmail:    {ContentFormatPlain, ContentFormatHTML},
+}
+
+// 通知渠道类型
+const (
+	ChannelTypeWxPusher   = "wxpusher"
+	ChannelTypeWebhook    = "webhook"
+	ChannelTypeEmail      = "email"
+	ChannelTypeTelegram   = "telegram"
+	ChannelTypeDingTalk   = "dingtalk"
+	ChannelTypeFeishu     = "feishu"
+	ChannelTypeServerChan = "serverchan"
+	ChannelTypeBark       = "bark"
+)
+
+// NotificationChannel 通知渠道配置
+// 不同类型的渠道使用的字段不同，见各字段说明
+type NotificationChannel struct {
+	Name       string     `yaml:"name"`       // 渠道名称，记录在通知历史中，默认与 type 相同
+	Type       string     `yaml:"type"`       // wxpusher, webhook, email, telegram, dingtalk, feishu, serverchan, bark
+	Disabled   bool       `yaml:"disabled"`   // 是否停用该渠道
+	Format     string     `yaml:"format"`     // 内容格式 plain/markdown/html，仅 wxpusher、webhook、email 可配置
+	URL        string     `yaml:"url"`        // webhook/dingtalk/feishu 的推送地址；其余类型可覆盖默认 API 地址
+	Token      string     `yaml:"token"`      // wxpusher app_token / telegram bot token / serverchan send key / bark device key
+	Secret     string     `yaml:"secret"`     // webhook HMAC 密钥 / dingtalk、feishu 加签密钥
+	Recipients []string   `yaml:"recipients"` // wxpusher UID / 收件邮箱 / telegram chat id
+	SMTP       SMTPConfig `yaml:"smtp"`       // email 渠道的 SMTP 服务器
+	// DigestWindow 汇总窗口，大于 0 时窗口内的非 critical 通知合并为一条发送
+	DigestWindow time.Duration `yaml:"digest_window"`
+	// QuietHours 免打扰时段，如 "23:00-07:00"（服务器本地时间），期间非 critical 通知推迟到时段结束后发送
+	QuietHours string `yaml:"quiet_hours"`
+}
+
+// EffectiveChannels 返回实际生效的通知渠道列表，名称为空时使用渠道类型
+// 旧的 wxpusher 配置作为名为 "wxpusher" 的渠道排在最前
+func (n NotificationConfig) EffectiveChannels() []NotificationChannel {
+	channels := make([]NotificationChannel, 0, len(n.Channels)+1)
+	if n.WxPusher.AppToken != "" && n.WxPusher.UID != "" {
+		channels = append(channels, NotificationChannel{
+			Name:         ChannelTypeWxPusher,
+			Type:         ChannelTypeWxPusher,
+			Token:        n.WxPusher.AppToken,
+			Recipients:   []string{n.WxPusher.UID},
+			DigestWindow: n.WxPusher.DigestWindow,
+			QuietHours:   n.WxPusher.QuietHours,
+		})
+	}
+	for _, channel := range n.Channels {
+		if channel.Name == "" {
+			channel.Name = channel.Type
+		}
+		channels = append(channels, channel)
+	}
+	return channels
+}
+
+// QuietHours 每日免打扰时段，结束时间早于开始时间表示跨越午夜
+type QuietHours struct {
+	Start time.Duration // 自零点起的开始时间
+	End   time.Duration // 自零点起的结束时间
+}
+
+// ParseQuietHours 解析 "HH:MM-HH:MM" 格式的免打扰时段
+func ParseQuietHours(s string) (QuietHours, error) {
+	start, end, ok := strings.Cut(strings.TrimSpace(s), "-")
+	if !ok {
+		return QuietHours{}, fmt.Errorf("invalid quiet_hours %q (expected HH:MM-HH:MM)", s)
+	}
+	var q QuietHours
+	var err error
+	if q.Start, err = parseClock(start); err != nil {
+		return QuietHours{}, fmt.Errorf("invalid quiet_hours %q: %w", s, err)
+	}
+	if q.End, err = parseClock(end); err != nil {
+		return QuietHours{}, fmt.Errorf("invalid quiet_hours %q: %w", s, err)
+	}
+	if q.Start == q.End {
+		return QuietHours{}, fmt.Errorf("invalid quiet_hours %q (start and end must differ)", s)
+	}
+	return q, nil
+}
+
+// parseClock 解析 HH:MM 为自零点起的时长
+func parseClock(s string) (time.Duration, error) {
+	t, err := time.Parse("15:04", strings.TrimSpace(s))
+	if err != nil {
+		return 0, fmt.Errorf("invalid time %q", s)
+	}
+	return time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute, nil
+}
+
+// Until 返回 t 处于免打扰时段时该时段的结束时间，否则返回 t 本身
+func (q QuietHours) Until(t time.Time) time.Time {
+	midnight := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+	clock := t.Sub(midnight)
+
+	if q.Start < q.End {
+		if clock >= q.Start && clock < q.End {
+			return midnight.Add(q.End)
+		}
+		return t
+	}
+
+	// 跨越午夜，如 23:00-07:00
+	switch {
+	case clock >= q.Start:
+		return midnight.AddDate(0, 0, 1).Add(q.End)
+	case clock < q.End:
+		return midnight.Add(q.End)
+	default:
+		return t
+	}
+}
+
+// SMTPConfig SMTP服务器配置
+type SMTPConfig struct {
+	Host     string `yaml:"host"`
+	Port     int    `yaml:"port"` // 465 使用 TLS 直连，其余端口在服务器支持时使用 STARTTLS
+	Username string `yaml:"username"`
+	Password string `yaml:"password"`
+	From     string `yaml:"from"`
+}
+
+// RetentionConfig 数据保留配置
+// 各保留时长为 0 表示该数据永久保留
+type RetentionConfig struct {
+	Enabled          bool          `yaml:"enabled"`            // 是否启用定期清理
+	Interval         time.Duration `yaml:"interval"`           // 清理任务执行周期
+	BatchSize        int           `yaml:"batch_size"`         // 每批删除的最大行数
+	Notifications    time.Duration `yaml:"notifications"`      // 通知记录保留时长
+	CompletedTasks   time.Duration `yaml:"completed_tasks"`    // 已完成任务保留时长
+	TrashGracePeriod time.Duration `yaml:"trash_grace_period"` // 回收站中的任务和用户保留时长，超期后彻底删除
+	LoginFailures    time.Duration `yaml:"login_failures"`     // 失败登录审计记录保留时长
+	AuditLog         time.Duration `yaml:"audit_log"`          // 管理操作审计记录保留时长
+	Statistics       time.Duration `yaml:"statistics"`         // 兑换请求和验证码识别记录保留时长，用于统计报表
+}
+
+// MetricsConfig Prometheus 指标接口配置
+type MetricsConfig struct {
+	Enabled bool   `yaml:"enabled"` // 是否提供 /metrics 接口
+	Token   string `yaml:"token"`   // 非空时请求须携带 Authorization: Bearer <token>
 }
 
 // LoadConfig 从文件和环境变量加载配置
@@ -133,6 +418,7 @@ func LoadConfig(configPath string) (*Config, error) {
 func defaultConfig() *Config {
 	return &Config{
 		Server: ServerConfig{
+			Mode:         ServerModeProduction,
 			Host:         "0.0.0.0",
 			Port:         10999,
 			ReadTimeout:  30 * time.Second,
@@ -142,8 +428,8 @@ func defaultConfig() *Config {
 				Enabled:          true,
 				AllowOrigins:     []string{"*"},
 				AllowMethods:     []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"},
-				AllowHeaders:     []string{"Origin", "Content-Type", "Accept", "Authorization", "X-Request-ID"},
-				ExposeHeaders:    []string{"X-Request-ID"},
+				AllowHeaders:     []string{"Origin", "Content-Type", "Accept", "Authorization", "X-Request-ID", "X-API-Key"},
+				ExposeHeaders:    []string{"X-Request-ID", "RateLimit-Limit", "RateLimit-Remaining", "RateLimit-Reset", "Retry-After"},
 				AllowCredentials: false,
 				MaxAge:           3600,
 			},
@@ -158,9 +444,12 @@ func defaultConfig() *Config {
 			Providers: []CaptchaProvider{},
 		},
 		Job: JobConfig{
-			DelayTime:      2 * time.Second,
-			PeriodTime:     30 * time.Second,
-			WorkerPoolSize: 5,
+			DelayTime:           2 * time.Second,
+			PeriodTime:          30 * time.Second,
+			WorkerPoolSize:      5,
+			UserRefreshInterval: 24 * time.Hour,
+			MaxTaskRetries:      20,
+			GameAPIURL:          "https://wjdr-giftcode-api.campfiregames.cn/api/",
 		},
 		Logging: LoggingConfig{
 			Level:  "info",
@@ -168,22 +457,64 @@ func defaultConfig() *Config {
 		},
 		Security: SecurityConfig{
 			RateLimit: RateLimitConfig{
-				Enabled: true,
-				Rate:    10,
-				Burst:   20,
+				Enabled:     true,
+				Rate:        10,
+				Burst:       20,
+				MaxKeys:     10000,
+				IdleTimeout: 10 * time.Minute,
+				Groups: map[string]RateLimitRule{
+					RateLimitGroupLogin:  {Rate: 1, Burst: 10},
+					RateLimitGroupAdmin:  {Rate: 20, Burst: 60},
+					RateLimitGroupAPIKey: {Rate: 1, Burst: 10},
+				},
+			},
+			LoginProtection: LoginProtectionConfig{
+				Enabled:            true,
+				MaxFailuresPerUser: 5,
+				MaxFailuresPerIP:   20,
+				Window:             15 * time.Minute,
+				BaseDelay:          time.Second,
+				MaxDelay:           30 * time.Second,
+				LockoutDuration:    15 * time.Minute,
+				MaxLockoutDuration: 24 * time.Hour,
+			},
+			APIKey: APIKeyConfig{
+				Required: true,
 			},
 		},
 		Admin: AdminConfig{
-			Username:      "admin",
-			PasswordHash:  "$2a$10$N9qo8uLOickgx2ZMRZoMyeIjZAgcfl7p92ldGxad68LJZdL17lhWy", // bcrypt hash of "admin123"
-			TokenSecret:   "default-secret-key-change-in-production-min-32-characters",
-			TokenDuration: 24 * time.Hour,
+			Username:             "admin",
+			PasswordHash:         "$2a$10$N9qo8uLOickgx2ZMRZoMyeIjZAgcfl7p92ldGxad68LJZdL17lhWy", // bcrypt hash of "admin123"
+			TokenSecret:          "default-secret-key-change-in-production-min-32-characters",
+			TokenDuration:        15 * time.Minute,
+			RefreshTokenDuration: 7 * 24 * time.Hour,
+			TOTPIssuer:           "cdk-get",
 		},
 		Notification: NotificationConfig{
 			WxPusher: WxPusherConfig{
 				AppToken: "",
 				UID:      "",
 			},
+			Outbox: OutboxConfig{
+				MaxAttempts:    5,
+				InitialBackoff: 30 * time.Second,
+				MaxBackoff:     30 * time.Minute,
+				PollInterval:   10 * time.Second,
+			},
+		},
+		Retention: RetentionConfig{
+			Enabled:          true,
+			Interval:         6 * time.Hour,
+			BatchSize:        500,
+			Notifications:    90 * 24 * time.Hour,
+			CompletedTasks:   365 * 24 * time.Hour,
+			TrashGracePeriod: 7 * 24 * time.Hour,
+			LoginFailures:    90 * 24 * time.Hour,
+			AuditLog:         365 * 24 * time.Hour,
+			Statistics:       365 * 24 * time.Hour,
+		},
+		Metrics: MetricsConfig{
+			Enabled: true,
 		},
 	}
 }
@@ -211,6 +542,18 @@ func overrideFromEnv(config *Config) {
 	if port := os.Getenv("SERVER_PORT"); port != "" {
 		fmt.Sscanf(port, "%d", &config.Server.Port)
 	}
+	if mode := os.Getenv("SERVER_MODE"); mode != "" {
+		config.Server.Mode = mode
+	}
+	// 多个地址以逗号分隔
+	if proxies := os.Getenv("SERVER_TRUSTED_PROXIES"); proxies != "" {
+		config.Server.TrustedProxies = nil
+		for _, proxy := range strings.Split(proxies, ",") {
+			if proxy = strings.TrimSpace(proxy); proxy != "" {
+				config.Server.TrustedProxies = append(config.Server.TrustedProxies, proxy)
+			}
+		}
+	}
 
 	// Database配置
 	if dbPath := os.Getenv("DATABASE_PATH"); dbPath != "" {
@@ -282,6 +625,14 @@ func overrideFromEnv(config *Config) {
 			config.Admin.TokenDuration = duration
 		}
 	}
+	if refreshTokenDuration := os.Getenv("ADMIN_REFRESH_TOKEN_DURATION"); refreshTokenDuration != "" {
+		if duration, err := time.ParseDuration(refreshTokenDuration); err == nil {
+			config.Admin.RefreshTokenDuration = duration
+		}
+	}
+	if totpIssuer := os.Getenv("ADMIN_TOTP_ISSUER"); totpIssuer != "" {
+		config.Admin.TOTPIssuer = totpIssuer
+	}
 
 	// Notification配置
 	if wxpusherAppToken := os.Getenv("WXPUSHER_APP_TOKEN"); wxpusherAppToken != "" {
@@ -290,11 +641,19 @@ func overrideFromEnv(config *Config) {
 	if wxpusherUID := os.Getenv("WXPUSHER_UID"); wxpusherUID != "" {
 		config.Notification.WxPusher.UID = wxpusherUID
 	}
+
+	// Metrics配置
+	if metricsToken := os.Getenv("METRICS_TOKEN"); metricsToken != "" {
+		config.Metrics.Token = metricsToken
+	}
 }
 
 // Validate 验证配置有效性
 func (c *Config) Validate() error {
 	// 验证Server配置
+	if c.Server.Mode != ServerModeProduction && c.Server.Mode != ServerModeDev {
+		return fmt.Errorf("invalid server mode: %s (must be one of: production, dev)", c.Server.Mode)
+	}
 	if c.Server.Port <= 0 || c.Server.Port > 65535 {
 		return fmt.Errorf("invalid server port: %d (must be between 1 and 65535)", c.Server.Port)
 	}
@@ -304,6 +663,13 @@ func (c *Config) Validate() error {
 	if c.Server.WriteTimeout <= 0 {
 		return fmt.Errorf("invalid server write_timeout: %v (must be positive)", c.Server.WriteTimeout)
 	}
+	for _, proxy := range c.Server.TrustedProxies {
+		if net.ParseIP(proxy) == nil {
+			if _, _, err := net.ParseCIDR(proxy); err != nil {
+				return fmt.Errorf("invalid server trusted_proxies entry: %q (must be an IP or CIDR)", proxy)
+			}
+		}
+	}
 
 	// 验证Database配置
 	if c.Database.Path == "" {
@@ -341,6 +707,9 @@ func (c *Config) Validate() error {
 				return fmt.Errorf("captcha provider at index %d is missing credentials_json", i)
 			}
 		}
+		if provider.UnitPrice < 0 {
+			return fmt.Errorf("invalid captcha unit_price at index %d: %v (must be non-negative)", i, provider.UnitPrice)
+		}
 	}
 
 	// 验证Job配置
@@ -353,6 +722,15 @@ func (c *Config) Validate() error {
 	if c.Job.WorkerPoolSize <= 0 {
 		return fmt.Errorf("invalid job worker_pool_size: %d (must be positive)", c.Job.WorkerPoolSize)
 	}
+	if c.Job.UserRefreshInterval < 0 {
+		return fmt.Errorf("invalid job user_refresh_interval: %v (must be non-negative)", c.Job.UserRefreshInterval)
+	}
+	if c.Job.MaxTaskRetries < 0 {
+		return fmt.Errorf("invalid job max_task_retries: %d (must be non-negative)", c.Job.MaxTaskRetries)
+	}
+	if u, err := url.Parse(c.Job.GameAPIURL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("invalid job game_api_url: %q (must be an http or https URL)", c.Job.GameAPIURL)
+	}
 
 	// 验证Logging配置
 	validLogLevels := map[string]bool{
@@ -375,11 +753,13 @@ func (c *Config) Validate() error {
 
 	// 验证Security配置
 	if c.Security.RateLimit.Enabled {
-		if c.Security.RateLimit.Rate <= 0 {
-			return fmt.Errorf("invalid rate_limit rate: %d (must be positive when enabled)", c.Security.RateLimit.Rate)
+		if err := c.Security.RateLimit.validate(); err != nil {
+			return err
 		}
-		if c.Security.RateLimit.Burst <= 0 {
-			return fmt.Errorf("invalid rate_limit burst: %d (must be positive when enabled)", c.Security.RateLimit.Burst)
+	}
+	if c.Security.LoginProtection.Enabled {
+		if err := c.Security.LoginProtection.validate(); err != nil {
+			return err
 		}
 	}
 
@@ -399,6 +779,195 @@ func (c *Config) Validate() error {
 	if c.Admin.TokenDuration <= 0 {
 		return fmt.Errorf("invalid admin token_duration: %v (must be positive)", c.Admin.TokenDuration)
 	}
+	if c.Admin.RefreshTokenDuration < c.Admin.TokenDuration {
+		return fmt.Errorf("invalid admin refresh_token_duration: %v (must not be shorter than token_duration %v)",
+			c.Admin.RefreshTokenDuration, c.Admin.TokenDuration)
+	}
+	if strings.TrimSpace(c.Admin.TOTPIssuer) == "" {
+		return fmt.Errorf("admin totp_issuer is required")
+	}
+
+	// 验证Notification配置
+	if c.Notification.WxPusher.DigestWindow < 0 {
+		return fmt.Errorf("invalid wxpusher digest_window: %v (must be non-negative)", c.Notification.WxPusher.DigestWindow)
+	}
+	if c.Notification.WxPusher.QuietHours != "" {
+		if _, err := ParseQuietHours(c.Notification.WxPusher.QuietHours); err != nil {
+			return fmt.Errorf("invalid wxpusher config: %w", err)
+		}
+	}
+	for i, channel := range c.Notification.Channels {
+		if err := channel.validate(); err != nil {
+			return fmt.Errorf("invalid notification channel at index %d: %w", i, err)
+		}
+	}
+	if err := c.Notification.Outbox.validate(); err != nil {
+		return err
+	}
+	for i, route := range c.Notification.Routes {
+		if len(route.Channels) == 0 {
+			return fmt.Errorf("invalid notification route at index %d: channels is required", i)
+		}
+	}
+
+	// 验证Retention配置
+	if c.Retention.Enabled {
+		if c.Retention.Interval <= 0 {
+			return fmt.Errorf("invalid retention interval: %v (must be positive when enabled)", c.Retention.Interval)
+		}
+		if c.Retention.BatchSize <= 0 {
+			return fmt.Errorf("invalid retention batch_size: %d (must be positive when enabled)", c.Retention.BatchSize)
+		}
+	}
+	if c.Retention.Notifications < 0 {
+		return fmt.Errorf("invalid retention notifications: %v (must be non-negative)", c.Retention.Notifications)
+	}
+	if c.Retention.CompletedTasks < 0 {
+		return fmt.Errorf("invalid retention completed_tasks: %v (must be non-negative)", c.Retention.CompletedTasks)
+	}
+	if c.Retention.TrashGracePeriod < 0 {
+		return fmt.Errorf("invalid retention trash_grace_period: %v (must be non-negative)", c.Retention.TrashGracePeriod)
+	}
+	if c.Retention.LoginFailures < 0 {
+		return fmt.Errorf("invalid retention login_failures: %v (must be non-negative)", c.Retention.LoginFailures)
+	}
+	if c.Retention.AuditLog < 0 {
+		return fmt.Errorf("invalid retention audit_log: %v (must be non-negative)", c.Retention.AuditLog)
+	}
+	if c.Retention.Statistics < 0 {
+		return fmt.Errorf("invalid retention statistics: %v (must be non-negative)", c.Retention.Statistics)
+	}
+
+	return nil
+}
+
+// validate 校验限流配置
+func (r RateLimitConfig) validate() error {
+	if r.Rate <= 0 {
+		return fmt.Errorf("invalid rate_limit rate: %v (must be positive when enabled)", r.Rate)
+	}
+	if r.Burst <= 0 {
+		return fmt.Errorf("invalid rate_limit burst: %d (must be positive when enabled)", r.Burst)
+	}
+	if r.MaxKeys <= 0 {
+		return fmt.Errorf("invalid rate_limit max_keys: %d (must be positive when enabled)", r.MaxKeys)
+	}
+	if r.IdleTimeout <= 0 {
+		return fmt.Errorf("invalid rate_limit idle_timeout: %v (must be positive when enabled)", r.IdleTimeout)
+	}
+	for group, rule := range r.Groups {
+		if !slices.Contains(RateLimitGroups, group) {
+			return fmt.Errorf("invalid rate_limit group: %s (must be one of: %s)", group, strings.Join(RateLimitGroups, ", "))
+		}
+		if rule.Rate <= 0 {
+			return fmt.Errorf("invalid rate_limit group %s rate: %v (must be positive)", group, rule.Rate)
+		}
+		if rule.Burst <= 0 {
+			return fmt.Errorf("invalid rate_limit group %s burst: %d (must be positive)", group, rule.Burst)
+		}
+	}
+	return nil
+}
+
+// validate 校验登录防爆破配置
+func (l LoginProtectionConfig) validate() error {
+	if l.MaxFailuresPerUser <= 0 {
+		return fmt.Errorf("invalid login_protection max_failures_per_user: %d (must be positive)", l.MaxFailuresPerUser)
+	}
+	if l.MaxFailuresPerIP <= 0 {
+		return fmt.Errorf("invalid login_protection max_failures_per_ip: %d (must be positive)", l.MaxFailuresPerIP)
+	}
+	if l.Window <= 0 {
+		return fmt.Errorf("invalid login_protection window: %v (must be positive)", l.Window)
+	}
+	if l.BaseDelay < 0 {
+		return fmt.Errorf("invalid login_protection base_delay: %v (must be non-negative)", l.BaseDelay)
+	}
+	if l.MaxDelay < l.BaseDelay {
+		return fmt.Errorf("invalid login_protection max_delay: %v (must not be less than base_delay)", l.MaxDelay)
+	}
+	if l.LockoutDuration <= 0 {
+		return fmt.Errorf("invalid login_protection lockout_duration: %v (must be positive)", l.LockoutDuration)
+	}
+	if l.MaxLockoutDuration < l.LockoutDuration {
+		return fmt.Errorf("invalid login_protection max_lockout_duration: %v (must not be less than lockout_duration)", l.MaxLockoutDuration)
+	}
+	return nil
+}
 
+// validate 校验通知投递配置
+func (o OutboxConfig) validate() error {
+	if o.MaxAttempts <= 0 {
+		return fmt.Errorf("invalid notification outbox max_attempts: %d (must be positive)", o.MaxAttempts)
+	}
+	if o.InitialBackoff <= 0 {
+		return fmt.Errorf("invalid notification outbox initial_backoff: %v (must be positive)", o.InitialBackoff)
+	}
+	if o.MaxBackoff < o.InitialBackoff {
+		return fmt.Errorf("invalid notification outbox max_backoff: %v (must not be less than initial_backoff)", o.MaxBackoff)
+	}
+	if o.PollInterval <= 0 {
+		return fmt.Errorf("invalid notification outbox poll_interval: %v (must be positive)", o.PollInterval)
+	}
+	return nil
+}
+
+// validate 校验通知渠道所需的字段
+func (ch NotificationChannel) validate() error {
+	switch ch.Type {
+	case ChannelTypeWxPusher:
+		if ch.Token == "" {
+			return fmt.Errorf("wxpusher channel is missing token")
+		}
+		if len(ch.Recipients) == 0 {
+			return fmt.Errorf("wxpusher channel is missing recipients")
+		}
+	case ChannelTypeWebhook, ChannelTypeDingTalk, ChannelTypeFeishu:
+		if ch.URL == "" {
+			return fmt.Errorf("%s channel is missing url", ch.Type)
+		}
+	case ChannelTypeEmail:
+		if ch.SMTP.Host == "" || ch.SMTP.Port <= 0 {
+			return fmt.Errorf("email channel is missing smtp host or port")
+		}
+		if ch.SMTP.From == "" {
+			return fmt.Errorf("email channel is missing smtp from")
+		}
+		if len(ch.Recipients) == 0 {
+			return fmt.Errorf("email channel is missing recipients")
+		}
+	case ChannelTypeTelegram:
+		if ch.Token == "" {
+			return fmt.Errorf("telegram channel is missing token")
+		}
+		if len(ch.Recipients) == 0 {
+			return fmt.Errorf("telegram channel is missing recipients")
+		}
+	case ChannelTypeServerChan, ChannelTypeBark:
+		if ch.Token == "" {
+			return fmt.Errorf("%s channel is missing token", ch.Type)
+		}
+	default:
+		return fmt.Errorf("unknown channel type: %s", ch.Type)
+	}
+
+	if ch.DigestWindow < 0 {
+		return fmt.Errorf("invalid digest_window: %v (must be non-negative)", ch.DigestWindow)
+	}
+	if ch.QuietHours != "" {
+		if _, err := ParseQuietHours(ch.QuietHours); err != nil {
+			return err
+		}
+	}
+
+	if ch.Format != "" {
+		formats, ok := channelFormats[ch.Type]
+		if !ok {
+			return fmt.Errorf("%s channel does not support custom format", ch.Type)
+		}
+		if !slices.Contains(formats, ch.Format) {
+			return fmt.Errorf("%s channel does not support format: %s", ch.Type, ch.Format)
+		}
+	}
 	return nil
 }
@@ -4,6 +4,8 @@ import (
 	"os"
 	"testing"
 	"time"
+
+	"gopkg.in/yaml.v2"
 )
 
 func TestDefaultConfig(t *testing.T) {
@@ -45,6 +47,110 @@ func TestConfigValidation(t *testing.T) {
 			config:    defaultConfig(),
 			wantError: false,
 		},
+		{
+			name: "trusted proxies with ip and cidr",
+			config: func() *Config {
+				cfg := defaultConfig()
+				cfg.Server.TrustedProxies = []string{"127.0.0.1", "10.0.0.0/8", "::1"}
+				return cfg
+			}(),
+			wantError: false,
+		},
+		{
+			name: "trusted proxy not an ip",
+			config: func() *Config {
+				cfg := defaultConfig()
+				cfg.Server.TrustedProxies = []string{"nginx"}
+				return cfg
+			}(),
+			wantError: true,
+		},
+		{
+			name: "notification outbox max backoff below initial backoff",
+			config: func() *Config {
+				cfg := defaultConfig()
+				cfg.Notification.Outbox.MaxBackoff = time.Second
+				return cfg
+			}(),
+			wantError: true,
+		},
+		{
+			name: "rate limit unknown group",
+			config: func() *Config {
+				cfg := defaultConfig()
+				cfg.Security.RateLimit.Groups["websocket"] = RateLimitRule{Rate: 1, Burst: 1}
+				return cfg
+			}(),
+			wantError: true,
+		},
+		{
+			name: "rate limit group without burst",
+			config: func() *Config {
+				cfg := defaultConfig()
+				cfg.Security.RateLimit.Groups[RateLimitGroupPublic] = RateLimitRule{Rate: 0.5}
+				return cfg
+			}(),
+			wantError: true,
+		},
+		{
+			name: "login protection max lockout below lockout duration",
+			config: func() *Config {
+				cfg := defaultConfig()
+				cfg.Security.LoginProtection.MaxLockoutDuration = time.Minute
+				return cfg
+			}(),
+			wantError: true,
+		},
+		{
+			name: "login protection disabled skips validation",
+			config: func() *Config {
+				cfg := defaultConfig()
+				cfg.Security.LoginProtection = LoginProtectionConfig{Enabled: false}
+				return cfg
+			}(),
+			wantError: false,
+		},
+		{
+			name: "notification channel with invalid quiet hours",
+			config: func() *Config {
+				cfg := defaultConfig()
+				cfg.Notification.Channels = []NotificationChannel{{
+					Type:       ChannelTypeTelegram,
+					Token:      "123456:bot-token",
+					Recipients: []string{"1"},
+					QuietHours: "23:00",
+				}}
+				return cfg
+			}(),
+			wantError: true,
+		},
+		{
+			name: "game api url without scheme",
+			config: func() *Config {
+				cfg := defaultConfig()
+				cfg.Job.GameAPIURL = "wjdr-giftcode-api.campfiregames.cn/api/"
+				return cfg
+			}(),
+			wantError: true,
+		},
+		{
+			name: "invalid server mode",
+			config: func() *Config {
+				cfg := defaultConfig()
+				cfg.Server.Mode = "staging"
+				return cfg
+			}(),
+			wantError: true,
+		},
+		{
+			name: "refresh token shorter than access token",
+			config: func() *Config {
+				cfg := defaultConfig()
+				cfg.Admin.RefreshTokenDuration = cfg.Admin.TokenDuration / 2
+				return cfg
+			}(),
+			wantError: true,
+		},
 		{
 			name: "invalid port - too low",
 			config: &Config{
@@ -89,6 +195,71 @@ func TestConfigValidation(t *testing.T) {
 			},
 			wantError: true,
 		},
+		{
+			name: "negative user refresh interval",
+			config: &Config{
+				Server:   ServerConfig{Port: 8080, ReadTimeout: 1 * time.Second, WriteTimeout: 1 * time.Second},
+				Database: DatabaseConfig{Path: "./test.db", MaxOpenConns: 10},
+				Job:      JobConfig{PeriodTime: 1 * time.Second, WorkerPoolSize: 1, UserRefreshInterval: -time.Hour},
+				Logging:  LoggingConfig{Level: "info", Format: "json"},
+				Security: SecurityConfig{RateLimit: RateLimitConfig{Enabled: false}},
+			},
+			wantError: true,
+		},
+		{
+			name: "notification channel missing url",
+			config: &Config{
+				Server:   ServerConfig{Port: 8080, ReadTimeout: 1 * time.Second, WriteTimeout: 1 * time.Second},
+				Database: DatabaseConfig{Path: "./test.db", MaxOpenConns: 10},
+				Job:      JobConfig{PeriodTime: 1 * time.Second, WorkerPoolSize: 1},
+				Logging:  LoggingConfig{Level: "info", Format: "json"},
+				Security: SecurityConfig{RateLimit: RateLimitConfig{Enabled: false}},
+				Notification: NotificationConfig{
+					Channels: []NotificationChannel{{Type: ChannelTypeWebhook}},
+				},
+			},
+			wantError: true,
+		},
+		{
+			name: "notification channel with unsupported format",
+			config: &Config{
+				Server:   ServerConfig{Port: 8080, ReadTimeout: 1 * time.Second, WriteTimeout: 1 * time.Second},
+				Database: DatabaseConfig{Path: "./test.db", MaxOpenConns: 10},
+				Job:      JobConfig{PeriodTime: 1 * time.Second, WorkerPoolSize: 1},
+				Logging:  LoggingConfig{Level: "info", Format: "json"},
+				Security: SecurityConfig{RateLimit: RateLimitConfig{Enabled: false}},
+				Notification: NotificationConfig{
+					Channels: []NotificationChannel{{Type: ChannelTypeEmail, Format: ContentFormatMarkdown,
+						Recipients: []string{"a@example.com"}, SMTP: SMTPConfig{Host: "smtp", Port: 25, From: "b@example.com"}}},
+				},
+			},
+			wantError: true,
+		},
+		{
+			name: "notification route without channels",
+			config: &Config{
+				Server:   ServerConfig{Port: 8080, ReadTimeout: 1 * time.Second, WriteTimeout: 1 * time.Second},
+				Database: DatabaseConfig{Path: "./test.db", MaxOpenConns: 10},
+				Job:      JobConfig{PeriodTime: 1 * time.Second, WorkerPoolSize: 1},
+				Logging:  LoggingConfig{Level: "info", Format: "json"},
+				Security: SecurityConfig{RateLimit: RateLimitConfig{Enabled: false}},
+				Notification: NotificationConfig{
+					Routes: []NotificationRoute{{Events: []string{"task_failed"}}},
+				},
+			},
+			wantError: true,
+		},
+		{
+			name: "negative max task retries",
+			config: &Config{
+				Server:   ServerConfig{Port: 8080, ReadTimeout: 1 * time.Second, WriteTimeout: 1 * time.Second},
+				Database: DatabaseConfig{Path: "./test.db", MaxOpenConns: 10},
+				Job:      JobConfig{PeriodTime: 1 * time.Second, WorkerPoolSize: 1, MaxTaskRetries: -1},
+				Logging:  LoggingConfig{Level: "info", Format: "json"},
+				Security: SecurityConfig{RateLimit: RateLimitConfig{Enabled: false}},
+			},
+			wantError: true,
+		},
 		{
 			name: "invalid captcha provider type",
 			config: &Config{
@@ -105,6 +276,34 @@ func TestConfigValidation(t *testing.T) {
 			},
 			wantError: true,
 		},
+		{
+			name: "negative captcha unit price",
+			config: &Config{
+				Server:   ServerConfig{Port: 8080, ReadTimeout: 1 * time.Second, WriteTimeout: 1 * time.Second},
+				Database: DatabaseConfig{Path: "./test.db", MaxOpenConns: 10},
+				Captcha: CaptchaConfig{
+					Providers: []CaptchaProvider{
+						{Type: "ali", AccessKey: "key", SecretKey: "secret", UnitPrice: -0.01},
+					},
+				},
+				Job:      JobConfig{PeriodTime: 1 * time.Second, WorkerPoolSize: 1},
+				Logging:  LoggingConfig{Level: "info", Format: "json"},
+				Security: SecurityConfig{RateLimit: RateLimitConfig{Enabled: false}},
+			},
+			wantError: true,
+		},
+		{
+			name: "negative statistics retention",
+			config: &Config{
+				Server:    ServerConfig{Port: 8080, ReadTimeout: 1 * time.Second, WriteTimeout: 1 * time.Second},
+				Database:  DatabaseConfig{Path: "./test.db", MaxOpenConns: 10},
+				Job:       JobConfig{PeriodTime: 1 * time.Second, WorkerPoolSize: 1},
+				Logging:   LoggingConfig{Level: "info", Format: "json"},
+				Security:  SecurityConfig{RateLimit: RateLimitConfig{Enabled: false}},
+				Retention: RetentionConfig{Statistics: -time.Hour},
+			},
+			wantError: true,
+		},
 	}
 
 	for _, tt := range tests {
@@ -117,15 +316,101 @@ func TestConfigValidation(t *testing.T) {
 	}
 }
 
+func TestQuietHours(t *testing.T) {
+	day := func(hour, minute int) time.Time {
+		return time.Date(2026, 3, 10, hour, minute, 0, 0, time.Local)
+	}
+
+	tests := []struct {
+		quiet string
+		at    time.Time
+		want  time.Time
+	}{
+		{"23:00-07:00", day(23, 30), day(7, 0).AddDate(0, 0, 1)},
+		{"23:00-07:00", day(6, 59), day(7, 0)},
+		{"23:00-07:00", day(7, 0), day(7, 0)},
+		{"23:00-07:00", day(12, 0), day(12, 0)},
+		{"12:00-13:30", day(12, 15), day(13, 30)},
+		{"12:00-13:30", day(13, 30), day(13, 30)},
+	}
+	for _, tt := range tests {
+		q, err := ParseQuietHours(tt.quiet)
+		if err != nil {
+			t.Fatalf("ParseQuietHours(%q) failed: %v", tt.quiet, err)
+		}
+		if got := q.Until(tt.at); !got.Equal(tt.want) {
+			t.Errorf("%s Until(%v) = %v, want %v", tt.quiet, tt.at, got, tt.want)
+		}
+	}
+
+	for _, invalid := range []string{"", "23:00", "25:00-07:00", "07:00-07:00"} {
+		if _, err := ParseQuietHours(invalid); err == nil {
+			t.Errorf("ParseQuietHours(%q) expected error", invalid)
+		}
+	}
+}
+
+func TestScanSecrets(t *testing.T) {
+	// 默认配置使用内置的默认密钥和示例密码哈希
+	cfg := defaultConfig()
+	findings := cfg.ScanSecrets()
+	if len(findings) != 2 || findings[0].Field != "admin.token_secret" || findings[1].Field != "admin.password_hash" {
+		t.Fatalf("expected default secrets to be reported, got %v", findings)
+	}
+
+	cfg.Admin.TokenSecret = "k3Jd9sLq0vXz7RtYb2NcPw4MhGf6EaUo"
+	cfg.Admin.PasswordHash = "$2a$10$abcdefghijklmnopqrstuuGJ6P3KkRk6S4w1F3dOT0bR7cR5z9w6a"
+	cfg.Notification.WxPusher = WxPusherConfig{AppToken: "${WXPUSHER_APP_TOKEN}", UID: "UID_x"}
+	cfg.Notification.Channels = []NotificationChannel{
+		{Type: ChannelTypeWebhook, URL: "https://example.com/hook", Secret: "hmac-secret"},
+		{Type: ChannelTypeDingTalk, URL: "https://example.com/robot", Secret: "SECxxx", Disabled: true},
+	}
+	findings = cfg.ScanSecrets()
+	if len(findings) != 2 {
+		t.Fatalf("expected 2 findings, got %v", findings)
+	}
+	if findings[0].Field != "notification.wxpusher.app_token" || findings[1].Field != "notification.channels[0].secret" {
+		t.Errorf("unexpected findings: %v", findings)
+	}
+
+	cfg.Notification = NotificationConfig{}
+	if findings := cfg.ScanSecrets(); len(findings) != 0 {
+		t.Errorf("expected no findings, got %v", findings)
+	}
+
+	// 验证码提供商的密钥仍为示例配置中的占位符
+	cfg.Captcha.Providers = []CaptchaProvider{
+		{Type: "ali", AccessKey: "${ACCESS_KEY}", SecretKey: "${ACCESS_SECRET}"},
+		{Type: "google", CredentialsJSON: `{"type":"service_account"}`},
+	}
+	findings = cfg.ScanSecrets()
+	if len(findings) != 2 || findings[0].Field != "captcha.providers[0].access_key" || findings[1].Field != "captcha.providers[0].secret_key" {
+		t.Errorf("expected captcha placeholders to be reported, got %v", findings)
+	}
+	cfg.Captcha.Providers = nil
+
+	// 指标接口令牌仅在启用时检查
+	cfg.Metrics.Token = "${METRICS_TOKEN}"
+	if findings := cfg.ScanSecrets(); len(findings) != 1 || findings[0].Field != "metrics.token" {
+		t.Errorf("expected metrics token to be reported, got %v", findings)
+	}
+	cfg.Metrics.Enabled = false
+	if findings := cfg.ScanSecrets(); len(findings) != 0 {
+		t.Errorf("expected no findings with metrics disabled, got %v", findings)
+	}
+}
+
 func TestEnvOverride(t *testing.T) {
 	// 设置环境变量
 	os.Setenv("SERVER_PORT", "8080")
 	os.Setenv("DATABASE_PATH", "/tmp/test.db")
 	os.Setenv("LOG_LEVEL", "debug")
+	os.Setenv("SERVER_TRUSTED_PROXIES", "127.0.0.1, 10.0.0.0/8")
 	defer func() {
 		os.Unsetenv("SERVER_PORT")
 		os.Unsetenv("DATABASE_PATH")
 		os.Unsetenv("LOG_LEVEL")
+		os.Unsetenv("SERVER_TRUSTED_PROXIES")
 	}()
 
 	config, err := LoadConfig("")
@@ -144,6 +429,10 @@ func TestEnvOverride(t *testing.T) {
 	if config.Logging.Level != "debug" {
 		t.Errorf("expected log level 'debug' from env, got %s", config.Logging.Level)
 	}
+
+	if len(config.Server.TrustedProxies) != 2 || config.Server.TrustedProxies[1] != "10.0.0.0/8" {
+		t.Errorf("expected trusted proxies from env, got %v", config.Server.TrustedProxies)
+	}
 }
 
 func TestCaptchaProviderFromEnv(t *testing.T) {
@@ -179,3 +468,26 @@ func TestCaptchaProviderFromEnv(t *testing.T) {
 		t.Error("expected ali provider to be added from environment variables")
 	}
 }
+
+func TestRateLimitRule(t *testing.T) {
+	cfg := defaultConfig()
+
+	// 配置文件中的分组覆盖与默认分组合并
+	if err := yaml.Unmarshal([]byte("security:\n  rate_limit:\n    groups:\n      public: {rate: 0.5, burst: 5}\n"), cfg); err != nil {
+		t.Fatalf("failed to parse yaml: %v", err)
+	}
+
+	tests := []struct {
+		group string
+		want  RateLimitRule
+	}{
+		{RateLimitGroupPublic, RateLimitRule{Rate: 0.5, Burst: 5}},
+		{RateLimitGroupAdmin, RateLimitRule{Rate: 20, Burst: 60}},
+		{RateLimitGroupStatic, RateLimitRule{Rate: 10, Burst: 20}},
+	}
+	for _, tt := range tests {
+		if got := cfg.Security.RateLimit.Rule(tt.group); got != tt.want {
+			t.Errorf("Rule(%s) = %+v, want %+v", tt.group, got, tt.want)
+		}
+	}
+}
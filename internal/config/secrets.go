@@ -0,0 +1,87 @@
+package config
+
+import (
+	"fmt"
+	"strings"
+)
+
+// SecretFinding 密钥扫描发现的问题
+type SecretFinding struct {
+	Field  string // 配置项，如 admin.token_secret
+	Reason string
+}
+
+// String 返回便于记录日志的描述
+func (f SecretFinding) String() string {
+	return fmt.Sprintf("%s: %s", f.Field, f.Reason)
+}
+
+// knownSecrets 公开的示例或默认密钥，值为来源说明
+var knownSecrets = map[string]string{
+	"your-secret-key-change-in-production-min-32-chars":            "sample value from etc/config.example.yaml",
+	"default-secret-key-change-in-production-min-32-characters":    "built-in default",
+	"$2a$10$N9qo8uLOickgx2ZMRZoMyeIjZAgcfl7p92ldGxad68LJZdL17lhWy": "sample hash of the password \"admin123\"",
+	"SECxxx":           "sample value from etc/config.example.yaml",
+	"hmac-secret":      "sample value from etc/config.example.yaml",
+	"123456:bot-token": "sample value from etc/config.example.yaml",
+	"SCTxxx":           "sample value from etc/config.example.yaml",
+	"device-key":       "sample value from etc/config.example.yaml",
+	"password":         "sample value from etc/config.example.yaml",
+}
+
+// ScanSecrets 检查配置中是否仍在使用示例、默认或占位的密钥
+// 只检查实际生效的配置，停用的通知渠道会被跳过
+func (c *Config) ScanSecrets() []SecretFinding {
+	var findings []SecretFinding
+	check := func(field, value string) {
+		if reason := placeholderReason(value); reason != "" {
+			findings = append(findings, SecretFinding{Field: field, Reason: reason})
+		}
+	}
+
+	check("admin.token_secret", c.Admin.TokenSecret)
+	check("admin.password_hash", c.Admin.PasswordHash)
+	if c.Metrics.Enabled {
+		check("metrics.token", c.Metrics.Token)
+	}
+
+	for i, provider := range c.Captcha.Providers {
+		field := fmt.Sprintf("captcha.providers[%d]", i)
+		check(field+".access_key", provider.AccessKey)
+		check(field+".secret_key", provider.SecretKey)
+		check(field+".credentials_json", provider.CredentialsJSON)
+	}
+
+	if c.Notification.WxPusher.AppToken != "" && c.Notification.WxPusher.UID != "" {
+		check("notification.wxpusher.app_token", c.Notification.WxPusher.AppToken)
+	}
+	for i, channel := range c.Notification.Channels {
+		if channel.Disabled {
+			continue
+		}
+		field := fmt.Sprintf("notification.channels[%d]", i)
+		check(field+".token", channel.Token)
+		check(field+".secret", channel.Secret)
+		check(field+".smtp.password", channel.SMTP.Password)
+	}
+
+	return findings
+}
+
+// placeholderReason 返回值为示例或占位密钥的原因，不是时返回空字符串
+func placeholderReason(value string) string {
+	if value == "" {
+		return ""
+	}
+	if source, ok := knownSecrets[value]; ok {
+		return "uses a published " + source
+	}
+	// 配置文件不会展开环境变量，未设置对应环境变量时会保留占位符原文
+	if strings.HasPrefix(value, "${") && strings.HasSuffix(value, "}") {
+		return fmt.Sprintf("environment placeholder %s was not substituted", value)
+	}
+	if strings.Contains(strings.ToLower(value), "change-in-production") {
+		return "looks like a placeholder that must be changed in production"
+	}
+	return ""
+}
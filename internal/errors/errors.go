@@ -2,6 +2,7 @@ package errors
 
 import (
 	"fmt"
+	"net/http"
 )
 
 // 错误代码常量
@@ -16,8 +17,32 @@ const (
 	ErrCodeTimeout       = "TIMEOUT_ERROR"
 	ErrCodeUnauthorized  = "UNAUTHORIZED"
 	ErrCodeRateLimit     = "RATE_LIMIT_EXCEEDED"
+	ErrCodeUnavailable   = "SERVICE_UNAVAILABLE"
 )
 
+// httpStatuses 错误代码对应的HTTP状态码
+var httpStatuses = map[string]int{
+	ErrCodeValidation:    http.StatusBadRequest,
+	ErrCodeUnauthorized:  http.StatusUnauthorized,
+	ErrCodeNotFound:      http.StatusNotFound,
+	ErrCodeAlreadyExists: http.StatusConflict,
+	ErrCodeRateLimit:     http.StatusTooManyRequests,
+	ErrCodeCaptcha:       http.StatusBadGateway,
+	ErrCodeExternal:      http.StatusBadGateway,
+	ErrCodeUnavailable:   http.StatusServiceUnavailable,
+	ErrCodeTimeout:       http.StatusGatewayTimeout,
+	ErrCodeDatabase:      http.StatusInternalServerError,
+	ErrCodeInternal:      http.StatusInternalServerError,
+}
+
+// HTTPStatus 返回错误代码对应的HTTP状态码，未知代码返回 500
+func HTTPStatus(code string) int {
+	if status, ok := httpStatuses[code]; ok {
+		return status
+	}
+	return http.StatusInternalServerError
+}
+
 // AppError 应用错误类型
 type AppError struct {
 	Code    string                 // 错误代码
@@ -125,6 +150,12 @@ func NewRateLimitError() *AppError {
 	return New(ErrCodeRateLimit, "rate limit exceeded")
 }
 
+// NewUnavailableError 创建服务不可用错误
+func NewUnavailableError(service string) *AppError {
+	return New(ErrCodeUnavailable, fmt.Sprintf("service unavailable: %s", service)).
+		WithContext("service", service)
+}
+
 // NewInternalError 创建内部错误
 func NewInternalError(message string, err error) *AppError {
 	if err != nil {
@@ -194,6 +194,11 @@ func TestAllErrorConstructors(t *testing.T) {
 			createFn: func() *AppError { return NewRateLimitError() },
 			wantCode: ErrCodeRateLimit,
 		},
+		{
+			name:     "NewUnavailableError",
+			createFn: func() *AppError { return NewUnavailableError("service") },
+			wantCode: ErrCodeUnavailable,
+		},
 		{
 			name:     "NewInternalError",
 			createFn: func() *AppError { return NewInternalError("message", nil) },
@@ -213,3 +218,29 @@ func TestAllErrorConstructors(t *testing.T) {
 		})
 	}
 }
+
+func TestHTTPStatus(t *testing.T) {
+	tests := []struct {
+		code string
+		want int
+	}{
+		{ErrCodeValidation, 400},
+		{ErrCodeUnauthorized, 401},
+		{ErrCodeNotFound, 404},
+		{ErrCodeAlreadyExists, 409},
+		{ErrCodeRateLimit, 429},
+		{ErrCodeExternal, 502},
+		{ErrCodeUnavailable, 503},
+		{ErrCodeTimeout, 504},
+		{ErrCodeDatabase, 500},
+		{"UNKNOWN_CODE", 500},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.code, func(t *testing.T) {
+			if got := HTTPStatus(tt.code); got != tt.want {
+				t.Errorf("HTTPStatus(%q) = %v, want %v", tt.code, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,201 @@
+package events
+
+import (
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// 事件类型，按 "对象.动作" 命名，订阅时可按前缀过滤
+const (
+	// TaskChanged 任务被创建、更新重试信息、完成、删除、恢复或彻底删除，数据为 TaskChange
+	TaskChanged = "task.changed"
+	// TaskProgress 定时任务为一个用户兑换完成，数据为 TaskProgressData
+	TaskProgress = "task.progress"
+	// UserChanged 用户被添加、修改、启用/禁用、删除、恢复或彻底删除，数据为 UserChange
+	UserChanged = "user.changed"
+	// NotificationQueued 通知写入通知历史等待投递，数据为 NotificationData
+	NotificationQueued = "notification.queued"
+	// NotificationDelivered 一次通知投递结束（成功、等待重试或失败），数据为 NotificationData
+	NotificationDelivered = "notification.delivered"
+	// JobStarted 后台任务开始执行，数据为 JobRun
+	JobStarted = "job.started"
+	// JobFinished 后台任务执行结束，数据为 JobRun
+	JobFinished = "job.finished"
+)
+
+// 任务和用户的变更类型
+const (
+	ChangeCreated   = "created"
+	ChangeUpdated   = "updated"
+	ChangeRetried   = "retried"
+	ChangeCompleted = "completed"
+	ChangeDisabled  = "disabled"
+	ChangeEnabled   = "enabled"
+	ChangeDeleted   = "deleted"
+	ChangeRestored  = "restored"
+	ChangePurged    = "purged"
+)
+
+// Event 事件总线上传递的事件
+type Event struct {
+	ID   uint64    `json:"id"`
+	Type string    `json:"type"`
+	Time time.Time `json:"time"`
+	Data any       `json:"data,omitempty"`
+}
+
+// TaskChange 任务变更
+type TaskChange struct {
+	Code   string `json:"code"`
+	Change string `json:"change"`
+	// RetryCount、LastError 仅在 Change 为 retried 时有值
+	RetryCount int    `json:"retry_count,omitempty"`
+	LastError  string `json:"last_error,omitempty"`
+}
+
+// TaskProgressData 单个用户的兑换结果
+type TaskProgressData struct {
+	Code     string `json:"code"`
+	FID      string `json:"fid"`
+	Nickname string `json:"nickname,omitempty"`
+	Done     bool   `json:"done"` // 该用户已兑换完成（成功或已兑换），不再重试
+	Result   string `json:"result"`
+	Index    int    `json:"index"` // 本次执行中已处理的用户数
+	Total    int    `json:"total"`
+}
+
+// UserChange 用户变更
+type UserChange struct {
+	FID    string `json:"fid"`
+	Change string `json:"change"`
+}
+
+// NotificationData 通知记录的状态
+type NotificationData struct {
+	ID       int64  `json:"id"`
+	Channel  string `json:"channel"`
+	Event    string `json:"event,omitempty"`
+	FID      string `json:"fid,omitempty"`
+	Title    string `json:"title"`
+	Status   string `json:"status"`
+	Attempts int    `json:"attempts"`
+	Result   string `json:"result,omitempty"`
+}
+
+// JobRun 后台任务的一次执行
+type JobRun struct {
+	Job string `json:"job"`
+	// DurationMS 执行耗时（毫秒），仅 job.finished 有值
+	DurationMS int64 `json:"duration_ms,omitempty"`
+}
+
+// subscriberBuffer 每个订阅者缓冲的事件数，缓冲满时丢弃新事件
+const subscriberBuffer = 64
+
+// Bus 进程内事件总线
+// Publish 不会阻塞：订阅者处理不及时、缓冲已满时丢弃该订阅者的事件并计数
+// nil 的 *Bus 可以安全使用，Publish 不做任何事
+type Bus struct {
+	mu          sync.RWMutex
+	subscribers map[*subscription]struct{}
+	closed      bool
+	nextID      atomic.Uint64
+}
+
+// subscription 一个订阅者
+type subscription struct {
+	ch       chan Event
+	prefixes []string
+	dropped  atomic.Uint64
+}
+
+// NewBus 创建事件总线
+func NewBus() *Bus {
+	return &Bus{subscribers: make(map[*subscription]struct{})}
+}
+
+// Publish 发布事件
+func (b *Bus) Publish(eventType string, data any) {
+	if b == nil {
+		return
+	}
+
+	event := Event{ID: b.nextID.Add(1), Type: eventType, Time: time.Now(), Data: data}
+
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	for sub := range b.subscribers {
+		if !sub.matches(eventType) {
+			continue
+		}
+		select {
+		case sub.ch <- event:
+		default:
+			sub.dropped.Add(1)
+		}
+	}
+}
+
+// Subscribe 订阅事件，prefixes 为空时订阅全部事件，否则只接收类型以其中之一开头的事件
+// 返回的 cancel 取消订阅并关闭 channel，dropped 返回因缓冲已满而丢弃的事件数
+// 总线关闭后 channel 被关闭，关闭后订阅得到已关闭的 channel
+func (b *Bus) Subscribe(prefixes ...string) (events <-chan Event, dropped func() uint64, cancel func()) {
+	sub := &subscription{ch: make(chan Event, subscriberBuffer), prefixes: prefixes}
+
+	b.mu.Lock()
+	if b.closed {
+		close(sub.ch)
+	} else {
+		b.subscribers[sub] = struct{}{}
+	}
+	b.mu.Unlock()
+
+	cancel = func() {
+		b.mu.Lock()
+		defer b.mu.Unlock()
+		if _, ok := b.subscribers[sub]; ok {
+			delete(b.subscribers, sub)
+			close(sub.ch)
+		}
+	}
+	return sub.ch, sub.dropped.Load, cancel
+}
+
+// Close 关闭总线，结束所有订阅，用于服务关闭时断开事件流
+func (b *Bus) Close() {
+	if b == nil {
+		return
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.closed = true
+	for sub := range b.subscribers {
+		delete(b.subscribers, sub)
+		close(sub.ch)
+	}
+}
+
+// Subscribers 返回当前的订阅者数量
+func (b *Bus) Subscribers() int {
+	if b == nil {
+		return 0
+	}
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	return len(b.subscribers)
+}
+
+// matches 事件类型是否符合订阅的前缀
+func (s *subscription) matches(eventType string) bool {
+	if len(s.prefixes) == 0 {
+		return true
+	}
+	for _, prefix := range s.prefixes {
+		if strings.HasPrefix(eventType, prefix) {
+			return true
+		}
+	}
+	return false
+}
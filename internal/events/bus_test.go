@@ -0,0 +1,83 @@
+package events
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBusPublishSubscribe(t *testing.T) {
+	bus := NewBus()
+
+	all, _, cancelAll := bus.Subscribe()
+	defer cancelAll()
+	tasks, _, cancelTasks := bus.Subscribe("task.", "job.finished")
+	defer cancelTasks()
+	assert.Equal(t, 2, bus.Subscribers())
+
+	bus.Publish(TaskChanged, TaskChange{Code: "VIP", Change: ChangeCreated})
+	bus.Publish(UserChanged, UserChange{FID: "1", Change: ChangeDeleted})
+	bus.Publish(JobStarted, JobRun{Job: "GetCodeJob"})
+	bus.Publish(JobFinished, JobRun{Job: "GetCodeJob", DurationMS: 12})
+
+	var types []string
+	for range 4 {
+		event := <-all
+		types = append(types, event.Type)
+	}
+	assert.Equal(t, []string{TaskChanged, UserChanged, JobStarted, JobFinished}, types)
+
+	first := <-tasks
+	assert.Equal(t, TaskChanged, first.Type)
+	assert.Equal(t, TaskChange{Code: "VIP", Change: ChangeCreated}, first.Data)
+	assert.Equal(t, uint64(1), first.ID)
+	second := <-tasks
+	assert.Equal(t, JobFinished, second.Type)
+	assert.Equal(t, uint64(4), second.ID)
+	assert.Empty(t, tasks)
+}
+
+func TestBusDropsWhenSubscriberIsSlow(t *testing.T) {
+	bus := NewBus()
+	ch, dropped, cancel := bus.Subscribe()
+
+	for range subscriberBuffer + 3 {
+		bus.Publish(TaskProgress, nil)
+	}
+	assert.Len(t, ch, subscriberBuffer)
+	assert.Equal(t, uint64(3), dropped())
+
+	// 取消后 channel 关闭，重复取消不会 panic
+	cancel()
+	cancel()
+	assert.Equal(t, 0, bus.Subscribers())
+	for range ch {
+	}
+	bus.Publish(TaskProgress, nil)
+}
+
+func TestBusClose(t *testing.T) {
+	bus := NewBus()
+	ch, _, cancel := bus.Subscribe()
+
+	bus.Close()
+	_, ok := <-ch
+	assert.False(t, ok)
+	cancel()
+
+	// 关闭后订阅得到已关闭的 channel
+	late, _, cancelLate := bus.Subscribe()
+	defer cancelLate()
+	_, ok = <-late
+	assert.False(t, ok)
+}
+
+func TestNilBus(t *testing.T) {
+	var bus *Bus
+	require.NotPanics(t, func() {
+		bus.Publish(TaskChanged, nil)
+		bus.Close()
+	})
+	assert.Equal(t, 0, bus.Subscribers())
+}
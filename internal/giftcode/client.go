@@ -5,6 +5,8 @@ import (
 	"cdk-get/internal/storage"
 	"cdk-get/internal/utls"
 	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"net/url"
 	"strings"
@@ -19,6 +21,10 @@ const ErrMsgRetryMsg = "TIMEOUT RETRY"
 
 const ErrMsgReceived = "RECEIVED."
 const ErrMsgCdkNotFound = "CDK NOT FOUND."
+const ErrMsgRoleNotExist = "role not exist."
+
+// ErrPlayerNotFound 玩家接口查询不到该 fid
+var ErrPlayerNotFound = errors.New("player not found")
 
 type DdResult struct {
 	Code int    `json:"code"`
@@ -36,6 +42,13 @@ type DdPlayerMsg struct {
 	} `json:"data"`
 }
 
+// ddPlayerRawMsg 玩家接口原始响应
+// 查询失败时 data 返回空数组，因此先按原始 JSON 接收
+type ddPlayerRawMsg struct {
+	DdResult
+	Data json.RawMessage `json:"data"`
+}
+
 type DdImgMsg struct {
 	DdResult
 	ErrCode int `json:"err_code"`
@@ -46,6 +59,7 @@ type DdImgMsg struct {
 
 type PlayerGiftCode struct {
 	Fid           string
+	baseURL       string // 兑换接口的地址前缀
 	init          bool
 	expireTime    time.Time
 	Player        *DdPlayerMsg
@@ -54,9 +68,10 @@ type PlayerGiftCode struct {
 	correlationID string // 用于日志关联
 }
 
-func NewPlayerGiftCode(fid string, clientFn func() captcha.RemoteClient, storageCli storage.KeyStorage) *PlayerGiftCode {
+func NewPlayerGiftCode(baseURL, fid string, clientFn func() captcha.RemoteClient, storageCli storage.KeyStorage) *PlayerGiftCode {
 	return &PlayerGiftCode{
 		Fid:           fid,
+		baseURL:       baseURL,
 		clientFn:      clientFn,
 		storageCli:    storageCli,
 		correlationID: generateCorrelationID(),
@@ -122,7 +137,7 @@ func (g *PlayerGiftCode) GetGiftWithContext(ctx context.Context, code string) (r
 	params.Add("time", fmt.Sprintf("%d", time.Now().UnixMilli()))
 	params.Add("cdk", code)
 
-	result, err = utls.SendRequestV2[DdResult]("gift_code", params, ddSecretKey)
+	result, err = utls.SendRequestV2[DdResult](g.baseURL, "gift_code", params, ddSecretKey)
 	if err != nil {
 		log.WithError(err).Error("failed to send gift code request")
 		return nil, fmt.Errorf("failed to send gift code request: %w", err)
@@ -160,7 +175,7 @@ func (g *PlayerGiftCode) getCaptchaWithContext(ctx context.Context) (result *DdI
 	params.Add("time", fmt.Sprintf("%d", time.Now().UnixMilli()))
 	params.Add("init", "0")
 
-	result, err = utls.SendRequestV2[DdImgMsg]("captcha", params, ddSecretKey)
+	result, err = utls.SendRequestV2[DdImgMsg](g.baseURL, "captcha", params, ddSecretKey)
 	if err != nil {
 		log.WithError(err).Error("failed to send captcha request")
 		return nil, fmt.Errorf("failed to send captcha request: %w", err)
@@ -200,18 +215,10 @@ func (g *PlayerGiftCode) doInitWithContext(ctx context.Context) error {
 	default:
 	}
 
-	params := url.Values{}
-	params.Add("fid", g.Fid)
-	params.Add("time", fmt.Sprintf("%d", time.Now().UnixMilli()))
-
-	player, err := utls.SendRequestV2[DdPlayerMsg]("player", params, ddSecretKey)
+	player, err := FetchPlayerInfo(ctx, g.baseURL, g.Fid)
 	if err != nil {
 		log.WithError(err).Error("failed to get player info")
-		return fmt.Errorf("failed to get player info: %w", err)
-	}
-	if player == nil {
-		log.Error("player response is nil")
-		return fmt.Errorf("无法获取用户信息")
+		return err
 	}
 
 	g.Player = player
@@ -249,6 +256,44 @@ func (g *PlayerGiftCode) InitWithContext(ctx context.Context) error {
 	}
 }
 
+// FetchPlayerInfo 通过 baseURL 下的玩家接口查询 fid 对应的玩家资料，不写入存储
+// fid 不存在时返回 ErrPlayerNotFound
+func FetchPlayerInfo(ctx context.Context, baseURL, fid string) (*DdPlayerMsg, error) {
+	select {
+	case <-ctx.Done():
+		return nil, fmt.Errorf("context cancelled before fetching player: %w", ctx.Err())
+	default:
+	}
+
+	params := url.Values{}
+	params.Add("fid", fid)
+	params.Add("time", fmt.Sprintf("%d", time.Now().UnixMilli()))
+
+	raw, err := utls.SendRequestV2[ddPlayerRawMsg](baseURL, "player", params, ddSecretKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get player info: %w", err)
+	}
+	if raw == nil {
+		return nil, fmt.Errorf("无法获取用户信息")
+	}
+	if raw.Code != 0 {
+		if strings.EqualFold(raw.Msg, ErrMsgRoleNotExist) {
+			return nil, fmt.Errorf("%w: %s", ErrPlayerNotFound, fid)
+		}
+		return nil, fmt.Errorf("获取用户信息失败，错误信息：%s", raw.Msg)
+	}
+
+	player := &DdPlayerMsg{DdResult: raw.DdResult}
+	if err := json.Unmarshal(raw.Data, &player.Data); err != nil {
+		return nil, fmt.Errorf("failed to decode player info: %w", err)
+	}
+	if player.Data.Fid == 0 {
+		return nil, fmt.Errorf("%w: %s", ErrPlayerNotFound, fid)
+	}
+
+	return player, nil
+}
+
 // generateCorrelationID 生成唯一的关联ID用于日志追踪
 func generateCorrelationID() string {
 	return fmt.Sprintf("%d", time.Now().UnixNano())
@@ -0,0 +1,98 @@
+package giftcode
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// playerAPI 启动返回固定响应的玩家接口，返回接口的地址前缀
+func playerAPI(t *testing.T, status int, body string) string {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/player" {
+			t.Errorf("unexpected path %s", r.URL.Path)
+		}
+		if err := r.ParseForm(); err != nil || r.PostForm.Get("fid") == "" || r.PostForm.Get("sign") == "" {
+			t.Errorf("expected signed form with fid, got %v", r.PostForm)
+		}
+		w.WriteHeader(status)
+		w.Write([]byte(body))
+	}))
+	t.Cleanup(server.Close)
+	return server.URL + "/"
+}
+
+func TestFetchPlayerInfo(t *testing.T) {
+	baseURL := playerAPI(t, http.StatusOK, `{"code":0,"msg":"success","data":{"fid":123,"nickname":"alice","kid":42,"avatar_image":"https://example.com/a.png"}}`)
+
+	player, err := FetchPlayerInfo(context.Background(), baseURL, "123")
+	if err != nil {
+		t.Fatalf("failed to fetch player: %v", err)
+	}
+	if player.Data.Fid != 123 || player.Data.Nickname != "alice" || player.Data.Kid != 42 || player.Data.Avatar != "https://example.com/a.png" {
+		t.Errorf("unexpected player data: %+v", player.Data)
+	}
+}
+
+func TestFetchPlayerInfoErrors(t *testing.T) {
+	tests := []struct {
+		name     string
+		status   int
+		body     string
+		notFound bool
+	}{
+		{
+			name:     "role not exist",
+			status:   http.StatusOK,
+			body:     `{"code":1,"msg":"role not exist.","data":[]}`,
+			notFound: true,
+		},
+		{
+			name:     "empty player",
+			status:   http.StatusOK,
+			body:     `{"code":0,"msg":"success","data":{"fid":0}}`,
+			notFound: true,
+		},
+		{
+			name:   "api error",
+			status: http.StatusOK,
+			body:   `{"code":1,"msg":"Sign Error","data":[]}`,
+		},
+		{
+			name:   "http error",
+			status: http.StatusInternalServerError,
+			body:   `upstream unavailable`,
+		},
+		{
+			name:   "invalid json",
+			status: http.StatusOK,
+			body:   `<html>`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			baseURL := playerAPI(t, tt.status, tt.body)
+
+			player, err := FetchPlayerInfo(context.Background(), baseURL, "123")
+			if err == nil {
+				t.Fatalf("expected error, got player %+v", player)
+			}
+			if errors.Is(err, ErrPlayerNotFound) != tt.notFound {
+				t.Errorf("errors.Is(err, ErrPlayerNotFound) = %v, want %v (err: %v)", !tt.notFound, tt.notFound, err)
+			}
+		})
+	}
+}
+
+func TestFetchPlayerInfoCancelled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	if _, err := FetchPlayerInfo(ctx, "http://127.0.0.1/", "123"); !errors.Is(err, context.Canceled) {
+		t.Errorf("expected context.Canceled, got %v", err)
+	}
+}
@@ -0,0 +1,69 @@
+package job
+
+import (
+	"cdk-get/internal/captcha"
+	"io"
+	"sync"
+
+	"github.com/sirupsen/logrus"
+)
+
+// captchaDownThreshold 连续失败多少次后认为验证码识别服务不可用
+const captchaDownThreshold = 5
+
+// monitoredClient 统计验证码识别客户端的连续失败次数
+// 达到阈值时调用一次 onDown，成功识别后重置
+type monitoredClient struct {
+	captcha.RemoteClient
+	name   string
+	onDown func(name string, failures int, err error)
+
+	mu       sync.Mutex
+	failures int
+	down     bool
+}
+
+func newMonitoredClient(cli captcha.RemoteClient, name string, onDown func(name string, failures int, err error)) *monitoredClient {
+	return &monitoredClient{
+		RemoteClient: cli,
+		name:         name,
+		onDown:       onDown,
+	}
+}
+
+func (m *monitoredClient) DoWithBase64Img(base64Img string) (*captcha.CaptchaResponse, error) {
+	resp, err := m.RemoteClient.DoWithBase64Img(base64Img)
+	m.observe(err)
+	return resp, err
+}
+
+func (m *monitoredClient) DoWithReader(r io.Reader) (*captcha.CaptchaResponse, error) {
+	resp, err := m.RemoteClient.DoWithReader(r)
+	m.observe(err)
+	return resp, err
+}
+
+func (m *monitoredClient) observe(err error) {
+	m.mu.Lock()
+	if err == nil {
+		if m.down {
+			logrus.Infof("验证码识别服务 %s 已恢复", m.name)
+		}
+		m.failures = 0
+		m.down = false
+		m.mu.Unlock()
+		return
+	}
+
+	m.failures++
+	notify := !m.down && m.failures >= captchaDownThreshold
+	if notify {
+		m.down = true
+	}
+	failures := m.failures
+	m.mu.Unlock()
+
+	if notify && m.onDown != nil {
+		m.onDown(m.name, failures, err)
+	}
+}
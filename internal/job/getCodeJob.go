@@ -3,13 +3,19 @@ package job
 import (
 	"cdk-get/internal/captcha"
 	"cdk-get/internal/config"
+	"cdk-get/internal/events"
 	"cdk-get/internal/giftcode"
+	"cdk-get/internal/metrics"
+	"cdk-get/internal/notification"
+	"cdk-get/internal/service"
+	"cdk-get/internal/storage"
 	"cdk-get/internal/svc"
 	"context"
 	"errors"
 	"fmt"
 	"os"
 	"runtime/debug"
+	"strconv"
 	"strings"
 	"sync"
 	"time"
@@ -30,21 +36,25 @@ type GetCodeJob struct {
 }
 
 func NewGetCodeJob(svcCtx *svc.ServiceContext) *GetCodeJob {
-	clients, err := initClients()
+	clients, names, err := initClients()
 	if err != nil {
 		panic(err)
 	}
-	return &GetCodeJob{
+	g := &GetCodeJob{
 		svcCtx:    svcCtx,
 		cliKeep:   make(map[string]*giftcode.PlayerGiftCode),
-		clients:   clients,
 		shardLock: sync.Mutex{},
 	}
+	for i, cli := range clients {
+		cli = captcha.Observe(cli, names[i], g.recordCaptchaCall)
+		g.clients = append(g.clients, newMonitoredClient(cli, names[i], g.captchaProviderDown))
+	}
+	return g
 }
 
 func (g *GetCodeJob) Run(ctx context.Context) {
-	codes, err := g.svcCtx.SqlClient.GetTask()
-	if len(codes) == 0 {
+	tasks, err := g.svcCtx.Repository.ListPendingTasks(ctx)
+	if len(tasks) == 0 {
 		logrus.Infof("未发现代办任务")
 	}
 	if err != nil {
@@ -59,8 +69,27 @@ func (g *GetCodeJob) Run(ctx context.Context) {
 	if len(fids) == 0 {
 		fids = fidsDefault
 	}
-	for _, code := range codes {
-		g.processCodeSafely(code, fids)
+	maxRetries := g.svcCtx.Config.Job.MaxTaskRetries
+	for _, task := range tasks {
+		// 重试次数耗尽的任务不再自动处理
+		if maxRetries > 0 && task.RetryCount >= maxRetries {
+			logrus.Debugf("任务 %s 已重试 %d 次, 跳过", task.Code, task.RetryCount)
+			continue
+		}
+		taskFids := fids
+		// 指定了目标分组的任务只处理该分组内的用户
+		if task.TargetGroupID != nil {
+			taskFids, err = g.svcCtx.Repository.ListGroupFids(ctx, *task.TargetGroupID)
+			if err != nil {
+				logrus.Errorf("获取任务 %s 的分组处理人失败: %v", task.Code, err)
+				continue
+			}
+			if len(taskFids) == 0 {
+				logrus.Warnf("任务 %s 的目标分组 %s 没有可处理的用户, 跳过", task.Code, task.TargetGroup)
+				continue
+			}
+		}
+		g.processCodeSafely(task.Code, taskFids)
 	}
 }
 
@@ -71,13 +100,7 @@ func (g *GetCodeJob) processCodeSafely(code string, fids []string) {
 			logrus.Errorf("堆栈信息: %s", debug.Stack())
 
 			// Update retry count and error on panic
-			ctx := context.Background()
-			task, _ := g.svcCtx.Repository.GetTaskByCode(ctx, code)
-			if task != nil {
-				retryCount := task.RetryCount + 1
-				errorMsg := fmt.Sprintf("Panic: %v", err)
-				_ = g.svcCtx.Repository.UpdateTaskRetry(ctx, code, retryCount, errorMsg)
-			}
+			g.recordFailure(context.Background(), code, fmt.Sprintf("Panic: %v", err))
 		}
 	}()
 
@@ -85,80 +108,123 @@ func (g *GetCodeJob) processCodeSafely(code string, fids []string) {
 	startTime := time.Now()
 
 	ctx := context.Background()
-	alldone, msg, err := g.once(ctx, code, fids)
+	alldone, notFound, results, failure, err := g.once(ctx, code, fids)
+	msg := formatResults(results)
+	if notFound {
+		msg = fmt.Sprintf("兑换码:%s 不存在", code)
+	}
 
 	if err != nil {
 		logrus.Errorf("GetCodeJob GetTask err: %v", err)
 
 		// Update retry count and error on failure
-		task, _ := g.svcCtx.Repository.GetTaskByCode(ctx, code)
-		if task != nil {
-			retryCount := task.RetryCount + 1
-			_ = g.svcCtx.Repository.UpdateTaskRetry(ctx, code, retryCount, err.Error())
-		}
+		g.recordFailure(ctx, code, err.Error())
 	} else if alldone {
 		// Mark task as complete with timestamp
 		completedAt := time.Now()
 		if err := g.svcCtx.Repository.UpdateTaskComplete(ctx, code, completedAt); err != nil {
 			logrus.Errorf("GetCodeJob UpdateTaskComplete err: %v", err)
+		} else if notFound {
+			g.publish(ctx, notification.NewEvent(
+				notification.EventCodeNotFound,
+				"兑换码不存在",
+				fmt.Sprintf("兑换码[%s]不存在", code),
+				msg,
+				map[string]any{"code": code},
+			))
 		} else {
-			// Send notification using NotificationService
-			if g.svcCtx.NotificationService != nil {
-				title := "兑换码兑换成功"
-				summary := fmt.Sprintf("兑换码[%s]兑换成功", code)
-				_ = g.svcCtx.NotificationService.SendAndSave(ctx, title, summary, msg)
-			}
+			g.publish(ctx, notification.NewEvent(
+				notification.EventTaskCompleted,
+				"兑换码兑换成功",
+				fmt.Sprintf("兑换码[%s]兑换成功", code),
+				msg,
+				map[string]any{"code": code, "fids": len(fids), "results": results},
+			))
+			g.notifyOwners(ctx, code, results)
 		}
+	} else if failure != "" {
+		// 每轮执行最多记录一次失败，避免一轮内多个处理人失败耗尽重试次数
+		g.recordFailure(ctx, code, failure)
 	}
 	logrus.Infof("任务执行信息: %s", msg)
 	endTime := time.Now()
 	logrus.Infof("完成code: %s任务, 耗时: %s", code, endTime.Sub(startTime).String())
 }
 
-func (g *GetCodeJob) once(ctx context.Context, code string, fids []string) (bool, string, error) {
+// once 为所有处理人兑换一次，返回是否全部完成、兑换码是否不存在、每个处理人的兑换结果
+// 以及本轮最后一次兑换接口返回的错误
+func (g *GetCodeJob) once(ctx context.Context, code string, fids []string) (bool, bool, []notification.RedeemResult, string, error) {
 	repository := g.svcCtx.SqlClient
 	cliKeep := g.cliKeep
 	if code == "" {
-		return false, "", errors.New("code is empty")
+		return false, false, nil, "", errors.New("code is empty")
 	}
 	var (
 		notFound bool
+		failure  string
 		alldone  = true
-		line     = strings.Builder{}
+		results  = make([]notification.RedeemResult, 0, len(fids))
 	)
-	for _, fid := range fids {
+	for i, fid := range fids {
 		var (
-			gfc *giftcode.PlayerGiftCode
-			ok  bool
-			msg string
+			gfc    *giftcode.PlayerGiftCode
+			ok     bool
+			failed bool
+			msg    string
 		)
 		if gfc, ok = cliKeep[fid]; !ok {
-			gfc = giftcode.NewPlayerGiftCode(fid, g.getClient, repository)
+			gfc = giftcode.NewPlayerGiftCode(g.svcCtx.Config.Job.GameAPIURL, fid, g.getClient, repository)
 			if err := gfc.Init(); err != nil {
-				return false, "", err
+				return false, false, nil, "", err
 			}
 			cliKeep[fid] = gfc
 		}
 
-		line.WriteString(fmt.Sprintf("fid:%v, 昵称:%v, 区服:%v", gfc.Player.Data.Fid, gfc.Player.Data.Nickname, gfc.Player.Data.Kid))
-		if ok, notFound, msg = g.getOnceCodeWithOneFid(ctx, code, gfc); !ok {
+		if ok, notFound, failed, msg = g.getOnceCodeWithOneFid(ctx, code, gfc); !ok {
 			alldone = false
 		}
+		if failed {
+			failure = msg
+		}
+		g.svcCtx.Events.Publish(events.TaskProgress, events.TaskProgressData{
+			Code:     code,
+			FID:      fid,
+			Nickname: gfc.Player.Data.Nickname,
+			Done:     ok,
+			Result:   msg,
+			Index:    i + 1,
+			Total:    len(fids),
+		})
 		if notFound {
 			break
 		}
-		line.WriteString(fmt.Sprintf(" 结果: %s \n", msg))
+		results = append(results, notification.RedeemResult{
+			FID:      strconv.Itoa(gfc.Player.Data.Fid),
+			Nickname: gfc.Player.Data.Nickname,
+			KID:      gfc.Player.Data.Kid,
+			Result:   msg,
+		})
 	}
 	if notFound {
 		for _, fid := range fids {
 			_ = repository.Save(fid, code)
 		}
-		return true, fmt.Sprintf("兑换码:%s 不存在", code), nil
+		return true, true, nil, "", nil
 	}
-	return alldone, line.String(), nil
+	return alldone, false, results, failure, nil
 }
 
-func (g *GetCodeJob) getOnceCodeWithOneFid(ctx context.Context, code string, gfc *giftcode.PlayerGiftCode) (done bool, notFound bool, msg string) {
+// formatResults 将兑换结果格式化为纯文本，每个处理人一行
+func formatResults(results []notification.RedeemResult) string {
+	var sb strings.Builder
+	for _, r := range results {
+		sb.WriteString(fmt.Sprintf("fid:%v, 昵称:%v, 区服:%v 结果: %s\n", r.FID, r.Nickname, r.KID, r.Result))
+	}
+	return sb.String()
+}
+
+// getOnceCodeWithOneFid 为一个处理人兑换，failed 表示兑换接口返回了错误
+func (g *GetCodeJob) getOnceCodeWithOneFid(ctx context.Context, code string, gfc *giftcode.PlayerGiftCode) (done bool, notFound bool, failed bool, msg string) {
 	repository := g.svcCtx.SqlClient
 	if exists, err := repository.IsReceived(gfc.Fid, code); err != nil {
 		done = false
@@ -172,6 +238,9 @@ func (g *GetCodeJob) getOnceCodeWithOneFid(ctx context.Context, code string, gfc
 
 	// 获取兑换码
 	result, err := gfc.GetGift(code)
+	if err := g.svcCtx.Repository.RecordRedeemAttempt(ctx, service.NewRedeemAttempt(gfc.Fid, code, result, err)); err != nil {
+		logrus.Warnf("GetCodeJob RecordRedeemAttempt err: %v", err)
+	}
 	if err != nil {
 		done = false
 		msg = fmt.Sprintf("%v", err)
@@ -191,13 +260,8 @@ func (g *GetCodeJob) getOnceCodeWithOneFid(ctx context.Context, code string, gfc
 			_ = repository.Save(gfc.Fid, code)
 		} else {
 			done = false
+			failed = true
 			msg = result.Msg
-			// 写入失败次数
-			task, _ := g.svcCtx.Repository.GetTaskByCode(ctx, code)
-			if task != nil {
-				retryCount := task.RetryCount + 1
-				_ = g.svcCtx.Repository.UpdateTaskRetry(ctx, code, retryCount, result.Msg)
-			}
 		}
 		return
 	} else {
@@ -208,6 +272,80 @@ func (g *GetCodeJob) getOnceCodeWithOneFid(ctx context.Context, code string, gfc
 	}
 }
 
+// recordFailure 增加任务重试次数并记录错误
+// 重试次数达到上限时发布任务失败通知
+func (g *GetCodeJob) recordFailure(ctx context.Context, code string, errMsg string) {
+	task, _ := g.svcCtx.Repository.GetTaskByCode(ctx, code)
+	if task == nil {
+		return
+	}
+	retryCount := task.RetryCount + 1
+	if err := g.svcCtx.Repository.UpdateTaskRetry(ctx, code, retryCount, errMsg); err != nil {
+		logrus.Errorf("GetCodeJob UpdateTaskRetry err: %v", err)
+		return
+	}
+
+	maxRetries := g.svcCtx.Config.Job.MaxTaskRetries
+	if maxRetries > 0 && task.RetryCount < maxRetries && retryCount >= maxRetries {
+		logrus.Warnf("任务 %s 重试 %d 次仍失败, 停止重试", code, retryCount)
+		g.publish(ctx, notification.NewEvent(
+			notification.EventTaskFailed,
+			"兑换码任务失败",
+			fmt.Sprintf("兑换码[%s]重试%d次仍失败", code, retryCount),
+			fmt.Sprintf("兑换码: %s\n重试次数: %d\n最后错误: %s", code, retryCount, errMsg),
+			map[string]any{"code": code, "retry_count": retryCount, "error": errMsg},
+		))
+	}
+}
+
+// notifyOwners 向每个用户订阅的通知目标发送其个人兑换结果
+func (g *GetCodeJob) notifyOwners(ctx context.Context, code string, results []notification.RedeemResult) {
+	for _, r := range results {
+		event := notification.NewEvent(
+			notification.EventUserCodeRedeemed,
+			"兑换码兑换结果",
+			fmt.Sprintf("%s 兑换码[%s]%s", r.Nickname, code, r.Result),
+			fmt.Sprintf("兑换码: %s\n角色: %s (fid:%s, 区服:%d)\n结果: %s", code, r.Nickname, r.FID, r.KID, r.Result),
+			map[string]any{"code": code, "fid": r.FID, "nickname": r.Nickname, "kid": r.KID, "result": r.Result},
+		)
+		if err := g.svcCtx.NotificationService.NotifyUser(ctx, r.FID, event); err != nil {
+			logrus.Warnf("发送用户 %s 的个人通知失败: %v", r.FID, err)
+		}
+	}
+}
+
+// recordCaptchaCall 记录一次验证码识别及按提供商单价估算的费用
+func (g *GetCodeJob) recordCaptchaCall(provider string, elapsed time.Duration, err error) {
+	metrics.ObserveCaptcha(provider, elapsed, err)
+	call := &storage.CaptchaCall{
+		Provider: provider,
+		Success:  err == nil,
+		Cost:     g.svcCtx.Config.Captcha.UnitPrice(provider),
+	}
+	if err := g.svcCtx.Repository.RecordCaptchaCall(context.Background(), call); err != nil {
+		logrus.Warnf("GetCodeJob RecordCaptchaCall err: %v", err)
+	}
+}
+
+// captchaProviderDown 验证码识别服务连续失败时发布通知
+func (g *GetCodeJob) captchaProviderDown(name string, failures int, err error) {
+	logrus.Errorf("验证码识别服务 %s 连续失败 %d 次: %v", name, failures, err)
+	go g.publish(context.Background(), notification.NewEvent(
+		notification.EventCaptchaProviderDown,
+		"验证码识别服务异常",
+		fmt.Sprintf("验证码识别服务[%s]连续失败%d次", name, failures),
+		fmt.Sprintf("服务: %s\n连续失败次数: %d\n最后错误: %v", name, failures, err),
+		map[string]any{"provider": name, "failures": failures, "error": err.Error()},
+	))
+}
+
+// publish 发布通知事件，通知服务未配置时忽略
+func (g *GetCodeJob) publish(ctx context.Context, event notification.Event) {
+	if err := g.svcCtx.NotificationService.Publish(ctx, event); err != nil {
+		logrus.Warnf("发布通知事件 %s 失败: %v", event.Type, err)
+	}
+}
+
 func (g *GetCodeJob) DelayTime() time.Duration {
 	return 2 * time.Second
 }
@@ -235,7 +373,8 @@ func (g *GetCodeJob) getClient() captcha.RemoteClient {
 	return cli
 }
 
-func initClients() (clients []captcha.RemoteClient, err error) {
+// initClients 初始化验证码识别客户端，names 为与 clients 一一对应的服务名称
+func initClients() (clients []captcha.RemoteClient, names []string, err error) {
 	// 尝试从配置文件加载配置
 	cfg, err := config.LoadConfig("./etc/config.yaml")
 	if err != nil {
@@ -252,6 +391,7 @@ func initClients() (clients []captcha.RemoteClient, err error) {
 				alicli, err := captcha.NewAliCaptchaClient(provider.AccessKey, provider.SecretKey)
 				if err == nil {
 					clients = append(clients, alicli)
+					names = append(names, provider.Type)
 					logrus.Infof("成功初始化阿里云OCR客户端")
 				} else {
 					logrus.Errorf("初始化阿里云图片识别错误: %v", err)
@@ -262,6 +402,7 @@ func initClients() (clients []captcha.RemoteClient, err error) {
 				tccli, err := captcha.NewTcCaptchaClient(provider.AccessKey, provider.SecretKey)
 				if err == nil {
 					clients = append(clients, tccli)
+					names = append(names, provider.Type)
 					logrus.Infof("成功初始化腾讯云OCR客户端")
 				} else {
 					logrus.Errorf("初始化腾讯云图片识别错误: %v", err)
@@ -272,6 +413,7 @@ func initClients() (clients []captcha.RemoteClient, err error) {
 				googlecli, err := captcha.NewGoogleCaptchaClient(provider.CredentialsJSON)
 				if err == nil {
 					clients = append(clients, googlecli)
+					names = append(names, provider.Type)
 					logrus.Infof("成功初始化Google Vision OCR客户端")
 				} else {
 					logrus.Errorf("初始化Google Vision图片识别错误: %v", err)
@@ -290,6 +432,7 @@ func initClients() (clients []captcha.RemoteClient, err error) {
 				alicli, err := captcha.NewAliCaptchaClient(accessKey, secretKey)
 				if err == nil {
 					clients = append(clients, alicli)
+					names = append(names, "ali")
 					logrus.Infof("从环境变量成功初始化阿里云OCR客户端")
 				} else {
 					logrus.Errorf("从环境变量初始化阿里云图片识别错误: %v", err)
@@ -299,9 +442,9 @@ func initClients() (clients []captcha.RemoteClient, err error) {
 	}
 
 	if len(clients) == 0 {
-		return nil, errors.New("未能初始化任何OCR客户端")
+		return nil, nil, errors.New("未能初始化任何OCR客户端")
 	}
 
 	logrus.Infof("共初始化 %d 个OCR客户端", len(clients))
-	return clients, nil
+	return clients, names, nil
 }
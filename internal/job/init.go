@@ -10,10 +10,16 @@ var globalScheduler *Scheduler
 
 // InitTask 初始化任务调度
 func InitTask(svcCtx *svc.ServiceContext) error {
-	globalScheduler = NewScheduler()
+	globalScheduler = NewScheduler(svcCtx.Events)
 
 	// 添加任务
 	globalScheduler.AddJob(NewGetCodeJob(svcCtx))
+	if svcCtx.Config != nil && svcCtx.Config.Retention.Enabled {
+		globalScheduler.AddJob(NewPruneJob(svcCtx))
+	}
+	if svcCtx.Config != nil && svcCtx.Config.Job.UserRefreshInterval > 0 && svcCtx.GiftService != nil {
+		globalScheduler.AddJob(NewUserRefreshJob(svcCtx))
+	}
 
 	// 启动调度器
 	if err := globalScheduler.Start(); err != nil {
@@ -0,0 +1,120 @@
+package job
+
+import (
+	"cdk-get/internal/storage"
+	"cdk-get/internal/svc"
+	"context"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// pruneBatchPause 两批删除之间的间隔，避免长时间占用数据库写锁
+const pruneBatchPause = 200 * time.Millisecond
+
+// prunePolicy 单个清理目标的保留策略
+type prunePolicy struct {
+	target string
+	keep   time.Duration
+	prune  func(ctx context.Context, before time.Time, limit int) (int64, error)
+}
+
+// PruneJob 按保留策略分批清理历史数据
+type PruneJob struct {
+	svcCtx *svc.ServiceContext
+}
+
+func NewPruneJob(svcCtx *svc.ServiceContext) *PruneJob {
+	return &PruneJob{
+		svcCtx: svcCtx,
+	}
+}
+
+func (p *PruneJob) Run(ctx context.Context) {
+	for _, policy := range p.policies() {
+		if policy.keep <= 0 {
+			continue
+		}
+		p.runPolicy(ctx, policy)
+	}
+}
+
+func (p *PruneJob) policies() []prunePolicy {
+	cfg := p.svcCtx.Config.Retention
+	repo := p.svcCtx.Repository
+	return []prunePolicy{
+		{target: storage.PruneTargetNotifications, keep: cfg.Notifications, prune: repo.PruneNotifications},
+		{target: storage.PruneTargetCompletedTasks, keep: cfg.CompletedTasks, prune: repo.PruneCompletedTasks},
+		{target: storage.PruneTargetDeletedTasks, keep: cfg.TrashGracePeriod, prune: repo.PurgeDeletedTasks},
+		{target: storage.PruneTargetDeletedUsers, keep: cfg.TrashGracePeriod, prune: repo.PurgeDeletedUsers},
+		{target: storage.PruneTargetLoginFailures, keep: cfg.LoginFailures, prune: repo.PruneLoginFailures},
+		{target: storage.PruneTargetAuditLog, keep: cfg.AuditLog, prune: repo.PruneAuditLog},
+		{target: storage.PruneTargetRedeemAttempts, keep: cfg.Statistics, prune: repo.PruneRedeemAttempts},
+		{target: storage.PruneTargetCaptchaCalls, keep: cfg.Statistics, prune: repo.PruneCaptchaCalls},
+	}
+}
+
+func (p *PruneJob) runPolicy(ctx context.Context, policy prunePolicy) {
+	batchSize := p.svcCtx.Config.Retention.BatchSize
+	run := &storage.PruneRun{
+		Target:    policy.target,
+		Cutoff:    time.Now().Add(-policy.keep),
+		Status:    storage.PruneRunStatusSuccess,
+		StartedAt: time.Now(),
+	}
+	log := logrus.WithFields(logrus.Fields{
+		"job":    p.Name(),
+		"target": policy.target,
+		"cutoff": run.Cutoff,
+	})
+
+	for {
+		deleted, err := policy.prune(ctx, run.Cutoff, batchSize)
+		run.Deleted += deleted
+		if err != nil {
+			run.Status = storage.PruneRunStatusFailed
+			run.Error = err.Error()
+			log.WithError(err).Error("清理数据失败")
+			break
+		}
+		if deleted < int64(batchSize) {
+			break
+		}
+		if err := sleepWithContext(ctx, pruneBatchPause); err != nil {
+			run.Status = storage.PruneRunStatusFailed
+			run.Error = err.Error()
+			break
+		}
+	}
+	run.FinishedAt = time.Now()
+
+	// 使用独立的 context 保存执行记录，保证调度器停止时也能记录本次结果
+	if err := p.svcCtx.Repository.SavePruneRun(context.Background(), run); err != nil {
+		log.WithError(err).Error("保存清理记录失败")
+	}
+	log.WithField("deleted", run.Deleted).Infof("数据清理完成, 耗时: %s", run.FinishedAt.Sub(run.StartedAt).String())
+}
+
+// sleepWithContext 等待 d 时长，context 取消时提前返回错误
+func sleepWithContext(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+func (p *PruneJob) DelayTime() time.Duration {
+	return time.Minute
+}
+
+func (p *PruneJob) PeriodTime() time.Duration {
+	return p.svcCtx.Config.Retention.Interval
+}
+
+func (p *PruneJob) Name() string {
+	return "PruneJob"
+}
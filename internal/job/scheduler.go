@@ -1,6 +1,8 @@
 package job
 
 import (
+	"cdk-get/internal/events"
+	"cdk-get/internal/metrics"
 	"context"
 	"sync"
 	"time"
@@ -23,15 +25,17 @@ type Scheduler struct {
 	cancel context.CancelFunc
 	wg     sync.WaitGroup
 	mu     sync.Mutex
+	events *events.Bus
 }
 
-// NewScheduler 创建新的调度器
-func NewScheduler() *Scheduler {
+// NewScheduler 创建新的调度器，每次执行任务前后向 bus 发布 job.started、job.finished 事件，bus 可以为 nil
+func NewScheduler(bus *events.Bus) *Scheduler {
 	ctx, cancel := context.WithCancel(context.Background())
 	return &Scheduler{
 		jobs:   make([]Job, 0),
 		ctx:    ctx,
 		cancel: cancel,
+		events: bus,
 	}
 }
 
@@ -91,7 +95,12 @@ func (s *Scheduler) runJob(job Job) {
 		case <-timer.C:
 			// 执行任务
 			log.Debug("executing job")
+			s.events.Publish(events.JobStarted, events.JobRun{Job: job.Name()})
+			start := time.Now()
 			job.Run(s.ctx)
+			elapsed := time.Since(start)
+			metrics.ObserveJobRun(job.Name(), elapsed)
+			s.events.Publish(events.JobFinished, events.JobRun{Job: job.Name(), DurationMS: elapsed.Milliseconds()})
 
 			// 重置定时器
 			timer.Reset(job.PeriodTime())
@@ -0,0 +1,103 @@
+package job
+
+import (
+	"cdk-get/internal/giftcode"
+	"cdk-get/internal/svc"
+	"context"
+	"errors"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// userRefreshPause 两次玩家接口查询之间的间隔，避免触发接口限流
+const userRefreshPause = 500 * time.Millisecond
+
+// UserRefreshJob 定期通过玩家接口刷新用户昵称、区服和头像
+type UserRefreshJob struct {
+	svcCtx *svc.ServiceContext
+}
+
+func NewUserRefreshJob(svcCtx *svc.ServiceContext) *UserRefreshJob {
+	return &UserRefreshJob{
+		svcCtx: svcCtx,
+	}
+}
+
+func (u *UserRefreshJob) Run(ctx context.Context) {
+	log := logrus.WithField("job", u.Name())
+
+	users, err := u.svcCtx.Repository.ListUsers(ctx)
+	if err != nil {
+		log.WithError(err).Error("获取用户列表失败")
+		return
+	}
+
+	var updated, failed int
+	startTime := time.Now()
+	for i, user := range users {
+		if i > 0 {
+			if err := sleepWithContext(ctx, userRefreshPause); err != nil {
+				log.WithError(err).Warn("刷新用户资料被中断")
+				break
+			}
+		}
+
+		userLog := log.WithField("fid", user.FID)
+		player, err := u.svcCtx.GiftService.RefreshUserInfo(ctx, user.FID)
+		if err != nil {
+			failed++
+			if errors.Is(err, giftcode.ErrPlayerNotFound) {
+				userLog.Warn("玩家接口查询不到该用户")
+			} else {
+				userLog.WithError(err).Error("查询用户资料失败")
+			}
+			continue
+		}
+
+		d := player.Data
+		if d.Nickname == user.Nickname && d.Kid == user.KID && d.Avatar == user.AvatarImage {
+			continue
+		}
+		if d.Kid != user.KID {
+			userLog.WithFields(logrus.Fields{
+				"old_kid": user.KID,
+				"new_kid": d.Kid,
+			}).Info("用户已迁移区服")
+		}
+		if d.Nickname != user.Nickname {
+			userLog.WithFields(logrus.Fields{
+				"old_nickname": user.Nickname,
+				"new_nickname": d.Nickname,
+			}).Info("用户昵称已变更")
+		}
+
+		user.Nickname = d.Nickname
+		user.KID = d.Kid
+		user.AvatarImage = d.Avatar
+		if err := u.svcCtx.Repository.UpdateUser(ctx, user); err != nil {
+			failed++
+			userLog.WithError(err).Error("更新用户资料失败")
+			continue
+		}
+		updated++
+	}
+
+	log.WithFields(logrus.Fields{
+		"total":   len(users),
+		"updated": updated,
+		"failed":  failed,
+	}).Infof("用户资料刷新完成, 耗时: %s", time.Since(startTime).String())
+}
+
+func (u *UserRefreshJob) DelayTime() time.Duration {
+	return time.Minute
+}
+
+func (u *UserRefreshJob) PeriodTime() time.Duration {
+	return u.svcCtx.Config.Job.UserRefreshInterval
+}
+
+func (u *UserRefreshJob) Name() string {
+	return "UserRefreshJob"
+}
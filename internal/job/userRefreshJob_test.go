@@ -0,0 +1,81 @@
+package job
+
+import (
+	"cdk-get/internal/config"
+	"cdk-get/internal/service"
+	"cdk-get/internal/storage"
+	"cdk-get/internal/svc"
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+)
+
+func TestUserRefreshJob(t *testing.T) {
+	// fid 1 资料未变，fid 2 改了昵称，fid 3 查询不到
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch fid := r.FormValue("fid"); fid {
+		case "1":
+			fmt.Fprint(w, `{"code":0,"msg":"success","data":{"fid":1,"nickname":"alice","kid":7,"avatar_image":"a.png"}}`)
+		case "2":
+			fmt.Fprint(w, `{"code":0,"msg":"success","data":{"fid":2,"nickname":"bob","kid":7,"avatar_image":"b.png"}}`)
+		default:
+			fmt.Fprint(w, `{"code":1,"msg":"role not exist.","data":[]}`)
+		}
+	}))
+	defer upstream.Close()
+
+	logger := logrus.New()
+	logger.SetLevel(logrus.ErrorLevel)
+
+	sqliteConfig := storage.DefaultSqliteConfig()
+	sqliteConfig.Path = filepath.Join(t.TempDir(), "refresh.db")
+	repo, err := storage.NewSqliteRepository(sqliteConfig, logger)
+	if err != nil {
+		t.Fatalf("failed to create repository: %v", err)
+	}
+	defer repo.Close()
+
+	ctx := context.Background()
+	for _, user := range []*storage.User{
+		{FID: "1", Nickname: "alice", KID: 7, AvatarImage: "a.png"},
+		{FID: "2", Nickname: "bobby", KID: 7, AvatarImage: "b.png"},
+		{FID: "3", Nickname: "carol", KID: 7, AvatarImage: "c.png"},
+	} {
+		if err := repo.SaveUser(ctx, user); err != nil {
+			t.Fatalf("failed to save user: %v", err)
+		}
+	}
+
+	svcCtx := svc.NewServiceContext(&config.Config{}, nil, repo, nil, service.NewGiftService(repo, nil, nil, nil, upstream.URL+"/", 0, logger), nil)
+	NewUserRefreshJob(svcCtx).Run(ctx)
+
+	// 只有资料变化的用户写入变更记录
+	for fid, want := range map[string]int{"1": 0, "2": 1, "3": 0} {
+		history, err := repo.ListUserProfileHistory(ctx, fid)
+		if err != nil {
+			t.Fatalf("failed to list profile history: %v", err)
+		}
+		if len(history) != want {
+			t.Errorf("fid %s: expected %d profile changes, got %d", fid, want, len(history))
+		}
+	}
+
+	history, _ := repo.ListUserProfileHistory(ctx, "2")
+	if len(history) == 1 && (history[0].Field != "nickname" || history[0].OldValue != "bobby" || history[0].NewValue != "bob") {
+		t.Errorf("unexpected profile change: %+v", history[0])
+	}
+
+	// 查询失败的用户保持原样
+	user, err := repo.GetUser(ctx, "3")
+	if err != nil {
+		t.Fatalf("failed to get user: %v", err)
+	}
+	if user.Nickname != "carol" {
+		t.Errorf("expected nickname carol to be kept, got %s", user.Nickname)
+	}
+}
@@ -0,0 +1,194 @@
+// Package metrics 定义服务的 Prometheus 指标，由 /metrics 接口输出
+package metrics
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"sync/atomic"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// namespace 所有指标名的前缀
+const namespace = "cdk_get"
+
+// 调用结果
+const (
+	OutcomeSuccess   = "success"    // 成功
+	OutcomeAPIError  = "api_error"  // 接口返回了非 0 的业务错误码
+	OutcomeHTTPError = "http_error" // 接口返回 4xx/5xx
+	OutcomeError     = "error"      // 网络错误、超时或响应无法解析
+)
+
+// pendingTasksTimeout 统计未完成任务数的超时时间
+const pendingTasksTimeout = 5 * time.Second
+
+// Registry 服务指标的注册表，包含 Go 运行时和进程指标
+var Registry = prometheus.NewRegistry()
+
+var (
+	httpRequests = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "http_requests_total",
+		Help:      "HTTP requests handled, by method, route and status code.",
+	}, []string{"method", "route", "status"})
+
+	httpRequestDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "http_request_duration_seconds",
+		Help:      "HTTP request latency, by method and route.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"method", "route"})
+
+	gameAPIRequests = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "game_api_requests_total",
+		Help:      "Game gift code API calls, by endpoint and outcome.",
+	}, []string{"endpoint", "outcome"})
+
+	gameAPIRequestDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "game_api_request_duration_seconds",
+		Help:      "Game gift code API latency, by endpoint.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"endpoint"})
+
+	captchaRecognitions = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "captcha_recognitions_total",
+		Help:      "Captcha recognitions, by provider and outcome.",
+	}, []string{"provider", "outcome"})
+
+	captchaRecognitionDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "captcha_recognition_duration_seconds",
+		Help:      "Captcha recognition latency, by provider.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"provider"})
+
+	jobRunDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "job_run_duration_seconds",
+		Help:      "Duration of background job runs, by job.",
+		Buckets:   []float64{0.1, 0.5, 1, 5, 10, 30, 60, 120, 300, 600},
+	}, []string{"job"})
+
+	notificationsSent = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "notifications_sent_total",
+		Help:      "Notification delivery attempts, by channel and resulting status (success, pending for a scheduled retry, failed).",
+	}, []string{"channel", "status"})
+
+	dbQueryDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "db_query_duration_seconds",
+		Help:      "SQLite statement latency, by operation (select, insert, update, delete, other).",
+		Buckets:   []float64{0.0005, 0.001, 0.0025, 0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1},
+	}, []string{"operation"})
+
+	pendingTasks = &pendingTasksCollector{
+		desc: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "", "pending_tasks"),
+			"Gift code tasks waiting to be redeemed.",
+			nil, nil,
+		),
+	}
+)
+
+func init() {
+	Registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		httpRequests,
+		httpRequestDuration,
+		gameAPIRequests,
+		gameAPIRequestDuration,
+		captchaRecognitions,
+		captchaRecognitionDuration,
+		jobRunDuration,
+		notificationsSent,
+		dbQueryDuration,
+		pendingTasks,
+	)
+}
+
+// Handler 返回输出 Registry 中指标的 HTTP 处理器
+// 个别指标采集失败时仍输出其余指标
+func Handler() http.Handler {
+	return promhttp.HandlerFor(Registry, promhttp.HandlerOpts{
+		Registry:      Registry,
+		ErrorHandling: promhttp.ContinueOnError,
+	})
+}
+
+// ObserveHTTPRequest 记录一次 HTTP 请求，route 为路由模板，避免按实际路径产生过多序列
+func ObserveHTTPRequest(method, route string, status int, elapsed time.Duration) {
+	httpRequests.WithLabelValues(method, route, strconv.Itoa(status)).Inc()
+	httpRequestDuration.WithLabelValues(method, route).Observe(elapsed.Seconds())
+}
+
+// ObserveGameAPI 记录一次游戏接口调用，outcome 为 Outcome* 之一
+func ObserveGameAPI(endpoint, outcome string, elapsed time.Duration) {
+	gameAPIRequests.WithLabelValues(endpoint, outcome).Inc()
+	gameAPIRequestDuration.WithLabelValues(endpoint).Observe(elapsed.Seconds())
+}
+
+// ObserveCaptcha 记录一次验证码识别，err 不为 nil 时结果为 error
+func ObserveCaptcha(provider string, elapsed time.Duration, err error) {
+	outcome := OutcomeSuccess
+	if err != nil {
+		outcome = OutcomeError
+	}
+	captchaRecognitions.WithLabelValues(provider, outcome).Inc()
+	captchaRecognitionDuration.WithLabelValues(provider).Observe(elapsed.Seconds())
+}
+
+// ObserveJobRun 记录一次后台任务执行的耗时
+func ObserveJobRun(job string, elapsed time.Duration) {
+	jobRunDuration.WithLabelValues(job).Observe(elapsed.Seconds())
+}
+
+// ObserveNotification 记录一次通知投递及投递后的状态
+func ObserveNotification(channel, status string) {
+	notificationsSent.WithLabelValues(channel, status).Inc()
+}
+
+// ObserveQuery 记录一条 SQL 语句的执行耗时
+func ObserveQuery(operation string, elapsed time.Duration) {
+	dbQueryDuration.WithLabelValues(operation).Observe(elapsed.Seconds())
+}
+
+// SetPendingTasksSource 设置采集时统计未完成任务数的函数，为 nil 时不输出该指标
+func SetPendingTasksSource(count func(ctx context.Context) (int, error)) {
+	pendingTasks.count.Store(&count)
+}
+
+// pendingTasksCollector 在采集时统计未完成任务数
+type pendingTasksCollector struct {
+	desc  *prometheus.Desc
+	count atomic.Pointer[func(ctx context.Context) (int, error)]
+}
+
+func (p *pendingTasksCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- p.desc
+}
+
+func (p *pendingTasksCollector) Collect(ch chan<- prometheus.Metric) {
+	count := p.count.Load()
+	if count == nil || *count == nil {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), pendingTasksTimeout)
+	defer cancel()
+	n, err := (*count)(ctx)
+	if err != nil {
+		ch <- prometheus.NewInvalidMetric(p.desc, err)
+		return
+	}
+	ch <- prometheus.MustNewConstMetric(p.desc, prometheus.GaugeValue, float64(n))
+}
@@ -0,0 +1,65 @@
+package metrics
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// scrape returns the text exposition of Registry
+func scrape(t *testing.T) (int, string) {
+	t.Helper()
+	w := httptest.NewRecorder()
+	Handler().ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	body, err := io.ReadAll(w.Body)
+	require.NoError(t, err)
+	return w.Code, string(body)
+}
+
+func TestObserve(t *testing.T) {
+	ObserveHTTPRequest(http.MethodGet, "/api/admin/users/:fid", 200, 20*time.Millisecond)
+	ObserveGameAPI("gift_code", OutcomeAPIError, time.Second)
+	ObserveCaptcha("ali", 300*time.Millisecond, errors.New("timeout"))
+	ObserveJobRun("GetCodeJob", 2*time.Second)
+	ObserveNotification("webhook", "failed")
+	ObserveQuery("select", time.Millisecond)
+
+	code, body := scrape(t)
+	require.Equal(t, http.StatusOK, code)
+	for _, line := range []string{
+		`cdk_get_http_requests_total{method="GET",route="/api/admin/users/:fid",status="200"} 1`,
+		`cdk_get_game_api_requests_total{endpoint="gift_code",outcome="api_error"} 1`,
+		`cdk_get_captcha_recognitions_total{outcome="error",provider="ali"} 1`,
+		`cdk_get_job_run_duration_seconds_count{job="GetCodeJob"} 1`,
+		`cdk_get_notifications_sent_total{channel="webhook",status="failed"} 1`,
+		`cdk_get_db_query_duration_seconds_count{operation="select"} 1`,
+		`go_goroutines`,
+	} {
+		assert.Contains(t, body, line)
+	}
+}
+
+func TestPendingTasks(t *testing.T) {
+	defer SetPendingTasksSource(nil)
+
+	_, body := scrape(t)
+	assert.NotContains(t, body, "cdk_get_pending_tasks")
+
+	SetPendingTasksSource(func(ctx context.Context) (int, error) { return 3, nil })
+	_, body = scrape(t)
+	assert.Contains(t, body, "cdk_get_pending_tasks 3")
+
+	// 统计失败时其余指标仍然输出
+	SetPendingTasksSource(func(ctx context.Context) (int, error) { return 0, errors.New("database is locked") })
+	code, body := scrape(t)
+	assert.Equal(t, http.StatusOK, code)
+	assert.NotContains(t, body, "cdk_get_pending_tasks")
+	assert.Contains(t, body, "go_goroutines")
+}
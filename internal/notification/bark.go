@@ -0,0 +1,85 @@
+package notification
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+)
+
+// DefaultBarkServer is the public Bark server
+const DefaultBarkServer = "https://api.day.app"
+
+// BarkNotifier implements Notifier for Bark iOS push
+type BarkNotifier struct {
+	name      string
+	server    string
+	deviceKey string
+	client    *http.Client
+	logger    *logrus.Logger
+}
+
+// barkResponse represents the Bark server response
+type barkResponse struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+// NewBarkNotifier creates a new Bark notifier.
+// An empty server uses DefaultBarkServer.
+func NewBarkNotifier(name, server, deviceKey string, logger *logrus.Logger) *BarkNotifier {
+	if server == "" {
+		server = DefaultBarkServer
+	}
+	return &BarkNotifier{
+		name:      name,
+		server:    strings.TrimRight(server, "/"),
+		deviceKey: deviceKey,
+		client:    newHTTPClient(),
+		logger:    logger,
+	}
+}
+
+// Send implements Notifier.Send
+func (b *BarkNotifier) Send(ctx context.Context, req NotificationRequest) (*NotificationResult, error) {
+	payload := map[string]string{
+		"device_key": b.deviceKey,
+		"title":      req.Title,
+		"body":       req.Content,
+		"group":      "cdk-get",
+	}
+
+	respBody, err := postJSON(ctx, b.client, b.server+"/push", payload, nil)
+	if err != nil {
+		b.logger.WithError(err).WithField("channel", b.name).Error("Bark request failed")
+		return failure(err)
+	}
+
+	var resp barkResponse
+	if err := json.Unmarshal(respBody, &resp); err != nil {
+		return failure(fmt.Errorf("failed to parse Bark response: %w", err))
+	}
+	if resp.Code != http.StatusOK {
+		return failure(fmt.Errorf("Bark API error: code=%d, message=%s", resp.Code, resp.Message))
+	}
+
+	b.logger.WithFields(logrus.Fields{
+		"channel": b.name,
+		"title":   req.Title,
+	}).Info("notification sent successfully via Bark")
+
+	return success(resp.Message)
+}
+
+// GetChannel implements Notifier.GetChannel
+func (b *BarkNotifier) GetChannel() string {
+	return b.name
+}
+
+// Format implements Notifier.Format
+func (b *BarkNotifier) Format() Format {
+	return FormatPlain
+}
@@ -0,0 +1,43 @@
+package notification
+
+import (
+	"fmt"
+	"html"
+	"strings"
+)
+
+// Digest merges notifications queued for one channel into a single message.
+// Each request is already rendered in format; a single request is returned as is.
+func Digest(reqs []NotificationRequest, format Format) NotificationRequest {
+	if len(reqs) == 1 {
+		return reqs[0]
+	}
+
+	sections := make([]string, 0, len(reqs))
+	for _, req := range reqs {
+		switch format {
+		case FormatHTML:
+			sections = append(sections, fmt.Sprintf("<h3>%s</h3>\n%s", html.EscapeString(req.Title), req.Content))
+		case FormatMarkdown:
+			sections = append(sections, fmt.Sprintf("### %s\n\n%s", req.Title, req.Content))
+		default:
+			sections = append(sections, fmt.Sprintf("【%s】\n%s", req.Title, req.Content))
+		}
+	}
+
+	var separator string
+	switch format {
+	case FormatHTML:
+		separator = "\n<hr/>\n"
+	case FormatMarkdown:
+		separator = "\n\n---\n\n"
+	default:
+		separator = "\n\n"
+	}
+
+	return NotificationRequest{
+		Title:   fmt.Sprintf("通知汇总（%d 条）", len(reqs)),
+		Summary: fmt.Sprintf("%s 等 %d 条通知", reqs[0].Summary, len(reqs)),
+		Content: strings.Join(sections, separator),
+	}
+}
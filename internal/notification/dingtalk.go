@@ -0,0 +1,112 @@
+package notification
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// DingTalkNotifier implements Notifier for DingTalk custom robots
+type DingTalkNotifier struct {
+	name    string
+	webhook string
+	secret  string
+	client  *http.Client
+	logger  *logrus.Logger
+}
+
+// dingTalkResponse represents the DingTalk robot API response
+type dingTalkResponse struct {
+	ErrCode int    `json:"errcode"`
+	ErrMsg  string `json:"errmsg"`
+}
+
+// NewDingTalkNotifier creates a new DingTalk notifier.
+// When secret is set the robot's "加签" security setting is used.
+func NewDingTalkNotifier(name, webhook, secret string, logger *logrus.Logger) *DingTalkNotifier {
+	return &DingTalkNotifier{
+		name:    name,
+		webhook: webhook,
+		secret:  secret,
+		client:  newHTTPClient(),
+		logger:  logger,
+	}
+}
+
+// Send implements Notifier.Send
+func (d *DingTalkNotifier) Send(ctx context.Context, req NotificationRequest) (*NotificationResult, error) {
+	target := d.webhook
+	if d.secret != "" {
+		signed, err := d.signURL(time.Now())
+		if err != nil {
+			return failure(err)
+		}
+		target = signed
+	}
+
+	payload := map[string]any{
+		"msgtype": "markdown",
+		"markdown": map[string]string{
+			"title": req.Title,
+			"text":  "### " + req.Title + "\n\n" + req.Content,
+		},
+	}
+
+	respBody, err := postJSON(ctx, d.client, target, payload, nil)
+	if err != nil {
+		d.logger.WithError(err).WithField("channel", d.name).Error("DingTalk request failed")
+		return failure(err)
+	}
+
+	var resp dingTalkResponse
+	if err := json.Unmarshal(respBody, &resp); err != nil {
+		return failure(fmt.Errorf("failed to parse DingTalk response: %w", err))
+	}
+	if resp.ErrCode != 0 {
+		return failure(fmt.Errorf("DingTalk API error: errcode=%d, errmsg=%s", resp.ErrCode, resp.ErrMsg))
+	}
+
+	d.logger.WithFields(logrus.Fields{
+		"channel": d.name,
+		"title":   req.Title,
+	}).Info("notification sent successfully via DingTalk")
+
+	return success(resp.ErrMsg)
+}
+
+// GetChannel implements Notifier.GetChannel
+func (d *DingTalkNotifier) GetChannel() string {
+	return d.name
+}
+
+// Format implements Notifier.Format
+func (d *DingTalkNotifier) Format() Format {
+	return FormatMarkdown
+}
+
+// signURL appends the timestamp and sign query parameters required by signed robots
+func (d *DingTalkNotifier) signURL(now time.Time) (string, error) {
+	u, err := url.Parse(d.webhook)
+	if err != nil {
+		return "", fmt.Errorf("invalid DingTalk webhook: %w", err)
+	}
+
+	timestamp := strconv.FormatInt(now.UnixMilli(), 10)
+	mac := hmac.New(sha256.New, []byte(d.secret))
+	mac.Write([]byte(timestamp + "\n" + d.secret))
+
+	query := u.Query()
+	query.Set("timestamp", timestamp)
+	query.Set("sign", base64.StdEncoding.EncodeToString(mac.Sum(nil)))
+	u.RawQuery = query.Encode()
+	return u.String(), nil
+}
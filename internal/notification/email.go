@@ -0,0 +1,173 @@
+package notification
+
+import (
+	"bytes"
+	"cdk-get/internal/config"
+	"context"
+	"crypto/tls"
+	"encoding/base64"
+	"fmt"
+	"mime"
+	"net"
+	"net/smtp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// smtpTimeout bounds the whole SMTP conversation
+const smtpTimeout = 30 * time.Second
+
+// EmailNotifier implements Notifier by sending mail over SMTP
+type EmailNotifier struct {
+	name   string
+	smtp   config.SMTPConfig
+	to     []string
+	format Format
+	logger *logrus.Logger
+}
+
+// NewEmailNotifier creates a new email notifier sending to the given recipients.
+// An empty format sends HTML mail.
+func NewEmailNotifier(name string, smtpConfig config.SMTPConfig, to []string, format string, logger *logrus.Logger) *EmailNotifier {
+	return &EmailNotifier{
+		name:   name,
+		smtp:   smtpConfig,
+		to:     to,
+		format: formatOrDefault(format, FormatHTML),
+		logger: logger,
+	}
+}
+
+// Send implements Notifier.Send
+func (e *EmailNotifier) Send(ctx context.Context, req NotificationRequest) (*NotificationResult, error) {
+	if err := e.send(ctx, req); err != nil {
+		e.logger.WithError(err).WithField("channel", e.name).Error("email send failed")
+		return failure(err)
+	}
+
+	e.logger.WithFields(logrus.Fields{
+		"channel":    e.name,
+		"title":      req.Title,
+		"recipients": len(e.to),
+	}).Info("notification sent successfully via email")
+
+	return success(fmt.Sprintf("Mail sent to %d recipient(s)", len(e.to)))
+}
+
+func (e *EmailNotifier) send(ctx context.Context, req NotificationRequest) error {
+	host := e.smtp.Host
+	addr := net.JoinHostPort(host, strconv.Itoa(e.smtp.Port))
+
+	ctx, cancel := context.WithTimeout(ctx, smtpTimeout)
+	defer cancel()
+
+	dialer := &net.Dialer{}
+	conn, err := dialer.DialContext(ctx, "tcp", addr)
+	if err != nil {
+		return fmt.Errorf("failed to connect to SMTP server: %w", err)
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		_ = conn.SetDeadline(deadline)
+	}
+
+	// 465 端口使用 TLS 直连
+	implicitTLS := e.smtp.Port == 465
+	if implicitTLS {
+		conn = tls.Client(conn, &tls.Config{ServerName: host})
+	}
+
+	client, err := smtp.NewClient(conn, host)
+	if err != nil {
+		conn.Close()
+		return fmt.Errorf("failed to create SMTP client: %w", err)
+	}
+	defer client.Close()
+
+	if !implicitTLS {
+		if ok, _ := client.Extension("STARTTLS"); ok {
+			if err := client.StartTLS(&tls.Config{ServerName: host}); err != nil {
+				return fmt.Errorf("failed to start TLS: %w", err)
+			}
+		}
+	}
+
+	if e.smtp.Username != "" {
+		auth := smtp.PlainAuth("", e.smtp.Username, e.smtp.Password, host)
+		if err := client.Auth(auth); err != nil {
+			return fmt.Errorf("SMTP authentication failed: %w", err)
+		}
+	}
+
+	if err := client.Mail(e.smtp.From); err != nil {
+		return fmt.Errorf("MAIL FROM failed: %w", err)
+	}
+	for _, rcpt := range e.to {
+		if err := client.Rcpt(rcpt); err != nil {
+			return fmt.Errorf("RCPT TO %s failed: %w", rcpt, err)
+		}
+	}
+
+	w, err := client.Data()
+	if err != nil {
+		return fmt.Errorf("DATA failed: %w", err)
+	}
+	if _, err := w.Write(e.buildMessage(req)); err != nil {
+		return fmt.Errorf("failed to write message: %w", err)
+	}
+	if err := w.Close(); err != nil {
+		return fmt.Errorf("failed to finish message: %w", err)
+	}
+
+	return client.Quit()
+}
+
+// buildMessage builds a UTF-8 plain text or HTML MIME message
+func (e *EmailNotifier) buildMessage(req NotificationRequest) []byte {
+	var buf bytes.Buffer
+	buf.WriteString("From: " + e.smtp.From + "\r\n")
+	buf.WriteString("To: " + strings.Join(e.to, ", ") + "\r\n")
+	buf.WriteString("Subject: " + mime.BEncoding.Encode("UTF-8", req.Title) + "\r\n")
+	buf.WriteString("Date: " + time.Now().Format(time.RFC1123Z) + "\r\n")
+	buf.WriteString("MIME-Version: 1.0\r\n")
+	if e.format == FormatHTML {
+		buf.WriteString("Content-Type: text/html; charset=UTF-8\r\n")
+	} else {
+		buf.WriteString("Content-Type: text/plain; charset=UTF-8\r\n")
+	}
+	buf.WriteString("Content-Transfer-Encoding: base64\r\n")
+	buf.WriteString("\r\n")
+
+	encoded := base64.StdEncoding.EncodeToString([]byte(req.Content))
+	for len(encoded) > 76 {
+		buf.WriteString(encoded[:76] + "\r\n")
+		encoded = encoded[76:]
+	}
+	buf.WriteString(encoded + "\r\n")
+
+	return buf.Bytes()
+}
+
+// GetChannel implements Notifier.GetChannel
+func (e *EmailNotifier) GetChannel() string {
+	return e.name
+}
+
+// Format implements Notifier.Format
+func (e *EmailNotifier) Format() Format {
+	return e.format
+}
+
+// RecipientType implements Addressable.RecipientType
+func (e *EmailNotifier) RecipientType() string {
+	return config.ChannelTypeEmail
+}
+
+// WithRecipient implements Addressable.WithRecipient, sending to a single address
+func (e *EmailNotifier) WithRecipient(address string) Notifier {
+	copied := *e
+	copied.to = []string{address}
+	return &copied
+}
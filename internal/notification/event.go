@@ -0,0 +1,117 @@
+package notification
+
+import "fmt"
+
+// EventType identifies what happened and selects the routing rules and template
+type EventType string
+
+// 通知事件类型
+const (
+	EventTaskCompleted       EventType = "task_completed"        // 兑换码已为所有用户兑换完成
+	EventTaskFailed          EventType = "task_failed"           // 任务重试次数耗尽，不再自动重试
+	EventCodeNotFound        EventType = "code_not_found"        // 兑换码不存在
+	EventCaptchaProviderDown EventType = "captcha_provider_down" // 验证码识别服务连续失败
+	EventNewCodeDiscovered   EventType = "new_code_discovered"   // 新增兑换码任务
+	EventBackupFailed        EventType = "backup_failed"         // 数据备份失败，预留给备份任务，目前没有发布方
+	EventLoginNewIP          EventType = "login_new_ip"          // 管理员从新的IP登录
+	EventLoginLocked         EventType = "login_locked"          // 登录失败次数过多，IP 或用户名被临时锁定
+	EventUserCodeRedeemed    EventType = "user_code_redeemed"    // 兑换码已为某个用户兑换，仅发送给该用户的通知目标
+)
+
+// EventTypes lists every known event type
+var EventTypes = []EventType{
+	EventTaskCompleted,
+	EventTaskFailed,
+	EventCodeNotFound,
+	EventCaptchaProviderDown,
+	EventNewCodeDiscovered,
+	EventBackupFailed,
+	EventLoginNewIP,
+	EventLoginLocked,
+	EventUserCodeRedeemed,
+}
+
+// IsValid reports whether the event type is known
+func (t EventType) IsValid() bool {
+	for _, known := range EventTypes {
+		if t == known {
+			return true
+		}
+	}
+	return false
+}
+
+// DefaultSeverity returns the severity an event is published with
+func (t EventType) DefaultSeverity() Severity {
+	switch t {
+	case EventTaskFailed, EventCaptchaProviderDown, EventBackupFailed:
+		return SeverityCritical
+	case EventCodeNotFound, EventLoginNewIP, EventLoginLocked:
+		return SeverityWarning
+	default:
+		return SeverityInfo
+	}
+}
+
+// Severity orders events by importance
+type Severity string
+
+// 事件级别，由低到高
+const (
+	SeverityInfo     Severity = "info"
+	SeverityWarning  Severity = "warning"
+	SeverityCritical Severity = "critical"
+)
+
+// ParseSeverity parses a severity name; an empty name means info
+func ParseSeverity(s string) (Severity, error) {
+	switch Severity(s) {
+	case "":
+		return SeverityInfo, nil
+	case SeverityInfo, SeverityWarning, SeverityCritical:
+		return Severity(s), nil
+	default:
+		return "", fmt.Errorf("unknown severity: %s", s)
+	}
+}
+
+// rank returns the ordering of the severity, higher is more important
+func (s Severity) rank() int {
+	switch s {
+	case SeverityWarning:
+		return 1
+	case SeverityCritical:
+		return 2
+	default:
+		return 0
+	}
+}
+
+// AtLeast reports whether s is at least as important as min
+func (s Severity) AtLeast(min Severity) bool {
+	return s.rank() >= min.rank()
+}
+
+// Event is a typed notification published by the application.
+// Title, Summary and Content are the default texts; templates configured
+// for the event type override them and can reference Data.
+type Event struct {
+	Type     EventType
+	Severity Severity
+	Title    string
+	Summary  string
+	Content  string
+	Data     map[string]any
+}
+
+// NewEvent creates an event with the default severity of its type
+func NewEvent(eventType EventType, title, summary, content string, data map[string]any) Event {
+	return Event{
+		Type:     eventType,
+		Severity: eventType.DefaultSeverity(),
+		Title:    title,
+		Summary:  summary,
+		Content:  content,
+		Data:     data,
+	}
+}
@@ -0,0 +1,58 @@
+package notification
+
+import (
+	"cdk-get/internal/config"
+	"fmt"
+
+	"github.com/sirupsen/logrus"
+)
+
+// NewNotifiersFromConfig creates a notifier for every enabled channel in cfg.
+// The legacy wxpusher section is treated as an extra channel named "wxpusher".
+func NewNotifiersFromConfig(cfg config.NotificationConfig, logger *logrus.Logger) ([]Notifier, error) {
+	channels := cfg.EffectiveChannels()
+
+	notifiers := make([]Notifier, 0, len(channels))
+	seen := make(map[string]bool, len(channels))
+	for _, channel := range channels {
+		if channel.Disabled {
+			continue
+		}
+		if seen[channel.Name] {
+			return nil, fmt.Errorf("duplicate notification channel name: %s", channel.Name)
+		}
+		seen[channel.Name] = true
+
+		notifier, err := NewNotifier(channel.Name, channel, logger)
+		if err != nil {
+			return nil, err
+		}
+		notifiers = append(notifiers, notifier)
+	}
+
+	return notifiers, nil
+}
+
+// NewNotifier creates the notifier for a single channel
+func NewNotifier(name string, channel config.NotificationChannel, logger *logrus.Logger) (Notifier, error) {
+	switch channel.Type {
+	case config.ChannelTypeWxPusher:
+		return NewWxPusherNotifier(name, channel.URL, channel.Token, channel.Recipients, channel.Format, logger), nil
+	case config.ChannelTypeWebhook:
+		return NewWebhookNotifier(name, channel.URL, channel.Secret, channel.Format, logger), nil
+	case config.ChannelTypeEmail:
+		return NewEmailNotifier(name, channel.SMTP, channel.Recipients, channel.Format, logger), nil
+	case config.ChannelTypeTelegram:
+		return NewTelegramNotifier(name, channel.URL, channel.Token, channel.Recipients, logger), nil
+	case config.ChannelTypeDingTalk:
+		return NewDingTalkNotifier(name, channel.URL, channel.Secret, logger), nil
+	case config.ChannelTypeFeishu:
+		return NewFeishuNotifier(name, channel.URL, channel.Secret, logger), nil
+	case config.ChannelTypeServerChan:
+		return NewServerChanNotifier(name, channel.URL, channel.Token, logger), nil
+	case config.ChannelTypeBark:
+		return NewBarkNotifier(name, channel.URL, channel.Token, logger), nil
+	default:
+		return nil, fmt.Errorf("unknown notification channel type: %s", channel.Type)
+	}
+}
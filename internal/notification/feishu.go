@@ -0,0 +1,94 @@
+package notification
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// FeishuNotifier implements Notifier for Feishu/Lark custom bots
+type FeishuNotifier struct {
+	name    string
+	webhook string
+	secret  string
+	client  *http.Client
+	logger  *logrus.Logger
+}
+
+// feishuResponse represents the Feishu bot API response
+type feishuResponse struct {
+	Code int    `json:"code"`
+	Msg  string `json:"msg"`
+}
+
+// NewFeishuNotifier creates a new Feishu/Lark notifier.
+// When secret is set the bot's signature verification is used.
+func NewFeishuNotifier(name, webhook, secret string, logger *logrus.Logger) *FeishuNotifier {
+	return &FeishuNotifier{
+		name:    name,
+		webhook: webhook,
+		secret:  secret,
+		client:  newHTTPClient(),
+		logger:  logger,
+	}
+}
+
+// Send implements Notifier.Send
+func (f *FeishuNotifier) Send(ctx context.Context, req NotificationRequest) (*NotificationResult, error) {
+	payload := map[string]any{
+		"msg_type": "text",
+		"content": map[string]string{
+			"text": plainText(req),
+		},
+	}
+	if f.secret != "" {
+		timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+		payload["timestamp"] = timestamp
+		payload["sign"] = f.sign(timestamp)
+	}
+
+	respBody, err := postJSON(ctx, f.client, f.webhook, payload, nil)
+	if err != nil {
+		f.logger.WithError(err).WithField("channel", f.name).Error("Feishu request failed")
+		return failure(err)
+	}
+
+	var resp feishuResponse
+	if err := json.Unmarshal(respBody, &resp); err != nil {
+		return failure(fmt.Errorf("failed to parse Feishu response: %w", err))
+	}
+	if resp.Code != 0 {
+		return failure(fmt.Errorf("Feishu API error: code=%d, msg=%s", resp.Code, resp.Msg))
+	}
+
+	f.logger.WithFields(logrus.Fields{
+		"channel": f.name,
+		"title":   req.Title,
+	}).Info("notification sent successfully via Feishu")
+
+	return success(resp.Msg)
+}
+
+// GetChannel implements Notifier.GetChannel
+func (f *FeishuNotifier) GetChannel() string {
+	return f.name
+}
+
+// Format implements Notifier.Format
+func (f *FeishuNotifier) Format() Format {
+	return FormatPlain
+}
+
+// sign computes the Feishu bot signature, the key is "timestamp\nsecret" and the message is empty
+func (f *FeishuNotifier) sign(timestamp string) string {
+	mac := hmac.New(sha256.New, []byte(timestamp+"\n"+f.secret))
+	return base64.StdEncoding.EncodeToString(mac.Sum(nil))
+}
@@ -0,0 +1,39 @@
+package notification
+
+import (
+	"cdk-get/internal/config"
+	"html"
+	"strings"
+)
+
+// Format is the content format a channel renders
+type Format string
+
+// 通知内容格式
+const (
+	FormatPlain    Format = config.ContentFormatPlain
+	FormatMarkdown Format = config.ContentFormatMarkdown
+	FormatHTML     Format = config.ContentFormatHTML
+)
+
+// formatOrDefault returns the configured format, or def when none is set
+func formatOrDefault(configured string, def Format) Format {
+	if configured == "" {
+		return def
+	}
+	return Format(configured)
+}
+
+// ConvertPlain converts plain text content to the given format so that
+// line breaks survive rendering
+func ConvertPlain(text string, format Format) string {
+	switch format {
+	case FormatHTML:
+		return strings.ReplaceAll(html.EscapeString(text), "\n", "<br/>\n")
+	case FormatMarkdown:
+		// Markdown 中单个换行不会换行，行尾加两个空格强制换行
+		return strings.ReplaceAll(text, "\n", "  \n")
+	default:
+		return text
+	}
+}
@@ -0,0 +1,91 @@
+package notification
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"time"
+)
+
+// newHTTPClient creates the HTTP client shared by HTTP based notifiers
+func newHTTPClient() *http.Client {
+	return &http.Client{
+		Transport: &http.Transport{
+			DialContext: (&net.Dialer{
+				Timeout:   20 * time.Second,
+				KeepAlive: 30 * time.Second,
+			}).DialContext,
+			MaxIdleConns:          100,
+			IdleConnTimeout:       20 * time.Second,
+			TLSHandshakeTimeout:   10 * time.Second,
+			ExpectContinueTimeout: 3 * time.Second,
+		},
+		Timeout: 30 * time.Second,
+	}
+}
+
+// postJSON sends payload as a JSON POST request and returns the response body.
+// Responses with a status code >= 400 are returned as errors.
+func postJSON(ctx context.Context, client *http.Client, url string, payload any, headers map[string]string) ([]byte, error) {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal request: %w", err)
+	}
+	return post(ctx, client, url, "application/json", body, headers)
+}
+
+// post sends a POST request with the given content type and returns the response body
+func post(ctx context.Context, client *http.Client, url, contentType string, body []byte, headers map[string]string) ([]byte, error) {
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	httpReq.Header.Set("Content-Type", contentType)
+	for key, value := range headers {
+		httpReq.Header.Set(key, value)
+	}
+
+	resp, err := client.Do(httpReq)
+	if err != nil {
+		return nil, fmt.Errorf("request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response: %w", err)
+	}
+	if resp.StatusCode >= 400 {
+		return respBody, fmt.Errorf("request failed with status %d: %s", resp.StatusCode, string(respBody))
+	}
+	return respBody, nil
+}
+
+// failure builds a failed NotificationResult for err
+func failure(err error) (*NotificationResult, error) {
+	return &NotificationResult{
+		Success: false,
+		Message: err.Error(),
+		Error:   err,
+	}, err
+}
+
+// success builds a successful NotificationResult
+func success(message string) (*NotificationResult, error) {
+	return &NotificationResult{
+		Success: true,
+		Message: message,
+	}, nil
+}
+
+// plainText joins the title and content for channels without a separate title field
+func plainText(req NotificationRequest) string {
+	if req.Content == "" {
+		return req.Title
+	}
+	return req.Title + "\n\n" + req.Content
+}
@@ -11,6 +11,21 @@ type Notifier interface {
 
 	// GetChannel returns the channel name for this notifier
 	GetChannel() string
+
+	// Format returns the content format the channel renders
+	Format() Format
+}
+
+// Addressable is implemented by notifiers that can deliver to an individual
+// recipient, used to send personal notifications to account owners
+type Addressable interface {
+	Notifier
+
+	// RecipientType returns the notification target type the notifier accepts
+	RecipientType() string
+
+	// WithRecipient returns a copy of the notifier sending only to recipient
+	WithRecipient(recipient string) Notifier
 }
 
 // NotificationRequest contains notification parameters
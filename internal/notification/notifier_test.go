@@ -0,0 +1,332 @@
+package notification
+
+import (
+	"bufio"
+	"cdk-get/internal/config"
+	"context"
+	"encoding/json"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+)
+
+func testLogger() *logrus.Logger {
+	logger := logrus.New()
+	logger.SetLevel(logrus.ErrorLevel)
+	return logger
+}
+
+var testRequest = NotificationRequest{
+	Title:   "兑换码兑换成功",
+	Summary: "兑换码[GIFT2026]兑换成功",
+	Content: "fid:1001 结果: 兑换成功",
+}
+
+// jsonServer starts a server that records the last request body and replies with response
+func jsonServer(t *testing.T, status int, response string, check func(r *http.Request, body []byte)) *httptest.Server {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		if check != nil {
+			check(r, body)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(status)
+		_, _ = w.Write([]byte(response))
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestWxPusherNotifier(t *testing.T) {
+	server := jsonServer(t, 200, `{"code":1000,"msg":"处理成功","success":true}`, func(r *http.Request, body []byte) {
+		var req wxPusherRequest
+		if err := json.Unmarshal(body, &req); err != nil {
+			t.Fatalf("invalid request body: %v", err)
+		}
+		if req.AppToken != "AT_test" || len(req.UIDs) != 2 || req.ContentType != 3 {
+			t.Errorf("unexpected request: %+v", req)
+		}
+	})
+
+	notifier := NewWxPusherNotifier("wx", server.URL, "AT_test", []string{"UID_1", "UID_2"}, "markdown", testLogger())
+	result, err := notifier.Send(context.Background(), testRequest)
+	if err != nil || !result.Success {
+		t.Fatalf("expected success, got result=%+v err=%v", result, err)
+	}
+	if notifier.GetChannel() != "wx" {
+		t.Errorf("expected channel name wx, got %s", notifier.GetChannel())
+	}
+}
+
+func TestWebhookNotifier(t *testing.T) {
+	server := jsonServer(t, 200, `{}`, func(r *http.Request, body []byte) {
+		timestamp := r.Header.Get(WebhookTimestampHeader)
+		if r.Header.Get(WebhookSignatureHeader) != SignWebhook("s3cret", timestamp, body) {
+			t.Errorf("signature mismatch")
+		}
+		var payload webhookPayload
+		if err := json.Unmarshal(body, &payload); err != nil {
+			t.Fatalf("invalid payload: %v", err)
+		}
+		if payload.Title != testRequest.Title || payload.Channel != "hook" || payload.Format != FormatPlain {
+			t.Errorf("unexpected payload: %+v", payload)
+		}
+	})
+
+	notifier := NewWebhookNotifier("hook", server.URL, "s3cret", "", testLogger())
+	if result, err := notifier.Send(context.Background(), testRequest); err != nil || !result.Success {
+		t.Fatalf("expected success, got result=%+v err=%v", result, err)
+	}
+
+	failing := jsonServer(t, 500, `boom`, nil)
+	notifier = NewWebhookNotifier("hook", failing.URL, "", "", testLogger())
+	if result, err := notifier.Send(context.Background(), testRequest); err == nil || result.Success {
+		t.Errorf("expected failure on HTTP 500")
+	}
+}
+
+func TestAddressableNotifiers(t *testing.T) {
+	server := jsonServer(t, 200, `{"code":1000,"msg":"处理成功","success":true}`, func(r *http.Request, body []byte) {
+		var req wxPusherRequest
+		if err := json.Unmarshal(body, &req); err != nil {
+			t.Fatalf("invalid request body: %v", err)
+		}
+		if len(req.UIDs) != 1 || req.UIDs[0] != "UID_owner" {
+			t.Errorf("expected only the owner uid, got %v", req.UIDs)
+		}
+	})
+
+	wx := NewWxPusherNotifier("wx", server.URL, "AT_test", []string{"UID_admin"}, "", testLogger())
+	personal := wx.WithRecipient("UID_owner")
+	if result, err := personal.Send(context.Background(), testRequest); err != nil || !result.Success {
+		t.Fatalf("expected success, got result=%+v err=%v", result, err)
+	}
+	if len(wx.uids) != 1 || wx.uids[0] != "UID_admin" {
+		t.Errorf("WithRecipient must not modify the channel notifier, got %v", wx.uids)
+	}
+
+	// 个人 webhook 不使用渠道密钥签名
+	hook := jsonServer(t, 200, `{}`, func(r *http.Request, body []byte) {
+		if r.Header.Get(WebhookSignatureHeader) != "" {
+			t.Errorf("expected unsigned request to user webhook")
+		}
+	})
+	webhook := NewWebhookNotifier("hook", "http://127.0.0.1:1", "s3cret", "", testLogger())
+	if result, err := webhook.WithRecipient(hook.URL).Send(context.Background(), testRequest); err != nil || !result.Success {
+		t.Fatalf("expected success, got result=%+v err=%v", result, err)
+	}
+
+	var _ Addressable = wx
+	var _ Addressable = webhook
+	var _ Addressable = NewEmailNotifier("mail", config.SMTPConfig{}, nil, "", testLogger())
+	var _ Addressable = NewTelegramNotifier("tg", "", "token", nil, testLogger())
+}
+
+func TestDingTalkNotifier(t *testing.T) {
+	server := jsonServer(t, 200, `{"errcode":0,"errmsg":"ok"}`, func(r *http.Request, body []byte) {
+		query := r.URL.Query()
+		if query.Get("access_token") != "tok" || query.Get("timestamp") == "" || query.Get("sign") == "" {
+			t.Errorf("expected signed url, got %s", r.URL.RawQuery)
+		}
+		if !strings.Contains(string(body), `"msgtype":"markdown"`) {
+			t.Errorf("expected markdown message, got %s", body)
+		}
+	})
+
+	notifier := NewDingTalkNotifier("ding", server.URL+"/robot/send?access_token=tok", "SECxxx", testLogger())
+	if result, err := notifier.Send(context.Background(), testRequest); err != nil || !result.Success {
+		t.Fatalf("expected success, got result=%+v err=%v", result, err)
+	}
+
+	rejected := jsonServer(t, 200, `{"errcode":310000,"errmsg":"sign not match"}`, nil)
+	notifier = NewDingTalkNotifier("ding", rejected.URL, "", testLogger())
+	if _, err := notifier.Send(context.Background(), testRequest); err == nil || !strings.Contains(err.Error(), "sign not match") {
+		t.Errorf("expected API error, got %v", err)
+	}
+}
+
+func TestFeishuNotifier(t *testing.T) {
+	server := jsonServer(t, 200, `{"code":0,"msg":"success"}`, func(r *http.Request, body []byte) {
+		var payload map[string]any
+		if err := json.Unmarshal(body, &payload); err != nil {
+			t.Fatalf("invalid payload: %v", err)
+		}
+		if payload["msg_type"] != "text" || payload["sign"] == nil || payload["timestamp"] == nil {
+			t.Errorf("unexpected payload: %v", payload)
+		}
+	})
+
+	notifier := NewFeishuNotifier("lark", server.URL, "secret", testLogger())
+	if result, err := notifier.Send(context.Background(), testRequest); err != nil || !result.Success {
+		t.Fatalf("expected success, got result=%+v err=%v", result, err)
+	}
+}
+
+func TestTelegramNotifier(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/bot123:abc/sendMessage" {
+			t.Errorf("unexpected path: %s", r.URL.Path)
+		}
+		var payload map[string]string
+		_ = json.NewDecoder(r.Body).Decode(&payload)
+		if payload["chat_id"] == "bad" {
+			w.WriteHeader(400)
+			_, _ = w.Write([]byte(`{"ok":false,"description":"Bad Request: chat not found"}`))
+			return
+		}
+		_, _ = w.Write([]byte(`{"ok":true}`))
+	}))
+	defer server.Close()
+
+	notifier := NewTelegramNotifier("tg", server.URL, "123:abc", []string{"42", "43"}, testLogger())
+	if result, err := notifier.Send(context.Background(), testRequest); err != nil || !result.Success {
+		t.Fatalf("expected success, got result=%+v err=%v", result, err)
+	}
+
+	notifier = NewTelegramNotifier("tg", server.URL, "123:abc", []string{"42", "bad"}, testLogger())
+	if _, err := notifier.Send(context.Background(), testRequest); err == nil || !strings.Contains(err.Error(), "chat not found") {
+		t.Errorf("expected chat not found error, got %v", err)
+	}
+}
+
+func TestServerChanNotifier(t *testing.T) {
+	server := jsonServer(t, 200, `{"code":0,"message":""}`, func(r *http.Request, body []byte) {
+		if r.URL.Path != "/SCT123.send" {
+			t.Errorf("unexpected path: %s", r.URL.Path)
+		}
+		if !strings.Contains(string(body), "title=") || !strings.Contains(string(body), "desp=") {
+			t.Errorf("unexpected form: %s", body)
+		}
+	})
+
+	notifier := NewServerChanNotifier("sc", server.URL, "SCT123", testLogger())
+	if result, err := notifier.Send(context.Background(), testRequest); err != nil || !result.Success {
+		t.Fatalf("expected success, got result=%+v err=%v", result, err)
+	}
+}
+
+func TestBarkNotifier(t *testing.T) {
+	server := jsonServer(t, 200, `{"code":200,"message":"success"}`, func(r *http.Request, body []byte) {
+		var payload map[string]string
+		_ = json.Unmarshal(body, &payload)
+		if r.URL.Path != "/push" || payload["device_key"] != "devkey" {
+			t.Errorf("unexpected request: %s %v", r.URL.Path, payload)
+		}
+	})
+
+	notifier := NewBarkNotifier("bark", server.URL, "devkey", testLogger())
+	if result, err := notifier.Send(context.Background(), testRequest); err != nil || !result.Success {
+		t.Fatalf("expected success, got result=%+v err=%v", result, err)
+	}
+}
+
+// startSMTPStub starts a minimal SMTP server accepting one message and returns
+// its port and a channel receiving the DATA section
+func startSMTPStub(t *testing.T) (int, <-chan string) {
+	t.Helper()
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+	t.Cleanup(func() { listener.Close() })
+
+	data := make(chan string, 1)
+	go func() {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+
+		reader := bufio.NewReader(conn)
+		reply := func(line string) { _, _ = conn.Write([]byte(line + "\r\n")) }
+		reply("220 localhost ESMTP stub")
+		for {
+			line, err := reader.ReadString('\n')
+			if err != nil {
+				return
+			}
+			cmd := strings.ToUpper(strings.TrimSpace(line))
+			switch {
+			case strings.HasPrefix(cmd, "EHLO"), strings.HasPrefix(cmd, "HELO"):
+				reply("250 localhost")
+			case strings.HasPrefix(cmd, "MAIL"), strings.HasPrefix(cmd, "RCPT"):
+				reply("250 OK")
+			case cmd == "DATA":
+				reply("354 End data with <CR><LF>.<CR><LF>")
+				var msg strings.Builder
+				for {
+					l, err := reader.ReadString('\n')
+					if err != nil || l == ".\r\n" {
+						break
+					}
+					msg.WriteString(l)
+				}
+				data <- msg.String()
+				reply("250 OK")
+			case cmd == "QUIT":
+				reply("221 Bye")
+				return
+			default:
+				reply("250 OK")
+			}
+		}
+	}()
+
+	return listener.Addr().(*net.TCPAddr).Port, data
+}
+
+func TestEmailNotifier(t *testing.T) {
+	port, data := startSMTPStub(t)
+
+	notifier := NewEmailNotifier("mail", config.SMTPConfig{
+		Host: "127.0.0.1",
+		Port: port,
+		From: "bot@example.com",
+	}, []string{"officer@example.com"}, "", testLogger())
+
+	if result, err := notifier.Send(context.Background(), testRequest); err != nil || !result.Success {
+		t.Fatalf("expected success, got result=%+v err=%v", result, err)
+	}
+
+	msg := <-data
+	if !strings.Contains(msg, "To: officer@example.com") || !strings.Contains(msg, "Subject: =?UTF-8?b?") ||
+		!strings.Contains(msg, "Content-Type: text/html") {
+		t.Errorf("unexpected message headers:\n%s", msg)
+	}
+}
+
+func TestNewNotifiersFromConfig(t *testing.T) {
+	cfg := config.NotificationConfig{
+		WxPusher: config.WxPusherConfig{AppToken: "AT_x", UID: "UID_x"},
+		Channels: []config.NotificationChannel{
+			{Type: config.ChannelTypeBark, Token: "dev"},
+			{Name: "ops-hook", Type: config.ChannelTypeWebhook, URL: "http://127.0.0.1/hook"},
+			{Name: "off", Type: config.ChannelTypeTelegram, Token: "t", Recipients: []string{"1"}, Disabled: true},
+		},
+	}
+
+	notifiers, err := NewNotifiersFromConfig(cfg, testLogger())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	var names []string
+	for _, n := range notifiers {
+		names = append(names, n.GetChannel())
+	}
+	if strings.Join(names, ",") != "wxpusher,bark,ops-hook" {
+		t.Errorf("unexpected channels: %v", names)
+	}
+
+	cfg.Channels = append(cfg.Channels, config.NotificationChannel{Name: "ops-hook", Type: config.ChannelTypeBark, Token: "x"})
+	if _, err := NewNotifiersFromConfig(cfg, testLogger()); err == nil {
+		t.Errorf("expected duplicate channel name error")
+	}
+}
@@ -0,0 +1,304 @@
+package notification
+
+import (
+	"bytes"
+	"cdk-get/internal/config"
+	"errors"
+	"fmt"
+	htmltemplate "html/template"
+	"io"
+	"os"
+	"text/template"
+)
+
+// Router decides which channels receive an event and renders its message
+type Router struct {
+	routes    []route
+	templates map[EventType]eventTemplate
+	channels  []string
+}
+
+type route struct {
+	events      map[EventType]bool
+	minSeverity Severity
+	channels    []string
+}
+
+// eventTemplate holds the templates configured for one event type
+type eventTemplate struct {
+	base     templateSet
+	channels map[string]templateSet
+}
+
+// executor is implemented by both text/template and html/template templates
+type executor interface {
+	Execute(w io.Writer, data any) error
+}
+
+// templateSet is a parsed title, summary and per-format content template.
+// Nil or missing templates fall through to the next set.
+type templateSet struct {
+	title   *template.Template
+	summary *template.Template
+	content map[Format]executor
+}
+
+// NewRouter validates the routing rules and templates in cfg.
+// channels are the names of the enabled notifiers; rules may also name
+// disabled channels, which are skipped when routing.
+func NewRouter(cfg config.NotificationConfig, channels []string) (*Router, error) {
+	known := make(map[string]bool, len(cfg.Channels)+1)
+	known[config.ChannelTypeWxPusher] = cfg.WxPusher.AppToken != "" && cfg.WxPusher.UID != ""
+	for _, channel := range cfg.Channels {
+		name := channel.Name
+		if name == "" {
+			name = channel.Type
+		}
+		known[name] = true
+	}
+
+	enabled := make(map[string]bool, len(channels))
+	for _, name := range channels {
+		enabled[name] = true
+	}
+
+	r := &Router{
+		templates: make(map[EventType]eventTemplate, len(cfg.Templates)),
+		channels:  channels,
+	}
+
+	for i, rc := range cfg.Routes {
+		minSeverity, err := ParseSeverity(rc.MinSeverity)
+		if err != nil {
+			return nil, fmt.Errorf("notification route %d: %w", i, err)
+		}
+
+		rt := route{minSeverity: minSeverity}
+		if len(rc.Events) > 0 {
+			rt.events = make(map[EventType]bool, len(rc.Events))
+			for _, name := range rc.Events {
+				eventType := EventType(name)
+				if !eventType.IsValid() {
+					return nil, fmt.Errorf("notification route %d: unknown event type: %s", i, name)
+				}
+				rt.events[eventType] = true
+			}
+		}
+		for _, name := range rc.Channels {
+			if !known[name] {
+				return nil, fmt.Errorf("notification route %d: unknown channel: %s", i, name)
+			}
+			if enabled[name] {
+				rt.channels = append(rt.channels, name)
+			}
+		}
+		r.routes = append(r.routes, rt)
+	}
+
+	for name, tc := range cfg.Templates {
+		eventType := EventType(name)
+		if !eventType.IsValid() {
+			return nil, fmt.Errorf("notification template: unknown event type: %s", name)
+		}
+
+		base, err := parseTemplateSet(name, tc)
+		if err != nil {
+			return nil, err
+		}
+		et := eventTemplate{base: base}
+
+		for channel, override := range tc.Channels {
+			if !known[channel] {
+				return nil, fmt.Errorf("notification template %s: unknown channel: %s", name, channel)
+			}
+			if len(override.Channels) > 0 {
+				return nil, fmt.Errorf("notification template %s.%s: channel templates cannot be nested", name, channel)
+			}
+			set, err := parseTemplateSet(name+"."+channel, override)
+			if err != nil {
+				return nil, err
+			}
+			if et.channels == nil {
+				et.channels = make(map[string]templateSet)
+			}
+			et.channels[channel] = set
+		}
+		r.templates[eventType] = et
+	}
+
+	return r, nil
+}
+
+// parseTemplateSet parses the templates of one config entry, loading the
+// content templates from files when configured
+func parseTemplateSet(name string, tc config.NotificationTemplate) (templateSet, error) {
+	var (
+		set templateSet
+		err error
+	)
+	if set.title, err = parseText(name+".title", tc.Title); err != nil {
+		return set, err
+	}
+	if set.summary, err = parseText(name+".summary", tc.Summary); err != nil {
+		return set, err
+	}
+
+	set.content = make(map[Format]executor)
+	for _, c := range []struct {
+		format Format
+		text   string
+		file   string
+	}{
+		{FormatPlain, tc.Content, tc.ContentFile},
+		{FormatMarkdown, tc.Markdown, tc.MarkdownFile},
+		{FormatHTML, tc.HTML, tc.HTMLFile},
+	} {
+		text := c.text
+		if c.file != "" {
+			data, err := os.ReadFile(c.file)
+			if err != nil {
+				return set, fmt.Errorf("notification template %s: %w", name, err)
+			}
+			text = string(data)
+		}
+		if text == "" {
+			continue
+		}
+
+		tmplName := fmt.Sprintf("%s.%s", name, c.format)
+		var tmpl executor
+		if c.format == FormatHTML {
+			tmpl, err = htmltemplate.New(tmplName).Option("missingkey=zero").Funcs(htmltemplate.FuncMap(templateFuncs)).Parse(text)
+		} else {
+			tmpl, err = template.New(tmplName).Option("missingkey=zero").Funcs(templateFuncs).Parse(text)
+		}
+		if err != nil {
+			return set, fmt.Errorf("notification template %s: %w", tmplName, err)
+		}
+		set.content[c.format] = tmpl
+	}
+	return set, nil
+}
+
+// parseText parses a text template; empty text yields nil
+func parseText(name, text string) (*template.Template, error) {
+	if text == "" {
+		return nil, nil
+	}
+	tmpl, err := template.New(name).Option("missingkey=zero").Funcs(templateFuncs).Parse(text)
+	if err != nil {
+		return nil, fmt.Errorf("notification template %s: %w", name, err)
+	}
+	return tmpl, nil
+}
+
+// Channels returns the names of the channels that should receive the event.
+// Without routing rules every event goes to every channel.
+func (r *Router) Channels(event Event) []string {
+	if len(r.routes) == 0 {
+		return r.channels
+	}
+
+	seen := make(map[string]bool)
+	var channels []string
+	for _, rt := range r.routes {
+		if rt.events != nil && !rt.events[event.Type] {
+			continue
+		}
+		if !event.Severity.AtLeast(rt.minSeverity) {
+			continue
+		}
+		for _, name := range rt.channels {
+			if !seen[name] {
+				seen[name] = true
+				channels = append(channels, name)
+			}
+		}
+	}
+	return channels
+}
+
+// Render builds the notification of the event for a channel.
+// Templates are looked up in order: the channel override, the event template,
+// then the built-in template. For the content, a template in the channel's
+// format is preferred over a plain text one, which is converted to the format.
+// Fields without a usable template keep the event's default text; template
+// execution errors are returned after falling back.
+func (r *Router) Render(event Event, channel string, format Format) (NotificationRequest, error) {
+	var sets []templateSet
+	if et, ok := r.templates[event.Type]; ok {
+		if set, ok := et.channels[channel]; ok {
+			sets = append(sets, set)
+		}
+		sets = append(sets, et.base)
+	}
+	if builtin, ok := builtinTemplates[event.Type]; ok && event.Data[builtin.requires] != nil {
+		sets = append(sets, builtin.set)
+	}
+
+	var errs []error
+	req := NotificationRequest{
+		Title:   event.Title,
+		Summary: event.Summary,
+		Content: ConvertPlain(event.Content, format),
+	}
+
+	for _, set := range sets {
+		if set.title == nil {
+			continue
+		}
+		if text, err := execute(set.title, event); err != nil {
+			errs = append(errs, err)
+		} else {
+			req.Title = text
+			break
+		}
+	}
+
+	for _, set := range sets {
+		if set.summary == nil {
+			continue
+		}
+		if text, err := execute(set.summary, event); err != nil {
+			errs = append(errs, err)
+		} else {
+			req.Summary = text
+			break
+		}
+	}
+
+	formats := []Format{format}
+	if format != FormatPlain {
+		formats = append(formats, FormatPlain)
+	}
+content:
+	for _, set := range sets {
+		for _, f := range formats {
+			tmpl, ok := set.content[f]
+			if !ok {
+				continue
+			}
+			text, err := execute(tmpl, event)
+			if err != nil {
+				errs = append(errs, err)
+				continue
+			}
+			if f != format {
+				text = ConvertPlain(text, format)
+			}
+			req.Content = text
+			break content
+		}
+	}
+
+	return req, errors.Join(errs...)
+}
+
+// execute runs a template against data and returns the output
+func execute(tmpl executor, data any) (string, error) {
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, data); err != nil {
+		return "", err
+	}
+	return buf.String(), nil
+}
@@ -0,0 +1,202 @@
+package notification
+
+import (
+	"cdk-get/internal/config"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestRouterChannels(t *testing.T) {
+	cfg := config.NotificationConfig{
+		Channels: []config.NotificationChannel{
+			{Name: "ops", Type: config.ChannelTypeWebhook},
+			{Name: "mail", Type: config.ChannelTypeEmail},
+			{Name: "off", Type: config.ChannelTypeBark, Disabled: true},
+		},
+		Routes: []config.NotificationRoute{
+			{Events: []string{string(EventTaskCompleted)}, Channels: []string{"mail"}},
+			{MinSeverity: string(SeverityWarning), Channels: []string{"ops", "off"}},
+		},
+	}
+
+	router, err := NewRouter(cfg, []string{"ops", "mail"})
+	if err != nil {
+		t.Fatalf("NewRouter() error = %v", err)
+	}
+
+	tests := []struct {
+		event EventType
+		want  []string
+	}{
+		{EventTaskCompleted, []string{"mail"}},
+		{EventNewCodeDiscovered, nil},
+		{EventCodeNotFound, []string{"ops"}},
+		{EventTaskFailed, []string{"ops"}},
+	}
+	for _, tt := range tests {
+		got := router.Channels(NewEvent(tt.event, "", "", "", nil))
+		if !slices.Equal(got, tt.want) {
+			t.Errorf("Channels(%s) = %v, want %v", tt.event, got, tt.want)
+		}
+	}
+
+	// 未配置路由规则时发送到所有渠道
+	router, err = NewRouter(config.NotificationConfig{}, []string{"ops", "mail"})
+	if err != nil {
+		t.Fatalf("NewRouter() error = %v", err)
+	}
+	if got := router.Channels(NewEvent(EventLoginNewIP, "", "", "", nil)); !slices.Equal(got, []string{"ops", "mail"}) {
+		t.Errorf("Channels() without routes = %v, want all channels", got)
+	}
+}
+
+func TestNewRouterValidation(t *testing.T) {
+	channels := []config.NotificationChannel{{Name: "ops", Type: config.ChannelTypeWebhook}}
+	tests := []struct {
+		name string
+		cfg  config.NotificationConfig
+	}{
+		{"unknown event", config.NotificationConfig{
+			Channels: channels,
+			Routes:   []config.NotificationRoute{{Events: []string{"nope"}, Channels: []string{"ops"}}},
+		}},
+		{"unknown channel", config.NotificationConfig{
+			Channels: channels,
+			Routes:   []config.NotificationRoute{{Channels: []string{"missing"}}},
+		}},
+		{"unknown severity", config.NotificationConfig{
+			Channels: channels,
+			Routes:   []config.NotificationRoute{{MinSeverity: "urgent", Channels: []string{"ops"}}},
+		}},
+		{"bad template", config.NotificationConfig{
+			Templates: map[string]config.NotificationTemplate{string(EventTaskCompleted): {Title: "{{.Title"}},
+		}},
+		{"template for unknown event", config.NotificationConfig{
+			Templates: map[string]config.NotificationTemplate{"nope": {Title: "x"}},
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := NewRouter(tt.cfg, []string{"ops"}); err == nil {
+				t.Error("NewRouter() expected error")
+			}
+		})
+	}
+}
+
+func TestRouterRender(t *testing.T) {
+	htmlFile := filepath.Join(t.TempDir(), "completed.html")
+	if err := os.WriteFile(htmlFile, []byte(`<b>{{.Data.code}}</b>`), 0o644); err != nil {
+		t.Fatalf("write template file: %v", err)
+	}
+
+	cfg := config.NotificationConfig{
+		Channels: []config.NotificationChannel{{Name: "ops", Type: config.ChannelTypeWebhook}},
+		Templates: map[string]config.NotificationTemplate{
+			string(EventTaskCompleted): {
+				Title:    "[{{.Severity}}] {{.Data.code}} 完成",
+				Content:  "code={{.Data.code}}\nok",
+				HTMLFile: htmlFile,
+				Channels: map[string]config.NotificationTemplate{
+					"ops": {Title: "ops {{.Data.code}}"},
+				},
+			},
+		},
+	}
+	router, err := NewRouter(cfg, []string{"ops"})
+	if err != nil {
+		t.Fatalf("NewRouter() error = %v", err)
+	}
+
+	event := NewEvent(EventTaskCompleted, "默认标题", "默认摘要", "默认内容", map[string]any{"code": "<GIFT>"})
+	tests := []struct {
+		name    string
+		channel string
+		format  Format
+		title   string
+		content string
+	}{
+		{"plain", "mail", FormatPlain, "[info] <GIFT> 完成", "code=<GIFT>\nok"},
+		{"markdown falls back to plain", "mail", FormatMarkdown, "[info] <GIFT> 完成", "code=<GIFT>  \nok"},
+		{"html template from file", "mail", FormatHTML, "[info] <GIFT> 完成", "<b>&lt;GIFT&gt;</b>"},
+		{"channel override", "ops", FormatPlain, "ops <GIFT>", "code=<GIFT>\nok"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, err := router.Render(event, tt.channel, tt.format)
+			if err != nil {
+				t.Fatalf("Render() error = %v", err)
+			}
+			if req.Title != tt.title || req.Content != tt.content {
+				t.Errorf("Render() = %q / %q, want %q / %q", req.Title, req.Content, tt.title, tt.content)
+			}
+			if req.Summary != "默认摘要" {
+				t.Errorf("Summary = %q, want default", req.Summary)
+			}
+		})
+	}
+
+	// 没有模板的事件使用默认文本，并按格式转换
+	req, _ := router.Render(NewEvent(EventCodeNotFound, "标题", "摘要", "a<b\nc", nil), "ops", FormatHTML)
+	if req.Title != "标题" || req.Content != "a&lt;b<br/>\nc" {
+		t.Errorf("Render() default = %+v", req)
+	}
+}
+
+func TestRouterRenderResultTable(t *testing.T) {
+	router, err := NewRouter(config.NotificationConfig{}, nil)
+	if err != nil {
+		t.Fatalf("NewRouter() error = %v", err)
+	}
+
+	event := SampleEvent(EventTaskCompleted, map[string]any{
+		"results": []RedeemResult{{FID: "1001", Nickname: "a|b", KID: 7, Result: "兑换成功"}},
+	})
+
+	req, err := router.Render(event, "", FormatMarkdown)
+	if err != nil {
+		t.Fatalf("Render() error = %v", err)
+	}
+	if !strings.Contains(req.Content, "| 1001 | a\\|b | 7 | 兑换成功 |") {
+		t.Errorf("markdown table missing row:\n%s", req.Content)
+	}
+
+	req, _ = router.Render(event, "", FormatHTML)
+	if !strings.Contains(req.Content, "<tr><td>1001</td><td>a|b</td><td>7</td><td>兑换成功</td></tr>") {
+		t.Errorf("html table missing row:\n%s", req.Content)
+	}
+
+	req, _ = router.Render(event, "", FormatPlain)
+	if !strings.Contains(req.Content, "1001 | a|b | 7 | 兑换成功") {
+		t.Errorf("plain table missing row:\n%s", req.Content)
+	}
+}
+
+func TestDigest(t *testing.T) {
+	reqs := []NotificationRequest{
+		{Title: "兑换码兑换成功", Summary: "兑换码[A]兑换成功", Content: "fid:1 结果: 兑换成功"},
+		{Title: "兑换码 <B>", Summary: "兑换码[B]兑换成功", Content: "fid:2 结果: 兑换成功"},
+	}
+
+	if got := Digest(reqs[:1], FormatPlain); got != reqs[0] {
+		t.Errorf("Digest() of a single request = %+v, want it unchanged", got)
+	}
+
+	got := Digest(reqs, FormatPlain)
+	if got.Title != "通知汇总（2 条）" || got.Summary != "兑换码[A]兑换成功 等 2 条通知" {
+		t.Errorf("unexpected digest title/summary: %+v", got)
+	}
+	if !strings.Contains(got.Content, "【兑换码兑换成功】\nfid:1") || !strings.Contains(got.Content, "fid:2 结果") {
+		t.Errorf("plain digest missing sections: %q", got.Content)
+	}
+
+	if got := Digest(reqs, FormatMarkdown); !strings.Contains(got.Content, "### 兑换码兑换成功") || !strings.Contains(got.Content, "---") {
+		t.Errorf("unexpected markdown digest: %q", got.Content)
+	}
+	if got := Digest(reqs, FormatHTML); !strings.Contains(got.Content, "<h3>兑换码 &lt;B&gt;</h3>") || !strings.Contains(got.Content, "<hr/>") {
+		t.Errorf("unexpected html digest: %q", got.Content)
+	}
+}
@@ -0,0 +1,78 @@
+package notification
+
+import "fmt"
+
+// SampleEvent returns an event of the given type filled with example data,
+// used to preview templates. Keys in data override the example data.
+func SampleEvent(eventType EventType, data map[string]any) Event {
+	sample := map[string]any{}
+	var event Event
+
+	switch eventType {
+	case EventTaskCompleted:
+		results := []RedeemResult{
+			{FID: "366184723", Nickname: "示例玩家", KID: 100, Result: "兑换成功"},
+			{FID: "366184724", Nickname: "另一位玩家", KID: 101, Result: "兑换码已兑换"},
+		}
+		sample["code"] = "SAMPLE2026"
+		sample["fids"] = len(results)
+		sample["results"] = results
+		event = NewEvent(eventType, "兑换码兑换成功", "兑换码[SAMPLE2026]兑换成功", "fid:366184723, 昵称:示例玩家, 区服:100 结果: 兑换成功", sample)
+	case EventTaskFailed:
+		sample["code"] = "SAMPLE2026"
+		sample["retry_count"] = 5
+		sample["error"] = "验证码错误"
+		event = NewEvent(eventType, "兑换码任务失败", "兑换码[SAMPLE2026]重试5次仍失败", "兑换码: SAMPLE2026\n重试次数: 5\n最后错误: 验证码错误", sample)
+	case EventCodeNotFound:
+		sample["code"] = "SAMPLE2026"
+		event = NewEvent(eventType, "兑换码不存在", "兑换码[SAMPLE2026]不存在", "兑换码:SAMPLE2026 不存在", sample)
+	case EventCaptchaProviderDown:
+		sample["provider"] = "ali"
+		sample["failures"] = 5
+		sample["error"] = "request timeout"
+		event = NewEvent(eventType, "验证码识别服务异常", "验证码识别服务[ali]连续失败5次", "服务: ali\n连续失败次数: 5\n最后错误: request timeout", sample)
+	case EventNewCodeDiscovered:
+		sample["code"] = "SAMPLE2026"
+		sample["source"] = "admin"
+		event = NewEvent(eventType, "发现新兑换码", "新增兑换码[SAMPLE2026]", "兑换码: SAMPLE2026\n来源: admin", sample)
+	case EventBackupFailed:
+		sample["path"] = "./backup/giftcode.db"
+		sample["error"] = "disk full"
+		event = NewEvent(eventType, "数据备份失败", "数据备份失败: disk full", "备份文件: ./backup/giftcode.db\n错误: disk full", sample)
+	case EventLoginNewIP:
+		sample["username"] = "admin"
+		sample["ip"] = "203.0.113.7"
+		event = NewEvent(eventType, "管理员从新的IP登录", "管理员[admin]从新的IP 203.0.113.7 登录", "用户名: admin\nIP: 203.0.113.7", sample)
+	case EventLoginLocked:
+		sample["kind"] = "username"
+		sample["identity"] = "admin"
+		sample["ip"] = "203.0.113.7"
+		sample["locked_until"] = "2026-01-01 12:15:00"
+		event = NewEvent(eventType, "管理员登录已锁定", "用户名[admin]登录失败次数过多，已锁定至 2026-01-01 12:15:00", "锁定对象: 用户名 admin\n最近失败来源IP: 203.0.113.7\n锁定至: 2026-01-01 12:15:00", sample)
+	case EventUserCodeRedeemed:
+		sample["code"] = "SAMPLE2026"
+		sample["fid"] = "366184723"
+		sample["nickname"] = "示例玩家"
+		sample["kid"] = 100
+		sample["result"] = "兑换成功"
+		event = NewEvent(eventType, "兑换码兑换结果", "示例玩家 兑换码[SAMPLE2026]兑换成功", "兑换码: SAMPLE2026\n角色: 示例玩家 (fid:366184723, 区服:100)\n结果: 兑换成功", sample)
+	default:
+		event = NewEvent(eventType, string(eventType), string(eventType), fmt.Sprintf("示例事件: %s", eventType), sample)
+	}
+
+	for key, value := range data {
+		event.Data[key] = value
+	}
+	return event
+}
+
+// Preview is an event rendered for one channel
+type Preview struct {
+	Channel string `json:"channel"`
+	Format  Format `json:"format"`
+	Routed  bool   `json:"routed"` // 事件按路由规则是否会发送到该渠道
+	Title   string `json:"title"`
+	Summary string `json:"summary"`
+	Content string `json:"content"`
+	Error   string `json:"error,omitempty"` // 模板执行错误，出错的字段使用默认文本
+}
@@ -0,0 +1,87 @@
+package notification
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+)
+
+// DefaultServerChanAPI is the ServerChan Turbo API base URL
+const DefaultServerChanAPI = "https://sctapi.ftqq.com"
+
+// ServerChanNotifier implements Notifier for ServerChan (Server酱)
+type ServerChanNotifier struct {
+	name    string
+	apiBase string
+	sendKey string
+	client  *http.Client
+	logger  *logrus.Logger
+}
+
+// serverChanResponse represents the ServerChan API response
+type serverChanResponse struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+// NewServerChanNotifier creates a new ServerChan notifier.
+// An empty apiBase uses DefaultServerChanAPI.
+func NewServerChanNotifier(name, apiBase, sendKey string, logger *logrus.Logger) *ServerChanNotifier {
+	if apiBase == "" {
+		apiBase = DefaultServerChanAPI
+	}
+	return &ServerChanNotifier{
+		name:    name,
+		apiBase: strings.TrimRight(apiBase, "/"),
+		sendKey: sendKey,
+		client:  newHTTPClient(),
+		logger:  logger,
+	}
+}
+
+// Send implements Notifier.Send
+func (s *ServerChanNotifier) Send(ctx context.Context, req NotificationRequest) (*NotificationResult, error) {
+	form := url.Values{}
+	form.Set("title", req.Title)
+	form.Set("desp", req.Content)
+	if req.Summary != "" {
+		form.Set("short", req.Summary)
+	}
+
+	endpoint := fmt.Sprintf("%s/%s.send", s.apiBase, s.sendKey)
+	respBody, err := post(ctx, s.client, endpoint, "application/x-www-form-urlencoded", []byte(form.Encode()), nil)
+	if err != nil {
+		s.logger.WithError(err).WithField("channel", s.name).Error("ServerChan request failed")
+		return failure(err)
+	}
+
+	var resp serverChanResponse
+	if err := json.Unmarshal(respBody, &resp); err != nil {
+		return failure(fmt.Errorf("failed to parse ServerChan response: %w", err))
+	}
+	if resp.Code != 0 {
+		return failure(fmt.Errorf("ServerChan API error: code=%d, message=%s", resp.Code, resp.Message))
+	}
+
+	s.logger.WithFields(logrus.Fields{
+		"channel": s.name,
+		"title":   req.Title,
+	}).Info("notification sent successfully via ServerChan")
+
+	return success(resp.Message)
+}
+
+// GetChannel implements Notifier.GetChannel
+func (s *ServerChanNotifier) GetChannel() string {
+	return s.name
+}
+
+// Format implements Notifier.Format
+func (s *ServerChanNotifier) Format() Format {
+	return FormatMarkdown
+}
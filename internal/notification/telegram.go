@@ -0,0 +1,124 @@
+package notification
+
+import (
+	"cdk-get/internal/config"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+)
+
+// DefaultTelegramAPI is the Telegram Bot API base URL
+const DefaultTelegramAPI = "https://api.telegram.org"
+
+// TelegramNotifier implements Notifier for Telegram bots
+type TelegramNotifier struct {
+	name    string
+	apiBase string
+	token   string
+	chatIDs []string
+	client  *http.Client
+	logger  *logrus.Logger
+}
+
+// telegramResponse represents the Telegram Bot API response
+type telegramResponse struct {
+	OK          bool   `json:"ok"`
+	Description string `json:"description"`
+}
+
+// NewTelegramNotifier creates a new Telegram notifier sending to chatIDs.
+// An empty apiBase uses DefaultTelegramAPI.
+func NewTelegramNotifier(name, apiBase, token string, chatIDs []string, logger *logrus.Logger) *TelegramNotifier {
+	if apiBase == "" {
+		apiBase = DefaultTelegramAPI
+	}
+	return &TelegramNotifier{
+		name:    name,
+		apiBase: strings.TrimRight(apiBase, "/"),
+		token:   token,
+		chatIDs: chatIDs,
+		client:  newHTTPClient(),
+		logger:  logger,
+	}
+}
+
+// Send implements Notifier.Send, the message is sent to every chat
+func (t *TelegramNotifier) Send(ctx context.Context, req NotificationRequest) (*NotificationResult, error) {
+	endpoint := fmt.Sprintf("%s/bot%s/sendMessage", t.apiBase, t.token)
+	text := plainText(req)
+
+	var errs []error
+	for _, chatID := range t.chatIDs {
+		if err := t.sendTo(ctx, endpoint, chatID, text); err != nil {
+			t.logger.WithError(err).WithFields(logrus.Fields{
+				"channel": t.name,
+				"chat_id": chatID,
+			}).Error("Telegram request failed")
+			errs = append(errs, fmt.Errorf("chat %s: %w", chatID, err))
+		}
+	}
+	if len(errs) > 0 {
+		return failure(errors.Join(errs...))
+	}
+
+	t.logger.WithFields(logrus.Fields{
+		"channel": t.name,
+		"title":   req.Title,
+		"chats":   len(t.chatIDs),
+	}).Info("notification sent successfully via Telegram")
+
+	return success(fmt.Sprintf("Sent to %d chat(s)", len(t.chatIDs)))
+}
+
+func (t *TelegramNotifier) sendTo(ctx context.Context, endpoint, chatID, text string) error {
+	payload := map[string]string{
+		"chat_id": chatID,
+		"text":    text,
+	}
+
+	respBody, err := postJSON(ctx, t.client, endpoint, payload, nil)
+	if err != nil {
+		// Telegram returns the reason in the body of 4xx responses
+		var resp telegramResponse
+		if json.Unmarshal(respBody, &resp) == nil && resp.Description != "" {
+			return errors.New(resp.Description)
+		}
+		return err
+	}
+
+	var resp telegramResponse
+	if err := json.Unmarshal(respBody, &resp); err != nil {
+		return fmt.Errorf("failed to parse Telegram response: %w", err)
+	}
+	if !resp.OK {
+		return fmt.Errorf("Telegram API error: %s", resp.Description)
+	}
+	return nil
+}
+
+// GetChannel implements Notifier.GetChannel
+func (t *TelegramNotifier) GetChannel() string {
+	return t.name
+}
+
+// Format implements Notifier.Format
+func (t *TelegramNotifier) Format() Format {
+	return FormatPlain
+}
+
+// RecipientType implements Addressable.RecipientType
+func (t *TelegramNotifier) RecipientType() string {
+	return config.ChannelTypeTelegram
+}
+
+// WithRecipient implements Addressable.WithRecipient, sending to a single chat
+func (t *TelegramNotifier) WithRecipient(chatID string) Notifier {
+	copied := *t
+	copied.chatIDs = []string{chatID}
+	return &copied
+}
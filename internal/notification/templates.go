@@ -0,0 +1,62 @@
+package notification
+
+import (
+	"fmt"
+	htmltemplate "html/template"
+	"strings"
+	"text/template"
+)
+
+// RedeemResult is the outcome of redeeming a code for one fid.
+// GetCodeJob attaches them to task_completed events as Data["results"].
+type RedeemResult struct {
+	FID      string
+	Nickname string
+	KID      int
+	Result   string
+}
+
+// templateFuncs are available to every notification template
+var templateFuncs = template.FuncMap{
+	// mdcell 转义 Markdown 表格单元格中的竖线和换行
+	"mdcell": func(v any) string {
+		s := strings.ReplaceAll(fmt.Sprint(v), "|", "\\|")
+		return strings.ReplaceAll(s, "\n", " ")
+	},
+}
+
+// builtinTemplate is used when neither the channel nor the event has a
+// template, provided the event carries the Data key it requires
+type builtinTemplate struct {
+	requires string
+	set      templateSet
+}
+
+// builtinTemplates render the per-fid result table of completed tasks
+var builtinTemplates = map[EventType]builtinTemplate{
+	EventTaskCompleted: {
+		requires: "results",
+		set: templateSet{
+			content: map[Format]executor{
+				FormatPlain: template.Must(template.New("task_completed.plain").Funcs(templateFuncs).Parse(
+					`兑换码 {{.Data.code}} 兑换结果:
+FID | 昵称 | 区服 | 结果
+{{range .Data.results}}{{.FID}} | {{.Nickname}} | {{.KID}} | {{.Result}}
+{{end}}`)),
+				FormatMarkdown: template.Must(template.New("task_completed.markdown").Funcs(templateFuncs).Parse(
+					`兑换码 **{{.Data.code}}** 兑换结果:
+
+| FID | 昵称 | 区服 | 结果 |
+| --- | --- | --- | --- |
+{{range .Data.results}}| {{mdcell .FID}} | {{mdcell .Nickname}} | {{.KID}} | {{mdcell .Result}} |
+{{end}}`)),
+				FormatHTML: htmltemplate.Must(htmltemplate.New("task_completed.html").Parse(
+					`<p>兑换码 <b>{{.Data.code}}</b> 兑换结果:</p>
+<table border="1" cellpadding="4" cellspacing="0">
+<tr><th>FID</th><th>昵称</th><th>区服</th><th>结果</th></tr>
+{{range .Data.results}}<tr><td>{{.FID}}</td><td>{{.Nickname}}</td><td>{{.KID}}</td><td>{{.Result}}</td></tr>
+{{end}}</table>`)),
+			},
+		},
+	},
+}
@@ -0,0 +1,125 @@
+package notification
+
+import (
+	"cdk-get/internal/config"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// Webhook signature headers.
+// X-Signature is "sha256=" + hex(HMAC-SHA256(secret, timestamp + "." + body)).
+const (
+	WebhookTimestampHeader = "X-Signature-Timestamp"
+	WebhookSignatureHeader = "X-Signature"
+)
+
+// WebhookNotifier implements Notifier by POSTing a JSON payload to a URL
+type WebhookNotifier struct {
+	name   string
+	url    string
+	secret string
+	format Format
+	client *http.Client
+	logger *logrus.Logger
+}
+
+// webhookPayload is the JSON body sent to the webhook
+type webhookPayload struct {
+	Channel   string `json:"channel"`
+	Title     string `json:"title"`
+	Summary   string `json:"summary"`
+	Content   string `json:"content"`
+	Format    Format `json:"format"`
+	Timestamp int64  `json:"timestamp"`
+}
+
+// NewWebhookNotifier creates a new webhook notifier.
+// Requests are signed when secret is not empty. An empty format sends plain text.
+func NewWebhookNotifier(name, url, secret, format string, logger *logrus.Logger) *WebhookNotifier {
+	return &WebhookNotifier{
+		name:   name,
+		url:    url,
+		secret: secret,
+		format: formatOrDefault(format, FormatPlain),
+		client: newHTTPClient(),
+		logger: logger,
+	}
+}
+
+// Send implements Notifier.Send
+func (w *WebhookNotifier) Send(ctx context.Context, req NotificationRequest) (*NotificationResult, error) {
+	timestamp := time.Now().Unix()
+	body, err := json.Marshal(webhookPayload{
+		Channel:   w.name,
+		Title:     req.Title,
+		Summary:   req.Summary,
+		Content:   req.Content,
+		Format:    w.format,
+		Timestamp: timestamp,
+	})
+	if err != nil {
+		return failure(fmt.Errorf("failed to marshal request: %w", err))
+	}
+
+	headers := map[string]string{}
+	if w.secret != "" {
+		ts := strconv.FormatInt(timestamp, 10)
+		headers[WebhookTimestampHeader] = ts
+		headers[WebhookSignatureHeader] = SignWebhook(w.secret, ts, body)
+	}
+
+	if _, err := post(ctx, w.client, w.url, "application/json", body, headers); err != nil {
+		w.logger.WithError(err).WithField("channel", w.name).Error("webhook request failed")
+		return failure(err)
+	}
+
+	w.logger.WithFields(logrus.Fields{
+		"channel": w.name,
+		"title":   req.Title,
+	}).Info("notification sent successfully via webhook")
+
+	return success("Webhook delivered")
+}
+
+// GetChannel implements Notifier.GetChannel
+func (w *WebhookNotifier) GetChannel() string {
+	return w.name
+}
+
+// Format implements Notifier.Format
+func (w *WebhookNotifier) Format() Format {
+	return w.format
+}
+
+// RecipientType implements Addressable.RecipientType
+func (w *WebhookNotifier) RecipientType() string {
+	return config.ChannelTypeWebhook
+}
+
+// WithRecipient implements Addressable.WithRecipient, posting to the given URL.
+// The copy is unsigned so the channel secret is never used for user URLs.
+func (w *WebhookNotifier) WithRecipient(url string) Notifier {
+	copied := *w
+	copied.url = url
+	copied.secret = ""
+	return &copied
+}
+
+// SignWebhook computes the X-Signature header value for a webhook body,
+// receivers can use it to verify the request
+func SignWebhook(secret, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
@@ -2,21 +2,27 @@ package notification
 
 import (
 	"bytes"
+	"cdk-get/internal/config"
 	"context"
 	"encoding/json"
 	"fmt"
 	"io"
-	"net"
 	"net/http"
 	"time"
 
 	"github.com/sirupsen/logrus"
 )
 
+// DefaultWxPusherURL is the WxPusher message API endpoint
+const DefaultWxPusherURL = "https://wxpusher.zjiecode.com/api/send/message"
+
 // WxPusherNotifier implements Notifier for WxPusher
 type WxPusherNotifier struct {
+	name     string
+	apiURL   string
 	appToken string
-	uid      string
+	uids     []string
+	format   Format
 	client   *http.Client
 	logger   *logrus.Logger
 }
@@ -41,25 +47,20 @@ type wxPusherResponse struct {
 	Success bool   `json:"success"`
 }
 
-// NewWxPusherNotifier creates a new WxPusher notifier
-func NewWxPusherNotifier(appToken, uid string, logger *logrus.Logger) *WxPusherNotifier {
+// NewWxPusherNotifier creates a new WxPusher notifier sending to uids.
+// An empty apiURL uses DefaultWxPusherURL and an empty format sends HTML.
+func NewWxPusherNotifier(name, apiURL, appToken string, uids []string, format string, logger *logrus.Logger) *WxPusherNotifier {
+	if apiURL == "" {
+		apiURL = DefaultWxPusherURL
+	}
 	return &WxPusherNotifier{
+		name:     name,
+		apiURL:   apiURL,
 		appToken: appToken,
-		uid:      uid,
-		client: &http.Client{
-			Transport: &http.Transport{
-				DialContext: (&net.Dialer{
-					Timeout:   20 * time.Second,
-					KeepAlive: 30 * time.Second,
-				}).DialContext,
-				MaxIdleConns:          100,
-				IdleConnTimeout:       20 * time.Second,
-				TLSHandshakeTimeout:   10 * time.Second,
-				ExpectContinueTimeout: 3 * time.Second,
-			},
-			Timeout: 30 * time.Second,
-		},
-		logger: logger,
+		uids:     uids,
+		format:   formatOrDefault(format, FormatHTML),
+		client:   newHTTPClient(),
+		logger:   logger,
 	}
 }
 
@@ -71,8 +72,8 @@ func (w *WxPusherNotifier) Send(ctx context.Context, req NotificationRequest) (*
 		Content:       req.Content,
 		Summary:       req.Summary,
 		Title:         req.Title,
-		ContentType:   2, // HTML content type
-		UIDs:          []string{w.uid},
+		ContentType:   wxPusherContentType(w.format),
+		UIDs:          w.uids,
 		VerifyPay:     false,
 		VerifyPayType: 0,
 	}
@@ -87,23 +88,22 @@ func (w *WxPusherNotifier) Send(ctx context.Context, req NotificationRequest) (*
 		}, err
 	}
 
-	// Create HTTP request
-	httpReq, err := http.NewRequestWithContext(ctx, "POST", "https://wxpusher.zjiecode.com/api/send/message", bytes.NewBuffer(jsonBody))
-	if err != nil {
-		w.logger.WithError(err).Error("failed to create WxPusher HTTP request")
-		return &NotificationResult{
-			Success: false,
-			Message: fmt.Sprintf("Failed to create request: %v", err),
-			Error:   err,
-		}, err
-	}
-
-	httpReq.Header.Set("Content-Type", "application/json")
-
 	// Send request with retry logic
 	var lastErr error
 	maxRetries := 3
 	for attempt := 1; attempt <= maxRetries; attempt++ {
+		// Create HTTP request, the body can only be read once per attempt
+		httpReq, err := http.NewRequestWithContext(ctx, "POST", w.apiURL, bytes.NewReader(jsonBody))
+		if err != nil {
+			w.logger.WithError(err).Error("failed to create WxPusher HTTP request")
+			return &NotificationResult{
+				Success: false,
+				Message: fmt.Sprintf("Failed to create request: %v", err),
+				Error:   err,
+			}, err
+		}
+		httpReq.Header.Set("Content-Type", "application/json")
+
 		resp, err := w.client.Do(httpReq)
 		if err != nil {
 			lastErr = err
@@ -195,5 +195,34 @@ func (w *WxPusherNotifier) Send(ctx context.Context, req NotificationRequest) (*
 
 // GetChannel implements Notifier.GetChannel
 func (w *WxPusherNotifier) GetChannel() string {
-	return "wxpusher"
+	return w.name
+}
+
+// Format implements Notifier.Format
+func (w *WxPusherNotifier) Format() Format {
+	return w.format
+}
+
+// RecipientType implements Addressable.RecipientType
+func (w *WxPusherNotifier) RecipientType() string {
+	return config.ChannelTypeWxPusher
+}
+
+// WithRecipient implements Addressable.WithRecipient, sending to a single UID
+func (w *WxPusherNotifier) WithRecipient(uid string) Notifier {
+	copied := *w
+	copied.uids = []string{uid}
+	return &copied
+}
+
+// wxPusherContentType maps a format to the WxPusher contentType field
+func wxPusherContentType(format Format) int {
+	switch format {
+	case FormatHTML:
+		return 2
+	case FormatMarkdown:
+		return 3
+	default:
+		return 1
+	}
 }
@@ -15,14 +15,17 @@ import (
 	"github.com/sirupsen/logrus"
 )
 
+// defaultWorkerPoolSize 未配置并发数时批量兑换的默认并发数
+const defaultWorkerPoolSize = 5
+
 // RedeemResult 兑换结果
 type RedeemResult struct {
-	FID      string
-	Code     string
-	Success  bool
-	Message  string
-	Nickname string
-	Kid      int
+	FID      string `json:"fid"`
+	Code     string `json:"code"`
+	Success  bool   `json:"success"`
+	Message  string `json:"message"`
+	Nickname string `json:"nickname,omitempty"`
+	Kid      int    `json:"kid,omitempty"`
 }
 
 // GiftService 礼品码服务
@@ -31,29 +34,44 @@ type GiftService struct {
 	keyStorage  storage.KeyStorage // 用于 PlayerGiftCode 的存储接口
 	captchaPool *captcha.CaptchaPool
 	httpClient  *http.Client
+	apiBaseURL  string // 兑换接口的地址前缀
+	workers     int    // 批量兑换的并发数
 	logger      *logrus.Logger
 	playerCache sync.Map        // 缓存 PlayerGiftCode 实例
 	userCache   *cache.LRUCache // 用户信息缓存 (10分钟TTL)
 }
 
 // NewGiftService 创建礼品码服务
+// apiBaseURL 为兑换接口的地址前缀，workerPoolSize 为批量兑换的并发数，小于等于 0 时使用默认值
 func NewGiftService(
 	repo storage.Repository,
 	keyStorage storage.KeyStorage,
 	captchaPool *captcha.CaptchaPool,
 	httpClient *http.Client,
+	apiBaseURL string,
+	workerPoolSize int,
 	logger *logrus.Logger,
 ) *GiftService {
+	if workerPoolSize <= 0 {
+		workerPoolSize = defaultWorkerPoolSize
+	}
 	return &GiftService{
 		repo:        repo,
 		keyStorage:  keyStorage,
 		captchaPool: captchaPool,
 		httpClient:  httpClient,
+		apiBaseURL:  apiBaseURL,
+		workers:     workerPoolSize,
 		logger:      logger,
 		userCache:   cache.NewLRUCache(10 * time.Minute),
 	}
 }
 
+// CanRedeem 验证码池是否可用，不可用时只能查询玩家资料，无法兑换
+func (s *GiftService) CanRedeem() bool {
+	return s.captchaPool != nil
+}
+
 // RedeemGiftCode 兑换单个礼品码
 func (s *GiftService) RedeemGiftCode(ctx context.Context, fid, code string) (*RedeemResult, error) {
 	// 添加日志上下文
@@ -89,6 +107,9 @@ func (s *GiftService) RedeemGiftCode(ctx context.Context, fid, code string) (*Re
 
 	// 执行兑换
 	result, err := player.GetGift(code)
+	if err := s.repo.RecordRedeemAttempt(ctx, NewRedeemAttempt(fid, code, result, err)); err != nil {
+		log.WithError(err).Warn("failed to record redeem attempt")
+	}
 	if err != nil {
 		log.WithError(err).Error("failed to get gift")
 		return &RedeemResult{
@@ -147,6 +168,22 @@ func (s *GiftService) RedeemGiftCode(ctx context.Context, fid, code string) (*Re
 // 为所有用户兑换指定的礼品码
 // 使用 worker pool 限制并发数
 func (s *GiftService) BatchRedeemGiftCode(ctx context.Context, fids []string, code string, workerPoolSize int) ([]*RedeemResult, error) {
+	results := make([]*RedeemResult, 0, len(fids))
+	for result := range s.redeemEach(ctx, fids, code, workerPoolSize) {
+		results = append(results, result)
+	}
+	return results, nil
+}
+
+// StreamRedeemGiftCode 以配置的并发数为每个 fid 兑换礼品码，每完成一个就从返回的 channel 发送其结果
+// 全部完成后关闭 channel；ctx 取消后不再开始新的兑换，进行中的兑换完成后关闭 channel
+// 调用方需要读完 channel
+func (s *GiftService) StreamRedeemGiftCode(ctx context.Context, fids []string, code string) <-chan *RedeemResult {
+	return s.redeemEach(ctx, fids, code, s.workers)
+}
+
+// redeemEach 使用 worker pool 并发兑换，按完成顺序发送结果
+func (s *GiftService) redeemEach(ctx context.Context, fids []string, code string, workerPoolSize int) <-chan *RedeemResult {
 	log := s.logger.WithFields(logrus.Fields{
 		"operation":        "batch_redeem_gift_code",
 		"code":             code,
@@ -158,54 +195,61 @@ func (s *GiftService) BatchRedeemGiftCode(ctx context.Context, fids []string, co
 
 	// 如果 workerPoolSize 未指定或无效，使用默认值
 	if workerPoolSize <= 0 {
-		workerPoolSize = 5
+		workerPoolSize = defaultWorkerPoolSize
 	}
 
-	results := make([]*RedeemResult, 0, len(fids))
-	var mu sync.Mutex
-
-	// 创建 semaphore channel 限制并发数
-	semaphore := make(chan struct{}, workerPoolSize)
-
-	// 使用 WaitGroup 等待所有兑换完成
-	var wg sync.WaitGroup
+	results := make(chan *RedeemResult, workerPoolSize)
 
-	for _, fid := range fids {
-		wg.Add(1)
+	go func() {
+		// 创建 semaphore channel 限制并发数
+		semaphore := make(chan struct{}, workerPoolSize)
 
-		// 获取 semaphore
-		semaphore <- struct{}{}
+		// 使用 WaitGroup 等待所有兑换完成
+		var wg sync.WaitGroup
+		count := 0
 
-		go func(fid string) {
-			defer wg.Done()
-			defer func() { <-semaphore }() // 释放 semaphore
-
-			result, err := s.RedeemGiftCode(ctx, fid, code)
-			if err != nil {
-				log.WithFields(logrus.Fields{
-					"fid":   fid,
-					"error": err,
-				}).Error("failed to redeem gift code for fid")
+	dispatch:
+		for _, fid := range fids {
+			// 获取 semaphore，ctx 取消后停止分派
+			select {
+			case semaphore <- struct{}{}:
+			case <-ctx.Done():
+				log.WithField("dispatched", count).Warn("batch redemption canceled")
+				break dispatch
+			}
 
-				result = &RedeemResult{
-					FID:     fid,
-					Code:    code,
-					Success: false,
-					Message: fmt.Sprintf("兑换失败: %v", err),
+			wg.Add(1)
+			count++
+			go func(fid string) {
+				defer wg.Done()
+				defer func() { <-semaphore }() // 释放 semaphore
+
+				result, err := s.RedeemGiftCode(ctx, fid, code)
+				if err != nil {
+					log.WithFields(logrus.Fields{
+						"fid":   fid,
+						"error": err,
+					}).Error("failed to redeem gift code for fid")
+
+					result = &RedeemResult{
+						FID:     fid,
+						Code:    code,
+						Success: false,
+						Message: fmt.Sprintf("兑换失败: %v", err),
+					}
 				}
-			}
 
-			mu.Lock()
-			results = append(results, result)
-			mu.Unlock()
-		}(fid)
-	}
+				results <- result
+			}(fid)
+		}
 
-	wg.Wait()
+		wg.Wait()
+		close(results)
 
-	log.WithField("result_count", len(results)).Info("batch redemption completed")
+		log.WithField("result_count", count).Info("batch redemption completed")
+	}()
 
-	return results, nil
+	return results
 }
 
 // getOrCreatePlayer 获取或创建 PlayerGiftCode 实例
@@ -216,8 +260,12 @@ func (s *GiftService) getOrCreatePlayer(ctx context.Context, fid string) (*giftc
 		return cached.(*giftcode.PlayerGiftCode), nil
 	}
 
+	if s.captchaPool == nil {
+		return nil, fmt.Errorf("captcha pool is not configured")
+	}
+
 	// 创建新实例
-	player := giftcode.NewPlayerGiftCode(fid, s.captchaPool.Get, s.keyStorage)
+	player := giftcode.NewPlayerGiftCode(s.apiBaseURL, fid, s.captchaPool.Get, s.keyStorage)
 
 	// 初始化
 	if err := player.InitWithContext(ctx); err != nil {
@@ -236,17 +284,24 @@ func (s *GiftService) getOrCreatePlayer(ctx context.Context, fid string) (*giftc
 }
 
 // GetUserInfo 获取用户信息（带缓存）
+// fid 不存在时返回的错误可用 errors.Is(err, giftcode.ErrPlayerNotFound) 判断
 func (s *GiftService) GetUserInfo(ctx context.Context, fid string) (*giftcode.DdPlayerMsg, error) {
 	// 尝试从缓存获取
 	if cached, ok := s.userCache.Get(fid); ok {
 		return cached.(*giftcode.DdPlayerMsg), nil
 	}
 
-	// 缓存未命中，从数据库或API获取
-	player, err := s.getOrCreatePlayer(ctx, fid)
+	return s.RefreshUserInfo(ctx, fid)
+}
+
+// RefreshUserInfo 跳过缓存，直接通过玩家接口获取最新的用户信息
+func (s *GiftService) RefreshUserInfo(ctx context.Context, fid string) (*giftcode.DdPlayerMsg, error) {
+	player, err := giftcode.FetchPlayerInfo(ctx, s.apiBaseURL, fid)
 	if err != nil {
+		s.userCache.Delete(fid)
 		return nil, err
 	}
 
-	return player.Player, nil
+	s.userCache.Set(fid, player)
+	return player, nil
 }
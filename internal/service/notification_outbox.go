@@ -0,0 +1,299 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"cdk-get/internal/events"
+	"cdk-get/internal/metrics"
+	"cdk-get/internal/notification"
+	"cdk-get/internal/storage"
+
+	"github.com/sirupsen/logrus"
+)
+
+// outboxBatchSize 每批投递的通知数量
+const outboxBatchSize = 20
+
+// Start launches the worker delivering pending notifications.
+// Notifications left pending by a previous run are delivered first.
+func (s *NotificationService) Start() {
+	if s == nil || s.cancel != nil {
+		return
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	s.cancel = cancel
+	s.done = make(chan struct{})
+	go s.run(ctx)
+
+	s.logger.WithFields(logrus.Fields{
+		"max_attempts":  s.outbox.MaxAttempts,
+		"poll_interval": s.outbox.PollInterval,
+	}).Info("notification outbox worker started")
+}
+
+// Stop stops the delivery worker and waits for the current batch to finish.
+// Notifications not yet delivered stay pending and are sent after restart.
+func (s *NotificationService) Stop() {
+	if s == nil || s.cancel == nil {
+		return
+	}
+	s.cancel()
+	<-s.done
+	s.cancel = nil
+}
+
+// Wake asks the delivery worker to check for due notifications now
+func (s *NotificationService) Wake() {
+	if s == nil {
+		return
+	}
+	select {
+	case s.wake <- struct{}{}:
+	default:
+	}
+}
+
+func (s *NotificationService) run(ctx context.Context) {
+	defer close(s.done)
+
+	ticker := time.NewTicker(s.outbox.PollInterval)
+	defer ticker.Stop()
+
+	for {
+		s.deliverDue(ctx)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		case <-s.wake:
+		}
+	}
+}
+
+// deliverDue delivers due notifications in batches until none are left
+func (s *NotificationService) deliverDue(ctx context.Context) {
+	for ctx.Err() == nil {
+		now := time.Now()
+		due, err := s.repository.ListDueNotifications(ctx, now, outboxBatchSize)
+		if err != nil {
+			s.logger.WithError(err).Error("failed to list due notifications")
+			return
+		}
+		if len(due) == 0 {
+			return
+		}
+
+		deliveries := s.collectBatches(ctx, due, now)
+
+		delivered := make([]bool, len(deliveries))
+		var wg sync.WaitGroup
+		for i, batch := range deliveries {
+			wg.Add(1)
+			go func(i int, batch []*storage.Notification) {
+				defer wg.Done()
+				delivered[i] = s.deliver(ctx, batch)
+			}(i, batch)
+		}
+		wg.Wait()
+
+		// 逐条更新，避免并发写入 SQLite；被停止打断的投递保持原状
+		for i, batch := range deliveries {
+			if !delivered[i] {
+				continue
+			}
+			for _, notif := range batch {
+				metrics.ObserveNotification(notif.Channel, notif.Status)
+				if err := s.repository.UpdateNotificationDelivery(context.Background(), notif); err != nil {
+					s.logger.WithFields(logrus.Fields{
+						"id":      notif.ID,
+						"channel": notif.Channel,
+						"error":   err.Error(),
+					}).Error("failed to save notification delivery")
+					continue
+				}
+				s.events.Publish(events.NotificationDelivered, notificationEventData(notif))
+			}
+		}
+
+		if len(due) < outboxBatchSize {
+			return
+		}
+	}
+}
+
+// collectBatches groups due notifications into deliveries. A notification
+// with a batch key is delivered together with every due notification of its
+// digest batch, including those beyond the current page.
+func (s *NotificationService) collectBatches(ctx context.Context, due []*storage.Notification, now time.Time) [][]*storage.Notification {
+	var deliveries [][]*storage.Notification
+	seen := make(map[string]bool)
+	for _, notif := range due {
+		if notif.BatchKey == "" {
+			deliveries = append(deliveries, []*storage.Notification{notif})
+			continue
+		}
+		if seen[notif.BatchKey] {
+			continue
+		}
+		seen[notif.BatchKey] = true
+
+		batch, err := s.repository.ListDueBatch(ctx, notif.BatchKey, now)
+		if err != nil || len(batch) == 0 {
+			if err != nil {
+				s.logger.WithFields(logrus.Fields{
+					"batch_key": notif.BatchKey,
+					"error":     err.Error(),
+				}).Error("failed to list digest batch")
+			}
+			// 退回为仅投递本页中属于该批次的通知
+			batch = nil
+			for _, other := range due {
+				if other.BatchKey == notif.BatchKey {
+					batch = append(batch, other)
+				}
+			}
+		}
+		deliveries = append(deliveries, batch)
+	}
+	return deliveries
+}
+
+// deliver sends a queued notification, or a digest batch merged into one
+// message, through its channel and updates the status, result, attempt
+// count and next attempt time of every notification in the batch.
+// It returns false, leaving the batch unchanged, when Stop interrupted the send.
+func (s *NotificationService) deliver(ctx context.Context, batch []*storage.Notification) bool {
+	first := batch[0]
+	log := s.logger.WithFields(logrus.Fields{
+		"id":      first.ID,
+		"title":   first.Title,
+		"channel": first.Channel,
+		"fid":     first.FID,
+		"attempt": first.Attempts + 1,
+		"batch":   len(batch),
+	})
+	// 尝试次数按条计算，重试中的通知与新通知合并投递时互不影响
+	update := func(status, result string, sentAt, next *time.Time) {
+		for _, notif := range batch {
+			notif.Attempts++
+			notif.Status = status
+			notif.Result = result
+			notif.SentAt = sentAt
+			notif.NextAttemptAt = next
+		}
+	}
+
+	notifier := s.deliveryNotifier(first)
+	if notifier == nil {
+		update(storage.NotificationStatusFailed, "Channel is not configured", nil, nil)
+		log.Error("notification channel is not configured")
+		return true
+	}
+
+	reqs := make([]notification.NotificationRequest, 0, len(batch))
+	for _, notif := range batch {
+		reqs = append(reqs, notification.NotificationRequest{
+			Title:   notif.Title,
+			Summary: notif.Summary,
+			Content: notif.Content,
+		})
+	}
+
+	log.Info("sending notification")
+	result, err := notifier.Send(ctx, notification.Digest(reqs, notifier.Format()))
+
+	if err == nil && (result == nil || result.Success) {
+		now := time.Now()
+		message := "Notification sent successfully"
+		if result != nil && result.Message != "" {
+			message = result.Message
+		}
+		if len(batch) > 1 {
+			message = fmt.Sprintf("Sent in digest of %d: %s", len(batch), message)
+		}
+		update(storage.NotificationStatusSuccess, message, &now, nil)
+		log.WithField("result", message).Info("notification sent successfully")
+		return true
+	}
+
+	// 停止时被取消的发送不计入尝试次数，保持待发送状态在重启后投递
+	if ctx.Err() != nil {
+		log.Info("notification send interrupted by shutdown, left pending")
+		return false
+	}
+
+	var message string
+	if err != nil {
+		message = fmt.Sprintf("Error: %v", err)
+	} else if result.Message != "" {
+		message = result.Message
+	} else {
+		message = "Unknown error"
+	}
+
+	// 达到最大尝试次数的通知标记为失败，其余按各自的尝试次数退避后重试
+	now := time.Now()
+	retrying := 0
+	for _, notif := range batch {
+		notif.Attempts++
+		notif.Result = message
+		notif.SentAt = nil
+		if notif.Attempts >= s.outbox.MaxAttempts {
+			notif.Status = storage.NotificationStatusFailed
+			notif.NextAttemptAt = nil
+			continue
+		}
+		next := now.Add(s.backoff(notif.Attempts))
+		notif.Status = storage.NotificationStatusPending
+		notif.NextAttemptAt = &next
+		retrying++
+	}
+
+	if retrying == 0 {
+		log.WithField("result", message).Error("notification send failed, giving up")
+		return true
+	}
+	log.WithFields(logrus.Fields{
+		"result":   message,
+		"retrying": retrying,
+		"failed":   len(batch) - retrying,
+	}).Warn("notification send failed, will retry")
+	return true
+}
+
+// backoff returns the delay before the next attempt after attempts failures
+func (s *NotificationService) backoff(attempts int) time.Duration {
+	delay := s.outbox.InitialBackoff
+	for i := 1; i < attempts && delay < s.outbox.MaxBackoff; i++ {
+		delay *= 2
+	}
+	return min(delay, s.outbox.MaxBackoff)
+}
+
+// deliveryNotifier returns the notifier delivering notif: the channel's
+// notifier, addressed to the recipient for personal notifications
+func (s *NotificationService) deliveryNotifier(notif *storage.Notification) notification.Notifier {
+	if notif.Recipient == "" {
+		return s.notifier(notif.Channel)
+	}
+	for _, transport := range s.transports {
+		if transport.GetChannel() == notif.Channel {
+			return transport.WithRecipient(notif.Recipient)
+		}
+	}
+	return nil
+}
+
+// notifier returns the notifier of the named channel, or nil
+func (s *NotificationService) notifier(channel string) notification.Notifier {
+	for _, notifier := range s.notifiers {
+		if notifier.GetChannel() == channel {
+			return notifier
+		}
+	}
+	return nil
+}
@@ -2,105 +2,397 @@ package service
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"sync"
 	"time"
 
+	"cdk-get/internal/config"
+	"cdk-get/internal/events"
 	"cdk-get/internal/notification"
 	"cdk-get/internal/storage"
 
 	"github.com/sirupsen/logrus"
 )
 
+// ErrUnknownChannel is returned when a notification channel does not exist
+var ErrUnknownChannel = errors.New("unknown notification channel")
+
 // NotificationService handles notification sending and persistence
 type NotificationService struct {
-	notifier   notification.Notifier
+	notifiers  []notification.Notifier
+	transports map[string]notification.Addressable // 按通知目标类型发送个人通知的渠道
+	router     *notification.Router
+	outbox     config.OutboxConfig
+	schedules  map[string]channelSchedule // 按渠道名称的汇总窗口和免打扰时段
 	repository storage.Repository
+	events     *events.Bus
 	logger     *logrus.Logger
+
+	// queueMu 串行化入队，保证同一汇总批次共享投递时间
+	queueMu sync.Mutex
+
+	// 投递任务
+	wake   chan struct{}
+	cancel context.CancelFunc
+	done   chan struct{}
 }
 
-// NewNotificationService creates a new notification service
+// channelSchedule controls when non-critical notifications of a channel are sent
+type channelSchedule struct {
+	digestWindow time.Duration
+	quietHours   *config.QuietHours
+}
+
+// NewNotificationService creates a new notification service fanning out to notifiers.
+// router selects the channels and templates for published events; nil sends
+// every event to every channel with the built-in templates. cfg provides the
+// outbox retry settings and the channels' digest windows and quiet hours.
+// Queued notifications are delivered once Start has been called. Queued and
+// delivered notifications are published to bus, which may be nil.
 func NewNotificationService(
-	notifier notification.Notifier,
+	notifiers []notification.Notifier,
+	router *notification.Router,
+	cfg config.NotificationConfig,
 	repository storage.Repository,
+	bus *events.Bus,
 	logger *logrus.Logger,
 ) *NotificationService {
-	return &NotificationService{
-		notifier:   notifier,
+	s := &NotificationService{
+		notifiers:  notifiers,
+		transports: newTransports(notifiers, logger),
+		router:     router,
+		outbox:     cfg.Outbox,
+		schedules:  newSchedules(cfg, logger),
 		repository: repository,
+		events:     bus,
 		logger:     logger,
+		wake:       make(chan struct{}, 1),
+	}
+	if s.router == nil {
+		// 空配置不会校验失败
+		s.router, _ = notification.NewRouter(config.NotificationConfig{}, s.Channels())
+	}
+	return s
+}
+
+// newSchedules collects the digest windows and quiet hours of the channels
+func newSchedules(cfg config.NotificationConfig, logger *logrus.Logger) map[string]channelSchedule {
+	schedules := make(map[string]channelSchedule)
+	for _, channel := range cfg.EffectiveChannels() {
+		schedule := channelSchedule{digestWindow: channel.DigestWindow}
+		if channel.QuietHours != "" {
+			quiet, err := config.ParseQuietHours(channel.QuietHours)
+			if err != nil {
+				logger.WithFields(logrus.Fields{
+					"channel": channel.Name,
+					"error":   err.Error(),
+				}).Warn("ignoring invalid quiet hours")
+			} else {
+				schedule.quietHours = &quiet
+			}
+		}
+		if schedule.digestWindow > 0 || schedule.quietHours != nil {
+			schedules[channel.Name] = schedule
+		}
+	}
+	return schedules
+}
+
+// userWebhookChannel names the channel of personal webhook notifications
+// when no webhook channel is configured
+const userWebhookChannel = "user-webhook"
+
+// newTransports picks, for every notification target type, the first
+// channel of that type to deliver personal notifications through.
+// Webhook targets need no credentials and always have a transport.
+func newTransports(notifiers []notification.Notifier, logger *logrus.Logger) map[string]notification.Addressable {
+	transports := make(map[string]notification.Addressable)
+	for _, notifier := range notifiers {
+		addressable, ok := notifier.(notification.Addressable)
+		if !ok {
+			continue
+		}
+		if _, exists := transports[addressable.RecipientType()]; !exists {
+			transports[addressable.RecipientType()] = addressable
+		}
+	}
+	if _, exists := transports[storage.NotificationTargetWebhook]; !exists {
+		transports[storage.NotificationTargetWebhook] = notification.NewWebhookNotifier(userWebhookChannel, "", "", "", logger)
+	}
+	return transports
+}
+
+// Channels returns the names of the configured channels
+func (s *NotificationService) Channels() []string {
+	names := make([]string, 0, len(s.notifiers))
+	for _, notifier := range s.notifiers {
+		names = append(names, notifier.GetChannel())
 	}
+	return names
 }
 
-// SendAndSave sends a notification and saves the result to database
+// SendAndSave queues a notification for every channel, one record per channel.
+// Records are saved as pending and delivered by the outbox worker.
+// The content is plain text and converted to each channel's format.
 func (s *NotificationService) SendAndSave(ctx context.Context, title, summary, content string) error {
-	// Send notification
-	req := notification.NotificationRequest{
-		Title:   title,
-		Summary: summary,
-		Content: content,
+	return s.sendAndSave(ctx, s.notifiers, "", notification.SeverityInfo, func(notifier notification.Notifier) notification.NotificationRequest {
+		return notification.NotificationRequest{
+			Title:   title,
+			Summary: summary,
+			Content: notification.ConvertPlain(content, notifier.Format()),
+		}
+	})
+}
+
+// Publish sends an event to the channels selected by the routing rules,
+// rendered with the event's template. A nil service drops the event.
+func (s *NotificationService) Publish(ctx context.Context, event notification.Event) error {
+	if s == nil {
+		return nil
+	}
+	if event.Severity == "" {
+		event.Severity = event.Type.DefaultSeverity()
 	}
 
-	s.logger.WithFields(logrus.Fields{
-		"title":   title,
-		"summary": summary,
-		"channel": s.notifier.GetChannel(),
-	}).Info("sending notification")
+	notifiers := s.selectNotifiers(s.router.Channels(event))
+	if len(notifiers) == 0 {
+		s.logger.WithFields(logrus.Fields{
+			"event":    event.Type,
+			"severity": event.Severity,
+		}).Debug("no notification channel routed for event")
+		return nil
+	}
 
-	result, err := s.notifier.Send(ctx, req)
+	return s.sendAndSave(ctx, notifiers, string(event.Type), event.Severity, func(notifier notification.Notifier) notification.NotificationRequest {
+		return s.render(event, notifier)
+	})
+}
 
-	// Prepare notification record
-	notif := &storage.Notification{
-		Channel:   s.notifier.GetChannel(),
-		Title:     title,
-		Content:   content,
-		CreatedAt: time.Now(),
+// NotifyUser sends an event to the personal notification targets of the user
+// with fid, bypassing the routing rules. Targets whose type has no configured
+// channel are skipped. A nil service drops the event.
+func (s *NotificationService) NotifyUser(ctx context.Context, fid string, event notification.Event) error {
+	if s == nil {
+		return nil
+	}
+	if event.Severity == "" {
+		event.Severity = event.Type.DefaultSeverity()
 	}
 
-	// Set status and result based on send outcome
-	if err != nil || (result != nil && !result.Success) {
-		notif.Status = storage.NotificationStatusFailed
-		if err != nil {
-			notif.Result = fmt.Sprintf("Error: %v", err)
-		} else if result != nil && result.Message != "" {
-			notif.Result = result.Message
-		} else {
-			notif.Result = "Unknown error"
+	targets, err := s.repository.ListUserNotificationTargets(ctx, fid)
+	if err != nil {
+		return err
+	}
+	if len(targets) == 0 {
+		return nil
+	}
+
+	var errs []error
+	for _, target := range targets {
+		transport := s.transports[target.Type]
+		if transport == nil {
+			s.logger.WithFields(logrus.Fields{
+				"fid":  fid,
+				"type": target.Type,
+			}).Warn("no notification channel for target type, skipping")
+			continue
+		}
+
+		req := s.render(event, transport)
+		if err := s.queue(ctx, &storage.Notification{
+			Channel:   transport.GetChannel(),
+			Event:     string(event.Type),
+			FID:       fid,
+			Recipient: target.Target,
+			Title:     req.Title,
+			Summary:   req.Summary,
+			Content:   req.Content,
+			Status:    storage.NotificationStatusPending,
+			CreatedAt: time.Now(),
+		}, event.Severity); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", target.Type, err))
 		}
+	}
 
+	s.Wake()
+	return errors.Join(errs...)
+}
+
+// render renders the event for a notifier, falling back to the default text
+// on template errors
+func (s *NotificationService) render(event notification.Event, notifier notification.Notifier) notification.NotificationRequest {
+	req, err := s.router.Render(event, notifier.GetChannel(), notifier.Format())
+	if err != nil {
 		s.logger.WithFields(logrus.Fields{
-			"title":  title,
-			"error":  err,
-			"result": notif.Result,
-		}).Error("notification send failed")
-	} else {
-		notif.Status = storage.NotificationStatusSuccess
-		if result != nil && result.Message != "" {
-			notif.Result = result.Message
-		} else {
-			notif.Result = "Notification sent successfully"
+			"event":   event.Type,
+			"channel": notifier.GetChannel(),
+			"error":   err.Error(),
+		}).Warn("failed to render notification template, using default text")
+	}
+	return req
+}
+
+// Preview renders an event for the given channel, or for every channel the
+// event is routed to when channel is empty, without sending it
+func (s *NotificationService) Preview(event notification.Event, channel string) ([]notification.Preview, error) {
+	if event.Severity == "" {
+		event.Severity = event.Type.DefaultSeverity()
+	}
+
+	routed := make(map[string]bool)
+	for _, name := range s.router.Channels(event) {
+		routed[name] = true
+	}
+
+	previews := []notification.Preview{}
+	for _, notifier := range s.notifiers {
+		name := notifier.GetChannel()
+		if channel != "" && name != channel {
+			continue
+		}
+		if channel == "" && !routed[name] {
+			continue
+		}
+
+		req, err := s.router.Render(event, name, notifier.Format())
+		preview := notification.Preview{
+			Channel: name,
+			Format:  notifier.Format(),
+			Routed:  routed[name],
+			Title:   req.Title,
+			Summary: req.Summary,
+			Content: req.Content,
+		}
+		if err != nil {
+			preview.Error = err.Error()
+		}
+		previews = append(previews, preview)
+	}
+
+	if channel != "" && len(previews) == 0 {
+		return nil, fmt.Errorf("%w: %s", ErrUnknownChannel, channel)
+	}
+	return previews, nil
+}
+
+// selectNotifiers returns the notifiers with the given channel names
+func (s *NotificationService) selectNotifiers(channels []string) []notification.Notifier {
+	var notifiers []notification.Notifier
+	for _, notifier := range s.notifiers {
+		for _, name := range channels {
+			if notifier.GetChannel() == name {
+				notifiers = append(notifiers, notifier)
+				break
+			}
+		}
+	}
+	return notifiers
+}
+
+// sendAndSave saves the request built by render for each notifier as a
+// pending notification tagged with event and wakes the delivery worker.
+// The returned error joins the per-channel save failures.
+func (s *NotificationService) sendAndSave(ctx context.Context, notifiers []notification.Notifier, event string, severity notification.Severity, render func(notification.Notifier) notification.NotificationRequest) error {
+	var errs []error
+	for _, notifier := range notifiers {
+		req := render(notifier)
+		if err := s.queue(ctx, &storage.Notification{
+			Channel:   notifier.GetChannel(),
+			Event:     event,
+			Title:     req.Title,
+			Summary:   req.Summary,
+			Content:   req.Content,
+			Status:    storage.NotificationStatusPending,
+			CreatedAt: time.Now(),
+		}, severity); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", notifier.GetChannel(), err))
 		}
+	}
+
+	s.Wake()
+	return errors.Join(errs...)
+}
 
+// queue schedules and saves a pending notification for the delivery worker
+func (s *NotificationService) queue(ctx context.Context, notif *storage.Notification, severity notification.Severity) error {
+	// 逐条保存，避免并发写入 SQLite
+	s.queueMu.Lock()
+	defer s.queueMu.Unlock()
+
+	if err := s.schedule(ctx, notif, severity); err != nil {
 		s.logger.WithFields(logrus.Fields{
-			"title":  title,
-			"result": notif.Result,
-		}).Info("notification sent successfully")
+			"channel": notif.Channel,
+			"error":   err.Error(),
+		}).Warn("failed to schedule notification, sending immediately")
+		notif.BatchKey = ""
+		notif.NextAttemptAt = nil
 	}
 
-	// Save to database
-	if saveErr := s.repository.SaveNotification(ctx, notif); saveErr != nil {
+	if err := s.repository.SaveNotification(ctx, notif); err != nil {
 		s.logger.WithFields(logrus.Fields{
-			"title": title,
-			"error": saveErr.Error(),
-		}).Error("failed to save notification record")
-		return saveErr
+			"title":   notif.Title,
+			"channel": notif.Channel,
+			"error":   err.Error(),
+		}).Error("failed to queue notification")
+		return err
 	}
 
 	s.logger.WithFields(logrus.Fields{
-		"title":      title,
-		"channel":    notif.Channel,
-		"status":     notif.Status,
-		"created_at": notif.CreatedAt,
-	}).Info("notification record saved to database")
+		"id":      notif.ID,
+		"title":   notif.Title,
+		"channel": notif.Channel,
+		"event":   notif.Event,
+		"fid":     notif.FID,
+		"batch":   notif.BatchKey,
+	}).Info("notification queued")
+	s.events.Publish(events.NotificationQueued, notificationEventData(notif))
+	return nil
+}
+
+// notificationEventData converts a notification record to the event bus payload
+func notificationEventData(notif *storage.Notification) events.NotificationData {
+	return events.NotificationData{
+		ID:       notif.ID,
+		Channel:  notif.Channel,
+		Event:    notif.Event,
+		FID:      notif.FID,
+		Title:    notif.Title,
+		Status:   notif.Status,
+		Attempts: notif.Attempts,
+		Result:   notif.Result,
+	}
+}
 
-	return err
+// schedule sets when a new notification is delivered. Critical events are
+// sent at once. Others join the channel's open digest batch, or open one
+// ending after the digest window, and are deferred past the quiet hours.
+func (s *NotificationService) schedule(ctx context.Context, notif *storage.Notification, severity notification.Severity) error {
+	schedule, ok := s.schedules[notif.Channel]
+	if !ok || severity.AtLeast(notification.SeverityCritical) {
+		return nil
+	}
+
+	at := notif.CreatedAt
+	if schedule.digestWindow > 0 {
+		// 个人通知按接收目标分别汇总
+		notif.BatchKey = notif.Channel + "|" + notif.Recipient
+		deadline, err := s.repository.GetBatchDeadline(ctx, notif.BatchKey, at)
+		if err != nil {
+			return err
+		}
+		if deadline != nil {
+			at = *deadline
+		} else {
+			at = at.Add(schedule.digestWindow)
+		}
+	}
+	if schedule.quietHours != nil {
+		at = schedule.quietHours.Until(at)
+	}
+
+	if at.After(notif.CreatedAt) {
+		notif.NextAttemptAt = &at
+	}
+	return nil
 }
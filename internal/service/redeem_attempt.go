@@ -0,0 +1,27 @@
+package service
+
+import (
+	"cdk-get/internal/giftcode"
+	"cdk-get/internal/storage"
+)
+
+// NewRedeemAttempt 根据兑换接口的返回构造兑换请求记录
+// err 为请求错误，result 为兑换接口返回的结果
+func NewRedeemAttempt(fid, code string, result *giftcode.DdResult, err error) *storage.RedeemAttempt {
+	attempt := &storage.RedeemAttempt{FID: fid, Code: code}
+	switch {
+	case err != nil:
+		attempt.Status = storage.RedeemStatusFailed
+		attempt.Message = err.Error()
+	case result.Code == 0:
+		attempt.Status = storage.RedeemStatusSuccess
+	case result.Msg == giftcode.ErrMsgReceived:
+		attempt.Status = storage.RedeemStatusReceived
+	case result.Msg == giftcode.ErrMsgCdkNotFound:
+		attempt.Status = storage.RedeemStatusNotFound
+	default:
+		attempt.Status = storage.RedeemStatusFailed
+		attempt.Message = result.Msg
+	}
+	return attempt
+}
@@ -0,0 +1,168 @@
+package storage
+
+import (
+	"context"
+	"time"
+
+	"cdk-get/internal/events"
+)
+
+// EventRepository 在任务和用户写入成功后向事件总线发布变更事件的 Repository
+// 其余方法直接调用被包装的 Repository；事务中的写入在提交成功后才发布
+type EventRepository struct {
+	Repository
+	bus *events.Bus
+	// pending 不为 nil 时处于事务中，事件暂存到这里，提交后再发布
+	pending *[]pendingEvent
+}
+
+// pendingEvent 事务中暂存的事件
+type pendingEvent struct {
+	eventType string
+	data      any
+}
+
+// NewEventRepository 包装 repo，写入成功后向 bus 发布 task.changed、user.changed 事件
+func NewEventRepository(repo Repository, bus *events.Bus) *EventRepository {
+	return &EventRepository{Repository: repo, bus: bus}
+}
+
+// WithTransaction 实现 Repository，fn 中的写入产生的事件在事务提交成功后发布，回滚时丢弃
+func (r *EventRepository) WithTransaction(ctx context.Context, fn func(Repository) error) error {
+	pending := r.pending
+	outermost := pending == nil
+	if outermost {
+		pending = &[]pendingEvent{}
+	}
+
+	err := r.Repository.WithTransaction(ctx, func(tx Repository) error {
+		return fn(&EventRepository{Repository: tx, bus: r.bus, pending: pending})
+	})
+	// 嵌套事务的事件随最外层事务一起发布
+	if err != nil || !outermost {
+		return err
+	}
+
+	for _, event := range *pending {
+		r.bus.Publish(event.eventType, event.data)
+	}
+	return nil
+}
+
+// publish 发布事件，事务中先暂存
+func (r *EventRepository) publish(eventType string, data any) {
+	if r.pending != nil {
+		*r.pending = append(*r.pending, pendingEvent{eventType: eventType, data: data})
+		return
+	}
+	r.bus.Publish(eventType, data)
+}
+
+// publishTask 写入成功时发布任务变更
+func (r *EventRepository) publishTask(err error, change events.TaskChange) error {
+	if err == nil {
+		r.publish(events.TaskChanged, change)
+	}
+	return err
+}
+
+// publishUser 写入成功时发布用户变更
+func (r *EventRepository) publishUser(err error, fid, change string) error {
+	if err == nil {
+		r.publish(events.UserChanged, events.UserChange{FID: fid, Change: change})
+	}
+	return err
+}
+
+// CreateTask 实现 Repository，兑换码已存在时不发布事件
+func (r *EventRepository) CreateTask(ctx context.Context, code string) (bool, error) {
+	created, err := r.Repository.CreateTask(ctx, code)
+	if created {
+		r.publishTask(err, events.TaskChange{Code: code, Change: events.ChangeCreated})
+	}
+	return created, err
+}
+
+// MarkTaskComplete 实现 Repository
+func (r *EventRepository) MarkTaskComplete(ctx context.Context, code string) error {
+	return r.publishTask(r.Repository.MarkTaskComplete(ctx, code), events.TaskChange{Code: code, Change: events.ChangeCompleted})
+}
+
+// UpdateTaskRetry 实现 Repository
+func (r *EventRepository) UpdateTaskRetry(ctx context.Context, code string, retryCount int, lastError string) error {
+	return r.publishTask(r.Repository.UpdateTaskRetry(ctx, code, retryCount, lastError), events.TaskChange{
+		Code:       code,
+		Change:     events.ChangeRetried,
+		RetryCount: retryCount,
+		LastError:  lastError,
+	})
+}
+
+// UpdateTaskComplete 实现 Repository
+func (r *EventRepository) UpdateTaskComplete(ctx context.Context, code string, completedAt time.Time) error {
+	return r.publishTask(r.Repository.UpdateTaskComplete(ctx, code, completedAt), events.TaskChange{Code: code, Change: events.ChangeCompleted})
+}
+
+// SetTaskTargetGroup 实现 Repository
+func (r *EventRepository) SetTaskTargetGroup(ctx context.Context, code string, groupID *int64) error {
+	return r.publishTask(r.Repository.SetTaskTargetGroup(ctx, code, groupID), events.TaskChange{Code: code, Change: events.ChangeUpdated})
+}
+
+// DeleteTask 实现 Repository
+func (r *EventRepository) DeleteTask(ctx context.Context, code string) error {
+	return r.publishTask(r.Repository.DeleteTask(ctx, code), events.TaskChange{Code: code, Change: events.ChangeDeleted})
+}
+
+// RestoreTask 实现 Repository
+func (r *EventRepository) RestoreTask(ctx context.Context, code string) error {
+	return r.publishTask(r.Repository.RestoreTask(ctx, code), events.TaskChange{Code: code, Change: events.ChangeRestored})
+}
+
+// PurgeTask 实现 Repository
+func (r *EventRepository) PurgeTask(ctx context.Context, code string) error {
+	return r.publishTask(r.Repository.PurgeTask(ctx, code), events.TaskChange{Code: code, Change: events.ChangePurged})
+}
+
+// SaveUser 实现 Repository
+func (r *EventRepository) SaveUser(ctx context.Context, user *User) error {
+	return r.publishUser(r.Repository.SaveUser(ctx, user), user.FID, events.ChangeUpdated)
+}
+
+// UpdateUser 实现 Repository
+func (r *EventRepository) UpdateUser(ctx context.Context, user *User) error {
+	return r.publishUser(r.Repository.UpdateUser(ctx, user), user.FID, events.ChangeUpdated)
+}
+
+// SetUserDisabled 实现 Repository
+func (r *EventRepository) SetUserDisabled(ctx context.Context, fid string, disabled bool) error {
+	change := events.ChangeEnabled
+	if disabled {
+		change = events.ChangeDisabled
+	}
+	return r.publishUser(r.Repository.SetUserDisabled(ctx, fid, disabled), fid, change)
+}
+
+// SetUserGroups 实现 Repository
+func (r *EventRepository) SetUserGroups(ctx context.Context, fid string, groupIDs []int64) error {
+	return r.publishUser(r.Repository.SetUserGroups(ctx, fid, groupIDs), fid, events.ChangeUpdated)
+}
+
+// SetUserNotificationTargets 实现 Repository
+func (r *EventRepository) SetUserNotificationTargets(ctx context.Context, fid string, targets []*NotificationTarget) error {
+	return r.publishUser(r.Repository.SetUserNotificationTargets(ctx, fid, targets), fid, events.ChangeUpdated)
+}
+
+// DeleteUser 实现 Repository
+func (r *EventRepository) DeleteUser(ctx context.Context, fid string) error {
+	return r.publishUser(r.Repository.DeleteUser(ctx, fid), fid, events.ChangeDeleted)
+}
+
+// RestoreUser 实现 Repository
+func (r *EventRepository) RestoreUser(ctx context.Context, fid string) error {
+	return r.publishUser(r.Repository.RestoreUser(ctx, fid), fid, events.ChangeRestored)
+}
+
+// PurgeUser 实现 Repository
+func (r *EventRepository) PurgeUser(ctx context.Context, fid string) error {
+	return r.publishUser(r.Repository.PurgeUser(ctx, fid), fid, events.ChangePurged)
+}
@@ -0,0 +1,81 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+
+	"cdk-get/internal/events"
+
+	"github.com/sirupsen/logrus"
+)
+
+func TestEventRepository_WithTransaction(t *testing.T) {
+	config := DefaultSqliteConfig()
+	config.Path = filepath.Join(t.TempDir(), "events.db")
+
+	logger := logrus.New()
+	logger.SetLevel(logrus.ErrorLevel)
+
+	sqliteRepo, err := NewSqliteRepository(config, logger)
+	if err != nil {
+		t.Fatalf("failed to create repository: %v", err)
+	}
+	defer sqliteRepo.Close()
+
+	bus := events.NewBus()
+	defer bus.Close()
+	received, _, cancel := bus.Subscribe()
+	defer cancel()
+
+	repo := NewEventRepository(sqliteRepo, bus)
+	ctx := context.Background()
+
+	// 回滚的事务不发布事件
+	rollback := errors.New("rollback")
+	err = repo.WithTransaction(ctx, func(tx Repository) error {
+		if _, err := tx.CreateTask(ctx, "ROLLBACK"); err != nil {
+			return err
+		}
+		return rollback
+	})
+	if !errors.Is(err, rollback) {
+		t.Fatalf("expected rollback error, got %v", err)
+	}
+	select {
+	case event := <-received:
+		t.Fatalf("expected no event after rollback, got %s", event.Type)
+	default:
+	}
+
+	// 提交前不发布，提交后按写入顺序发布
+	err = repo.WithTransaction(ctx, func(tx Repository) error {
+		if _, err := tx.CreateTask(ctx, "COMMIT"); err != nil {
+			return err
+		}
+		if err := tx.SaveUser(ctx, &User{FID: "1", Nickname: "alice"}); err != nil {
+			return err
+		}
+		select {
+		case event := <-received:
+			t.Errorf("expected no event before commit, got %s", event.Type)
+		default:
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("transaction failed: %v", err)
+	}
+
+	for _, want := range []string{events.TaskChanged, events.UserChanged} {
+		select {
+		case event := <-received:
+			if event.Type != want {
+				t.Errorf("expected %s event, got %s", want, event.Type)
+			}
+		default:
+			t.Errorf("expected %s event after commit", want)
+		}
+	}
+}
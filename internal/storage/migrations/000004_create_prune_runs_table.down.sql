@@ -0,0 +1,5 @@
+-- Migration rollback: Drop prune_runs table
+-- Removes the prune_runs table and its index
+
+DROP INDEX IF EXISTS idx_prune_runs_target;
+DROP TABLE IF EXISTS prune_runs;
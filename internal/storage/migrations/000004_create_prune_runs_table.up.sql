@@ -0,0 +1,16 @@
+-- Migration: Create prune_runs table
+-- Records every retention pruning run so the admin dashboard can report the last run per target
+
+CREATE TABLE IF NOT EXISTS prune_runs (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    target TEXT NOT NULL,
+    cutoff TIMESTAMP NOT NULL,
+    deleted INTEGER NOT NULL DEFAULT 0,
+    status TEXT NOT NULL CHECK(status IN ('success', 'failed')),
+    error TEXT NOT NULL DEFAULT '',
+    started_at TIMESTAMP NOT NULL,
+    finished_at TIMESTAMP NOT NULL
+);
+
+-- Create index for querying the latest run per target
+CREATE INDEX IF NOT EXISTS idx_prune_runs_target ON prune_runs(target, id DESC);
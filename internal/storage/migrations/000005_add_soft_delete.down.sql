@@ -0,0 +1,10 @@
+-- Migration rollback: Remove soft delete support
+-- Removes the fields added in 000005_add_soft_delete.up.sql
+
+-- Drop the indexes first
+DROP INDEX IF EXISTS idx_fid_deleted;
+DROP INDEX IF EXISTS idx_task_deleted;
+
+-- Drop the added columns
+ALTER TABLE fid_list DROP COLUMN deleted_at;
+ALTER TABLE gift_code_task DROP COLUMN deleted_at;
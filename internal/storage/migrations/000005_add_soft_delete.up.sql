@@ -0,0 +1,10 @@
+-- Migration: Add soft delete support for tasks and users
+-- Deleted rows keep their gift_codes history until purged after the grace period
+
+-- Add deleted_at field (NULL means the row is active)
+ALTER TABLE gift_code_task ADD COLUMN deleted_at TIMESTAMP;
+ALTER TABLE fid_list ADD COLUMN deleted_at TIMESTAMP;
+
+-- Create indexes for trash listing and purge
+CREATE INDEX IF NOT EXISTS idx_task_deleted ON gift_code_task(deleted_at);
+CREATE INDEX IF NOT EXISTS idx_fid_deleted ON fid_list(deleted_at);
@@ -0,0 +1,10 @@
+-- Migration rollback: Remove user lifecycle fields and user groups
+-- Removes the tables and fields added in 000006_add_user_groups.up.sql
+
+ALTER TABLE gift_code_task DROP COLUMN target_group_id;
+
+DROP INDEX IF EXISTS idx_group_members_fid;
+DROP TABLE IF EXISTS user_group_members;
+DROP TABLE IF EXISTS user_groups;
+
+ALTER TABLE fid_list DROP COLUMN disabled;
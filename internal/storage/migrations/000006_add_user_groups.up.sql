@@ -0,0 +1,27 @@
+-- Migration: Add user lifecycle fields and user groups
+-- Disabled users are skipped during redemption but keep their history
+-- Tasks may target a single group instead of all users
+
+-- Add disabled flag to fid_list (0 = enabled)
+ALTER TABLE fid_list ADD COLUMN disabled INTEGER NOT NULL DEFAULT 0;
+
+-- User groups table (e.g. alliance, main/alt accounts)
+CREATE TABLE IF NOT EXISTS user_groups (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    name TEXT NOT NULL UNIQUE,
+    description TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Group membership table
+CREATE TABLE IF NOT EXISTS user_group_members (
+    group_id INTEGER NOT NULL,
+    fid TEXT NOT NULL,
+    PRIMARY KEY (group_id, fid)
+);
+
+-- Create index for looking up the groups of a user
+CREATE INDEX IF NOT EXISTS idx_group_members_fid ON user_group_members(fid);
+
+-- Add target group to gift_code_task (NULL means all users)
+ALTER TABLE gift_code_task ADD COLUMN target_group_id INTEGER;
@@ -0,0 +1,4 @@
+-- Rollback: Drop user_profile_history table
+
+DROP INDEX IF EXISTS idx_profile_history_fid;
+DROP TABLE IF EXISTS user_profile_history;
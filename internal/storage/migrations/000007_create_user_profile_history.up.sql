@@ -0,0 +1,15 @@
+-- Migration: Create user_profile_history table
+-- Records nickname renames, kid (server/state) transfers and avatar changes
+-- so a member can be followed across renames and migrations
+
+CREATE TABLE IF NOT EXISTS user_profile_history (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    fid TEXT NOT NULL,
+    field TEXT NOT NULL,
+    old_value TEXT NOT NULL DEFAULT '',
+    new_value TEXT NOT NULL DEFAULT '',
+    changed_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Create index for listing the history of a user
+CREATE INDEX IF NOT EXISTS idx_profile_history_fid ON user_profile_history(fid, changed_at);
@@ -0,0 +1,5 @@
+-- Rollback: Remove notification event types and admin login IP tracking
+
+DROP TABLE IF EXISTS admin_login_ips;
+DROP INDEX IF EXISTS idx_notification_event;
+ALTER TABLE notifications DROP COLUMN event;
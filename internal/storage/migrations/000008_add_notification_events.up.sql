@@ -0,0 +1,18 @@
+-- Migration: Add notification event types and admin login IP tracking
+-- Every notification record remembers the event that produced it
+-- Admin login IPs are tracked to alert on logins from new addresses
+
+-- Add event type to notifications ('' for records created before events existed)
+ALTER TABLE notifications ADD COLUMN event TEXT NOT NULL DEFAULT '';
+
+-- Create index for filtering notifications by event
+CREATE INDEX IF NOT EXISTS idx_notification_event ON notifications(event);
+
+-- Known admin login IPs
+CREATE TABLE IF NOT EXISTS admin_login_ips (
+    username TEXT NOT NULL,
+    ip TEXT NOT NULL,
+    first_seen_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    last_seen_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (username, ip)
+);
@@ -0,0 +1,24 @@
+-- Rollback: Restore the notifications table without outbox fields
+-- Pending notifications cannot be represented and are dropped
+
+CREATE TABLE notifications_old (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    channel TEXT NOT NULL,
+    title TEXT NOT NULL,
+    content TEXT NOT NULL,
+    result TEXT NOT NULL,
+    status TEXT NOT NULL CHECK(status IN ('success', 'failed')),
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    event TEXT NOT NULL DEFAULT ''
+);
+
+INSERT INTO notifications_old (id, channel, title, content, result, status, created_at, event)
+SELECT id, channel, title, content, result, status, created_at, event
+FROM notifications
+WHERE status != 'pending';
+
+DROP TABLE notifications;
+ALTER TABLE notifications_old RENAME TO notifications;
+
+CREATE INDEX IF NOT EXISTS idx_notification_created ON notifications(created_at DESC);
+CREATE INDEX IF NOT EXISTS idx_notification_event ON notifications(event);
@@ -0,0 +1,36 @@
+-- Migration: Turn notifications into a delivery outbox
+-- Notifications are persisted as 'pending' first and delivered by a background
+-- worker with retries, so the status CHECK constraint has to allow 'pending'.
+-- SQLite cannot alter a CHECK constraint, so the table is rebuilt.
+
+CREATE TABLE notifications_new (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    channel TEXT NOT NULL,
+    event TEXT NOT NULL DEFAULT '',
+    title TEXT NOT NULL,
+    summary TEXT NOT NULL DEFAULT '',
+    content TEXT NOT NULL,
+    result TEXT NOT NULL DEFAULT '',
+    status TEXT NOT NULL CHECK(status IN ('pending', 'success', 'failed')),
+    attempts INTEGER NOT NULL DEFAULT 0,
+    next_attempt_at TIMESTAMP,
+    sent_at TIMESTAMP,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Existing records were each delivered exactly once
+INSERT INTO notifications_new (id, channel, event, title, content, result, status, attempts, sent_at, created_at, updated_at)
+SELECT id, channel, event, title, content, result, status, 1,
+       CASE WHEN status = 'success' THEN created_at END,
+       created_at, created_at
+FROM notifications;
+
+DROP TABLE notifications;
+ALTER TABLE notifications_new RENAME TO notifications;
+
+CREATE INDEX IF NOT EXISTS idx_notification_created ON notifications(created_at DESC);
+CREATE INDEX IF NOT EXISTS idx_notification_event ON notifications(event);
+
+-- Create index for the delivery worker picking up due notifications
+CREATE INDEX IF NOT EXISTS idx_notification_pending ON notifications(status, next_attempt_at);
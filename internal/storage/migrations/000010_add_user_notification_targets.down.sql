@@ -0,0 +1,6 @@
+-- Rollback: Remove per-user notification targets
+
+DROP INDEX IF EXISTS idx_notification_fid;
+ALTER TABLE notifications DROP COLUMN recipient;
+ALTER TABLE notifications DROP COLUMN fid;
+DROP TABLE IF EXISTS user_notification_targets;
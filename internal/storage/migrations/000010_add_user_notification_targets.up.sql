@@ -0,0 +1,19 @@
+-- Migration: Per-user notification targets
+-- Account owners receive a personal summary of their redemption results
+
+-- Notification targets of a user (wxpusher UID, email, telegram chat id, webhook URL)
+CREATE TABLE IF NOT EXISTS user_notification_targets (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    fid TEXT NOT NULL,
+    type TEXT NOT NULL CHECK (type IN ('wxpusher', 'email', 'telegram', 'webhook')),
+    target TEXT NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE (fid, type, target)
+);
+
+-- Personal notifications record the user and the recipient they were sent to
+ALTER TABLE notifications ADD COLUMN fid TEXT NOT NULL DEFAULT '';
+ALTER TABLE notifications ADD COLUMN recipient TEXT NOT NULL DEFAULT '';
+
+-- Create index for looking up the personal notifications of a user
+CREATE INDEX IF NOT EXISTS idx_notification_fid ON notifications(fid);
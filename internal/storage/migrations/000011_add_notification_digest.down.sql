@@ -0,0 +1,4 @@
+-- Rollback: Remove notification digest grouping
+
+DROP INDEX IF EXISTS idx_notification_batch;
+ALTER TABLE notifications DROP COLUMN batch_key;
@@ -0,0 +1,7 @@
+-- Migration: Group notifications into digests
+-- Pending notifications with the same batch_key are merged into one message
+
+ALTER TABLE notifications ADD COLUMN batch_key TEXT NOT NULL DEFAULT '';
+
+-- Create index for collecting the notifications of a digest
+CREATE INDEX IF NOT EXISTS idx_notification_batch ON notifications(batch_key, status, next_attempt_at);
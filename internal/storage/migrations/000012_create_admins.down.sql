@@ -0,0 +1,3 @@
+-- Rollback: Admin accounts with roles
+
+DROP TABLE IF EXISTS admins;
@@ -0,0 +1,5 @@
+-- Rollback: Admin sessions with rotating refresh tokens
+
+DROP INDEX IF EXISTS idx_admin_session_previous;
+DROP INDEX IF EXISTS idx_admin_session_username;
+DROP TABLE IF EXISTS admin_sessions;
@@ -0,0 +1,20 @@
+-- Migration: Admin sessions with rotating refresh tokens
+-- Access tokens are short-lived JWTs bound to a session; revoking the session logs them out
+
+CREATE TABLE IF NOT EXISTS admin_sessions (
+    id TEXT PRIMARY KEY,
+    username TEXT NOT NULL,
+    refresh_token_hash TEXT NOT NULL UNIQUE,
+    -- Hash replaced by the last rotation, used to detect refresh token reuse
+    previous_token_hash TEXT NOT NULL DEFAULT '',
+    ip TEXT NOT NULL DEFAULT '',
+    user_agent TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMP NOT NULL,
+    last_used_at TIMESTAMP NOT NULL,
+    expires_at TIMESTAMP NOT NULL,
+    revoked_at TIMESTAMP
+);
+
+-- Create indexes for listing the sessions of an admin and reuse detection
+CREATE INDEX IF NOT EXISTS idx_admin_session_username ON admin_sessions(username);
+CREATE INDEX IF NOT EXISTS idx_admin_session_previous ON admin_sessions(previous_token_hash);
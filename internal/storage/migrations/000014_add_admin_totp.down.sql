@@ -0,0 +1,8 @@
+-- Rollback: Remove TOTP two-factor authentication
+
+DROP INDEX IF EXISTS idx_admin_recovery_code_username;
+DROP TABLE IF EXISTS admin_recovery_codes;
+ALTER TABLE admins DROP COLUMN totp_last_step;
+ALTER TABLE admins DROP COLUMN totp_required;
+ALTER TABLE admins DROP COLUMN totp_enabled;
+ALTER TABLE admins DROP COLUMN totp_secret;
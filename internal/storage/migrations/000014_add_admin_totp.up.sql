@@ -0,0 +1,20 @@
+-- Migration: TOTP two-factor authentication for admin accounts
+-- totp_secret holds a pending secret until the first code is confirmed and totp_enabled is set
+
+ALTER TABLE admins ADD COLUMN totp_secret TEXT NOT NULL DEFAULT '';
+ALTER TABLE admins ADD COLUMN totp_enabled INTEGER NOT NULL DEFAULT 0;
+-- Set by an owner; the account must enroll before it can use the dashboard
+ALTER TABLE admins ADD COLUMN totp_required INTEGER NOT NULL DEFAULT 0;
+-- Last accepted time step, so a code cannot be replayed within its window
+ALTER TABLE admins ADD COLUMN totp_last_step INTEGER NOT NULL DEFAULT 0;
+
+CREATE TABLE IF NOT EXISTS admin_recovery_codes (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    username TEXT NOT NULL,
+    code_hash TEXT NOT NULL,
+    created_at TIMESTAMP NOT NULL,
+    used_at TIMESTAMP
+);
+
+-- Create index for checking the recovery codes of an admin
+CREATE INDEX IF NOT EXISTS idx_admin_recovery_code_username ON admin_recovery_codes(username, code_hash);
@@ -0,0 +1,5 @@
+-- Rollback: Remove login brute-force protection
+
+DROP INDEX IF EXISTS idx_admin_login_failure_created;
+DROP TABLE IF EXISTS admin_login_failures;
+DROP TABLE IF EXISTS login_throttles;
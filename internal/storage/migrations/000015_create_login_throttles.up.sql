@@ -0,0 +1,27 @@
+-- Migration: Login brute-force protection
+-- Failed admin logins are counted per client IP and per username; reaching the limit locks the identity temporarily
+
+CREATE TABLE IF NOT EXISTS login_throttles (
+    kind TEXT NOT NULL CHECK (kind IN ('ip', 'username')),
+    identity TEXT NOT NULL,
+    -- Consecutive failures within the window, reset by a successful login
+    failures INTEGER NOT NULL DEFAULT 0,
+    -- Consecutive lockouts, each one doubles the lockout duration
+    lockouts INTEGER NOT NULL DEFAULT 0,
+    last_failed_at TIMESTAMP NOT NULL,
+    locked_until TIMESTAMP,
+    PRIMARY KEY (kind, identity)
+);
+
+-- Audit trail of failed admin logins
+CREATE TABLE IF NOT EXISTS admin_login_failures (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    username TEXT NOT NULL,
+    ip TEXT NOT NULL,
+    user_agent TEXT NOT NULL DEFAULT '',
+    reason TEXT NOT NULL,
+    created_at TIMESTAMP NOT NULL
+);
+
+-- Create index for listing recent failures and retention
+CREATE INDEX IF NOT EXISTS idx_admin_login_failure_created ON admin_login_failures(created_at);
@@ -0,0 +1,19 @@
+-- Migration: API keys for the public endpoints
+-- Scripts and bots authenticate /giftcode and /add_user with a scoped key instead of an admin JWT
+
+CREATE TABLE IF NOT EXISTS api_keys (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    name TEXT NOT NULL,
+    -- Leading characters of the key, shown so the key can be recognised
+    prefix TEXT NOT NULL,
+    -- SHA-256 of the key, the key itself is only shown once on creation
+    key_hash TEXT NOT NULL UNIQUE,
+    -- Comma separated scopes: enqueue-code, add-user, read-only
+    scopes TEXT NOT NULL,
+    created_by TEXT NOT NULL,
+    created_at TIMESTAMP NOT NULL,
+    last_used_at TIMESTAMP,
+    last_used_ip TEXT NOT NULL DEFAULT '',
+    usage_count INTEGER NOT NULL DEFAULT 0,
+    revoked_at TIMESTAMP
+);
@@ -0,0 +1,6 @@
+-- Rollback: Remove admin audit log
+
+DROP INDEX IF EXISTS idx_audit_log_action;
+DROP INDEX IF EXISTS idx_audit_log_actor;
+DROP INDEX IF EXISTS idx_audit_log_created;
+DROP TABLE IF EXISTS audit_log;
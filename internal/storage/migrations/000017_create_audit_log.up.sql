@@ -0,0 +1,22 @@
+-- Migration: Admin audit log
+-- Every mutating admin action is recorded with the acting admin, client IP, request ID and the affected data
+
+CREATE TABLE IF NOT EXISTS audit_log (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    actor TEXT NOT NULL,
+    -- Action name such as task.delete or user.update
+    action TEXT NOT NULL,
+    -- Affected record, e.g. the gift code or fid
+    target TEXT NOT NULL DEFAULT '',
+    ip TEXT NOT NULL DEFAULT '',
+    request_id TEXT NOT NULL DEFAULT '',
+    -- JSON snapshots of the record before and after the change, empty when not applicable
+    before_data TEXT NOT NULL DEFAULT '',
+    after_data TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMP NOT NULL
+);
+
+-- Create indexes for filtering and retention
+CREATE INDEX IF NOT EXISTS idx_audit_log_created ON audit_log(created_at);
+CREATE INDEX IF NOT EXISTS idx_audit_log_actor ON audit_log(actor);
+CREATE INDEX IF NOT EXISTS idx_audit_log_action ON audit_log(action);
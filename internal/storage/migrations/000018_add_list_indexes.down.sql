@@ -0,0 +1,8 @@
+-- Rollback: Remove list indexes
+
+DROP INDEX IF EXISTS idx_notification_channel;
+DROP INDEX IF EXISTS idx_task_completed_time;
+DROP INDEX IF EXISTS idx_task_created_time;
+DROP INDEX IF EXISTS idx_fid_kid;
+DROP INDEX IF EXISTS idx_fid_nickname;
+DROP INDEX IF EXISTS idx_fid_number;
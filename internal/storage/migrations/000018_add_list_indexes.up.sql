@@ -0,0 +1,16 @@
+-- Migration: Add indexes for filtering and sorting the admin lists
+-- Lists are paginated by (sort value, unique key), so each sort index ends with the key.
+-- Time columns hold both CURRENT_TIMESTAMP and Go formatted values and are sorted
+-- by julianday(), which needs expression indexes.
+
+-- Create indexes for sorting users by fid, nickname and kid
+CREATE INDEX IF NOT EXISTS idx_fid_number ON fid_list(CAST(fid AS INTEGER), fid);
+CREATE INDEX IF NOT EXISTS idx_fid_nickname ON fid_list(nickname, fid);
+CREATE INDEX IF NOT EXISTS idx_fid_kid ON fid_list(kid, fid);
+
+-- Create indexes for sorting tasks by creation and completion time
+CREATE INDEX IF NOT EXISTS idx_task_created_time ON gift_code_task(COALESCE(julianday(created_at), 0), code);
+CREATE INDEX IF NOT EXISTS idx_task_completed_time ON gift_code_task(COALESCE(julianday(completed_at), 0), code);
+
+-- Create index for filtering notifications by channel
+CREATE INDEX IF NOT EXISTS idx_notification_channel ON notifications(channel, id);
@@ -0,0 +1,7 @@
+-- Rollback: Remove redemption statistics
+
+DROP INDEX IF EXISTS idx_captcha_call_created;
+DROP INDEX IF EXISTS idx_redeem_attempt_code;
+DROP INDEX IF EXISTS idx_redeem_attempt_created;
+DROP TABLE IF EXISTS captcha_calls;
+DROP TABLE IF EXISTS redeem_attempts;
@@ -0,0 +1,28 @@
+-- Migration: Record redemption attempts and captcha recognitions for statistics
+-- gift_codes only records that a fid has a code; these tables keep every outcome and when it happened
+
+-- Each call to the redeem API for a fid, from the scheduled job or an admin redeem now
+CREATE TABLE IF NOT EXISTS redeem_attempts (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    code TEXT NOT NULL,
+    fid TEXT NOT NULL,
+    -- success, received (already redeemed), not_found or failed
+    status TEXT NOT NULL,
+    -- Failure reason returned by the API, empty unless failed
+    message TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMP NOT NULL
+);
+
+-- Each captcha recognition, with the provider's unit price at the time of the call
+CREATE TABLE IF NOT EXISTS captcha_calls (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    provider TEXT NOT NULL,
+    success INTEGER NOT NULL,
+    cost REAL NOT NULL DEFAULT 0,
+    created_at TIMESTAMP NOT NULL
+);
+
+-- Create indexes for aggregating by time range and code, and for retention
+CREATE INDEX IF NOT EXISTS idx_redeem_attempt_created ON redeem_attempts(julianday(created_at));
+CREATE INDEX IF NOT EXISTS idx_redeem_attempt_code ON redeem_attempts(code, status);
+CREATE INDEX IF NOT EXISTS idx_captcha_call_created ON captcha_calls(julianday(created_at));
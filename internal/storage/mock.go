@@ -38,7 +38,16 @@ func (m *MockKeyStorage) DoneTask(code string) error {
 
 // MockRepository 用于测试的Repository mock实现
 type MockRepository struct {
-	DeleteTaskFunc func(ctx context.Context, code string) error
+	DeleteTaskFunc         func(ctx context.Context, code string) error
+	GetAdminFunc           func(ctx context.Context, username string) (*Admin, error)
+	GetAdminSessionFunc    func(ctx context.Context, id string) (*AdminSession, error)
+	GetAPIKeyByHashFunc    func(ctx context.Context, keyHash string) (*APIKey, error)
+	RecordAPIKeyUsageFunc  func(ctx context.Context, id int64, count int64, at time.Time, ip string) error
+	GetTaskByCodeFunc      func(ctx context.Context, code string) (*Task, error)
+	CreateTaskFunc         func(ctx context.Context, code string) (bool, error)
+	CreateAuditEntryFunc   func(ctx context.Context, entry *AuditEntry) error
+	IsGiftCodeReceivedFunc func(ctx context.Context, fid, code string) (bool, error)
+	CountRedemptionsFunc   func(ctx context.Context, filter StatsFilter, interval string, offset time.Duration) ([]*RedemptionBucket, error)
 }
 
 func (m *MockRepository) SaveGiftCode(ctx context.Context, fid, code string) error {
@@ -46,6 +55,9 @@ func (m *MockRepository) SaveGiftCode(ctx context.Context, fid, code string) err
 }
 
 func (m *MockRepository) IsGiftCodeReceived(ctx context.Context, fid, code string) (bool, error) {
+	if m.IsGiftCodeReceivedFunc != nil {
+		return m.IsGiftCodeReceivedFunc(ctx, fid, code)
+	}
 	return false, nil
 }
 
@@ -65,10 +77,69 @@ func (m *MockRepository) ListUsers(ctx context.Context) ([]*User, error) {
 	return []*User{}, nil
 }
 
-func (m *MockRepository) CreateTask(ctx context.Context, code string) error {
+func (m *MockRepository) QueryUsers(ctx context.Context, filter UserFilter) (*Page[User], error) {
+	return &Page[User]{Items: []*User{}}, nil
+}
+
+func (m *MockRepository) DeleteUser(ctx context.Context, fid string) error {
+	return nil
+}
+
+func (m *MockRepository) UpdateUser(ctx context.Context, user *User) error {
+	return nil
+}
+
+func (m *MockRepository) SetUserDisabled(ctx context.Context, fid string, disabled bool) error {
+	return nil
+}
+
+func (m *MockRepository) SetUserGroups(ctx context.Context, fid string, groupIDs []int64) error {
+	return nil
+}
+
+func (m *MockRepository) ListUserNotificationTargets(ctx context.Context, fid string) ([]*NotificationTarget, error) {
+	return nil, nil
+}
+
+func (m *MockRepository) SetUserNotificationTargets(ctx context.Context, fid string, targets []*NotificationTarget) error {
+	return nil
+}
+
+func (m *MockRepository) ListUserProfileHistory(ctx context.Context, fid string) ([]*ProfileChange, error) {
+	return nil, nil
+}
+
+func (m *MockRepository) CreateUserGroup(ctx context.Context, group *UserGroup) error {
+	return nil
+}
+
+func (m *MockRepository) UpdateUserGroup(ctx context.Context, group *UserGroup) error {
+	return nil
+}
+
+func (m *MockRepository) DeleteUserGroup(ctx context.Context, id int64) error {
 	return nil
 }
 
+func (m *MockRepository) GetUserGroup(ctx context.Context, id int64) (*UserGroup, error) {
+	return nil, ErrGroupNotFound
+}
+
+func (m *MockRepository) ListUserGroups(ctx context.Context) ([]*UserGroup, error) {
+	return []*UserGroup{}, nil
+}
+
+func (m *MockRepository) ListGroupFids(ctx context.Context, groupID int64) ([]string, error) {
+	return []string{}, nil
+}
+
+func (m *MockRepository) CreateTask(ctx context.Context, code string) (bool, error) {
+	if m.CreateTaskFunc != nil {
+		return m.CreateTaskFunc(ctx, code)
+	}
+	return true, nil
+}
+
 func (m *MockRepository) ListPendingTasks(ctx context.Context) ([]*Task, error) {
 	return []*Task{}, nil
 }
@@ -78,6 +149,9 @@ func (m *MockRepository) MarkTaskComplete(ctx context.Context, code string) erro
 }
 
 func (m *MockRepository) GetTaskByCode(ctx context.Context, code string) (*Task, error) {
+	if m.GetTaskByCodeFunc != nil {
+		return m.GetTaskByCodeFunc(ctx, code)
+	}
 	return nil, nil
 }
 
@@ -93,6 +167,14 @@ func (m *MockRepository) ListCompletedTasks(ctx context.Context, limit int) ([]*
 	return []*Task{}, nil
 }
 
+func (m *MockRepository) QueryTasks(ctx context.Context, filter TaskFilter) (*Page[Task], error) {
+	return &Page[Task]{Items: []*Task{}}, nil
+}
+
+func (m *MockRepository) SetTaskTargetGroup(ctx context.Context, code string, groupID *int64) error {
+	return nil
+}
+
 func (m *MockRepository) DeleteTask(ctx context.Context, code string) error {
 	if m.DeleteTaskFunc != nil {
 		return m.DeleteTaskFunc(ctx, code)
@@ -100,6 +182,38 @@ func (m *MockRepository) DeleteTask(ctx context.Context, code string) error {
 	return nil
 }
 
+func (m *MockRepository) ListDeletedTasks(ctx context.Context) ([]*Task, error) {
+	return []*Task{}, nil
+}
+
+func (m *MockRepository) RestoreTask(ctx context.Context, code string) error {
+	return nil
+}
+
+func (m *MockRepository) PurgeTask(ctx context.Context, code string) error {
+	return nil
+}
+
+func (m *MockRepository) ListDeletedUsers(ctx context.Context) ([]*User, error) {
+	return []*User{}, nil
+}
+
+func (m *MockRepository) RestoreUser(ctx context.Context, fid string) error {
+	return nil
+}
+
+func (m *MockRepository) PurgeUser(ctx context.Context, fid string) error {
+	return nil
+}
+
+func (m *MockRepository) PurgeDeletedTasks(ctx context.Context, before time.Time, limit int) (int64, error) {
+	return 0, nil
+}
+
+func (m *MockRepository) PurgeDeletedUsers(ctx context.Context, before time.Time, limit int) (int64, error) {
+	return 0, nil
+}
+
 func (m *MockRepository) SaveNotification(ctx context.Context, notification *Notification) error {
 	return nil
 }
@@ -108,6 +222,264 @@ func (m *MockRepository) ListNotifications(ctx context.Context, limit int) ([]*N
 	return []*Notification{}, nil
 }
 
+func (m *MockRepository) QueryNotifications(ctx context.Context, filter NotificationFilter) (*Page[Notification], error) {
+	return &Page[Notification]{Items: []*Notification{}}, nil
+}
+
+func (m *MockRepository) GetNotification(ctx context.Context, id int64) (*Notification, error) {
+	return nil, ErrNotificationNotFound
+}
+
+func (m *MockRepository) ListDueNotifications(ctx context.Context, now time.Time, limit int) ([]*Notification, error) {
+	return []*Notification{}, nil
+}
+
+func (m *MockRepository) GetBatchDeadline(ctx context.Context, batchKey string, now time.Time) (*time.Time, error) {
+	return nil, nil
+}
+
+func (m *MockRepository) ListDueBatch(ctx context.Context, batchKey string, now time.Time) ([]*Notification, error) {
+	return nil, nil
+}
+
+func (m *MockRepository) UpdateNotificationDelivery(ctx context.Context, notification *Notification) error {
+	return nil
+}
+
+func (m *MockRepository) ResendNotification(ctx context.Context, id int64) error {
+	return nil
+}
+
+func (m *MockRepository) RecordLoginIP(ctx context.Context, username, ip string) (bool, error) {
+	return false, nil
+}
+
+func (m *MockRepository) CreateAdmin(ctx context.Context, admin *Admin) error {
+	return nil
+}
+
+func (m *MockRepository) BootstrapAdmin(ctx context.Context, admin *Admin) (bool, error) {
+	return false, nil
+}
+
+func (m *MockRepository) GetAdmin(ctx context.Context, username string) (*Admin, error) {
+	if m.GetAdminFunc != nil {
+		return m.GetAdminFunc(ctx, username)
+	}
+	return nil, ErrAdminNotFound
+}
+
+func (m *MockRepository) ListAdmins(ctx context.Context) ([]*Admin, error) {
+	return []*Admin{}, nil
+}
+
+func (m *MockRepository) UpdateAdminRole(ctx context.Context, username, role string) error {
+	return nil
+}
+
+func (m *MockRepository) UpdateAdminPassword(ctx context.Context, username, passwordHash string) error {
+	return nil
+}
+
+func (m *MockRepository) DeleteAdmin(ctx context.Context, username string) error {
+	return nil
+}
+
+func (m *MockRepository) CreateAdminSession(ctx context.Context, session *AdminSession) error {
+	return nil
+}
+
+func (m *MockRepository) GetAdminSession(ctx context.Context, id string) (*AdminSession, error) {
+	if m.GetAdminSessionFunc != nil {
+		return m.GetAdminSessionFunc(ctx, id)
+	}
+	return nil, ErrSessionNotFound
+}
+
+func (m *MockRepository) RotateAdminSession(ctx context.Context, oldHash string, session *AdminSession) (*AdminSession, error) {
+	return nil, ErrSessionNotFound
+}
+
+func (m *MockRepository) TouchAdminSession(ctx context.Context, id string, at time.Time) error {
+	return nil
+}
+
+func (m *MockRepository) ListAdminSessions(ctx context.Context, username string, now time.Time) ([]*AdminSession, error) {
+	return []*AdminSession{}, nil
+}
+
+func (m *MockRepository) RevokeAdminSession(ctx context.Context, username, id string) error {
+	return nil
+}
+
+func (m *MockRepository) RevokeAdminSessions(ctx context.Context, username, exceptID string) (int64, error) {
+	return 0, nil
+}
+
+func (m *MockRepository) SetAdminTOTPSecret(ctx context.Context, username, secret string) error {
+	return nil
+}
+
+func (m *MockRepository) EnableAdminTOTP(ctx context.Context, username string, step int64, recoveryCodeHashes []string) error {
+	return nil
+}
+
+func (m *MockRepository) DisableAdminTOTP(ctx context.Context, username string) error {
+	return nil
+}
+
+func (m *MockRepository) SetAdminTOTPRequired(ctx context.Context, username string, required bool) error {
+	return nil
+}
+
+func (m *MockRepository) UseAdminTOTPStep(ctx context.Context, username string, step int64) (bool, error) {
+	return true, nil
+}
+
+func (m *MockRepository) ReplaceAdminRecoveryCodes(ctx context.Context, username string, codeHashes []string) error {
+	return nil
+}
+
+func (m *MockRepository) UseAdminRecoveryCode(ctx context.Context, username, codeHash string) (bool, error) {
+	return false, nil
+}
+
+func (m *MockRepository) CountAdminRecoveryCodes(ctx context.Context, username string) (int, error) {
+	return 0, nil
+}
+
+func (m *MockRepository) GetLoginThrottle(ctx context.Context, kind, identity string) (*LoginThrottle, error) {
+	return nil, ErrLoginThrottleNotFound
+}
+
+func (m *MockRepository) SaveLoginThrottle(ctx context.Context, throttle *LoginThrottle) error {
+	return nil
+}
+
+func (m *MockRepository) DeleteLoginThrottle(ctx context.Context, kind, identity string) error {
+	return nil
+}
+
+func (m *MockRepository) ListLoginThrottles(ctx context.Context) ([]*LoginThrottle, error) {
+	return []*LoginThrottle{}, nil
+}
+
+func (m *MockRepository) CreateLoginFailure(ctx context.Context, failure *LoginFailure) error {
+	return nil
+}
+
+func (m *MockRepository) ListLoginFailures(ctx context.Context, limit int) ([]*LoginFailure, error) {
+	return []*LoginFailure{}, nil
+}
+
+func (m *MockRepository) CreateAPIKey(ctx context.Context, key *APIKey) error {
+	return nil
+}
+
+func (m *MockRepository) GetAPIKeyByHash(ctx context.Context, keyHash string) (*APIKey, error) {
+	if m.GetAPIKeyByHashFunc != nil {
+		return m.GetAPIKeyByHashFunc(ctx, keyHash)
+	}
+	return nil, ErrAPIKeyNotFound
+}
+
+func (m *MockRepository) ListAPIKeys(ctx context.Context) ([]*APIKey, error) {
+	return []*APIKey{}, nil
+}
+
+func (m *MockRepository) RevokeAPIKey(ctx context.Context, id int64) error {
+	return nil
+}
+
+func (m *MockRepository) RecordAPIKeyUsage(ctx context.Context, id int64, count int64, at time.Time, ip string) error {
+	if m.RecordAPIKeyUsageFunc != nil {
+		return m.RecordAPIKeyUsageFunc(ctx, id, count, at, ip)
+	}
+	return nil
+}
+
+func (m *MockRepository) CreateAuditEntry(ctx context.Context, entry *AuditEntry) error {
+	if m.CreateAuditEntryFunc != nil {
+		return m.CreateAuditEntryFunc(ctx, entry)
+	}
+	return nil
+}
+
+func (m *MockRepository) ListAuditEntries(ctx context.Context, filter AuditFilter) ([]*AuditEntry, error) {
+	return []*AuditEntry{}, nil
+}
+
+func (m *MockRepository) RecordRedeemAttempt(ctx context.Context, attempt *RedeemAttempt) error {
+	return nil
+}
+
+func (m *MockRepository) RecordCaptchaCall(ctx context.Context, call *CaptchaCall) error {
+	return nil
+}
+
+func (m *MockRepository) CountRedemptions(ctx context.Context, filter StatsFilter, interval string, offset time.Duration) ([]*RedemptionBucket, error) {
+	if m.CountRedemptionsFunc != nil {
+		return m.CountRedemptionsFunc(ctx, filter, interval, offset)
+	}
+	return []*RedemptionBucket{}, nil
+}
+
+func (m *MockRepository) ListCodeStats(ctx context.Context, filter StatsFilter) ([]*CodeStat, error) {
+	return []*CodeStat{}, nil
+}
+
+func (m *MockRepository) GetCompletionStats(ctx context.Context, filter StatsFilter) (*CompletionStat, error) {
+	return &CompletionStat{}, nil
+}
+
+func (m *MockRepository) ListFailureReasons(ctx context.Context, filter StatsFilter) ([]*FailureReason, error) {
+	return []*FailureReason{}, nil
+}
+
+func (m *MockRepository) ListFidCoverage(ctx context.Context, limit int) ([]*FidCoverage, error) {
+	return []*FidCoverage{}, nil
+}
+
+func (m *MockRepository) ListCaptchaUsage(ctx context.Context, filter StatsFilter) ([]*CaptchaUsage, error) {
+	return []*CaptchaUsage{}, nil
+}
+
+func (m *MockRepository) PruneRedeemAttempts(ctx context.Context, before time.Time, limit int) (int64, error) {
+	return 0, nil
+}
+
+func (m *MockRepository) PruneCaptchaCalls(ctx context.Context, before time.Time, limit int) (int64, error) {
+	return 0, nil
+}
+
+func (m *MockRepository) PruneAuditLog(ctx context.Context, before time.Time, limit int) (int64, error) {
+	return 0, nil
+}
+
+func (m *MockRepository) PruneNotifications(ctx context.Context, before time.Time, limit int) (int64, error) {
+	return 0, nil
+}
+
+func (m *MockRepository) PruneCompletedTasks(ctx context.Context, before time.Time, limit int) (int64, error) {
+	return 0, nil
+}
+
+func (m *MockRepository) PruneLoginFailures(ctx context.Context, before time.Time, limit int) (int64, error) {
+	return 0, nil
+}
+
+func (m *MockRepository) SavePruneRun(ctx context.Context, run *PruneRun) error {
+	return nil
+}
+
+func (m *MockRepository) ListLatestPruneRuns(ctx context.Context) ([]*PruneRun, error) {
+	return []*PruneRun{}, nil
+}
+
+func (m *MockRepository) GetTableStats(ctx context.Context) ([]*TableStat, error) {
+	return []*TableStat{}, nil
+}
+
 func (m *MockRepository) WithTransaction(ctx context.Context, fn func(Repository) error) error {
 	return fn(m)
 }
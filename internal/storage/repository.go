@@ -2,7 +2,9 @@ package storage
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
+	"slices"
 	"time"
 )
 
@@ -14,26 +16,211 @@ type Repository interface {
 	ListGiftCodesByFID(ctx context.Context, fid string) ([]*GiftCodeRecord, error)
 
 	// User operations
+	// SaveUser 保存或更新用户，用户在回收站中时不做修改并返回 ErrUserInTrash
 	SaveUser(ctx context.Context, user *User) error
 	GetUser(ctx context.Context, fid string) (*User, error)
 	ListUsers(ctx context.Context) ([]*User, error)
+	// QueryUsers 按筛选条件分页列出未删除的用户
+	QueryUsers(ctx context.Context, filter UserFilter) (*Page[User], error)
+	// DeleteUser 软删除用户，兑换记录保留至回收站清除
+	// 如果用户不存在或已删除，返回 ErrUserNotFound
+	DeleteUser(ctx context.Context, fid string) error
+	// UpdateUser 更新用户资料（昵称、区服、头像），用户不存在时返回 ErrUserNotFound
+	UpdateUser(ctx context.Context, user *User) error
+	// SetUserDisabled 启用/禁用用户，禁用的用户不参与兑换但保留兑换记录
+	SetUserDisabled(ctx context.Context, fid string, disabled bool) error
+	// SetUserGroups 以 groupIDs 覆盖用户所属分组
+	SetUserGroups(ctx context.Context, fid string, groupIDs []int64) error
+
+	// ListUserNotificationTargets 列出用户的个人通知目标
+	ListUserNotificationTargets(ctx context.Context, fid string) ([]*NotificationTarget, error)
+	// SetUserNotificationTargets 以 targets 覆盖用户的个人通知目标，用户不存在时返回 ErrUserNotFound
+	SetUserNotificationTargets(ctx context.Context, fid string, targets []*NotificationTarget) error
+
+	// ListUserProfileHistory 按时间倒序列出用户的资料变更记录
+	ListUserProfileHistory(ctx context.Context, fid string) ([]*ProfileChange, error)
+
+	// User group operations
+	// 分组不存在时返回 ErrGroupNotFound，名称重复时返回 ErrGroupNameTaken
+	CreateUserGroup(ctx context.Context, group *UserGroup) error
+	UpdateUserGroup(ctx context.Context, group *UserGroup) error
+	// DeleteUserGroup 删除分组，仍有未完成任务指向该分组时返回 ErrGroupInUse
+	DeleteUserGroup(ctx context.Context, id int64) error
+	GetUserGroup(ctx context.Context, id int64) (*UserGroup, error)
+	ListUserGroups(ctx context.Context) ([]*UserGroup, error)
+	// ListGroupFids 列出分组内参与兑换的用户（未删除且未禁用）
+	ListGroupFids(ctx context.Context, groupID int64) ([]string, error)
 
 	// Task operations
-	CreateTask(ctx context.Context, code string) error
+	// CreateTask 创建任务，返回是否插入了新任务；兑换码已存在（包括在回收站中）时返回 false
+	CreateTask(ctx context.Context, code string) (bool, error)
 	ListPendingTasks(ctx context.Context) ([]*Task, error)
 	MarkTaskComplete(ctx context.Context, code string) error
 	GetTaskByCode(ctx context.Context, code string) (*Task, error)
 	UpdateTaskRetry(ctx context.Context, code string, retryCount int, lastError string) error
 	UpdateTaskComplete(ctx context.Context, code string, completedAt time.Time) error
 	ListCompletedTasks(ctx context.Context, limit int) ([]*Task, error)
-	// DeleteTask 删除任务及其关联的兑换码
-	// 在事务中执行，确保原子性
-	// 如果任务不存在，返回 ErrTaskNotFound
+	// QueryTasks 按筛选条件分页列出未删除的任务
+	QueryTasks(ctx context.Context, filter TaskFilter) (*Page[Task], error)
+	// SetTaskTargetGroup 设置任务的目标分组，groupID 为 nil 表示所有用户
+	SetTaskTargetGroup(ctx context.Context, code string, groupID *int64) error
+	// DeleteTask 软删除任务，任务移至回收站，关联的兑换码保留
+	// 如果任务不存在或已删除，返回 ErrTaskNotFound
 	DeleteTask(ctx context.Context, code string) error
 
+	// Trash operations
+	// 恢复/彻底删除仅作用于回收站中的记录，不存在时返回 ErrTaskNotFound / ErrUserNotFound
+	ListDeletedTasks(ctx context.Context) ([]*Task, error)
+	RestoreTask(ctx context.Context, code string) error
+	PurgeTask(ctx context.Context, code string) error
+	ListDeletedUsers(ctx context.Context) ([]*User, error)
+	RestoreUser(ctx context.Context, fid string) error
+	PurgeUser(ctx context.Context, fid string) error
+	// PurgeDeletedTasks 彻底删除删除时间早于 before 的任务及其兑换记录，单次最多删除 limit 个任务
+	PurgeDeletedTasks(ctx context.Context, before time.Time, limit int) (int64, error)
+	// PurgeDeletedUsers 彻底删除删除时间早于 before 的用户及其兑换记录，单次最多删除 limit 个用户
+	PurgeDeletedUsers(ctx context.Context, before time.Time, limit int) (int64, error)
+
 	// Notification operations
 	SaveNotification(ctx context.Context, notification *Notification) error
 	ListNotifications(ctx context.Context, limit int) ([]*Notification, error)
+	// QueryNotifications 按筛选条件分页列出通知记录
+	QueryNotifications(ctx context.Context, filter NotificationFilter) (*Page[Notification], error)
+	// GetNotification 获取通知记录，不存在时返回 ErrNotificationNotFound
+	GetNotification(ctx context.Context, id int64) (*Notification, error)
+	// ListDueNotifications 列出下次投递时间不晚于 now 的 pending 通知，按投递时间排序
+	ListDueNotifications(ctx context.Context, now time.Time, limit int) ([]*Notification, error)
+	// GetBatchDeadline 返回 batchKey 对应的、尚未到达投递时间且未尝试过的汇总批次的投递时间，没有时返回 nil
+	GetBatchDeadline(ctx context.Context, batchKey string, now time.Time) (*time.Time, error)
+	// ListDueBatch 列出 batchKey 对应的、下次投递时间不晚于 now 的 pending 通知
+	ListDueBatch(ctx context.Context, batchKey string, now time.Time) ([]*Notification, error)
+	// UpdateNotificationDelivery 保存一次投递后的状态、结果、尝试次数和下次投递时间
+	UpdateNotificationDelivery(ctx context.Context, notification *Notification) error
+	// ResendNotification 将通知重置为 pending 并立即投递，尝试次数清零
+	// 不存在时返回 ErrNotificationNotFound
+	ResendNotification(ctx context.Context, id int64) error
+
+	// RecordLoginIP 记录管理员登录IP
+	// 该用户此前从其他IP登录过且本次IP为首次出现时返回 true
+	RecordLoginIP(ctx context.Context, username, ip string) (bool, error)
+
+	// Admin account operations
+	// 账号不存在时返回 ErrAdminNotFound，用户名重复时返回 ErrAdminNameTaken
+	CreateAdmin(ctx context.Context, admin *Admin) error
+	// BootstrapAdmin 仅在没有任何管理员账号时创建 admin，返回是否已创建
+	BootstrapAdmin(ctx context.Context, admin *Admin) (bool, error)
+	GetAdmin(ctx context.Context, username string) (*Admin, error)
+	ListAdmins(ctx context.Context) ([]*Admin, error)
+	// UpdateAdminRole 修改角色，降级最后一个 owner 时返回 ErrLastOwner
+	UpdateAdminRole(ctx context.Context, username, role string) error
+	UpdateAdminPassword(ctx context.Context, username, passwordHash string) error
+	// DeleteAdmin 删除管理员账号及其会话，删除最后一个 owner 时返回 ErrLastOwner
+	DeleteAdmin(ctx context.Context, username string) error
+
+	// Admin session operations
+	// CreateAdminSession 保存新会话，同时清理已过期的会话
+	CreateAdminSession(ctx context.Context, session *AdminSession) error
+	// GetAdminSession 获取会话（包括已撤销的），不存在时返回 ErrSessionNotFound
+	GetAdminSession(ctx context.Context, id string) (*AdminSession, error)
+	// RotateAdminSession 以新的刷新令牌哈希替换 oldHash，并更新IP、UA、最近使用时间和过期时间
+	// oldHash 无效、已过期或会话已撤销时返回 ErrSessionNotFound；
+	// oldHash 是已被轮换掉的旧令牌时撤销该会话并返回 ErrRefreshTokenReused
+	RotateAdminSession(ctx context.Context, oldHash string, session *AdminSession) (*AdminSession, error)
+	// TouchAdminSession 更新会话的最近使用时间
+	TouchAdminSession(ctx context.Context, id string, at time.Time) error
+	// ListAdminSessions 按最近使用时间倒序列出管理员在 now 时仍有效的会话
+	ListAdminSessions(ctx context.Context, username string, now time.Time) ([]*AdminSession, error)
+	// RevokeAdminSession 撤销管理员的一个会话，不存在或已撤销时返回 ErrSessionNotFound
+	RevokeAdminSession(ctx context.Context, username, id string) error
+	// RevokeAdminSessions 撤销管理员除 exceptID 外的全部会话，返回撤销数量
+	RevokeAdminSessions(ctx context.Context, username, exceptID string) (int64, error)
+
+	// Admin two-factor operations
+	// SetAdminTOTPSecret 保存待启用的 TOTP 密钥，已启用的两步验证保持不变
+	SetAdminTOTPSecret(ctx context.Context, username, secret string) error
+	// EnableAdminTOTP 启用两步验证，记录已使用的时间步并替换恢复码
+	EnableAdminTOTP(ctx context.Context, username string, step int64, recoveryCodeHashes []string) error
+	// DisableAdminTOTP 停用两步验证，清除密钥和恢复码
+	DisableAdminTOTP(ctx context.Context, username string) error
+	// SetAdminTOTPRequired 设置账号是否必须启用两步验证
+	SetAdminTOTPRequired(ctx context.Context, username string, required bool) error
+	// UseAdminTOTPStep 记录通过校验的时间步，step 不晚于上次记录时返回 false（动态码重放）
+	UseAdminTOTPStep(ctx context.Context, username string, step int64) (bool, error)
+	// ReplaceAdminRecoveryCodes 以新的恢复码哈希替换账号的全部恢复码
+	ReplaceAdminRecoveryCodes(ctx context.Context, username string, codeHashes []string) error
+	// UseAdminRecoveryCode 使用一个恢复码，不存在或已使用时返回 false
+	UseAdminRecoveryCode(ctx context.Context, username, codeHash string) (bool, error)
+	// CountAdminRecoveryCodes 统计账号未使用的恢复码数量
+	CountAdminRecoveryCodes(ctx context.Context, username string) (int, error)
+
+	// Login protection operations
+	// GetLoginThrottle 获取 IP 或用户名的登录失败计数，不存在时返回 ErrLoginThrottleNotFound
+	GetLoginThrottle(ctx context.Context, kind, identity string) (*LoginThrottle, error)
+	// SaveLoginThrottle 保存登录失败计数，已存在时覆盖
+	SaveLoginThrottle(ctx context.Context, throttle *LoginThrottle) error
+	// DeleteLoginThrottle 清除登录失败计数（解除锁定），不存在时返回 ErrLoginThrottleNotFound
+	DeleteLoginThrottle(ctx context.Context, kind, identity string) error
+	// ListLoginThrottles 按最近失败时间倒序列出全部登录失败计数
+	ListLoginThrottles(ctx context.Context) ([]*LoginThrottle, error)
+	// CreateLoginFailure 记录一次失败的登录
+	CreateLoginFailure(ctx context.Context, failure *LoginFailure) error
+	// ListLoginFailures 按时间倒序列出最近 limit 条失败的登录
+	ListLoginFailures(ctx context.Context, limit int) ([]*LoginFailure, error)
+
+	// API key operations
+	// CreateAPIKey 保存新的 API 密钥
+	CreateAPIKey(ctx context.Context, key *APIKey) error
+	// GetAPIKeyByHash 按密钥哈希获取未撤销的 API 密钥，不存在或已撤销时返回 ErrAPIKeyNotFound
+	GetAPIKeyByHash(ctx context.Context, keyHash string) (*APIKey, error)
+	// ListAPIKeys 按创建时间倒序列出全部 API 密钥（包括已撤销的）
+	ListAPIKeys(ctx context.Context) ([]*APIKey, error)
+	// RevokeAPIKey 撤销 API 密钥，不存在或已撤销时返回 ErrAPIKeyNotFound
+	RevokeAPIKey(ctx context.Context, id int64) error
+	// RecordAPIKeyUsage 使用次数增加 count 并记录最近使用时间和 IP
+	RecordAPIKeyUsage(ctx context.Context, id int64, count int64, at time.Time, ip string) error
+
+	// Audit log operations
+	// CreateAuditEntry 记录一次管理操作
+	CreateAuditEntry(ctx context.Context, entry *AuditEntry) error
+	// ListAuditEntries 按时间倒序列出符合筛选条件的管理操作记录
+	ListAuditEntries(ctx context.Context, filter AuditFilter) ([]*AuditEntry, error)
+
+	// Statistics operations
+	// RecordRedeemAttempt 记录一次兑换请求的结果
+	RecordRedeemAttempt(ctx context.Context, attempt *RedeemAttempt) error
+	// RecordCaptchaCall 记录一次验证码识别
+	RecordCaptchaCall(ctx context.Context, call *CaptchaCall) error
+	// CountRedemptions 按天或周（StatsIntervalDay/StatsIntervalWeek）统计兑换请求结果，只返回有请求的时间段
+	// offset 为划分日期所用时区相对 UTC 的偏移
+	CountRedemptions(ctx context.Context, filter StatsFilter, interval string, offset time.Duration) ([]*RedemptionBucket, error)
+	// ListCodeStats 按最近一次兑换请求倒序列出各兑换码的兑换请求统计
+	ListCodeStats(ctx context.Context, filter StatsFilter) ([]*CodeStat, error)
+	// GetCompletionStats 统计完成时间在范围内的任务从添加到完成的耗时
+	GetCompletionStats(ctx context.Context, filter StatsFilter) (*CompletionStat, error)
+	// ListFailureReasons 按次数倒序列出兑换失败的原因
+	ListFailureReasons(ctx context.Context, filter StatsFilter) ([]*FailureReason, error)
+	// ListFidCoverage 按领取比例升序列出未删除用户已领取和可领取的兑换码数，最多 limit 个
+	ListFidCoverage(ctx context.Context, limit int) ([]*FidCoverage, error)
+	// ListCaptchaUsage 按提供商统计验证码识别次数和费用
+	ListCaptchaUsage(ctx context.Context, filter StatsFilter) ([]*CaptchaUsage, error)
+
+	// Retention operations
+	// PruneNotifications 删除创建时间早于 before 的通知记录，单次最多删除 limit 行
+	PruneNotifications(ctx context.Context, before time.Time, limit int) (int64, error)
+	// PruneCompletedTasks 删除完成时间早于 before 的任务及其兑换记录，单次最多删除 limit 个任务
+	PruneCompletedTasks(ctx context.Context, before time.Time, limit int) (int64, error)
+	// PruneAuditLog 删除早于 before 的管理操作记录，单次最多删除 limit 条记录
+	PruneAuditLog(ctx context.Context, before time.Time, limit int) (int64, error)
+	// PruneLoginFailures 删除早于 before 的登录失败记录，以及最近失败早于 before 且未锁定的失败计数，单次最多删除 limit 条记录
+	PruneLoginFailures(ctx context.Context, before time.Time, limit int) (int64, error)
+	// PruneRedeemAttempts 删除早于 before 的兑换请求记录，单次最多删除 limit 条记录
+	PruneRedeemAttempts(ctx context.Context, before time.Time, limit int) (int64, error)
+	// PruneCaptchaCalls 删除早于 before 的验证码识别记录，单次最多删除 limit 条记录
+	PruneCaptchaCalls(ctx context.Context, before time.Time, limit int) (int64, error)
+	SavePruneRun(ctx context.Context, run *PruneRun) error
+	// ListLatestPruneRuns 列出每个清理目标最近一次的执行记录
+	ListLatestPruneRuns(ctx context.Context) ([]*PruneRun, error)
+	GetTableStats(ctx context.Context) ([]*TableStat, error)
 
 	// Transaction support
 	WithTransaction(ctx context.Context, fn func(Repository) error) error
@@ -45,12 +232,15 @@ type Repository interface {
 
 // User 用户模型
 type User struct {
-	FID         string    `json:"fid"`
-	Nickname    string    `json:"nickname"`
-	KID         int       `json:"kid"`
-	AvatarImage string    `json:"avatar_image"`
-	CreatedAt   time.Time `json:"created_at"`
-	UpdatedAt   time.Time `json:"updated_at"`
+	FID         string     `json:"fid"`
+	Nickname    string     `json:"nickname"`
+	KID         int        `json:"kid"`
+	AvatarImage string     `json:"avatar_image"`
+	Disabled    bool       `json:"disabled"`
+	Groups      []string   `json:"groups"`
+	CreatedAt   time.Time  `json:"created_at"`
+	UpdatedAt   time.Time  `json:"updated_at"`
+	DeletedAt   *time.Time `json:"deleted_at,omitempty"`
 }
 
 // Task 任务模型
@@ -62,6 +252,367 @@ type Task struct {
 	CreatedAt   time.Time  `json:"created_at"`
 	UpdatedAt   time.Time  `json:"updated_at"`
 	CompletedAt *time.Time `json:"completed_at,omitempty"`
+	DeletedAt   *time.Time `json:"deleted_at,omitempty"`
+	// TargetGroupID 目标分组，nil 表示所有用户
+	TargetGroupID *int64 `json:"target_group_id,omitempty"`
+	TargetGroup   string `json:"target_group,omitempty"`
+}
+
+// UserGroup 用户分组模型
+type UserGroup struct {
+	ID          int64     `json:"id"`
+	Name        string    `json:"name"`
+	Description string    `json:"description"`
+	MemberCount int       `json:"member_count"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+// NotificationTarget 用户的个人通知目标
+type NotificationTarget struct {
+	ID        int64     `json:"id"`
+	FID       string    `json:"fid"`
+	Type      string    `json:"type"`   // wxpusher, email, telegram, webhook
+	Target    string    `json:"target"` // WxPusher UID / 邮箱 / Telegram chat id / webhook 地址
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// NotificationTargetType 个人通知目标类型常量，与通知渠道类型一致
+const (
+	NotificationTargetWxPusher = "wxpusher"
+	NotificationTargetEmail    = "email"
+	NotificationTargetTelegram = "telegram"
+	NotificationTargetWebhook  = "webhook"
+)
+
+// Admin 管理后台账号
+type Admin struct {
+	ID           int64  `json:"id"`
+	Username     string `json:"username"`
+	PasswordHash string `json:"-"`
+	Role         string `json:"role"` // owner, operator, viewer
+	// TOTPSecret 在确认首个动态码前为待启用的密钥，TOTPEnabled 为 true 后才在登录时校验
+	TOTPSecret   string    `json:"-"`
+	TOTPEnabled  bool      `json:"totp_enabled"`
+	TOTPRequired bool      `json:"totp_required"` // owner 要求该账号启用两步验证
+	TOTPLastStep int64     `json:"-"`             // 最近一次通过校验的时间步，防止动态码重放
+	CreatedAt    time.Time `json:"created_at"`
+	UpdatedAt    time.Time `json:"updated_at"`
+}
+
+// AdminSession 管理员登录会话，刷新令牌仅保存哈希
+type AdminSession struct {
+	ID               string     `json:"id"`
+	Username         string     `json:"username"`
+	RefreshTokenHash string     `json:"-"`
+	IP               string     `json:"ip"`
+	UserAgent        string     `json:"user_agent"`
+	CreatedAt        time.Time  `json:"created_at"`
+	LastUsedAt       time.Time  `json:"last_used_at"`
+	ExpiresAt        time.Time  `json:"expires_at"`
+	RevokedAt        *time.Time `json:"revoked_at,omitempty"`
+}
+
+// Active 判断会话在 now 时是否仍有效
+func (s *AdminSession) Active(now time.Time) bool {
+	return s.RevokedAt == nil && now.Before(s.ExpiresAt)
+}
+
+// LoginThrottle 登录失败计数，按客户端 IP 和用户名分别统计
+type LoginThrottle struct {
+	Kind         string     `json:"kind"` // ip 或 username
+	Identity     string     `json:"identity"`
+	Failures     int        `json:"failures"` // 计数窗口内的连续失败次数
+	Lockouts     int        `json:"lockouts"` // 连续锁定次数，决定下次锁定的时长
+	LastFailedAt time.Time  `json:"last_failed_at"`
+	LockedUntil  *time.Time `json:"locked_until,omitempty"`
+}
+
+// Locked 判断在 now 时是否处于锁定状态
+func (t *LoginThrottle) Locked(now time.Time) bool {
+	return t.LockedUntil != nil && now.Before(*t.LockedUntil)
+}
+
+// LoginThrottleKind 登录失败计数的统计维度
+const (
+	LoginThrottleKindIP       = "ip"
+	LoginThrottleKindUsername = "username"
+)
+
+// LoginFailure 失败的登录审计记录
+type LoginFailure struct {
+	ID        int64     `json:"id"`
+	Username  string    `json:"username"`
+	IP        string    `json:"ip"`
+	UserAgent string    `json:"user_agent"`
+	Reason    string    `json:"reason"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// AuditEntry 管理操作审计记录
+// Before/After 为变更前后的记录快照（JSON），不适用时为空
+type AuditEntry struct {
+	ID        int64           `json:"id"`
+	Actor     string          `json:"actor"`
+	Action    string          `json:"action"`
+	Target    string          `json:"target"`
+	IP        string          `json:"ip"`
+	RequestID string          `json:"request_id"`
+	Before    json.RawMessage `json:"before,omitempty"`
+	After     json.RawMessage `json:"after,omitempty"`
+	CreatedAt time.Time       `json:"created_at"`
+}
+
+// AuditFilter 管理操作记录筛选条件，零值字段不参与筛选
+type AuditFilter struct {
+	Actor  string
+	Action string // 精确匹配，以 "." 结尾时按前缀匹配，如 "task." 匹配所有任务操作
+	Target string
+	Since  time.Time
+	Until  time.Time
+	Limit  int
+}
+
+// 兑换请求结果常量
+const (
+	RedeemStatusSuccess  = "success"
+	RedeemStatusReceived = "received"  // 该用户已兑换过
+	RedeemStatusNotFound = "not_found" // 兑换码不存在
+	RedeemStatusFailed   = "failed"
+)
+
+// RedeemAttempt 一次兑换请求及其结果
+type RedeemAttempt struct {
+	ID        int64     `json:"id"`
+	Code      string    `json:"code"`
+	FID       string    `json:"fid"`
+	Status    string    `json:"status"`
+	Message   string    `json:"message,omitempty"` // 失败原因，仅 failed 有值
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// CaptchaCall 一次验证码识别，Cost 为识别时提供商的单次费用
+type CaptchaCall struct {
+	ID        int64     `json:"id"`
+	Provider  string    `json:"provider"`
+	Success   bool      `json:"success"`
+	Cost      float64   `json:"cost"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// 统计的时间粒度
+const (
+	StatsIntervalDay  = "day"
+	StatsIntervalWeek = "week" // 每周从周一开始
+)
+
+// StatsFilter 统计的时间范围 [Since, Until)，零值表示不限制；Limit 为列表统计的最大条数
+type StatsFilter struct {
+	Since time.Time
+	Until time.Time
+	Limit int
+}
+
+// RedemptionBucket 一个时间段内各结果的兑换请求数
+type RedemptionBucket struct {
+	Period   string `json:"period"` // 时间段开始日期 yyyy-mm-dd，按周统计时为周一
+	Success  int64  `json:"success"`
+	Received int64  `json:"received"`
+	NotFound int64  `json:"not_found"`
+	Failed   int64  `json:"failed"`
+}
+
+// CodeStat 单个兑换码的兑换请求统计
+type CodeStat struct {
+	Code     string `json:"code"`
+	Attempts int64  `json:"attempts"`
+	Success  int64  `json:"success"`
+	Received int64  `json:"received"`
+	NotFound int64  `json:"not_found"`
+	Failed   int64  `json:"failed"`
+	FIDs     int64  `json:"fids"` // 发起过兑换请求的用户数
+	// SuccessRate 兑换成功或已兑换的请求占全部请求的比例
+	SuccessRate float64 `json:"success_rate"`
+}
+
+// CompletionStat 任务从添加到完成的耗时（秒），Tasks 为 0 时其余字段为 0
+type CompletionStat struct {
+	Tasks      int64   `json:"tasks"`
+	AvgSeconds float64 `json:"avg_seconds"`
+	MinSeconds float64 `json:"min_seconds"`
+	MaxSeconds float64 `json:"max_seconds"`
+}
+
+// FailureReason 一种兑换失败原因的次数
+type FailureReason struct {
+	Reason string `json:"reason"`
+	Count  int64  `json:"count"`
+	Codes  int64  `json:"codes"` // 涉及的兑换码数
+	FIDs   int64  `json:"fids"`  // 涉及的用户数
+}
+
+// FidCoverage 用户已领取的兑换码数与可领取的兑换码数
+// 可领取的兑换码为未删除、目标为所有用户或用户所在分组的任务
+type FidCoverage struct {
+	FID       string  `json:"fid"`
+	Nickname  string  `json:"nickname"`
+	KID       int     `json:"kid"`
+	Disabled  bool    `json:"disabled"`
+	Received  int64   `json:"received"`
+	Available int64   `json:"available"`
+	Coverage  float64 `json:"coverage"` // Received / Available，没有可领取的兑换码时为 1
+}
+
+// CaptchaUsage 一个验证码提供商的识别次数和估算费用
+type CaptchaUsage struct {
+	Provider string  `json:"provider"`
+	Calls    int64   `json:"calls"`
+	Failures int64   `json:"failures"`
+	Cost     float64 `json:"cost"`
+}
+
+// 列表分页大小
+const (
+	DefaultPageSize = 50
+	MaxPageSize     = 500
+)
+
+// 排序方向
+const (
+	SortAsc  = "asc"
+	SortDesc = "desc"
+)
+
+// ListOptions 列表查询的游标分页和排序参数
+// Cursor 为上一页返回的 NextCursor，为空表示第一页；游标只在筛选和排序条件不变时有效
+type ListOptions struct {
+	Cursor string
+	Limit  int    // <= 0 时为 DefaultPageSize，超过 MaxPageSize 时按 MaxPageSize
+	Sort   string // 排序字段，为空时使用列表的默认排序
+	Order  string // SortAsc 或 SortDesc，为空时使用排序字段的默认方向
+}
+
+// Page 分页结果，NextCursor 为空表示没有更多数据
+type Page[T any] struct {
+	Items      []*T   `json:"items"`
+	NextCursor string `json:"next_cursor,omitempty"`
+}
+
+// 用户排序字段
+const (
+	UserSortFID      = "fid"
+	UserSortNickname = "nickname"
+	UserSortKID      = "kid"
+)
+
+// UserFilter 用户列表筛选条件，零值字段不参与筛选
+type UserFilter struct {
+	ListOptions
+	Search   string // 按 fid 或昵称模糊匹配
+	KID      *int
+	Disabled *bool
+}
+
+// 任务状态筛选
+const (
+	TaskStatusPending   = "pending"
+	TaskStatusCompleted = "completed"
+)
+
+// 任务排序字段
+const (
+	TaskSortCode        = "code"
+	TaskSortCreatedAt   = "created_at"
+	TaskSortCompletedAt = "completed_at"
+	TaskSortRetryCount  = "retry_count"
+)
+
+// TaskFilter 任务列表筛选条件，零值字段不参与筛选
+// Since/Until 在 Status 为 completed 时按完成时间筛选，否则按创建时间筛选
+type TaskFilter struct {
+	ListOptions
+	Search string // 按兑换码模糊匹配
+	Status string // TaskStatusPending 或 TaskStatusCompleted，为空表示全部
+	Since  time.Time
+	Until  time.Time
+}
+
+// 通知排序字段
+const (
+	NotificationSortCreatedAt = "created_at"
+	NotificationSortAttempts  = "attempts"
+)
+
+// NotificationFilter 通知列表筛选条件，零值字段不参与筛选
+type NotificationFilter struct {
+	ListOptions
+	Search  string // 按 fid、标题或内容模糊匹配
+	Status  string
+	Channel string
+	Event   string
+	Since   time.Time
+	Until   time.Time
+}
+
+// LoginFailureReason 登录失败原因
+const (
+	LoginFailureInvalidCredentials = "invalid_credentials" // 用户名或密码错误
+	LoginFailureInvalidTOTP        = "invalid_totp"        // 两步验证码错误
+	LoginFailureBlocked            = "blocked"             // 处于锁定或等待期间，未校验密码
+)
+
+// APIKey 调用公开接口的 API 密钥
+type APIKey struct {
+	ID         int64      `json:"id"`
+	Name       string     `json:"name"`
+	Prefix     string     `json:"prefix"` // 密钥开头的几个字符，便于识别
+	KeyHash    string     `json:"-"`
+	Scopes     []string   `json:"scopes"`
+	CreatedBy  string     `json:"created_by"`
+	CreatedAt  time.Time  `json:"created_at"`
+	LastUsedAt *time.Time `json:"last_used_at,omitempty"`
+	LastUsedIP string     `json:"last_used_ip"`
+	UsageCount int64      `json:"usage_count"`
+	RevokedAt  *time.Time `json:"revoked_at,omitempty"`
+}
+
+// HasScope 判断密钥是否拥有 scope 权限
+func (k *APIKey) HasScope(scope string) bool {
+	return slices.Contains(k.Scopes, scope)
+}
+
+// APIKeyScope API 密钥权限常量
+const (
+	APIKeyScopeEnqueueCode = "enqueue-code" // 添加兑换码任务
+	APIKeyScopeAddUser     = "add-user"     // 添加用户
+	APIKeyScopeReadOnly    = "read-only"    // 查询兑换码任务状态
+)
+
+// APIKeyScopes lists every API key scope
+var APIKeyScopes = []string{APIKeyScopeEnqueueCode, APIKeyScopeAddUser, APIKeyScopeReadOnly}
+
+// AdminRole 管理员角色常量
+// owner 可管理管理员账号，operator 可修改数据，viewer 只读
+const (
+	AdminRoleOwner    = "owner"
+	AdminRoleOperator = "operator"
+	AdminRoleViewer   = "viewer"
+)
+
+// 资料变更字段
+const (
+	ProfileFieldNickname = "nickname"
+	ProfileFieldKID      = "kid"
+	ProfileFieldAvatar   = "avatar_image"
+)
+
+// ProfileChange 用户资料变更记录模型
+type ProfileChange struct {
+	ID        int64     `json:"id"`
+	FID       string    `json:"fid"`
+	Field     string    `json:"field"`
+	OldValue  string    `json:"old_value"`
+	NewValue  string    `json:"new_value"`
+	ChangedAt time.Time `json:"changed_at"`
 }
 
 // GiftCodeRecord 礼品码记录模型
@@ -82,21 +633,108 @@ const (
 )
 
 // Notification 通知记录模型
+// 记录同时作为投递队列：先以 pending 状态保存，由投递任务发送后更新为 success 或 failed
+// 设置了 BatchKey 的记录按批次合并发送，同一批次的记录共享投递结果
 type Notification struct {
-	ID        int64     `json:"id"`
-	Channel   string    `json:"channel"`
-	Title     string    `json:"title"`
-	Content   string    `json:"content"`
-	Result    string    `json:"result"`
-	Status    string    `json:"status"`
-	CreatedAt time.Time `json:"created_at"`
+	ID            int64      `json:"id"`
+	Channel       string     `json:"channel"`
+	Event         string     `json:"event"`
+	FID           string     `json:"fid,omitempty"`       // 个人通知的用户
+	Recipient     string     `json:"recipient,omitempty"` // 个人通知的接收目标，为空表示发送给渠道配置的接收人
+	BatchKey      string     `json:"batch_key,omitempty"` // 汇总批次，相同批次的 pending 通知合并为一条发送
+	Title         string     `json:"title"`
+	Summary       string     `json:"summary"`
+	Content       string     `json:"content"`
+	Result        string     `json:"result"`
+	Status        string     `json:"status"`
+	Attempts      int        `json:"attempts"`
+	NextAttemptAt *time.Time `json:"next_attempt_at,omitempty"`
+	SentAt        *time.Time `json:"sent_at,omitempty"`
+	CreatedAt     time.Time  `json:"created_at"`
+	UpdatedAt     time.Time  `json:"updated_at"`
 }
 
 // NotificationStatus 通知状态常量
 const (
+	NotificationStatusPending = "pending"
 	NotificationStatusSuccess = "success"
 	NotificationStatusFailed  = "failed"
 )
 
+// PruneRun 数据清理执行记录
+type PruneRun struct {
+	ID         int64     `json:"id"`
+	Target     string    `json:"target"`
+	Cutoff     time.Time `json:"cutoff"`
+	Deleted    int64     `json:"deleted"`
+	Status     string    `json:"status"`
+	Error      string    `json:"error,omitempty"`
+	StartedAt  time.Time `json:"started_at"`
+	FinishedAt time.Time `json:"finished_at"`
+}
+
+// PruneTarget 清理目标常量
+const (
+	PruneTargetNotifications  = "notifications"
+	PruneTargetCompletedTasks = "completed_tasks"
+	PruneTargetDeletedTasks   = "deleted_tasks"
+	PruneTargetDeletedUsers   = "deleted_users"
+	PruneTargetLoginFailures  = "login_failures"
+	PruneTargetAuditLog       = "audit_log"
+	PruneTargetRedeemAttempts = "redeem_attempts"
+	PruneTargetCaptchaCalls   = "captcha_calls"
+)
+
+// PruneRunStatus 清理执行状态常量
+const (
+	PruneRunStatusSuccess = "success"
+	PruneRunStatusFailed  = "failed"
+)
+
+// TableStat 数据表行数统计
+type TableStat struct {
+	Table string `json:"table"`
+	Rows  int64  `json:"rows"`
+}
+
 // ErrTaskNotFound 任务不存在错误
 var ErrTaskNotFound = errors.New("task not found")
+
+// ErrUserNotFound 用户不存在错误
+var ErrUserNotFound = errors.New("user not found")
+
+// ErrUserInTrash 用户在回收站中错误
+var ErrUserInTrash = errors.New("user is in the trash")
+
+// ErrGroupNotFound 分组不存在错误
+var ErrGroupNotFound = errors.New("group not found")
+
+// ErrGroupNameTaken 分组名称已存在错误
+var ErrGroupNameTaken = errors.New("group name already taken")
+
+// ErrNotificationNotFound 通知记录不存在错误
+var ErrNotificationNotFound = errors.New("notification not found")
+
+// ErrGroupInUse 分组仍被未完成任务使用错误
+var ErrGroupInUse = errors.New("group is targeted by unfinished tasks")
+
+// ErrAdminNotFound 管理员账号不存在错误
+var ErrAdminNotFound = errors.New("admin not found")
+
+// ErrAdminNameTaken 管理员用户名已存在错误
+var ErrAdminNameTaken = errors.New("admin username already taken")
+
+// ErrLastOwner 删除或降级最后一个 owner 错误
+var ErrLastOwner = errors.New("cannot remove the last owner")
+
+// ErrSessionNotFound 会话不存在、已过期或已撤销错误
+var ErrSessionNotFound = errors.New("session not found")
+
+// ErrRefreshTokenReused 已轮换的刷新令牌被再次使用错误
+var ErrRefreshTokenReused = errors.New("refresh token reused")
+
+// ErrLoginThrottleNotFound 登录失败计数不存在错误
+var ErrLoginThrottleNotFound = errors.New("login throttle not found")
+
+// ErrAPIKeyNotFound API 密钥不存在或已撤销错误
+var ErrAPIKeyNotFound = errors.New("api key not found")
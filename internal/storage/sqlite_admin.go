@@ -0,0 +1,326 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"cdk-get/internal/errors"
+)
+
+// RecordLoginIP 记录管理员登录IP
+// 该用户此前从其他IP登录过且本次IP为首次出现时返回 true，首次登录不视为新IP
+func (r *SqliteRepository) RecordLoginIP(ctx context.Context, username, ip string) (bool, error) {
+	var newIP bool
+	err := r.inTx(ctx, func(db dbInterface) error {
+		var known, total int
+		if err := db.QueryRowContext(ctx,
+			`SELECT COUNT(*), COALESCE(SUM(ip = ?), 0) FROM admin_login_ips WHERE username = ?`,
+			ip, username).Scan(&total, &known); err != nil {
+			return errors.NewDatabaseError("check_login_ip", err)
+		}
+
+		if known > 0 {
+			if _, err := db.ExecContext(ctx,
+				`UPDATE admin_login_ips SET last_seen_at = CURRENT_TIMESTAMP WHERE username = ? AND ip = ?`,
+				username, ip); err != nil {
+				return errors.NewDatabaseError("update_login_ip", err)
+			}
+			return nil
+		}
+
+		if _, err := db.ExecContext(ctx,
+			`INSERT INTO admin_login_ips (username, ip, first_seen_at, last_seen_at) VALUES (?, ?, CURRENT_TIMESTAMP, CURRENT_TIMESTAMP)`,
+			username, ip); err != nil {
+			return errors.NewDatabaseError("insert_login_ip", err)
+		}
+		newIP = total > 0
+		return nil
+	})
+	if err != nil {
+		return false, err
+	}
+
+	if newIP {
+		r.logger.WithFields(logrus.Fields{
+			"username": username,
+			"ip":       ip,
+		}).Info("admin login from new ip")
+	}
+
+	return newIP, nil
+}
+
+// adminColumns 管理员账号查询列，与 scanAdmin 的顺序一致
+const adminColumns = `id, username, password_hash, role, totp_secret, totp_enabled, totp_required, totp_last_step, created_at, updated_at`
+
+// CreateAdmin 创建管理员账号
+func (r *SqliteRepository) CreateAdmin(ctx context.Context, admin *Admin) error {
+	if err := validateAdmin(admin); err != nil {
+		return err
+	}
+
+	err := r.inTx(ctx, func(db dbInterface) error {
+		return insertAdmin(ctx, db, admin)
+	})
+	if err != nil {
+		return err
+	}
+
+	r.logger.WithFields(logrus.Fields{
+		"username": admin.Username,
+		"role":     admin.Role,
+	}).Info("admin created")
+
+	return nil
+}
+
+// BootstrapAdmin 在 admins 表为空时创建第一个管理员账号
+func (r *SqliteRepository) BootstrapAdmin(ctx context.Context, admin *Admin) (bool, error) {
+	if err := validateAdmin(admin); err != nil {
+		return false, err
+	}
+
+	var created bool
+	err := r.inTx(ctx, func(db dbInterface) error {
+		var count int
+		if err := db.QueryRowContext(ctx, `SELECT COUNT(*) FROM admins`).Scan(&count); err != nil {
+			return errors.NewDatabaseError("count_admins", err)
+		}
+		if count > 0 {
+			return nil
+		}
+		created = true
+		return insertAdmin(ctx, db, admin)
+	})
+	if err != nil {
+		return false, err
+	}
+
+	if created {
+		r.logger.WithFields(logrus.Fields{
+			"username": admin.Username,
+			"role":     admin.Role,
+		}).Info("bootstrap admin created")
+	}
+
+	return created, nil
+}
+
+// insertAdmin 插入管理员账号，用户名重复时返回 ErrAdminNameTaken
+func insertAdmin(ctx context.Context, db dbInterface, admin *Admin) error {
+	var taken bool
+	if err := db.QueryRowContext(ctx,
+		`SELECT EXISTS(SELECT 1 FROM admins WHERE username = ?)`, admin.Username).Scan(&taken); err != nil {
+		return errors.NewDatabaseError("check_admin_username", err)
+	}
+	if taken {
+		return ErrAdminNameTaken
+	}
+
+	admin.CreatedAt = time.Now()
+	admin.UpdatedAt = admin.CreatedAt
+	result, err := db.ExecContext(ctx,
+		`INSERT INTO admins (username, password_hash, role, created_at, updated_at) VALUES (?, ?, ?, ?, ?)`,
+		admin.Username, admin.PasswordHash, admin.Role, admin.CreatedAt, admin.UpdatedAt)
+	if err != nil {
+		return errors.NewDatabaseError("create_admin", err)
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		return errors.NewDatabaseError("get_admin_id", err)
+	}
+	admin.ID = id
+	return nil
+}
+
+// GetAdmin 按用户名获取管理员账号
+func (r *SqliteRepository) GetAdmin(ctx context.Context, username string) (*Admin, error) {
+	row := r.db.QueryRowContext(ctx, `SELECT `+adminColumns+` FROM admins WHERE username = ?`, username)
+	admin, err := scanAdmin(row)
+	if err == sql.ErrNoRows {
+		return nil, ErrAdminNotFound
+	}
+	if err != nil {
+		return nil, errors.NewDatabaseError("get_admin", err)
+	}
+	return admin, nil
+}
+
+// ListAdmins 按创建顺序列出管理员账号
+func (r *SqliteRepository) ListAdmins(ctx context.Context) ([]*Admin, error) {
+	rows, err := r.db.QueryContext(ctx, `SELECT `+adminColumns+` FROM admins ORDER BY id ASC`)
+	if err != nil {
+		return nil, errors.NewDatabaseError("list_admins", err)
+	}
+	defer rows.Close()
+
+	var admins []*Admin
+	for rows.Next() {
+		admin, err := scanAdmin(rows)
+		if err != nil {
+			return nil, errors.NewDatabaseError("scan_admin", err)
+		}
+		admins = append(admins, admin)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, errors.NewDatabaseError("iterate_admins", err)
+	}
+
+	return admins, nil
+}
+
+// UpdateAdminRole 修改管理员角色，至少保留一个 owner
+func (r *SqliteRepository) UpdateAdminRole(ctx context.Context, username, role string) error {
+	if !IsValidAdminRole(role) {
+		return errors.NewValidationError("role", "must be one of owner, operator, viewer")
+	}
+
+	err := r.inTx(ctx, func(db dbInterface) error {
+		if role != AdminRoleOwner {
+			if err := checkNotLastOwner(ctx, db, username); err != nil {
+				return err
+			}
+		}
+		return updateAdmin(ctx, db, "update_admin_role",
+			`UPDATE admins SET role = ?, updated_at = CURRENT_TIMESTAMP WHERE username = ?`, role, username)
+	})
+	if err != nil {
+		return err
+	}
+
+	r.logger.WithFields(logrus.Fields{
+		"username": username,
+		"role":     role,
+	}).Info("admin role updated")
+
+	return nil
+}
+
+// UpdateAdminPassword 修改管理员密码哈希
+func (r *SqliteRepository) UpdateAdminPassword(ctx context.Context, username, passwordHash string) error {
+	if passwordHash == "" {
+		return errors.NewValidationError("password_hash", "must not be empty")
+	}
+
+	if err := updateAdmin(ctx, r.db, "update_admin_password",
+		`UPDATE admins SET password_hash = ?, updated_at = CURRENT_TIMESTAMP WHERE username = ?`,
+		passwordHash, username); err != nil {
+		return err
+	}
+
+	r.logger.WithFields(logrus.Fields{
+		"username": username,
+	}).Info("admin password updated")
+
+	return nil
+}
+
+// DeleteAdmin 删除管理员账号，至少保留一个 owner
+func (r *SqliteRepository) DeleteAdmin(ctx context.Context, username string) error {
+	err := r.inTx(ctx, func(db dbInterface) error {
+		if err := checkNotLastOwner(ctx, db, username); err != nil {
+			return err
+		}
+		if err := updateAdmin(ctx, db, "delete_admin", `DELETE FROM admins WHERE username = ?`, username); err != nil {
+			return err
+		}
+		if _, err := db.ExecContext(ctx, `DELETE FROM admin_login_ips WHERE username = ?`, username); err != nil {
+			return errors.NewDatabaseError("delete_admin_login_ips", err)
+		}
+		if _, err := db.ExecContext(ctx, `DELETE FROM admin_sessions WHERE username = ?`, username); err != nil {
+			return errors.NewDatabaseError("delete_admin_sessions", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	r.logger.WithFields(logrus.Fields{
+		"username": username,
+	}).Info("admin deleted")
+
+	return nil
+}
+
+// updateAdmin 执行修改单个管理员账号的语句，未命中任何行时返回 ErrAdminNotFound
+func updateAdmin(ctx context.Context, db dbInterface, op, query string, args ...interface{}) error {
+	result, err := db.ExecContext(ctx, query, args...)
+	if err != nil {
+		return errors.NewDatabaseError(op, err)
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return errors.NewDatabaseError("get_rows_affected", err)
+	}
+	if rowsAffected == 0 {
+		return ErrAdminNotFound
+	}
+	return nil
+}
+
+// checkNotLastOwner 账号为唯一的 owner 时返回 ErrLastOwner
+func checkNotLastOwner(ctx context.Context, db dbInterface, username string) error {
+	var isOwner bool
+	var owners int
+	if err := db.QueryRowContext(ctx,
+		`SELECT COALESCE(SUM(username = ?), 0) > 0, COUNT(*) FROM admins WHERE role = ?`,
+		username, AdminRoleOwner).Scan(&isOwner, &owners); err != nil {
+		return errors.NewDatabaseError("count_owners", err)
+	}
+	if isOwner && owners <= 1 {
+		return ErrLastOwner
+	}
+	return nil
+}
+
+// scanAdmin 扫描 adminColumns 查询出的一行
+func scanAdmin(row interface{ Scan(...interface{}) error }) (*Admin, error) {
+	var admin Admin
+	var createdAt, updatedAt sql.NullTime
+	if err := row.Scan(&admin.ID, &admin.Username, &admin.PasswordHash, &admin.Role,
+		&admin.TOTPSecret, &admin.TOTPEnabled, &admin.TOTPRequired, &admin.TOTPLastStep,
+		&createdAt, &updatedAt); err != nil {
+		return nil, err
+	}
+	if createdAt.Valid {
+		admin.CreatedAt = createdAt.Time
+	}
+	if updatedAt.Valid {
+		admin.UpdatedAt = updatedAt.Time
+	}
+	return &admin, nil
+}
+
+// IsValidAdminRole 判断是否为已知的管理员角色
+func IsValidAdminRole(role string) bool {
+	switch role {
+	case AdminRoleOwner, AdminRoleOperator, AdminRoleViewer:
+		return true
+	}
+	return false
+}
+
+// validateAdmin 校验新建管理员账号的用户名、角色和密码哈希
+func validateAdmin(admin *Admin) error {
+	admin.Username = strings.TrimSpace(admin.Username)
+	if admin.Username == "" {
+		return errors.NewValidationError("username", "must not be empty")
+	}
+	if len(admin.Username) > 64 {
+		return errors.NewValidationError("username", "must be at most 64 characters")
+	}
+	if !IsValidAdminRole(admin.Role) {
+		return errors.NewValidationError("role", "must be one of owner, operator, viewer")
+	}
+	if admin.PasswordHash == "" {
+		return errors.NewValidationError("password_hash", "must not be empty")
+	}
+	return nil
+}
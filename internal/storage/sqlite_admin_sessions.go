@@ -0,0 +1,219 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"cdk-get/internal/errors"
+)
+
+// adminSessionColumns 会话查询列，与 scanAdminSession 的顺序一致
+const adminSessionColumns = `id, username, refresh_token_hash, ip, user_agent, created_at, last_used_at, expires_at, revoked_at`
+
+// CreateAdminSession 保存新会话
+func (r *SqliteRepository) CreateAdminSession(ctx context.Context, session *AdminSession) error {
+	err := r.inTx(ctx, func(db dbInterface) error {
+		// 过期的会话已无法刷新，顺带清理
+		if _, err := db.ExecContext(ctx,
+			`DELETE FROM admin_sessions WHERE julianday(expires_at) <= julianday(?)`, session.CreatedAt); err != nil {
+			return errors.NewDatabaseError("delete_expired_admin_sessions", err)
+		}
+
+		if _, err := db.ExecContext(ctx,
+			`INSERT INTO admin_sessions (id, username, refresh_token_hash, ip, user_agent, created_at, last_used_at, expires_at)
+			 VALUES (?, ?, ?, ?, ?, ?, ?, ?)`,
+			session.ID, session.Username, session.RefreshTokenHash, session.IP, session.UserAgent,
+			session.CreatedAt, session.LastUsedAt, session.ExpiresAt); err != nil {
+			return errors.NewDatabaseError("create_admin_session", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	r.logger.WithFields(logrus.Fields{
+		"session_id": session.ID,
+		"username":   session.Username,
+		"ip":         session.IP,
+	}).Info("admin session created")
+
+	return nil
+}
+
+// GetAdminSession 获取会话
+func (r *SqliteRepository) GetAdminSession(ctx context.Context, id string) (*AdminSession, error) {
+	row := r.db.QueryRowContext(ctx, `SELECT `+adminSessionColumns+` FROM admin_sessions WHERE id = ?`, id)
+	session, err := scanAdminSession(row)
+	if err == sql.ErrNoRows {
+		return nil, ErrSessionNotFound
+	}
+	if err != nil {
+		return nil, errors.NewDatabaseError("get_admin_session", err)
+	}
+	return session, nil
+}
+
+// RotateAdminSession 轮换会话的刷新令牌
+// session 提供新的令牌哈希、IP、UA、最近使用时间和过期时间
+func (r *SqliteRepository) RotateAdminSession(ctx context.Context, oldHash string, session *AdminSession) (*AdminSession, error) {
+	var rotated *AdminSession
+	var reusedID string
+	err := r.inTx(ctx, func(db dbInterface) error {
+		current, err := scanAdminSession(db.QueryRowContext(ctx,
+			`SELECT `+adminSessionColumns+` FROM admin_sessions WHERE refresh_token_hash = ?`, oldHash))
+		if err == sql.ErrNoRows {
+			// 旧令牌被再次使用，说明令牌可能已泄露，撤销整个会话
+			if err := db.QueryRowContext(ctx,
+				`SELECT id FROM admin_sessions WHERE previous_token_hash = ? AND revoked_at IS NULL`,
+				oldHash).Scan(&reusedID); err == sql.ErrNoRows {
+				return ErrSessionNotFound
+			} else if err != nil {
+				return errors.NewDatabaseError("check_refresh_token_reuse", err)
+			}
+			if _, err := db.ExecContext(ctx,
+				`UPDATE admin_sessions SET revoked_at = ? WHERE id = ?`, session.LastUsedAt, reusedID); err != nil {
+				return errors.NewDatabaseError("revoke_admin_session", err)
+			}
+			return nil
+		}
+		if err != nil {
+			return errors.NewDatabaseError("get_admin_session", err)
+		}
+		if !current.Active(session.LastUsedAt) {
+			return ErrSessionNotFound
+		}
+
+		if _, err := db.ExecContext(ctx,
+			`UPDATE admin_sessions
+			 SET refresh_token_hash = ?, previous_token_hash = ?, ip = ?, user_agent = ?, last_used_at = ?, expires_at = ?
+			 WHERE id = ?`,
+			session.RefreshTokenHash, oldHash, session.IP, session.UserAgent,
+			session.LastUsedAt, session.ExpiresAt, current.ID); err != nil {
+			return errors.NewDatabaseError("rotate_admin_session", err)
+		}
+
+		current.RefreshTokenHash = session.RefreshTokenHash
+		current.IP = session.IP
+		current.UserAgent = session.UserAgent
+		current.LastUsedAt = session.LastUsedAt
+		current.ExpiresAt = session.ExpiresAt
+		rotated = current
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if reusedID != "" {
+		r.logger.WithFields(logrus.Fields{
+			"session_id": reusedID,
+			"ip":         session.IP,
+		}).Warn("refresh token reused, admin session revoked")
+		return nil, ErrRefreshTokenReused
+	}
+
+	return rotated, nil
+}
+
+// TouchAdminSession 更新会话的最近使用时间
+func (r *SqliteRepository) TouchAdminSession(ctx context.Context, id string, at time.Time) error {
+	if _, err := r.db.ExecContext(ctx,
+		`UPDATE admin_sessions SET last_used_at = ? WHERE id = ?`, at, id); err != nil {
+		return errors.NewDatabaseError("touch_admin_session", err)
+	}
+	return nil
+}
+
+// ListAdminSessions 列出管理员仍有效的会话
+func (r *SqliteRepository) ListAdminSessions(ctx context.Context, username string, now time.Time) ([]*AdminSession, error) {
+	rows, err := r.db.QueryContext(ctx,
+		`SELECT `+adminSessionColumns+` FROM admin_sessions
+		 WHERE username = ? AND revoked_at IS NULL AND julianday(expires_at) > julianday(?)
+		 ORDER BY julianday(last_used_at) DESC`,
+		username, now)
+	if err != nil {
+		return nil, errors.NewDatabaseError("list_admin_sessions", err)
+	}
+	defer rows.Close()
+
+	var sessions []*AdminSession
+	for rows.Next() {
+		session, err := scanAdminSession(rows)
+		if err != nil {
+			return nil, errors.NewDatabaseError("scan_admin_session", err)
+		}
+		sessions = append(sessions, session)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, errors.NewDatabaseError("iterate_admin_sessions", err)
+	}
+
+	return sessions, nil
+}
+
+// RevokeAdminSession 撤销管理员的一个会话
+func (r *SqliteRepository) RevokeAdminSession(ctx context.Context, username, id string) error {
+	result, err := r.db.ExecContext(ctx,
+		`UPDATE admin_sessions SET revoked_at = ? WHERE id = ? AND username = ? AND revoked_at IS NULL`,
+		time.Now(), id, username)
+	if err != nil {
+		return errors.NewDatabaseError("revoke_admin_session", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return errors.NewDatabaseError("get_rows_affected", err)
+	}
+	if rowsAffected == 0 {
+		return ErrSessionNotFound
+	}
+
+	r.logger.WithFields(logrus.Fields{
+		"session_id": id,
+		"username":   username,
+	}).Info("admin session revoked")
+
+	return nil
+}
+
+// RevokeAdminSessions 撤销管理员除 exceptID 外的全部会话
+func (r *SqliteRepository) RevokeAdminSessions(ctx context.Context, username, exceptID string) (int64, error) {
+	result, err := r.db.ExecContext(ctx,
+		`UPDATE admin_sessions SET revoked_at = ? WHERE username = ? AND id != ? AND revoked_at IS NULL`,
+		time.Now(), username, exceptID)
+	if err != nil {
+		return 0, errors.NewDatabaseError("revoke_admin_sessions", err)
+	}
+
+	revoked, err := result.RowsAffected()
+	if err != nil {
+		return 0, errors.NewDatabaseError("get_rows_affected", err)
+	}
+
+	r.logger.WithFields(logrus.Fields{
+		"username": username,
+		"kept":     exceptID,
+		"revoked":  revoked,
+	}).Info("admin sessions revoked")
+
+	return revoked, nil
+}
+
+// scanAdminSession 扫描 adminSessionColumns 查询出的一行
+func scanAdminSession(row interface{ Scan(...interface{}) error }) (*AdminSession, error) {
+	var session AdminSession
+	var revokedAt sql.NullTime
+	if err := row.Scan(&session.ID, &session.Username, &session.RefreshTokenHash, &session.IP, &session.UserAgent,
+		&session.CreatedAt, &session.LastUsedAt, &session.ExpiresAt, &revokedAt); err != nil {
+		return nil, err
+	}
+	if revokedAt.Valid {
+		session.RevokedAt = &revokedAt.Time
+	}
+	return &session, nil
+}
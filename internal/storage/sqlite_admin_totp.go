@@ -0,0 +1,168 @@
+package storage
+
+import (
+	"context"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"cdk-get/internal/errors"
+)
+
+// SetAdminTOTPSecret 保存待启用的 TOTP 密钥
+func (r *SqliteRepository) SetAdminTOTPSecret(ctx context.Context, username, secret string) error {
+	if secret == "" {
+		return errors.NewValidationError("totp_secret", "must not be empty")
+	}
+
+	return updateAdmin(ctx, r.db, "set_admin_totp_secret",
+		`UPDATE admins SET totp_secret = ?, updated_at = CURRENT_TIMESTAMP WHERE username = ? AND totp_enabled = 0`,
+		secret, username)
+}
+
+// EnableAdminTOTP 启用两步验证并替换恢复码
+func (r *SqliteRepository) EnableAdminTOTP(ctx context.Context, username string, step int64, recoveryCodeHashes []string) error {
+	err := r.inTx(ctx, func(db dbInterface) error {
+		if err := updateAdmin(ctx, db, "enable_admin_totp",
+			`UPDATE admins SET totp_enabled = 1, totp_last_step = ?, updated_at = CURRENT_TIMESTAMP
+			 WHERE username = ? AND totp_secret != ''`,
+			step, username); err != nil {
+			return err
+		}
+		return replaceRecoveryCodes(ctx, db, username, recoveryCodeHashes)
+	})
+	if err != nil {
+		return err
+	}
+
+	r.logger.WithFields(logrus.Fields{
+		"username": username,
+	}).Info("admin totp enabled")
+
+	return nil
+}
+
+// DisableAdminTOTP 停用两步验证，清除密钥和恢复码
+func (r *SqliteRepository) DisableAdminTOTP(ctx context.Context, username string) error {
+	err := r.inTx(ctx, func(db dbInterface) error {
+		if err := updateAdmin(ctx, db, "disable_admin_totp",
+			`UPDATE admins SET totp_secret = '', totp_enabled = 0, totp_last_step = 0, updated_at = CURRENT_TIMESTAMP
+			 WHERE username = ?`,
+			username); err != nil {
+			return err
+		}
+		return replaceRecoveryCodes(ctx, db, username, nil)
+	})
+	if err != nil {
+		return err
+	}
+
+	r.logger.WithFields(logrus.Fields{
+		"username": username,
+	}).Info("admin totp disabled")
+
+	return nil
+}
+
+// SetAdminTOTPRequired 设置账号是否必须启用两步验证
+func (r *SqliteRepository) SetAdminTOTPRequired(ctx context.Context, username string, required bool) error {
+	if err := updateAdmin(ctx, r.db, "set_admin_totp_required",
+		`UPDATE admins SET totp_required = ?, updated_at = CURRENT_TIMESTAMP WHERE username = ?`,
+		required, username); err != nil {
+		return err
+	}
+
+	r.logger.WithFields(logrus.Fields{
+		"username": username,
+		"required": required,
+	}).Info("admin totp requirement updated")
+
+	return nil
+}
+
+// UseAdminTOTPStep 记录通过校验的时间步
+// 条件更新保证同一时间步的动态码只能使用一次，并发请求中只有一个成功
+func (r *SqliteRepository) UseAdminTOTPStep(ctx context.Context, username string, step int64) (bool, error) {
+	result, err := r.db.ExecContext(ctx,
+		`UPDATE admins SET totp_last_step = ? WHERE username = ? AND totp_enabled = 1 AND totp_last_step < ?`,
+		step, username, step)
+	if err != nil {
+		return false, errors.NewDatabaseError("use_admin_totp_step", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return false, errors.NewDatabaseError("get_rows_affected", err)
+	}
+	return rowsAffected > 0, nil
+}
+
+// ReplaceAdminRecoveryCodes 以新的恢复码替换账号的全部恢复码
+func (r *SqliteRepository) ReplaceAdminRecoveryCodes(ctx context.Context, username string, codeHashes []string) error {
+	err := r.inTx(ctx, func(db dbInterface) error {
+		return replaceRecoveryCodes(ctx, db, username, codeHashes)
+	})
+	if err != nil {
+		return err
+	}
+
+	r.logger.WithFields(logrus.Fields{
+		"username": username,
+		"count":    len(codeHashes),
+	}).Info("admin recovery codes regenerated")
+
+	return nil
+}
+
+// UseAdminRecoveryCode 使用一个恢复码
+func (r *SqliteRepository) UseAdminRecoveryCode(ctx context.Context, username, codeHash string) (bool, error) {
+	result, err := r.db.ExecContext(ctx,
+		`UPDATE admin_recovery_codes SET used_at = ?
+		 WHERE id = (SELECT id FROM admin_recovery_codes WHERE username = ? AND code_hash = ? AND used_at IS NULL LIMIT 1)`,
+		time.Now(), username, codeHash)
+	if err != nil {
+		return false, errors.NewDatabaseError("use_admin_recovery_code", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return false, errors.NewDatabaseError("get_rows_affected", err)
+	}
+	if rowsAffected == 0 {
+		return false, nil
+	}
+
+	r.logger.WithFields(logrus.Fields{
+		"username": username,
+	}).Info("admin recovery code used")
+
+	return true, nil
+}
+
+// CountAdminRecoveryCodes 统计账号未使用的恢复码数量
+func (r *SqliteRepository) CountAdminRecoveryCodes(ctx context.Context, username string) (int, error) {
+	var count int
+	if err := r.db.QueryRowContext(ctx,
+		`SELECT COUNT(*) FROM admin_recovery_codes WHERE username = ? AND used_at IS NULL`,
+		username).Scan(&count); err != nil {
+		return 0, errors.NewDatabaseError("count_admin_recovery_codes", err)
+	}
+	return count, nil
+}
+
+// replaceRecoveryCodes 删除账号的全部恢复码后插入 codeHashes
+func replaceRecoveryCodes(ctx context.Context, db dbInterface, username string, codeHashes []string) error {
+	if _, err := db.ExecContext(ctx, `DELETE FROM admin_recovery_codes WHERE username = ?`, username); err != nil {
+		return errors.NewDatabaseError("delete_admin_recovery_codes", err)
+	}
+
+	now := time.Now()
+	for _, hash := range codeHashes {
+		if _, err := db.ExecContext(ctx,
+			`INSERT INTO admin_recovery_codes (username, code_hash, created_at) VALUES (?, ?, ?)`,
+			username, hash, now); err != nil {
+			return errors.NewDatabaseError("insert_admin_recovery_code", err)
+		}
+	}
+	return nil
+}
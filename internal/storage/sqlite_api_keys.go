@@ -0,0 +1,132 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"slices"
+	"strings"
+	"time"
+
+	"cdk-get/internal/errors"
+)
+
+// apiKeyColumns API 密钥查询列，与 scanAPIKey 的顺序一致
+const apiKeyColumns = `id, name, prefix, key_hash, scopes, created_by, created_at,
+	last_used_at, last_used_ip, usage_count, revoked_at`
+
+// CreateAPIKey 保存新的 API 密钥
+func (r *SqliteRepository) CreateAPIKey(ctx context.Context, key *APIKey) error {
+	if strings.TrimSpace(key.Name) == "" {
+		return errors.NewValidationError("name", "must not be empty")
+	}
+	if len(key.Scopes) == 0 {
+		return errors.NewValidationError("scopes", "must not be empty")
+	}
+	for _, scope := range key.Scopes {
+		if !slices.Contains(APIKeyScopes, scope) {
+			return errors.NewValidationError("scopes", "unknown scope "+scope)
+		}
+	}
+	if key.CreatedAt.IsZero() {
+		key.CreatedAt = time.Now()
+	}
+
+	result, err := r.db.ExecContext(ctx,
+		`INSERT INTO api_keys (name, prefix, key_hash, scopes, created_by, created_at) VALUES (?, ?, ?, ?, ?, ?)`,
+		key.Name, key.Prefix, key.KeyHash, strings.Join(key.Scopes, ","), key.CreatedBy, key.CreatedAt)
+	if err != nil {
+		return errors.NewDatabaseError("create_api_key", err)
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		return errors.NewDatabaseError("get_api_key_id", err)
+	}
+	key.ID = id
+	return nil
+}
+
+// GetAPIKeyByHash 按密钥哈希获取未撤销的 API 密钥
+func (r *SqliteRepository) GetAPIKeyByHash(ctx context.Context, keyHash string) (*APIKey, error) {
+	row := r.db.QueryRowContext(ctx,
+		`SELECT `+apiKeyColumns+` FROM api_keys WHERE key_hash = ? AND revoked_at IS NULL`, keyHash)
+	key, err := scanAPIKey(row)
+	if err == sql.ErrNoRows {
+		return nil, ErrAPIKeyNotFound
+	}
+	if err != nil {
+		return nil, errors.NewDatabaseError("get_api_key", err)
+	}
+	return key, nil
+}
+
+// ListAPIKeys 按创建时间倒序列出全部 API 密钥
+func (r *SqliteRepository) ListAPIKeys(ctx context.Context) ([]*APIKey, error) {
+	rows, err := r.db.QueryContext(ctx, `SELECT `+apiKeyColumns+` FROM api_keys ORDER BY id DESC`)
+	if err != nil {
+		return nil, errors.NewDatabaseError("list_api_keys", err)
+	}
+	defer rows.Close()
+
+	var keys []*APIKey
+	for rows.Next() {
+		key, err := scanAPIKey(rows)
+		if err != nil {
+			return nil, errors.NewDatabaseError("scan_api_key", err)
+		}
+		keys = append(keys, key)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, errors.NewDatabaseError("iterate_api_keys", err)
+	}
+
+	return keys, nil
+}
+
+// RevokeAPIKey 撤销 API 密钥，撤销后的密钥保留在列表中以便查看使用记录
+func (r *SqliteRepository) RevokeAPIKey(ctx context.Context, id int64) error {
+	result, err := r.db.ExecContext(ctx,
+		`UPDATE api_keys SET revoked_at = ? WHERE id = ? AND revoked_at IS NULL`, time.Now(), id)
+	if err != nil {
+		return errors.NewDatabaseError("revoke_api_key", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return errors.NewDatabaseError("get_rows_affected", err)
+	}
+	if rowsAffected == 0 {
+		return ErrAPIKeyNotFound
+	}
+	return nil
+}
+
+// RecordAPIKeyUsage 使用次数增加 count 并记录最近使用时间和 IP
+func (r *SqliteRepository) RecordAPIKeyUsage(ctx context.Context, id int64, count int64, at time.Time, ip string) error {
+	if _, err := r.db.ExecContext(ctx,
+		`UPDATE api_keys SET usage_count = usage_count + ?, last_used_at = ?, last_used_ip = ? WHERE id = ?`,
+		count, at, ip, id); err != nil {
+		return errors.NewDatabaseError("record_api_key_usage", err)
+	}
+	return nil
+}
+
+// scanAPIKey 扫描 apiKeyColumns 查询出的一行
+func scanAPIKey(row interface{ Scan(...interface{}) error }) (*APIKey, error) {
+	var key APIKey
+	var scopes string
+	var lastUsedAt, revokedAt sql.NullTime
+	if err := row.Scan(&key.ID, &key.Name, &key.Prefix, &key.KeyHash, &scopes, &key.CreatedBy,
+		&key.CreatedAt, &lastUsedAt, &key.LastUsedIP, &key.UsageCount, &revokedAt); err != nil {
+		return nil, err
+	}
+	key.Scopes = strings.Split(scopes, ",")
+	if lastUsedAt.Valid {
+		key.LastUsedAt = &lastUsedAt.Time
+	}
+	if revokedAt.Valid {
+		key.RevokedAt = &revokedAt.Time
+	}
+	return &key, nil
+}
@@ -0,0 +1,129 @@
+package storage
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"cdk-get/internal/errors"
+)
+
+// CreateAuditEntry 记录一次管理操作
+func (r *SqliteRepository) CreateAuditEntry(ctx context.Context, entry *AuditEntry) error {
+	if entry.Action == "" {
+		return errors.NewValidationError("action", "must not be empty")
+	}
+	if entry.CreatedAt.IsZero() {
+		entry.CreatedAt = time.Now()
+	}
+
+	result, err := r.db.ExecContext(ctx,
+		`INSERT INTO audit_log (actor, action, target, ip, request_id, before_data, after_data, created_at)
+		 VALUES (?, ?, ?, ?, ?, ?, ?, ?)`,
+		entry.Actor, entry.Action, entry.Target, entry.IP, entry.RequestID,
+		string(entry.Before), string(entry.After), entry.CreatedAt)
+	if err != nil {
+		return errors.NewDatabaseError("create_audit_entry", err)
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		return errors.NewDatabaseError("get_audit_entry_id", err)
+	}
+	entry.ID = id
+	return nil
+}
+
+// ListAuditEntries 按时间倒序列出符合筛选条件的管理操作记录
+func (r *SqliteRepository) ListAuditEntries(ctx context.Context, filter AuditFilter) ([]*AuditEntry, error) {
+	var conditions []string
+	var args []interface{}
+	if filter.Actor != "" {
+		conditions = append(conditions, "actor = ?")
+		args = append(args, filter.Actor)
+	}
+	if prefix, ok := strings.CutSuffix(filter.Action, "."); ok {
+		conditions = append(conditions, "substr(action, 1, ?) = ?")
+		args = append(args, len(prefix)+1, prefix+".")
+	} else if filter.Action != "" {
+		conditions = append(conditions, "action = ?")
+		args = append(args, filter.Action)
+	}
+	if filter.Target != "" {
+		conditions = append(conditions, "target = ?")
+		args = append(args, filter.Target)
+	}
+	if !filter.Since.IsZero() {
+		conditions = append(conditions, "julianday(created_at) >= julianday(?)")
+		args = append(args, filter.Since)
+	}
+	if !filter.Until.IsZero() {
+		conditions = append(conditions, "julianday(created_at) < julianday(?)")
+		args = append(args, filter.Until)
+	}
+
+	query := `SELECT id, actor, action, target, ip, request_id, before_data, after_data, created_at FROM audit_log`
+	if len(conditions) > 0 {
+		query += " WHERE " + strings.Join(conditions, " AND ")
+	}
+	query += " ORDER BY id DESC LIMIT ?"
+	args = append(args, filter.Limit)
+
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, errors.NewDatabaseError("list_audit_entries", err)
+	}
+	defer rows.Close()
+
+	var entries []*AuditEntry
+	for rows.Next() {
+		var entry AuditEntry
+		var before, after string
+		if err := rows.Scan(&entry.ID, &entry.Actor, &entry.Action, &entry.Target, &entry.IP,
+			&entry.RequestID, &before, &after, &entry.CreatedAt); err != nil {
+			return nil, errors.NewDatabaseError("scan_audit_entry", err)
+		}
+		if before != "" {
+			entry.Before = []byte(before)
+		}
+		if after != "" {
+			entry.After = []byte(after)
+		}
+		entries = append(entries, &entry)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, errors.NewDatabaseError("iterate_audit_entries", err)
+	}
+
+	return entries, nil
+}
+
+// PruneAuditLog 删除早于 before 的管理操作记录
+// 单次最多删除 limit 条记录，调用方需循环调用直到返回值小于 limit
+func (r *SqliteRepository) PruneAuditLog(ctx context.Context, before time.Time, limit int) (int64, error) {
+	result, err := r.db.ExecContext(ctx,
+		`DELETE FROM audit_log WHERE id IN (
+		     SELECT id FROM audit_log
+		     WHERE julianday(created_at) < julianday(?)
+		     ORDER BY id ASC
+		     LIMIT ?)`, before, limit)
+	if err != nil {
+		return 0, errors.NewDatabaseError("prune_audit_log", err)
+	}
+
+	deleted, err := result.RowsAffected()
+	if err != nil {
+		return 0, errors.NewDatabaseError("get_rows_affected", err)
+	}
+
+	r.logger.WithFields(logrus.Fields{
+		"before":  before,
+		"limit":   limit,
+		"deleted": deleted,
+	}).Debug("audit log pruned")
+
+	return deleted, nil
+}
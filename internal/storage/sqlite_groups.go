@@ -0,0 +1,387 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+
+	"cdk-get/internal/errors"
+)
+
+// UpdateUser 更新用户资料，并记录昵称、区服和头像的变化
+func (r *SqliteRepository) UpdateUser(ctx context.Context, user *User) error {
+	err := r.inTx(ctx, func(db dbInterface) error {
+		old, err := loadProfile(ctx, db,
+			`SELECT nickname, kid, avatar_image FROM fid_list WHERE fid = ? AND deleted_at IS NULL`, user.FID)
+		if err == sql.ErrNoRows {
+			return ErrUserNotFound
+		}
+		if err != nil {
+			return errors.NewDatabaseError("get_user_profile", err)
+		}
+
+		if _, err := db.ExecContext(ctx,
+			`UPDATE fid_list SET nickname = ?, kid = ?, avatar_image = ? WHERE fid = ?`,
+			user.Nickname, user.KID, user.AvatarImage, user.FID); err != nil {
+			return errors.NewDatabaseError("update_user", err)
+		}
+		return r.recordProfileChanges(ctx, db, old, user)
+	})
+	if err != nil {
+		return err
+	}
+
+	r.logger.WithFields(logrus.Fields{
+		"fid": user.FID,
+	}).Debug("user profile updated")
+
+	return nil
+}
+
+// SetUserDisabled 启用/禁用用户
+func (r *SqliteRepository) SetUserDisabled(ctx context.Context, fid string, disabled bool) error {
+	query := `UPDATE fid_list SET disabled = ? WHERE fid = ? AND deleted_at IS NULL`
+	stmt, err := r.db.PrepareContext(ctx, query)
+	if err != nil {
+		return errors.NewDatabaseError("prepare_set_user_disabled", err)
+	}
+	defer stmt.Close()
+
+	result, err := stmt.ExecContext(ctx, disabled, fid)
+	if err != nil {
+		return errors.NewDatabaseError("set_user_disabled", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return errors.NewDatabaseError("get_rows_affected", err)
+	}
+	if rowsAffected == 0 {
+		return ErrUserNotFound
+	}
+
+	r.logger.WithFields(logrus.Fields{
+		"fid":      fid,
+		"disabled": disabled,
+	}).Info("user status updated")
+
+	return nil
+}
+
+// SetUserGroups 以 groupIDs 覆盖用户所属分组
+func (r *SqliteRepository) SetUserGroups(ctx context.Context, fid string, groupIDs []int64) error {
+	err := r.inTx(ctx, func(db dbInterface) error {
+		var exists bool
+		if err := db.QueryRowContext(ctx,
+			`SELECT EXISTS(SELECT 1 FROM fid_list WHERE fid = ? AND deleted_at IS NULL)`, fid).Scan(&exists); err != nil {
+			return errors.NewDatabaseError("check_user", err)
+		}
+		if !exists {
+			return ErrUserNotFound
+		}
+
+		if _, err := db.ExecContext(ctx, `DELETE FROM user_group_members WHERE fid = ?`, fid); err != nil {
+			return errors.NewDatabaseError("clear_user_groups", err)
+		}
+
+		for _, groupID := range groupIDs {
+			if err := checkGroupExists(ctx, db, groupID); err != nil {
+				return err
+			}
+			if _, err := db.ExecContext(ctx,
+				`INSERT OR IGNORE INTO user_group_members (group_id, fid) VALUES (?, ?)`, groupID, fid); err != nil {
+				return errors.NewDatabaseError("add_user_group", err)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	r.logger.WithFields(logrus.Fields{
+		"fid":    fid,
+		"groups": groupIDs,
+	}).Info("user groups updated")
+
+	return nil
+}
+
+// CreateUserGroup 创建用户分组
+func (r *SqliteRepository) CreateUserGroup(ctx context.Context, group *UserGroup) error {
+	if err := validateGroupName(group.Name); err != nil {
+		return err
+	}
+
+	err := r.inTx(ctx, func(db dbInterface) error {
+		if err := checkGroupNameFree(ctx, db, group.Name, 0); err != nil {
+			return err
+		}
+
+		result, err := db.ExecContext(ctx,
+			`INSERT INTO user_groups (name, description, created_at) VALUES (?, ?, CURRENT_TIMESTAMP)`,
+			group.Name, group.Description)
+		if err != nil {
+			return errors.NewDatabaseError("create_user_group", err)
+		}
+
+		id, err := result.LastInsertId()
+		if err != nil {
+			return errors.NewDatabaseError("get_user_group_id", err)
+		}
+		group.ID = id
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	r.logger.WithFields(logrus.Fields{
+		"id":   group.ID,
+		"name": group.Name,
+	}).Info("user group created")
+
+	return nil
+}
+
+// UpdateUserGroup 更新用户分组名称和描述
+func (r *SqliteRepository) UpdateUserGroup(ctx context.Context, group *UserGroup) error {
+	if err := validateGroupName(group.Name); err != nil {
+		return err
+	}
+
+	err := r.inTx(ctx, func(db dbInterface) error {
+		if err := checkGroupExists(ctx, db, group.ID); err != nil {
+			return err
+		}
+		if err := checkGroupNameFree(ctx, db, group.Name, group.ID); err != nil {
+			return err
+		}
+
+		if _, err := db.ExecContext(ctx,
+			`UPDATE user_groups SET name = ?, description = ? WHERE id = ?`,
+			group.Name, group.Description, group.ID); err != nil {
+			return errors.NewDatabaseError("update_user_group", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	r.logger.WithFields(logrus.Fields{
+		"id":   group.ID,
+		"name": group.Name,
+	}).Info("user group updated")
+
+	return nil
+}
+
+// DeleteUserGroup 删除用户分组及其成员关系
+// 仍有未完成任务（包括回收站中的任务）指向该分组时拒绝删除
+func (r *SqliteRepository) DeleteUserGroup(ctx context.Context, id int64) error {
+	err := r.inTx(ctx, func(db dbInterface) error {
+		if err := checkGroupExists(ctx, db, id); err != nil {
+			return err
+		}
+
+		var inUse bool
+		if err := db.QueryRowContext(ctx,
+			`SELECT EXISTS(SELECT 1 FROM gift_code_task WHERE target_group_id = ? AND all_done = 0)`, id).Scan(&inUse); err != nil {
+			return errors.NewDatabaseError("check_user_group_in_use", err)
+		}
+		if inUse {
+			return ErrGroupInUse
+		}
+
+		if _, err := db.ExecContext(ctx, `DELETE FROM user_group_members WHERE group_id = ?`, id); err != nil {
+			return errors.NewDatabaseError("delete_user_group_members", err)
+		}
+		if _, err := db.ExecContext(ctx, `DELETE FROM user_groups WHERE id = ?`, id); err != nil {
+			return errors.NewDatabaseError("delete_user_group", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	r.logger.WithFields(logrus.Fields{
+		"id": id,
+	}).Info("user group deleted")
+
+	return nil
+}
+
+// userGroupColumns 分组查询的字段列表，与 scanUserGroup 的扫描顺序一致
+const userGroupColumns = `g.id, g.name, g.description, g.created_at,
+	          (SELECT COUNT(*) FROM user_group_members m
+	           JOIN fid_list f ON f.fid = m.fid
+	           WHERE m.group_id = g.id AND f.deleted_at IS NULL)`
+
+// scanUserGroup 扫描一行分组数据
+func scanUserGroup(row rowScanner) (*UserGroup, error) {
+	var group UserGroup
+	var createdAt sql.NullTime
+	err := row.Scan(
+		&group.ID,
+		&group.Name,
+		&group.Description,
+		&createdAt,
+		&group.MemberCount,
+	)
+	if err != nil {
+		return nil, err
+	}
+	if createdAt.Valid {
+		group.CreatedAt = createdAt.Time
+	}
+	return &group, nil
+}
+
+// GetUserGroup 获取分组及成员数量，分组不存在时返回 ErrGroupNotFound
+func (r *SqliteRepository) GetUserGroup(ctx context.Context, id int64) (*UserGroup, error) {
+	query := `SELECT ` + userGroupColumns + ` FROM user_groups g WHERE g.id = ?`
+	stmt, err := r.db.PrepareContext(ctx, query)
+	if err != nil {
+		return nil, errors.NewDatabaseError("prepare_get_user_group", err)
+	}
+	defer stmt.Close()
+
+	group, err := scanUserGroup(stmt.QueryRowContext(ctx, id))
+	if err == sql.ErrNoRows {
+		return nil, ErrGroupNotFound
+	}
+	if err != nil {
+		return nil, errors.NewDatabaseError("get_user_group", err)
+	}
+
+	return group, nil
+}
+
+// ListUserGroups 列出所有用户分组及成员数量
+func (r *SqliteRepository) ListUserGroups(ctx context.Context) ([]*UserGroup, error) {
+	query := `SELECT ` + userGroupColumns + ` FROM user_groups g ORDER BY g.name ASC`
+	stmt, err := r.db.PrepareContext(ctx, query)
+	if err != nil {
+		return nil, errors.NewDatabaseError("prepare_list_user_groups", err)
+	}
+	defer stmt.Close()
+
+	rows, err := stmt.QueryContext(ctx)
+	if err != nil {
+		return nil, errors.NewDatabaseError("list_user_groups", err)
+	}
+	defer rows.Close()
+
+	var groups []*UserGroup
+	for rows.Next() {
+		group, err := scanUserGroup(rows)
+		if err != nil {
+			return nil, errors.NewDatabaseError("scan_user_group", err)
+		}
+		groups = append(groups, group)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, errors.NewDatabaseError("iterate_user_groups", err)
+	}
+
+	return groups, nil
+}
+
+// ListGroupFids 列出分组内参与兑换的用户（未删除且未禁用）
+func (r *SqliteRepository) ListGroupFids(ctx context.Context, groupID int64) ([]string, error) {
+	keys, err := queryKeys(ctx, r.db,
+		`SELECT f.fid FROM user_group_members m
+		 JOIN fid_list f ON f.fid = m.fid
+		 WHERE m.group_id = ? AND f.deleted_at IS NULL AND f.disabled = 0
+		 ORDER BY f.fid DESC`, groupID)
+	if err != nil {
+		return nil, errors.NewDatabaseError("list_group_fids", err)
+	}
+
+	fids := make([]string, 0, len(keys))
+	for _, key := range keys {
+		fids = append(fids, key.(string))
+	}
+	return fids, nil
+}
+
+// SetTaskTargetGroup 设置任务的目标分组
+func (r *SqliteRepository) SetTaskTargetGroup(ctx context.Context, code string, groupID *int64) error {
+	err := r.inTx(ctx, func(db dbInterface) error {
+		var target interface{}
+		if groupID != nil {
+			if err := checkGroupExists(ctx, db, *groupID); err != nil {
+				return err
+			}
+			target = *groupID
+		}
+
+		result, err := db.ExecContext(ctx,
+			`UPDATE gift_code_task SET target_group_id = ? WHERE code = ? AND deleted_at IS NULL`, target, code)
+		if err != nil {
+			return errors.NewDatabaseError("set_task_target_group", err)
+		}
+
+		rowsAffected, err := result.RowsAffected()
+		if err != nil {
+			return errors.NewDatabaseError("get_rows_affected", err)
+		}
+		if rowsAffected == 0 {
+			return ErrTaskNotFound
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	r.logger.WithFields(logrus.Fields{
+		"code":     code,
+		"group_id": groupID,
+	}).Debug("task target group updated")
+
+	return nil
+}
+
+// validateGroupName 校验分组名称
+// 名称会以逗号拼接返回给用户列表，因此不允许包含逗号
+func validateGroupName(name string) error {
+	if strings.TrimSpace(name) == "" {
+		return errors.NewValidationError("name", "must not be empty")
+	}
+	if strings.Contains(name, ",") {
+		return errors.NewValidationError("name", "must not contain ','")
+	}
+	return nil
+}
+
+// checkGroupExists 检查分组是否存在
+func checkGroupExists(ctx context.Context, db dbInterface, id int64) error {
+	var exists bool
+	if err := db.QueryRowContext(ctx,
+		`SELECT EXISTS(SELECT 1 FROM user_groups WHERE id = ?)`, id).Scan(&exists); err != nil {
+		return errors.NewDatabaseError("check_user_group", err)
+	}
+	if !exists {
+		return ErrGroupNotFound
+	}
+	return nil
+}
+
+// checkGroupNameFree 检查分组名称是否未被其他分组占用
+func checkGroupNameFree(ctx context.Context, db dbInterface, name string, excludeID int64) error {
+	var taken bool
+	if err := db.QueryRowContext(ctx,
+		`SELECT EXISTS(SELECT 1 FROM user_groups WHERE name = ? AND id != ?)`, name, excludeID).Scan(&taken); err != nil {
+		return errors.NewDatabaseError("check_user_group_name", err)
+	}
+	if taken {
+		return ErrGroupNameTaken
+	}
+	return nil
+}
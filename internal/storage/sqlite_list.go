@@ -0,0 +1,267 @@
+package storage
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strings"
+
+	"cdk-get/internal/errors"
+)
+
+// sortField 排序字段对应的 SQL 表达式及默认方向
+type sortField struct {
+	expr string
+	desc bool
+}
+
+// listQuery 游标分页查询的定义
+// 下一页从上一页最后一条记录的 (排序值, 唯一键) 之后开始，避免 OFFSET 在翻页期间因数据变化而跳过或重复记录
+type listQuery struct {
+	op          string // 错误信息中的操作名
+	columns     string
+	from        string
+	conditions  []string
+	args        []any
+	sorts       map[string]sortField
+	defaultSort string
+	key         string // 唯一键表达式，排序值相同时按其排序
+}
+
+// listCursor 游标内容，记录排序字段以及最后一条记录的排序值和唯一键
+type listCursor struct {
+	Sort  string `json:"s"`
+	Value any    `json:"v"`
+	Key   any    `json:"k"`
+}
+
+// where 添加筛选条件
+func (q *listQuery) where(condition string, args ...any) {
+	q.conditions = append(q.conditions, condition)
+	q.args = append(q.args, args...)
+}
+
+// queryPage 按分页参数执行 listQuery，使用 scan 扫描 columns 对应的字段
+func queryPage[T any](ctx context.Context, r *SqliteRepository, q listQuery, opts ListOptions, scan func(rowScanner) (*T, error)) (*Page[T], error) {
+	sortName := opts.Sort
+	if sortName == "" {
+		sortName = q.defaultSort
+	}
+	field, ok := q.sorts[sortName]
+	if !ok {
+		return nil, errors.NewValidationError("sort", "unknown sort field "+sortName)
+	}
+
+	desc := field.desc
+	switch opts.Order {
+	case "":
+	case SortAsc:
+		desc = false
+	case SortDesc:
+		desc = true
+	default:
+		return nil, errors.NewValidationError("order", "must be asc or desc")
+	}
+
+	limit := opts.Limit
+	if limit <= 0 {
+		limit = DefaultPageSize
+	}
+	limit = min(limit, MaxPageSize)
+
+	cmp, dir := ">", "ASC"
+	if desc {
+		cmp, dir = "<", "DESC"
+	}
+
+	conditions := slices.Clone(q.conditions)
+	args := slices.Clone(q.args)
+	if opts.Cursor != "" {
+		cursor, err := decodeListCursor(opts.Cursor)
+		if err != nil || cursor.Sort != sortName {
+			return nil, errors.NewValidationError("cursor", "is invalid or does not match the sort field")
+		}
+		conditions = append(conditions, fmt.Sprintf("(%[1]s %[2]s ? OR (%[1]s = ? AND %[3]s %[2]s ?))", field.expr, cmp, q.key))
+		args = append(args, cursor.Value, cursor.Value, cursor.Key)
+	}
+
+	query := `SELECT ` + q.columns + `, ` + field.expr + `, ` + q.key + ` FROM ` + q.from
+	if len(conditions) > 0 {
+		query += " WHERE " + strings.Join(conditions, " AND ")
+	}
+	query += fmt.Sprintf(" ORDER BY %s %s, %s %s LIMIT ?", field.expr, dir, q.key, dir)
+	// 多取一条用于判断是否还有下一页
+	args = append(args, limit+1)
+
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, errors.NewDatabaseError(q.op, err)
+	}
+	defer rows.Close()
+
+	page := &Page[T]{Items: []*T{}}
+	var last listCursor
+	for rows.Next() {
+		cursor := listCursor{Sort: sortName}
+		item, err := scan(cursorScanner{rows, []any{&cursor.Value, &cursor.Key}})
+		if err != nil {
+			return nil, errors.NewDatabaseError("scan_"+q.op, err)
+		}
+		if len(page.Items) == limit {
+			page.NextCursor = encodeListCursor(last)
+			break
+		}
+		page.Items = append(page.Items, item)
+		last = cursor
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, errors.NewDatabaseError("iterate_"+q.op, err)
+	}
+
+	return page, nil
+}
+
+// cursorScanner 在 scan 函数的扫描目标之后追加排序值和唯一键
+type cursorScanner struct {
+	rowScanner
+	extra []any
+}
+
+// Scan 实现 rowScanner
+func (s cursorScanner) Scan(dest ...any) error {
+	return s.rowScanner.Scan(append(dest, s.extra...)...)
+}
+
+// encodeListCursor 将游标编码为不透明的字符串
+func encodeListCursor(cursor listCursor) string {
+	data, _ := json.Marshal(cursor)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// decodeListCursor 解析 encodeListCursor 生成的游标
+func decodeListCursor(value string) (listCursor, error) {
+	var cursor listCursor
+	data, err := base64.RawURLEncoding.DecodeString(value)
+	if err != nil {
+		return cursor, err
+	}
+	err = json.Unmarshal(data, &cursor)
+	return cursor, err
+}
+
+// likePattern 构造包含匹配的 LIKE 参数，转义其中的通配符，配合 ESCAPE '\' 使用
+func likePattern(s string) string {
+	return "%" + strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(s) + "%"
+}
+
+// QueryUsers 按筛选条件分页列出未删除的用户，默认按 fid 倒序
+func (r *SqliteRepository) QueryUsers(ctx context.Context, filter UserFilter) (*Page[User], error) {
+	q := listQuery{
+		op:      "query_users",
+		columns: userColumns,
+		from:    "fid_list",
+		sorts: map[string]sortField{
+			UserSortFID:      {expr: "CAST(fid AS INTEGER)", desc: true},
+			UserSortNickname: {expr: "nickname"},
+			UserSortKID:      {expr: "kid"},
+		},
+		defaultSort: UserSortFID,
+		key:         "fid",
+	}
+	q.where("deleted_at IS NULL")
+	if search := strings.TrimSpace(filter.Search); search != "" {
+		q.where(`(fid LIKE ? ESCAPE '\' OR nickname LIKE ? ESCAPE '\')`, likePattern(search), likePattern(search))
+	}
+	if filter.KID != nil {
+		q.where("kid = ?", *filter.KID)
+	}
+	if filter.Disabled != nil {
+		q.where("disabled = ?", *filter.Disabled)
+	}
+
+	return queryPage(ctx, r, q, filter.ListOptions, scanUser)
+}
+
+// QueryTasks 按筛选条件分页列出未删除的任务
+// 默认排序：已完成任务按完成时间倒序，其余按兑换码
+func (r *SqliteRepository) QueryTasks(ctx context.Context, filter TaskFilter) (*Page[Task], error) {
+	q := listQuery{
+		op:      "query_tasks",
+		columns: taskColumns,
+		from:    taskTables,
+		sorts: map[string]sortField{
+			TaskSortCode:        {expr: "t.code"},
+			TaskSortCreatedAt:   {expr: "COALESCE(julianday(t.created_at), 0)", desc: true},
+			TaskSortCompletedAt: {expr: "COALESCE(julianday(t.completed_at), 0)", desc: true},
+			TaskSortRetryCount:  {expr: "t.retry_count", desc: true},
+		},
+		defaultSort: TaskSortCode,
+		key:         "t.code",
+	}
+	q.where("t.deleted_at IS NULL")
+
+	timeColumn := "t.created_at"
+	switch filter.Status {
+	case "":
+	case TaskStatusPending:
+		q.where("t.all_done = 0")
+	case TaskStatusCompleted:
+		q.where("t.all_done = 1")
+		q.defaultSort = TaskSortCompletedAt
+		timeColumn = "t.completed_at"
+	default:
+		return nil, errors.NewValidationError("status", "must be pending or completed")
+	}
+
+	if search := strings.TrimSpace(filter.Search); search != "" {
+		q.where(`t.code LIKE ? ESCAPE '\'`, likePattern(search))
+	}
+	if !filter.Since.IsZero() {
+		q.where("julianday("+timeColumn+") >= julianday(?)", filter.Since)
+	}
+	if !filter.Until.IsZero() {
+		q.where("julianday("+timeColumn+") < julianday(?)", filter.Until)
+	}
+
+	return queryPage(ctx, r, q, filter.ListOptions, scanTask)
+}
+
+// QueryNotifications 按筛选条件分页列出通知记录，默认按创建时间倒序
+func (r *SqliteRepository) QueryNotifications(ctx context.Context, filter NotificationFilter) (*Page[Notification], error) {
+	q := listQuery{
+		op:      "query_notifications",
+		columns: notificationColumns,
+		from:    "notifications",
+		sorts: map[string]sortField{
+			// id 自增，与创建时间顺序一致
+			NotificationSortCreatedAt: {expr: "id", desc: true},
+			NotificationSortAttempts:  {expr: "attempts", desc: true},
+		},
+		defaultSort: NotificationSortCreatedAt,
+		key:         "id",
+	}
+	if search := strings.TrimSpace(filter.Search); search != "" {
+		pattern := likePattern(search)
+		q.where(`(fid LIKE ? ESCAPE '\' OR title LIKE ? ESCAPE '\' OR content LIKE ? ESCAPE '\')`, pattern, pattern, pattern)
+	}
+	if filter.Status != "" {
+		q.where("status = ?", filter.Status)
+	}
+	if filter.Channel != "" {
+		q.where("channel = ?", filter.Channel)
+	}
+	if filter.Event != "" {
+		q.where("event = ?", filter.Event)
+	}
+	if !filter.Since.IsZero() {
+		q.where("julianday(created_at) >= julianday(?)", filter.Since)
+	}
+	if !filter.Until.IsZero() {
+		q.where("julianday(created_at) < julianday(?)", filter.Until)
+	}
+
+	return queryPage(ctx, r, q, filter.ListOptions, scanNotification)
+}
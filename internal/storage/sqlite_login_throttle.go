@@ -0,0 +1,190 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"cdk-get/internal/errors"
+)
+
+// loginThrottleColumns 登录失败计数查询列，与 scanLoginThrottle 的顺序一致
+const loginThrottleColumns = `kind, identity, failures, lockouts, last_failed_at, locked_until`
+
+// GetLoginThrottle 获取 IP 或用户名的登录失败计数
+func (r *SqliteRepository) GetLoginThrottle(ctx context.Context, kind, identity string) (*LoginThrottle, error) {
+	row := r.db.QueryRowContext(ctx,
+		`SELECT `+loginThrottleColumns+` FROM login_throttles WHERE kind = ? AND identity = ?`, kind, identity)
+	throttle, err := scanLoginThrottle(row)
+	if err == sql.ErrNoRows {
+		return nil, ErrLoginThrottleNotFound
+	}
+	if err != nil {
+		return nil, errors.NewDatabaseError("get_login_throttle", err)
+	}
+	return throttle, nil
+}
+
+// SaveLoginThrottle 保存登录失败计数
+func (r *SqliteRepository) SaveLoginThrottle(ctx context.Context, throttle *LoginThrottle) error {
+	if throttle.Kind != LoginThrottleKindIP && throttle.Kind != LoginThrottleKindUsername {
+		return errors.NewValidationError("kind", "must be ip or username")
+	}
+
+	if _, err := r.db.ExecContext(ctx,
+		`INSERT INTO login_throttles (kind, identity, failures, lockouts, last_failed_at, locked_until)
+		 VALUES (?, ?, ?, ?, ?, ?)
+		 ON CONFLICT(kind, identity) DO UPDATE SET
+		     failures = excluded.failures,
+		     lockouts = excluded.lockouts,
+		     last_failed_at = excluded.last_failed_at,
+		     locked_until = excluded.locked_until`,
+		throttle.Kind, throttle.Identity, throttle.Failures, throttle.Lockouts,
+		throttle.LastFailedAt, throttle.LockedUntil); err != nil {
+		return errors.NewDatabaseError("save_login_throttle", err)
+	}
+	return nil
+}
+
+// DeleteLoginThrottle 清除登录失败计数
+func (r *SqliteRepository) DeleteLoginThrottle(ctx context.Context, kind, identity string) error {
+	result, err := r.db.ExecContext(ctx,
+		`DELETE FROM login_throttles WHERE kind = ? AND identity = ?`, kind, identity)
+	if err != nil {
+		return errors.NewDatabaseError("delete_login_throttle", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return errors.NewDatabaseError("get_rows_affected", err)
+	}
+	if rowsAffected == 0 {
+		return ErrLoginThrottleNotFound
+	}
+	return nil
+}
+
+// ListLoginThrottles 按最近失败时间倒序列出全部登录失败计数
+func (r *SqliteRepository) ListLoginThrottles(ctx context.Context) ([]*LoginThrottle, error) {
+	rows, err := r.db.QueryContext(ctx,
+		`SELECT `+loginThrottleColumns+` FROM login_throttles ORDER BY julianday(last_failed_at) DESC`)
+	if err != nil {
+		return nil, errors.NewDatabaseError("list_login_throttles", err)
+	}
+	defer rows.Close()
+
+	var throttles []*LoginThrottle
+	for rows.Next() {
+		throttle, err := scanLoginThrottle(rows)
+		if err != nil {
+			return nil, errors.NewDatabaseError("scan_login_throttle", err)
+		}
+		throttles = append(throttles, throttle)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, errors.NewDatabaseError("iterate_login_throttles", err)
+	}
+
+	return throttles, nil
+}
+
+// CreateLoginFailure 记录一次失败的登录
+func (r *SqliteRepository) CreateLoginFailure(ctx context.Context, failure *LoginFailure) error {
+	if failure.CreatedAt.IsZero() {
+		failure.CreatedAt = time.Now()
+	}
+
+	result, err := r.db.ExecContext(ctx,
+		`INSERT INTO admin_login_failures (username, ip, user_agent, reason, created_at) VALUES (?, ?, ?, ?, ?)`,
+		failure.Username, failure.IP, failure.UserAgent, failure.Reason, failure.CreatedAt)
+	if err != nil {
+		return errors.NewDatabaseError("create_login_failure", err)
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		return errors.NewDatabaseError("get_login_failure_id", err)
+	}
+	failure.ID = id
+	return nil
+}
+
+// ListLoginFailures 按时间倒序列出最近 limit 条失败的登录
+func (r *SqliteRepository) ListLoginFailures(ctx context.Context, limit int) ([]*LoginFailure, error) {
+	rows, err := r.db.QueryContext(ctx,
+		`SELECT id, username, ip, user_agent, reason, created_at FROM admin_login_failures
+		 ORDER BY id DESC LIMIT ?`, limit)
+	if err != nil {
+		return nil, errors.NewDatabaseError("list_login_failures", err)
+	}
+	defer rows.Close()
+
+	var failures []*LoginFailure
+	for rows.Next() {
+		var failure LoginFailure
+		if err := rows.Scan(&failure.ID, &failure.Username, &failure.IP, &failure.UserAgent,
+			&failure.Reason, &failure.CreatedAt); err != nil {
+			return nil, errors.NewDatabaseError("scan_login_failure", err)
+		}
+		failures = append(failures, &failure)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, errors.NewDatabaseError("iterate_login_failures", err)
+	}
+
+	return failures, nil
+}
+
+// PruneLoginFailures 删除早于 before 的登录失败记录
+// 同时清理最近失败早于 before 且未处于锁定状态的失败计数，这些计数早已超出计数窗口
+// 单次最多删除 limit 条记录，调用方需循环调用直到返回值小于 limit
+func (r *SqliteRepository) PruneLoginFailures(ctx context.Context, before time.Time, limit int) (int64, error) {
+	result, err := r.db.ExecContext(ctx,
+		`DELETE FROM admin_login_failures WHERE id IN (
+		     SELECT id FROM admin_login_failures
+		     WHERE julianday(created_at) < julianday(?)
+		     ORDER BY id ASC
+		     LIMIT ?)`, before, limit)
+	if err != nil {
+		return 0, errors.NewDatabaseError("prune_login_failures", err)
+	}
+
+	deleted, err := result.RowsAffected()
+	if err != nil {
+		return 0, errors.NewDatabaseError("get_rows_affected", err)
+	}
+
+	if _, err := r.db.ExecContext(ctx,
+		`DELETE FROM login_throttles
+		 WHERE julianday(last_failed_at) < julianday(?)
+		   AND (locked_until IS NULL OR julianday(locked_until) < julianday(?))`,
+		before, before); err != nil {
+		return deleted, errors.NewDatabaseError("prune_login_throttles", err)
+	}
+
+	r.logger.WithFields(logrus.Fields{
+		"before":  before,
+		"limit":   limit,
+		"deleted": deleted,
+	}).Debug("login failures pruned")
+
+	return deleted, nil
+}
+
+// scanLoginThrottle 扫描 loginThrottleColumns 查询出的一行
+func scanLoginThrottle(row interface{ Scan(...interface{}) error }) (*LoginThrottle, error) {
+	var throttle LoginThrottle
+	var lockedUntil sql.NullTime
+	if err := row.Scan(&throttle.Kind, &throttle.Identity, &throttle.Failures, &throttle.Lockouts,
+		&throttle.LastFailedAt, &lockedUntil); err != nil {
+		return nil, err
+	}
+	if lockedUntil.Valid {
+		throttle.LockedUntil = &lockedUntil.Time
+	}
+	return &throttle, nil
+}
@@ -0,0 +1,119 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"net/mail"
+	"net/url"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+
+	"cdk-get/internal/errors"
+)
+
+// ListUserNotificationTargets 列出用户的个人通知目标
+func (r *SqliteRepository) ListUserNotificationTargets(ctx context.Context, fid string) ([]*NotificationTarget, error) {
+	query := `SELECT id, fid, type, target, created_at
+	          FROM user_notification_targets
+	          WHERE fid = ?
+	          ORDER BY id ASC`
+	stmt, err := r.db.PrepareContext(ctx, query)
+	if err != nil {
+		return nil, errors.NewDatabaseError("prepare_list_notification_targets", err)
+	}
+	defer stmt.Close()
+
+	rows, err := stmt.QueryContext(ctx, fid)
+	if err != nil {
+		return nil, errors.NewDatabaseError("list_notification_targets", err)
+	}
+	defer rows.Close()
+
+	var targets []*NotificationTarget
+	for rows.Next() {
+		var target NotificationTarget
+		var createdAt sql.NullTime
+		if err := rows.Scan(&target.ID, &target.FID, &target.Type, &target.Target, &createdAt); err != nil {
+			return nil, errors.NewDatabaseError("scan_notification_target", err)
+		}
+		if createdAt.Valid {
+			target.CreatedAt = createdAt.Time
+		}
+		targets = append(targets, &target)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, errors.NewDatabaseError("iterate_notification_targets", err)
+	}
+
+	return targets, nil
+}
+
+// SetUserNotificationTargets 以 targets 覆盖用户的个人通知目标
+// 重复的目标只保存一次
+func (r *SqliteRepository) SetUserNotificationTargets(ctx context.Context, fid string, targets []*NotificationTarget) error {
+	for _, target := range targets {
+		target.Target = strings.TrimSpace(target.Target)
+		if err := validateNotificationTarget(target); err != nil {
+			return err
+		}
+	}
+
+	err := r.inTx(ctx, func(db dbInterface) error {
+		var exists bool
+		if err := db.QueryRowContext(ctx,
+			`SELECT EXISTS(SELECT 1 FROM fid_list WHERE fid = ? AND deleted_at IS NULL)`, fid).Scan(&exists); err != nil {
+			return errors.NewDatabaseError("check_user", err)
+		}
+		if !exists {
+			return ErrUserNotFound
+		}
+
+		if _, err := db.ExecContext(ctx, `DELETE FROM user_notification_targets WHERE fid = ?`, fid); err != nil {
+			return errors.NewDatabaseError("clear_notification_targets", err)
+		}
+
+		for _, target := range targets {
+			if _, err := db.ExecContext(ctx,
+				`INSERT OR IGNORE INTO user_notification_targets (fid, type, target) VALUES (?, ?, ?)`,
+				fid, target.Type, target.Target); err != nil {
+				return errors.NewDatabaseError("add_notification_target", err)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	r.logger.WithFields(logrus.Fields{
+		"fid":     fid,
+		"targets": len(targets),
+	}).Info("user notification targets updated")
+
+	return nil
+}
+
+// validateNotificationTarget 校验通知目标类型和地址格式
+func validateNotificationTarget(target *NotificationTarget) error {
+	if target.Target == "" {
+		return errors.NewValidationError("target", "must not be empty")
+	}
+
+	switch target.Type {
+	case NotificationTargetWxPusher, NotificationTargetTelegram:
+	case NotificationTargetEmail:
+		if _, err := mail.ParseAddress(target.Target); err != nil {
+			return errors.NewValidationError("target", "must be a valid email address")
+		}
+	case NotificationTargetWebhook:
+		u, err := url.Parse(target.Target)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return errors.NewValidationError("target", "must be an http(s) URL")
+		}
+	default:
+		return errors.NewValidationError("type", "must be one of wxpusher, email, telegram, webhook")
+	}
+	return nil
+}
@@ -0,0 +1,194 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"cdk-get/internal/errors"
+)
+
+// GetNotification 获取通知记录
+func (r *SqliteRepository) GetNotification(ctx context.Context, id int64) (*Notification, error) {
+	query := `SELECT ` + notificationColumns + ` FROM notifications WHERE id = ?`
+	notification, err := scanNotification(r.db.QueryRowContext(ctx, query, id))
+	if err == sql.ErrNoRows {
+		return nil, ErrNotificationNotFound
+	}
+	if err != nil {
+		return nil, errors.NewDatabaseError("get_notification", err)
+	}
+	return notification, nil
+}
+
+// ListDueNotifications 列出到达投递时间的 pending 通知
+func (r *SqliteRepository) ListDueNotifications(ctx context.Context, now time.Time, limit int) ([]*Notification, error) {
+	query := `SELECT ` + notificationColumns + `
+	          FROM notifications
+	          WHERE status = ? AND julianday(next_attempt_at) <= julianday(?)
+	          ORDER BY julianday(next_attempt_at) ASC, id ASC
+	          LIMIT ?`
+	return r.queryNotifications(ctx, query, NotificationStatusPending, now, limit)
+}
+
+// GetBatchDeadline 返回仍在收集中的汇总批次的投递时间
+func (r *SqliteRepository) GetBatchDeadline(ctx context.Context, batchKey string, now time.Time) (*time.Time, error) {
+	var deadline sql.NullTime
+	err := r.db.QueryRowContext(ctx,
+		`SELECT next_attempt_at FROM notifications
+		 WHERE batch_key = ? AND status = ? AND attempts = 0 AND julianday(next_attempt_at) > julianday(?)
+		 ORDER BY julianday(next_attempt_at) ASC
+		 LIMIT 1`,
+		batchKey, NotificationStatusPending, now).Scan(&deadline)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, errors.NewDatabaseError("get_batch_deadline", err)
+	}
+	if !deadline.Valid {
+		return nil, nil
+	}
+	return &deadline.Time, nil
+}
+
+// ListDueBatch 列出汇总批次中到达投递时间的 pending 通知
+func (r *SqliteRepository) ListDueBatch(ctx context.Context, batchKey string, now time.Time) ([]*Notification, error) {
+	query := `SELECT ` + notificationColumns + `
+	          FROM notifications
+	          WHERE batch_key = ? AND status = ? AND julianday(next_attempt_at) <= julianday(?)
+	          ORDER BY id ASC`
+	return r.queryNotifications(ctx, query, batchKey, NotificationStatusPending, now)
+}
+
+// UpdateNotificationDelivery 保存投递结果
+func (r *SqliteRepository) UpdateNotificationDelivery(ctx context.Context, notification *Notification) error {
+	notification.UpdatedAt = time.Now()
+	result, err := r.db.ExecContext(ctx,
+		`UPDATE notifications
+		 SET status = ?, result = ?, attempts = ?, next_attempt_at = ?, sent_at = ?, updated_at = ?
+		 WHERE id = ?`,
+		notification.Status,
+		notification.Result,
+		notification.Attempts,
+		notification.NextAttemptAt,
+		notification.SentAt,
+		notification.UpdatedAt,
+		notification.ID,
+	)
+	if err != nil {
+		return errors.NewDatabaseError("update_notification_delivery", err)
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return errors.NewDatabaseError("get_rows_affected", err)
+	}
+	if affected == 0 {
+		return ErrNotificationNotFound
+	}
+	return nil
+}
+
+// ResendNotification 重新投递通知
+func (r *SqliteRepository) ResendNotification(ctx context.Context, id int64) error {
+	now := time.Now()
+	result, err := r.db.ExecContext(ctx,
+		`UPDATE notifications
+		 SET status = ?, attempts = 0, next_attempt_at = ?, updated_at = ?
+		 WHERE id = ?`,
+		NotificationStatusPending, now, now, id)
+	if err != nil {
+		return errors.NewDatabaseError("resend_notification", err)
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return errors.NewDatabaseError("get_rows_affected", err)
+	}
+	if affected == 0 {
+		return ErrNotificationNotFound
+	}
+
+	r.logger.WithField("id", id).Info("notification queued for resend")
+	return nil
+}
+
+// queryNotifications 执行查询并扫描通知记录
+func (r *SqliteRepository) queryNotifications(ctx context.Context, query string, args ...any) ([]*Notification, error) {
+	stmt, err := r.db.PrepareContext(ctx, query)
+	if err != nil {
+		return nil, errors.NewDatabaseError("prepare_list_notifications", err)
+	}
+	defer stmt.Close()
+
+	rows, err := stmt.QueryContext(ctx, args...)
+	if err != nil {
+		return nil, errors.NewDatabaseError("list_notifications", err)
+	}
+	defer rows.Close()
+
+	var notifications []*Notification
+	for rows.Next() {
+		notification, err := scanNotification(rows)
+		if err != nil {
+			r.logger.WithFields(logrus.Fields{
+				"error": err,
+			}).Error("failed to scan notification")
+			return nil, errors.NewDatabaseError("scan_notification", err)
+		}
+		notifications = append(notifications, notification)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, errors.NewDatabaseError("iterate_notifications", err)
+	}
+
+	return notifications, nil
+}
+
+// scanNotification 扫描一行通知数据，列顺序见 notificationColumns
+func scanNotification(row rowScanner) (*Notification, error) {
+	var notification Notification
+	var nextAttemptAt, sentAt, createdAt, updatedAt sql.NullTime
+
+	err := row.Scan(
+		&notification.ID,
+		&notification.Channel,
+		&notification.Event,
+		&notification.FID,
+		&notification.Recipient,
+		&notification.BatchKey,
+		&notification.Title,
+		&notification.Summary,
+		&notification.Content,
+		&notification.Result,
+		&notification.Status,
+		&notification.Attempts,
+		&nextAttemptAt,
+		&sentAt,
+		&createdAt,
+		&updatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	if nextAttemptAt.Valid {
+		notification.NextAttemptAt = &nextAttemptAt.Time
+	}
+	if sentAt.Valid {
+		notification.SentAt = &sentAt.Time
+	}
+	if createdAt.Valid {
+		notification.CreatedAt = createdAt.Time
+	}
+	if updatedAt.Valid {
+		notification.UpdatedAt = updatedAt.Time
+	} else {
+		notification.UpdatedAt = notification.CreatedAt
+	}
+	return &notification, nil
+}
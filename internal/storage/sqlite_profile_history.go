@@ -0,0 +1,103 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"strconv"
+
+	"github.com/sirupsen/logrus"
+
+	"cdk-get/internal/errors"
+)
+
+// ListUserProfileHistory 按时间倒序列出用户的资料变更记录
+func (r *SqliteRepository) ListUserProfileHistory(ctx context.Context, fid string) ([]*ProfileChange, error) {
+	query := `SELECT id, fid, field, old_value, new_value, changed_at
+	          FROM user_profile_history
+	          WHERE fid = ?
+	          ORDER BY julianday(changed_at) DESC, id DESC`
+	stmt, err := r.db.PrepareContext(ctx, query)
+	if err != nil {
+		return nil, errors.NewDatabaseError("prepare_list_profile_history", err)
+	}
+	defer stmt.Close()
+
+	rows, err := stmt.QueryContext(ctx, fid)
+	if err != nil {
+		return nil, errors.NewDatabaseError("list_profile_history", err)
+	}
+	defer rows.Close()
+
+	var changes []*ProfileChange
+	for rows.Next() {
+		var change ProfileChange
+		var changedAt sql.NullTime
+		err := rows.Scan(
+			&change.ID,
+			&change.FID,
+			&change.Field,
+			&change.OldValue,
+			&change.NewValue,
+			&changedAt,
+		)
+		if err != nil {
+			return nil, errors.NewDatabaseError("scan_profile_history", err)
+		}
+		if changedAt.Valid {
+			change.ChangedAt = changedAt.Time
+		}
+		changes = append(changes, &change)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, errors.NewDatabaseError("iterate_profile_history", err)
+	}
+
+	return changes, nil
+}
+
+// loadProfile 读取用户当前的昵称、区服和头像
+// 用户不存在时返回 sql.ErrNoRows
+func loadProfile(ctx context.Context, db dbInterface, query string, fid string) (*User, error) {
+	user := &User{FID: fid}
+	err := db.QueryRowContext(ctx, query, fid).Scan(&user.Nickname, &user.KID, &user.AvatarImage)
+	if err != nil {
+		return nil, err
+	}
+	return user, nil
+}
+
+// recordProfileChanges 比较新旧资料并写入变更记录
+// 旧值为占位值（空昵称、非正数区服、空头像）时视为首次补全资料，不记录
+func (r *SqliteRepository) recordProfileChanges(ctx context.Context, db dbInterface, old, updated *User) error {
+	type change struct {
+		field    string
+		oldValue string
+		newValue string
+		skip     bool
+	}
+	changes := []change{
+		{ProfileFieldNickname, old.Nickname, updated.Nickname, old.Nickname == ""},
+		{ProfileFieldKID, strconv.Itoa(old.KID), strconv.Itoa(updated.KID), old.KID <= 0},
+		{ProfileFieldAvatar, old.AvatarImage, updated.AvatarImage, old.AvatarImage == ""},
+	}
+
+	for _, c := range changes {
+		if c.skip || c.oldValue == c.newValue {
+			continue
+		}
+		if _, err := db.ExecContext(ctx,
+			`INSERT INTO user_profile_history (fid, field, old_value, new_value, changed_at) VALUES (?, ?, ?, ?, CURRENT_TIMESTAMP)`,
+			old.FID, c.field, c.oldValue, c.newValue); err != nil {
+			return errors.NewDatabaseError("insert_profile_history", err)
+		}
+
+		r.logger.WithFields(logrus.Fields{
+			"fid":       old.FID,
+			"field":     c.field,
+			"old_value": c.oldValue,
+			"new_value": c.newValue,
+		}).Info("user profile changed")
+	}
+	return nil
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"database/sql"
 	"fmt"
+	"strings"
 	"time"
 
 	_ "github.com/ncruces/go-sqlite3/driver"
@@ -11,6 +12,7 @@ import (
 	"github.com/sirupsen/logrus"
 
 	"cdk-get/internal/errors"
+	"cdk-get/internal/metrics"
 )
 
 // SqliteRepository SQLite数据库仓库实现
@@ -179,51 +181,48 @@ func (r *SqliteRepository) ListGiftCodesByFID(ctx context.Context, fid string) (
 }
 
 // SaveUser 保存或更新用户信息
+// 更新已有用户时，昵称、区服和头像的变化会写入资料变更记录
+// 用户在回收站中时不做修改，返回 ErrUserInTrash
 func (r *SqliteRepository) SaveUser(ctx context.Context, user *User) error {
-	// 检查用户是否存在
-	var exists bool
-	checkQuery := `SELECT EXISTS(SELECT 1 FROM fid_list WHERE fid = ?)`
-	checkStmt, err := r.db.PrepareContext(ctx, checkQuery)
-	if err != nil {
-		return errors.NewDatabaseError("prepare_check_user", err)
-	}
-	defer checkStmt.Close()
-
-	err = checkStmt.QueryRowContext(ctx, user.FID).Scan(&exists)
-	if err != nil {
-		return errors.NewDatabaseError("check_user", err)
-	}
-
-	var query string
-	var stmt *sql.Stmt
-
-	if exists {
-		// 更新现有用户
-		query = `UPDATE fid_list SET nickname = ?, kid = ?, avatar_image = ? WHERE fid = ?`
-		stmt, err = r.db.PrepareContext(ctx, query)
-		if err != nil {
-			return errors.NewDatabaseError("prepare_update_user", err)
+	return r.inTx(ctx, func(db dbInterface) error {
+		var trashed bool
+		err := db.QueryRowContext(ctx,
+			`SELECT deleted_at IS NOT NULL FROM fid_list WHERE fid = ?`, user.FID).Scan(&trashed)
+		if err != nil && err != sql.ErrNoRows {
+			return errors.NewDatabaseError("check_user", err)
+		}
+		if trashed {
+			return ErrUserInTrash
 		}
-		defer stmt.Close()
 
-		_, err = stmt.ExecContext(ctx, user.Nickname, user.KID, user.AvatarImage, user.FID)
-		if err != nil {
-			return errors.NewDatabaseError("update_user", err)
+		old, err := loadProfile(ctx, db,
+			`SELECT nickname, kid, avatar_image FROM fid_list WHERE fid = ?`, user.FID)
+		if err != nil && err != sql.ErrNoRows {
+			return errors.NewDatabaseError("check_user", err)
 		}
 
-		r.logger.WithFields(logrus.Fields{
-			"fid": user.FID,
-		}).Debug("user updated successfully")
-	} else {
-		// 插入新用户
-		query = `INSERT INTO fid_list (fid, nickname, kid, avatar_image) VALUES (?, ?, ?, ?)`
-		stmt, err = r.db.PrepareContext(ctx, query)
-		if err != nil {
-			return errors.NewDatabaseError("prepare_insert_user", err)
+		if old != nil {
+			// 更新现有用户
+			_, err = db.ExecContext(ctx,
+				`UPDATE fid_list SET nickname = ?, kid = ?, avatar_image = ? WHERE fid = ?`,
+				user.Nickname, user.KID, user.AvatarImage, user.FID)
+			if err != nil {
+				return errors.NewDatabaseError("update_user", err)
+			}
+			if err := r.recordProfileChanges(ctx, db, old, user); err != nil {
+				return err
+			}
+
+			r.logger.WithFields(logrus.Fields{
+				"fid": user.FID,
+			}).Debug("user updated successfully")
+			return nil
 		}
-		defer stmt.Close()
 
-		_, err = stmt.ExecContext(ctx, user.FID, user.Nickname, user.KID, user.AvatarImage)
+		// 插入新用户
+		_, err = db.ExecContext(ctx,
+			`INSERT INTO fid_list (fid, nickname, kid, avatar_image) VALUES (?, ?, ?, ?)`,
+			user.FID, user.Nickname, user.KID, user.AvatarImage)
 		if err != nil {
 			return errors.NewDatabaseError("insert_user", err)
 		}
@@ -231,27 +230,20 @@ func (r *SqliteRepository) SaveUser(ctx context.Context, user *User) error {
 		r.logger.WithFields(logrus.Fields{
 			"fid": user.FID,
 		}).Debug("user created successfully")
-	}
-
-	return nil
+		return nil
+	})
 }
 
 // GetUser 获取用户信息
 func (r *SqliteRepository) GetUser(ctx context.Context, fid string) (*User, error) {
-	query := `SELECT fid, nickname, kid, avatar_image FROM fid_list WHERE fid = ?`
+	query := `SELECT ` + userColumns + ` FROM fid_list WHERE fid = ? AND deleted_at IS NULL`
 	stmt, err := r.db.PrepareContext(ctx, query)
 	if err != nil {
 		return nil, errors.NewDatabaseError("prepare_get_user", err)
 	}
 	defer stmt.Close()
 
-	var user User
-	err = stmt.QueryRowContext(ctx, fid).Scan(
-		&user.FID,
-		&user.Nickname,
-		&user.KID,
-		&user.AvatarImage,
-	)
+	user, err := scanUser(stmt.QueryRowContext(ctx, fid))
 	if err == sql.ErrNoRows {
 		return nil, errors.NewNotFoundError("user", fid)
 	}
@@ -259,16 +251,12 @@ func (r *SqliteRepository) GetUser(ctx context.Context, fid string) (*User, erro
 		return nil, errors.NewDatabaseError("get_user", err)
 	}
 
-	// Set default timestamps since fid_list doesn't have these fields
-	user.CreatedAt = time.Now()
-	user.UpdatedAt = time.Now()
-
-	return &user, nil
+	return user, nil
 }
 
 // ListUsers 列出所有用户
 func (r *SqliteRepository) ListUsers(ctx context.Context) ([]*User, error) {
-	query := `SELECT fid, nickname, kid, avatar_image FROM fid_list ORDER BY fid DESC`
+	query := `SELECT ` + userColumns + ` FROM fid_list WHERE deleted_at IS NULL ORDER BY fid DESC`
 	stmt, err := r.db.PrepareContext(ctx, query)
 	if err != nil {
 		return nil, errors.NewDatabaseError("prepare_list_users", err)
@@ -283,20 +271,11 @@ func (r *SqliteRepository) ListUsers(ctx context.Context) ([]*User, error) {
 
 	var users []*User
 	for rows.Next() {
-		var user User
-		err := rows.Scan(
-			&user.FID,
-			&user.Nickname,
-			&user.KID,
-			&user.AvatarImage,
-		)
+		user, err := scanUser(rows)
 		if err != nil {
 			return nil, errors.NewDatabaseError("scan_user", err)
 		}
-		// Set default timestamps since fid_list doesn't have these fields
-		user.CreatedAt = time.Now()
-		user.UpdatedAt = time.Now()
-		users = append(users, &user)
+		users = append(users, user)
 	}
 
 	if err := rows.Err(); err != nil {
@@ -306,49 +285,79 @@ func (r *SqliteRepository) ListUsers(ctx context.Context) ([]*User, error) {
 	return users, nil
 }
 
-// CreateTask 创建新任务
-func (r *SqliteRepository) CreateTask(ctx context.Context, code string) error {
-	// Check if task already exists
-	var exists bool
-	checkQuery := `SELECT EXISTS(SELECT 1 FROM gift_code_task WHERE code = ?)`
-	checkStmt, err := r.db.PrepareContext(ctx, checkQuery)
+// userColumns 用户查询的字段列表，与 scanUser 的扫描顺序一致
+// 分组名称以逗号拼接，分组名称不允许包含逗号
+const userColumns = `fid, nickname, kid, avatar_image, disabled,
+	          (SELECT GROUP_CONCAT(g.name, ',') FROM user_group_members m
+	           JOIN user_groups g ON g.id = m.group_id
+	           WHERE m.fid = fid_list.fid)`
+
+// scanUser 扫描一行用户数据
+func scanUser(row rowScanner) (*User, error) {
+	var user User
+	var disabled int
+	var groups sql.NullString
+
+	err := row.Scan(
+		&user.FID,
+		&user.Nickname,
+		&user.KID,
+		&user.AvatarImage,
+		&disabled,
+		&groups,
+	)
 	if err != nil {
-		return errors.NewDatabaseError("prepare_check_task", err)
+		return nil, err
+	}
+
+	user.Disabled = disabled != 0
+	user.Groups = []string{}
+	if groups.Valid && groups.String != "" {
+		user.Groups = strings.Split(groups.String, ",")
 	}
-	defer checkStmt.Close()
+	// Set default timestamps since fid_list doesn't have these fields
+	user.CreatedAt = time.Now()
+	user.UpdatedAt = time.Now()
 
-	err = checkStmt.QueryRowContext(ctx, code).Scan(&exists)
+	return &user, nil
+}
+
+// CreateTask 创建新任务
+// 返回是否插入了新任务，已存在（包括在回收站中）的兑换码不会重复创建
+// 由单条 INSERT OR IGNORE 判断是否插入，并发添加同一兑换码时只有一个调用得到 true
+func (r *SqliteRepository) CreateTask(ctx context.Context, code string) (bool, error) {
+	query := `INSERT OR IGNORE INTO gift_code_task (code, all_done, created_at) VALUES (?, 0, CURRENT_TIMESTAMP)`
+	stmt, err := r.db.PrepareContext(ctx, query)
 	if err != nil {
-		return errors.NewDatabaseError("check_task", err)
+		return false, errors.NewDatabaseError("prepare_create_task", err)
 	}
+	defer stmt.Close()
 
-	if !exists {
-		query := `INSERT INTO gift_code_task (code, all_done, created_at) VALUES (?, 0, CURRENT_TIMESTAMP)`
-		stmt, err := r.db.PrepareContext(ctx, query)
-		if err != nil {
-			return errors.NewDatabaseError("prepare_create_task", err)
-		}
-		defer stmt.Close()
+	result, err := stmt.ExecContext(ctx, code)
+	if err != nil {
+		return false, errors.NewDatabaseError("create_task", err)
+	}
 
-		_, err = stmt.ExecContext(ctx, code)
-		if err != nil {
-			return errors.NewDatabaseError("create_task", err)
-		}
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return false, errors.NewDatabaseError("create_task_rows_affected", err)
+	}
 
+	if rows > 0 {
 		r.logger.WithFields(logrus.Fields{
 			"code": code,
 		}).Debug("task created successfully")
 	}
 
-	return nil
+	return rows > 0, nil
 }
 
 // ListPendingTasks 列出所有待处理任务
 func (r *SqliteRepository) ListPendingTasks(ctx context.Context) ([]*Task, error) {
-	query := `SELECT code, all_done, retry_count, last_error, created_at, completed_at 
-	          FROM gift_code_task 
-	          WHERE all_done = 0 
-	          ORDER BY code ASC`
+	query := `SELECT ` + taskColumns + `
+	          FROM ` + taskTables + `
+	          WHERE t.all_done = 0 AND t.deleted_at IS NULL 
+	          ORDER BY t.code ASC`
 	stmt, err := r.db.PrepareContext(ctx, query)
 	if err != nil {
 		return nil, errors.NewDatabaseError("prepare_list_pending_tasks", err)
@@ -363,37 +372,11 @@ func (r *SqliteRepository) ListPendingTasks(ctx context.Context) ([]*Task, error
 
 	var tasks []*Task
 	for rows.Next() {
-		var task Task
-		var allDone int
-		var createdAt sql.NullTime
-		var completedAt sql.NullTime
-
-		err := rows.Scan(
-			&task.Code,
-			&allDone,
-			&task.RetryCount,
-			&task.LastError,
-			&createdAt,
-			&completedAt,
-		)
+		task, err := scanTask(rows)
 		if err != nil {
 			return nil, errors.NewDatabaseError("scan_task", err)
 		}
-
-		task.AllDone = allDone != 0
-		if createdAt.Valid {
-			task.CreatedAt = createdAt.Time
-			task.UpdatedAt = createdAt.Time
-		} else {
-			// Set default time if NULL
-			task.CreatedAt = time.Now()
-			task.UpdatedAt = time.Now()
-		}
-		if completedAt.Valid {
-			task.CompletedAt = &completedAt.Time
-		}
-
-		tasks = append(tasks, &task)
+		tasks = append(tasks, task)
 	}
 
 	if err := rows.Err(); err != nil {
@@ -517,10 +500,10 @@ func (r *SqliteRepository) UpdateTaskComplete(ctx context.Context, code string,
 
 // ListCompletedTasks 列出已完成的任务
 func (r *SqliteRepository) ListCompletedTasks(ctx context.Context, limit int) ([]*Task, error) {
-	query := `SELECT code, all_done, retry_count, last_error, created_at, completed_at 
-	          FROM gift_code_task 
-	          WHERE all_done = 1 
-	          ORDER BY completed_at DESC 
+	query := `SELECT ` + taskColumns + `
+	          FROM ` + taskTables + `
+	          WHERE t.all_done = 1 AND t.deleted_at IS NULL 
+	          ORDER BY t.completed_at DESC 
 	          LIMIT ?`
 	stmt, err := r.db.PrepareContext(ctx, query)
 	if err != nil {
@@ -542,40 +525,14 @@ func (r *SqliteRepository) ListCompletedTasks(ctx context.Context, limit int) ([
 
 	var tasks []*Task
 	for rows.Next() {
-		var task Task
-		var allDone int
-		var createdAt sql.NullTime
-		var completedAt sql.NullTime
-
-		err := rows.Scan(
-			&task.Code,
-			&allDone,
-			&task.RetryCount,
-			&task.LastError,
-			&createdAt,
-			&completedAt,
-		)
+		task, err := scanTask(rows)
 		if err != nil {
 			r.logger.WithFields(logrus.Fields{
 				"error": err,
 			}).Error("failed to scan completed task")
 			return nil, errors.NewDatabaseError("scan_completed_task", err)
 		}
-
-		task.AllDone = allDone != 0
-		if createdAt.Valid {
-			task.CreatedAt = createdAt.Time
-			task.UpdatedAt = createdAt.Time
-		} else {
-			// Set default time if NULL
-			task.CreatedAt = time.Now()
-			task.UpdatedAt = time.Now()
-		}
-		if completedAt.Valid {
-			task.CompletedAt = &completedAt.Time
-		}
-
-		tasks = append(tasks, &task)
+		tasks = append(tasks, task)
 	}
 
 	if err := rows.Err(); err != nil {
@@ -590,18 +547,35 @@ func (r *SqliteRepository) ListCompletedTasks(ctx context.Context, limit int) ([
 	return tasks, nil
 }
 
+// notificationColumns 通知记录查询列，与 scanNotification 的顺序一致
+const notificationColumns = `id, channel, event, fid, recipient, batch_key, title, summary, content, result, status,
+	attempts, next_attempt_at, sent_at, created_at, updated_at`
+
 // SaveNotification 保存通知记录
+// 状态为 pending 的记录由投递任务发送，未指定下次投递时间时立即投递
 func (r *SqliteRepository) SaveNotification(ctx context.Context, notification *Notification) error {
 	// 验证 status 字段值
-	if notification.Status != NotificationStatusSuccess && notification.Status != NotificationStatusFailed {
+	switch notification.Status {
+	case NotificationStatusPending, NotificationStatusSuccess, NotificationStatusFailed:
+	default:
 		r.logger.WithFields(logrus.Fields{
 			"status": notification.Status,
 		}).Error("invalid notification status")
-		return errors.NewValidationError("status", "must be 'success' or 'failed'")
+		return errors.NewValidationError("status", "must be 'pending', 'success' or 'failed'")
+	}
+
+	if notification.CreatedAt.IsZero() {
+		notification.CreatedAt = time.Now()
+	}
+	notification.UpdatedAt = notification.CreatedAt
+	if notification.Status == NotificationStatusPending && notification.NextAttemptAt == nil {
+		nextAttemptAt := notification.CreatedAt
+		notification.NextAttemptAt = &nextAttemptAt
 	}
 
-	query := `INSERT INTO notifications (channel, title, content, result, status, created_at) 
-	          VALUES (?, ?, ?, ?, ?, ?)`
+	query := `INSERT INTO notifications (channel, event, fid, recipient, batch_key, title, summary, content, result, status,
+	              attempts, next_attempt_at, sent_at, created_at, updated_at)
+	          VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`
 	stmt, err := r.db.PrepareContext(ctx, query)
 	if err != nil {
 		r.logger.WithFields(logrus.Fields{
@@ -613,11 +587,20 @@ func (r *SqliteRepository) SaveNotification(ctx context.Context, notification *N
 
 	result, err := stmt.ExecContext(ctx,
 		notification.Channel,
+		notification.Event,
+		notification.FID,
+		notification.Recipient,
+		notification.BatchKey,
 		notification.Title,
+		notification.Summary,
 		notification.Content,
 		notification.Result,
 		notification.Status,
+		notification.Attempts,
+		notification.NextAttemptAt,
+		notification.SentAt,
 		notification.CreatedAt,
+		notification.UpdatedAt,
 	)
 	if err != nil {
 		r.logger.WithFields(logrus.Fields{
@@ -647,53 +630,16 @@ func (r *SqliteRepository) SaveNotification(ctx context.Context, notification *N
 
 // ListNotifications 列出通知记录
 func (r *SqliteRepository) ListNotifications(ctx context.Context, limit int) ([]*Notification, error) {
-	query := `SELECT id, channel, title, content, result, status, created_at 
+	query := `SELECT ` + notificationColumns + `
 	          FROM notifications 
 	          ORDER BY created_at DESC 
 	          LIMIT ?`
-	stmt, err := r.db.PrepareContext(ctx, query)
-	if err != nil {
-		r.logger.WithFields(logrus.Fields{
-			"error": err,
-		}).Error("failed to prepare list notifications statement")
-		return nil, errors.NewDatabaseError("prepare_list_notifications", err)
-	}
-	defer stmt.Close()
-
-	rows, err := stmt.QueryContext(ctx, limit)
+	notifications, err := r.queryNotifications(ctx, query, limit)
 	if err != nil {
 		r.logger.WithFields(logrus.Fields{
 			"error": err,
 		}).Error("failed to list notifications")
-		return nil, errors.NewDatabaseError("list_notifications", err)
-	}
-	defer rows.Close()
-
-	var notifications []*Notification
-	for rows.Next() {
-		var notification Notification
-
-		err := rows.Scan(
-			&notification.ID,
-			&notification.Channel,
-			&notification.Title,
-			&notification.Content,
-			&notification.Result,
-			&notification.Status,
-			&notification.CreatedAt,
-		)
-		if err != nil {
-			r.logger.WithFields(logrus.Fields{
-				"error": err,
-			}).Error("failed to scan notification")
-			return nil, errors.NewDatabaseError("scan_notification", err)
-		}
-
-		notifications = append(notifications, &notification)
-	}
-
-	if err := rows.Err(); err != nil {
-		return nil, errors.NewDatabaseError("iterate_notifications", err)
+		return nil, err
 	}
 
 	r.logger.WithFields(logrus.Fields{
@@ -706,31 +652,58 @@ func (r *SqliteRepository) ListNotifications(ctx context.Context, limit int) ([]
 
 // GetTaskByCode 获取任务信息
 func (r *SqliteRepository) GetTaskByCode(ctx context.Context, code string) (*Task, error) {
-	query := `SELECT code, all_done, retry_count, last_error, created_at, completed_at FROM gift_code_task WHERE code = ?`
+	query := `SELECT ` + taskColumns + ` FROM ` + taskTables + ` WHERE t.code = ? AND t.deleted_at IS NULL`
 	stmt, err := r.db.PrepareContext(ctx, query)
 	if err != nil {
 		return nil, errors.NewDatabaseError("prepare_get_task", err)
 	}
 	defer stmt.Close()
 
+	task, err := scanTask(stmt.QueryRowContext(ctx, code))
+	if err == sql.ErrNoRows {
+		return nil, errors.NewNotFoundError("task", code)
+	}
+	if err != nil {
+		return nil, errors.NewDatabaseError("get_task", err)
+	}
+
+	return task, nil
+}
+
+// taskColumns 任务查询的字段列表，与 scanTask 的扫描顺序一致
+const taskColumns = `t.code, t.all_done, t.retry_count, t.last_error, t.created_at, t.completed_at,
+	          t.deleted_at, t.target_group_id, COALESCE(g.name, '')`
+
+// taskTables 任务查询的数据来源，关联目标分组名称
+const taskTables = `gift_code_task t LEFT JOIN user_groups g ON g.id = t.target_group_id`
+
+// rowScanner 兼容 *sql.Row 与 *sql.Rows
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+// scanTask 扫描一行任务数据
+func scanTask(row rowScanner) (*Task, error) {
 	var task Task
 	var allDone int
 	var createdAt sql.NullTime
 	var completedAt sql.NullTime
+	var deletedAt sql.NullTime
+	var targetGroupID sql.NullInt64
 
-	err = stmt.QueryRowContext(ctx, code).Scan(
+	err := row.Scan(
 		&task.Code,
 		&allDone,
 		&task.RetryCount,
 		&task.LastError,
 		&createdAt,
 		&completedAt,
+		&deletedAt,
+		&targetGroupID,
+		&task.TargetGroup,
 	)
-	if err == sql.ErrNoRows {
-		return nil, errors.NewNotFoundError("task", code)
-	}
 	if err != nil {
-		return nil, errors.NewDatabaseError("get_task", err)
+		return nil, err
 	}
 
 	task.AllDone = allDone != 0
@@ -745,6 +718,12 @@ func (r *SqliteRepository) GetTaskByCode(ctx context.Context, code string) (*Tas
 	if completedAt.Valid {
 		task.CompletedAt = &completedAt.Time
 	}
+	if deletedAt.Valid {
+		task.DeletedAt = &deletedAt.Time
+	}
+	if targetGroupID.Valid {
+		task.TargetGroupID = &targetGroupID.Int64
+	}
 
 	return &task, nil
 }
@@ -793,19 +772,26 @@ type txDB struct {
 	tx *sql.Tx
 }
 
-func (t *txDB) PrepareContext(ctx context.Context, query string) (*sql.Stmt, error) {
-	return t.tx.PrepareContext(ctx, query)
+func (t *txDB) PrepareContext(ctx context.Context, query string) (*timedStmt, error) {
+	stmt, err := t.tx.PrepareContext(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	return &timedStmt{Stmt: stmt, operation: queryOperation(query)}, nil
 }
 
 func (t *txDB) ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+	defer observeQuery(query, time.Now())
 	return t.tx.ExecContext(ctx, query, args...)
 }
 
 func (t *txDB) QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error) {
+	defer observeQuery(query, time.Now())
 	return t.tx.QueryContext(ctx, query, args...)
 }
 
 func (t *txDB) QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row {
+	defer observeQuery(query, time.Now())
 	return t.tx.QueryRowContext(ctx, query, args...)
 }
 
@@ -835,8 +821,9 @@ func (r *SqliteRepository) Close() error {
 }
 
 // dbInterface 定义数据库操作接口，用于支持事务
+// 实现在执行语句时记录 db_query_duration_seconds 指标
 type dbInterface interface {
-	PrepareContext(ctx context.Context, query string) (*sql.Stmt, error)
+	PrepareContext(ctx context.Context, query string) (*timedStmt, error)
 	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
 	QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error)
 	QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row
@@ -847,19 +834,26 @@ type sqlDB struct {
 	db *sql.DB
 }
 
-func (s *sqlDB) PrepareContext(ctx context.Context, query string) (*sql.Stmt, error) {
-	return s.db.PrepareContext(ctx, query)
+func (s *sqlDB) PrepareContext(ctx context.Context, query string) (*timedStmt, error) {
+	stmt, err := s.db.PrepareContext(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	return &timedStmt{Stmt: stmt, operation: queryOperation(query)}, nil
 }
 
 func (s *sqlDB) ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+	defer observeQuery(query, time.Now())
 	return s.db.ExecContext(ctx, query, args...)
 }
 
 func (s *sqlDB) QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error) {
+	defer observeQuery(query, time.Now())
 	return s.db.QueryContext(ctx, query, args...)
 }
 
 func (s *sqlDB) QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row {
+	defer observeQuery(query, time.Now())
 	return s.db.QueryRowContext(ctx, query, args...)
 }
 
@@ -875,6 +869,53 @@ func (s *sqlDB) BeginTx(ctx context.Context, opts *sql.TxOptions) (*sql.Tx, erro
 	return s.db.BeginTx(ctx, opts)
 }
 
+// timedStmt 包装 *sql.Stmt，执行时记录语句耗时
+type timedStmt struct {
+	*sql.Stmt
+	operation string
+}
+
+func (s *timedStmt) ExecContext(ctx context.Context, args ...interface{}) (sql.Result, error) {
+	defer observeOperation(s.operation, time.Now())
+	return s.Stmt.ExecContext(ctx, args...)
+}
+
+func (s *timedStmt) QueryContext(ctx context.Context, args ...interface{}) (*sql.Rows, error) {
+	defer observeOperation(s.operation, time.Now())
+	return s.Stmt.QueryContext(ctx, args...)
+}
+
+func (s *timedStmt) QueryRowContext(ctx context.Context, args ...interface{}) *sql.Row {
+	defer observeOperation(s.operation, time.Now())
+	return s.Stmt.QueryRowContext(ctx, args...)
+}
+
+// queryOperation 返回语句的类型（select、insert、update、delete），其余语句为 other
+// WITH 开头的语句按 select 统计
+func queryOperation(query string) string {
+	fields := strings.Fields(query)
+	if len(fields) == 0 {
+		return "other"
+	}
+	switch op := strings.ToLower(fields[0]); op {
+	case "select", "insert", "update", "delete":
+		return op
+	case "with":
+		return "select"
+	default:
+		return "other"
+	}
+}
+
+// observeQuery 记录从 start 开始执行的语句耗时
+func observeQuery(query string, start time.Time) {
+	observeOperation(queryOperation(query), start)
+}
+
+func observeOperation(operation string, start time.Time) {
+	metrics.ObserveQuery(operation, time.Since(start))
+}
+
 // KeyStorage interface implementation for backward compatibility
 // These methods wrap the context-aware methods with a background context
 
@@ -889,15 +930,18 @@ func (r *SqliteRepository) Save(fid, code string) error {
 }
 
 // GetFids 获取用户id列表
+// 仅返回参与兑换的用户（未删除且未禁用）
 func (r *SqliteRepository) GetFids() ([]string, error) {
-	users, err := r.ListUsers(context.Background())
+	ctx := context.Background()
+	keys, err := queryKeys(ctx, r.db,
+		`SELECT fid FROM fid_list WHERE deleted_at IS NULL AND disabled = 0 ORDER BY fid DESC`)
 	if err != nil {
-		return nil, err
+		return nil, errors.NewDatabaseError("get_fids", err)
 	}
 
-	fids := make([]string, 0, len(users))
-	for _, user := range users {
-		fids = append(fids, user.FID)
+	fids := make([]string, 0, len(keys))
+	for _, key := range keys {
+		fids = append(fids, key.(string))
 	}
 	return fids, nil
 }
@@ -915,7 +959,8 @@ func (r *SqliteRepository) SaveFidInfo(fid int, nickname string, kid int, avatar
 
 // AddTask 新增任务
 func (r *SqliteRepository) AddTask(code string) error {
-	return r.CreateTask(context.Background(), code)
+	_, err := r.CreateTask(context.Background(), code)
+	return err
 }
 
 // GetTask 获取未完成的任务
@@ -937,8 +982,8 @@ func (r *SqliteRepository) DoneTask(code string) error {
 	return r.MarkTaskComplete(context.Background(), code)
 }
 
-// DeleteTask 删除任务及其关联的兑换码
-// 在事务中执行，确保原子性
+// DeleteTask 软删除任务
+// 任务移至回收站，关联的兑换码保留，便于误删后恢复
 func (r *SqliteRepository) DeleteTask(ctx context.Context, code string) error {
 	// 记录删除操作开始
 	r.logger.WithFields(logrus.Fields{
@@ -947,85 +992,30 @@ func (r *SqliteRepository) DeleteTask(ctx context.Context, code string) error {
 		"stage":     "start",
 	}).Info("starting delete task operation")
 
-	// 获取底层的 *sql.DB 以开始事务
-	sqlDB, ok := r.db.(*sqlDB)
-	if !ok {
-		r.logger.WithFields(logrus.Fields{
-			"code":       code,
-			"operation":  "DeleteTask",
-			"error_type": "type_assertion_failed",
-		}).Error("cannot start transaction on non-db connection")
-		return fmt.Errorf("cannot start transaction on non-db connection")
-	}
-
-	// 开始事务
-	tx, err := sqlDB.BeginTx(ctx, nil)
-	if err != nil {
-		r.logger.WithFields(logrus.Fields{
-			"code":       code,
-			"operation":  "DeleteTask",
-			"stage":      "begin_transaction",
-			"error_type": "transaction_begin_failed",
-			"error":      err.Error(),
-		}).Error("failed to begin transaction for delete task")
-		return fmt.Errorf("failed to begin transaction: %w", err)
-	}
-	defer tx.Rollback() // 如果没有提交，自动回滚
-
-	// 注意：当前 gift_codes 表没有 task_id 字段，无法建立关联
-	// 这里只删除 gift_codes 表中 code 字段匹配的记录
-	// 如果需要真正的级联删除，需要先添加 task_id 字段到 gift_codes 表
-	result, err := tx.ExecContext(ctx,
-		"DELETE FROM gift_codes WHERE code = ?",
-		code)
+	query := `UPDATE gift_code_task SET deleted_at = ? WHERE code = ? AND deleted_at IS NULL`
+	stmt, err := r.db.PrepareContext(ctx, query)
 	if err != nil {
-		r.logger.WithFields(logrus.Fields{
-			"code":       code,
-			"operation":  "DeleteTask",
-			"stage":      "delete_gift_codes",
-			"error_type": "delete_failed",
-			"error":      err.Error(),
-			"table":      "gift_codes",
-		}).Error("failed to delete gift codes")
-		return fmt.Errorf("failed to delete gift codes: %w", err)
+		return errors.NewDatabaseError("prepare_delete_task", err)
 	}
+	defer stmt.Close()
 
-	// 记录删除的兑换码数量
-	giftCodesDeleted, _ := result.RowsAffected()
-	r.logger.WithFields(logrus.Fields{
-		"code":               code,
-		"operation":          "DeleteTask",
-		"stage":              "delete_gift_codes",
-		"gift_codes_deleted": giftCodesDeleted,
-	}).Debug("gift codes deleted")
-
-	// 删除任务记录
-	result, err = tx.ExecContext(ctx,
-		"DELETE FROM gift_code_task WHERE code = ?",
-		code)
+	result, err := stmt.ExecContext(ctx, time.Now(), code)
 	if err != nil {
 		r.logger.WithFields(logrus.Fields{
 			"code":       code,
 			"operation":  "DeleteTask",
-			"stage":      "delete_task",
-			"error_type": "delete_failed",
+			"stage":      "soft_delete",
+			"error_type": "update_failed",
 			"error":      err.Error(),
 			"table":      "gift_code_task",
-		}).Error("failed to delete task")
-		return fmt.Errorf("failed to delete task: %w", err)
+		}).Error("failed to soft delete task")
+		return errors.NewDatabaseError("delete_task", err)
 	}
 
 	// 检查任务是否存在
 	rowsAffected, err := result.RowsAffected()
 	if err != nil {
-		r.logger.WithFields(logrus.Fields{
-			"code":       code,
-			"operation":  "DeleteTask",
-			"stage":      "check_rows_affected",
-			"error_type": "rows_affected_failed",
-			"error":      err.Error(),
-		}).Error("failed to get rows affected")
-		return fmt.Errorf("failed to get rows affected: %w", err)
+		return errors.NewDatabaseError("get_rows_affected", err)
 	}
 	if rowsAffected == 0 {
 		r.logger.WithFields(logrus.Fields{
@@ -1037,27 +1027,13 @@ func (r *SqliteRepository) DeleteTask(ctx context.Context, code string) error {
 		return ErrTaskNotFound
 	}
 
-	// 提交事务
-	if err = tx.Commit(); err != nil {
-		r.logger.WithFields(logrus.Fields{
-			"code":       code,
-			"operation":  "DeleteTask",
-			"stage":      "commit_transaction",
-			"error_type": "commit_failed",
-			"error":      err.Error(),
-		}).Error("failed to commit transaction")
-		return fmt.Errorf("failed to commit transaction: %w", err)
-	}
-
 	// 记录成功删除
 	r.logger.WithFields(logrus.Fields{
-		"code":               code,
-		"operation":          "DeleteTask",
-		"stage":              "complete",
-		"result":             "success",
-		"tasks_deleted":      rowsAffected,
-		"gift_codes_deleted": giftCodesDeleted,
-	}).Info("task deleted successfully")
+		"code":      code,
+		"operation": "DeleteTask",
+		"stage":     "complete",
+		"result":    "success",
+	}).Info("task moved to trash")
 
 	return nil
 }
@@ -2,7 +2,9 @@ package storage
 
 import (
 	"context"
+	"fmt"
 	"os"
+	"strings"
 	"testing"
 	"time"
 
@@ -148,9 +150,22 @@ func TestSqliteRepository_Task(t *testing.T) {
 
 	// 测试创建任务
 	code := "TASK2024"
-	if err := repo.CreateTask(ctx, code); err != nil {
+	created, err := repo.CreateTask(ctx, code)
+	if err != nil {
 		t.Fatalf("failed to create task: %v", err)
 	}
+	if !created {
+		t.Error("expected first CreateTask to insert the task")
+	}
+
+	// 重复创建不插入
+	created, err = repo.CreateTask(ctx, code)
+	if err != nil {
+		t.Fatalf("failed to create duplicate task: %v", err)
+	}
+	if created {
+		t.Error("expected duplicate CreateTask to report not inserted")
+	}
 
 	// 测试列出待处理任务
 	tasks, err := repo.ListPendingTasks(ctx)
@@ -257,7 +272,7 @@ func TestSqliteRepository_EnhancedTaskMethods(t *testing.T) {
 
 	// 创建测试任务
 	code := "ENHANCED_TASK_2024"
-	if err := repo.CreateTask(ctx, code); err != nil {
+	if _, err := repo.CreateTask(ctx, code); err != nil {
 		t.Fatalf("failed to create task: %v", err)
 	}
 
@@ -423,7 +438,7 @@ func TestSqliteRepository_DeleteTask(t *testing.T) {
 	t.Run("delete existing task", func(t *testing.T) {
 		// 创建测试任务
 		code := "DELETE_TEST_2024"
-		if err := repo.CreateTask(ctx, code); err != nil {
+		if _, err := repo.CreateTask(ctx, code); err != nil {
 			t.Fatalf("failed to create task: %v", err)
 		}
 
@@ -443,13 +458,18 @@ func TestSqliteRepository_DeleteTask(t *testing.T) {
 			t.Error("expected error when getting deleted task, got nil")
 		}
 
-		// 验证关联的兑换码也被删除
+		// 软删除保留关联的兑换码
 		received, err := repo.IsGiftCodeReceived(ctx, "user1", code)
 		if err != nil {
 			t.Fatalf("failed to check gift code: %v", err)
 		}
-		if received {
-			t.Error("expected gift code to be deleted, but it still exists")
+		if !received {
+			t.Error("expected gift code to be kept after soft delete")
+		}
+
+		// 重复删除应返回 ErrTaskNotFound
+		if err := repo.DeleteTask(ctx, code); err != ErrTaskNotFound {
+			t.Errorf("expected ErrTaskNotFound on second delete, got %v", err)
 		}
 	})
 
@@ -465,7 +485,7 @@ func TestSqliteRepository_DeleteTask(t *testing.T) {
 	t.Run("delete task without gift codes", func(t *testing.T) {
 		// 创建任务但不添加兑换码
 		code := "TASK_NO_CODES"
-		if err := repo.CreateTask(ctx, code); err != nil {
+		if _, err := repo.CreateTask(ctx, code); err != nil {
 			t.Fatalf("failed to create task: %v", err)
 		}
 
@@ -481,3 +501,1813 @@ func TestSqliteRepository_DeleteTask(t *testing.T) {
 		}
 	})
 }
+
+func TestSqliteRepository_Retention(t *testing.T) {
+	tmpFile := "./test_retention.db"
+	defer os.Remove(tmpFile)
+
+	config := DefaultSqliteConfig()
+	config.Path = tmpFile
+
+	logger := logrus.New()
+	logger.SetLevel(logrus.ErrorLevel)
+
+	repo, err := NewSqliteRepository(config, logger)
+	if err != nil {
+		t.Fatalf("failed to create repository: %v", err)
+	}
+	defer repo.Close()
+
+	ctx := context.Background()
+	now := time.Now()
+	cutoff := now.Add(-24 * time.Hour)
+
+	t.Run("prune notifications in batches", func(t *testing.T) {
+		for i := 0; i < 5; i++ {
+			notif := &Notification{
+				Channel:   "wxpusher",
+				Title:     fmt.Sprintf("old %d", i),
+				Content:   "content",
+				Result:    "ok",
+				Status:    NotificationStatusSuccess,
+				CreatedAt: now.Add(-48 * time.Hour),
+			}
+			if err := repo.SaveNotification(ctx, notif); err != nil {
+				t.Fatalf("failed to save notification: %v", err)
+			}
+		}
+		recent := &Notification{
+			Channel:   "wxpusher",
+			Title:     "recent",
+			Content:   "content",
+			Result:    "ok",
+			Status:    NotificationStatusSuccess,
+			CreatedAt: now,
+		}
+		if err := repo.SaveNotification(ctx, recent); err != nil {
+			t.Fatalf("failed to save notification: %v", err)
+		}
+
+		deleted, err := repo.PruneNotifications(ctx, cutoff, 3)
+		if err != nil {
+			t.Fatalf("failed to prune notifications: %v", err)
+		}
+		if deleted != 3 {
+			t.Errorf("expected 3 notifications deleted in first batch, got %d", deleted)
+		}
+
+		deleted, err = repo.PruneNotifications(ctx, cutoff, 3)
+		if err != nil {
+			t.Fatalf("failed to prune notifications: %v", err)
+		}
+		if deleted != 2 {
+			t.Errorf("expected 2 notifications deleted in second batch, got %d", deleted)
+		}
+
+		remaining, err := repo.ListNotifications(ctx, 10)
+		if err != nil {
+			t.Fatalf("failed to list notifications: %v", err)
+		}
+		if len(remaining) != 1 || remaining[0].Title != "recent" {
+			t.Errorf("expected only the recent notification to remain, got %d", len(remaining))
+		}
+	})
+
+	t.Run("prune completed tasks with gift codes", func(t *testing.T) {
+		if _, err := repo.CreateTask(ctx, "OLD_TASK"); err != nil {
+			t.Fatalf("failed to create task: %v", err)
+		}
+		if err := repo.SaveGiftCode(ctx, "user1", "OLD_TASK"); err != nil {
+			t.Fatalf("failed to save gift code: %v", err)
+		}
+		if err := repo.UpdateTaskComplete(ctx, "OLD_TASK", now.Add(-48*time.Hour)); err != nil {
+			t.Fatalf("failed to complete task: %v", err)
+		}
+		if _, err := repo.CreateTask(ctx, "NEW_TASK"); err != nil {
+			t.Fatalf("failed to create task: %v", err)
+		}
+		if err := repo.UpdateTaskComplete(ctx, "NEW_TASK", now); err != nil {
+			t.Fatalf("failed to complete task: %v", err)
+		}
+		if _, err := repo.CreateTask(ctx, "PENDING_TASK"); err != nil {
+			t.Fatalf("failed to create task: %v", err)
+		}
+
+		deleted, err := repo.PruneCompletedTasks(ctx, cutoff, 10)
+		if err != nil {
+			t.Fatalf("failed to prune completed tasks: %v", err)
+		}
+		if deleted != 1 {
+			t.Errorf("expected 1 task deleted, got %d", deleted)
+		}
+
+		if _, err := repo.GetTaskByCode(ctx, "OLD_TASK"); err == nil {
+			t.Error("expected old task to be pruned")
+		}
+		if _, err := repo.GetTaskByCode(ctx, "NEW_TASK"); err != nil {
+			t.Errorf("expected new task to remain: %v", err)
+		}
+		if _, err := repo.GetTaskByCode(ctx, "PENDING_TASK"); err != nil {
+			t.Errorf("expected pending task to remain: %v", err)
+		}
+		received, err := repo.IsGiftCodeReceived(ctx, "user1", "OLD_TASK")
+		if err != nil {
+			t.Fatalf("failed to check gift code: %v", err)
+		}
+		if received {
+			t.Error("expected gift codes of pruned task to be deleted")
+		}
+	})
+
+	t.Run("prune runs and table stats", func(t *testing.T) {
+		for _, deleted := range []int64{1, 7} {
+			run := &PruneRun{
+				Target:     PruneTargetNotifications,
+				Cutoff:     cutoff,
+				Deleted:    deleted,
+				Status:     PruneRunStatusSuccess,
+				StartedAt:  now,
+				FinishedAt: now,
+			}
+			if err := repo.SavePruneRun(ctx, run); err != nil {
+				t.Fatalf("failed to save prune run: %v", err)
+			}
+		}
+
+		runs, err := repo.ListLatestPruneRuns(ctx)
+		if err != nil {
+			t.Fatalf("failed to list prune runs: %v", err)
+		}
+		if len(runs) != 1 {
+			t.Fatalf("expected 1 latest run, got %d", len(runs))
+		}
+		if runs[0].Deleted != 7 {
+			t.Errorf("expected latest run to have deleted 7, got %d", runs[0].Deleted)
+		}
+
+		invalid := &PruneRun{Target: PruneTargetNotifications, Status: "unknown"}
+		if err := repo.SavePruneRun(ctx, invalid); err == nil {
+			t.Error("expected validation error for invalid status")
+		}
+
+		stats, err := repo.GetTableStats(ctx)
+		if err != nil {
+			t.Fatalf("failed to get table stats: %v", err)
+		}
+		rows := make(map[string]int64)
+		for _, stat := range stats {
+			rows[stat.Table] = stat.Rows
+		}
+		if rows["notifications"] != 1 {
+			t.Errorf("expected 1 notification row, got %d", rows["notifications"])
+		}
+		if rows["gift_code_task"] != 2 {
+			t.Errorf("expected 2 task rows, got %d", rows["gift_code_task"])
+		}
+		if rows["prune_runs"] != 2 {
+			t.Errorf("expected 2 prune run rows, got %d", rows["prune_runs"])
+		}
+	})
+}
+
+func TestSqliteRepository_Trash(t *testing.T) {
+	tmpFile := "./test_trash.db"
+	defer os.Remove(tmpFile)
+
+	config := DefaultSqliteConfig()
+	config.Path = tmpFile
+
+	logger := logrus.New()
+	logger.SetLevel(logrus.ErrorLevel)
+
+	repo, err := NewSqliteRepository(config, logger)
+	if err != nil {
+		t.Fatalf("failed to create repository: %v", err)
+	}
+	defer repo.Close()
+
+	ctx := context.Background()
+
+	t.Run("restore task", func(t *testing.T) {
+		code := "TRASH_RESTORE"
+		if _, err := repo.CreateTask(ctx, code); err != nil {
+			t.Fatalf("failed to create task: %v", err)
+		}
+		if err := repo.DeleteTask(ctx, code); err != nil {
+			t.Fatalf("failed to delete task: %v", err)
+		}
+
+		pending, err := repo.ListPendingTasks(ctx)
+		if err != nil {
+			t.Fatalf("failed to list pending tasks: %v", err)
+		}
+		for _, task := range pending {
+			if task.Code == code {
+				t.Error("expected deleted task to be excluded from pending tasks")
+			}
+		}
+
+		deleted, err := repo.ListDeletedTasks(ctx)
+		if err != nil {
+			t.Fatalf("failed to list deleted tasks: %v", err)
+		}
+		if len(deleted) != 1 || deleted[0].Code != code || deleted[0].DeletedAt == nil {
+			t.Fatalf("expected task %s in trash, got %+v", code, deleted)
+		}
+
+		if err := repo.RestoreTask(ctx, code); err != nil {
+			t.Fatalf("failed to restore task: %v", err)
+		}
+		if _, err := repo.GetTaskByCode(ctx, code); err != nil {
+			t.Errorf("expected restored task to be visible: %v", err)
+		}
+		if err := repo.RestoreTask(ctx, code); err != ErrTaskNotFound {
+			t.Errorf("expected ErrTaskNotFound when restoring active task, got %v", err)
+		}
+	})
+
+	t.Run("delete and restore user", func(t *testing.T) {
+		user := &User{FID: "trash_user", Nickname: "Trash", KID: 1}
+		if err := repo.SaveUser(ctx, user); err != nil {
+			t.Fatalf("failed to save user: %v", err)
+		}
+		if err := repo.DeleteUser(ctx, user.FID); err != nil {
+			t.Fatalf("failed to delete user: %v", err)
+		}
+		if err := repo.DeleteUser(ctx, user.FID); err != ErrUserNotFound {
+			t.Errorf("expected ErrUserNotFound on second delete, got %v", err)
+		}
+
+		fids, err := repo.GetFids()
+		if err != nil {
+			t.Fatalf("failed to get fids: %v", err)
+		}
+		for _, fid := range fids {
+			if fid == user.FID {
+				t.Error("expected deleted user to be excluded from fids")
+			}
+		}
+
+		deleted, err := repo.ListDeletedUsers(ctx)
+		if err != nil {
+			t.Fatalf("failed to list deleted users: %v", err)
+		}
+		if len(deleted) != 1 || deleted[0].FID != user.FID {
+			t.Fatalf("expected user %s in trash, got %+v", user.FID, deleted)
+		}
+
+		// 回收站中的用户不会被保存覆盖
+		if err := repo.SaveUser(ctx, &User{FID: user.FID, Nickname: "Renamed", KID: 2}); err != ErrUserInTrash {
+			t.Errorf("expected ErrUserInTrash when saving trashed user, got %v", err)
+		}
+		deleted, err = repo.ListDeletedUsers(ctx)
+		if err != nil {
+			t.Fatalf("failed to list deleted users: %v", err)
+		}
+		if deleted[0].Nickname != "Trash" || deleted[0].KID != 1 {
+			t.Errorf("expected trashed user to be unchanged, got %+v", deleted[0])
+		}
+
+		if err := repo.RestoreUser(ctx, user.FID); err != nil {
+			t.Fatalf("failed to restore user: %v", err)
+		}
+		if _, err := repo.GetUser(ctx, user.FID); err != nil {
+			t.Errorf("expected restored user to be visible: %v", err)
+		}
+	})
+
+	t.Run("purge only affects trash", func(t *testing.T) {
+		code := "TRASH_PURGE"
+		if _, err := repo.CreateTask(ctx, code); err != nil {
+			t.Fatalf("failed to create task: %v", err)
+		}
+		if err := repo.SaveGiftCode(ctx, "user1", code); err != nil {
+			t.Fatalf("failed to save gift code: %v", err)
+		}
+
+		if err := repo.PurgeTask(ctx, code); err != ErrTaskNotFound {
+			t.Errorf("expected ErrTaskNotFound when purging active task, got %v", err)
+		}
+
+		if err := repo.DeleteTask(ctx, code); err != nil {
+			t.Fatalf("failed to delete task: %v", err)
+		}
+		if err := repo.PurgeTask(ctx, code); err != nil {
+			t.Fatalf("failed to purge task: %v", err)
+		}
+
+		received, err := repo.IsGiftCodeReceived(ctx, "user1", code)
+		if err != nil {
+			t.Fatalf("failed to check gift code: %v", err)
+		}
+		if received {
+			t.Error("expected gift codes to be deleted with purged task")
+		}
+	})
+
+	t.Run("purge expired trash", func(t *testing.T) {
+		if _, err := repo.CreateTask(ctx, "TRASH_EXPIRED"); err != nil {
+			t.Fatalf("failed to create task: %v", err)
+		}
+		if err := repo.DeleteTask(ctx, "TRASH_EXPIRED"); err != nil {
+			t.Fatalf("failed to delete task: %v", err)
+		}
+		user := &User{FID: "expired_user", Nickname: "Expired", KID: 1}
+		if err := repo.SaveUser(ctx, user); err != nil {
+			t.Fatalf("failed to save user: %v", err)
+		}
+		if err := repo.SaveGiftCode(ctx, user.FID, "TRASH_RESTORE"); err != nil {
+			t.Fatalf("failed to save gift code: %v", err)
+		}
+		if err := repo.DeleteUser(ctx, user.FID); err != nil {
+			t.Fatalf("failed to delete user: %v", err)
+		}
+
+		// 截止时间早于删除时间，不应清除
+		deleted, err := repo.PurgeDeletedTasks(ctx, time.Now().Add(-time.Hour), 10)
+		if err != nil {
+			t.Fatalf("failed to purge deleted tasks: %v", err)
+		}
+		if deleted != 0 {
+			t.Errorf("expected no tasks purged before grace period, got %d", deleted)
+		}
+
+		deleted, err = repo.PurgeDeletedTasks(ctx, time.Now().Add(time.Hour), 10)
+		if err != nil {
+			t.Fatalf("failed to purge deleted tasks: %v", err)
+		}
+		if deleted != 1 {
+			t.Errorf("expected 1 task purged, got %d", deleted)
+		}
+
+		deleted, err = repo.PurgeDeletedUsers(ctx, time.Now().Add(time.Hour), 10)
+		if err != nil {
+			t.Fatalf("failed to purge deleted users: %v", err)
+		}
+		if deleted != 1 {
+			t.Errorf("expected 1 user purged, got %d", deleted)
+		}
+
+		records, err := repo.ListGiftCodesByFID(ctx, user.FID)
+		if err != nil {
+			t.Fatalf("failed to list gift codes: %v", err)
+		}
+		if len(records) != 0 {
+			t.Errorf("expected gift codes of purged user to be deleted, got %d", len(records))
+		}
+	})
+}
+
+func TestSqliteRepository_UserGroups(t *testing.T) {
+	tmpFile := "./test_user_groups.db"
+	defer os.Remove(tmpFile)
+
+	config := DefaultSqliteConfig()
+	config.Path = tmpFile
+
+	logger := logrus.New()
+	logger.SetLevel(logrus.ErrorLevel)
+
+	repo, err := NewSqliteRepository(config, logger)
+	if err != nil {
+		t.Fatalf("failed to create repository: %v", err)
+	}
+	defer repo.Close()
+
+	ctx := context.Background()
+
+	for _, fid := range []string{"1001", "1002", "1003"} {
+		if err := repo.SaveUser(ctx, &User{FID: fid, Nickname: "user" + fid, KID: 1}); err != nil {
+			t.Fatalf("failed to save user: %v", err)
+		}
+	}
+
+	alliance := &UserGroup{Name: "alliance", Description: "main alliance"}
+	if err := repo.CreateUserGroup(ctx, alliance); err != nil {
+		t.Fatalf("failed to create group: %v", err)
+	}
+	alts := &UserGroup{Name: "alts"}
+	if err := repo.CreateUserGroup(ctx, alts); err != nil {
+		t.Fatalf("failed to create group: %v", err)
+	}
+
+	t.Run("group name validation", func(t *testing.T) {
+		if err := repo.CreateUserGroup(ctx, &UserGroup{Name: "alliance"}); err != ErrGroupNameTaken {
+			t.Errorf("expected ErrGroupNameTaken, got %v", err)
+		}
+		if err := repo.CreateUserGroup(ctx, &UserGroup{Name: "a,b"}); err == nil {
+			t.Error("expected validation error for name containing comma")
+		}
+		if err := repo.UpdateUserGroup(ctx, &UserGroup{ID: alts.ID, Name: "alliance"}); err != ErrGroupNameTaken {
+			t.Errorf("expected ErrGroupNameTaken on rename, got %v", err)
+		}
+	})
+
+	t.Run("assign groups and disable users", func(t *testing.T) {
+		if err := repo.SetUserGroups(ctx, "1001", []int64{alliance.ID, alts.ID}); err != nil {
+			t.Fatalf("failed to set user groups: %v", err)
+		}
+		if err := repo.SetUserGroups(ctx, "1002", []int64{alliance.ID}); err != nil {
+			t.Fatalf("failed to set user groups: %v", err)
+		}
+		if err := repo.SetUserGroups(ctx, "1003", []int64{999}); err != ErrGroupNotFound {
+			t.Errorf("expected ErrGroupNotFound, got %v", err)
+		}
+
+		user, err := repo.GetUser(ctx, "1001")
+		if err != nil {
+			t.Fatalf("failed to get user: %v", err)
+		}
+		if len(user.Groups) != 2 {
+			t.Errorf("expected 2 groups, got %v", user.Groups)
+		}
+
+		if err := repo.SetUserDisabled(ctx, "1002", true); err != nil {
+			t.Fatalf("failed to disable user: %v", err)
+		}
+
+		fids, err := repo.ListGroupFids(ctx, alliance.ID)
+		if err != nil {
+			t.Fatalf("failed to list group fids: %v", err)
+		}
+		if len(fids) != 1 || fids[0] != "1001" {
+			t.Errorf("expected only enabled member 1001, got %v", fids)
+		}
+
+		all, err := repo.GetFids()
+		if err != nil {
+			t.Fatalf("failed to get fids: %v", err)
+		}
+		if len(all) != 2 {
+			t.Errorf("expected disabled user to be excluded from fids, got %v", all)
+		}
+
+		// 禁用的用户仍出现在用户列表中
+		users, err := repo.ListUsers(ctx)
+		if err != nil {
+			t.Fatalf("failed to list users: %v", err)
+		}
+		if len(users) != 3 {
+			t.Errorf("expected 3 users in list, got %d", len(users))
+		}
+	})
+
+	t.Run("update user profile", func(t *testing.T) {
+		if err := repo.UpdateUser(ctx, &User{FID: "1003", Nickname: "renamed", KID: 7}); err != nil {
+			t.Fatalf("failed to update user: %v", err)
+		}
+		user, err := repo.GetUser(ctx, "1003")
+		if err != nil {
+			t.Fatalf("failed to get user: %v", err)
+		}
+		if user.Nickname != "renamed" || user.KID != 7 {
+			t.Errorf("expected updated profile, got %+v", user)
+		}
+		if err := repo.UpdateUser(ctx, &User{FID: "missing"}); err != ErrUserNotFound {
+			t.Errorf("expected ErrUserNotFound, got %v", err)
+		}
+	})
+
+	t.Run("task target group", func(t *testing.T) {
+		if _, err := repo.CreateTask(ctx, "GROUP_CODE"); err != nil {
+			t.Fatalf("failed to create task: %v", err)
+		}
+		if err := repo.SetTaskTargetGroup(ctx, "GROUP_CODE", &alliance.ID); err != nil {
+			t.Fatalf("failed to set task target group: %v", err)
+		}
+		task, err := repo.GetTaskByCode(ctx, "GROUP_CODE")
+		if err != nil {
+			t.Fatalf("failed to get task: %v", err)
+		}
+		if task.TargetGroupID == nil || *task.TargetGroupID != alliance.ID || task.TargetGroup != "alliance" {
+			t.Errorf("expected task to target alliance, got %+v", task)
+		}
+
+		if err := repo.DeleteUserGroup(ctx, alliance.ID); err != ErrGroupInUse {
+			t.Errorf("expected ErrGroupInUse, got %v", err)
+		}
+
+		if err := repo.UpdateTaskComplete(ctx, "GROUP_CODE", time.Now()); err != nil {
+			t.Fatalf("failed to complete task: %v", err)
+		}
+		if err := repo.DeleteUserGroup(ctx, alliance.ID); err != nil {
+			t.Fatalf("failed to delete group: %v", err)
+		}
+
+		groups, err := repo.ListUserGroups(ctx)
+		if err != nil {
+			t.Fatalf("failed to list groups: %v", err)
+		}
+		if len(groups) != 1 || groups[0].Name != "alts" || groups[0].MemberCount != 1 {
+			t.Errorf("expected only alts group with 1 member, got %+v", groups)
+		}
+
+		group, err := repo.GetUserGroup(ctx, alts.ID)
+		if err != nil {
+			t.Fatalf("failed to get group: %v", err)
+		}
+		if group.Name != "alts" || group.MemberCount != 1 {
+			t.Errorf("expected alts group with 1 member, got %+v", group)
+		}
+		if _, err := repo.GetUserGroup(ctx, alliance.ID); err != ErrGroupNotFound {
+			t.Errorf("expected ErrGroupNotFound for deleted group, got %v", err)
+		}
+	})
+}
+
+func TestSqliteRepository_ProfileHistory(t *testing.T) {
+	tmpFile := "./test_profile_history.db"
+	defer os.Remove(tmpFile)
+
+	config := DefaultSqliteConfig()
+	config.Path = tmpFile
+
+	logger := logrus.New()
+	logger.SetLevel(logrus.ErrorLevel)
+
+	repo, err := NewSqliteRepository(config, logger)
+	if err != nil {
+		t.Fatalf("failed to create repository: %v", err)
+	}
+	defer repo.Close()
+
+	ctx := context.Background()
+
+	// 占位资料补全不算变更
+	if err := repo.SaveUser(ctx, &User{FID: "2001", KID: -1}); err != nil {
+		t.Fatalf("failed to save user: %v", err)
+	}
+	if err := repo.SaveUser(ctx, &User{FID: "2001", Nickname: "alice", KID: 100, AvatarImage: "a.png"}); err != nil {
+		t.Fatalf("failed to save user: %v", err)
+	}
+	history, err := repo.ListUserProfileHistory(ctx, "2001")
+	if err != nil {
+		t.Fatalf("failed to list profile history: %v", err)
+	}
+	if len(history) != 0 {
+		t.Fatalf("expected no history after filling placeholder profile, got %d", len(history))
+	}
+
+	// 改名并转区
+	if err := repo.SaveUser(ctx, &User{FID: "2001", Nickname: "alice2", KID: 200, AvatarImage: "a.png"}); err != nil {
+		t.Fatalf("failed to save user: %v", err)
+	}
+	// 通过 UpdateUser 修改头像
+	if err := repo.UpdateUser(ctx, &User{FID: "2001", Nickname: "alice2", KID: 200, AvatarImage: "b.png"}); err != nil {
+		t.Fatalf("failed to update user: %v", err)
+	}
+
+	history, err = repo.ListUserProfileHistory(ctx, "2001")
+	if err != nil {
+		t.Fatalf("failed to list profile history: %v", err)
+	}
+	if len(history) != 3 {
+		t.Fatalf("expected 3 history entries, got %d", len(history))
+	}
+	if history[0].Field != ProfileFieldAvatar || history[0].OldValue != "a.png" || history[0].NewValue != "b.png" {
+		t.Errorf("unexpected latest change: %+v", history[0])
+	}
+
+	changes := make(map[string]*ProfileChange)
+	for _, change := range history {
+		changes[change.Field] = change
+	}
+	if c := changes[ProfileFieldNickname]; c == nil || c.OldValue != "alice" || c.NewValue != "alice2" {
+		t.Errorf("unexpected nickname change: %+v", c)
+	}
+	if c := changes[ProfileFieldKID]; c == nil || c.OldValue != "100" || c.NewValue != "200" {
+		t.Errorf("unexpected kid change: %+v", c)
+	}
+
+	// 资料未变化时不记录
+	if err := repo.SaveUser(ctx, &User{FID: "2001", Nickname: "alice2", KID: 200, AvatarImage: "b.png"}); err != nil {
+		t.Fatalf("failed to save user: %v", err)
+	}
+	history, _ = repo.ListUserProfileHistory(ctx, "2001")
+	if len(history) != 3 {
+		t.Errorf("expected history unchanged, got %d entries", len(history))
+	}
+
+	// 彻底删除用户时一并删除变更记录
+	if err := repo.DeleteUser(ctx, "2001"); err != nil {
+		t.Fatalf("failed to delete user: %v", err)
+	}
+	if err := repo.PurgeUser(ctx, "2001"); err != nil {
+		t.Fatalf("failed to purge user: %v", err)
+	}
+	history, _ = repo.ListUserProfileHistory(ctx, "2001")
+	if len(history) != 0 {
+		t.Errorf("expected history purged, got %d entries", len(history))
+	}
+}
+
+func TestSqliteRepository_RecordLoginIP(t *testing.T) {
+	tmpFile := "./test_login_ip.db"
+	defer os.Remove(tmpFile)
+
+	config := DefaultSqliteConfig()
+	config.Path = tmpFile
+
+	logger := logrus.New()
+	logger.SetLevel(logrus.ErrorLevel)
+
+	repo, err := NewSqliteRepository(config, logger)
+	if err != nil {
+		t.Fatalf("failed to create repository: %v", err)
+	}
+	defer repo.Close()
+
+	ctx := context.Background()
+
+	steps := []struct {
+		username string
+		ip       string
+		want     bool
+	}{
+		{"admin", "10.0.0.1", false}, // 首次登录不算新IP
+		{"admin", "10.0.0.1", false},
+		{"admin", "10.0.0.2", true},
+		{"admin", "10.0.0.2", false},
+		{"other", "10.0.0.3", false},
+	}
+	for i, step := range steps {
+		got, err := repo.RecordLoginIP(ctx, step.username, step.ip)
+		if err != nil {
+			t.Fatalf("step %d: RecordLoginIP failed: %v", i, err)
+		}
+		if got != step.want {
+			t.Errorf("step %d: RecordLoginIP(%s, %s) = %v, want %v", i, step.username, step.ip, got, step.want)
+		}
+	}
+}
+
+func TestSqliteRepository_NotificationOutbox(t *testing.T) {
+	tmpFile := "./test_notification_outbox.db"
+	defer os.Remove(tmpFile)
+
+	config := DefaultSqliteConfig()
+	config.Path = tmpFile
+
+	logger := logrus.New()
+	logger.SetLevel(logrus.ErrorLevel)
+
+	repo, err := NewSqliteRepository(config, logger)
+	if err != nil {
+		t.Fatalf("failed to create repository: %v", err)
+	}
+	defer repo.Close()
+
+	ctx := context.Background()
+	now := time.Now()
+
+	pending := &Notification{
+		Channel:   "wxpusher",
+		Event:     "task_completed",
+		Title:     "Queued",
+		Summary:   "summary",
+		Content:   "content",
+		Status:    NotificationStatusPending,
+		CreatedAt: now.Add(-time.Hour),
+	}
+	if err := repo.SaveNotification(ctx, pending); err != nil {
+		t.Fatalf("failed to save pending notification: %v", err)
+	}
+
+	due, err := repo.ListDueNotifications(ctx, now, 10)
+	if err != nil {
+		t.Fatalf("failed to list due notifications: %v", err)
+	}
+	if len(due) != 1 || due[0].ID != pending.ID || due[0].Summary != "summary" {
+		t.Fatalf("expected the pending notification to be due, got %+v", due)
+	}
+
+	// 投递失败后推迟到下次投递时间
+	next := now.Add(time.Minute)
+	due[0].Attempts = 1
+	due[0].Result = "Error: timeout"
+	due[0].NextAttemptAt = &next
+	if err := repo.UpdateNotificationDelivery(ctx, due[0]); err != nil {
+		t.Fatalf("failed to update delivery: %v", err)
+	}
+	if due, _ := repo.ListDueNotifications(ctx, now, 10); len(due) != 0 {
+		t.Errorf("expected no due notifications before next attempt, got %d", len(due))
+	}
+	if due, _ := repo.ListDueNotifications(ctx, next.Add(time.Second), 10); len(due) != 1 {
+		t.Errorf("expected notification due after next attempt time, got %d", len(due))
+	}
+
+	// pending 记录不会被清理
+	if deleted, err := repo.PruneNotifications(ctx, now, 10); err != nil || deleted != 0 {
+		t.Errorf("expected pending notification to survive prune, deleted=%d err=%v", deleted, err)
+	}
+
+	// 最终失败后可重新投递
+	got, err := repo.GetNotification(ctx, pending.ID)
+	if err != nil {
+		t.Fatalf("failed to get notification: %v", err)
+	}
+	got.Status = NotificationStatusFailed
+	got.Attempts = 5
+	got.NextAttemptAt = nil
+	if err := repo.UpdateNotificationDelivery(ctx, got); err != nil {
+		t.Fatalf("failed to update delivery: %v", err)
+	}
+	if err := repo.ResendNotification(ctx, pending.ID); err != nil {
+		t.Fatalf("failed to resend notification: %v", err)
+	}
+	got, _ = repo.GetNotification(ctx, pending.ID)
+	if got.Status != NotificationStatusPending || got.Attempts != 0 || got.NextAttemptAt == nil {
+		t.Errorf("expected notification reset to pending, got %+v", got)
+	}
+
+	if err := repo.ResendNotification(ctx, 9999); err != ErrNotificationNotFound {
+		t.Errorf("expected ErrNotificationNotFound, got %v", err)
+	}
+	if _, err := repo.GetNotification(ctx, 9999); err != ErrNotificationNotFound {
+		t.Errorf("expected ErrNotificationNotFound, got %v", err)
+	}
+}
+
+func TestSqliteRepository_UserNotificationTargets(t *testing.T) {
+	tmpFile := "./test_notification_targets.db"
+	defer os.Remove(tmpFile)
+
+	config := DefaultSqliteConfig()
+	config.Path = tmpFile
+
+	logger := logrus.New()
+	logger.SetLevel(logrus.ErrorLevel)
+
+	repo, err := NewSqliteRepository(config, logger)
+	if err != nil {
+		t.Fatalf("failed to create repository: %v", err)
+	}
+	defer repo.Close()
+
+	ctx := context.Background()
+	if err := repo.SaveUser(ctx, &User{FID: "1001"}); err != nil {
+		t.Fatalf("failed to save user: %v", err)
+	}
+
+	targets := []*NotificationTarget{
+		{Type: NotificationTargetWxPusher, Target: "UID_1001"},
+		{Type: NotificationTargetEmail, Target: " owner@example.com "},
+		{Type: NotificationTargetWxPusher, Target: "UID_1001"}, // 重复目标只保存一次
+	}
+	if err := repo.SetUserNotificationTargets(ctx, "1001", targets); err != nil {
+		t.Fatalf("failed to set targets: %v", err)
+	}
+	got, err := repo.ListUserNotificationTargets(ctx, "1001")
+	if err != nil {
+		t.Fatalf("failed to list targets: %v", err)
+	}
+	if len(got) != 2 || got[1].Target != "owner@example.com" {
+		t.Fatalf("unexpected targets: %+v", got)
+	}
+
+	invalid := [][]*NotificationTarget{
+		{{Type: "sms", Target: "123"}},
+		{{Type: NotificationTargetEmail, Target: "not-an-email"}},
+		{{Type: NotificationTargetWebhook, Target: "ftp://example.com"}},
+		{{Type: NotificationTargetTelegram, Target: " "}},
+	}
+	for i, targets := range invalid {
+		if err := repo.SetUserNotificationTargets(ctx, "1001", targets); err == nil {
+			t.Errorf("case %d: expected validation error", i)
+		}
+	}
+
+	if err := repo.SetUserNotificationTargets(ctx, "9999", nil); err != ErrUserNotFound {
+		t.Errorf("expected ErrUserNotFound, got %v", err)
+	}
+
+	// 覆盖为空即取消订阅
+	if err := repo.SetUserNotificationTargets(ctx, "1001", nil); err != nil {
+		t.Fatalf("failed to clear targets: %v", err)
+	}
+	if got, _ := repo.ListUserNotificationTargets(ctx, "1001"); len(got) != 0 {
+		t.Errorf("expected no targets, got %d", len(got))
+	}
+
+	// 个人通知记录用户和接收目标
+	notif := &Notification{
+		Channel:   "wxpusher",
+		Event:     "user_code_redeemed",
+		FID:       "1001",
+		Recipient: "UID_1001",
+		Title:     "personal",
+		Status:    NotificationStatusPending,
+	}
+	if err := repo.SaveNotification(ctx, notif); err != nil {
+		t.Fatalf("failed to save notification: %v", err)
+	}
+	saved, err := repo.GetNotification(ctx, notif.ID)
+	if err != nil {
+		t.Fatalf("failed to get notification: %v", err)
+	}
+	if saved.FID != "1001" || saved.Recipient != "UID_1001" {
+		t.Errorf("expected fid and recipient to be saved, got %+v", saved)
+	}
+}
+
+func TestSqliteRepository_NotificationBatch(t *testing.T) {
+	tmpFile := "./test_notification_batch.db"
+	defer os.Remove(tmpFile)
+
+	config := DefaultSqliteConfig()
+	config.Path = tmpFile
+
+	logger := logrus.New()
+	logger.SetLevel(logrus.ErrorLevel)
+
+	repo, err := NewSqliteRepository(config, logger)
+	if err != nil {
+		t.Fatalf("failed to create repository: %v", err)
+	}
+	defer repo.Close()
+
+	ctx := context.Background()
+	now := time.Now()
+
+	if deadline, err := repo.GetBatchDeadline(ctx, "wxpusher|", now); err != nil || deadline != nil {
+		t.Fatalf("expected no open batch, got %v err=%v", deadline, err)
+	}
+
+	deadline := now.Add(5 * time.Minute)
+	for i := 0; i < 3; i++ {
+		if err := repo.SaveNotification(ctx, &Notification{
+			Channel:       "wxpusher",
+			BatchKey:      "wxpusher|",
+			Title:         fmt.Sprintf("code %d", i),
+			Status:        NotificationStatusPending,
+			NextAttemptAt: &deadline,
+		}); err != nil {
+			t.Fatalf("failed to save notification: %v", err)
+		}
+	}
+	// 其他批次不受影响
+	if err := repo.SaveNotification(ctx, &Notification{
+		Channel:  "telegram",
+		BatchKey: "telegram|",
+		Status:   NotificationStatusPending,
+	}); err != nil {
+		t.Fatalf("failed to save notification: %v", err)
+	}
+
+	got, err := repo.GetBatchDeadline(ctx, "wxpusher|", now)
+	if err != nil || got == nil || !got.Equal(deadline) {
+		t.Fatalf("expected open batch deadline %v, got %v err=%v", deadline, got, err)
+	}
+
+	if batch, _ := repo.ListDueBatch(ctx, "wxpusher|", now); len(batch) != 0 {
+		t.Errorf("expected batch not due before deadline, got %d", len(batch))
+	}
+	batch, err := repo.ListDueBatch(ctx, "wxpusher|", deadline.Add(time.Second))
+	if err != nil {
+		t.Fatalf("failed to list batch: %v", err)
+	}
+	if len(batch) != 3 || batch[0].BatchKey != "wxpusher|" || batch[0].Title != "code 0" {
+		t.Fatalf("unexpected batch: %+v", batch)
+	}
+
+	// 到达投递时间后批次不再接收新通知
+	if got, _ := repo.GetBatchDeadline(ctx, "wxpusher|", deadline.Add(time.Second)); got != nil {
+		t.Errorf("expected closed batch, got deadline %v", got)
+	}
+}
+
+func TestSqliteRepository_Admins(t *testing.T) {
+	tmpFile := "./test_admins.db"
+	defer os.Remove(tmpFile)
+
+	config := DefaultSqliteConfig()
+	config.Path = tmpFile
+
+	logger := logrus.New()
+	logger.SetLevel(logrus.ErrorLevel)
+
+	repo, err := NewSqliteRepository(config, logger)
+	if err != nil {
+		t.Fatalf("failed to create repository: %v", err)
+	}
+	defer repo.Close()
+
+	ctx := context.Background()
+
+	// 仅在没有账号时创建第一个 owner
+	created, err := repo.BootstrapAdmin(ctx, &Admin{Username: "admin", PasswordHash: "hash", Role: AdminRoleOwner})
+	if err != nil || !created {
+		t.Fatalf("expected bootstrap admin to be created, got %v, %v", created, err)
+	}
+	created, err = repo.BootstrapAdmin(ctx, &Admin{Username: "other", PasswordHash: "hash", Role: AdminRoleOwner})
+	if err != nil || created {
+		t.Fatalf("expected second bootstrap to be skipped, got %v, %v", created, err)
+	}
+
+	if err := repo.CreateAdmin(ctx, &Admin{Username: "ops", PasswordHash: "hash", Role: AdminRoleOperator}); err != nil {
+		t.Fatalf("failed to create admin: %v", err)
+	}
+	if err := repo.CreateAdmin(ctx, &Admin{Username: "ops", PasswordHash: "hash", Role: AdminRoleViewer}); err != ErrAdminNameTaken {
+		t.Fatalf("expected ErrAdminNameTaken, got %v", err)
+	}
+	if err := repo.CreateAdmin(ctx, &Admin{Username: "root", PasswordHash: "hash", Role: "root"}); err == nil {
+		t.Fatal("expected invalid role to be rejected")
+	}
+
+	admins, err := repo.ListAdmins(ctx)
+	if err != nil {
+		t.Fatalf("failed to list admins: %v", err)
+	}
+	if len(admins) != 2 || admins[0].Username != "admin" || admins[1].Role != AdminRoleOperator {
+		t.Fatalf("unexpected admins: %+v", admins)
+	}
+
+	// 最后一个 owner 不能被降级或删除
+	if err := repo.UpdateAdminRole(ctx, "admin", AdminRoleViewer); err != ErrLastOwner {
+		t.Fatalf("expected ErrLastOwner on demote, got %v", err)
+	}
+	if err := repo.DeleteAdmin(ctx, "admin"); err != ErrLastOwner {
+		t.Fatalf("expected ErrLastOwner on delete, got %v", err)
+	}
+
+	if err := repo.UpdateAdminRole(ctx, "ops", AdminRoleOwner); err != nil {
+		t.Fatalf("failed to promote admin: %v", err)
+	}
+	if err := repo.UpdateAdminRole(ctx, "admin", AdminRoleViewer); err != nil {
+		t.Fatalf("failed to demote admin with another owner: %v", err)
+	}
+	if err := repo.UpdateAdminPassword(ctx, "admin", "new-hash"); err != nil {
+		t.Fatalf("failed to update password: %v", err)
+	}
+	admin, err := repo.GetAdmin(ctx, "admin")
+	if err != nil {
+		t.Fatalf("failed to get admin: %v", err)
+	}
+	if admin.Role != AdminRoleViewer || admin.PasswordHash != "new-hash" {
+		t.Fatalf("unexpected admin: %+v", admin)
+	}
+
+	if err := repo.DeleteAdmin(ctx, "admin"); err != nil {
+		t.Fatalf("failed to delete admin: %v", err)
+	}
+	if _, err := repo.GetAdmin(ctx, "admin"); err != ErrAdminNotFound {
+		t.Fatalf("expected ErrAdminNotFound, got %v", err)
+	}
+	if err := repo.DeleteAdmin(ctx, "admin"); err != ErrAdminNotFound {
+		t.Fatalf("expected ErrAdminNotFound on second delete, got %v", err)
+	}
+}
+
+func TestSqliteRepository_AdminSessions(t *testing.T) {
+	tmpFile := "./test_admin_sessions.db"
+	defer os.Remove(tmpFile)
+
+	config := DefaultSqliteConfig()
+	config.Path = tmpFile
+
+	logger := logrus.New()
+	logger.SetLevel(logrus.ErrorLevel)
+
+	repo, err := NewSqliteRepository(config, logger)
+	if err != nil {
+		t.Fatalf("failed to create repository: %v", err)
+	}
+	defer repo.Close()
+
+	ctx := context.Background()
+	now := time.Now()
+	newSession := func(id, hash string) *AdminSession {
+		return &AdminSession{
+			ID:               id,
+			Username:         "admin",
+			RefreshTokenHash: hash,
+			IP:               "10.0.0.1",
+			UserAgent:        "test",
+			CreatedAt:        now,
+			LastUsedAt:       now,
+			ExpiresAt:        now.Add(time.Hour),
+		}
+	}
+	for _, session := range []*AdminSession{newSession("s1", "h1"), newSession("s2", "h2"), newSession("s3", "h3")} {
+		if err := repo.CreateAdminSession(ctx, session); err != nil {
+			t.Fatalf("failed to create session: %v", err)
+		}
+	}
+
+	// 轮换后旧令牌失效，新令牌可用
+	later := now.Add(time.Minute)
+	rotated, err := repo.RotateAdminSession(ctx, "h1", &AdminSession{
+		RefreshTokenHash: "h1b", IP: "10.0.0.2", UserAgent: "test", LastUsedAt: later, ExpiresAt: later.Add(time.Hour),
+	})
+	if err != nil {
+		t.Fatalf("failed to rotate session: %v", err)
+	}
+	if rotated.ID != "s1" || rotated.IP != "10.0.0.2" {
+		t.Fatalf("unexpected rotated session: %+v", rotated)
+	}
+	if _, err := repo.RotateAdminSession(ctx, "unknown", &AdminSession{RefreshTokenHash: "x", LastUsedAt: later}); err != ErrSessionNotFound {
+		t.Fatalf("expected ErrSessionNotFound for unknown token, got %v", err)
+	}
+
+	// 再次使用已轮换的旧令牌会撤销整个会话
+	if _, err := repo.RotateAdminSession(ctx, "h1", &AdminSession{RefreshTokenHash: "h1c", LastUsedAt: later}); err != ErrRefreshTokenReused {
+		t.Fatalf("expected ErrRefreshTokenReused, got %v", err)
+	}
+	session, err := repo.GetAdminSession(ctx, "s1")
+	if err != nil {
+		t.Fatalf("failed to get session: %v", err)
+	}
+	if session.Active(later) {
+		t.Fatal("expected reused session to be revoked")
+	}
+	if _, err := repo.RotateAdminSession(ctx, "h1b", &AdminSession{RefreshTokenHash: "h1d", LastUsedAt: later}); err != ErrSessionNotFound {
+		t.Fatalf("expected revoked session to reject refresh, got %v", err)
+	}
+
+	if err := repo.RevokeAdminSession(ctx, "admin", "s2"); err != nil {
+		t.Fatalf("failed to revoke session: %v", err)
+	}
+	if err := repo.RevokeAdminSession(ctx, "admin", "s2"); err != ErrSessionNotFound {
+		t.Fatalf("expected ErrSessionNotFound on second revoke, got %v", err)
+	}
+
+	sessions, err := repo.ListAdminSessions(ctx, "admin", later)
+	if err != nil {
+		t.Fatalf("failed to list sessions: %v", err)
+	}
+	if len(sessions) != 1 || sessions[0].ID != "s3" {
+		t.Fatalf("unexpected active sessions: %+v", sessions)
+	}
+
+	revoked, err := repo.RevokeAdminSessions(ctx, "admin", "")
+	if err != nil || revoked != 1 {
+		t.Fatalf("expected 1 session revoked, got %d, %v", revoked, err)
+	}
+}
+
+func TestSqliteRepository_AdminTOTP(t *testing.T) {
+	tmpFile := "./test_admin_totp.db"
+	defer os.Remove(tmpFile)
+
+	config := DefaultSqliteConfig()
+	config.Path = tmpFile
+
+	logger := logrus.New()
+	logger.SetLevel(logrus.ErrorLevel)
+
+	repo, err := NewSqliteRepository(config, logger)
+	if err != nil {
+		t.Fatalf("failed to create repository: %v", err)
+	}
+	defer repo.Close()
+
+	ctx := context.Background()
+	if err := repo.CreateAdmin(ctx, &Admin{Username: "admin", PasswordHash: "hash", Role: AdminRoleOwner}); err != nil {
+		t.Fatalf("failed to create admin: %v", err)
+	}
+
+	// 待启用的密钥不会开启两步验证
+	if err := repo.SetAdminTOTPSecret(ctx, "admin", "SECRET"); err != nil {
+		t.Fatalf("failed to set totp secret: %v", err)
+	}
+	admin, err := repo.GetAdmin(ctx, "admin")
+	if err != nil {
+		t.Fatalf("failed to get admin: %v", err)
+	}
+	if admin.TOTPSecret != "SECRET" || admin.TOTPEnabled {
+		t.Fatalf("unexpected pending totp state: %+v", admin)
+	}
+
+	if err := repo.EnableAdminTOTP(ctx, "admin", 100, []string{"c1", "c2"}); err != nil {
+		t.Fatalf("failed to enable totp: %v", err)
+	}
+	if err := repo.SetAdminTOTPSecret(ctx, "admin", "OTHER"); err != ErrAdminNotFound {
+		t.Fatalf("expected enabled secret to be kept, got %v", err)
+	}
+
+	// 同一时间步和更早的时间步都被拒绝
+	for step, want := range map[int64]bool{100: false, 99: false} {
+		if ok, err := repo.UseAdminTOTPStep(ctx, "admin", step); err != nil || ok != want {
+			t.Fatalf("step %d: expected %v, got %v, %v", step, want, ok, err)
+		}
+	}
+	if ok, err := repo.UseAdminTOTPStep(ctx, "admin", 101); err != nil || !ok {
+		t.Fatalf("expected next step to be accepted, got %v, %v", ok, err)
+	}
+
+	// 恢复码只能使用一次
+	if ok, err := repo.UseAdminRecoveryCode(ctx, "admin", "c1"); err != nil || !ok {
+		t.Fatalf("expected recovery code to be accepted, got %v, %v", ok, err)
+	}
+	if ok, err := repo.UseAdminRecoveryCode(ctx, "admin", "c1"); err != nil || ok {
+		t.Fatalf("expected used recovery code to be rejected, got %v, %v", ok, err)
+	}
+	if count, err := repo.CountAdminRecoveryCodes(ctx, "admin"); err != nil || count != 1 {
+		t.Fatalf("expected 1 recovery code left, got %d, %v", count, err)
+	}
+	if err := repo.ReplaceAdminRecoveryCodes(ctx, "admin", []string{"c3", "c4", "c5"}); err != nil {
+		t.Fatalf("failed to replace recovery codes: %v", err)
+	}
+	if ok, _ := repo.UseAdminRecoveryCode(ctx, "admin", "c2"); ok {
+		t.Fatal("expected replaced recovery code to be rejected")
+	}
+
+	if err := repo.SetAdminTOTPRequired(ctx, "admin", true); err != nil {
+		t.Fatalf("failed to require totp: %v", err)
+	}
+	if err := repo.DisableAdminTOTP(ctx, "admin"); err != nil {
+		t.Fatalf("failed to disable totp: %v", err)
+	}
+	admin, err = repo.GetAdmin(ctx, "admin")
+	if err != nil {
+		t.Fatalf("failed to get admin: %v", err)
+	}
+	if admin.TOTPSecret != "" || admin.TOTPEnabled || !admin.TOTPRequired {
+		t.Fatalf("unexpected disabled totp state: %+v", admin)
+	}
+	if count, err := repo.CountAdminRecoveryCodes(ctx, "admin"); err != nil || count != 0 {
+		t.Fatalf("expected recovery codes to be removed, got %d, %v", count, err)
+	}
+}
+
+func TestSqliteRepository_LoginThrottles(t *testing.T) {
+	tmpFile := "./test_login_throttles.db"
+	defer os.Remove(tmpFile)
+
+	config := DefaultSqliteConfig()
+	config.Path = tmpFile
+
+	logger := logrus.New()
+	logger.SetLevel(logrus.ErrorLevel)
+
+	repo, err := NewSqliteRepository(config, logger)
+	if err != nil {
+		t.Fatalf("failed to create repository: %v", err)
+	}
+	defer repo.Close()
+
+	ctx := context.Background()
+	now := time.Now()
+
+	if _, err := repo.GetLoginThrottle(ctx, LoginThrottleKindIP, "10.0.0.1"); err != ErrLoginThrottleNotFound {
+		t.Fatalf("expected ErrLoginThrottleNotFound, got %v", err)
+	}
+
+	lockedUntil := now.Add(15 * time.Minute)
+	throttles := []*LoginThrottle{
+		{Kind: LoginThrottleKindIP, Identity: "10.0.0.1", Failures: 2, LastFailedAt: now.Add(-48 * time.Hour)},
+		{Kind: LoginThrottleKindUsername, Identity: "admin", Failures: 5, Lockouts: 1, LastFailedAt: now, LockedUntil: &lockedUntil},
+	}
+	for _, throttle := range throttles {
+		if err := repo.SaveLoginThrottle(ctx, throttle); err != nil {
+			t.Fatalf("failed to save throttle: %v", err)
+		}
+	}
+	if err := repo.SaveLoginThrottle(ctx, &LoginThrottle{Kind: "email", Identity: "x", LastFailedAt: now}); err == nil {
+		t.Fatal("expected unknown kind to be rejected")
+	}
+
+	// 再次保存覆盖已有计数
+	throttles[0].Failures = 3
+	if err := repo.SaveLoginThrottle(ctx, throttles[0]); err != nil {
+		t.Fatalf("failed to update throttle: %v", err)
+	}
+	got, err := repo.GetLoginThrottle(ctx, LoginThrottleKindIP, "10.0.0.1")
+	if err != nil {
+		t.Fatalf("failed to get throttle: %v", err)
+	}
+	if got.Failures != 3 || got.LockedUntil != nil {
+		t.Fatalf("unexpected throttle: %+v", got)
+	}
+
+	list, err := repo.ListLoginThrottles(ctx)
+	if err != nil {
+		t.Fatalf("failed to list throttles: %v", err)
+	}
+	if len(list) != 2 || list[0].Identity != "admin" || !list[0].Locked(now) || list[0].Locked(lockedUntil) {
+		t.Fatalf("unexpected throttle list: %+v", list)
+	}
+
+	for _, failure := range []*LoginFailure{
+		{Username: "admin", IP: "10.0.0.1", Reason: LoginFailureInvalidCredentials, CreatedAt: now.Add(-48 * time.Hour)},
+		{Username: "admin", IP: "10.0.0.2", UserAgent: "curl", Reason: LoginFailureBlocked},
+	} {
+		if err := repo.CreateLoginFailure(ctx, failure); err != nil {
+			t.Fatalf("failed to create login failure: %v", err)
+		}
+	}
+	failures, err := repo.ListLoginFailures(ctx, 10)
+	if err != nil {
+		t.Fatalf("failed to list login failures: %v", err)
+	}
+	if len(failures) != 2 || failures[0].Reason != LoginFailureBlocked || failures[0].UserAgent != "curl" {
+		t.Fatalf("unexpected login failures: %+v", failures)
+	}
+
+	// 清理旧的失败记录和未锁定的旧计数，锁定中的计数保留
+	deleted, err := repo.PruneLoginFailures(ctx, now.Add(-24*time.Hour), 100)
+	if err != nil {
+		t.Fatalf("failed to prune login failures: %v", err)
+	}
+	if deleted != 1 {
+		t.Fatalf("expected 1 login failure pruned, got %d", deleted)
+	}
+	if _, err := repo.GetLoginThrottle(ctx, LoginThrottleKindIP, "10.0.0.1"); err != ErrLoginThrottleNotFound {
+		t.Fatalf("expected stale throttle to be pruned, got %v", err)
+	}
+
+	if err := repo.DeleteLoginThrottle(ctx, LoginThrottleKindUsername, "admin"); err != nil {
+		t.Fatalf("failed to delete throttle: %v", err)
+	}
+	if err := repo.DeleteLoginThrottle(ctx, LoginThrottleKindUsername, "admin"); err != ErrLoginThrottleNotFound {
+		t.Fatalf("expected ErrLoginThrottleNotFound, got %v", err)
+	}
+}
+
+func TestSqliteRepository_APIKeys(t *testing.T) {
+	tmpFile := "./test_api_keys.db"
+	defer os.Remove(tmpFile)
+
+	config := DefaultSqliteConfig()
+	config.Path = tmpFile
+
+	logger := logrus.New()
+	logger.SetLevel(logrus.ErrorLevel)
+
+	repo, err := NewSqliteRepository(config, logger)
+	if err != nil {
+		t.Fatalf("failed to create repository: %v", err)
+	}
+	defer repo.Close()
+
+	ctx := context.Background()
+
+	if err := repo.CreateAPIKey(ctx, &APIKey{Name: "bot", KeyHash: "h0", Scopes: []string{"delete-all"}}); err == nil {
+		t.Fatal("expected unknown scope to be rejected")
+	}
+	if err := repo.CreateAPIKey(ctx, &APIKey{Name: "bot", KeyHash: "h0"}); err == nil {
+		t.Fatal("expected empty scopes to be rejected")
+	}
+
+	key := &APIKey{
+		Name:      "discord-bot",
+		Prefix:    "cdk_abcd",
+		KeyHash:   "hash-1",
+		Scopes:    []string{APIKeyScopeEnqueueCode, APIKeyScopeReadOnly},
+		CreatedBy: "boss",
+	}
+	if err := repo.CreateAPIKey(ctx, key); err != nil {
+		t.Fatalf("failed to create api key: %v", err)
+	}
+	if key.ID == 0 {
+		t.Fatal("expected api key id to be set")
+	}
+
+	got, err := repo.GetAPIKeyByHash(ctx, "hash-1")
+	if err != nil {
+		t.Fatalf("failed to get api key: %v", err)
+	}
+	if got.Name != "discord-bot" || !got.HasScope(APIKeyScopeEnqueueCode) || got.HasScope(APIKeyScopeAddUser) {
+		t.Fatalf("unexpected api key: %+v", got)
+	}
+	if got.LastUsedAt != nil || got.UsageCount != 0 {
+		t.Fatalf("expected unused api key, got %+v", got)
+	}
+
+	usedAt := time.Now()
+	for _, count := range []int64{1, 2} {
+		if err := repo.RecordAPIKeyUsage(ctx, key.ID, count, usedAt, "10.0.0.1"); err != nil {
+			t.Fatalf("failed to record usage: %v", err)
+		}
+	}
+	got, err = repo.GetAPIKeyByHash(ctx, "hash-1")
+	if err != nil {
+		t.Fatalf("failed to get api key: %v", err)
+	}
+	if got.UsageCount != 3 || got.LastUsedIP != "10.0.0.1" || got.LastUsedAt == nil {
+		t.Fatalf("unexpected usage: %+v", got)
+	}
+
+	if err := repo.RevokeAPIKey(ctx, key.ID); err != nil {
+		t.Fatalf("failed to revoke api key: %v", err)
+	}
+	if err := repo.RevokeAPIKey(ctx, key.ID); err != ErrAPIKeyNotFound {
+		t.Fatalf("expected ErrAPIKeyNotFound for revoked key, got %v", err)
+	}
+	if _, err := repo.GetAPIKeyByHash(ctx, "hash-1"); err != ErrAPIKeyNotFound {
+		t.Fatalf("expected revoked key to be rejected, got %v", err)
+	}
+
+	keys, err := repo.ListAPIKeys(ctx)
+	if err != nil {
+		t.Fatalf("failed to list api keys: %v", err)
+	}
+	if len(keys) != 1 || keys[0].RevokedAt == nil {
+		t.Fatalf("unexpected api key list: %+v", keys)
+	}
+}
+
+func TestSqliteRepository_AuditLog(t *testing.T) {
+	tmpFile := "./test_audit_log.db"
+	defer os.Remove(tmpFile)
+
+	config := DefaultSqliteConfig()
+	config.Path = tmpFile
+
+	logger := logrus.New()
+	logger.SetLevel(logrus.ErrorLevel)
+
+	repo, err := NewSqliteRepository(config, logger)
+	if err != nil {
+		t.Fatalf("failed to create repository: %v", err)
+	}
+	defer repo.Close()
+
+	ctx := context.Background()
+	now := time.Now()
+
+	if err := repo.CreateAuditEntry(ctx, &AuditEntry{Actor: "boss"}); err == nil {
+		t.Fatal("expected empty action to be rejected")
+	}
+
+	entries := []*AuditEntry{
+		{Actor: "boss", Action: "admin.login", Target: "boss", IP: "10.0.0.1", CreatedAt: now.Add(-48 * time.Hour)},
+		{Actor: "alice", Action: "task.create", Target: "VIP888", After: []byte(`{"code":"VIP888"}`), CreatedAt: now.Add(-time.Hour)},
+		{Actor: "boss", Action: "task.delete", Target: "VIP888", RequestID: "req-1", Before: []byte(`{"code":"VIP888"}`), CreatedAt: now},
+		{Actor: "boss", Action: "task_group.update", Target: "1", CreatedAt: now},
+	}
+	for _, entry := range entries {
+		if err := repo.CreateAuditEntry(ctx, entry); err != nil {
+			t.Fatalf("failed to create audit entry: %v", err)
+		}
+	}
+
+	all, err := repo.ListAuditEntries(ctx, AuditFilter{Limit: 10})
+	if err != nil {
+		t.Fatalf("failed to list audit entries: %v", err)
+	}
+	if len(all) != 4 || all[1].Action != "task.delete" || string(all[1].Before) != `{"code":"VIP888"}` || all[1].After != nil {
+		t.Fatalf("unexpected audit entries: %+v", all)
+	}
+
+	tests := []struct {
+		name   string
+		filter AuditFilter
+		want   int
+	}{
+		{"actor", AuditFilter{Actor: "boss", Limit: 10}, 3},
+		{"action", AuditFilter{Action: "task.delete", Limit: 10}, 1},
+		{"action prefix", AuditFilter{Action: "task.", Limit: 10}, 2},
+		{"target", AuditFilter{Target: "VIP888", Limit: 10}, 2},
+		{"since", AuditFilter{Since: now.Add(-2 * time.Hour), Limit: 10}, 3},
+		{"until", AuditFilter{Until: now.Add(-2 * time.Hour), Limit: 10}, 1},
+		{"limit", AuditFilter{Limit: 2}, 2},
+	}
+	for _, tt := range tests {
+		got, err := repo.ListAuditEntries(ctx, tt.filter)
+		if err != nil {
+			t.Fatalf("%s: failed to list audit entries: %v", tt.name, err)
+		}
+		if len(got) != tt.want {
+			t.Errorf("%s: got %d entries, want %d", tt.name, len(got), tt.want)
+		}
+	}
+
+	deleted, err := repo.PruneAuditLog(ctx, now.Add(-24*time.Hour), 100)
+	if err != nil {
+		t.Fatalf("failed to prune audit log: %v", err)
+	}
+	if deleted != 1 {
+		t.Fatalf("expected 1 pruned entry, got %d", deleted)
+	}
+}
+
+func TestSqliteRepository_QueryLists(t *testing.T) {
+	tmpFile := "./test_query_lists.db"
+	defer os.Remove(tmpFile)
+
+	config := DefaultSqliteConfig()
+	config.Path = tmpFile
+
+	logger := logrus.New()
+	logger.SetLevel(logrus.ErrorLevel)
+
+	repo, err := NewSqliteRepository(config, logger)
+	if err != nil {
+		t.Fatalf("failed to create repository: %v", err)
+	}
+	defer repo.Close()
+
+	ctx := context.Background()
+	now := time.Now()
+
+	// 用户：分页、搜索、筛选和排序
+	for i, nickname := range []string{"alpha", "bravo", "charlie", "delta", "echo"} {
+		user := &User{FID: fmt.Sprintf("%d", 100+i*10), Nickname: nickname, KID: 1 + i%2}
+		if err := repo.SaveUser(ctx, user); err != nil {
+			t.Fatalf("failed to save user: %v", err)
+		}
+	}
+	if err := repo.SetUserDisabled(ctx, "120", true); err != nil {
+		t.Fatalf("failed to disable user: %v", err)
+	}
+
+	var fids []string
+	cursor := ""
+	for pages := 0; ; pages++ {
+		page, err := repo.QueryUsers(ctx, UserFilter{ListOptions: ListOptions{Cursor: cursor, Limit: 2}})
+		if err != nil {
+			t.Fatalf("failed to query users: %v", err)
+		}
+		for _, user := range page.Items {
+			fids = append(fids, user.FID)
+		}
+		if page.NextCursor == "" {
+			if pages != 2 {
+				t.Fatalf("expected 3 pages, got %d", pages+1)
+			}
+			break
+		}
+		cursor = page.NextCursor
+	}
+	if strings.Join(fids, ",") != "140,130,120,110,100" {
+		t.Fatalf("unexpected user order: %v", fids)
+	}
+
+	kid := 2
+	disabled := true
+	userTests := []struct {
+		name   string
+		filter UserFilter
+		want   string
+	}{
+		{"search nickname", UserFilter{Search: "ha"}, "120,100"},
+		{"search fid", UserFilter{Search: "13"}, "130"},
+		{"kid", UserFilter{KID: &kid}, "130,110"},
+		{"disabled", UserFilter{Disabled: &disabled}, "120"},
+		{"sort nickname", UserFilter{ListOptions: ListOptions{Sort: UserSortNickname, Limit: 2}}, "100,110"},
+		{"sort nickname desc", UserFilter{ListOptions: ListOptions{Sort: UserSortNickname, Order: SortDesc, Limit: 2}}, "140,130"},
+	}
+	for _, tt := range userTests {
+		page, err := repo.QueryUsers(ctx, tt.filter)
+		if err != nil {
+			t.Fatalf("%s: failed to query users: %v", tt.name, err)
+		}
+		var got []string
+		for _, user := range page.Items {
+			got = append(got, user.FID)
+		}
+		if strings.Join(got, ",") != tt.want {
+			t.Errorf("%s: got %v, want %s", tt.name, got, tt.want)
+		}
+	}
+
+	// 任务：状态、搜索、时间范围以及按完成时间翻页
+	for i, code := range []string{"VIP100", "VIP200", "GIFT300", "GIFT400"} {
+		if _, err := repo.CreateTask(ctx, code); err != nil {
+			t.Fatalf("failed to create task: %v", err)
+		}
+		if i < 3 {
+			if err := repo.UpdateTaskComplete(ctx, code, now.Add(-time.Duration(i)*time.Hour)); err != nil {
+				t.Fatalf("failed to complete task: %v", err)
+			}
+		}
+	}
+
+	page, err := repo.QueryTasks(ctx, TaskFilter{Status: TaskStatusCompleted, ListOptions: ListOptions{Limit: 2}})
+	if err != nil {
+		t.Fatalf("failed to query tasks: %v", err)
+	}
+	if len(page.Items) != 2 || page.Items[0].Code != "VIP100" || page.Items[1].Code != "VIP200" || page.NextCursor == "" {
+		t.Fatalf("unexpected first page of completed tasks: %+v", page)
+	}
+	next, err := repo.QueryTasks(ctx, TaskFilter{Status: TaskStatusCompleted, ListOptions: ListOptions{Limit: 2, Cursor: page.NextCursor}})
+	if err != nil {
+		t.Fatalf("failed to query tasks: %v", err)
+	}
+	if len(next.Items) != 1 || next.Items[0].Code != "GIFT300" || next.NextCursor != "" {
+		t.Fatalf("unexpected second page of completed tasks: %+v", next)
+	}
+
+	taskTests := []struct {
+		name   string
+		filter TaskFilter
+		want   int
+	}{
+		{"all", TaskFilter{}, 4},
+		{"pending", TaskFilter{Status: TaskStatusPending}, 1},
+		{"search", TaskFilter{Search: "gift"}, 2},
+		{"completed since", TaskFilter{Status: TaskStatusCompleted, Since: now.Add(-90 * time.Minute)}, 2},
+		{"completed until", TaskFilter{Status: TaskStatusCompleted, Until: now.Add(-90 * time.Minute)}, 1},
+	}
+	for _, tt := range taskTests {
+		page, err := repo.QueryTasks(ctx, tt.filter)
+		if err != nil {
+			t.Fatalf("%s: failed to query tasks: %v", tt.name, err)
+		}
+		if len(page.Items) != tt.want {
+			t.Errorf("%s: got %d tasks, want %d", tt.name, len(page.Items), tt.want)
+		}
+	}
+
+	// 通知：渠道、状态和搜索
+	notifications := []*Notification{
+		{Channel: "wxpusher", Title: "VIP100 兑换完成", Content: "done", Status: NotificationStatusSuccess},
+		{Channel: "telegram", Title: "VIP100 兑换完成", Content: "done", Status: NotificationStatusFailed},
+		{Channel: "wxpusher", Title: "新兑换码", Content: "GIFT300", FID: "110", Status: NotificationStatusSuccess},
+	}
+	for _, notification := range notifications {
+		if err := repo.SaveNotification(ctx, notification); err != nil {
+			t.Fatalf("failed to save notification: %v", err)
+		}
+	}
+
+	notificationTests := []struct {
+		name   string
+		filter NotificationFilter
+		want   int
+	}{
+		{"all", NotificationFilter{}, 3},
+		{"channel", NotificationFilter{Channel: "wxpusher"}, 2},
+		{"status", NotificationFilter{Status: NotificationStatusFailed}, 1},
+		{"search title", NotificationFilter{Search: "VIP100"}, 2},
+		{"search content", NotificationFilter{Search: "GIFT"}, 1},
+		{"search fid", NotificationFilter{Search: "110"}, 1},
+		{"since", NotificationFilter{Since: now.Add(time.Hour)}, 0},
+	}
+	for _, tt := range notificationTests {
+		page, err := repo.QueryNotifications(ctx, tt.filter)
+		if err != nil {
+			t.Fatalf("%s: failed to query notifications: %v", tt.name, err)
+		}
+		if len(page.Items) != tt.want {
+			t.Errorf("%s: got %d notifications, want %d", tt.name, len(page.Items), tt.want)
+		}
+	}
+
+	// 无效的排序字段、方向和游标
+	if _, err := repo.QueryUsers(ctx, UserFilter{ListOptions: ListOptions{Sort: "avatar_image"}}); err == nil {
+		t.Error("expected unknown sort field to be rejected")
+	}
+	if _, err := repo.QueryUsers(ctx, UserFilter{ListOptions: ListOptions{Order: "up"}}); err == nil {
+		t.Error("expected invalid order to be rejected")
+	}
+	if _, err := repo.QueryTasks(ctx, TaskFilter{ListOptions: ListOptions{Cursor: "%%%"}}); err == nil {
+		t.Error("expected invalid cursor to be rejected")
+	}
+	if _, err := repo.QueryTasks(ctx, TaskFilter{Status: TaskStatusCompleted, ListOptions: ListOptions{Cursor: page.NextCursor}}); err != nil {
+		t.Errorf("expected cursor to be accepted with the same sort: %v", err)
+	}
+	if _, err := repo.QueryTasks(ctx, TaskFilter{ListOptions: ListOptions{Cursor: page.NextCursor}}); err == nil {
+		t.Error("expected cursor of another sort field to be rejected")
+	}
+	if _, err := repo.QueryTasks(ctx, TaskFilter{Status: "failed"}); err == nil {
+		t.Error("expected unknown status to be rejected")
+	}
+}
+
+func TestSqliteRepository_Statistics(t *testing.T) {
+	tmpFile := "./test_statistics.db"
+	defer os.Remove(tmpFile)
+
+	config := DefaultSqliteConfig()
+	config.Path = tmpFile
+
+	logger := logrus.New()
+	logger.SetLevel(logrus.ErrorLevel)
+
+	repo, err := NewSqliteRepository(config, logger)
+	if err != nil {
+		t.Fatalf("failed to create repository: %v", err)
+	}
+	defer repo.Close()
+
+	ctx := context.Background()
+
+	// 2026-03-02 为周一
+	monday := time.Date(2026, 3, 2, 23, 30, 0, 0, time.UTC)
+	tuesday := time.Date(2026, 3, 3, 10, 0, 0, 0, time.UTC)
+	nextMonday := time.Date(2026, 3, 9, 1, 0, 0, 0, time.UTC)
+	attempts := []*RedeemAttempt{
+		{Code: "A", FID: "1", Status: RedeemStatusSuccess, CreatedAt: monday},
+		{Code: "A", FID: "2", Status: RedeemStatusFailed, Message: "验证码错误", CreatedAt: monday},
+		{Code: "A", FID: "2", Status: RedeemStatusSuccess, CreatedAt: tuesday},
+		{Code: "B", FID: "2", Status: RedeemStatusReceived, CreatedAt: tuesday},
+		{Code: "C", FID: "1", Status: RedeemStatusFailed, Message: "验证码错误", CreatedAt: nextMonday},
+		{Code: "C", FID: "2", Status: RedeemStatusFailed, Message: "超时", CreatedAt: nextMonday},
+		{Code: "X", FID: "1", Status: RedeemStatusNotFound, CreatedAt: nextMonday},
+	}
+	for _, attempt := range attempts {
+		if err := repo.RecordRedeemAttempt(ctx, attempt); err != nil {
+			t.Fatalf("failed to record redeem attempt: %v", err)
+		}
+	}
+
+	t.Run("redemptions per day and week", func(t *testing.T) {
+		tests := []struct {
+			name     string
+			filter   StatsFilter
+			interval string
+			offset   time.Duration
+			want     []RedemptionBucket
+		}{
+			{"day", StatsFilter{}, StatsIntervalDay, 0, []RedemptionBucket{
+				{Period: "2026-03-02", Success: 1, Failed: 1},
+				{Period: "2026-03-03", Success: 1, Received: 1},
+				{Period: "2026-03-09", NotFound: 1, Failed: 2},
+			}},
+			{"day with offset", StatsFilter{}, StatsIntervalDay, time.Hour, []RedemptionBucket{
+				{Period: "2026-03-03", Success: 2, Received: 1, Failed: 1},
+				{Period: "2026-03-09", NotFound: 1, Failed: 2},
+			}},
+			{"week", StatsFilter{}, StatsIntervalWeek, 0, []RedemptionBucket{
+				{Period: "2026-03-02", Success: 2, Received: 1, Failed: 1},
+				{Period: "2026-03-09", NotFound: 1, Failed: 2},
+			}},
+			{"range", StatsFilter{Since: tuesday, Until: nextMonday}, StatsIntervalDay, 0, []RedemptionBucket{
+				{Period: "2026-03-03", Success: 1, Received: 1},
+			}},
+		}
+		for _, tt := range tests {
+			buckets, err := repo.CountRedemptions(ctx, tt.filter, tt.interval, tt.offset)
+			if err != nil {
+				t.Fatalf("%s: failed to count redemptions: %v", tt.name, err)
+			}
+			if len(buckets) != len(tt.want) {
+				t.Fatalf("%s: got %d buckets, want %d", tt.name, len(buckets), len(tt.want))
+			}
+			for i, bucket := range buckets {
+				if *bucket != tt.want[i] {
+					t.Errorf("%s: bucket %d = %+v, want %+v", tt.name, i, *bucket, tt.want[i])
+				}
+			}
+		}
+
+		if _, err := repo.CountRedemptions(ctx, StatsFilter{}, "month", 0); err == nil {
+			t.Error("expected unknown interval to be rejected")
+		}
+	})
+
+	t.Run("code stats and failure reasons", func(t *testing.T) {
+		stats, err := repo.ListCodeStats(ctx, StatsFilter{Limit: 10})
+		if err != nil {
+			t.Fatalf("failed to list code stats: %v", err)
+		}
+		if len(stats) != 4 || stats[0].Code != "X" || stats[3].Code != "A" {
+			t.Fatalf("unexpected code stats order: %+v", stats)
+		}
+		a := stats[3]
+		if a.Attempts != 3 || a.Success != 2 || a.Failed != 1 || a.FIDs != 2 || a.SuccessRate < 0.66 || a.SuccessRate > 0.67 {
+			t.Errorf("unexpected stats for code A: %+v", a)
+		}
+
+		reasons, err := repo.ListFailureReasons(ctx, StatsFilter{Limit: 10})
+		if err != nil {
+			t.Fatalf("failed to list failure reasons: %v", err)
+		}
+		if len(reasons) != 2 || *reasons[0] != (FailureReason{Reason: "验证码错误", Count: 2, Codes: 2, FIDs: 2}) {
+			t.Fatalf("unexpected failure reasons: %+v", reasons)
+		}
+
+		limited, err := repo.ListFailureReasons(ctx, StatsFilter{Since: tuesday, Limit: 1})
+		if err != nil {
+			t.Fatalf("failed to list failure reasons: %v", err)
+		}
+		if len(limited) != 1 {
+			t.Fatalf("expected 1 failure reason, got %d", len(limited))
+		}
+	})
+
+	t.Run("completion time", func(t *testing.T) {
+		for _, code := range []string{"A", "B", "C"} {
+			if _, err := repo.CreateTask(ctx, code); err != nil {
+				t.Fatalf("failed to create task: %v", err)
+			}
+		}
+		task, err := repo.GetTaskByCode(ctx, "A")
+		if err != nil {
+			t.Fatalf("failed to get task: %v", err)
+		}
+		if err := repo.UpdateTaskComplete(ctx, "A", task.CreatedAt.Add(time.Hour)); err != nil {
+			t.Fatalf("failed to complete task: %v", err)
+		}
+		if err := repo.UpdateTaskComplete(ctx, "B", task.CreatedAt.Add(3*time.Hour)); err != nil {
+			t.Fatalf("failed to complete task: %v", err)
+		}
+
+		stat, err := repo.GetCompletionStats(ctx, StatsFilter{})
+		if err != nil {
+			t.Fatalf("failed to get completion stats: %v", err)
+		}
+		if stat.Tasks != 2 || stat.AvgSeconds < 7195 || stat.AvgSeconds > 7205 || stat.MinSeconds < 3595 || stat.MaxSeconds > 10805 {
+			t.Errorf("unexpected completion stats: %+v", stat)
+		}
+
+		empty, err := repo.GetCompletionStats(ctx, StatsFilter{Until: task.CreatedAt.Add(-time.Hour)})
+		if err != nil {
+			t.Fatalf("failed to get completion stats: %v", err)
+		}
+		if *empty != (CompletionStat{}) {
+			t.Errorf("expected no completed tasks, got %+v", empty)
+		}
+	})
+
+	t.Run("fid coverage", func(t *testing.T) {
+		for _, fid := range []string{"1", "2", "3"} {
+			if err := repo.SaveUser(ctx, &User{FID: fid, Nickname: "user" + fid, KID: 1}); err != nil {
+				t.Fatalf("failed to save user: %v", err)
+			}
+		}
+		if err := repo.DeleteUser(ctx, "3"); err != nil {
+			t.Fatalf("failed to delete user: %v", err)
+		}
+		group := &UserGroup{Name: "alliance"}
+		if err := repo.CreateUserGroup(ctx, group); err != nil {
+			t.Fatalf("failed to create group: %v", err)
+		}
+		if err := repo.SetUserGroups(ctx, "2", []int64{group.ID}); err != nil {
+			t.Fatalf("failed to set user groups: %v", err)
+		}
+		// B 只发给分组内的用户 2
+		if err := repo.SetTaskTargetGroup(ctx, "B", &group.ID); err != nil {
+			t.Fatalf("failed to set task target group: %v", err)
+		}
+		for _, record := range [][2]string{{"1", "A"}, {"2", "A"}, {"2", "B"}} {
+			if err := repo.SaveGiftCode(ctx, record[0], record[1]); err != nil {
+				t.Fatalf("failed to save gift code: %v", err)
+			}
+		}
+
+		coverage, err := repo.ListFidCoverage(ctx, 10)
+		if err != nil {
+			t.Fatalf("failed to list fid coverage: %v", err)
+		}
+		if len(coverage) != 2 {
+			t.Fatalf("expected 2 users, got %d", len(coverage))
+		}
+		if coverage[0].FID != "1" || coverage[0].Received != 1 || coverage[0].Available != 2 || coverage[0].Coverage != 0.5 {
+			t.Errorf("unexpected coverage for user 1: %+v", coverage[0])
+		}
+		if coverage[1].FID != "2" || coverage[1].Received != 2 || coverage[1].Available != 3 {
+			t.Errorf("unexpected coverage for user 2: %+v", coverage[1])
+		}
+	})
+
+	t.Run("captcha usage", func(t *testing.T) {
+		calls := []*CaptchaCall{
+			{Provider: "ali", Success: true, Cost: 0.01},
+			{Provider: "ali", Success: true, Cost: 0.01},
+			{Provider: "ali", Success: false, Cost: 0.01},
+			{Provider: "google", Success: true},
+		}
+		for _, call := range calls {
+			if err := repo.RecordCaptchaCall(ctx, call); err != nil {
+				t.Fatalf("failed to record captcha call: %v", err)
+			}
+		}
+
+		usage, err := repo.ListCaptchaUsage(ctx, StatsFilter{Since: time.Now().Add(-time.Hour)})
+		if err != nil {
+			t.Fatalf("failed to list captcha usage: %v", err)
+		}
+		if len(usage) != 2 || usage[0].Provider != "ali" || usage[0].Calls != 3 || usage[0].Failures != 1 ||
+			usage[0].Cost < 0.0299 || usage[0].Cost > 0.0301 || usage[1].Calls != 1 || usage[1].Cost != 0 {
+			t.Fatalf("unexpected captcha usage: %+v %+v", usage[0], usage[len(usage)-1])
+		}
+	})
+
+	t.Run("prune", func(t *testing.T) {
+		deleted, err := repo.PruneRedeemAttempts(ctx, nextMonday, 100)
+		if err != nil {
+			t.Fatalf("failed to prune redeem attempts: %v", err)
+		}
+		if deleted != 4 {
+			t.Errorf("expected 4 pruned attempts, got %d", deleted)
+		}
+
+		deleted, err = repo.PruneCaptchaCalls(ctx, time.Now().Add(time.Hour), 3)
+		if err != nil {
+			t.Fatalf("failed to prune captcha calls: %v", err)
+		}
+		if deleted != 3 {
+			t.Errorf("expected 3 pruned captcha calls, got %d", deleted)
+		}
+	})
+}
+
+func TestQueryOperation(t *testing.T) {
+	tests := map[string]string{
+		"SELECT fid FROM fid_list":                               "select",
+		"\n\t\tinsert into gift_codes (fid, code) VALUES (?, ?)": "insert",
+		"UPDATE gift_code_task SET all_done = 1":                 "update",
+		"DELETE FROM notifications WHERE id = ?":                 "delete",
+		"WITH due AS (SELECT id FROM notifications) SELECT":      "select",
+		"PRAGMA wal_checkpoint(TRUNCATE)":                        "other",
+		"":                                                       "other",
+	}
+	for query, want := range tests {
+		if got := queryOperation(query); got != want {
+			t.Errorf("queryOperation(%q) = %q, want %q", query, got, want)
+		}
+	}
+}
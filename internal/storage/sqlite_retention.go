@@ -0,0 +1,317 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"cdk-get/internal/errors"
+)
+
+// statTables 参与行数统计的数据表
+var statTables = []string{
+	"fid_list",
+	"gift_codes",
+	"gift_code_task",
+	"notifications",
+	"prune_runs",
+	"user_groups",
+	"user_group_members",
+	"user_profile_history",
+	"user_notification_targets",
+	"admin_login_ips",
+	"admins",
+	"admin_sessions",
+	"admin_recovery_codes",
+	"login_throttles",
+	"admin_login_failures",
+	"api_keys",
+	"audit_log",
+	"redeem_attempts",
+	"captcha_calls",
+}
+
+// PruneNotifications 删除创建时间早于 before 的通知记录
+// 尚未投递的 pending 记录不会删除
+// 单次最多删除 limit 行，调用方需循环调用直到返回值小于 limit
+func (r *SqliteRepository) PruneNotifications(ctx context.Context, before time.Time, limit int) (int64, error) {
+	query := `DELETE FROM notifications WHERE id IN (
+	              SELECT id FROM notifications
+	              WHERE julianday(created_at) < julianday(?) AND status != 'pending'
+	              ORDER BY id ASC
+	              LIMIT ?)`
+	stmt, err := r.db.PrepareContext(ctx, query)
+	if err != nil {
+		return 0, errors.NewDatabaseError("prepare_prune_notifications", err)
+	}
+	defer stmt.Close()
+
+	result, err := stmt.ExecContext(ctx, before, limit)
+	if err != nil {
+		return 0, errors.NewDatabaseError("prune_notifications", err)
+	}
+
+	deleted, err := result.RowsAffected()
+	if err != nil {
+		return 0, errors.NewDatabaseError("get_rows_affected", err)
+	}
+
+	r.logger.WithFields(logrus.Fields{
+		"before":  before,
+		"limit":   limit,
+		"deleted": deleted,
+	}).Debug("notifications pruned")
+
+	return deleted, nil
+}
+
+// PruneCompletedTasks 删除完成时间早于 before 的任务及其兑换记录
+// 单次最多删除 limit 个任务，任务与兑换记录在同一事务中删除
+func (r *SqliteRepository) PruneCompletedTasks(ctx context.Context, before time.Time, limit int) (int64, error) {
+	var deleted int64
+	err := r.inTx(ctx, func(db dbInterface) error {
+		codes, err := queryKeys(ctx, db,
+			`SELECT code FROM gift_code_task
+			 WHERE all_done = 1 AND completed_at IS NOT NULL
+			   AND julianday(completed_at) < julianday(?)
+			 ORDER BY completed_at ASC
+			 LIMIT ?`, before, limit)
+		if err != nil {
+			return errors.NewDatabaseError("select_prunable_tasks", err)
+		}
+
+		deleted, err = deleteTasksByCodes(ctx, db, codes)
+		return err
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	r.logger.WithFields(logrus.Fields{
+		"before":  before,
+		"limit":   limit,
+		"deleted": deleted,
+	}).Debug("completed tasks pruned")
+
+	return deleted, nil
+}
+
+// SavePruneRun 保存清理执行记录
+func (r *SqliteRepository) SavePruneRun(ctx context.Context, run *PruneRun) error {
+	if run.Status != PruneRunStatusSuccess && run.Status != PruneRunStatusFailed {
+		return errors.NewValidationError("status", "must be 'success' or 'failed'")
+	}
+
+	query := `INSERT INTO prune_runs (target, cutoff, deleted, status, error, started_at, finished_at)
+	          VALUES (?, ?, ?, ?, ?, ?, ?)`
+	stmt, err := r.db.PrepareContext(ctx, query)
+	if err != nil {
+		return errors.NewDatabaseError("prepare_save_prune_run", err)
+	}
+	defer stmt.Close()
+
+	result, err := stmt.ExecContext(ctx,
+		run.Target,
+		run.Cutoff,
+		run.Deleted,
+		run.Status,
+		run.Error,
+		run.StartedAt,
+		run.FinishedAt,
+	)
+	if err != nil {
+		return errors.NewDatabaseError("save_prune_run", err)
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		return errors.NewDatabaseError("get_prune_run_id", err)
+	}
+	run.ID = id
+
+	return nil
+}
+
+// ListLatestPruneRuns 列出每个清理目标最近一次的执行记录
+func (r *SqliteRepository) ListLatestPruneRuns(ctx context.Context) ([]*PruneRun, error) {
+	query := `SELECT id, target, cutoff, deleted, status, error, started_at, finished_at
+	          FROM prune_runs
+	          WHERE id IN (SELECT MAX(id) FROM prune_runs GROUP BY target)
+	          ORDER BY target ASC`
+	stmt, err := r.db.PrepareContext(ctx, query)
+	if err != nil {
+		return nil, errors.NewDatabaseError("prepare_list_prune_runs", err)
+	}
+	defer stmt.Close()
+
+	rows, err := stmt.QueryContext(ctx)
+	if err != nil {
+		return nil, errors.NewDatabaseError("list_prune_runs", err)
+	}
+	defer rows.Close()
+
+	var runs []*PruneRun
+	for rows.Next() {
+		var run PruneRun
+		err := rows.Scan(
+			&run.ID,
+			&run.Target,
+			&run.Cutoff,
+			&run.Deleted,
+			&run.Status,
+			&run.Error,
+			&run.StartedAt,
+			&run.FinishedAt,
+		)
+		if err != nil {
+			return nil, errors.NewDatabaseError("scan_prune_run", err)
+		}
+		runs = append(runs, &run)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, errors.NewDatabaseError("iterate_prune_runs", err)
+	}
+
+	return runs, nil
+}
+
+// GetTableStats 统计各数据表的行数
+func (r *SqliteRepository) GetTableStats(ctx context.Context) ([]*TableStat, error) {
+	stats := make([]*TableStat, 0, len(statTables))
+	for _, table := range statTables {
+		// 表名来自固定白名单，可以安全拼接
+		var count int64
+		if err := r.db.QueryRowContext(ctx, fmt.Sprintf("SELECT COUNT(*) FROM %s", table)).Scan(&count); err != nil {
+			return nil, errors.NewDatabaseError("count_"+table, err)
+		}
+		stats = append(stats, &TableStat{Table: table, Rows: count})
+	}
+	return stats, nil
+}
+
+// queryKeys 查询单列主键列表，结果可直接作为 IN 占位符参数
+func queryKeys(ctx context.Context, db dbInterface, query string, args ...interface{}) ([]interface{}, error) {
+	rows, err := db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var keys []interface{}
+	for rows.Next() {
+		var key string
+		if err := rows.Scan(&key); err != nil {
+			return nil, err
+		}
+		keys = append(keys, key)
+	}
+	return keys, rows.Err()
+}
+
+// inPlaceholders 生成 n 个以逗号分隔的占位符
+func inPlaceholders(n int) string {
+	return strings.TrimSuffix(strings.Repeat("?,", n), ",")
+}
+
+// deleteTasksByCodes 彻底删除任务及其关联的兑换码，需在事务中调用
+func deleteTasksByCodes(ctx context.Context, db dbInterface, codes []interface{}) (int64, error) {
+	if len(codes) == 0 {
+		return 0, nil
+	}
+	placeholders := inPlaceholders(len(codes))
+
+	if _, err := db.ExecContext(ctx,
+		fmt.Sprintf("DELETE FROM gift_codes WHERE code IN (%s)", placeholders), codes...); err != nil {
+		return 0, errors.NewDatabaseError("delete_task_gift_codes", err)
+	}
+
+	result, err := db.ExecContext(ctx,
+		fmt.Sprintf("DELETE FROM gift_code_task WHERE code IN (%s)", placeholders), codes...)
+	if err != nil {
+		return 0, errors.NewDatabaseError("delete_tasks", err)
+	}
+
+	deleted, err := result.RowsAffected()
+	if err != nil {
+		return 0, errors.NewDatabaseError("get_rows_affected", err)
+	}
+	return deleted, nil
+}
+
+// deleteUsersByFids 彻底删除用户及其兑换记录、分组关系和通知目标，需在事务中调用
+func deleteUsersByFids(ctx context.Context, db dbInterface, fids []interface{}) (int64, error) {
+	if len(fids) == 0 {
+		return 0, nil
+	}
+	placeholders := inPlaceholders(len(fids))
+
+	if _, err := db.ExecContext(ctx,
+		fmt.Sprintf("DELETE FROM gift_codes WHERE fid IN (%s)", placeholders), fids...); err != nil {
+		return 0, errors.NewDatabaseError("delete_user_gift_codes", err)
+	}
+
+	if _, err := db.ExecContext(ctx,
+		fmt.Sprintf("DELETE FROM user_group_members WHERE fid IN (%s)", placeholders), fids...); err != nil {
+		return 0, errors.NewDatabaseError("delete_user_group_members", err)
+	}
+
+	if _, err := db.ExecContext(ctx,
+		fmt.Sprintf("DELETE FROM user_profile_history WHERE fid IN (%s)", placeholders), fids...); err != nil {
+		return 0, errors.NewDatabaseError("delete_user_profile_history", err)
+	}
+
+	if _, err := db.ExecContext(ctx,
+		fmt.Sprintf("DELETE FROM user_notification_targets WHERE fid IN (%s)", placeholders), fids...); err != nil {
+		return 0, errors.NewDatabaseError("delete_user_notification_targets", err)
+	}
+
+	result, err := db.ExecContext(ctx,
+		fmt.Sprintf("DELETE FROM fid_list WHERE fid IN (%s)", placeholders), fids...)
+	if err != nil {
+		return 0, errors.NewDatabaseError("delete_users", err)
+	}
+
+	deleted, err := result.RowsAffected()
+	if err != nil {
+		return 0, errors.NewDatabaseError("get_rows_affected", err)
+	}
+	return deleted, nil
+}
+
+// inTx 在事务中执行 fn
+// 如果当前仓库已处于事务中，则直接复用该事务
+func (r *SqliteRepository) inTx(ctx context.Context, fn func(db dbInterface) error) error {
+	if tx, ok := r.db.(*txDB); ok {
+		return fn(tx)
+	}
+
+	sqlDB, ok := r.db.(*sqlDB)
+	if !ok {
+		return errors.NewInternalError("cannot start transaction on non-db connection", nil)
+	}
+
+	tx, err := sqlDB.BeginTx(ctx, nil)
+	if err != nil {
+		return errors.NewDatabaseError("begin_transaction", err)
+	}
+
+	if err := fn(&txDB{tx: tx}); err != nil {
+		if rbErr := tx.Rollback(); rbErr != nil && rbErr != sql.ErrTxDone {
+			r.logger.WithFields(logrus.Fields{
+				"error":          err,
+				"rollback_error": rbErr,
+			}).Error("failed to rollback transaction")
+		}
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return errors.NewDatabaseError("commit_transaction", err)
+	}
+	return nil
+}
@@ -0,0 +1,315 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"cdk-get/internal/errors"
+)
+
+// RecordRedeemAttempt 记录一次兑换请求的结果
+func (r *SqliteRepository) RecordRedeemAttempt(ctx context.Context, attempt *RedeemAttempt) error {
+	if attempt.CreatedAt.IsZero() {
+		attempt.CreatedAt = time.Now()
+	}
+
+	result, err := r.db.ExecContext(ctx,
+		`INSERT INTO redeem_attempts (code, fid, status, message, created_at) VALUES (?, ?, ?, ?, ?)`,
+		attempt.Code, attempt.FID, attempt.Status, attempt.Message, attempt.CreatedAt)
+	if err != nil {
+		return errors.NewDatabaseError("record_redeem_attempt", err)
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		return errors.NewDatabaseError("get_redeem_attempt_id", err)
+	}
+	attempt.ID = id
+	return nil
+}
+
+// RecordCaptchaCall 记录一次验证码识别
+func (r *SqliteRepository) RecordCaptchaCall(ctx context.Context, call *CaptchaCall) error {
+	if call.CreatedAt.IsZero() {
+		call.CreatedAt = time.Now()
+	}
+
+	result, err := r.db.ExecContext(ctx,
+		`INSERT INTO captcha_calls (provider, success, cost, created_at) VALUES (?, ?, ?, ?)`,
+		call.Provider, call.Success, call.Cost, call.CreatedAt)
+	if err != nil {
+		return errors.NewDatabaseError("record_captcha_call", err)
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		return errors.NewDatabaseError("get_captcha_call_id", err)
+	}
+	call.ID = id
+	return nil
+}
+
+// statsRange 返回 column 在统计时间范围内的条件，以 " AND " 开头，没有限制时为空
+func statsRange(column string, filter StatsFilter) (string, []interface{}) {
+	var condition string
+	var args []interface{}
+	if !filter.Since.IsZero() {
+		condition += fmt.Sprintf(" AND julianday(%s) >= julianday(?)", column)
+		args = append(args, filter.Since)
+	}
+	if !filter.Until.IsZero() {
+		condition += fmt.Sprintf(" AND julianday(%s) < julianday(?)", column)
+		args = append(args, filter.Until)
+	}
+	return condition, args
+}
+
+// CountRedemptions 按天或周统计兑换请求结果，只返回有请求的时间段
+func (r *SqliteRepository) CountRedemptions(ctx context.Context, filter StatsFilter, interval string, offset time.Duration) ([]*RedemptionBucket, error) {
+	// 先按偏移换算为本地日期，按周统计时再移到所在周的周一
+	period := "date(created_at, ?)"
+	switch interval {
+	case StatsIntervalDay:
+	case StatsIntervalWeek:
+		period = "date(created_at, ?, 'weekday 0', '-6 days')"
+	default:
+		return nil, errors.NewValidationError("interval", "must be day or week")
+	}
+
+	condition, args := statsRange("created_at", filter)
+	query := fmt.Sprintf(`SELECT %s AS period,
+	              SUM(status = 'success'), SUM(status = 'received'), SUM(status = 'not_found'), SUM(status = 'failed')
+	          FROM redeem_attempts
+	          WHERE 1 = 1%s
+	          GROUP BY period
+	          ORDER BY period ASC`, period, condition)
+	args = append([]interface{}{fmt.Sprintf("%+d minutes", int(offset.Minutes()))}, args...)
+
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, errors.NewDatabaseError("count_redemptions", err)
+	}
+	defer rows.Close()
+
+	buckets := make([]*RedemptionBucket, 0)
+	for rows.Next() {
+		var bucket RedemptionBucket
+		if err := rows.Scan(&bucket.Period, &bucket.Success, &bucket.Received, &bucket.NotFound, &bucket.Failed); err != nil {
+			return nil, errors.NewDatabaseError("scan_redemption_bucket", err)
+		}
+		buckets = append(buckets, &bucket)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, errors.NewDatabaseError("iterate_redemption_buckets", err)
+	}
+
+	return buckets, nil
+}
+
+// ListCodeStats 按最近一次兑换请求倒序列出各兑换码的兑换请求统计
+func (r *SqliteRepository) ListCodeStats(ctx context.Context, filter StatsFilter) ([]*CodeStat, error) {
+	condition, args := statsRange("created_at", filter)
+	query := fmt.Sprintf(`SELECT code, COUNT(*),
+	              SUM(status = 'success'), SUM(status = 'received'), SUM(status = 'not_found'), SUM(status = 'failed'),
+	              COUNT(DISTINCT fid)
+	          FROM redeem_attempts
+	          WHERE 1 = 1%s
+	          GROUP BY code
+	          ORDER BY MAX(id) DESC
+	          LIMIT ?`, condition)
+	args = append(args, filter.Limit)
+
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, errors.NewDatabaseError("list_code_stats", err)
+	}
+	defer rows.Close()
+
+	stats := make([]*CodeStat, 0)
+	for rows.Next() {
+		var stat CodeStat
+		if err := rows.Scan(&stat.Code, &stat.Attempts, &stat.Success, &stat.Received,
+			&stat.NotFound, &stat.Failed, &stat.FIDs); err != nil {
+			return nil, errors.NewDatabaseError("scan_code_stat", err)
+		}
+		if stat.Attempts > 0 {
+			stat.SuccessRate = float64(stat.Success+stat.Received) / float64(stat.Attempts)
+		}
+		stats = append(stats, &stat)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, errors.NewDatabaseError("iterate_code_stats", err)
+	}
+
+	return stats, nil
+}
+
+// GetCompletionStats 统计完成时间在范围内的任务从添加到完成的耗时，包括已移至回收站的任务
+func (r *SqliteRepository) GetCompletionStats(ctx context.Context, filter StatsFilter) (*CompletionStat, error) {
+	condition, args := statsRange("completed_at", filter)
+	query := fmt.Sprintf(`SELECT COUNT(*), AVG(seconds), MIN(seconds), MAX(seconds)
+	          FROM (SELECT (julianday(completed_at) - julianday(created_at)) * 86400 AS seconds
+	                FROM gift_code_task
+	                WHERE all_done = 1 AND completed_at IS NOT NULL AND created_at IS NOT NULL%s)`, condition)
+
+	var stat CompletionStat
+	var avg, min, max sql.NullFloat64
+	if err := r.db.QueryRowContext(ctx, query, args...).Scan(&stat.Tasks, &avg, &min, &max); err != nil {
+		return nil, errors.NewDatabaseError("get_completion_stats", err)
+	}
+	stat.AvgSeconds = avg.Float64
+	stat.MinSeconds = min.Float64
+	stat.MaxSeconds = max.Float64
+	return &stat, nil
+}
+
+// ListFailureReasons 按次数倒序列出兑换失败的原因
+func (r *SqliteRepository) ListFailureReasons(ctx context.Context, filter StatsFilter) ([]*FailureReason, error) {
+	condition, args := statsRange("created_at", filter)
+	query := fmt.Sprintf(`SELECT message, COUNT(*), COUNT(DISTINCT code), COUNT(DISTINCT fid)
+	          FROM redeem_attempts
+	          WHERE status = 'failed'%s
+	          GROUP BY message
+	          ORDER BY COUNT(*) DESC, message ASC
+	          LIMIT ?`, condition)
+	args = append(args, filter.Limit)
+
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, errors.NewDatabaseError("list_failure_reasons", err)
+	}
+	defer rows.Close()
+
+	reasons := make([]*FailureReason, 0)
+	for rows.Next() {
+		var reason FailureReason
+		if err := rows.Scan(&reason.Reason, &reason.Count, &reason.Codes, &reason.FIDs); err != nil {
+			return nil, errors.NewDatabaseError("scan_failure_reason", err)
+		}
+		reasons = append(reasons, &reason)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, errors.NewDatabaseError("iterate_failure_reasons", err)
+	}
+
+	return reasons, nil
+}
+
+// ListFidCoverage 按领取比例升序列出未删除用户已领取和可领取的兑换码数，最多 limit 个
+// 兑换码不存在的任务完成时为所有用户写入兑换记录，同样计为已领取
+func (r *SqliteRepository) ListFidCoverage(ctx context.Context, limit int) ([]*FidCoverage, error) {
+	query := `SELECT f.fid, f.nickname, f.kid, f.disabled, COUNT(g.code), COUNT(t.code)
+	          FROM fid_list f
+	          LEFT JOIN gift_code_task t ON t.deleted_at IS NULL
+	               AND (t.target_group_id IS NULL
+	                    OR t.target_group_id IN (SELECT group_id FROM user_group_members m WHERE m.fid = f.fid))
+	          LEFT JOIN gift_codes g ON g.fid = f.fid AND g.code = t.code
+	          WHERE f.deleted_at IS NULL
+	          GROUP BY f.fid
+	          ORDER BY CASE WHEN COUNT(t.code) = 0 THEN 1.0 ELSE COUNT(g.code) * 1.0 / COUNT(t.code) END ASC,
+	                   CAST(f.fid AS INTEGER) ASC
+	          LIMIT ?`
+
+	rows, err := r.db.QueryContext(ctx, query, limit)
+	if err != nil {
+		return nil, errors.NewDatabaseError("list_fid_coverage", err)
+	}
+	defer rows.Close()
+
+	coverages := make([]*FidCoverage, 0)
+	for rows.Next() {
+		var coverage FidCoverage
+		if err := rows.Scan(&coverage.FID, &coverage.Nickname, &coverage.KID, &coverage.Disabled,
+			&coverage.Received, &coverage.Available); err != nil {
+			return nil, errors.NewDatabaseError("scan_fid_coverage", err)
+		}
+		coverage.Coverage = 1
+		if coverage.Available > 0 {
+			coverage.Coverage = float64(coverage.Received) / float64(coverage.Available)
+		}
+		coverages = append(coverages, &coverage)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, errors.NewDatabaseError("iterate_fid_coverage", err)
+	}
+
+	return coverages, nil
+}
+
+// ListCaptchaUsage 按提供商统计验证码识别次数和费用
+func (r *SqliteRepository) ListCaptchaUsage(ctx context.Context, filter StatsFilter) ([]*CaptchaUsage, error) {
+	condition, args := statsRange("created_at", filter)
+	query := fmt.Sprintf(`SELECT provider, COUNT(*), SUM(success = 0), TOTAL(cost)
+	          FROM captcha_calls
+	          WHERE 1 = 1%s
+	          GROUP BY provider
+	          ORDER BY provider ASC`, condition)
+
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, errors.NewDatabaseError("list_captcha_usage", err)
+	}
+	defer rows.Close()
+
+	usages := make([]*CaptchaUsage, 0)
+	for rows.Next() {
+		var usage CaptchaUsage
+		if err := rows.Scan(&usage.Provider, &usage.Calls, &usage.Failures, &usage.Cost); err != nil {
+			return nil, errors.NewDatabaseError("scan_captcha_usage", err)
+		}
+		usages = append(usages, &usage)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, errors.NewDatabaseError("iterate_captcha_usage", err)
+	}
+
+	return usages, nil
+}
+
+// PruneRedeemAttempts 删除早于 before 的兑换请求记录
+// 单次最多删除 limit 条记录，调用方需循环调用直到返回值小于 limit
+func (r *SqliteRepository) PruneRedeemAttempts(ctx context.Context, before time.Time, limit int) (int64, error) {
+	return r.pruneByCreatedAt(ctx, "redeem_attempts", before, limit)
+}
+
+// PruneCaptchaCalls 删除早于 before 的验证码识别记录
+// 单次最多删除 limit 条记录，调用方需循环调用直到返回值小于 limit
+func (r *SqliteRepository) PruneCaptchaCalls(ctx context.Context, before time.Time, limit int) (int64, error) {
+	return r.pruneByCreatedAt(ctx, "captcha_calls", before, limit)
+}
+
+// pruneByCreatedAt 按 created_at 分批删除 table 中早于 before 的记录，table 必须是固定的表名
+func (r *SqliteRepository) pruneByCreatedAt(ctx context.Context, table string, before time.Time, limit int) (int64, error) {
+	result, err := r.db.ExecContext(ctx, fmt.Sprintf(
+		`DELETE FROM %[1]s WHERE id IN (
+		     SELECT id FROM %[1]s
+		     WHERE julianday(created_at) < julianday(?)
+		     ORDER BY id ASC
+		     LIMIT ?)`, table), before, limit)
+	if err != nil {
+		return 0, errors.NewDatabaseError("prune_"+table, err)
+	}
+
+	deleted, err := result.RowsAffected()
+	if err != nil {
+		return 0, errors.NewDatabaseError("get_rows_affected", err)
+	}
+
+	r.logger.WithFields(logrus.Fields{
+		"table":   table,
+		"before":  before,
+		"limit":   limit,
+		"deleted": deleted,
+	}).Debug("statistics pruned")
+
+	return deleted, nil
+}
@@ -0,0 +1,294 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"cdk-get/internal/errors"
+)
+
+// DeleteUser 软删除用户
+// 用户移至回收站后不再参与兑换，兑换记录保留
+func (r *SqliteRepository) DeleteUser(ctx context.Context, fid string) error {
+	query := `UPDATE fid_list SET deleted_at = ? WHERE fid = ? AND deleted_at IS NULL`
+	stmt, err := r.db.PrepareContext(ctx, query)
+	if err != nil {
+		return errors.NewDatabaseError("prepare_delete_user", err)
+	}
+	defer stmt.Close()
+
+	result, err := stmt.ExecContext(ctx, time.Now(), fid)
+	if err != nil {
+		return errors.NewDatabaseError("delete_user", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return errors.NewDatabaseError("get_rows_affected", err)
+	}
+	if rowsAffected == 0 {
+		return ErrUserNotFound
+	}
+
+	r.logger.WithFields(logrus.Fields{
+		"fid": fid,
+	}).Info("user moved to trash")
+
+	return nil
+}
+
+// ListDeletedTasks 列出回收站中的任务
+func (r *SqliteRepository) ListDeletedTasks(ctx context.Context) ([]*Task, error) {
+	query := `SELECT ` + taskColumns + `
+	          FROM ` + taskTables + `
+	          WHERE t.deleted_at IS NOT NULL
+	          ORDER BY t.deleted_at DESC`
+	stmt, err := r.db.PrepareContext(ctx, query)
+	if err != nil {
+		return nil, errors.NewDatabaseError("prepare_list_deleted_tasks", err)
+	}
+	defer stmt.Close()
+
+	rows, err := stmt.QueryContext(ctx)
+	if err != nil {
+		return nil, errors.NewDatabaseError("list_deleted_tasks", err)
+	}
+	defer rows.Close()
+
+	var tasks []*Task
+	for rows.Next() {
+		task, err := scanTask(rows)
+		if err != nil {
+			return nil, errors.NewDatabaseError("scan_deleted_task", err)
+		}
+		tasks = append(tasks, task)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, errors.NewDatabaseError("iterate_deleted_tasks", err)
+	}
+
+	return tasks, nil
+}
+
+// RestoreTask 从回收站恢复任务
+func (r *SqliteRepository) RestoreTask(ctx context.Context, code string) error {
+	query := `UPDATE gift_code_task SET deleted_at = NULL WHERE code = ? AND deleted_at IS NOT NULL`
+	stmt, err := r.db.PrepareContext(ctx, query)
+	if err != nil {
+		return errors.NewDatabaseError("prepare_restore_task", err)
+	}
+	defer stmt.Close()
+
+	result, err := stmt.ExecContext(ctx, code)
+	if err != nil {
+		return errors.NewDatabaseError("restore_task", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return errors.NewDatabaseError("get_rows_affected", err)
+	}
+	if rowsAffected == 0 {
+		return ErrTaskNotFound
+	}
+
+	r.logger.WithFields(logrus.Fields{
+		"code": code,
+	}).Info("task restored from trash")
+
+	return nil
+}
+
+// PurgeTask 彻底删除回收站中的任务及其关联的兑换码
+func (r *SqliteRepository) PurgeTask(ctx context.Context, code string) error {
+	var deleted int64
+	err := r.inTx(ctx, func(db dbInterface) error {
+		codes, err := queryKeys(ctx, db,
+			`SELECT code FROM gift_code_task WHERE code = ? AND deleted_at IS NOT NULL`, code)
+		if err != nil {
+			return errors.NewDatabaseError("select_purgeable_task", err)
+		}
+
+		deleted, err = deleteTasksByCodes(ctx, db, codes)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	if deleted == 0 {
+		return ErrTaskNotFound
+	}
+
+	r.logger.WithFields(logrus.Fields{
+		"code": code,
+	}).Info("task purged from trash")
+
+	return nil
+}
+
+// ListDeletedUsers 列出回收站中的用户
+func (r *SqliteRepository) ListDeletedUsers(ctx context.Context) ([]*User, error) {
+	query := `SELECT fid, nickname, kid, avatar_image, deleted_at
+	          FROM fid_list
+	          WHERE deleted_at IS NOT NULL
+	          ORDER BY deleted_at DESC`
+	stmt, err := r.db.PrepareContext(ctx, query)
+	if err != nil {
+		return nil, errors.NewDatabaseError("prepare_list_deleted_users", err)
+	}
+	defer stmt.Close()
+
+	rows, err := stmt.QueryContext(ctx)
+	if err != nil {
+		return nil, errors.NewDatabaseError("list_deleted_users", err)
+	}
+	defer rows.Close()
+
+	var users []*User
+	for rows.Next() {
+		var user User
+		var deletedAt sql.NullTime
+		err := rows.Scan(
+			&user.FID,
+			&user.Nickname,
+			&user.KID,
+			&user.AvatarImage,
+			&deletedAt,
+		)
+		if err != nil {
+			return nil, errors.NewDatabaseError("scan_deleted_user", err)
+		}
+		if deletedAt.Valid {
+			user.DeletedAt = &deletedAt.Time
+		}
+		users = append(users, &user)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, errors.NewDatabaseError("iterate_deleted_users", err)
+	}
+
+	return users, nil
+}
+
+// RestoreUser 从回收站恢复用户
+func (r *SqliteRepository) RestoreUser(ctx context.Context, fid string) error {
+	query := `UPDATE fid_list SET deleted_at = NULL WHERE fid = ? AND deleted_at IS NOT NULL`
+	stmt, err := r.db.PrepareContext(ctx, query)
+	if err != nil {
+		return errors.NewDatabaseError("prepare_restore_user", err)
+	}
+	defer stmt.Close()
+
+	result, err := stmt.ExecContext(ctx, fid)
+	if err != nil {
+		return errors.NewDatabaseError("restore_user", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return errors.NewDatabaseError("get_rows_affected", err)
+	}
+	if rowsAffected == 0 {
+		return ErrUserNotFound
+	}
+
+	r.logger.WithFields(logrus.Fields{
+		"fid": fid,
+	}).Info("user restored from trash")
+
+	return nil
+}
+
+// PurgeUser 彻底删除回收站中的用户及其兑换记录
+func (r *SqliteRepository) PurgeUser(ctx context.Context, fid string) error {
+	var deleted int64
+	err := r.inTx(ctx, func(db dbInterface) error {
+		fids, err := queryKeys(ctx, db,
+			`SELECT fid FROM fid_list WHERE fid = ? AND deleted_at IS NOT NULL`, fid)
+		if err != nil {
+			return errors.NewDatabaseError("select_purgeable_user", err)
+		}
+
+		deleted, err = deleteUsersByFids(ctx, db, fids)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	if deleted == 0 {
+		return ErrUserNotFound
+	}
+
+	r.logger.WithFields(logrus.Fields{
+		"fid": fid,
+	}).Info("user purged from trash")
+
+	return nil
+}
+
+// PurgeDeletedTasks 彻底删除删除时间早于 before 的任务
+// 单次最多删除 limit 个任务，调用方需循环调用直到返回值小于 limit
+func (r *SqliteRepository) PurgeDeletedTasks(ctx context.Context, before time.Time, limit int) (int64, error) {
+	var deleted int64
+	err := r.inTx(ctx, func(db dbInterface) error {
+		codes, err := queryKeys(ctx, db,
+			`SELECT code FROM gift_code_task
+			 WHERE deleted_at IS NOT NULL
+			   AND julianday(deleted_at) < julianday(?)
+			 ORDER BY deleted_at ASC
+			 LIMIT ?`, before, limit)
+		if err != nil {
+			return errors.NewDatabaseError("select_purgeable_tasks", err)
+		}
+
+		deleted, err = deleteTasksByCodes(ctx, db, codes)
+		return err
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	r.logger.WithFields(logrus.Fields{
+		"before":  before,
+		"limit":   limit,
+		"deleted": deleted,
+	}).Debug("deleted tasks purged")
+
+	return deleted, nil
+}
+
+// PurgeDeletedUsers 彻底删除删除时间早于 before 的用户
+// 单次最多删除 limit 个用户，调用方需循环调用直到返回值小于 limit
+func (r *SqliteRepository) PurgeDeletedUsers(ctx context.Context, before time.Time, limit int) (int64, error) {
+	var deleted int64
+	err := r.inTx(ctx, func(db dbInterface) error {
+		fids, err := queryKeys(ctx, db,
+			`SELECT fid FROM fid_list
+			 WHERE deleted_at IS NOT NULL
+			   AND julianday(deleted_at) < julianday(?)
+			 ORDER BY deleted_at ASC
+			 LIMIT ?`, before, limit)
+		if err != nil {
+			return errors.NewDatabaseError("select_purgeable_users", err)
+		}
+
+		deleted, err = deleteUsersByFids(ctx, db, fids)
+		return err
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	r.logger.WithFields(logrus.Fields{
+		"before":  before,
+		"limit":   limit,
+		"deleted": deleted,
+	}).Debug("deleted users purged")
+
+	return deleted, nil
+}
@@ -1,20 +1,28 @@
 package svc
 
 import (
+	"cdk-get/internal/config"
+	"cdk-get/internal/events"
 	"cdk-get/internal/service"
 	"cdk-get/internal/storage"
 )
 
 type ServiceContext struct {
+	Config              *config.Config
 	SqlClient           storage.KeyStorage
 	Repository          storage.Repository
 	NotificationService *service.NotificationService
+	GiftService         *service.GiftService
+	Events              *events.Bus
 }
 
-func NewServiceContext(sqlClient storage.KeyStorage, repository storage.Repository, notificationService *service.NotificationService) *ServiceContext {
+func NewServiceContext(cfg *config.Config, sqlClient storage.KeyStorage, repository storage.Repository, notificationService *service.NotificationService, giftService *service.GiftService, bus *events.Bus) *ServiceContext {
 	return &ServiceContext{
+		Config:              cfg,
 		SqlClient:           sqlClient,
 		Repository:          repository,
 		NotificationService: notificationService,
+		GiftService:         giftService,
+		Events:              bus,
 	}
 }
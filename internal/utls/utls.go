@@ -1,12 +1,11 @@
 package utls
 
 import (
-	"bytes"
+	"cdk-get/internal/metrics"
 	"crypto/md5"
 	"encoding/hex"
 	"encoding/json"
 	"fmt"
-	"github.com/sirupsen/logrus"
 	"io"
 	"net"
 	"net/http"
@@ -63,13 +62,20 @@ func generateSign(params url.Values, secretKey string) string {
 }
 
 // 发送POST请求
-func SendRequestV2[T any](path string, params url.Values, secretKey string) (*T, error) {
+// baseURL 为兑换接口的地址前缀，每次请求按接口路径和结果记录 game_api 指标
+func SendRequestV2[T any](baseURL, path string, params url.Values, secretKey string) (*T, error) {
+	start := time.Now()
+	outcome := metrics.OutcomeError
+	defer func() {
+		metrics.ObserveGameAPI(path, outcome, time.Since(start))
+	}()
+
 	// 生成签名并添加到参数
 	signature := generateSign(params, secretKey)
 	params.Add("sign", signature) // 根据实际字段名调整
 
 	// 创建请求
-	req, err := http.NewRequest("POST", fmt.Sprintf("https://wjdr-giftcode-api.campfiregames.cn/api/%s", path), strings.NewReader(params.Encode()))
+	req, err := http.NewRequest("POST", baseURL+path, strings.NewReader(params.Encode()))
 	if err != nil {
 		return nil, err
 	}
@@ -93,45 +99,24 @@ func SendRequestV2[T any](path string, params url.Values, secretKey string) (*T,
 
 	// 检查状态码
 	if resp.StatusCode >= 400 {
+		outcome = metrics.OutcomeHTTPError
 		return nil, fmt.Errorf("request failed with status %d: %s", resp.StatusCode, string(body))
 	}
 
 	// 反序列化
-	var result T
-	if err := json.Unmarshal(body, &result); err != nil {
+	var decoded T
+	if err := json.Unmarshal(body, &decoded); err != nil {
 		return nil, err
 	}
 
-	return &result, nil
-}
-
-func PushWithWxPusher(title, summary, content string) {
-	appToken := "AT_gVv4hK5XwHo4nHdyFafikJxt2eIGFSDa"
-	uid := "UID_Zgs55UrCKYtZnxXW3i9D7KQKCBhX"
-	body := map[string]interface{}{
-		"appToken":      appToken,
-		"content":       content,
-		"summary":       summary,
-		"title":         title,
-		"contentType":   2,
-		"uids":          []string{uid},
-		"verifyPay":     false,
-		"verifyPayType": 0,
+	// 接口以 code 字段返回业务结果，非 0 为业务错误
+	var status struct {
+		Code int `json:"code"`
 	}
-	jsonBody, _ := json.Marshal(body)
-	req, _ := http.NewRequest("POST", "https://wxpusher.zjiecode.com/api/send/message", bytes.NewBuffer(jsonBody))
-
-	req.Header.Add("Content-Type", "application/json")
-
-	resp, err := defaultClient.Do(req)
-	if err != nil {
-		logrus.Errorf("请求失败: %v", err)
+	outcome = metrics.OutcomeSuccess
+	if json.Unmarshal(body, &status) == nil && status.Code != 0 {
+		outcome = metrics.OutcomeAPIError
 	}
-	defer resp.Body.Close()
-	respBody, _ := io.ReadAll(resp.Body)
-	if resp.StatusCode >= 400 {
-		logrus.Errorf("request failed with status %d: %s", resp.StatusCode, string(respBody))
-		return
-	}
-	logrus.Infof("通知成功: %s", string(respBody))
+
+	return &decoded, nil
 }
@@ -14,7 +14,3 @@ func TestGenSign(t *testing.T) {
 	s := generateSign(params, ddSecretKey)
 	t.Logf("sign: %s", s)
 }
-
-func TestWxPush(t *testing.T) {
-	PushWithWxPusher("兑换码兑换成功", "兑换码abcd兑换成功", "兑换码abcd全部用户兑换成功, 有用户a,b,c")
-}